@@ -2,20 +2,37 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
 	"errors"
+	"flag"
 	"fmt"
 	"golang.org/x/sync/errgroup"
 	"homework10/internal/adapters/adrepo"
+	"homework10/internal/adapters/filerepo"
+	"homework10/internal/adapters/memuow"
+	"homework10/internal/adapters/searchindex"
+	"homework10/internal/adapters/sqlrepo"
 	"homework10/internal/adapters/userrepo"
 	"homework10/internal/app/adsapp"
+	"homework10/internal/app/authapp"
 	"homework10/internal/app/userapp"
+	"homework10/internal/entities/uow"
+	"homework10/internal/entities/user"
 	grpcInterface "homework10/internal/ports/grpc"
 	"homework10/internal/ports/httpgin"
+	"homework10/pkg/blob"
+	"homework10/pkg/idgen"
+	"homework10/pkg/jwt"
 	"homework10/pkg/logger"
+	"homework10/pkg/mail"
+	"homework10/pkg/passwords"
+	"homework10/pkg/ratelimit"
 	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 )
@@ -23,12 +40,90 @@ import (
 const (
 	httpPort = ":18081"
 	grpcPort = ":50055"
+
+	purgeInterval = time.Hour
+	// scheduleInterval is how late an ad may go up or come down
+	scheduleInterval = time.Minute
 )
 
 func main() {
-	userRepo := userrepo.New()
-	adsRepo := adrepo.New()
+	storage := flag.String("storage", "memory", "storage backend: memory, sqlite or file")
+	dsn := flag.String("dsn", "ads.db", "sqlite database file, used with -storage=sqlite")
+	dataDir := flag.String("data-dir", "data", "write-ahead log and snapshot directory, used with -storage=file")
+	trashPeriod := flag.Duration("trash-period", 30*24*time.Hour, "how long deleted ads stay in the trash")
+	idKind := flag.String("id-generator", idgen.KindSequence, "id generator: sequence, snowflake or uuidv7")
+	node := flag.Int64("node", 0, "node number of this instance, used with -id-generator=snowflake or uuidv7")
+	hasherKind := flag.String("password-hasher", passwords.KindArgon2id, "password hashing algorithm: argon2id or bcrypt")
+	plaintext := flag.Bool("plaintext-passwords", false, "accept the passwords stored in plain text before hashing and rehash them on login")
+	params := passwords.DefaultParams()
+	flag.IntVar(&params.BcryptCost, "bcrypt-cost", params.BcryptCost, "bcrypt cost")
+	argon2Memory := flag.Uint("argon2-memory", uint(params.Argon2id.Memory), "argon2id memory in KiB")
+	argon2Time := flag.Uint("argon2-time", uint(params.Argon2id.Time), "argon2id number of passes")
+	argon2Threads := flag.Uint("argon2-threads", uint(params.Argon2id.Threads), "argon2id degree of parallelism")
+	tokenSecret := flag.String("token-secret", os.Getenv("TOKEN_SECRET"), "key signing the access, refresh and email verification and password reset tokens, at least 32 bytes; random when empty")
+	accessTTL := flag.Duration("access-ttl", authapp.DefaultAccessTTL, "how long an access token is valid")
+	refreshTTL := flag.Duration("refresh-ttl", authapp.DefaultRefreshTTL, "how long a refresh token is valid")
+	outbox := flag.String("mail-outbox", "outbox", "directory the mail to the users is written to")
+	blobDir := flag.String("blob-dir", "blobs", "directory the images of the ads are stored in")
+	adminIds := flag.String("admin-ids", "", "comma separated ids of the users who are made admins on start")
+	rateLimit := flag.String("rate-limit", "600/1m", "requests a client may make to a route or rpc, as requests/period, or off")
+	rateLimits := flag.String("rate-limits", "", `comma separated limits of single routes and rpcs, e.g. "POST /api/v1/ads=10/1m, /ad.AdService/CreateAd=10/1m"`)
+	flag.Parse()
+	params.Argon2id.Memory, params.Argon2id.Time = uint32(*argon2Memory), uint32(*argon2Time)
+	params.Argon2id.Threads = uint8(*argon2Threads)
+
 	log := logger.InitLog()
+	adIds, err := idgen.New(*idKind, *node)
+	if err != nil {
+		log.Info("err with creating id generator")
+		panic(err)
+	}
+	userIds, err := idgen.New(*idKind, *node)
+	if err != nil {
+		log.Info("err with creating id generator")
+		panic(err)
+	}
+	hasher, err := passwords.New(*hasherKind, params)
+	if err != nil {
+		log.Info("err with creating password hasher")
+		panic(err)
+	}
+	if *plaintext {
+		hasher.AllowPlaintext()
+	}
+	signer, err := newTokenSigner(*tokenSecret, log)
+	if err != nil {
+		log.Info("err with creating token signer")
+		panic(err)
+	}
+	mailer, err := mail.NewOutbox(*outbox)
+	if err != nil {
+		log.Info("err with creating mail outbox")
+		panic(err)
+	}
+	blobs, err := blob.NewLocal(*blobDir)
+	if err != nil {
+		log.Info("err with creating blob storage")
+		panic(err)
+	}
+	admins, err := parseIds(*adminIds)
+	if err != nil {
+		log.Info("err with parsing admin ids")
+		panic(err)
+	}
+
+	limiter, err := newRateLimiter(*rateLimit, *rateLimits)
+	if err != nil {
+		log.Info("err with parsing rate limits")
+		panic(err)
+	}
+
+	tx, closeRepo, err := newUnitOfWork(*storage, *dsn, *dataDir, adIds, userIds)
+	if err != nil {
+		log.Info("err with opening storage")
+		panic(err)
+	}
+	defer closeRepo()
 
 	lis, err := net.Listen("tcp", grpcPort)
 	if err != nil {
@@ -36,8 +131,25 @@ func main() {
 		panic(err)
 	}
 
-	httpServer := httpgin.NewHTTPServer(httpPort, adsapp.NewApp(adsRepo, userRepo), userapp.NewApp(userRepo), log)
-	grpcServer := grpcInterface.NewGrpcServer(adsRepo, userRepo)
+	index, err := searchindex.Load(context.Background(), tx.Repositories().Ads)
+	if err != nil {
+		log.Info("err with building search index")
+		panic(err)
+	}
+
+	adsApp, userApp := adsapp.NewApp(tx, index, blobs), userapp.NewApp(tx, hasher, signer, mailer)
+	for _, id := range admins {
+		err = tx.Do(context.Background(), func(repos uow.Repositories) error {
+			_, err := repos.Users.UpdateRole(context.Background(), id, user.RoleAdmin)
+			return err
+		})
+		if err != nil {
+			log.Infof("can't make user %d an admin: %v", id, err)
+		}
+	}
+	authApp := authapp.NewApp(userApp, signer, *accessTTL, *refreshTTL)
+	httpServer := httpgin.NewHTTPServer(httpPort, adsApp, userApp, authApp, limiter, log)
+	grpcServer := grpcInterface.NewGrpcServer(adsApp, userApp, authApp, limiter)
 
 	g, ctx := errgroup.WithContext(context.Background())
 	gracefulShutdown(ctx, g, log)
@@ -97,11 +209,120 @@ func main() {
 			return fmt.Errorf("grpc server can't listen and serve requests: %w", err)
 		}
 	})
+
+	g.Go(func() error {
+		ticker := time.NewTicker(purgeInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-ticker.C:
+				if err := adsApp.PurgeTrash(ctx, *trashPeriod); err != nil {
+					log.Infof("can't purge the trash: %s", err.Error())
+				}
+			}
+		}
+	})
+
+	g.Go(func() error {
+		log.Info("starting ad scheduler")
+		defer log.Info("stopping ad scheduler")
+
+		ticker := time.NewTicker(scheduleInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-ticker.C:
+				n, err := adsApp.ApplySchedule(ctx)
+				if err != nil && ctx.Err() == nil {
+					log.Infof("can't apply the ad schedule: %s", err.Error())
+				}
+				if n > 0 {
+					log.Infof("published or archived %d scheduled ads", n)
+				}
+			}
+		}
+	})
 	if err := g.Wait(); err != nil {
 		log.Infof("gracefully shutting down the servers: %s\n", err.Error())
 	}
 }
 
+func newUnitOfWork(storage, dsn, dataDir string, adIds, userIds idgen.IDGenerator) (uow.UnitOfWork, func(), error) {
+	switch storage {
+	case "memory":
+		return memuow.New(adrepo.New(adIds), userrepo.New(userIds)), func() {}, nil
+	case "sqlite":
+		repo, err := sqlrepo.Open(dsn, adIds, userIds)
+		if err != nil {
+			return nil, nil, err
+		}
+		return repo, func() { _ = repo.Close() }, nil
+	case "file":
+		repo, err := filerepo.Open(dataDir, filerepo.DefaultSnapshotEvery, adIds, userIds)
+		if err != nil {
+			return nil, nil, err
+		}
+		return repo, func() { _ = repo.Close() }, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown storage %q", storage)
+	}
+}
+
+// newTokenSigner signs with the given secret. Without one it makes up a
+// random key, so the tokens don't survive a restart.
+func newTokenSigner(secret string, log logger.Logger) (*jwt.Signer, error) {
+	if secret != "" {
+		return jwt.NewSigner([]byte(secret))
+	}
+	log.Info("no token secret given, tokens are signed with a random key")
+	key := make([]byte, jwt.MinKeyLen)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	return jwt.NewSigner(key)
+}
+
+// newRateLimiter takes the default limits of the ports, the ones given
+// replace them.
+func newRateLimiter(defaultLimit, limits string) (*ratelimit.Limiter, error) {
+	rules := ratelimit.Rules{Limits: httpgin.DefaultRateLimits()}
+	for name, limit := range grpcInterface.DefaultRateLimits() {
+		rules.Limits[name] = limit
+	}
+	var err error
+	if rules.Default, err = ratelimit.ParseLimit(defaultLimit); err != nil {
+		return nil, err
+	}
+	given, err := ratelimit.ParseLimits(limits)
+	if err != nil {
+		return nil, err
+	}
+	for name, limit := range given {
+		rules.Limits[name] = limit
+	}
+	return ratelimit.New(rules), nil
+}
+
+func parseIds(list string) ([]int64, error) {
+	var ids []int64
+	for _, field := range strings.Split(list, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		id, err := strconv.ParseInt(field, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
 func gracefulShutdown(ctx context.Context, g *errgroup.Group, log logger.Logger) {
 	signals := make(chan os.Signal, 1)
 	signal.Notify(signals, os.Interrupt, syscall.SIGTERM, syscall.SIGQUIT)
@@ -5,7 +5,9 @@ go 1.19
 require (
 	github.com/OkDenAl/validator v0.1.0
 	github.com/gin-gonic/gin v1.9.0
+	github.com/mattn/go-sqlite3 v1.14.16
 	github.com/stretchr/testify v1.8.2
+	golang.org/x/crypto v0.8.0
 	google.golang.org/grpc v1.54.0
 )
 
@@ -33,7 +35,6 @@ require (
 	github.com/ugorji/go/codec v1.2.11 // indirect
 	github.com/vektra/mockery v1.1.2 // indirect
 	golang.org/x/arch v0.3.0 // indirect
-	golang.org/x/crypto v0.8.0 // indirect
 	golang.org/x/net v0.9.0 // indirect
 	golang.org/x/sync v0.1.0 // indirect
 	golang.org/x/sys v0.7.0 // indirect
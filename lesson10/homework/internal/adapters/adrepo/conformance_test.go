@@ -0,0 +1,15 @@
+package adrepo_test
+
+import (
+	"homework10/internal/adapters/adrepo"
+	"homework10/internal/entities/ads"
+	"homework10/internal/entities/ads/adstest"
+	"homework10/pkg/idgen"
+	"testing"
+)
+
+func TestConformance(t *testing.T) {
+	adstest.RunRepositoryTests(t, func(t *testing.T) ads.Store {
+		return adrepo.New(idgen.NewSequence())
+	})
+}
@@ -3,58 +3,103 @@ package adrepo
 import (
 	"context"
 	"errors"
+	"homework10/internal/adapters/internal/memstore"
 	"homework10/internal/entities/ads"
-	"strconv"
+	"homework10/pkg/idgen"
+	"sort"
 	"strings"
 	"sync"
+	"time"
 )
 
 var (
-	ErrInvalidAdId    = errors.New("cant find this id in map")
-	ErrInvalidAdTitle = errors.New("cant find this title in map")
+	ErrInvalidAdId       = errors.New("cant find this id in map")
+	ErrInvalidAdTitle    = errors.New("cant find this title in map")
+	ErrInvalidCategoryId = errors.New("cant find this category")
+	ErrInvalidImageId    = errors.New("cant find this image")
 )
 
+type store struct {
+	mu         *sync.RWMutex
+	adDataById map[int64]*ads.Ad
+	revisions  map[int64][]*ads.Revision
+	categories map[int64]*ads.Category
+	// images by ad id
+	images map[int64][]*ads.Image
+	// category and image ids are not taken back when one is deleted
+	nextCategoryId int64
+	nextImageId    int64
+	ids            idgen.IDGenerator
+}
+
+// repository is a view of the store, the one handed to a transaction keeps
+// an undo log of its writes.
 type repository struct {
-	mu             *sync.RWMutex
-	adDataById     map[int64]*ads.Ad
-	curIdGenerator int64
+	*store
+	undo *undoLog
+}
+
+func New(ids idgen.IDGenerator) ads.Store {
+	return &repository{store: &store{
+		adDataById:     make(map[int64]*ads.Ad),
+		revisions:      make(map[int64][]*ads.Revision),
+		categories:     make(map[int64]*ads.Category),
+		images:         make(map[int64][]*ads.Image),
+		nextCategoryId: 1,
+		nextImageId:    1,
+		ids:            ids,
+		mu:             &sync.RWMutex{},
+	}}
 }
 
-func New() ads.Repository {
-	return &repository{adDataById: make(map[int64]*ads.Ad), curIdGenerator: 0, mu: &sync.RWMutex{}}
+func NewForTest(r map[int64]*ads.Ad, idGen int64) ads.Store {
+	ids := idgen.NewSequence()
+	ids.Resume(idGen - 1)
+	return &repository{store: &store{adDataById: r, revisions: make(map[int64][]*ads.Revision),
+		categories: make(map[int64]*ads.Category), images: make(map[int64][]*ads.Image), nextCategoryId: 1,
+		nextImageId: 1, ids: ids, mu: &sync.RWMutex{}}}
 }
 
-func NewForTest(r map[int64]*ads.Ad, idGen int64) ads.Repository {
-	return &repository{adDataById: r, curIdGenerator: idGen, mu: &sync.RWMutex{}}
+func cloneAd(ad *ads.Ad) *ads.Ad {
+	c := *ad
+	return &c
 }
 
 func (r *repository) AddAd(ctx context.Context, ad *ads.Ad) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
 	r.mu.Lock()
-	ad.ID = r.curIdGenerator
-	r.adDataById[r.curIdGenerator] = ad
-	r.curIdGenerator++
+	ad.ID = r.ids.NextID()
+	r.saveAd(ad.ID)
+	r.adDataById[ad.ID] = cloneAd(ad)
 	r.mu.Unlock()
 	return ad.ID, nil
 }
 
 func (r *repository) GetAdById(ctx context.Context, adId int64) (*ads.Ad, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	r.mu.RLock()
+	defer r.mu.RUnlock()
 	ad, ok := r.adDataById[adId]
-	r.mu.RUnlock()
-	if !ok {
+	if !ok || ad.Trashed() {
 		return nil, ErrInvalidAdId
 	}
-	return ad, nil
+	return cloneAd(ad), nil
 }
 
 func (r *repository) GetAdsByTitle(ctx context.Context, title string) ([]*ads.Ad, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 	resp := make([]*ads.Ad, 0)
-	for key := range r.adDataById {
-		r.mu.RLock()
-		ad := r.adDataById[key]
-		r.mu.RUnlock()
-		if strings.HasPrefix(ad.Title, title) {
-			resp = append(resp, ad)
+	for _, ad := range r.adDataById {
+		if !ad.Trashed() && strings.HasPrefix(ad.Title, title) {
+			resp = append(resp, cloneAd(ad))
 		}
 	}
 	if len(resp) == 0 {
@@ -64,46 +109,450 @@ func (r *repository) GetAdsByTitle(ctx context.Context, title string) ([]*ads.Ad
 }
 
 func (r *repository) GetAll(ctx context.Context, filters ads.Filters) ([]*ads.Ad, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 	resp := make([]*ads.Ad, 0)
-	for key := range r.adDataById {
-		r.mu.RLock()
-		val := r.adDataById[key]
-		r.mu.RUnlock()
-		if filters.Date != "" && val.CreationDate != filters.Date {
-			continue
+	for _, ad := range r.adDataById {
+		if filters.Match(ad) {
+			resp = append(resp, cloneAd(ad))
 		}
-		if filters.AuthorId != "" && strconv.FormatInt(val.AuthorID, 10) != filters.AuthorId {
-			continue
+	}
+	return filters.Page(resp)
+}
+
+// updateAd replaces the ad, trashed or not as trashed says, the undo log
+// keeps the old one.
+func (r *repository) updateAd(ctx context.Context, adId int64, trashed bool, change func(ad *ads.Ad)) (*ads.Ad, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ad, ok := r.adDataById[adId]
+	if !ok || ad.Trashed() != trashed {
+		return nil, ErrInvalidAdId
+	}
+	r.saveAd(adId)
+	ad = cloneAd(ad)
+	change(ad)
+	ad.Version++
+	r.adDataById[adId] = ad
+	return cloneAd(ad), nil
+}
+
+func (r *repository) UpdateAdState(ctx context.Context, adId int64, state ads.State, rejectionReason string) (*ads.Ad, error) {
+	return r.updateAd(ctx, adId, false, func(ad *ads.Ad) {
+		ad.SetState(state, rejectionReason)
+		ad.UpdateDate = today()
+	})
+}
+
+func (r *repository) UpdateAdTitleAndText(ctx context.Context, adId int64, newTitle, newText string) (*ads.Ad, error) {
+	return r.updateAd(ctx, adId, false, func(ad *ads.Ad) {
+		ad.Title = newTitle
+		ad.Text = newText
+		ad.UpdateDate = today()
+	})
+}
+
+func (r *repository) UpdateAdPublication(ctx context.Context, adId int64, state ads.State, publishAt, expiresAt time.Time) (*ads.Ad, error) {
+	return r.updateAd(ctx, adId, false, func(ad *ads.Ad) {
+		ad.SetState(state, "")
+		ad.PublishAt = publishAt
+		ad.ExpiresAt = expiresAt
+		ad.UpdateDate = today()
+	})
+}
+
+func (r *repository) GetDueAds(ctx context.Context, now time.Time) ([]*ads.Ad, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	resp := make([]*ads.Ad, 0)
+	for _, ad := range r.adDataById {
+		if ad.Due(now) {
+			resp = append(resp, cloneAd(ad))
 		}
-		if filters.Status == ads.Published && val.Published {
-			resp = append(resp, val)
+	}
+	sort.Slice(resp, func(i, j int) bool {
+		return resp[i].ID < resp[j].ID
+	})
+	return resp, nil
+}
+
+func (r *repository) DeleteAd(ctx context.Context, adId int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	r.saveAll(adId)
+	delete(r.adDataById, adId)
+	delete(r.revisions, adId)
+	delete(r.images, adId)
+	r.mu.Unlock()
+	return nil
+}
+
+func (r *repository) DeleteAdsByAuthor(ctx context.Context, authorId int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for id, ad := range r.adDataById {
+		if ad.AuthorID == authorId {
+			r.saveAll(id)
+			delete(r.adDataById, id)
+			delete(r.revisions, id)
+			delete(r.images, id)
 		}
-		if filters.Status == ads.Unpublished && !val.Published {
-			resp = append(resp, val)
+	}
+	return nil
+}
+
+func (r *repository) TrashAd(ctx context.Context, adId int64, at time.Time) (*ads.Ad, error) {
+	return r.updateAd(ctx, adId, false, func(ad *ads.Ad) {
+		ad.DeletedAt = at
+	})
+}
+
+func (r *repository) RestoreAd(ctx context.Context, adId int64) (*ads.Ad, error) {
+	return r.updateAd(ctx, adId, true, func(ad *ads.Ad) {
+		ad.DeletedAt = time.Time{}
+	})
+}
+
+func (r *repository) GetTrash(ctx context.Context, authorId int64) ([]*ads.Ad, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	resp := make([]*ads.Ad, 0)
+	for _, ad := range r.adDataById {
+		if ad.Trashed() && ad.AuthorID == authorId {
+			resp = append(resp, cloneAd(ad))
 		}
 	}
+	ads.SortTrash(resp)
 	return resp, nil
 }
 
-func (r *repository) UpdateAdStatus(ctx context.Context, adId int64, newStatus bool) (*ads.Ad, error) {
+func (r *repository) PurgeTrash(ctx context.Context, before time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	r.adDataById[adId].Published = newStatus
-	return r.adDataById[adId], nil
+	for id, ad := range r.adDataById {
+		if ad.Trashed() && ad.DeletedAt.Before(before) {
+			r.saveAll(id)
+			delete(r.adDataById, id)
+			delete(r.revisions, id)
+			delete(r.images, id)
+		}
+	}
+	return nil
 }
 
-func (r *repository) UpdateAdTitleAndText(ctx context.Context, adId int64, newTitle, newText string) (*ads.Ad, error) {
+func (r *repository) AddRevision(ctx context.Context, rev *ads.Revision) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	r.adDataById[adId].Text = newText
-	r.adDataById[adId].Title = newTitle
-	return r.adDataById[adId], nil
+	r.saveRevisions(rev.AdID)
+	rev.Number = int64(len(r.revisions[rev.AdID])) + 1
+	stored := *rev
+	r.revisions[rev.AdID] = append(r.revisions[rev.AdID], &stored)
+	return nil
 }
 
-func (r *repository) DeleteAd(ctx context.Context, adId int64) error {
+func (r *repository) GetRevisions(ctx context.Context, adId int64) ([]*ads.Revision, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	resp := make([]*ads.Revision, 0, len(r.revisions[adId]))
+	for _, rev := range r.revisions[adId] {
+		c := *rev
+		resp = append(resp, &c)
+	}
+	return resp, nil
+}
+
+func (r *repository) GetRevisionsByActor(ctx context.Context, actorId int64) ([]*ads.Revision, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	resp := make([]*ads.Revision, 0)
+	for _, list := range r.revisions {
+		for _, rev := range list {
+			if rev.ActorID == actorId {
+				c := *rev
+				resp = append(resp, &c)
+			}
+		}
+	}
+	ads.SortRevisions(resp)
+	return resp, nil
+}
+
+func (r *repository) EraseActor(ctx context.Context, actorId int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	r.mu.Lock()
-	delete(r.adDataById, adId)
-	r.curIdGenerator--
-	r.mu.Unlock()
+	defer r.mu.Unlock()
+	for adId, list := range r.revisions {
+		if erased, ok := ads.EraseActor(list, actorId); ok {
+			r.saveRevisions(adId)
+			r.revisions[adId] = erased
+		}
+	}
 	return nil
 }
+
+func (r *repository) AddCategory(ctx context.Context, c *ads.Category) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c.ID = r.nextCategoryId
+	r.nextCategoryId++
+	r.saveCategory(c.ID)
+	stored := *c
+	r.categories[c.ID] = &stored
+	return c.ID, nil
+}
+
+func (r *repository) GetCategory(ctx context.Context, id int64) (*ads.Category, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	c, ok := r.categories[id]
+	if !ok {
+		return nil, ErrInvalidCategoryId
+	}
+	resp := *c
+	return &resp, nil
+}
+
+func (r *repository) GetCategories(ctx context.Context) ([]*ads.Category, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return memstore.Categories(r.categories), nil
+}
+
+func (r *repository) UpdateCategory(ctx context.Context, c *ads.Category) (*ads.Category, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.categories[c.ID]; !ok {
+		return nil, ErrInvalidCategoryId
+	}
+	r.saveCategory(c.ID)
+	stored, resp := *c, *c
+	r.categories[c.ID] = &stored
+	return &resp, nil
+}
+
+func (r *repository) DeleteCategory(ctx context.Context, id int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.categories[id]; !ok {
+		return ErrInvalidCategoryId
+	}
+	if memstore.CategoryInUse(r.adDataById, r.categories, id) {
+		return ads.ErrCategoryInUse
+	}
+	r.saveCategory(id)
+	delete(r.categories, id)
+	return nil
+}
+
+func (r *repository) CountAds(ctx context.Context, status ads.Status) (map[int64]int, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return memstore.CountAds(r.adDataById, status), nil
+}
+
+func (r *repository) AddImage(ctx context.Context, img *ads.Image) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	img.ID = r.nextImageId
+	r.nextImageId++
+	img.Position = memstore.NextImagePosition(r.images[img.AdID])
+	r.saveImages(img.AdID)
+	stored := *img
+	r.images[img.AdID] = append(memstore.CloneImages(r.images[img.AdID]), &stored)
+	return img.ID, nil
+}
+
+func (r *repository) GetImages(ctx context.Context, adId int64) ([]*ads.Image, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return memstore.CloneImages(r.images[adId]), nil
+}
+
+func (r *repository) ReorderImages(ctx context.Context, adId int64, order []int64) ([]*ads.Image, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	list := memstore.CloneImages(r.images[adId])
+	if err := ads.ReorderImages(list, order); err != nil {
+		return nil, err
+	}
+	r.saveImages(adId)
+	r.images[adId] = list
+	return memstore.CloneImages(list), nil
+}
+
+func (r *repository) DeleteImage(ctx context.Context, adId, imageId int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	list, ok := memstore.WithoutImage(r.images[adId], imageId)
+	if !ok {
+		return ErrInvalidImageId
+	}
+	r.saveImages(adId)
+	if len(list) == 0 {
+		delete(r.images, adId)
+	} else {
+		r.images[adId] = list
+	}
+	return nil
+}
+
+// Begin returns a view that logs what its writes replace and a function
+// that puts it back, the writes made outside the view are kept.
+func (r *repository) Begin() (ads.Store, func()) {
+	tx := &repository{store: r.store, undo: &undoLog{}}
+	return tx, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		tx.undo.rollback()
+	}
+}
+
+// undoLog holds the steps that undo the writes of a transaction, in the order
+// the writes were made.
+type undoLog struct {
+	steps []func()
+}
+
+func (u *undoLog) add(step func()) {
+	u.steps = append(u.steps, step)
+}
+
+func (u *undoLog) rollback() {
+	for i := len(u.steps) - 1; i >= 0; i-- {
+		u.steps[i]()
+	}
+	u.steps = nil
+}
+
+// The save methods must be called with the lock held, before the write. The
+// stored values are never changed in place, so keeping them is enough.
+
+func (r *repository) saveAd(id int64) {
+	if r.undo == nil {
+		return
+	}
+	ad, ok := r.adDataById[id]
+	r.undo.add(func() {
+		if ok {
+			r.adDataById[id] = ad
+		} else {
+			delete(r.adDataById, id)
+		}
+	})
+}
+
+func (r *repository) saveRevisions(adId int64) {
+	if r.undo == nil {
+		return
+	}
+	// revisions are only appended, capping the list keeps them out of it
+	list, ok := r.revisions[adId]
+	list = list[:len(list):len(list)]
+	r.undo.add(func() {
+		if ok {
+			r.revisions[adId] = list
+		} else {
+			delete(r.revisions, adId)
+		}
+	})
+}
+
+func (r *repository) saveImages(adId int64) {
+	if r.undo == nil {
+		return
+	}
+	list, ok := r.images[adId]
+	r.undo.add(func() {
+		if ok {
+			r.images[adId] = list
+		} else {
+			delete(r.images, adId)
+		}
+	})
+}
+
+// saveAll saves the ad together with its revisions and images.
+func (r *repository) saveAll(adId int64) {
+	r.saveAd(adId)
+	r.saveRevisions(adId)
+	r.saveImages(adId)
+}
+
+func (r *repository) saveCategory(id int64) {
+	if r.undo == nil {
+		return
+	}
+	c, ok := r.categories[id]
+	r.undo.add(func() {
+		if ok {
+			r.categories[id] = c
+		} else {
+			delete(r.categories, id)
+		}
+	})
+}
+
+func today() string {
+	return time.Now().UTC().Format(time.DateOnly)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"github.com/stretchr/testify/assert"
 	"homework10/internal/entities/ads"
+	"homework10/pkg/idgen"
 	"testing"
 )
 
@@ -30,9 +31,9 @@ type GetByTitleBody struct {
 	title string
 }
 
-type UpdateAdStatusBody struct {
-	adId      int64
-	newStatus bool
+type UpdateAdStateBody struct {
+	adId  int64
+	state ads.State
 }
 
 type UpdateTitleBody struct {
@@ -74,11 +75,11 @@ func TestAdRepository(t *testing.T) {
 			Expect: ErrInvalidAdTitle, IsError: true,
 		},
 		{
-			Name: "update ad status ok", Body: UpdateAdStatusBody{newStatus: true, adId: 0},
+			Name: "update ad status ok", Body: UpdateAdStateBody{state: ads.StatePublished, adId: 0},
 			Expect: &ads.Ad{
-				Title:     "test",
-				ID:        0,
-				Published: true,
+				Title: "test",
+				ID:    0,
+				State: ads.StatePublished,
 			},
 		},
 		{
@@ -94,9 +95,9 @@ func TestAdRepository(t *testing.T) {
 		t.Run(tc.Name, func(t *testing.T) {
 			repo := NewForTest(map[int64]*ads.Ad{
 				0: {
-					Title:     "test",
-					ID:        0,
-					Published: false,
+					Title: "test",
+					ID:    0,
+					State: ads.StateDraft,
 				},
 			}, 1)
 			ctx := context.Background()
@@ -122,9 +123,9 @@ func TestAdRepository(t *testing.T) {
 					assert.Len(t, adsArr, 1)
 					assert.Equal(t, adsArr[0].Title, tc.Expect.([]*ads.Ad)[0].Title)
 				}
-			case UpdateAdStatusBody:
-				ad, _ := repo.UpdateAdStatus(ctx, tc.Body.(UpdateAdStatusBody).adId, tc.Body.(UpdateAdStatusBody).newStatus)
-				assert.Equal(t, ad.Published, tc.Expect.(*ads.Ad).Published)
+			case UpdateAdStateBody:
+				ad, _ := repo.UpdateAdState(ctx, tc.Body.(UpdateAdStateBody).adId, tc.Body.(UpdateAdStateBody).state, "")
+				assert.Equal(t, ad.State, tc.Expect.(*ads.Ad).State)
 			case UpdateTitleBody:
 				ad, _ := repo.UpdateAdTitleAndText(ctx, tc.Body.(UpdateTitleBody).adId, tc.Body.(UpdateTitleBody).newTitle,
 					tc.Body.(UpdateTitleBody).newText)
@@ -135,3 +136,20 @@ func TestAdRepository(t *testing.T) {
 		})
 	}
 }
+
+func TestAdRepositoryDoesNotReuseIds(t *testing.T) {
+	ctx := context.Background()
+	repo := New(idgen.NewSequence())
+	for i := 0; i < 3; i++ {
+		_, err := repo.AddAd(ctx, &ads.Ad{Title: "title"})
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, repo.DeleteAd(ctx, 2))
+	assert.NoError(t, repo.DeleteAd(ctx, 1))
+
+	id, err := repo.AddAd(ctx, &ads.Ad{Title: "new"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), id)
+	_, err = repo.GetAdById(ctx, 1)
+	assert.ErrorIs(t, err, ErrInvalidAdId)
+}
@@ -0,0 +1,998 @@
+package filerepo
+
+import (
+	"context"
+	"homework10/internal/adapters/adrepo"
+	"homework10/internal/adapters/internal/memstore"
+	"homework10/internal/adapters/userrepo"
+	"homework10/internal/entities/ads"
+	"homework10/internal/entities/uow"
+	"homework10/internal/entities/user"
+	"homework10/pkg/idgen"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const DefaultSnapshotEvery = 1000
+
+type store struct {
+	mu            *sync.RWMutex
+	dir           string
+	log           *os.File
+	logSize       int64
+	seq           uint64
+	pending       int
+	snapshotEvery int
+
+	adDataById   map[int64]*ads.Ad
+	userDataById map[int64]*user.User
+	revisions    map[int64][]*ads.Revision
+	keysById     map[string]*user.APIKey
+	categories   map[int64]*ads.Category
+	images       map[int64][]*ads.Image
+	// one past the highest id ever stored, so a sequence generator picks up
+	// after it on restart even if that entity is gone
+	nextAdId       int64
+	nextUserId     int64
+	nextCategoryId int64
+	nextImageId    int64
+	adIds          idgen.IDGenerator
+	userIds        idgen.IDGenerator
+}
+
+// txState collects the records of a running transaction together with the
+// state they replaced, so the transaction can be undone.
+type txState struct {
+	records        []record
+	undo           []record
+	nextAdId       int64
+	nextUserId     int64
+	nextCategoryId int64
+	nextImageId    int64
+}
+
+// Repository keeps ads and users in memory and logs every mutation ahead,
+// a snapshot replaces the log every snapshotEvery records.
+type Repository struct {
+	*store
+	// set on the repository handed to a transaction, the store lock is
+	// then held by Do for the whole transaction
+	tx *txState
+}
+
+// Open loads the store from dir, a log corrupted before its tail fails it
+// with ErrCorruptLog and is left for the operator to look at.
+func Open(dir string, snapshotEvery int, adIds, userIds idgen.IDGenerator) (*Repository, error) {
+	err := os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return nil, err
+	}
+	snap, err := readSnapshot(dir)
+	if err != nil {
+		return nil, err
+	}
+	s := &store{
+		mu:             &sync.RWMutex{},
+		dir:            dir,
+		seq:            snap.Seq,
+		snapshotEvery:  snapshotEvery,
+		adDataById:     snap.Ads,
+		userDataById:   snap.Users,
+		revisions:      snap.Revisions,
+		keysById:       snap.Keys,
+		categories:     snap.Categories,
+		images:         snap.Images,
+		nextAdId:       snap.NextAdId,
+		nextUserId:     snap.NextUserId,
+		nextCategoryId: snap.NextCategoryId,
+		nextImageId:    snap.NextImageId,
+		adIds:          adIds,
+		userIds:        userIds,
+	}
+
+	s.log, err = os.OpenFile(filepath.Join(dir, walFile), os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	offset, err := readRecords(s.log, func(rec record) {
+		// records already folded into the snapshot are left over from a
+		// crash between writing the snapshot and resetting the log
+		if rec.Seq <= s.seq {
+			return
+		}
+		s.apply(rec)
+		s.seq = rec.Seq
+		s.pending++
+	})
+	if err == ErrTornRecord {
+		err = s.log.Truncate(offset)
+	}
+	if err == nil {
+		_, err = s.log.Seek(offset, io.SeekStart)
+	}
+	if err != nil {
+		_ = s.log.Close()
+		return nil, err
+	}
+	s.logSize = offset
+	if s.nextCategoryId == 0 {
+		s.nextCategoryId = 1
+	}
+	if s.nextImageId == 0 {
+		s.nextImageId = 1
+	}
+	adIds.Resume(s.nextAdId - 1)
+	userIds.Resume(s.nextUserId - 1)
+	return &Repository{store: s}, nil
+}
+
+func (r *Repository) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	err := r.compact()
+	if closeErr := r.log.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// Snapshot writes the current state to disk and truncates the log.
+func (r *Repository) Snapshot() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.compact()
+}
+
+func (r *Repository) Repositories() uow.Repositories {
+	return uow.NewRepositories(r, r)
+}
+
+// Do holds the store lock while fn runs, so transactions are serializable.
+// Their records reach the log as a single batch on success.
+func (r *Repository) Do(ctx context.Context, fn func(repos uow.Repositories) error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	tx := &txState{nextAdId: r.nextAdId, nextUserId: r.nextUserId, nextCategoryId: r.nextCategoryId,
+		nextImageId: r.nextImageId}
+	txRepo := &Repository{store: r.store, tx: tx}
+
+	err := fn(txRepo.Repositories())
+	if err == nil && len(tx.records) > 0 {
+		err = r.appendLog(record{Op: opBatch, Batch: tx.records})
+	}
+	if err != nil {
+		for i := len(tx.undo) - 1; i >= 0; i-- {
+			r.apply(tx.undo[i])
+		}
+		r.nextAdId, r.nextUserId, r.nextCategoryId = tx.nextAdId, tx.nextUserId, tx.nextCategoryId
+		r.nextImageId = tx.nextImageId
+		return err
+	}
+	r.maybeCompact()
+	return nil
+}
+
+func (r *Repository) lock() {
+	if r.tx == nil {
+		r.mu.Lock()
+	}
+}
+
+func (r *Repository) unlock() {
+	if r.tx == nil {
+		r.mu.Unlock()
+	}
+}
+
+func (r *Repository) rlock() {
+	if r.tx == nil {
+		r.mu.RLock()
+	}
+}
+
+func (r *Repository) runlock() {
+	if r.tx == nil {
+		r.mu.RUnlock()
+	}
+}
+
+func (s *store) compact() error {
+	err := writeSnapshot(s.dir, &snapshot{
+		Seq:            s.seq,
+		NextAdId:       s.nextAdId,
+		NextUserId:     s.nextUserId,
+		NextCategoryId: s.nextCategoryId,
+		NextImageId:    s.nextImageId,
+		Ads:            s.adDataById,
+		Users:          s.userDataById,
+		Revisions:      s.revisions,
+		Keys:           s.keysById,
+		Categories:     s.categories,
+		Images:         s.images,
+	})
+	if err != nil {
+		return err
+	}
+	if err = s.log.Truncate(0); err != nil {
+		return err
+	}
+	if _, err = s.log.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	s.logSize = 0
+	s.pending = 0
+	return s.log.Sync()
+}
+
+func (s *store) maybeCompact() {
+	if s.snapshotEvery > 0 && s.pending >= s.snapshotEvery {
+		// the records are durable already, a failed compaction is
+		// retried on the next write
+		_ = s.compact()
+	}
+}
+
+func (s *store) appendLog(rec record) error {
+	rec.Seq = s.seq + 1
+	data, err := encodeRecord(rec)
+	if err != nil {
+		return err
+	}
+	if _, err = s.log.Write(data); err == nil {
+		err = s.log.Sync()
+	}
+	if err != nil {
+		// drop whatever part of the record made it to the file
+		_ = s.log.Truncate(s.logSize)
+		_, _ = s.log.Seek(s.logSize, io.SeekStart)
+		return err
+	}
+	s.logSize += int64(len(data))
+	s.seq = rec.Seq
+	s.pending++
+	return nil
+}
+
+// write must be called with the lock held. Outside a transaction the record
+// is applied to the maps only after it has reached the disk.
+func (r *Repository) write(rec record) error {
+	if r.tx != nil {
+		r.tx.undo = append(r.tx.undo, r.previous(rec))
+		r.tx.records = append(r.tx.records, rec)
+		r.apply(rec)
+		return nil
+	}
+	err := r.appendLog(rec)
+	if err != nil {
+		return err
+	}
+	r.apply(rec)
+	r.maybeCompact()
+	return nil
+}
+
+// previous returns a record that puts back what rec is about to overwrite.
+func (s *store) previous(rec record) record {
+	switch rec.Op {
+	case opPutAd, opDeleteAd:
+		if ad, ok := s.adDataById[rec.ID]; ok {
+			return record{Op: opPutAd, ID: rec.ID, Ad: ad}
+		}
+		return record{Op: opDeleteAd, ID: rec.ID}
+	case opAddRevision, opPutRevisions:
+		list := s.revisions[rec.ID]
+		return record{Op: opPutRevisions, ID: rec.ID, Revisions: list[:len(list):len(list)]}
+	case opPutKey, opDeleteKey:
+		if key, ok := s.keysById[rec.KeyID]; ok {
+			return record{Op: opPutKey, KeyID: rec.KeyID, Key: key}
+		}
+		return record{Op: opDeleteKey, KeyID: rec.KeyID}
+	case opPutCategory, opDeleteCategory:
+		if c, ok := s.categories[rec.ID]; ok {
+			return record{Op: opPutCategory, ID: rec.ID, Category: c}
+		}
+		return record{Op: opDeleteCategory, ID: rec.ID}
+	case opPutImages:
+		return record{Op: opPutImages, ID: rec.ID, Images: s.images[rec.ID]}
+	default:
+		if u, ok := s.userDataById[rec.ID]; ok {
+			return record{Op: opPutUser, ID: rec.ID, User: u}
+		}
+		return record{Op: opDeleteUser, ID: rec.ID}
+	}
+}
+
+func (s *store) apply(rec record) {
+	switch rec.Op {
+	case opPutAd:
+		s.adDataById[rec.ID] = rec.Ad
+		if rec.ID >= s.nextAdId {
+			s.nextAdId = rec.ID + 1
+		}
+	case opDeleteAd:
+		delete(s.adDataById, rec.ID)
+	case opPutUser:
+		s.userDataById[rec.ID] = rec.User
+		if rec.ID >= s.nextUserId {
+			s.nextUserId = rec.ID + 1
+		}
+	case opDeleteUser:
+		delete(s.userDataById, rec.ID)
+	case opPutKey:
+		s.keysById[rec.KeyID] = rec.Key
+	case opDeleteKey:
+		delete(s.keysById, rec.KeyID)
+	case opPutCategory:
+		s.categories[rec.ID] = rec.Category
+		if rec.ID >= s.nextCategoryId {
+			s.nextCategoryId = rec.ID + 1
+		}
+	case opDeleteCategory:
+		delete(s.categories, rec.ID)
+	case opAddRevision:
+		if int64(len(s.revisions[rec.ID])) < rec.Revision.Number {
+			s.revisions[rec.ID] = append(s.revisions[rec.ID], rec.Revision)
+		}
+	case opPutRevisions:
+		if len(rec.Revisions) == 0 {
+			delete(s.revisions, rec.ID)
+		} else {
+			s.revisions[rec.ID] = rec.Revisions
+		}
+	case opPutImages:
+		if len(rec.Images) == 0 {
+			delete(s.images, rec.ID)
+		} else {
+			s.images[rec.ID] = rec.Images
+		}
+		for _, img := range rec.Images {
+			if img.ID >= s.nextImageId {
+				s.nextImageId = img.ID + 1
+			}
+		}
+	case opBatch:
+		for _, sub := range rec.Batch {
+			s.apply(sub)
+		}
+	}
+}
+
+func cloneAd(ad *ads.Ad) *ads.Ad {
+	c := *ad
+	return &c
+}
+
+func cloneUser(u *user.User) *user.User {
+	c := *u
+	return &c
+}
+
+func (r *Repository) AddAd(ctx context.Context, ad *ads.Ad) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	r.lock()
+	defer r.unlock()
+	stored := cloneAd(ad)
+	stored.ID = r.adIds.NextID()
+	err := r.write(record{Op: opPutAd, ID: stored.ID, Ad: stored})
+	if err != nil {
+		return 0, err
+	}
+	ad.ID = stored.ID
+	return ad.ID, nil
+}
+
+func (r *Repository) GetAdById(ctx context.Context, adId int64) (*ads.Ad, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.rlock()
+	defer r.runlock()
+	ad, ok := r.adDataById[adId]
+	if !ok || ad.Trashed() {
+		return nil, adrepo.ErrInvalidAdId
+	}
+	return cloneAd(ad), nil
+}
+
+func (r *Repository) GetAdsByTitle(ctx context.Context, title string) ([]*ads.Ad, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.rlock()
+	defer r.runlock()
+	resp := make([]*ads.Ad, 0)
+	for _, ad := range r.adDataById {
+		if !ad.Trashed() && strings.HasPrefix(ad.Title, title) {
+			resp = append(resp, cloneAd(ad))
+		}
+	}
+	if len(resp) == 0 {
+		return nil, adrepo.ErrInvalidAdTitle
+	}
+	sortAds(resp)
+	return resp, nil
+}
+
+func (r *Repository) GetAll(ctx context.Context, filters ads.Filters) ([]*ads.Ad, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.rlock()
+	defer r.runlock()
+	resp := make([]*ads.Ad, 0)
+	for _, ad := range r.adDataById {
+		if filters.Match(ad) {
+			resp = append(resp, cloneAd(ad))
+		}
+	}
+	return filters.Page(resp)
+}
+
+// updateAd changes an ad that is in the trash or out of it, as trashed says.
+func (r *Repository) updateAd(ctx context.Context, adId int64, trashed bool, change func(ad *ads.Ad)) (*ads.Ad, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.lock()
+	defer r.unlock()
+	ad, ok := r.adDataById[adId]
+	if !ok || ad.Trashed() != trashed {
+		return nil, adrepo.ErrInvalidAdId
+	}
+	updated := cloneAd(ad)
+	change(updated)
+	updated.Version++
+	err := r.write(record{Op: opPutAd, ID: adId, Ad: updated})
+	if err != nil {
+		return nil, err
+	}
+	return cloneAd(updated), nil
+}
+
+func (r *Repository) UpdateAdState(ctx context.Context, adId int64, state ads.State, rejectionReason string) (*ads.Ad, error) {
+	return r.updateAd(ctx, adId, false, func(ad *ads.Ad) {
+		ad.SetState(state, rejectionReason)
+		ad.UpdateDate = today()
+	})
+}
+
+func (r *Repository) UpdateAdTitleAndText(ctx context.Context, adId int64, newTitle, newText string) (*ads.Ad, error) {
+	return r.updateAd(ctx, adId, false, func(ad *ads.Ad) {
+		ad.Title = newTitle
+		ad.Text = newText
+		ad.UpdateDate = today()
+	})
+}
+
+func (r *Repository) UpdateAdPublication(ctx context.Context, adId int64, state ads.State, publishAt, expiresAt time.Time) (*ads.Ad, error) {
+	return r.updateAd(ctx, adId, false, func(ad *ads.Ad) {
+		ad.SetState(state, "")
+		ad.PublishAt = publishAt
+		ad.ExpiresAt = expiresAt
+		ad.UpdateDate = today()
+	})
+}
+
+func (r *Repository) GetDueAds(ctx context.Context, now time.Time) ([]*ads.Ad, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.rlock()
+	defer r.runlock()
+	resp := make([]*ads.Ad, 0)
+	for _, ad := range r.adDataById {
+		if ad.Due(now) {
+			resp = append(resp, cloneAd(ad))
+		}
+	}
+	sortAds(resp)
+	return resp, nil
+}
+
+func (r *Repository) DeleteAd(ctx context.Context, adId int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.lock()
+	defer r.unlock()
+	if _, ok := r.adDataById[adId]; !ok {
+		return nil
+	}
+	return r.writeBatch(r.deleteAdRecords(adId))
+}
+
+// deleteAdRecords returns the records that remove an ad with its revisions
+// and images.
+func (s *store) deleteAdRecords(adId int64) []record {
+	batch := []record{{Op: opDeleteAd, ID: adId}}
+	if _, ok := s.revisions[adId]; ok {
+		batch = append(batch, record{Op: opPutRevisions, ID: adId})
+	}
+	if _, ok := s.images[adId]; ok {
+		batch = append(batch, record{Op: opPutImages, ID: adId})
+	}
+	return batch
+}
+
+func (r *Repository) DeleteAdsByAuthor(ctx context.Context, authorId int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.lock()
+	defer r.unlock()
+	var batch []record
+	for id, ad := range r.adDataById {
+		if ad.AuthorID == authorId {
+			batch = append(batch, r.deleteAdRecords(id)...)
+		}
+	}
+	return r.writeBatch(batch)
+}
+
+// writeBatch writes several records at once, must be called with the lock
+// held.
+func (r *Repository) writeBatch(batch []record) error {
+	if len(batch) == 0 {
+		return nil
+	}
+	if r.tx != nil {
+		for _, rec := range batch {
+			_ = r.write(rec)
+		}
+		return nil
+	}
+	return r.write(record{Op: opBatch, Batch: batch})
+}
+
+func (r *Repository) TrashAd(ctx context.Context, adId int64, at time.Time) (*ads.Ad, error) {
+	return r.updateAd(ctx, adId, false, func(ad *ads.Ad) {
+		ad.DeletedAt = at
+	})
+}
+
+func (r *Repository) RestoreAd(ctx context.Context, adId int64) (*ads.Ad, error) {
+	return r.updateAd(ctx, adId, true, func(ad *ads.Ad) {
+		ad.DeletedAt = time.Time{}
+	})
+}
+
+func (r *Repository) GetTrash(ctx context.Context, authorId int64) ([]*ads.Ad, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.rlock()
+	defer r.runlock()
+	resp := make([]*ads.Ad, 0)
+	for _, ad := range r.adDataById {
+		if ad.Trashed() && ad.AuthorID == authorId {
+			resp = append(resp, cloneAd(ad))
+		}
+	}
+	ads.SortTrash(resp)
+	return resp, nil
+}
+
+func (r *Repository) PurgeTrash(ctx context.Context, before time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.lock()
+	defer r.unlock()
+	var batch []record
+	for id, ad := range r.adDataById {
+		if ad.Trashed() && ad.DeletedAt.Before(before) {
+			batch = append(batch, r.deleteAdRecords(id)...)
+		}
+	}
+	return r.writeBatch(batch)
+}
+
+func (r *Repository) AddRevision(ctx context.Context, rev *ads.Revision) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.lock()
+	defer r.unlock()
+	stored := *rev
+	stored.Number = int64(len(r.revisions[rev.AdID])) + 1
+	err := r.write(record{Op: opAddRevision, ID: rev.AdID, Revision: &stored})
+	if err != nil {
+		return err
+	}
+	rev.Number = stored.Number
+	return nil
+}
+
+func (r *Repository) GetRevisions(ctx context.Context, adId int64) ([]*ads.Revision, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.rlock()
+	defer r.runlock()
+	resp := make([]*ads.Revision, 0, len(r.revisions[adId]))
+	for _, rev := range r.revisions[adId] {
+		c := *rev
+		resp = append(resp, &c)
+	}
+	return resp, nil
+}
+
+func (r *Repository) GetRevisionsByActor(ctx context.Context, actorId int64) ([]*ads.Revision, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.rlock()
+	defer r.runlock()
+	resp := make([]*ads.Revision, 0)
+	for _, list := range r.revisions {
+		for _, rev := range list {
+			if rev.ActorID == actorId {
+				c := *rev
+				resp = append(resp, &c)
+			}
+		}
+	}
+	ads.SortRevisions(resp)
+	return resp, nil
+}
+
+func (r *Repository) EraseActor(ctx context.Context, actorId int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.lock()
+	defer r.unlock()
+	var batch []record
+	for adId, list := range r.revisions {
+		if erased, ok := ads.EraseActor(list, actorId); ok {
+			batch = append(batch, record{Op: opPutRevisions, ID: adId, Revisions: erased})
+		}
+	}
+	return r.writeBatch(batch)
+}
+
+func (r *Repository) AddCategory(ctx context.Context, c *ads.Category) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	r.lock()
+	defer r.unlock()
+	stored := *c
+	stored.ID = r.nextCategoryId
+	err := r.write(record{Op: opPutCategory, ID: stored.ID, Category: &stored})
+	if err != nil {
+		return 0, err
+	}
+	c.ID = stored.ID
+	return c.ID, nil
+}
+
+func (r *Repository) GetCategory(ctx context.Context, id int64) (*ads.Category, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.rlock()
+	defer r.runlock()
+	c, ok := r.categories[id]
+	if !ok {
+		return nil, adrepo.ErrInvalidCategoryId
+	}
+	resp := *c
+	return &resp, nil
+}
+
+func (r *Repository) GetCategories(ctx context.Context) ([]*ads.Category, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.rlock()
+	defer r.runlock()
+	return memstore.Categories(r.categories), nil
+}
+
+func (r *Repository) UpdateCategory(ctx context.Context, c *ads.Category) (*ads.Category, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.lock()
+	defer r.unlock()
+	if _, ok := r.categories[c.ID]; !ok {
+		return nil, adrepo.ErrInvalidCategoryId
+	}
+	stored, resp := *c, *c
+	err := r.write(record{Op: opPutCategory, ID: c.ID, Category: &stored})
+	if err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+func (r *Repository) DeleteCategory(ctx context.Context, id int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.lock()
+	defer r.unlock()
+	if _, ok := r.categories[id]; !ok {
+		return adrepo.ErrInvalidCategoryId
+	}
+	if memstore.CategoryInUse(r.adDataById, r.categories, id) {
+		return ads.ErrCategoryInUse
+	}
+	return r.write(record{Op: opDeleteCategory, ID: id})
+}
+
+func (r *Repository) CountAds(ctx context.Context, status ads.Status) (map[int64]int, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.rlock()
+	defer r.runlock()
+	return memstore.CountAds(r.adDataById, status), nil
+}
+
+func (r *Repository) AddImage(ctx context.Context, img *ads.Image) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	r.lock()
+	defer r.unlock()
+	stored := *img
+	stored.ID = r.nextImageId
+	stored.Position = memstore.NextImagePosition(r.images[img.AdID])
+	list := append(memstore.CloneImages(r.images[img.AdID]), &stored)
+	err := r.write(record{Op: opPutImages, ID: img.AdID, Images: list})
+	if err != nil {
+		return 0, err
+	}
+	img.ID, img.Position = stored.ID, stored.Position
+	return img.ID, nil
+}
+
+func (r *Repository) GetImages(ctx context.Context, adId int64) ([]*ads.Image, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.rlock()
+	defer r.runlock()
+	return memstore.CloneImages(r.images[adId]), nil
+}
+
+func (r *Repository) ReorderImages(ctx context.Context, adId int64, order []int64) ([]*ads.Image, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.lock()
+	defer r.unlock()
+	list := memstore.CloneImages(r.images[adId])
+	if err := ads.ReorderImages(list, order); err != nil {
+		return nil, err
+	}
+	err := r.write(record{Op: opPutImages, ID: adId, Images: list})
+	if err != nil {
+		return nil, err
+	}
+	return memstore.CloneImages(list), nil
+}
+
+func (r *Repository) DeleteImage(ctx context.Context, adId, imageId int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.lock()
+	defer r.unlock()
+	list, ok := memstore.WithoutImage(r.images[adId], imageId)
+	if !ok {
+		return adrepo.ErrInvalidImageId
+	}
+	return r.write(record{Op: opPutImages, ID: adId, Images: list})
+}
+
+func (r *Repository) CreateUser(ctx context.Context, u *user.User) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	r.lock()
+	defer r.unlock()
+	if userrepo.EmailTaken(r.userDataById, u.Email) {
+		return 0, user.ErrEmailTaken
+	}
+	stored := cloneUser(u)
+	stored.Id = r.userIds.NextID()
+	err := r.write(record{Op: opPutUser, ID: stored.Id, User: stored})
+	if err != nil {
+		return 0, err
+	}
+	u.Id = stored.Id
+	return u.Id, nil
+}
+
+func (r *Repository) updateUser(ctx context.Context, id int64, change func(u *user.User)) (*user.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.lock()
+	defer r.unlock()
+	u, ok := r.userDataById[id]
+	if !ok {
+		return nil, userrepo.ErrInvalidUserId
+	}
+	updated := cloneUser(u)
+	change(updated)
+	err := r.write(record{Op: opPutUser, ID: id, User: updated})
+	if err != nil {
+		return nil, err
+	}
+	return cloneUser(updated), nil
+}
+
+func (r *Repository) UpdateNick(ctx context.Context, id int64, nick string) (*user.User, error) {
+	return r.updateUser(ctx, id, func(u *user.User) {
+		u.Nickname = nick
+	})
+}
+
+func (r *Repository) UpdatePassword(ctx context.Context, id int64, pass string) (*user.User, error) {
+	return r.updateUser(ctx, id, func(u *user.User) {
+		u.Password = pass
+	})
+}
+
+func (r *Repository) UpdateRole(ctx context.Context, id int64, role user.Role) (*user.User, error) {
+	return r.updateUser(ctx, id, func(u *user.User) {
+		u.Role = role
+	})
+}
+
+func (r *Repository) UpdateVerified(ctx context.Context, id int64, verified bool) (*user.User, error) {
+	return r.updateUser(ctx, id, func(u *user.User) {
+		u.Verified = verified
+	})
+}
+
+func (r *Repository) UpdateProfile(ctx context.Context, id int64, profile user.Profile) (*user.User, error) {
+	return r.updateUser(ctx, id, func(u *user.User) {
+		u.Profile = profile
+	})
+}
+
+func (r *Repository) RevokeSessions(ctx context.Context, id int64) (*user.User, error) {
+	return r.updateUser(ctx, id, func(u *user.User) {
+		u.SessionVersion++
+	})
+}
+
+func (r *Repository) GetUserByEmail(ctx context.Context, email string) (*user.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.rlock()
+	defer r.runlock()
+	u, ok := userrepo.FindByEmail(r.userDataById, email)
+	if !ok {
+		return nil, userrepo.ErrInvalidUserId
+	}
+	return cloneUser(u), nil
+}
+
+func (r *Repository) SearchUsers(ctx context.Context, search user.Search) ([]*user.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.rlock()
+	defer r.runlock()
+	return userrepo.SearchUsers(r.userDataById, search)
+}
+
+func (r *Repository) GetUser(ctx context.Context, id int64) (*user.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.rlock()
+	defer r.runlock()
+	u, ok := r.userDataById[id]
+	if !ok {
+		return nil, userrepo.ErrInvalidUserId
+	}
+	return cloneUser(u), nil
+}
+
+func (r *Repository) DeleteUser(ctx context.Context, id int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.lock()
+	defer r.unlock()
+	if _, ok := r.userDataById[id]; !ok {
+		return nil
+	}
+	batch := []record{{Op: opDeleteUser, ID: id}}
+	for keyId, key := range r.keysById {
+		if key.UserID == id {
+			batch = append(batch, record{Op: opDeleteKey, KeyID: keyId})
+		}
+	}
+	return r.writeBatch(batch)
+}
+
+func (r *Repository) AddAPIKey(ctx context.Context, key *user.APIKey) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.lock()
+	defer r.unlock()
+	return r.write(record{Op: opPutKey, KeyID: key.ID, Key: user.CloneAPIKey(key)})
+}
+
+func (r *Repository) GetAPIKey(ctx context.Context, id string) (*user.APIKey, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.rlock()
+	defer r.runlock()
+	key, ok := r.keysById[id]
+	if !ok {
+		return nil, userrepo.ErrInvalidAPIKeyId
+	}
+	return user.CloneAPIKey(key), nil
+}
+
+func (r *Repository) GetAPIKeys(ctx context.Context, userId int64) ([]*user.APIKey, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.rlock()
+	defer r.runlock()
+	resp := make([]*user.APIKey, 0)
+	for _, key := range r.keysById {
+		if key.UserID == userId {
+			resp = append(resp, user.CloneAPIKey(key))
+		}
+	}
+	user.SortAPIKeys(resp)
+	return resp, nil
+}
+
+func (r *Repository) DeleteAPIKey(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.lock()
+	defer r.unlock()
+	if _, ok := r.keysById[id]; !ok {
+		return nil
+	}
+	return r.write(record{Op: opDeleteKey, KeyID: id})
+}
+
+func (r *Repository) TouchAPIKey(ctx context.Context, id string, at time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.lock()
+	defer r.unlock()
+	key, ok := r.keysById[id]
+	if !ok {
+		return userrepo.ErrInvalidAPIKeyId
+	}
+	touched := user.CloneAPIKey(key)
+	touched.LastUsedAt = at
+	return r.write(record{Op: opPutKey, KeyID: id, Key: touched})
+}
+
+func sortAds(list []*ads.Ad) {
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].ID < list[j].ID
+	})
+}
+
+func today() string {
+	return time.Now().UTC().Format(time.DateOnly)
+}
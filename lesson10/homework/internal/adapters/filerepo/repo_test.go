@@ -0,0 +1,571 @@
+package filerepo
+
+import (
+	"context"
+	"encoding/binary"
+	"github.com/stretchr/testify/assert"
+	"hash/crc32"
+	"homework10/internal/adapters/adrepo"
+	"homework10/internal/adapters/userrepo"
+	"homework10/internal/entities/ads"
+	"homework10/internal/entities/uow"
+	"homework10/internal/entities/user"
+	"homework10/pkg/idgen"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type FileRepoTest struct {
+	Name    string
+	Body    any
+	Expect  any
+	IsError bool
+}
+
+type AddBody struct {
+	Ad *ads.Ad
+}
+
+type DeleteAdBody struct {
+	adId int64
+}
+
+type GetByIdBody struct {
+	adId int64
+}
+
+type GetByTitleBody struct {
+	title string
+}
+
+type UpdateAdStateBody struct {
+	adId  int64
+	state ads.State
+}
+
+type UpdateTitleBody struct {
+	adId     int64
+	newTitle string
+	newText  string
+}
+
+type CreateUserBody struct {
+	user *user.User
+}
+
+type DeleteUserBody struct {
+	userId int64
+}
+
+type UpdateNickBody struct {
+	userId  int64
+	newNick string
+}
+
+type UpdatePasswordBody struct {
+	userId  int64
+	newPass string
+}
+
+func newTestRepo(t *testing.T, dir string) *Repository {
+	repo, err := Open(dir, DefaultSnapshotEvery, idgen.NewSequence(), idgen.NewSequence())
+	assert.NoError(t, err)
+	ctx := context.Background()
+	_, err = repo.AddAd(ctx, &ads.Ad{Title: "test"})
+	assert.NoError(t, err)
+	_, err = repo.CreateUser(ctx, &user.User{Nickname: "nickname", Password: "pass", Email: "email"})
+	assert.NoError(t, err)
+	return repo
+}
+
+func TestFileRepository(t *testing.T) {
+	tests := []FileRepoTest{
+		{
+			Name: "add ad ok", Body: AddBody{Ad: &ads.Ad{Title: "title"}},
+			Expect: &ads.Ad{ID: 1, Title: "title"},
+		},
+		{
+			Name: "delete ad ok", Body: DeleteAdBody{adId: 0},
+			Expect: adrepo.ErrInvalidAdId, IsError: true,
+		},
+		{
+			Name: "get ad by id error", Body: GetByIdBody{adId: 2},
+			Expect: adrepo.ErrInvalidAdId, IsError: true,
+		},
+		{
+			Name: "get ads by title error", Body: GetByTitleBody{title: "kek"},
+			Expect: adrepo.ErrInvalidAdTitle, IsError: true,
+		},
+		{
+			Name: "update ad status ok", Body: UpdateAdStateBody{adId: 0, state: ads.StatePublished},
+			Expect: &ads.Ad{ID: 0, Title: "test", State: ads.StatePublished},
+		},
+		{
+			Name: "update ad status error", Body: UpdateAdStateBody{adId: 3, state: ads.StatePublished},
+			Expect: adrepo.ErrInvalidAdId, IsError: true,
+		},
+		{
+			Name: "update ad title ok", Body: UpdateTitleBody{adId: 0, newTitle: "new title", newText: "new text"},
+			Expect: &ads.Ad{ID: 0, Title: "new title", Text: "new text"},
+		},
+		{
+			Name: "create user ok", Body: CreateUserBody{user: &user.User{Nickname: "nick1", Password: "pass1"}},
+			Expect: &user.User{Id: 1, Nickname: "nick1", Password: "pass1"},
+		},
+		{
+			Name: "delete user ok", Body: DeleteUserBody{userId: 0},
+			Expect: userrepo.ErrInvalidUserId, IsError: true,
+		},
+		{
+			Name: "update nickname ok", Body: UpdateNickBody{userId: 0, newNick: "new nick"},
+			Expect: &user.User{Id: 0, Nickname: "new nick", Password: "pass", Email: "email"},
+		},
+		{
+			Name: "update password error", Body: UpdatePasswordBody{userId: 7, newPass: "new pass"},
+			Expect: userrepo.ErrInvalidUserId, IsError: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.Name, func(t *testing.T) {
+			dir := t.TempDir()
+			repo := newTestRepo(t, dir)
+			ctx := context.Background()
+			var (
+				gotAd   *ads.Ad
+				gotUser *user.User
+				err     error
+			)
+			switch body := tc.Body.(type) {
+			case AddBody:
+				var id int64
+				id, err = repo.AddAd(ctx, body.Ad)
+				assert.NoError(t, err)
+				gotAd, err = repo.GetAdById(ctx, id)
+			case DeleteAdBody:
+				assert.NoError(t, repo.DeleteAd(ctx, body.adId))
+				gotAd, err = repo.GetAdById(ctx, body.adId)
+			case GetByIdBody:
+				gotAd, err = repo.GetAdById(ctx, body.adId)
+			case GetByTitleBody:
+				_, err = repo.GetAdsByTitle(ctx, body.title)
+			case UpdateAdStateBody:
+				gotAd, err = repo.UpdateAdState(ctx, body.adId, body.state, "")
+			case UpdateTitleBody:
+				gotAd, err = repo.UpdateAdTitleAndText(ctx, body.adId, body.newTitle, body.newText)
+			case CreateUserBody:
+				var id int64
+				id, err = repo.CreateUser(ctx, body.user)
+				assert.NoError(t, err)
+				gotUser, err = repo.GetUser(ctx, id)
+			case DeleteUserBody:
+				assert.NoError(t, repo.DeleteUser(ctx, body.userId))
+				gotUser, err = repo.GetUser(ctx, body.userId)
+			case UpdateNickBody:
+				gotUser, err = repo.UpdateNick(ctx, body.userId, body.newNick)
+			case UpdatePasswordBody:
+				gotUser, err = repo.UpdatePassword(ctx, body.userId, body.newPass)
+			}
+			if tc.IsError {
+				assert.ErrorIs(t, err, tc.Expect.(error))
+				return
+			}
+			assert.NoError(t, err)
+
+			// everything that was acknowledged must be there after a restart
+			assert.NoError(t, repo.log.Close())
+			reopened, err := Open(dir, DefaultSnapshotEvery, idgen.NewSequence(), idgen.NewSequence())
+			assert.NoError(t, err)
+			defer reopened.Close()
+			switch expect := tc.Expect.(type) {
+			case *ads.Ad:
+				stored, err := reopened.GetAdById(ctx, expect.ID)
+				assert.NoError(t, err)
+				assert.Equal(t, expect.Title, gotAd.Title)
+				assert.Equal(t, expect.Title, stored.Title)
+				assert.Equal(t, expect.Text, stored.Text)
+				assert.Equal(t, expect.State, stored.State)
+			case *user.User:
+				stored, err := reopened.GetUser(ctx, expect.Id)
+				assert.NoError(t, err)
+				assert.Equal(t, expect.Nickname, gotUser.Nickname)
+				assert.Equal(t, expect, stored)
+			}
+		})
+	}
+}
+
+func TestFileRepositoryTornRecord(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+	repo := newTestRepo(t, dir)
+	_, err := repo.AddAd(ctx, &ads.Ad{Title: "second"})
+	assert.NoError(t, err)
+	assert.NoError(t, repo.log.Close())
+
+	// cut the last record in half as if the process died while writing it
+	path := filepath.Join(dir, walFile)
+	info, err := os.Stat(path)
+	assert.NoError(t, err)
+	assert.NoError(t, os.Truncate(path, info.Size()-5))
+
+	repo, err = Open(dir, DefaultSnapshotEvery, idgen.NewSequence(), idgen.NewSequence())
+	assert.NoError(t, err)
+	_, err = repo.GetAdById(ctx, 0)
+	assert.NoError(t, err)
+	_, err = repo.GetAdById(ctx, 1)
+	assert.ErrorIs(t, err, adrepo.ErrInvalidAdId)
+
+	// the log is usable again after the torn tail is dropped
+	id, err := repo.AddAd(ctx, &ads.Ad{Title: "third"})
+	assert.NoError(t, err)
+	assert.NoError(t, repo.log.Close())
+
+	repo, err = Open(dir, DefaultSnapshotEvery, idgen.NewSequence(), idgen.NewSequence())
+	assert.NoError(t, err)
+	defer repo.Close()
+	ad, err := repo.GetAdById(ctx, id)
+	assert.NoError(t, err)
+	assert.Equal(t, "third", ad.Title)
+}
+
+func TestFileRepositoryCorruptedRecord(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+	repo := newTestRepo(t, dir)
+	assert.NoError(t, repo.log.Close())
+
+	path := filepath.Join(dir, walFile)
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	data[len(data)-2] ^= 0xff
+	assert.NoError(t, os.WriteFile(path, data, 0o644))
+
+	repo, err = Open(dir, DefaultSnapshotEvery, idgen.NewSequence(), idgen.NewSequence())
+	assert.NoError(t, err)
+	defer repo.Close()
+	_, err = repo.GetAdById(ctx, 0)
+	assert.NoError(t, err)
+	_, err = repo.GetUser(ctx, 0)
+	assert.ErrorIs(t, err, userrepo.ErrInvalidUserId)
+}
+
+func TestFileRepositoryCorruptedMiddleRecord(t *testing.T) {
+	dir := t.TempDir()
+	repo := newTestRepo(t, dir)
+	assert.NoError(t, repo.log.Close())
+
+	// flip a byte of the first record, the user record after it is intact
+	path := filepath.Join(dir, walFile)
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	data[headerSize+2] ^= 0xff
+	assert.NoError(t, os.WriteFile(path, data, 0o644))
+
+	_, err = Open(dir, DefaultSnapshotEvery, idgen.NewSequence(), idgen.NewSequence())
+	assert.ErrorIs(t, err, ErrCorruptLog)
+	kept, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, data, kept)
+
+	// a garbage length with more of the log after it is no torn tail either
+	binary.LittleEndian.PutUint32(data[0:4], maxRecordSize+1)
+	data = append(data, make([]byte, maxRecordSize+1)...)
+	assert.NoError(t, os.WriteFile(path, data, 0o644))
+	_, err = Open(dir, DefaultSnapshotEvery, idgen.NewSequence(), idgen.NewSequence())
+	assert.ErrorIs(t, err, ErrCorruptLog)
+}
+
+func TestFileRepositoryLegacyStates(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	// a snapshot and a log written before the ads had states
+	snap := `{"seq":1,"next_ad_id":1,"next_user_id":0,"users":{},
+		"ads":{"0":{"ID":0,"Title":"up","Published":true,"Version":2}},
+		"revisions":{"0":[{"AdID":0,"Number":1,"Title":"up","Published":false},
+			{"AdID":0,"Number":2,"Title":"up","Published":true}]}}`
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, snapshotFile), []byte(snap), 0o644))
+	payload := []byte(`{"seq":2,"op":"batch","batch":[
+		{"op":"put_ad","id":1,"ad":{"ID":1,"Title":"down","Published":false,"Version":1}},
+		{"op":"add_revision","id":1,"revision":{"AdID":1,"Number":1,"Title":"down","Published":false}}]}`)
+	header := make([]byte, headerSize)
+	binary.LittleEndian.PutUint32(header[0:4], uint32(len(payload)))
+	binary.LittleEndian.PutUint32(header[4:8], crc32.ChecksumIEEE(payload))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, walFile), append(header, payload...), 0o644))
+
+	repo, err := Open(dir, DefaultSnapshotEvery, idgen.NewSequence(), idgen.NewSequence())
+	assert.NoError(t, err)
+	defer repo.Close()
+	up, err := repo.GetAdById(ctx, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, ads.StatePublished, up.State)
+	down, err := repo.GetAdById(ctx, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, ads.StateDraft, down.State)
+
+	revisions, err := repo.GetRevisions(ctx, 0)
+	assert.NoError(t, err)
+	if assert.Len(t, revisions, 2) {
+		assert.Equal(t, ads.StateDraft, revisions[0].State)
+		assert.Equal(t, ads.StatePublished, revisions[1].State)
+	}
+	revisions, err = repo.GetRevisions(ctx, 1)
+	assert.NoError(t, err)
+	if assert.Len(t, revisions, 1) {
+		assert.Equal(t, ads.StateDraft, revisions[0].State)
+	}
+}
+
+func TestFileRepositorySnapshot(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+	repo, err := Open(dir, 3, idgen.NewSequence(), idgen.NewSequence())
+	assert.NoError(t, err)
+	for i := 0; i < 4; i++ {
+		_, err = repo.AddAd(ctx, &ads.Ad{Title: "title"})
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, repo.DeleteAd(ctx, 1))
+
+	// three records went to the snapshot, two are still in the log
+	_, err = os.Stat(filepath.Join(dir, snapshotFile))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, repo.pending)
+	assert.NoError(t, repo.log.Close())
+
+	repo, err = Open(dir, 3, idgen.NewSequence(), idgen.NewSequence())
+	assert.NoError(t, err)
+	all, err := repo.GetAll(ctx, ads.Filters{Status: ads.Unpublished})
+	assert.NoError(t, err)
+	assert.Len(t, all, 3)
+
+	// deleted ids are not handed out again
+	id, err := repo.AddAd(ctx, &ads.Ad{Title: "title"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4), id)
+	assert.NoError(t, repo.Close())
+
+	info, err := os.Stat(filepath.Join(dir, walFile))
+	assert.NoError(t, err)
+	assert.Zero(t, info.Size())
+}
+
+func TestFileRepositoryDo(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+	repo := newTestRepo(t, dir)
+
+	err := repo.Do(ctx, func(repos uow.Repositories) error {
+		_, err := repos.Ads.AddAd(ctx, &ads.Ad{Title: "rolled back"})
+		assert.NoError(t, err)
+		assert.NoError(t, repos.Users.DeleteUser(ctx, 0))
+		return userrepo.ErrInvalidUserId
+	})
+	assert.ErrorIs(t, err, userrepo.ErrInvalidUserId)
+	_, err = repo.GetUser(ctx, 0)
+	assert.NoError(t, err)
+	_, err = repo.GetAdById(ctx, 1)
+	assert.ErrorIs(t, err, adrepo.ErrInvalidAdId)
+
+	err = repo.Do(ctx, func(repos uow.Repositories) error {
+		err := repos.Ads.DeleteAdsByAuthor(ctx, 0)
+		if err != nil {
+			return err
+		}
+		return repos.Users.DeleteUser(ctx, 0)
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, repo.log.Close())
+
+	// the committed batch is replayed as a whole
+	repo, err = Open(dir, DefaultSnapshotEvery, idgen.NewSequence(), idgen.NewSequence())
+	assert.NoError(t, err)
+	defer repo.Close()
+	_, err = repo.GetUser(ctx, 0)
+	assert.ErrorIs(t, err, userrepo.ErrInvalidUserId)
+	_, err = repo.GetAdById(ctx, 0)
+	assert.ErrorIs(t, err, adrepo.ErrInvalidAdId)
+}
+
+func TestFileRepositoryTrash(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+	repo := newTestRepo(t, dir)
+	deletedAt := time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	_, err := repo.AddAd(ctx, &ads.Ad{Title: "second"})
+	assert.NoError(t, err)
+	_, err = repo.TrashAd(ctx, 0, deletedAt)
+	assert.NoError(t, err)
+	_, err = repo.TrashAd(ctx, 1, deletedAt.Add(time.Hour))
+	assert.NoError(t, err)
+	_, err = repo.RestoreAd(ctx, 1)
+	assert.NoError(t, err)
+	_, err = repo.UpdateAdState(ctx, 0, ads.StatePublished, "")
+	assert.ErrorIs(t, err, adrepo.ErrInvalidAdId)
+	assert.NoError(t, repo.log.Close())
+
+	// the trash survives a restart
+	repo, err = Open(dir, DefaultSnapshotEvery, idgen.NewSequence(), idgen.NewSequence())
+	assert.NoError(t, err)
+	defer repo.Close()
+	_, err = repo.GetAdById(ctx, 0)
+	assert.ErrorIs(t, err, adrepo.ErrInvalidAdId)
+	_, err = repo.GetAdById(ctx, 1)
+	assert.NoError(t, err)
+	trash, err := repo.GetTrash(ctx, 0)
+	assert.NoError(t, err)
+	assert.Len(t, trash, 1)
+	assert.True(t, trash[0].DeletedAt.Equal(deletedAt))
+
+	assert.NoError(t, repo.PurgeTrash(ctx, deletedAt.Add(time.Minute)))
+	_, err = repo.RestoreAd(ctx, 0)
+	assert.ErrorIs(t, err, adrepo.ErrInvalidAdId)
+}
+
+func TestFileRepositoryRevisions(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+	repo := newTestRepo(t, dir)
+	at := time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	rev := &ads.Revision{AdID: 0, ActorID: 0, CreatedAt: at, Title: "test"}
+	assert.NoError(t, repo.AddRevision(ctx, rev))
+	assert.Equal(t, int64(1), rev.Number)
+	err := repo.Do(ctx, func(repos uow.Repositories) error {
+		assert.NoError(t, repos.Revisions.AddRevision(ctx, &ads.Revision{AdID: 0, CreatedAt: at, Title: "rolled back"}))
+		assert.NoError(t, repos.Revisions.EraseActor(ctx, 0))
+		return adrepo.ErrInvalidAdId
+	})
+	assert.ErrorIs(t, err, adrepo.ErrInvalidAdId)
+	rev = &ads.Revision{AdID: 0, ActorID: 0, CreatedAt: at, Title: "second",
+		Changes: []ads.Change{{Field: ads.FieldTitle, Old: "test", New: "second"}}}
+	assert.NoError(t, repo.AddRevision(ctx, rev))
+	assert.Equal(t, int64(2), rev.Number)
+	assert.NoError(t, repo.log.Close())
+
+	repo, err = Open(dir, DefaultSnapshotEvery, idgen.NewSequence(), idgen.NewSequence())
+	assert.NoError(t, err)
+	defer repo.Close()
+	revisions, err := repo.GetRevisions(ctx, 0)
+	assert.NoError(t, err)
+	assert.Len(t, revisions, 2)
+	assert.Equal(t, "second", revisions[1].Title)
+	assert.Equal(t, rev.Changes, revisions[1].Changes)
+	assert.Equal(t, int64(0), revisions[0].ActorID)
+
+	// an erased actor stays erased after a snapshot
+	assert.NoError(t, repo.EraseActor(ctx, 0))
+	assert.NoError(t, repo.Snapshot())
+	revisions, err = repo.GetRevisionsByActor(ctx, ads.ErasedActor)
+	assert.NoError(t, err)
+	assert.Len(t, revisions, 2)
+
+	// the revisions go away with the ad, a snapshot does not bring them back
+	assert.NoError(t, repo.DeleteAd(ctx, 0))
+	assert.NoError(t, repo.Snapshot())
+	revisions, err = repo.GetRevisions(ctx, 0)
+	assert.NoError(t, err)
+	assert.Empty(t, revisions)
+}
+
+func TestFileRepositoryAPIKeys(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+	repo := newTestRepo(t, dir)
+	at := time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	key := &user.APIKey{ID: "key", UserID: 0, Name: "importer", Hash: "hash", Scopes: []user.Scope{user.ScopeAdsRead},
+		CreatedAt: at}
+	assert.NoError(t, repo.AddAPIKey(ctx, key))
+	err := repo.Do(ctx, func(repos uow.Repositories) error {
+		assert.NoError(t, repos.Users.DeleteAPIKey(ctx, "key"))
+		return userrepo.ErrInvalidAPIKeyId
+	})
+	assert.ErrorIs(t, err, userrepo.ErrInvalidAPIKeyId)
+	assert.NoError(t, repo.TouchAPIKey(ctx, "key", at.Add(time.Hour)))
+	assert.NoError(t, repo.log.Close())
+
+	// the key and its last use survive a restart
+	repo, err = Open(dir, DefaultSnapshotEvery, idgen.NewSequence(), idgen.NewSequence())
+	assert.NoError(t, err)
+	defer repo.Close()
+	got, err := repo.GetAPIKey(ctx, "key")
+	assert.NoError(t, err)
+	assert.True(t, got.LastUsedAt.Equal(at.Add(time.Hour)))
+
+	// and go away with the user, a snapshot does not bring them back
+	assert.NoError(t, repo.DeleteUser(ctx, 0))
+	assert.NoError(t, repo.Snapshot())
+	_, err = repo.GetAPIKey(ctx, "key")
+	assert.ErrorIs(t, err, userrepo.ErrInvalidAPIKeyId)
+}
+
+func TestFileRepositoryCategories(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+	repo, err := Open(dir, 3, idgen.NewSequence(), idgen.NewSequence())
+	assert.NoError(t, err)
+	vehicles := &ads.Category{Name: "vehicles"}
+	_, err = repo.AddCategory(ctx, vehicles)
+	assert.NoError(t, err)
+	bikes := &ads.Category{Name: "bikes", ParentID: vehicles.ID}
+	_, err = repo.AddCategory(ctx, bikes)
+	assert.NoError(t, err)
+	_, err = repo.AddCategory(ctx, &ads.Category{Name: "boats"})
+	assert.NoError(t, err)
+	// the last category is dropped after the snapshot
+	assert.NoError(t, repo.DeleteCategory(ctx, 3))
+	_, err = repo.UpdateCategory(ctx, &ads.Category{ID: bikes.ID, ParentID: vehicles.ID, Name: "bicycles"})
+	assert.NoError(t, err)
+	assert.NoError(t, repo.log.Close())
+
+	repo, err = Open(dir, 3, idgen.NewSequence(), idgen.NewSequence())
+	assert.NoError(t, err)
+	defer repo.Close()
+	list, err := repo.GetCategories(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []*ads.Category{vehicles, {ID: bikes.ID, ParentID: vehicles.ID, Name: "bicycles"}}, list)
+
+	// deleted ids are not handed out again
+	id, err := repo.AddCategory(ctx, &ads.Category{Name: "furniture"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4), id)
+}
+
+func TestFileRepositoryImages(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+	repo, err := Open(dir, 3, idgen.NewSequence(), idgen.NewSequence())
+	assert.NoError(t, err)
+	ad := &ads.Ad{Title: "bike", Version: 1}
+	_, err = repo.AddAd(ctx, ad)
+	assert.NoError(t, err)
+	at := time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)
+	first := &ads.Image{AdID: ad.ID, ContentType: "image/jpeg", Width: 800, Height: 600, CreatedAt: at}
+	_, err = repo.AddImage(ctx, first)
+	assert.NoError(t, err)
+	second := &ads.Image{AdID: ad.ID, ContentType: "image/png", CreatedAt: at}
+	_, err = repo.AddImage(ctx, second)
+	assert.NoError(t, err)
+	// the snapshot is written here, the rest goes to the log
+	_, err = repo.ReorderImages(ctx, ad.ID, []int64{second.ID, first.ID})
+	assert.NoError(t, err)
+	assert.NoError(t, repo.DeleteImage(ctx, ad.ID, first.ID))
+	assert.NoError(t, repo.log.Close())
+
+	repo, err = Open(dir, 3, idgen.NewSequence(), idgen.NewSequence())
+	assert.NoError(t, err)
+	defer repo.Close()
+	list, err := repo.GetImages(ctx, ad.ID)
+	assert.NoError(t, err)
+	second.Position = 0
+	assert.Equal(t, []*ads.Image{second}, list)
+
+	// deleted ids are not handed out again
+	id, err := repo.AddImage(ctx, &ads.Image{AdID: ad.ID, ContentType: "image/jpeg", CreatedAt: at})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), id)
+}
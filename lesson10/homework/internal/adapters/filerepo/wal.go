@@ -0,0 +1,325 @@
+package filerepo
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"hash/crc32"
+	"homework10/internal/entities/ads"
+	"homework10/internal/entities/user"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+const (
+	walFile      = "wal.log"
+	snapshotFile = "snapshot.json"
+
+	// every record is prefixed with the payload length and its crc32
+	headerSize = 8
+	// a length above this means the header itself is garbage
+	maxRecordSize = 16 << 20
+)
+
+var (
+	ErrTornRecord = errors.New("torn or corrupted wal record")
+	// ErrCorruptLog is a bad record followed by more of the log, which no
+	// crash leaves behind, so the log is not cut there
+	ErrCorruptLog = errors.New("corrupted wal record in the middle of the log")
+)
+
+type op string
+
+const (
+	opPutAd      op = "put_ad"
+	opDeleteAd   op = "delete_ad"
+	opPutUser    op = "put_user"
+	opDeleteUser op = "delete_user"
+	// the api key records carry the key id in KeyID rather than in ID
+	opPutKey    op = "put_api_key"
+	opDeleteKey op = "delete_api_key"
+	// appends a revision, replaying it again is a no-op as the revision
+	// number is already taken
+	opAddRevision op = "add_revision"
+	// replaces all revisions of an ad, an empty list removes them
+	opPutRevisions   op = "put_revisions"
+	opPutCategory    op = "put_category"
+	opDeleteCategory op = "delete_category"
+	// replaces all images of an ad, an empty list removes them
+	opPutImages op = "put_images"
+	// a batch holds all records of one transaction, so they reach the
+	// log under a single checksum and are replayed all or not at all
+	opBatch op = "batch"
+)
+
+// record holds the full state of the changed entity, not the call that
+// changed it, so replaying a record twice leaves the store as it was.
+type record struct {
+	Seq       uint64          `json:"seq"`
+	Op        op              `json:"op"`
+	ID        int64           `json:"id"`
+	Ad        *ads.Ad         `json:"ad,omitempty"`
+	User      *user.User      `json:"user,omitempty"`
+	Revision  *ads.Revision   `json:"revision,omitempty"`
+	Revisions []*ads.Revision `json:"revisions,omitempty"`
+	KeyID     string          `json:"key_id,omitempty"`
+	Key       *user.APIKey    `json:"api_key,omitempty"`
+	Category  *ads.Category   `json:"category,omitempty"`
+	Images    []*ads.Image    `json:"images,omitempty"`
+	Batch     []record        `json:"batch,omitempty"`
+}
+
+type snapshot struct {
+	Seq        uint64 `json:"seq"`
+	NextAdId   int64  `json:"next_ad_id"`
+	NextUserId int64  `json:"next_user_id"`
+	// NextCategoryId is zero in the snapshots written before there were
+	// categories
+	NextCategoryId int64                `json:"next_category_id,omitempty"`
+	Ads            map[int64]*ads.Ad    `json:"ads"`
+	Users          map[int64]*user.User `json:"users"`
+	// revisions by ad id
+	Revisions  map[int64][]*ads.Revision `json:"revisions,omitempty"`
+	Keys       map[string]*user.APIKey   `json:"api_keys,omitempty"`
+	Categories map[int64]*ads.Category   `json:"categories,omitempty"`
+	// images by ad id, NextImageId is zero in the snapshots written before
+	// there were images
+	Images      map[int64][]*ads.Image `json:"images,omitempty"`
+	NextImageId int64                  `json:"next_image_id,omitempty"`
+}
+
+func encodeRecord(rec record) ([]byte, error) {
+	payload, err := json.Marshal(rec)
+	if err != nil {
+		return nil, err
+	}
+	buf := make([]byte, headerSize+len(payload))
+	binary.LittleEndian.PutUint32(buf[0:4], uint32(len(payload)))
+	binary.LittleEndian.PutUint32(buf[4:8], crc32.ChecksumIEEE(payload))
+	copy(buf[headerSize:], payload)
+	return buf, nil
+}
+
+// readRecords applies the intact records and returns the offset after the
+// last one, a torn tail is ErrTornRecord and a corrupted middle ErrCorruptLog.
+func readRecords(r io.Reader, apply func(rec record)) (int64, error) {
+	br := bufio.NewReader(r)
+	var (
+		offset int64
+		header [headerSize]byte
+	)
+	for {
+		_, err := io.ReadFull(br, header[:])
+		if errors.Is(err, io.EOF) {
+			return offset, nil
+		}
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return offset, ErrTornRecord
+		}
+		if err != nil {
+			return offset, err
+		}
+		size := binary.LittleEndian.Uint32(header[0:4])
+		if size > maxRecordSize {
+			// the garbage length is the tail only if the log ends
+			// before the record would
+			rest, err := io.Copy(io.Discard, br)
+			if err != nil {
+				return offset, err
+			}
+			if rest < int64(size) {
+				return offset, ErrTornRecord
+			}
+			return offset, ErrCorruptLog
+		}
+		payload := make([]byte, size)
+		_, err = io.ReadFull(br, payload)
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return offset, ErrTornRecord
+		}
+		if err != nil {
+			return offset, err
+		}
+		var rec record
+		if crc32.ChecksumIEEE(payload) != binary.LittleEndian.Uint32(header[4:8]) ||
+			json.Unmarshal(payload, &rec) != nil || upgradeRecord(&rec, payload) != nil {
+			return offset, badRecord(br)
+		}
+		apply(rec)
+		offset += int64(headerSize) + int64(size)
+	}
+}
+
+// badRecord tells a bad record that ends the log from one in its middle.
+func badRecord(br *bufio.Reader) error {
+	_, err := br.Peek(1)
+	if errors.Is(err, io.EOF) {
+		return ErrTornRecord
+	}
+	if err != nil {
+		return err
+	}
+	return ErrCorruptLog
+}
+
+func readSnapshot(dir string) (*snapshot, error) {
+	data, err := os.ReadFile(filepath.Join(dir, snapshotFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return &snapshot{
+			Ads:        make(map[int64]*ads.Ad),
+			Users:      make(map[int64]*user.User),
+			Revisions:  make(map[int64][]*ads.Revision),
+			Keys:       make(map[string]*user.APIKey),
+			Categories: make(map[int64]*ads.Category),
+			Images:     make(map[int64][]*ads.Image),
+		}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	snap := &snapshot{}
+	if err = json.Unmarshal(data, snap); err != nil {
+		return nil, err
+	}
+	if err = upgradeSnapshot(snap, data); err != nil {
+		return nil, err
+	}
+	if snap.Ads == nil {
+		snap.Ads = make(map[int64]*ads.Ad)
+	}
+	if snap.Users == nil {
+		snap.Users = make(map[int64]*user.User)
+	}
+	if snap.Revisions == nil {
+		snap.Revisions = make(map[int64][]*ads.Revision)
+	}
+	if snap.Keys == nil {
+		snap.Keys = make(map[string]*user.APIKey)
+	}
+	if snap.Categories == nil {
+		snap.Categories = make(map[int64]*ads.Category)
+	}
+	if snap.Images == nil {
+		snap.Images = make(map[int64][]*ads.Image)
+	}
+	return snap, nil
+}
+
+// legacyState is the Published flag the ads and the revisions were stored
+// with before they had a state.
+type legacyState struct {
+	Published bool
+}
+
+type legacyRecord struct {
+	Ad        *legacyState   `json:"ad"`
+	Revision  *legacyState   `json:"revision"`
+	Revisions []legacyState  `json:"revisions"`
+	Batch     []legacyRecord `json:"batch"`
+}
+
+type legacySnapshot struct {
+	Ads       map[int64]legacyState   `json:"ads"`
+	Revisions map[int64][]legacyState `json:"revisions"`
+}
+
+var legacyField = []byte(`"Published":`)
+
+// upgradeRecord gives the ads and the revisions of a record written before
+// there were states the state their Published flag maps to.
+func upgradeRecord(rec *record, payload []byte) error {
+	if !bytes.Contains(payload, legacyField) {
+		return nil
+	}
+	var legacy legacyRecord
+	if err := json.Unmarshal(payload, &legacy); err != nil {
+		return err
+	}
+	legacy.upgrade(rec)
+	return nil
+}
+
+func (l *legacyRecord) upgrade(rec *record) {
+	if rec.Ad != nil && l.Ad != nil {
+		l.Ad.upgradeAd(rec.Ad)
+	}
+	if rec.Revision != nil && l.Revision != nil {
+		l.Revision.upgradeRevision(rec.Revision)
+	}
+	for i := range l.Revisions {
+		l.Revisions[i].upgradeRevision(rec.Revisions[i])
+	}
+	for i := range l.Batch {
+		l.Batch[i].upgrade(&rec.Batch[i])
+	}
+}
+
+func upgradeSnapshot(snap *snapshot, data []byte) error {
+	if !bytes.Contains(data, legacyField) {
+		return nil
+	}
+	var legacy legacySnapshot
+	if err := json.Unmarshal(data, &legacy); err != nil {
+		return err
+	}
+	for id, l := range legacy.Ads {
+		l.upgradeAd(snap.Ads[id])
+	}
+	for id, list := range legacy.Revisions {
+		for i, l := range list {
+			l.upgradeRevision(snap.Revisions[id][i])
+		}
+	}
+	return nil
+}
+
+func (l legacyState) upgradeAd(ad *ads.Ad) {
+	if ad != nil && ad.State == "" {
+		ad.State = ads.StateOf(l.Published)
+	}
+}
+
+func (l legacyState) upgradeRevision(rev *ads.Revision) {
+	if rev != nil && rev.State == "" {
+		rev.State = ads.StateOf(l.Published)
+	}
+}
+
+// writeSnapshot replaces the snapshot atomically: a crash leaves either the
+// old file or the new one, never a half-written one.
+func writeSnapshot(dir string, snap *snapshot) error {
+	data, err := json.Marshal(snap)
+	if err != nil {
+		return err
+	}
+	tmp := filepath.Join(dir, snapshotFile+".tmp")
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(data); err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if err = os.Rename(tmp, filepath.Join(dir, snapshotFile)); err != nil {
+		return err
+	}
+	return syncDir(dir)
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
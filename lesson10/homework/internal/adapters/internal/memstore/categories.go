@@ -0,0 +1,47 @@
+// Package memstore holds what the repositories that keep ads in memory,
+// adrepo and filerepo, have in common.
+package memstore
+
+import (
+	"homework10/internal/entities/ads"
+)
+
+// Categories returns copies of the categories sorted by id.
+func Categories(categories map[int64]*ads.Category) []*ads.Category {
+	resp := make([]*ads.Category, 0, len(categories))
+	for _, c := range categories {
+		cp := *c
+		resp = append(resp, &cp)
+	}
+	ads.SortCategories(resp)
+	return resp
+}
+
+// CategoryInUse tells whether an ad, trashed or not, or another category
+// is in the category.
+func CategoryInUse(list map[int64]*ads.Ad, categories map[int64]*ads.Category, id int64) bool {
+	for _, ad := range list {
+		if ad.CategoryID == id {
+			return true
+		}
+	}
+	for _, c := range categories {
+		if c.ParentID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// CountAds counts the ads with the status by category, the trashed ones
+// left out.
+func CountAds(list map[int64]*ads.Ad, status ads.Status) map[int64]int {
+	filters := ads.Filters{Status: status}
+	counts := make(map[int64]int)
+	for _, ad := range list {
+		if filters.Match(ad) {
+			counts[ad.CategoryID]++
+		}
+	}
+	return counts
+}
@@ -0,0 +1,40 @@
+package memstore
+
+import (
+	"homework10/internal/entities/ads"
+)
+
+// NextImagePosition is the position of an image added after the given
+// ones.
+func NextImagePosition(list []*ads.Image) int {
+	if len(list) == 0 {
+		return 0
+	}
+	return list[len(list)-1].Position + 1
+}
+
+// CloneImages copies the images, the lists of images are never changed in
+// place.
+func CloneImages(list []*ads.Image) []*ads.Image {
+	resp := make([]*ads.Image, 0, len(list))
+	for _, img := range list {
+		c := *img
+		resp = append(resp, &c)
+	}
+	return resp
+}
+
+// WithoutImage returns copies of the images but the one with the id, ok is
+// false if there is no such image.
+func WithoutImage(list []*ads.Image, imageId int64) (resp []*ads.Image, ok bool) {
+	resp = make([]*ads.Image, 0, len(list))
+	for _, img := range list {
+		if img.ID == imageId {
+			ok = true
+			continue
+		}
+		c := *img
+		resp = append(resp, &c)
+	}
+	return resp, ok
+}
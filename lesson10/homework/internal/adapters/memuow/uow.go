@@ -0,0 +1,68 @@
+package memuow
+
+import (
+	"context"
+	"homework10/internal/entities/ads"
+	"homework10/internal/entities/uow"
+	"homework10/internal/entities/user"
+	"sync"
+)
+
+// The in-memory repositories hand a transaction a view of themselves that
+// logs what its writes replace, rollback puts that back.
+type (
+	adsBeginner interface {
+		Begin() (store ads.Store, rollback func())
+	}
+	usersBeginner interface {
+		Begin() (repo user.Repository, rollback func())
+	}
+)
+
+type unitOfWork struct {
+	mu       *sync.Mutex
+	adStore  ads.Store
+	userRepo user.Repository
+}
+
+// New returns a unit of work over adrepo and userrepo. Transactions are
+// serialized, a failed or panicking one undoes only its own writes.
+func New(adStore ads.Store, userRepo user.Repository) uow.UnitOfWork {
+	return &unitOfWork{mu: &sync.Mutex{}, adStore: adStore, userRepo: userRepo}
+}
+
+func (u *unitOfWork) Repositories() uow.Repositories {
+	return uow.NewRepositories(u.adStore, u.userRepo)
+}
+
+func (u *unitOfWork) Do(ctx context.Context, fn func(repos uow.Repositories) error) (err error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	adStore, userRepo := u.adStore, u.userRepo
+	var rollbacks []func()
+	if b, ok := adStore.(adsBeginner); ok {
+		var rollback func()
+		adStore, rollback = b.Begin()
+		rollbacks = append(rollbacks, rollback)
+	}
+	if b, ok := userRepo.(usersBeginner); ok {
+		var rollback func()
+		userRepo, rollback = b.Begin()
+		rollbacks = append(rollbacks, rollback)
+	}
+	// a panic is recovered by the servers, its writes must not stay
+	committed := false
+	defer func() {
+		if !committed {
+			for _, rollback := range rollbacks {
+				rollback()
+			}
+		}
+	}()
+	if err = fn(uow.NewRepositories(adStore, userRepo)); err != nil {
+		return err
+	}
+	committed = true
+	return nil
+}
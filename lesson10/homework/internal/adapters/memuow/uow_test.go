@@ -0,0 +1,72 @@
+package memuow
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"homework10/internal/adapters/adrepo"
+	"homework10/internal/adapters/userrepo"
+	"homework10/internal/entities/ads"
+	"homework10/internal/entities/uow"
+	"homework10/internal/entities/user"
+	"homework10/pkg/idgen"
+	"testing"
+)
+
+func TestDoRollback(t *testing.T) {
+	ctx := context.Background()
+	adRepo, userRepo := adrepo.New(idgen.NewSequence()), userrepo.New(idgen.NewSequence())
+	tx := New(adRepo, userRepo)
+	kept, err := adRepo.AddAd(ctx, &ads.Ad{Title: "kept"})
+	assert.NoError(t, err)
+	author, err := userRepo.CreateUser(ctx, &user.User{Nickname: "author"})
+	assert.NoError(t, err)
+
+	failure := errors.New("failure")
+	err = tx.Do(ctx, func(repos uow.Repositories) error {
+		_, err := repos.Ads.UpdateAdTitleAndText(ctx, kept, "changed", "")
+		assert.NoError(t, err)
+		_, err = repos.Ads.AddAd(ctx, &ads.Ad{Title: "dropped"})
+		assert.NoError(t, err)
+		assert.NoError(t, repos.Users.DeleteUser(ctx, author))
+		// a write made meanwhile outside the transaction
+		_, err = adRepo.AddAd(ctx, &ads.Ad{Title: "outside"})
+		assert.NoError(t, err)
+		return failure
+	})
+	assert.ErrorIs(t, err, failure)
+
+	ad, err := adRepo.GetAdById(ctx, kept)
+	assert.NoError(t, err)
+	assert.Equal(t, "kept", ad.Title)
+	assert.Zero(t, ad.Version)
+	_, err = userRepo.GetUser(ctx, author)
+	assert.NoError(t, err)
+	all, err := adRepo.GetAll(ctx, ads.Filters{Status: ads.Unpublished})
+	assert.NoError(t, err)
+	titles := make([]string, 0, len(all))
+	for _, ad := range all {
+		titles = append(titles, ad.Title)
+	}
+	assert.ElementsMatch(t, []string{"kept", "outside"}, titles)
+
+	err = tx.Do(ctx, func(repos uow.Repositories) error {
+		_, err := repos.Ads.UpdateAdTitleAndText(ctx, kept, "changed", "")
+		return err
+	})
+	assert.NoError(t, err)
+	ad, err = adRepo.GetAdById(ctx, kept)
+	assert.NoError(t, err)
+	assert.Equal(t, "changed", ad.Title)
+
+	assert.Panics(t, func() {
+		_ = tx.Do(ctx, func(repos uow.Repositories) error {
+			_, err := repos.Ads.UpdateAdTitleAndText(ctx, kept, "panicked", "")
+			assert.NoError(t, err)
+			panic("failure")
+		})
+	})
+	ad, err = adRepo.GetAdById(ctx, kept)
+	assert.NoError(t, err)
+	assert.Equal(t, "changed", ad.Title)
+}
@@ -0,0 +1,139 @@
+package searchindex
+
+import (
+	"context"
+	"homework10/internal/entities/ads"
+	"math"
+	"sort"
+	"sync"
+)
+
+// BM25 parameters, the usual defaults
+const (
+	k1 = 1.2
+	b  = 0.75
+)
+
+// words of the title count this many times, so an ad about a thing ranks
+// above an ad that only mentions it
+const titleWeight = 2
+
+type document struct {
+	terms  map[string]int
+	length int
+}
+
+type index struct {
+	mu       *sync.RWMutex
+	postings map[string]map[int64]int
+	docs     map[int64]document
+	totalLen int
+}
+
+func New() ads.SearchIndex {
+	return &index{
+		mu:       &sync.RWMutex{},
+		postings: make(map[string]map[int64]int),
+		docs:     make(map[int64]document),
+	}
+}
+
+// Load builds an index over every ad already stored in the repository.
+func Load(ctx context.Context, repo ads.Repository) (ads.SearchIndex, error) {
+	idx := New()
+	for _, status := range []ads.Status{ads.Published, ads.Unpublished} {
+		all, err := repo.GetAll(ctx, ads.Filters{Status: status})
+		if err != nil {
+			return nil, err
+		}
+		for _, ad := range all {
+			idx.Put(ad)
+		}
+	}
+	return idx, nil
+}
+
+func (i *index) Put(ad *ads.Ad) {
+	doc := document{terms: make(map[string]int)}
+	for _, term := range tokenize(ad.Title) {
+		doc.terms[term] += titleWeight
+		doc.length += titleWeight
+	}
+	for _, term := range tokenize(ad.Text) {
+		doc.terms[term]++
+		doc.length++
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.remove(ad.ID)
+	for term, tf := range doc.terms {
+		if i.postings[term] == nil {
+			i.postings[term] = make(map[int64]int)
+		}
+		i.postings[term][ad.ID] = tf
+	}
+	i.docs[ad.ID] = doc
+	i.totalLen += doc.length
+}
+
+func (i *index) Remove(adId int64) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.remove(adId)
+}
+
+func (i *index) remove(adId int64) {
+	doc, ok := i.docs[adId]
+	if !ok {
+		return
+	}
+	for term := range doc.terms {
+		delete(i.postings[term], adId)
+		if len(i.postings[term]) == 0 {
+			delete(i.postings, term)
+		}
+	}
+	delete(i.docs, adId)
+	i.totalLen -= doc.length
+}
+
+// Search ranks the ads that contain any of the query words with BM25.
+func (i *index) Search(query string) []int64 {
+	terms := tokenize(query)
+
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	if len(i.docs) == 0 {
+		return []int64{}
+	}
+	n := float64(len(i.docs))
+	avgLen := float64(i.totalLen) / n
+	scores := make(map[int64]float64)
+	seen := make(map[string]bool)
+	for _, term := range terms {
+		if seen[term] {
+			continue
+		}
+		seen[term] = true
+		posting := i.postings[term]
+		df := float64(len(posting))
+		idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+		for id, tf := range posting {
+			norm := 1 - b + b*float64(i.docs[id].length)/avgLen
+			scores[id] += idf * float64(tf) * (k1 + 1) / (float64(tf) + k1*norm)
+		}
+	}
+
+	ids := make([]int64, 0, len(scores))
+	for id := range scores {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(x, y int) bool {
+		if scores[ids[x]] != scores[ids[y]] {
+			return scores[ids[x]] > scores[ids[y]]
+		}
+		return ids[x] < ids[y]
+	})
+	return ids
+}
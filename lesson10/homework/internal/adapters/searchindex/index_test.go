@@ -0,0 +1,62 @@
+package searchindex
+
+import (
+	"github.com/stretchr/testify/assert"
+	"homework10/internal/entities/ads"
+	"testing"
+)
+
+type SearchTest struct {
+	Name   string
+	Query  string
+	Expect []int64
+}
+
+func newTestIndex() ads.SearchIndex {
+	idx := New()
+	idx.Put(&ads.Ad{ID: 0, Title: "Продам велосипед", Text: "Горный велосипед, почти новый"})
+	idx.Put(&ads.Ad{ID: 1, Title: "Куплю самокат", Text: "Рассмотрю велосипеды и самокаты"})
+	idx.Put(&ads.Ad{ID: 2, Title: "Selling a bike", Text: "Red mountain bike with new tires"})
+	idx.Put(&ads.Ad{ID: 3, Title: "Ёлка", Text: "Живая ёлка к празднику"})
+	return idx
+}
+
+func TestTokenize(t *testing.T) {
+	assert.Equal(t, []string{"велосипед", "2023"}, tokenize("Велосипед, 2023!"))
+	assert.Equal(t, tokenize("велосипеды"), tokenize("ВЕЛОСИПЕДОВ"))
+	assert.Equal(t, tokenize("bikes"), tokenize("Bike"))
+	assert.Equal(t, tokenize("ёлка"), tokenize("елки"))
+	assert.Equal(t, []string{"мы", "и", "он"}, tokenize("мы, и он"))
+	assert.Empty(t, tokenize(" ,.!? "))
+}
+
+func TestSearch(t *testing.T) {
+	tests := []SearchTest{
+		{Name: "title match ranks first", Query: "велосипед", Expect: []int64{0, 1}},
+		{Name: "inflected form", Query: "велосипеды", Expect: []int64{0, 1}},
+		{Name: "case folding", Query: "САМОКАТ", Expect: []int64{1}},
+		{Name: "text only", Query: "горный", Expect: []int64{0}},
+		{Name: "latin", Query: "mountain bikes", Expect: []int64{2}},
+		{Name: "yo folding", Query: "елку", Expect: []int64{3}},
+		{Name: "any word matches", Query: "самокат tires", Expect: []int64{1, 2}},
+		{Name: "no match", Query: "квартира", Expect: []int64{}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.Name, func(t *testing.T) {
+			assert.Equal(t, tc.Expect, newTestIndex().Search(tc.Query))
+		})
+	}
+}
+
+func TestPutAndRemove(t *testing.T) {
+	idx := newTestIndex()
+
+	idx.Put(&ads.Ad{ID: 0, Title: "Продам самокат", Text: "Детский"})
+	assert.Equal(t, []int64{1}, idx.Search("велосипед"))
+	assert.Equal(t, []int64{1, 0}, idx.Search("самокат"))
+
+	idx.Remove(1)
+	idx.Remove(10)
+	assert.Equal(t, []int64{0}, idx.Search("самокат"))
+	assert.Empty(t, idx.Search("велосипед"))
+}
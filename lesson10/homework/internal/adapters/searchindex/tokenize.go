@@ -0,0 +1,65 @@
+package searchindex
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// the shortest stem left after cutting an ending, shorter words are kept
+// as they are so that "дом" and "домов" meet, but "мы" does not turn into "м"
+const minStemLen = 3
+
+// endings are tried longest first, so the first match is the one cut off
+var (
+	russianEndings = []string{
+		"иями", "ями", "ами", "ого", "его", "ому", "ему", "ыми", "ими",
+		"ов", "ев", "ей", "ой", "ий", "ый", "ая", "яя", "ое", "ее", "ые", "ие",
+		"ом", "ем", "ам", "ям", "ах", "ях", "ую", "юю", "ью",
+		"а", "я", "ы", "и", "о", "е", "у", "ю", "ь", "й",
+	}
+	englishEndings = []string{
+		"ing", "ed", "s",
+	}
+)
+
+// tokenize splits text into words, folds their case and cuts common
+// russian and english inflection endings off.
+func tokenize(text string) []string {
+	words := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	terms := make([]string, 0, len(words))
+	for _, w := range words {
+		w = strings.ToLower(w)
+		w = strings.ReplaceAll(w, "ё", "е")
+		terms = append(terms, stem(w))
+	}
+	return terms
+}
+
+func stem(word string) string {
+	endings := englishEndings
+	if isCyrillic(word) {
+		endings = russianEndings
+	}
+	for _, end := range endings {
+		if !strings.HasSuffix(word, end) {
+			continue
+		}
+		stemmed := strings.TrimSuffix(word, end)
+		if utf8.RuneCountInString(stemmed) >= minStemLen {
+			return stemmed
+		}
+	}
+	return word
+}
+
+func isCyrillic(word string) bool {
+	for _, r := range word {
+		if unicode.Is(unicode.Cyrillic, r) {
+			return true
+		}
+	}
+	return false
+}
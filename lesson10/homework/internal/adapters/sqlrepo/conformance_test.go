@@ -0,0 +1,33 @@
+package sqlrepo
+
+import (
+	"github.com/stretchr/testify/assert"
+	"homework10/internal/entities/ads"
+	"homework10/internal/entities/ads/adstest"
+	"homework10/internal/entities/user"
+	"homework10/internal/entities/user/usertest"
+	"homework10/pkg/idgen"
+	"path/filepath"
+	"testing"
+)
+
+func newEmptyRepo(t *testing.T) *Repository {
+	repo, err := Open(filepath.Join(t.TempDir(), "test.db"), idgen.NewSequence(), idgen.NewSequence())
+	assert.NoError(t, err)
+	t.Cleanup(func() {
+		_ = repo.Close()
+	})
+	return repo
+}
+
+func TestAdsConformance(t *testing.T) {
+	adstest.RunRepositoryTests(t, func(t *testing.T) ads.Store {
+		return newEmptyRepo(t)
+	})
+}
+
+func TestUsersConformance(t *testing.T) {
+	usertest.RunRepositoryTests(t, func(t *testing.T) user.Repository {
+		return newEmptyRepo(t)
+	})
+}
@@ -0,0 +1,221 @@
+package sqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"homework10/internal/entities/user"
+)
+
+// migrations are applied in order, the index of the last applied one is
+// kept in PRAGMA user_version. Never edit an entry, append a new one.
+var migrations = []string{
+	`CREATE TABLE IF NOT EXISTS users (
+		id       INTEGER PRIMARY KEY,
+		nickname TEXT NOT NULL,
+		email    TEXT NOT NULL,
+		password TEXT NOT NULL
+	);
+	CREATE TABLE IF NOT EXISTS ads (
+		id            INTEGER PRIMARY KEY,
+		title         TEXT    NOT NULL,
+		text          TEXT    NOT NULL,
+		author_id     INTEGER NOT NULL,
+		creation_date TEXT    NOT NULL,
+		update_date   TEXT    NOT NULL DEFAULT '',
+		published     BOOLEAN NOT NULL DEFAULT 0
+	);
+	CREATE INDEX IF NOT EXISTS ads_author_id ON ads (author_id);`,
+	`ALTER TABLE ads ADD COLUMN version INTEGER NOT NULL DEFAULT 1;`,
+	// unix nanoseconds, NULL unless the ad is in the trash
+	`ALTER TABLE ads ADD COLUMN deleted_at INTEGER;`,
+	// created_at is in unix nanoseconds, changes is the json of []ads.Change
+	`CREATE TABLE IF NOT EXISTS ad_revisions (
+		ad_id      INTEGER NOT NULL,
+		number     INTEGER NOT NULL,
+		actor_id   INTEGER NOT NULL,
+		created_at INTEGER NOT NULL,
+		title      TEXT    NOT NULL,
+		text       TEXT    NOT NULL,
+		published  BOOLEAN NOT NULL,
+		changes    TEXT    NOT NULL,
+		PRIMARY KEY (ad_id, number)
+	);
+	CREATE TRIGGER IF NOT EXISTS ads_delete_revisions AFTER DELETE ON ads BEGIN
+		DELETE FROM ad_revisions WHERE ad_id = OLD.id;
+	END;`,
+	// the highest id ever stored per table, ids are never reused even when
+	// the row holding the highest one is deleted
+	`CREATE TABLE IF NOT EXISTS id_watermarks (
+		entity TEXT    PRIMARY KEY,
+		last   INTEGER NOT NULL
+	);
+	INSERT INTO id_watermarks (entity, last)
+		SELECT 'ads', last FROM (SELECT MAX(id) AS last FROM ads) WHERE last IS NOT NULL;
+	INSERT INTO id_watermarks (entity, last)
+		SELECT 'users', last FROM (SELECT MAX(id) AS last FROM users) WHERE last IS NOT NULL;
+	CREATE TRIGGER IF NOT EXISTS ads_id_watermark AFTER INSERT ON ads BEGIN
+		INSERT INTO id_watermarks (entity, last) VALUES ('ads', NEW.id)
+		ON CONFLICT (entity) DO UPDATE SET last = MAX(last, excluded.last);
+	END;
+	CREATE TRIGGER IF NOT EXISTS users_id_watermark AFTER INSERT ON users BEGIN
+		INSERT INTO id_watermarks (entity, last) VALUES ('users', NEW.id)
+		ON CONFLICT (entity) DO UPDATE SET last = MAX(last, excluded.last);
+	END;`,
+	// the empty role of the users created before roles existed means user
+	`ALTER TABLE users ADD COLUMN role TEXT NOT NULL DEFAULT '';`,
+	// reason is set when a moderator changed or deleted the ad of another user
+	`ALTER TABLE ad_revisions ADD COLUMN reason TEXT NOT NULL DEFAULT '';`,
+	// email_key is user.EmailKey of the email, NULL for an empty one, duplicate
+	// emails stored before fail the migration
+	`ALTER TABLE users ADD COLUMN verified BOOLEAN NOT NULL DEFAULT 0;
+	ALTER TABLE users ADD COLUMN email_key TEXT;
+	CREATE UNIQUE INDEX IF NOT EXISTS users_email_key ON users (email_key);`,
+	`ALTER TABLE users ADD COLUMN session_version INTEGER NOT NULL DEFAULT 0;`,
+	// the times are in unix nanoseconds, expires_at and last_used_at are
+	// NULL when unset, scopes is the json of []user.Scope
+	`CREATE TABLE IF NOT EXISTS api_keys (
+		id           TEXT    PRIMARY KEY,
+		user_id      INTEGER NOT NULL,
+		name         TEXT    NOT NULL,
+		hash         TEXT    NOT NULL,
+		scopes       TEXT    NOT NULL,
+		created_at   INTEGER NOT NULL,
+		expires_at   INTEGER,
+		last_used_at INTEGER
+	);
+	CREATE INDEX IF NOT EXISTS api_keys_user_id ON api_keys (user_id);
+	CREATE TRIGGER IF NOT EXISTS users_delete_api_keys AFTER DELETE ON users BEGIN
+		DELETE FROM api_keys WHERE user_id = OLD.id;
+	END;`,
+	`CREATE INDEX IF NOT EXISTS ad_revisions_actor_id ON ad_revisions (actor_id);`,
+	// nickname_key is user.NicknameKey of the nickname. created_at is in
+	// unix nanoseconds, NULL for the users registered before it was kept.
+	`ALTER TABLE users ADD COLUMN display_name TEXT NOT NULL DEFAULT '';
+	ALTER TABLE users ADD COLUMN bio TEXT NOT NULL DEFAULT '';
+	ALTER TABLE users ADD COLUMN city TEXT NOT NULL DEFAULT '';
+	ALTER TABLE users ADD COLUMN phone TEXT NOT NULL DEFAULT '';
+	ALTER TABLE users ADD COLUMN city_visibility TEXT NOT NULL DEFAULT '';
+	ALTER TABLE users ADD COLUMN phone_visibility TEXT NOT NULL DEFAULT '';
+	ALTER TABLE users ADD COLUMN created_at INTEGER;
+	ALTER TABLE users ADD COLUMN nickname_key TEXT NOT NULL DEFAULT '';
+	CREATE INDEX IF NOT EXISTS users_nickname_key ON users (nickname_key, id);`,
+	// the zero category_id is left on the ads created before there were
+	// categories, autoincrement keeps the ids of deleted categories unused
+	`CREATE TABLE IF NOT EXISTS categories (
+		id        INTEGER PRIMARY KEY AUTOINCREMENT,
+		parent_id INTEGER NOT NULL DEFAULT 0,
+		name      TEXT    NOT NULL
+	);
+	ALTER TABLE ads ADD COLUMN category_id INTEGER NOT NULL DEFAULT 0;
+	CREATE INDEX IF NOT EXISTS ads_category_id ON ads (category_id);`,
+	// price_amount is in minor units, the empty currency is left on the ads
+	// created before there were prices
+	`ALTER TABLE ads ADD COLUMN price_amount INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE ads ADD COLUMN price_currency TEXT NOT NULL DEFAULT '';
+	CREATE INDEX IF NOT EXISTS ads_price ON ads (price_currency, price_amount);`,
+	// created_at is in unix nanoseconds, autoincrement keeps the ids of
+	// deleted images unused
+	`CREATE TABLE IF NOT EXISTS ad_images (
+		id           INTEGER PRIMARY KEY AUTOINCREMENT,
+		ad_id        INTEGER NOT NULL,
+		position     INTEGER NOT NULL,
+		content_type TEXT    NOT NULL,
+		size         INTEGER NOT NULL,
+		width        INTEGER NOT NULL,
+		height       INTEGER NOT NULL,
+		created_at   INTEGER NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS ad_images_ad_id ON ad_images (ad_id, position);
+	CREATE TRIGGER IF NOT EXISTS ads_delete_images AFTER DELETE ON ads BEGIN
+		DELETE FROM ad_images WHERE ad_id = OLD.id;
+	END;`,
+	// publish_at and expires_at are in unix nanoseconds, NULL when not set
+	`ALTER TABLE ads ADD COLUMN publish_at INTEGER;
+	ALTER TABLE ads ADD COLUMN expires_at INTEGER;
+	CREATE INDEX IF NOT EXISTS ads_publish_at ON ads (publish_at);
+	CREATE INDEX IF NOT EXISTS ads_expires_at ON ads (expires_at);`,
+	// state is an ads.State and replaces published: the published ads stay
+	// published, the others become drafts
+	`ALTER TABLE ads ADD COLUMN state TEXT NOT NULL DEFAULT 'draft';
+	ALTER TABLE ads ADD COLUMN rejection_reason TEXT NOT NULL DEFAULT '';
+	UPDATE ads SET state = 'published' WHERE published;
+	ALTER TABLE ads DROP COLUMN published;
+	CREATE INDEX IF NOT EXISTS ads_state ON ads (state);
+	ALTER TABLE ad_revisions ADD COLUMN state TEXT NOT NULL DEFAULT 'draft';
+	UPDATE ad_revisions SET state = 'published' WHERE published;
+	ALTER TABLE ad_revisions DROP COLUMN published;`,
+}
+
+// backfills fill the columns whose values are computed in Go, each one
+// runs in the transaction of the migration with the same index.
+var backfills = map[int]func(ctx context.Context, tx *sql.Tx) error{
+	7: func(ctx context.Context, tx *sql.Tx) error {
+		return backfillUsers(ctx, tx, "email", "email_key", user.EmailKey)
+	},
+	11: func(ctx context.Context, tx *sql.Tx) error {
+		return backfillUsers(ctx, tx, "nickname", "nickname_key", user.NicknameKey)
+	},
+}
+
+// backfillUsers sets the column to of the users to the key of their column
+// from, the empty keys are left out.
+func backfillUsers(ctx context.Context, tx *sql.Tx, from, to string, key func(string) string) error {
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf("SELECT id, %s FROM users", from))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	ids := make([]int64, 0)
+	keys := make([]string, 0)
+	for rows.Next() {
+		var id int64
+		var value string
+		if err := rows.Scan(&id, &value); err != nil {
+			return err
+		}
+		if k := key(value); k != "" {
+			ids = append(ids, id)
+			keys = append(keys, k)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for i, id := range ids {
+		_, err := tx.ExecContext(ctx, fmt.Sprintf("UPDATE users SET %s = ? WHERE id = ?", to), keys[i], id)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func migrate(ctx context.Context, db *sql.DB) error {
+	var version int
+	err := db.QueryRowContext(ctx, "PRAGMA user_version").Scan(&version)
+	if err != nil {
+		return err
+	}
+	for i := version; i < len(migrations); i++ {
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, migrations[i])
+		if backfill := backfills[i]; err == nil && backfill != nil {
+			err = backfill(ctx, tx)
+		}
+		if err == nil {
+			_, err = tx.ExecContext(ctx, fmt.Sprintf("PRAGMA user_version = %d", i+1))
+		}
+		if err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("migration %d: %w", i+1, err)
+		}
+		if err = tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
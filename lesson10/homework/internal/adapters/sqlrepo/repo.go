@@ -0,0 +1,806 @@
+package sqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/mattn/go-sqlite3"
+	"homework10/internal/adapters/adrepo"
+	"homework10/internal/adapters/userrepo"
+	"homework10/internal/entities/ads"
+	"homework10/internal/entities/uow"
+	"homework10/internal/entities/user"
+	"homework10/pkg/idgen"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// Repository works either on the whole database or, inside Do, on a single
+// transaction, in which case db is the *sql.Tx and conn is nil.
+type Repository struct {
+	conn    *sql.DB
+	db      querier
+	adIds   idgen.IDGenerator
+	userIds idgen.IDGenerator
+}
+
+func Open(dsn string, adIds, userIds idgen.IDGenerator) (*Repository, error) {
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, err
+	}
+	// sqlite allows only one writer at a time, so a single connection
+	// keeps concurrent requests from failing with "database is locked"
+	db.SetMaxOpenConns(1)
+	r, err := New(db, adIds, userIds)
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+func New(db *sql.DB, adIds, userIds idgen.IDGenerator) (*Repository, error) {
+	ctx := context.Background()
+	err := migrate(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryContext(ctx, "SELECT entity, last FROM id_watermarks")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			entity string
+			last   int64
+		)
+		if err = rows.Scan(&entity, &last); err != nil {
+			return nil, err
+		}
+		switch entity {
+		case "ads":
+			adIds.Resume(last)
+		case "users":
+			userIds.Resume(last)
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return &Repository{conn: db, db: db, adIds: adIds, userIds: userIds}, nil
+}
+
+func (r *Repository) Close() error {
+	return r.conn.Close()
+}
+
+func (r *Repository) Repositories() uow.Repositories {
+	return uow.NewRepositories(r, r)
+}
+
+func (r *Repository) Do(ctx context.Context, fn func(repos uow.Repositories) error) error {
+	tx, err := r.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	txRepo := &Repository{db: tx, adIds: r.adIds, userIds: r.userIds}
+	err = fn(txRepo.Repositories())
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+const adColumns = "id, title, text, author_id, creation_date, update_date, state, rejection_reason, version, " +
+	"deleted_at, category_id, price_amount, price_currency, publish_at, expires_at"
+
+func scanAd(row interface{ Scan(...any) error }) (*ads.Ad, error) {
+	ad := &ads.Ad{}
+	var deletedAt, publishAt, expiresAt sql.NullInt64
+	err := row.Scan(&ad.ID, &ad.Title, &ad.Text, &ad.AuthorID, &ad.CreationDate, &ad.UpdateDate, &ad.State,
+		&ad.RejectionReason, &ad.Version, &deletedAt, &ad.CategoryID, &ad.Price.Amount, &ad.Price.Currency, &publishAt, &expiresAt)
+	if err != nil {
+		return nil, err
+	}
+	if deletedAt.Valid {
+		ad.DeletedAt = time.Unix(0, deletedAt.Int64).UTC()
+	}
+	ad.PublishAt = fromNullTime(publishAt)
+	ad.ExpiresAt = fromNullTime(expiresAt)
+	return ad, nil
+}
+
+func (r *Repository) queryAds(ctx context.Context, query string, args ...any) ([]*ads.Ad, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	resp := make([]*ads.Ad, 0)
+	for rows.Next() {
+		ad, err := scanAd(rows)
+		if err != nil {
+			return nil, err
+		}
+		resp = append(resp, ad)
+	}
+	return resp, rows.Err()
+}
+
+func (r *Repository) AddAd(ctx context.Context, ad *ads.Ad) (int64, error) {
+	row := r.db.QueryRowContext(ctx,
+		`INSERT INTO ads (id, title, text, author_id, creation_date, update_date, state, rejection_reason, version,
+			category_id, price_amount, price_currency, publish_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?) RETURNING id`,
+		r.adIds.NextID(), ad.Title, ad.Text, ad.AuthorID, ad.CreationDate, ad.UpdateDate, ad.State, ad.RejectionReason,
+		ad.Version, ad.CategoryID, ad.Price.Amount, ad.Price.Currency, nullTime(ad.PublishAt), nullTime(ad.ExpiresAt))
+	err := row.Scan(&ad.ID)
+	if err != nil {
+		return 0, err
+	}
+	return ad.ID, nil
+}
+
+func (r *Repository) GetAdById(ctx context.Context, adId int64) (*ads.Ad, error) {
+	ad, err := scanAd(r.db.QueryRowContext(ctx, "SELECT "+adColumns+" FROM ads WHERE id = ? AND deleted_at IS NULL", adId))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, adrepo.ErrInvalidAdId
+	}
+	return ad, err
+}
+
+func (r *Repository) GetAdsByTitle(ctx context.Context, title string) ([]*ads.Ad, error) {
+	resp, err := r.queryAds(ctx,
+		"SELECT "+adColumns+" FROM ads WHERE substr(title, 1, length(?1)) = ?1 AND deleted_at IS NULL ORDER BY id", title)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp) == 0 {
+		return nil, adrepo.ErrInvalidAdTitle
+	}
+	return resp, nil
+}
+
+func (r *Repository) GetAll(ctx context.Context, filters ads.Filters) ([]*ads.Ad, error) {
+	var (
+		conds = []string{"deleted_at IS NULL"}
+		args  []any
+	)
+	cond, arg, ok := statusCond(filters.Status)
+	if !ok {
+		return make([]*ads.Ad, 0), nil
+	}
+	conds = append(conds, cond)
+	args = append(args, arg)
+	if filters.Date != "" {
+		conds = append(conds, "creation_date = ?")
+		args = append(args, filters.Date)
+	}
+	if filters.AuthorId != "" {
+		conds = append(conds, "CAST(author_id AS TEXT) = ?")
+		args = append(args, filters.AuthorId)
+	}
+	if len(filters.Categories) > 0 {
+		conds = append(conds, "category_id IN (?"+strings.Repeat(", ?", len(filters.Categories)-1)+")")
+		for _, id := range filters.Categories {
+			args = append(args, id)
+		}
+	}
+	if filters.Currency != "" {
+		conds = append(conds, "price_currency = ?")
+		args = append(args, filters.Currency)
+	}
+	if filters.PriceMin > 0 {
+		conds = append(conds, "price_amount >= ?")
+		args = append(args, filters.PriceMin)
+	}
+	if filters.PriceMax > 0 {
+		conds = append(conds, "price_amount <= ?")
+		args = append(args, filters.PriceMax)
+	}
+
+	column, ok := sortColumns[filters.Sort]
+	if !ok {
+		return nil, ads.ErrInvalidFilters
+	}
+	cmp, dir := ">", "ASC"
+	if filters.Desc {
+		cmp, dir = "<", "DESC"
+	}
+	after, ok, err := filters.After()
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		conds = append(conds, fmt.Sprintf("(%[1]s, id) %[2]s (?, ?)", column, cmp))
+		args = append(args, sortValue(column, filters.SortKey(after), after.ID), after.ID)
+	}
+	query := "SELECT " + adColumns + " FROM ads WHERE " + strings.Join(conds, " AND ") +
+		fmt.Sprintf(" ORDER BY %[1]s %[2]s, id %[2]s", column, dir)
+	if filters.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, filters.Limit)
+	}
+	return r.queryAds(ctx, query, args...)
+}
+
+// statusCond is the condition on the state of the ads with the status and
+// its argument, false for an unknown status.
+func statusCond(status ads.Status) (string, any, bool) {
+	switch {
+	case status == ads.Unpublished:
+		return "state <> ?", ads.StatePublished, true
+	case status.State().Valid():
+		return "state = ?", status.State(), true
+	}
+	return "", nil, false
+}
+
+var sortColumns = map[ads.SortField]string{
+	"":                     "id",
+	ads.SortById:           "id",
+	ads.SortByCreationDate: "creation_date",
+	ads.SortByUpdateDate:   "update_date",
+	ads.SortByTitle:        "title",
+	ads.SortByPrice:        "price_amount",
+}
+
+// sortValue is the cursor key as the sort column stores it.
+func sortValue(column, key string, id int64) any {
+	switch column {
+	case "id":
+		return id
+	case "price_amount":
+		amount, _ := strconv.ParseInt(key, 10, 64)
+		return amount
+	}
+	return key
+}
+
+func (r *Repository) UpdateAdState(ctx context.Context, adId int64, state ads.State, rejectionReason string) (*ads.Ad, error) {
+	if state != ads.StateRejected {
+		rejectionReason = ""
+	}
+	ad, err := scanAd(r.db.QueryRowContext(ctx,
+		"UPDATE ads SET state = ?, rejection_reason = ?, update_date = ?, version = version + 1 "+
+			"WHERE id = ? AND deleted_at IS NULL RETURNING "+adColumns,
+		state, rejectionReason, today(), adId))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, adrepo.ErrInvalidAdId
+	}
+	return ad, err
+}
+
+func (r *Repository) UpdateAdTitleAndText(ctx context.Context, adId int64, newTitle, newText string) (*ads.Ad, error) {
+	ad, err := scanAd(r.db.QueryRowContext(ctx,
+		"UPDATE ads SET title = ?, text = ?, update_date = ?, version = version + 1 "+
+			"WHERE id = ? AND deleted_at IS NULL RETURNING "+adColumns,
+		newTitle, newText, today(), adId))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, adrepo.ErrInvalidAdId
+	}
+	return ad, err
+}
+
+func (r *Repository) UpdateAdPublication(ctx context.Context, adId int64, state ads.State, publishAt, expiresAt time.Time) (*ads.Ad, error) {
+	ad, err := scanAd(r.db.QueryRowContext(ctx,
+		"UPDATE ads SET state = ?, rejection_reason = '', publish_at = ?, expires_at = ?, update_date = ?, "+
+			"version = version + 1 WHERE id = ? AND deleted_at IS NULL RETURNING "+adColumns,
+		state, nullTime(publishAt), nullTime(expiresAt), today(), adId))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, adrepo.ErrInvalidAdId
+	}
+	return ad, err
+}
+
+func (r *Repository) GetDueAds(ctx context.Context, now time.Time) ([]*ads.Ad, error) {
+	return r.queryAds(ctx,
+		"SELECT "+adColumns+" FROM ads WHERE deleted_at IS NULL AND "+
+			"(state = ?2 AND publish_at <= ?1 OR state = ?3 AND expires_at <= ?1) ORDER BY id",
+		now.UnixNano(), ads.StateDraft, ads.StatePublished)
+}
+
+func (r *Repository) DeleteAd(ctx context.Context, adId int64) error {
+	_, err := r.db.ExecContext(ctx, "DELETE FROM ads WHERE id = ?", adId)
+	return err
+}
+
+func (r *Repository) DeleteAdsByAuthor(ctx context.Context, authorId int64) error {
+	_, err := r.db.ExecContext(ctx, "DELETE FROM ads WHERE author_id = ?", authorId)
+	return err
+}
+
+func (r *Repository) TrashAd(ctx context.Context, adId int64, at time.Time) (*ads.Ad, error) {
+	ad, err := scanAd(r.db.QueryRowContext(ctx,
+		"UPDATE ads SET deleted_at = ?, version = version + 1 "+
+			"WHERE id = ? AND deleted_at IS NULL RETURNING "+adColumns,
+		at.UnixNano(), adId))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, adrepo.ErrInvalidAdId
+	}
+	return ad, err
+}
+
+func (r *Repository) RestoreAd(ctx context.Context, adId int64) (*ads.Ad, error) {
+	ad, err := scanAd(r.db.QueryRowContext(ctx,
+		"UPDATE ads SET deleted_at = NULL, version = version + 1 "+
+			"WHERE id = ? AND deleted_at IS NOT NULL RETURNING "+adColumns,
+		adId))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, adrepo.ErrInvalidAdId
+	}
+	return ad, err
+}
+
+func (r *Repository) GetTrash(ctx context.Context, authorId int64) ([]*ads.Ad, error) {
+	return r.queryAds(ctx,
+		"SELECT "+adColumns+" FROM ads WHERE author_id = ? AND deleted_at IS NOT NULL ORDER BY deleted_at DESC, id",
+		authorId)
+}
+
+func (r *Repository) PurgeTrash(ctx context.Context, before time.Time) error {
+	_, err := r.db.ExecContext(ctx, "DELETE FROM ads WHERE deleted_at < ?", before.UnixNano())
+	return err
+}
+
+func (r *Repository) AddRevision(ctx context.Context, rev *ads.Revision) error {
+	changes, err := json.Marshal(rev.Changes)
+	if err != nil {
+		return err
+	}
+	row := r.db.QueryRowContext(ctx,
+		`INSERT INTO ad_revisions (ad_id, number, actor_id, created_at, title, text, state, changes, reason)
+		VALUES (?1, (SELECT COALESCE(MAX(number), 0) + 1 FROM ad_revisions WHERE ad_id = ?1), ?, ?, ?, ?, ?, ?, ?)
+		RETURNING number`,
+		rev.AdID, rev.ActorID, rev.CreatedAt.UnixNano(), rev.Title, rev.Text, rev.State, string(changes), rev.Reason)
+	return row.Scan(&rev.Number)
+}
+
+func (r *Repository) GetRevisions(ctx context.Context, adId int64) ([]*ads.Revision, error) {
+	return r.queryRevisions(ctx, "WHERE ad_id = ? ORDER BY number", adId)
+}
+
+func (r *Repository) queryRevisions(ctx context.Context, where string, args ...any) ([]*ads.Revision, error) {
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT ad_id, number, actor_id, created_at, title, text, state, changes, reason "+
+			"FROM ad_revisions "+where,
+		args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	resp := make([]*ads.Revision, 0)
+	for rows.Next() {
+		var (
+			rev       = &ads.Revision{}
+			createdAt int64
+			changes   string
+		)
+		err = rows.Scan(&rev.AdID, &rev.Number, &rev.ActorID, &createdAt, &rev.Title, &rev.Text, &rev.State,
+			&changes, &rev.Reason)
+		if err != nil {
+			return nil, err
+		}
+		rev.CreatedAt = time.Unix(0, createdAt).UTC()
+		if err = json.Unmarshal([]byte(changes), &rev.Changes); err != nil {
+			return nil, err
+		}
+		resp = append(resp, rev)
+	}
+	return resp, rows.Err()
+}
+
+func (r *Repository) GetRevisionsByActor(ctx context.Context, actorId int64) ([]*ads.Revision, error) {
+	return r.queryRevisions(ctx, "WHERE actor_id = ? ORDER BY ad_id, number", actorId)
+}
+
+func (r *Repository) EraseActor(ctx context.Context, actorId int64) error {
+	_, err := r.db.ExecContext(ctx, "UPDATE ad_revisions SET actor_id = ? WHERE actor_id = ?", ads.ErasedActor, actorId)
+	return err
+}
+
+func (r *Repository) AddCategory(ctx context.Context, c *ads.Category) (int64, error) {
+	row := r.db.QueryRowContext(ctx, "INSERT INTO categories (parent_id, name) VALUES (?, ?) RETURNING id",
+		c.ParentID, c.Name)
+	err := row.Scan(&c.ID)
+	if err != nil {
+		return 0, err
+	}
+	return c.ID, nil
+}
+
+func (r *Repository) GetCategory(ctx context.Context, id int64) (*ads.Category, error) {
+	c := &ads.Category{}
+	err := r.db.QueryRowContext(ctx, "SELECT id, parent_id, name FROM categories WHERE id = ?", id).
+		Scan(&c.ID, &c.ParentID, &c.Name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, adrepo.ErrInvalidCategoryId
+	}
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (r *Repository) GetCategories(ctx context.Context) ([]*ads.Category, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT id, parent_id, name FROM categories ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	resp := make([]*ads.Category, 0)
+	for rows.Next() {
+		c := &ads.Category{}
+		if err = rows.Scan(&c.ID, &c.ParentID, &c.Name); err != nil {
+			return nil, err
+		}
+		resp = append(resp, c)
+	}
+	return resp, rows.Err()
+}
+
+func (r *Repository) UpdateCategory(ctx context.Context, c *ads.Category) (*ads.Category, error) {
+	resp := &ads.Category{}
+	err := r.db.QueryRowContext(ctx,
+		"UPDATE categories SET parent_id = ?, name = ? WHERE id = ? RETURNING id, parent_id, name",
+		c.ParentID, c.Name, c.ID).Scan(&resp.ID, &resp.ParentID, &resp.Name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, adrepo.ErrInvalidCategoryId
+	}
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (r *Repository) DeleteCategory(ctx context.Context, id int64) error {
+	if _, err := r.GetCategory(ctx, id); err != nil {
+		return err
+	}
+	var inUse bool
+	err := r.db.QueryRowContext(ctx,
+		"SELECT EXISTS (SELECT 1 FROM ads WHERE category_id = ?1) OR "+
+			"EXISTS (SELECT 1 FROM categories WHERE parent_id = ?1)", id).Scan(&inUse)
+	if err != nil {
+		return err
+	}
+	if inUse {
+		return ads.ErrCategoryInUse
+	}
+	_, err = r.db.ExecContext(ctx, "DELETE FROM categories WHERE id = ?", id)
+	return err
+}
+
+func (r *Repository) CountAds(ctx context.Context, status ads.Status) (map[int64]int, error) {
+	counts := make(map[int64]int)
+	cond, arg, ok := statusCond(status)
+	if !ok {
+		return counts, nil
+	}
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT category_id, COUNT(*) FROM ads WHERE "+cond+" AND deleted_at IS NULL GROUP BY category_id",
+		arg)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int64
+		var n int
+		if err = rows.Scan(&id, &n); err != nil {
+			return nil, err
+		}
+		counts[id] = n
+	}
+	return counts, rows.Err()
+}
+
+func (r *Repository) AddImage(ctx context.Context, img *ads.Image) (int64, error) {
+	row := r.db.QueryRowContext(ctx,
+		`INSERT INTO ad_images (ad_id, position, content_type, size, width, height, created_at)
+		VALUES (?1, (SELECT COALESCE(MAX(position) + 1, 0) FROM ad_images WHERE ad_id = ?1), ?, ?, ?, ?, ?)
+		RETURNING id, position`,
+		img.AdID, img.ContentType, img.Size, img.Width, img.Height, img.CreatedAt.UnixNano())
+	err := row.Scan(&img.ID, &img.Position)
+	if err != nil {
+		return 0, err
+	}
+	return img.ID, nil
+}
+
+func (r *Repository) GetImages(ctx context.Context, adId int64) ([]*ads.Image, error) {
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT id, ad_id, position, content_type, size, width, height, created_at "+
+			"FROM ad_images WHERE ad_id = ? ORDER BY position, id",
+		adId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	resp := make([]*ads.Image, 0)
+	for rows.Next() {
+		var (
+			img       = &ads.Image{}
+			createdAt int64
+		)
+		err = rows.Scan(&img.ID, &img.AdID, &img.Position, &img.ContentType, &img.Size, &img.Width, &img.Height,
+			&createdAt)
+		if err != nil {
+			return nil, err
+		}
+		img.CreatedAt = time.Unix(0, createdAt).UTC()
+		resp = append(resp, img)
+	}
+	return resp, rows.Err()
+}
+
+func (r *Repository) ReorderImages(ctx context.Context, adId int64, order []int64) ([]*ads.Image, error) {
+	list, err := r.GetImages(ctx, adId)
+	if err != nil {
+		return nil, err
+	}
+	if err = ads.ReorderImages(list, order); err != nil {
+		return nil, err
+	}
+	for _, img := range list {
+		_, err = r.db.ExecContext(ctx, "UPDATE ad_images SET position = ? WHERE id = ?", img.Position, img.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return list, nil
+}
+
+func (r *Repository) DeleteImage(ctx context.Context, adId, imageId int64) error {
+	res, err := r.db.ExecContext(ctx, "DELETE FROM ad_images WHERE ad_id = ? AND id = ?", adId, imageId)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return adrepo.ErrInvalidImageId
+	}
+	return nil
+}
+
+const userColumns = "id, nickname, email, password, role, verified, session_version, display_name, bio, city, " +
+	"phone, city_visibility, phone_visibility, created_at"
+
+func scanUser(row interface{ Scan(...any) error }) (*user.User, error) {
+	var (
+		u         = &user.User{}
+		p         = &u.Profile
+		createdAt sql.NullInt64
+	)
+	err := row.Scan(&u.Id, &u.Nickname, &u.Email, &u.Password, &u.Role, &u.Verified, &u.SessionVersion,
+		&p.DisplayName, &p.Bio, &p.City, &p.Phone, &p.CityVisibility, &p.PhoneVisibility, &createdAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, userrepo.ErrInvalidUserId
+	}
+	if err != nil {
+		return nil, err
+	}
+	u.CreatedAt = fromNullTime(createdAt)
+	return u, nil
+}
+
+func (r *Repository) CreateUser(ctx context.Context, u *user.User) (int64, error) {
+	p := u.Profile
+	row := r.db.QueryRowContext(ctx,
+		`INSERT INTO users (id, nickname, email, password, role, verified, session_version, email_key, display_name,
+		bio, city, phone, city_visibility, phone_visibility, created_at, nickname_key)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?) RETURNING id`,
+		r.userIds.NextID(), u.Nickname, u.Email, u.Password, u.Role, u.Verified, u.SessionVersion, emailKey(u.Email),
+		p.DisplayName, p.Bio, p.City, p.Phone, p.CityVisibility, p.PhoneVisibility, nullTime(u.CreatedAt),
+		user.NicknameKey(u.Nickname))
+	err := row.Scan(&u.Id)
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+		return 0, user.ErrEmailTaken
+	}
+	if err != nil {
+		return 0, err
+	}
+	return u.Id, nil
+}
+
+// emailKey is NULL for an empty email, NULLs never collide in the unique
+// index.
+func emailKey(email string) sql.NullString {
+	key := user.EmailKey(email)
+	return sql.NullString{String: key, Valid: key != ""}
+}
+
+func (r *Repository) UpdateNick(ctx context.Context, id int64, nick string) (*user.User, error) {
+	return scanUser(r.db.QueryRowContext(ctx,
+		"UPDATE users SET nickname = ?, nickname_key = ? WHERE id = ? RETURNING "+userColumns,
+		nick, user.NicknameKey(nick), id))
+}
+
+func (r *Repository) UpdatePassword(ctx context.Context, id int64, pass string) (*user.User, error) {
+	return scanUser(r.db.QueryRowContext(ctx,
+		"UPDATE users SET password = ? WHERE id = ? RETURNING "+userColumns, pass, id))
+}
+
+func (r *Repository) UpdateRole(ctx context.Context, id int64, role user.Role) (*user.User, error) {
+	return scanUser(r.db.QueryRowContext(ctx,
+		"UPDATE users SET role = ? WHERE id = ? RETURNING "+userColumns, role, id))
+}
+
+func (r *Repository) UpdateVerified(ctx context.Context, id int64, verified bool) (*user.User, error) {
+	return scanUser(r.db.QueryRowContext(ctx,
+		"UPDATE users SET verified = ? WHERE id = ? RETURNING "+userColumns, verified, id))
+}
+
+func (r *Repository) UpdateProfile(ctx context.Context, id int64, p user.Profile) (*user.User, error) {
+	return scanUser(r.db.QueryRowContext(ctx,
+		`UPDATE users SET display_name = ?, bio = ?, city = ?, phone = ?, city_visibility = ?, phone_visibility = ?
+		WHERE id = ? RETURNING `+userColumns,
+		p.DisplayName, p.Bio, p.City, p.Phone, p.CityVisibility, p.PhoneVisibility, id))
+}
+
+func (r *Repository) RevokeSessions(ctx context.Context, id int64) (*user.User, error) {
+	return scanUser(r.db.QueryRowContext(ctx,
+		"UPDATE users SET session_version = session_version + 1 WHERE id = ? RETURNING "+userColumns, id))
+}
+
+// GetUserByEmail never finds the users with an empty email, their key is
+// NULL.
+func (r *Repository) GetUserByEmail(ctx context.Context, email string) (*user.User, error) {
+	return scanUser(r.db.QueryRowContext(ctx,
+		"SELECT "+userColumns+" FROM users WHERE email_key = ?", emailKey(email)))
+}
+
+// SearchUsers compares the prefix by characters, LIKE would need its
+// wildcards escaped and folds the case of ascii letters only.
+func (r *Repository) SearchUsers(ctx context.Context, search user.Search) ([]*user.User, error) {
+	after, ok, err := search.After()
+	if err != nil {
+		return nil, err
+	}
+	prefix := user.NicknameKey(search.Prefix)
+	query := "SELECT " + userColumns + " FROM users WHERE substr(nickname_key, 1, ?) = ?"
+	args := []any{utf8.RuneCountInString(prefix), prefix}
+	if ok {
+		query += " AND (nickname_key, id) > (?, ?)"
+		args = append(args, after.Nickname, after.Id)
+	}
+	query += " ORDER BY nickname_key, id"
+	if search.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, search.Limit)
+	}
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	resp := make([]*user.User, 0)
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		resp = append(resp, u)
+	}
+	return resp, rows.Err()
+}
+
+func (r *Repository) GetUser(ctx context.Context, id int64) (*user.User, error) {
+	return scanUser(r.db.QueryRowContext(ctx, "SELECT "+userColumns+" FROM users WHERE id = ?", id))
+}
+
+func (r *Repository) DeleteUser(ctx context.Context, id int64) error {
+	_, err := r.db.ExecContext(ctx, "DELETE FROM users WHERE id = ?", id)
+	return err
+}
+
+const apiKeyColumns = "id, user_id, name, hash, scopes, created_at, expires_at, last_used_at"
+
+func scanAPIKey(row interface{ Scan(...any) error }) (*user.APIKey, error) {
+	var (
+		key                   = &user.APIKey{}
+		scopes                string
+		createdAt             int64
+		expiresAt, lastUsedAt sql.NullInt64
+	)
+	err := row.Scan(&key.ID, &key.UserID, &key.Name, &key.Hash, &scopes, &createdAt, &expiresAt, &lastUsedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, userrepo.ErrInvalidAPIKeyId
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal([]byte(scopes), &key.Scopes); err != nil {
+		return nil, err
+	}
+	key.CreatedAt = time.Unix(0, createdAt).UTC()
+	key.ExpiresAt = fromNullTime(expiresAt)
+	key.LastUsedAt = fromNullTime(lastUsedAt)
+	return key, nil
+}
+
+// nullTime stores the zero time as NULL.
+func nullTime(t time.Time) sql.NullInt64 {
+	return sql.NullInt64{Int64: t.UnixNano(), Valid: !t.IsZero()}
+}
+
+func fromNullTime(t sql.NullInt64) time.Time {
+	if !t.Valid {
+		return time.Time{}
+	}
+	return time.Unix(0, t.Int64).UTC()
+}
+
+func (r *Repository) AddAPIKey(ctx context.Context, key *user.APIKey) error {
+	scopes, err := json.Marshal(key.Scopes)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.ExecContext(ctx, "INSERT INTO api_keys ("+apiKeyColumns+") VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+		key.ID, key.UserID, key.Name, key.Hash, string(scopes), key.CreatedAt.UnixNano(), nullTime(key.ExpiresAt),
+		nullTime(key.LastUsedAt))
+	return err
+}
+
+func (r *Repository) GetAPIKey(ctx context.Context, id string) (*user.APIKey, error) {
+	return scanAPIKey(r.db.QueryRowContext(ctx, "SELECT "+apiKeyColumns+" FROM api_keys WHERE id = ?", id))
+}
+
+func (r *Repository) GetAPIKeys(ctx context.Context, userId int64) ([]*user.APIKey, error) {
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT "+apiKeyColumns+" FROM api_keys WHERE user_id = ? ORDER BY created_at, id", userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	resp := make([]*user.APIKey, 0)
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		resp = append(resp, key)
+	}
+	return resp, rows.Err()
+}
+
+func (r *Repository) DeleteAPIKey(ctx context.Context, id string) error {
+	_, err := r.db.ExecContext(ctx, "DELETE FROM api_keys WHERE id = ?", id)
+	return err
+}
+
+func (r *Repository) TouchAPIKey(ctx context.Context, id string, at time.Time) error {
+	res, err := r.db.ExecContext(ctx, "UPDATE api_keys SET last_used_at = ? WHERE id = ?", at.UnixNano(), id)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return userrepo.ErrInvalidAPIKeyId
+	}
+	return nil
+}
+
+func today() string {
+	return time.Now().UTC().Format(time.DateOnly)
+}
@@ -0,0 +1,546 @@
+package sqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"homework10/internal/adapters/adrepo"
+	"homework10/internal/adapters/userrepo"
+	"homework10/internal/entities/ads"
+	"homework10/internal/entities/uow"
+	"homework10/internal/entities/user"
+	"homework10/pkg/idgen"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type RepoTest struct {
+	Name    string
+	Body    any
+	Expect  any
+	IsError bool
+}
+
+type AddBody struct {
+	Ad *ads.Ad
+}
+
+type DeleteAdBody struct {
+	adId int64
+}
+
+type GetByIdBody struct {
+	adId int64
+}
+
+type GetByTitleBody struct {
+	title string
+}
+
+type GetAllBody struct {
+	filters ads.Filters
+}
+
+type UpdateAdStateBody struct {
+	adId  int64
+	state ads.State
+}
+
+type UpdateTitleBody struct {
+	adId     int64
+	newTitle string
+	newText  string
+}
+
+type CreateUserBody struct {
+	user *user.User
+}
+
+type DeleteUserBody struct {
+	userId int64
+}
+
+type GetUserBody struct {
+	userId int64
+}
+
+type UpdatePasswordBody struct {
+	userId  int64
+	newPass string
+}
+
+type UpdateNickBody struct {
+	userId  int64
+	newNick string
+}
+
+func newTestRepo(t *testing.T) *Repository {
+	repo, err := Open(filepath.Join(t.TempDir(), "test.db"), idgen.NewSequence(), idgen.NewSequence())
+	assert.NoError(t, err)
+	t.Cleanup(func() {
+		_ = repo.Close()
+	})
+	ctx := context.Background()
+	_, err = repo.AddAd(ctx, &ads.Ad{Title: "test", CreationDate: "2023-04-28"})
+	assert.NoError(t, err)
+	_, err = repo.CreateUser(ctx, &user.User{Nickname: "nickname", Password: "pass", Email: "email"})
+	assert.NoError(t, err)
+	return repo
+}
+
+func TestRepository(t *testing.T) {
+	tests := []RepoTest{
+		{
+			Name: "add first ad ok", Body: AddBody{Ad: &ads.Ad{Title: "title"}},
+			Expect: int64(1),
+		},
+		{
+			Name: "delete ad ok", Body: DeleteAdBody{adId: 0},
+			Expect: nil,
+		},
+		{
+			Name: "get ad by id ok", Body: GetByIdBody{adId: 0},
+			Expect: &ads.Ad{Title: "test", ID: 0},
+		},
+		{
+			Name: "get ad by id error", Body: GetByIdBody{adId: 2},
+			Expect: adrepo.ErrInvalidAdId, IsError: true,
+		},
+		{
+			Name: "get ads by title ok", Body: GetByTitleBody{title: "te"},
+			Expect: []*ads.Ad{{Title: "test", ID: 0}},
+		},
+		{
+			Name: "get ads by title error", Body: GetByTitleBody{title: "kek"},
+			Expect: adrepo.ErrInvalidAdTitle, IsError: true,
+		},
+		{
+			Name: "get all unpublished ok", Body: GetAllBody{filters: ads.Filters{Status: ads.Unpublished, AuthorId: "0"}},
+			Expect: 1,
+		},
+		{
+			Name: "get all published ok", Body: GetAllBody{filters: ads.Filters{Status: ads.Published}},
+			Expect: 0,
+		},
+		{
+			Name: "get all by date ok", Body: GetAllBody{filters: ads.Filters{Status: ads.Unpublished, Date: "2023-04-28"}},
+			Expect: 1,
+		},
+		{
+			Name: "update ad status ok", Body: UpdateAdStateBody{state: ads.StatePublished, adId: 0},
+			Expect: &ads.Ad{Title: "test", ID: 0, State: ads.StatePublished},
+		},
+		{
+			Name: "update ad status error", Body: UpdateAdStateBody{state: ads.StatePublished, adId: 5},
+			Expect: adrepo.ErrInvalidAdId, IsError: true,
+		},
+		{
+			Name: "update ad title ok", Body: UpdateTitleBody{newTitle: "new title", newText: "new text", adId: 0},
+			Expect: &ads.Ad{Title: "new title", ID: 0, Text: "new text"},
+		},
+		{
+			Name: "create user ok", Body: CreateUserBody{user: &user.User{Password: "pass1", Email: "email1", Nickname: "nick1"}},
+			Expect: int64(1),
+		},
+		{
+			Name: "delete user ok", Body: DeleteUserBody{userId: 0},
+			Expect: nil,
+		},
+		{
+			Name: "update user nickname ok", Body: UpdateNickBody{userId: 0, newNick: "new nick"},
+			Expect: &user.User{Id: 0, Nickname: "new nick"},
+		},
+		{
+			Name: "update user password ok", Body: UpdatePasswordBody{userId: 0, newPass: "new pass"},
+			Expect: &user.User{Id: 0, Password: "new pass"},
+		},
+		{
+			Name: "get user ok", Body: GetUserBody{userId: 0},
+			Expect: &user.User{Id: 0, Nickname: "nickname"},
+		},
+		{
+			Name: "get user error", Body: GetUserBody{userId: -1},
+			Expect: userrepo.ErrInvalidUserId, IsError: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.Name, func(t *testing.T) {
+			repo := newTestRepo(t)
+			ctx := context.Background()
+			switch body := tc.Body.(type) {
+			case AddBody:
+				id, err := repo.AddAd(ctx, body.Ad)
+				assert.NoError(t, err)
+				assert.Equal(t, tc.Expect, id)
+			case DeleteAdBody:
+				err := repo.DeleteAd(ctx, body.adId)
+				assert.Nil(t, err)
+				_, err = repo.GetAdById(ctx, body.adId)
+				assert.ErrorIs(t, err, adrepo.ErrInvalidAdId)
+			case GetByIdBody:
+				ad, err := repo.GetAdById(ctx, body.adId)
+				if tc.IsError {
+					assert.ErrorIs(t, err, tc.Expect.(error))
+				} else {
+					assert.NoError(t, err)
+					assert.Equal(t, tc.Expect.(*ads.Ad).Title, ad.Title)
+				}
+			case GetByTitleBody:
+				adsArr, err := repo.GetAdsByTitle(ctx, body.title)
+				if tc.IsError {
+					assert.ErrorIs(t, err, tc.Expect.(error))
+				} else {
+					assert.Len(t, adsArr, 1)
+					assert.Equal(t, tc.Expect.([]*ads.Ad)[0].Title, adsArr[0].Title)
+				}
+			case GetAllBody:
+				adsArr, err := repo.GetAll(ctx, body.filters)
+				assert.NoError(t, err)
+				assert.Len(t, adsArr, tc.Expect.(int))
+			case UpdateAdStateBody:
+				ad, err := repo.UpdateAdState(ctx, body.adId, body.state, "")
+				if tc.IsError {
+					assert.ErrorIs(t, err, tc.Expect.(error))
+				} else {
+					assert.NoError(t, err)
+					assert.Equal(t, tc.Expect.(*ads.Ad).State, ad.State)
+					assert.NotEmpty(t, ad.UpdateDate)
+					assert.Equal(t, int64(1), ad.Version)
+				}
+			case UpdateTitleBody:
+				ad, err := repo.UpdateAdTitleAndText(ctx, body.adId, body.newTitle, body.newText)
+				assert.NoError(t, err)
+				assert.Equal(t, tc.Expect.(*ads.Ad).Text, ad.Text)
+				assert.Equal(t, tc.Expect.(*ads.Ad).Title, ad.Title)
+			case CreateUserBody:
+				id, err := repo.CreateUser(ctx, body.user)
+				assert.NoError(t, err)
+				assert.Equal(t, tc.Expect, id)
+			case DeleteUserBody:
+				err := repo.DeleteUser(ctx, body.userId)
+				assert.Nil(t, err)
+				_, err = repo.GetUser(ctx, body.userId)
+				assert.ErrorIs(t, err, userrepo.ErrInvalidUserId)
+			case GetUserBody:
+				u, err := repo.GetUser(ctx, body.userId)
+				if tc.IsError {
+					assert.ErrorIs(t, err, tc.Expect.(error))
+				} else {
+					assert.Equal(t, tc.Expect.(*user.User).Nickname, u.Nickname)
+				}
+			case UpdateNickBody:
+				u, err := repo.UpdateNick(ctx, body.userId, body.newNick)
+				assert.NoError(t, err)
+				assert.Equal(t, tc.Expect.(*user.User).Nickname, u.Nickname)
+			case UpdatePasswordBody:
+				u, err := repo.UpdatePassword(ctx, body.userId, body.newPass)
+				assert.NoError(t, err)
+				assert.Equal(t, tc.Expect.(*user.User).Password, u.Password)
+			}
+		})
+	}
+}
+
+func TestRepositoryPersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "persist.db")
+	ctx := context.Background()
+
+	repo, err := Open(path, idgen.NewSequence(), idgen.NewSequence())
+	assert.NoError(t, err)
+	id, err := repo.CreateUser(ctx, &user.User{Nickname: "nickname", Password: "pass", Email: "email"})
+	assert.NoError(t, err)
+	adId, err := repo.AddAd(ctx, &ads.Ad{Title: "title", Text: "text", AuthorID: id})
+	assert.NoError(t, err)
+	assert.NoError(t, repo.Close())
+
+	repo, err = Open(path, idgen.NewSequence(), idgen.NewSequence())
+	assert.NoError(t, err)
+	defer repo.Close()
+	u, err := repo.GetUser(ctx, id)
+	assert.NoError(t, err)
+	assert.Equal(t, "nickname", u.Nickname)
+	ad, err := repo.GetAdById(ctx, adId)
+	assert.NoError(t, err)
+	assert.Equal(t, "title", ad.Title)
+	assert.Equal(t, id, ad.AuthorID)
+}
+
+func TestRepositoryDoRollback(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := context.Background()
+	_, err := repo.AddAd(ctx, &ads.Ad{Title: "title", AuthorID: 0})
+	assert.NoError(t, err)
+
+	err = repo.Do(ctx, func(repos uow.Repositories) error {
+		assert.NoError(t, repos.Ads.DeleteAdsByAuthor(ctx, 0))
+		assert.NoError(t, repos.Users.DeleteUser(ctx, 0))
+		return userrepo.ErrInvalidUserId
+	})
+	assert.ErrorIs(t, err, userrepo.ErrInvalidUserId)
+	_, err = repo.GetUser(ctx, 0)
+	assert.NoError(t, err)
+	all, err := repo.GetAll(ctx, ads.Filters{Status: ads.Unpublished})
+	assert.NoError(t, err)
+	assert.Len(t, all, 2)
+
+	err = repo.Do(ctx, func(repos uow.Repositories) error {
+		err := repos.Ads.DeleteAdsByAuthor(ctx, 0)
+		if err != nil {
+			return err
+		}
+		return repos.Users.DeleteUser(ctx, 0)
+	})
+	assert.NoError(t, err)
+	_, err = repo.GetUser(ctx, 0)
+	assert.ErrorIs(t, err, userrepo.ErrInvalidUserId)
+	all, err = repo.GetAll(ctx, ads.Filters{Status: ads.Unpublished})
+	assert.NoError(t, err)
+	assert.Len(t, all, 0)
+}
+
+func TestRepositoryGetAllPages(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := context.Background()
+	for _, title := range []string{"d", "b", "test", "a"} {
+		_, err := repo.AddAd(ctx, &ads.Ad{Title: title, CreationDate: "2023-04-28"})
+		assert.NoError(t, err)
+	}
+
+	for _, f := range []ads.Filters{
+		{Status: ads.Unpublished, Limit: 2},
+		{Status: ads.Unpublished, Sort: ads.SortByTitle, Limit: 2},
+		{Status: ads.Unpublished, Sort: ads.SortByTitle, Desc: true, Limit: 3},
+		{Status: ads.Unpublished, Sort: ads.SortByCreationDate, Limit: 1},
+	} {
+		whole := f
+		whole.Limit = 0
+		expect, err := repo.GetAll(ctx, whole)
+		assert.NoError(t, err)
+		// the database orders the same way as the in-memory repositories
+		sorted := append([]*ads.Ad(nil), expect...)
+		sorted, err = whole.Page(sorted)
+		assert.NoError(t, err)
+		assert.Equal(t, sorted, expect)
+
+		var got []*ads.Ad
+		page, err := repo.GetAll(ctx, f)
+		assert.NoError(t, err)
+		for len(page) > 0 {
+			got = append(got, page...)
+			f.Cursor = f.NextCursor(page[len(page)-1])
+			page, err = repo.GetAll(ctx, f)
+			assert.NoError(t, err)
+		}
+		assert.Equal(t, expect, got)
+	}
+}
+
+func TestRepositoryTrash(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := context.Background()
+	deletedAt := time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	ad, err := repo.TrashAd(ctx, 0, deletedAt)
+	assert.NoError(t, err)
+	assert.Equal(t, deletedAt, ad.DeletedAt)
+	_, err = repo.TrashAd(ctx, 0, deletedAt)
+	assert.ErrorIs(t, err, adrepo.ErrInvalidAdId)
+	_, err = repo.GetAdById(ctx, 0)
+	assert.ErrorIs(t, err, adrepo.ErrInvalidAdId)
+	_, err = repo.GetAdsByTitle(ctx, "test")
+	assert.ErrorIs(t, err, adrepo.ErrInvalidAdTitle)
+	all, err := repo.GetAll(ctx, ads.Filters{Status: ads.Unpublished})
+	assert.NoError(t, err)
+	assert.Empty(t, all)
+	trash, err := repo.GetTrash(ctx, 0)
+	assert.NoError(t, err)
+	assert.Len(t, trash, 1)
+
+	ad, err = repo.RestoreAd(ctx, 0)
+	assert.NoError(t, err)
+	assert.False(t, ad.Trashed())
+	_, err = repo.RestoreAd(ctx, 0)
+	assert.ErrorIs(t, err, adrepo.ErrInvalidAdId)
+
+	_, err = repo.TrashAd(ctx, 0, deletedAt)
+	assert.NoError(t, err)
+	assert.NoError(t, repo.PurgeTrash(ctx, deletedAt))
+	trash, err = repo.GetTrash(ctx, 0)
+	assert.NoError(t, err)
+	assert.Len(t, trash, 1)
+	assert.NoError(t, repo.PurgeTrash(ctx, deletedAt.Add(time.Second)))
+	trash, err = repo.GetTrash(ctx, 0)
+	assert.NoError(t, err)
+	assert.Empty(t, trash)
+	_, err = repo.RestoreAd(ctx, 0)
+	assert.ErrorIs(t, err, adrepo.ErrInvalidAdId)
+}
+
+func TestRepositoryRevisions(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := context.Background()
+	at := time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	for _, title := range []string{"test", "second"} {
+		rev := &ads.Revision{AdID: 0, ActorID: 0, CreatedAt: at, Title: title}
+		assert.NoError(t, repo.AddRevision(ctx, rev))
+	}
+	rev := &ads.Revision{AdID: 0, ActorID: 0, CreatedAt: at.Add(time.Hour), Title: "second", State: ads.StatePublished,
+		Changes: []ads.Change{{Field: ads.FieldState, Old: "draft", New: "published"}}}
+	assert.NoError(t, repo.AddRevision(ctx, rev))
+	assert.Equal(t, int64(3), rev.Number)
+
+	revisions, err := repo.GetRevisions(ctx, 0)
+	assert.NoError(t, err)
+	assert.Len(t, revisions, 3)
+	assert.Equal(t, rev, revisions[2])
+	assert.Nil(t, revisions[0].Changes)
+
+	// the revisions are deleted together with the ad
+	assert.NoError(t, repo.DeleteAd(ctx, 0))
+	revisions, err = repo.GetRevisions(ctx, 0)
+	assert.NoError(t, err)
+	assert.Empty(t, revisions)
+}
+
+func TestRepositoryDoesNotReuseIds(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ids.db")
+	ctx := context.Background()
+
+	repo, err := Open(path, idgen.NewSequence(), idgen.NewSequence())
+	assert.NoError(t, err)
+	for i := 0; i < 2; i++ {
+		_, err = repo.AddAd(ctx, &ads.Ad{Title: "title"})
+		assert.NoError(t, err)
+		_, err = repo.CreateUser(ctx, &user.User{Nickname: "nickname"})
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, repo.DeleteAd(ctx, 1))
+	assert.NoError(t, repo.DeleteUser(ctx, 1))
+	assert.NoError(t, repo.Close())
+
+	// the deleted ids were the highest ones, a fresh sequence still skips them
+	repo, err = Open(path, idgen.NewSequence(), idgen.NewSequence())
+	assert.NoError(t, err)
+	defer repo.Close()
+	adId, err := repo.AddAd(ctx, &ads.Ad{Title: "title"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), adId)
+	userId, err := repo.CreateUser(ctx, &user.User{Nickname: "nickname"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), userId)
+}
+
+func TestRepositoryMigratesEmails(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "emails.db")
+	ctx := context.Background()
+
+	// a database left by the version before the emails were unique
+	db, err := sql.Open("sqlite3", path)
+	assert.NoError(t, err)
+	// the eighth migration made them unique
+	before := 7
+	for _, m := range migrations[:before] {
+		_, err = db.ExecContext(ctx, m)
+		assert.NoError(t, err)
+	}
+	_, err = db.ExecContext(ctx, fmt.Sprintf("PRAGMA user_version = %d", before))
+	assert.NoError(t, err)
+	_, err = db.ExecContext(ctx, `INSERT INTO users (id, nickname, email, password)
+		VALUES (0, 'old', ' Old@Mail.com', 'hash'), (1, 'no email', '', 'hash'),
+			(2, 'umlaut', 'Ünal@Mail.com', 'hash')`)
+	assert.NoError(t, err)
+	assert.NoError(t, db.Close())
+
+	repo, err := Open(path, idgen.NewSequence(), idgen.NewSequence())
+	assert.NoError(t, err)
+	defer repo.Close()
+	old, err := repo.GetUser(ctx, 0)
+	assert.NoError(t, err)
+	assert.False(t, old.Verified)
+	_, err = repo.CreateUser(ctx, &user.User{Nickname: "new", Email: "old@mail.com"})
+	assert.ErrorIs(t, err, user.ErrEmailTaken)
+	_, err = repo.CreateUser(ctx, &user.User{Nickname: "new", Email: "ünal@mail.com"})
+	assert.ErrorIs(t, err, user.ErrEmailTaken)
+	_, err = repo.CreateUser(ctx, &user.User{Nickname: "new"})
+	assert.NoError(t, err)
+}
+
+func TestRepositoryMigratesNicknames(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nicknames.db")
+	ctx := context.Background()
+
+	// a database left by the version before the nicknames could be searched,
+	// the twelfth migration added their keys
+	db, err := sql.Open("sqlite3", path)
+	assert.NoError(t, err)
+	before := 11
+	for _, m := range migrations[:before] {
+		_, err = db.ExecContext(ctx, m)
+		assert.NoError(t, err)
+	}
+	_, err = db.ExecContext(ctx, fmt.Sprintf("PRAGMA user_version = %d", before))
+	assert.NoError(t, err)
+	_, err = db.ExecContext(ctx, `INSERT INTO users (id, nickname, email, password)
+		VALUES (0, 'Ünal', '', 'hash'), (1, 'Other', '', 'hash')`)
+	assert.NoError(t, err)
+	assert.NoError(t, db.Close())
+
+	repo, err := Open(path, idgen.NewSequence(), idgen.NewSequence())
+	assert.NoError(t, err)
+	defer repo.Close()
+	list, err := repo.SearchUsers(ctx, user.Search{Prefix: "ün", Limit: 10})
+	assert.NoError(t, err)
+	if assert.Len(t, list, 1) {
+		assert.Equal(t, int64(0), list[0].Id)
+	}
+}
+
+func TestRepositoryMigratesStates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "states.db")
+	ctx := context.Background()
+
+	// a database left by the version before the ads had states, the last
+	// migration replaced published
+	db, err := sql.Open("sqlite3", path)
+	assert.NoError(t, err)
+	before := len(migrations) - 1
+	for _, m := range migrations[:before] {
+		_, err = db.ExecContext(ctx, m)
+		assert.NoError(t, err)
+	}
+	_, err = db.ExecContext(ctx, fmt.Sprintf("PRAGMA user_version = %d", before))
+	assert.NoError(t, err)
+	_, err = db.ExecContext(ctx, `INSERT INTO ads (id, title, text, author_id, creation_date, published)
+		VALUES (0, 'up', 'text', 0, '2023-05-01', 1), (1, 'down', 'text', 0, '2023-05-01', 0)`)
+	assert.NoError(t, err)
+	_, err = db.ExecContext(ctx, `INSERT INTO ad_revisions (ad_id, number, actor_id, created_at, title, text, published, changes)
+		VALUES (0, 1, 0, 0, 'up', 'text', 0, '[]'), (0, 2, 0, 0, 'up', 'text', 1, '[]')`)
+	assert.NoError(t, err)
+	assert.NoError(t, db.Close())
+
+	repo, err := Open(path, idgen.NewSequence(), idgen.NewSequence())
+	assert.NoError(t, err)
+	defer repo.Close()
+	up, err := repo.GetAdById(ctx, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, ads.StatePublished, up.State)
+	down, err := repo.GetAdById(ctx, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, ads.StateDraft, down.State)
+	revisions, err := repo.GetRevisions(ctx, 0)
+	assert.NoError(t, err)
+	if assert.Len(t, revisions, 2) {
+		assert.Equal(t, ads.StateDraft, revisions[0].State)
+		assert.Equal(t, ads.StatePublished, revisions[1].State)
+	}
+
+	list, err := repo.GetAll(ctx, ads.Filters{Status: ads.Unpublished})
+	assert.NoError(t, err)
+	assert.Len(t, list, 1)
+	rejected, err := repo.UpdateAdState(ctx, 0, ads.StateRejected, "spam")
+	assert.NoError(t, err)
+	assert.Equal(t, "spam", rejected.RejectionReason)
+}
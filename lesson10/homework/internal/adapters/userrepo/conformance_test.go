@@ -0,0 +1,15 @@
+package userrepo_test
+
+import (
+	"homework10/internal/adapters/userrepo"
+	"homework10/internal/entities/user"
+	"homework10/internal/entities/user/usertest"
+	"homework10/pkg/idgen"
+	"testing"
+)
+
+func TestConformance(t *testing.T) {
+	usertest.RunRepositoryTests(t, func(t *testing.T) user.Repository {
+		return userrepo.New(idgen.NewSequence())
+	})
+}
@@ -4,62 +4,326 @@ import (
 	"context"
 	"errors"
 	"homework10/internal/entities/user"
+	"homework10/pkg/idgen"
 	"sync"
+	"time"
 )
 
 var (
-	ErrInvalidUserId = errors.New("cant find this id in map")
+	ErrInvalidUserId   = errors.New("cant find this id in map")
+	ErrInvalidAPIKeyId = errors.New("cant find this api key")
 )
 
+type store struct {
+	mu           *sync.RWMutex
+	userDataById map[int64]*user.User
+	keysById     map[string]*user.APIKey
+	ids          idgen.IDGenerator
+}
+
+// repository is a view of the store, the one handed to a transaction keeps
+// an undo log of its writes.
 type repository struct {
-	mu             *sync.RWMutex
-	userDataById   map[int64]*user.User
-	curIdGenerator int64
+	*store
+	undo *undoLog
 }
 
-func New() user.Repository {
-	return &repository{userDataById: make(map[int64]*user.User), curIdGenerator: 0, mu: &sync.RWMutex{}}
+func New(ids idgen.IDGenerator) user.Repository {
+	return &repository{store: &store{userDataById: make(map[int64]*user.User),
+		keysById: make(map[string]*user.APIKey), ids: ids, mu: &sync.RWMutex{}}}
 }
 
 func NewForTest(r map[int64]*user.User, idGen int64) user.Repository {
-	return &repository{userDataById: r, curIdGenerator: idGen, mu: &sync.RWMutex{}}
+	ids := idgen.NewSequence()
+	ids.Resume(idGen - 1)
+	return &repository{store: &store{userDataById: r, keysById: make(map[string]*user.APIKey), ids: ids,
+		mu: &sync.RWMutex{}}}
 }
 
-func (r *repository) CreateUser(ctx context.Context, user *user.User) (int64, error) {
-	r.mu.Lock()
-	user.Id = r.curIdGenerator
-	r.userDataById[r.curIdGenerator] = user
-	r.curIdGenerator++
-	r.mu.Unlock()
-	return user.Id, nil
+func cloneUser(u *user.User) *user.User {
+	c := *u
+	return &c
 }
-func (r *repository) UpdateNick(ctx context.Context, id int64, nick string) (*user.User, error) {
+
+func (r *repository) CreateUser(ctx context.Context, u *user.User) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	r.userDataById[id].Nickname = nick
-	return r.userDataById[id], nil
+	if EmailTaken(r.userDataById, u.Email) {
+		return 0, user.ErrEmailTaken
+	}
+	u.Id = r.ids.NextID()
+	r.saveUser(u.Id)
+	r.userDataById[u.Id] = cloneUser(u)
+	return u.Id, nil
 }
-func (r *repository) UpdatePassword(ctx context.Context, id int64, pass string) (*user.User, error) {
+
+// EmailTaken tells whether one of the users has the email, in any case.
+func EmailTaken(users map[int64]*user.User, email string) bool {
+	_, ok := FindByEmail(users, email)
+	return ok
+}
+
+// FindByEmail returns the user with the email, in any case.
+func FindByEmail(users map[int64]*user.User, email string) (*user.User, bool) {
+	key := user.EmailKey(email)
+	if key == "" {
+		return nil, false
+	}
+	for _, u := range users {
+		if user.EmailKey(u.Email) == key {
+			return u, true
+		}
+	}
+	return nil, false
+}
+
+func (r *repository) updateUser(ctx context.Context, id int64, change func(u *user.User)) (*user.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	r.userDataById[id].Password = pass
-	return r.userDataById[id], nil
+	u, ok := r.userDataById[id]
+	if !ok {
+		return nil, ErrInvalidUserId
+	}
+	r.saveUser(id)
+	u = cloneUser(u)
+	change(u)
+	r.userDataById[id] = u
+	return cloneUser(u), nil
+}
+
+func (r *repository) UpdateNick(ctx context.Context, id int64, nick string) (*user.User, error) {
+	return r.updateUser(ctx, id, func(u *user.User) {
+		u.Nickname = nick
+	})
+}
+
+func (r *repository) UpdatePassword(ctx context.Context, id int64, pass string) (*user.User, error) {
+	return r.updateUser(ctx, id, func(u *user.User) {
+		u.Password = pass
+	})
+}
+
+func (r *repository) UpdateRole(ctx context.Context, id int64, role user.Role) (*user.User, error) {
+	return r.updateUser(ctx, id, func(u *user.User) {
+		u.Role = role
+	})
+}
+
+func (r *repository) UpdateVerified(ctx context.Context, id int64, verified bool) (*user.User, error) {
+	return r.updateUser(ctx, id, func(u *user.User) {
+		u.Verified = verified
+	})
+}
+
+func (r *repository) UpdateProfile(ctx context.Context, id int64, profile user.Profile) (*user.User, error) {
+	return r.updateUser(ctx, id, func(u *user.User) {
+		u.Profile = profile
+	})
+}
+
+func (r *repository) RevokeSessions(ctx context.Context, id int64) (*user.User, error) {
+	return r.updateUser(ctx, id, func(u *user.User) {
+		u.SessionVersion++
+	})
+}
+
+func (r *repository) GetUserByEmail(ctx context.Context, email string) (*user.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	u, ok := FindByEmail(r.userDataById, email)
+	if !ok {
+		return nil, ErrInvalidUserId
+	}
+	return cloneUser(u), nil
+}
+
+func (r *repository) SearchUsers(ctx context.Context, search user.Search) ([]*user.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return SearchUsers(r.userDataById, search)
+}
+
+// SearchUsers returns copies of the users on the page of the search.
+func SearchUsers(users map[int64]*user.User, search user.Search) ([]*user.User, error) {
+	list := make([]*user.User, 0, len(users))
+	for _, u := range users {
+		list = append(list, u)
+	}
+	page, err := search.Page(list)
+	if err != nil {
+		return nil, err
+	}
+	for i, u := range page {
+		page[i] = cloneUser(u)
+	}
+	return page, nil
 }
 
 func (r *repository) GetUser(ctx context.Context, id int64) (*user.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	r.mu.RLock()
+	defer r.mu.RUnlock()
 	data, ok := r.userDataById[id]
-	r.mu.RUnlock()
 	if !ok {
 		return nil, ErrInvalidUserId
 	}
-	return data, nil
+	return cloneUser(data), nil
 }
 
 func (r *repository) DeleteUser(ctx context.Context, id int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	r.mu.Lock()
+	r.saveUser(id)
 	delete(r.userDataById, id)
-	r.curIdGenerator--
+	for keyId, key := range r.keysById {
+		if key.UserID == id {
+			r.saveKey(keyId)
+			delete(r.keysById, keyId)
+		}
+	}
+	r.mu.Unlock()
+	return nil
+}
+
+func (r *repository) AddAPIKey(ctx context.Context, key *user.APIKey) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.saveKey(key.ID)
+	r.keysById[key.ID] = user.CloneAPIKey(key)
+	return nil
+}
+
+func (r *repository) GetAPIKey(ctx context.Context, id string) (*user.APIKey, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	key, ok := r.keysById[id]
+	if !ok {
+		return nil, ErrInvalidAPIKeyId
+	}
+	return user.CloneAPIKey(key), nil
+}
+
+func (r *repository) GetAPIKeys(ctx context.Context, userId int64) ([]*user.APIKey, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	resp := make([]*user.APIKey, 0)
+	for _, key := range r.keysById {
+		if key.UserID == userId {
+			resp = append(resp, user.CloneAPIKey(key))
+		}
+	}
+	user.SortAPIKeys(resp)
+	return resp, nil
+}
+
+func (r *repository) DeleteAPIKey(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	r.saveKey(id)
+	delete(r.keysById, id)
 	r.mu.Unlock()
 	return nil
 }
+
+func (r *repository) TouchAPIKey(ctx context.Context, id string, at time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key, ok := r.keysById[id]
+	if !ok {
+		return ErrInvalidAPIKeyId
+	}
+	r.saveKey(id)
+	key = user.CloneAPIKey(key)
+	key.LastUsedAt = at
+	r.keysById[id] = key
+	return nil
+}
+
+// Begin returns a view that logs what its writes replace and a function
+// that puts it back, the writes made outside the view are kept.
+func (r *repository) Begin() (user.Repository, func()) {
+	tx := &repository{store: r.store, undo: &undoLog{}}
+	return tx, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		tx.undo.rollback()
+	}
+}
+
+// undoLog holds the steps that undo the writes of a transaction, in the order
+// the writes were made.
+type undoLog struct {
+	steps []func()
+}
+
+func (u *undoLog) add(step func()) {
+	u.steps = append(u.steps, step)
+}
+
+func (u *undoLog) rollback() {
+	for i := len(u.steps) - 1; i >= 0; i-- {
+		u.steps[i]()
+	}
+	u.steps = nil
+}
+
+// The save methods must be called with the lock held, before the write. The
+// stored values are never changed in place, so keeping them is enough.
+
+func (r *repository) saveUser(id int64) {
+	if r.undo == nil {
+		return
+	}
+	u, ok := r.userDataById[id]
+	r.undo.add(func() {
+		if ok {
+			r.userDataById[id] = u
+		} else {
+			delete(r.userDataById, id)
+		}
+	})
+}
+
+func (r *repository) saveKey(id string) {
+	if r.undo == nil {
+		return
+	}
+	key, ok := r.keysById[id]
+	r.undo.add(func() {
+		if ok {
+			r.keysById[id] = key
+		} else {
+			delete(r.keysById, id)
+		}
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"github.com/stretchr/testify/assert"
 	"homework10/internal/entities/user"
+	"homework10/pkg/idgen"
 	"testing"
 )
 
@@ -98,3 +99,17 @@ func TestUserRepo(t *testing.T) {
 		})
 	}
 }
+
+func TestUserRepositoryDoesNotReuseIds(t *testing.T) {
+	ctx := context.Background()
+	repo := New(idgen.NewSequence())
+	for i := 0; i < 2; i++ {
+		_, err := repo.CreateUser(ctx, &user.User{Nickname: "nick"})
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, repo.DeleteUser(ctx, 1))
+
+	id, err := repo.CreateUser(ctx, &user.User{Nickname: "new"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), id)
+}
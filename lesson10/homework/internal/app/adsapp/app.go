@@ -3,9 +3,12 @@ package adsapp
 import (
 	"context"
 	"errors"
+	"homework10/internal/adapters/adrepo"
 	"homework10/internal/adapters/userrepo"
 	"homework10/internal/entities/ads"
-	"homework10/internal/entities/user"
+	"homework10/internal/entities/uow"
+	"homework10/pkg/blob"
+	"strings"
 	"time"
 )
 
@@ -14,124 +17,455 @@ var (
 )
 
 type App interface {
-	CreateAd(ctx context.Context, title, text string, id int64) (*ads.Ad, error)
+	CreateAd(ctx context.Context, title, text string, price ads.Money, categoryId, userId int64) (*ads.Ad, error)
 	GetAdById(ctx context.Context, id int64) (*ads.Ad, error)
 	GetAdsByTitle(ctx context.Context, title string) ([]*ads.Ad, error)
-	GetAll(ctx context.Context, filters ads.Filters) ([]*ads.Ad, error)
+	GetAll(ctx context.Context, filters ads.Filters) ([]*ads.Ad, string, error)
+	SearchAds(ctx context.Context, query string) ([]*ads.Ad, error)
 	ChangeAdStatus(ctx context.Context, adId, userId int64, newStatus bool) (*ads.Ad, error)
-	UpdateAd(ctx context.Context, adId, userId int64, title, text string) (*ads.Ad, error)
-	DeleteAd(ctx context.Context, adId, userId int64) error
+	UnpublishAd(ctx context.Context, adId, userId int64, reason string) (*ads.Ad, error)
+	ChangeAdState(ctx context.Context, adId, userId int64, state ads.State, reason string) (*ads.Ad, error)
+	UpdateAd(ctx context.Context, adId, userId int64, title, text string, version int64) (*ads.Ad, error)
+	DeleteAd(ctx context.Context, adId, userId int64, reason string) error
+	RestoreAd(ctx context.Context, adId, userId int64) (*ads.Ad, error)
+	GetTrash(ctx context.Context, userId int64) ([]*ads.Ad, error)
+	PurgeTrash(ctx context.Context, olderThan time.Duration) error
+	GetRevisions(ctx context.Context, adId int64) ([]*ads.Revision, error)
+	GetAdAtRevision(ctx context.Context, adId, revision int64) (*ads.Ad, error)
+	RevertAd(ctx context.Context, adId, userId, revision int64) (*ads.Ad, error)
+	ListCategories(ctx context.Context) ([]*ads.CategoryCount, error)
+	GetCategory(ctx context.Context, id int64) (*ads.CategoryCount, error)
+	CreateCategory(ctx context.Context, actorId int64, name string, parentId int64) (*ads.Category, error)
+	UpdateCategory(ctx context.Context, actorId, id int64, name string, parentId int64) (*ads.Category, error)
+	DeleteCategory(ctx context.Context, actorId, id int64) error
+	AddImage(ctx context.Context, adId, userId int64, contentType string, data []byte) (*ads.Image, error)
+	GetImages(ctx context.Context, adId int64) ([]*ads.Image, error)
+	GetImageFile(ctx context.Context, adId, imageId int64, variant ads.ImageVariant) (*ads.Image, []byte, error)
+	ReorderImages(ctx context.Context, adId, userId int64, order []int64) ([]*ads.Image, error)
+	DeleteImage(ctx context.Context, adId, userId, imageId int64) error
+	ScheduleAd(ctx context.Context, adId, userId int64, publishAt, expiresAt time.Time) (*ads.Ad, error)
+	RenewAd(ctx context.Context, adId, userId int64) (*ads.Ad, error)
+	ApplySchedule(ctx context.Context) (int, error)
 }
 
 type app struct {
-	repo     ads.Repository
-	userRepo user.Repository
+	tx    uow.UnitOfWork
+	index ads.SearchIndex
+	// blobs keeps the files of the images
+	blobs blob.Store
+	now   func() time.Time
 }
 
-func NewApp(repo ads.Repository, userRepo user.Repository) App {
-	return app{repo: repo, userRepo: userRepo}
+func NewApp(tx uow.UnitOfWork, index ads.SearchIndex, blobs blob.Store) App {
+	return app{tx: tx, index: index, blobs: blobs, now: time.Now}
 }
 
-func (a app) CreateAd(ctx context.Context, title, text string, userId int64) (*ads.Ad, error) {
-	_, err := a.userRepo.GetUser(ctx, userId)
-	if err != nil {
-		return nil, err
-	}
+// CreateAd fails with ads.ErrUnknownCategory if there is no such category
+// and with ads.ErrInvalidPrice if the price is wrong.
+func (a app) CreateAd(ctx context.Context, title, text string, price ads.Money, categoryId, userId int64) (*ads.Ad, error) {
 	t := time.Now().UTC()
 	ad := &ads.Ad{
 		Title:        title,
 		Text:         text,
 		AuthorID:     userId,
-		Published:    false,
+		CategoryID:   categoryId,
+		Price:        price,
+		State:        ads.StateDraft,
 		CreationDate: t.Format(time.DateOnly),
+		Version:      1,
 	}
-	err = ads.ValidateAd(ad)
-	if err != nil {
-		return nil, ads.ErrInvalidAdParams
-	}
-	id, err := a.repo.AddAd(ctx, ad)
+	err := a.tx.Do(ctx, func(repos uow.Repositories) error {
+		_, err := repos.Users.GetUser(ctx, userId)
+		if err != nil {
+			return err
+		}
+		err = ads.ValidateAd(ad)
+		if errors.Is(err, ads.ErrInvalidPrice) {
+			return err
+		}
+		if err != nil {
+			return ads.ErrInvalidAdParams
+		}
+		if err = checkCategory(ctx, repos.Categories, categoryId); err != nil {
+			return err
+		}
+		id, err := repos.Ads.AddAd(ctx, ad)
+		if err != nil {
+			return err
+		}
+		ad.ID = id
+		return repos.Revisions.AddRevision(ctx, ads.NewRevision(nil, ad, userId, t))
+	})
 	if err != nil {
 		return nil, err
 	}
-	ad.ID = id
+	a.index.Put(ad)
 	return ad, nil
 }
 
 func (a app) GetAdById(ctx context.Context, id int64) (*ads.Ad, error) {
-	return a.repo.GetAdById(ctx, id)
+	return a.tx.Repositories().Ads.GetAdById(ctx, id)
 }
 
 func (a app) GetAdsByTitle(ctx context.Context, title string) ([]*ads.Ad, error) {
-	return a.repo.GetAdsByTitle(ctx, title)
+	return a.tx.Repositories().Ads.GetAdsByTitle(ctx, title)
 }
 
-func (a app) GetAll(ctx context.Context, filters ads.Filters) ([]*ads.Ad, error) {
+// GetAll returns one page of the matching ads and the cursor of the next
+// page, which is empty on the last one.
+func (a app) GetAll(ctx context.Context, filters ads.Filters) ([]*ads.Ad, string, error) {
 	err := filters.ValidateFilters()
 	if err != nil {
-		return nil, err
+		return nil, "", err
+	}
+	repos := a.tx.Repositories()
+	if filters.CategoryId != 0 {
+		list, err := repos.Categories.GetCategories(ctx)
+		if err != nil {
+			return nil, "", err
+		}
+		if !hasCategory(list, filters.CategoryId) {
+			return nil, "", ads.ErrInvalidFilters
+		}
+		filters.Categories = ads.Subtree(list, filters.CategoryId)
 	}
-	return a.repo.GetAll(ctx, filters)
+	// one ad more than asked for tells whether there is a next page
+	limit := filters.Limit
+	if limit > 0 {
+		filters.Limit++
+	}
+	list, err := repos.Ads.GetAll(ctx, filters)
+	if err != nil {
+		return nil, "", err
+	}
+	if limit == 0 || len(list) <= limit {
+		return list, "", nil
+	}
+	list = list[:limit]
+	return list, filters.NextCursor(list[limit-1]), nil
 }
 
-func (a app) ChangeAdStatus(ctx context.Context, adId, userId int64, newStatus bool) (*ads.Ad, error) {
-	_, err := a.userRepo.GetUser(ctx, userId)
-	if err != nil {
-		return nil, err
+// SearchAds returns the published ads matching the query, the most relevant
+// first.
+func (a app) SearchAds(ctx context.Context, query string) ([]*ads.Ad, error) {
+	if strings.TrimSpace(query) == "" {
+		return nil, ads.ErrEmptySearchQuery
 	}
-	ad, err := a.repo.GetAdById(ctx, adId)
-	if err != nil {
-		return nil, err
+	ids := a.index.Search(query)
+	repo := a.tx.Repositories().Ads
+	resp := make([]*ads.Ad, 0, len(ids))
+	for _, id := range ids {
+		ad, err := repo.GetAdById(ctx, id)
+		if errors.Is(err, adrepo.ErrInvalidAdId) {
+			// the ad went away without passing through this app, e.g.
+			// together with its author
+			a.index.Remove(id)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if ad.Published() {
+			resp = append(resp, ad)
+		}
 	}
-	if ad.AuthorID != userId {
-		return nil, ads.ErrUserCantChangeThisAd
+	return resp, nil
+}
+
+// ChangeAdStatus publishes the ad, or takes it down as UnpublishAd does.
+func (a app) ChangeAdStatus(ctx context.Context, adId, userId int64, newStatus bool) (*ads.Ad, error) {
+	if !newStatus {
+		return a.UnpublishAd(ctx, adId, userId, "")
 	}
-	if ad.Published == newStatus {
-		return ad, nil
+	return a.ChangeAdState(ctx, adId, userId, ads.StatePublished, "")
+}
+
+// UnpublishAd takes the published ad down, to a draft by its author or
+// rejected by a moderator, who has to give a reason.
+func (a app) UnpublishAd(ctx context.Context, adId, userId int64, reason string) (*ads.Ad, error) {
+	return a.changeState(ctx, adId, userId, reason, func(ad *ads.Ad) ads.State {
+		switch {
+		case !ad.Published():
+			return ad.State
+		case ad.AuthorID == userId:
+			return ads.StateDraft
+		}
+		return ads.StateRejected
+	})
+}
+
+// ChangeAdState moves the ad to the state if it can get there and the user
+// may move it there, only verified authors publish.
+func (a app) ChangeAdState(ctx context.Context, adId, userId int64, state ads.State, reason string) (*ads.Ad, error) {
+	if !state.Valid() {
+		return nil, ads.ErrInvalidState
 	}
+	return a.changeState(ctx, adId, userId, reason, func(*ads.Ad) ads.State {
+		return state
+	})
+}
 
-	t := time.Now().UTC()
-	ad.UpdateDate = t.Format(time.DateOnly)
+// changeState moves the ad to the state target picks for it.
+func (a app) changeState(ctx context.Context, adId, userId int64, reason string, target func(ad *ads.Ad) ads.State) (*ads.Ad, error) {
+	var ad *ads.Ad
+	err := a.tx.Do(ctx, func(repos uow.Repositories) error {
+		actor, err := repos.Users.GetUser(ctx, userId)
+		if err != nil {
+			return err
+		}
+		ad, err = repos.Ads.GetAdById(ctx, adId)
+		if err != nil {
+			return err
+		}
+		to := target(ad)
+		if err = authorizeTransition(userId, actor, ad, to, reason); err != nil {
+			return err
+		}
+		if err = authorizePublishing(userId, actor, ad, to); err != nil {
+			return err
+		}
+		if ad.State == to {
+			return nil
+		}
+
+		prev := *ad
+		t := time.Now().UTC()
+		ad, err = moveAd(ctx, repos, ad, to, reason, t)
+		if err != nil {
+			return err
+		}
+		rev := ads.NewRevision(&prev, ad, userId, t)
+		rev.Reason = reason
+		return repos.Revisions.AddRevision(ctx, rev)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ad, nil
+}
 
-	return a.repo.UpdateAdStatus(ctx, adId, newStatus)
+// moveAd stores the ad in the state. Publishing cancels the scheduled
+// publication of the ad, it expires as ads.Expiration tells.
+func moveAd(ctx context.Context, repos uow.Repositories, ad *ads.Ad, to ads.State, rejectionReason string, t time.Time) (*ads.Ad, error) {
+	if to == ads.StatePublished {
+		return repos.Schedule.UpdateAdPublication(ctx, ad.ID, to, time.Time{}, ads.Expiration(ad.ExpiresAt, t))
+	}
+	return repos.Ads.UpdateAdState(ctx, ad.ID, to, rejectionReason)
 }
 
-func (a app) UpdateAd(ctx context.Context, adId, userId int64, newTitle, newText string) (*ads.Ad, error) {
+// UpdateAd fails with ads.ErrVersionMismatch if the ad is no longer at the
+// given version, a zero version skips the check.
+func (a app) UpdateAd(ctx context.Context, adId, userId int64, newTitle, newText string, version int64) (*ads.Ad, error) {
 	err := ads.ValidateAd(&ads.Ad{Title: newTitle, Text: newText})
 	if err != nil {
 		return nil, ads.ErrInvalidAdParams
 	}
-	_, err = a.userRepo.GetUser(ctx, userId)
+	var ad *ads.Ad
+	err = a.tx.Do(ctx, func(repos uow.Repositories) error {
+		actor, err := repos.Users.GetUser(ctx, userId)
+		if err != nil {
+			return err
+		}
+		ad, err = repos.Ads.GetAdById(ctx, adId)
+		if err != nil {
+			return err
+		}
+		if err = authorize(userId, actor, ad, actionEdit, ""); err != nil {
+			return err
+		}
+		if version != 0 && ad.Version != version {
+			return ads.ErrVersionMismatch
+		}
+		if ad.Text == newText && ad.Title == newTitle {
+			return nil
+		}
+
+		prev := *ad
+		t := time.Now().UTC()
+		ad.UpdateDate = t.Format(time.DateOnly)
+
+		ad, err = repos.Ads.UpdateAdTitleAndText(ctx, adId, newTitle, newText)
+		if err != nil {
+			return err
+		}
+		return repos.Revisions.AddRevision(ctx, ads.NewRevision(&prev, ad, userId, t))
+	})
 	if err != nil {
 		return nil, err
 	}
-	ad, err := a.repo.GetAdById(ctx, adId)
+	a.index.Put(ad)
+	return ad, nil
+}
+
+// DeleteAd moves the ad to the trash of its author, a moderator deleting the
+// ad of another user has to give a reason.
+func (a app) DeleteAd(ctx context.Context, adId, userID int64, reason string) error {
+	err := a.tx.Do(ctx, func(repos uow.Repositories) error {
+		ad, err := repos.Ads.GetAdById(ctx, adId)
+		if err != nil {
+			return err
+		}
+		actor, err := repos.Users.GetUser(ctx, userID)
+		if err != nil {
+			return userrepo.ErrInvalidUserId
+		}
+		if err = authorize(userID, actor, ad, actionDelete, reason); err != nil {
+			return err
+		}
+		t := time.Now().UTC()
+		if ad.AuthorID != userID {
+			rev := ads.NewRevision(ad, ad, userID, t)
+			rev.Reason = reason
+			if err = repos.Revisions.AddRevision(ctx, rev); err != nil {
+				return err
+			}
+		}
+		_, err = repos.Ads.TrashAd(ctx, adId, t)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	a.index.Remove(adId)
+	return nil
+}
+
+func (a app) RestoreAd(ctx context.Context, adId, userId int64) (*ads.Ad, error) {
+	var ad *ads.Ad
+	err := a.tx.Do(ctx, func(repos uow.Repositories) error {
+		_, err := repos.Users.GetUser(ctx, userId)
+		if err != nil {
+			return err
+		}
+		// only the author sees the ad in the trash, to anyone else it
+		// does not exist
+		trash, err := repos.Ads.GetTrash(ctx, userId)
+		if err != nil {
+			return err
+		}
+		for _, trashed := range trash {
+			if trashed.ID == adId {
+				ad, err = repos.Ads.RestoreAd(ctx, adId)
+				return err
+			}
+		}
+		return adrepo.ErrInvalidAdId
+	})
 	if err != nil {
 		return nil, err
 	}
-	if ad.AuthorID != userId {
-		return nil, ads.ErrUserCantChangeThisAd
-	}
-	if ad.Text == newText && ad.Title == newTitle {
-		return ad, nil
+	a.index.Put(ad)
+	return ad, nil
+}
+
+func (a app) GetTrash(ctx context.Context, userId int64) ([]*ads.Ad, error) {
+	_, err := a.tx.Repositories().Users.GetUser(ctx, userId)
+	if err != nil {
+		return nil, err
 	}
+	return a.tx.Repositories().Ads.GetTrash(ctx, userId)
+}
 
-	t := time.Now().UTC()
-	ad.UpdateDate = t.Format(time.DateOnly)
+// PurgeTrash removes for good the ads trashed before olderThan ago and sweeps
+// the files of the images left behind.
+func (a app) PurgeTrash(ctx context.Context, olderThan time.Duration) error {
+	err := a.tx.Do(ctx, func(repos uow.Repositories) error {
+		return repos.Ads.PurgeTrash(ctx, time.Now().UTC().Add(-olderThan))
+	})
+	if err != nil {
+		return err
+	}
+	return a.sweepImages(ctx)
+}
 
-	return a.repo.UpdateAdTitleAndText(ctx, adId, newTitle, newText)
+// GetRevisions lists the revisions of the ad, the oldest first.
+func (a app) GetRevisions(ctx context.Context, adId int64) ([]*ads.Revision, error) {
+	repos := a.tx.Repositories()
+	_, err := repos.Ads.GetAdById(ctx, adId)
+	if err != nil {
+		return nil, err
+	}
+	return repos.Revisions.GetRevisions(ctx, adId)
 }
 
-func (a app) DeleteAd(ctx context.Context, adId, userID int64) error {
-	ad, err := a.repo.GetAdById(ctx, adId)
+// GetAdAtRevision returns the ad as it was right after the given revision.
+func (a app) GetAdAtRevision(ctx context.Context, adId, revision int64) (*ads.Ad, error) {
+	repos := a.tx.Repositories()
+	ad, err := repos.Ads.GetAdById(ctx, adId)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	if ad.AuthorID != userID {
-		return ErrUnableToDelete
+	rev, err := findRevision(ctx, repos.Revisions, adId, revision)
+	if err != nil {
+		return nil, err
+	}
+	return rev.Apply(ad), nil
+}
+
+// RevertAd records the given revision again as a new one, its state has to
+// be one the user may move the ad to.
+func (a app) RevertAd(ctx context.Context, adId, userId, revision int64) (*ads.Ad, error) {
+	var ad *ads.Ad
+	err := a.tx.Do(ctx, func(repos uow.Repositories) error {
+		actor, err := repos.Users.GetUser(ctx, userId)
+		if err != nil {
+			return err
+		}
+		ad, err = repos.Ads.GetAdById(ctx, adId)
+		if err != nil {
+			return err
+		}
+		if err = authorize(userId, actor, ad, actionEdit, ""); err != nil {
+			return err
+		}
+		rev, err := findRevision(ctx, repos.Revisions, adId, revision)
+		if err != nil {
+			return err
+		}
+		if rev.State != ad.State {
+			if err = authorizeTransition(userId, actor, ad, rev.State, ""); err != nil {
+				return err
+			}
+			if err = authorizePublishing(userId, actor, ad, rev.State); err != nil {
+				return err
+			}
+		}
+
+		prev := *ad
+		t := time.Now().UTC()
+		if ad.Title != rev.Title || ad.Text != rev.Text {
+			ad, err = repos.Ads.UpdateAdTitleAndText(ctx, adId, rev.Title, rev.Text)
+			if err != nil {
+				return err
+			}
+		}
+		if ad.State != rev.State {
+			ad, err = moveAd(ctx, repos, ad, rev.State, "", t)
+			if err != nil {
+				return err
+			}
+		}
+		if ad.Version == prev.Version {
+			return nil
+		}
+		return repos.Revisions.AddRevision(ctx, ads.NewRevision(&prev, ad, userId, t))
+	})
+	if err != nil {
+		return nil, err
 	}
-	_, err = a.userRepo.GetUser(ctx, userID)
+	a.index.Put(ad)
+	return ad, nil
+}
+
+func findRevision(ctx context.Context, repo ads.RevisionRepository, adId, number int64) (*ads.Revision, error) {
+	list, err := repo.GetRevisions(ctx, adId)
 	if err != nil {
-		return userrepo.ErrInvalidUserId
+		return nil, err
+	}
+	for _, rev := range list {
+		if rev.Number == number {
+			return rev, nil
+		}
 	}
-	return a.repo.DeleteAd(ctx, adId)
+	return nil, ads.ErrInvalidRevision
 }
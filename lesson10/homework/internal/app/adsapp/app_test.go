@@ -1,32 +1,50 @@
 package adsapp
 
 import (
+	"bytes"
 	"context"
+	"encoding/binary"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
+	"hash/crc32"
 	"homework10/internal/adapters/adrepo"
+	"homework10/internal/adapters/memuow"
+	"homework10/internal/adapters/searchindex"
 	"homework10/internal/adapters/userrepo"
 	adMocks "homework10/internal/app/adsapp/mocks"
 	uMocks "homework10/internal/app/userapp/mocks"
 	"homework10/internal/entities/ads"
+	"homework10/internal/entities/uow"
+	"homework10/internal/entities/user"
+	"homework10/pkg/blob"
+	"homework10/pkg/idgen"
+	"image"
+	"image/png"
 	"log"
 	"testing"
+	"time"
 )
 
 type AdServiceAddAdTestSuite struct {
 	suite.Suite
-	adRepo  *adMocks.Repository
+	adRepo  *adMocks.Store
 	uRepo   *uMocks.Repository
 	service App
 }
 
 func (suite *AdServiceAddAdTestSuite) SetupTest() {
-	suite.adRepo = &adMocks.Repository{}
+	suite.adRepo = &adMocks.Store{}
 	suite.uRepo = &uMocks.Repository{}
 
 	suite.adRepo.On("AddAd", mock.AnythingOfType("*context.emptyCtx"), mock.AnythingOfType("*ads.Ad")).
 		Return(int64(0), nil)
+	suite.adRepo.On("AddRevision", mock.AnythingOfType("*context.emptyCtx"), mock.AnythingOfType("*ads.Revision")).
+		Return(nil)
+	suite.adRepo.On("GetCategory", mock.AnythingOfType("*context.emptyCtx"), int64(1)).
+		Return(&ads.Category{ID: 1, Name: "misc"}, nil)
+	suite.adRepo.On("GetCategory", mock.AnythingOfType("*context.emptyCtx"), mock.AnythingOfType("int64")).
+		Return(nil, adrepo.ErrInvalidCategoryId)
 }
 
 func (suite *AdServiceAddAdTestSuite) TearDownTest() {
@@ -36,29 +54,54 @@ func (suite *AdServiceAddAdTestSuite) TearDownTest() {
 func (suite *AdServiceAddAdTestSuite) TestCreateAd_OK() {
 	suite.uRepo.On("GetUser", mock.AnythingOfType("*context.emptyCtx"), mock.AnythingOfType("int64")).
 		Return(nil, nil)
-	suite.service = NewApp(suite.adRepo, suite.uRepo)
+	suite.service = NewApp(uow.Passthrough(suite.adRepo, suite.uRepo), searchindex.New(), blob.NewMemory())
 
-	ad, err := suite.service.CreateAd(context.Background(), "title", "text", int64(0))
+	ad, err := suite.service.CreateAd(context.Background(), "title", "text", ads.Money{Amount: 1250, Currency: "rub"},
+		int64(1), int64(0))
 	suite.Nil(err)
 	suite.Zero(ad.ID)
 	suite.Equal(ad.Text, "text")
+	suite.Equal(int64(1), ad.CategoryID)
+	suite.Equal(ads.Money{Amount: 1250, Currency: "RUB"}, ad.Price)
+}
+
+func (suite *AdServiceAddAdTestSuite) TestCreateAd_InvalidPrice() {
+	suite.uRepo.On("GetUser", mock.AnythingOfType("*context.emptyCtx"), mock.AnythingOfType("int64")).
+		Return(nil, nil)
+	suite.service = NewApp(uow.Passthrough(suite.adRepo, suite.uRepo), searchindex.New(), blob.NewMemory())
+
+	for _, price := range []ads.Money{{Amount: 100}, {Amount: -1, Currency: "RUB"}, {Amount: 100, Currency: "RUBLE"}} {
+		_, err := suite.service.CreateAd(context.Background(), "title", "text", price, int64(1), int64(0))
+		suite.ErrorIs(err, ads.ErrInvalidPrice)
+	}
+	suite.adRepo.AssertNotCalled(suite.T(), "AddAd", mock.Anything, mock.Anything)
+}
+
+func (suite *AdServiceAddAdTestSuite) TestCreateAd_UnknownCategory() {
+	suite.uRepo.On("GetUser", mock.AnythingOfType("*context.emptyCtx"), mock.AnythingOfType("int64")).
+		Return(nil, nil)
+	suite.service = NewApp(uow.Passthrough(suite.adRepo, suite.uRepo), searchindex.New(), blob.NewMemory())
+
+	_, err := suite.service.CreateAd(context.Background(), "title", "text", ads.Money{}, int64(2), int64(0))
+	suite.ErrorIs(err, ads.ErrUnknownCategory)
+	suite.adRepo.AssertNotCalled(suite.T(), "AddAd", mock.Anything, mock.Anything)
 }
 
 func (suite *AdServiceAddAdTestSuite) TestCreateAd_InvalidUserId() {
 	suite.uRepo.On("GetUser", mock.AnythingOfType("*context.emptyCtx"), mock.AnythingOfType("int64")).
 		Return(nil, userrepo.ErrInvalidUserId)
-	suite.service = NewApp(suite.adRepo, suite.uRepo)
+	suite.service = NewApp(uow.Passthrough(suite.adRepo, suite.uRepo), searchindex.New(), blob.NewMemory())
 
-	_, _ = suite.service.CreateAd(context.Background(), "title", "text", int64(1))
+	_, _ = suite.service.CreateAd(context.Background(), "title", "text", ads.Money{}, int64(1), int64(1))
 	suite.Error(userrepo.ErrInvalidUserId)
 }
 
 func (suite *AdServiceAddAdTestSuite) TestCreateAd_InvalidAdParams() {
 	suite.uRepo.On("GetUser", mock.AnythingOfType("*context.emptyCtx"), mock.AnythingOfType("int64")).
 		Return(nil, nil).Maybe()
-	suite.service = NewApp(suite.adRepo, suite.uRepo)
+	suite.service = NewApp(uow.Passthrough(suite.adRepo, suite.uRepo), searchindex.New(), blob.NewMemory())
 
-	_, _ = suite.service.CreateAd(context.Background(), "", "", int64(0))
+	_, _ = suite.service.CreateAd(context.Background(), "", "", ads.Money{}, int64(1), int64(0))
 	suite.Error(ads.ErrInvalidAdParams)
 }
 
@@ -67,11 +110,11 @@ func TestAdAdd(t *testing.T) {
 }
 
 func TestAdGetById(t *testing.T) {
-	adRepo := &adMocks.Repository{}
+	adRepo := &adMocks.Store{}
 
 	adRepo.On("GetAdById", mock.AnythingOfType("*context.emptyCtx"), mock.AnythingOfType("int64")).
 		Return(&ads.Ad{Title: "test", Text: "test", ID: int64(0)}, nil)
-	service := NewApp(adRepo, &uMocks.Repository{})
+	service := NewApp(uow.Passthrough(adRepo, &uMocks.Repository{}), searchindex.New(), blob.NewMemory())
 	ad, err := service.GetAdById(context.Background(), int64(0))
 	assert.Nil(t, err)
 	assert.Equal(t, ad.Title, "test")
@@ -79,46 +122,70 @@ func TestAdGetById(t *testing.T) {
 }
 
 func TestAdGetByTitle(t *testing.T) {
-	adRepo := &adMocks.Repository{}
+	adRepo := &adMocks.Store{}
 
 	adRepo.On("GetAdsByTitle", mock.AnythingOfType("*context.emptyCtx"), mock.AnythingOfType("string")).
 		Return([]*ads.Ad{{Title: "test 1", Text: "test", ID: int64(0)}, {Title: "test 2", Text: "test", ID: int64(0)}}, nil)
-	service := NewApp(adRepo, &uMocks.Repository{})
+	service := NewApp(uow.Passthrough(adRepo, &uMocks.Repository{}), searchindex.New(), blob.NewMemory())
 	all, err := service.GetAdsByTitle(context.Background(), "test")
 	assert.Nil(t, err)
 	assert.Len(t, all, 2)
 }
 
 func TestAdGetAll(t *testing.T) {
-	adRepo := &adMocks.Repository{}
+	adRepo := &adMocks.Store{}
 
 	adRepo.On("GetAll", mock.AnythingOfType("*context.emptyCtx"), mock.AnythingOfType("ads.Filters")).
 		Return([]*ads.Ad{{Title: "test2", Text: "test2", ID: int64(0)}, {Title: "test1", Text: "test1", ID: int64(0)}}, nil)
 
-	service := NewApp(adRepo, &uMocks.Repository{})
-	all, err := service.GetAll(context.Background(), ads.Filters{AuthorId: "0", Status: ads.Published})
+	service := NewApp(uow.Passthrough(adRepo, &uMocks.Repository{}), searchindex.New(), blob.NewMemory())
+	all, next, err := service.GetAll(context.Background(), ads.Filters{AuthorId: "0", Status: ads.Published})
 	assert.Nil(t, err)
 	assert.Len(t, all, 2)
+	assert.Empty(t, next)
 
-	_, _ = service.GetAll(context.Background(), ads.Filters{AuthorId: "0"})
+	_, _, _ = service.GetAll(context.Background(), ads.Filters{AuthorId: "0"})
 	assert.Error(t, ads.ErrInvalidFilters)
 }
 
+func TestAdGetAllPage(t *testing.T) {
+	adRepo := &adMocks.Store{}
+
+	adRepo.On("GetAll", mock.AnythingOfType("*context.emptyCtx"), mock.MatchedBy(func(f ads.Filters) bool {
+		return f.Limit == 3
+	})).Return([]*ads.Ad{{ID: 1}, {ID: 2}, {ID: 3}}, nil)
+
+	service := NewApp(uow.Passthrough(adRepo, &uMocks.Repository{}), searchindex.New(), blob.NewMemory())
+	filters := ads.Filters{Status: ads.Published, Limit: 2}
+	page, next, err := service.GetAll(context.Background(), filters)
+	assert.Nil(t, err)
+	assert.Len(t, page, 2)
+	assert.Equal(t, filters.NextCursor(page[1]), next)
+
+	_, _, err = service.GetAll(context.Background(), ads.Filters{Status: ads.Published, Cursor: "garbage"})
+	assert.ErrorIs(t, err, ads.ErrInvalidFilters)
+}
+
 type AdServiceChangeAdTestSuite struct {
 	suite.Suite
-	adRepo *adMocks.Repository
+	adRepo *adMocks.Store
 	uRepo  *uMocks.Repository
 }
 
 func (suite *AdServiceChangeAdTestSuite) SetupTest() {
-	suite.adRepo = &adMocks.Repository{}
+	suite.adRepo = &adMocks.Store{}
 	suite.uRepo = &uMocks.Repository{}
 
 	suite.adRepo.On("GetAdById", mock.AnythingOfType("*context.emptyCtx"), mock.AnythingOfType("int64")).
-		Return(&ads.Ad{Title: "test", Text: "test", ID: int64(0), Published: false}, nil)
-	suite.adRepo.On("UpdateAdStatus", mock.AnythingOfType("*context.emptyCtx"), mock.AnythingOfType("int64"),
-		mock.AnythingOfType("bool")).
-		Return(&ads.Ad{Title: "test", Text: "test", ID: int64(0), Published: true}, nil)
+		Return(&ads.Ad{Title: "test", Text: "test", ID: int64(0), State: ads.StateDraft}, nil)
+	// publishing sets when the ad expires
+	suite.adRepo.On("UpdateAdPublication", mock.AnythingOfType("*context.emptyCtx"), mock.AnythingOfType("int64"),
+		ads.StatePublished, time.Time{}, mock.MatchedBy(func(expiresAt time.Time) bool {
+			return expiresAt.After(time.Now().Add(ads.Lifetime - time.Minute))
+		})).
+		Return(&ads.Ad{Title: "test", Text: "test", ID: int64(0), State: ads.StatePublished}, nil)
+	suite.adRepo.On("AddRevision", mock.AnythingOfType("*context.emptyCtx"), mock.AnythingOfType("*ads.Revision")).
+		Return(nil)
 }
 
 func (suite *AdServiceChangeAdTestSuite) TearDownTest() {
@@ -127,20 +194,20 @@ func (suite *AdServiceChangeAdTestSuite) TearDownTest() {
 
 func (suite *AdServiceChangeAdTestSuite) TestChangeAdStatus_OK() {
 	suite.uRepo.On("GetUser", mock.AnythingOfType("*context.emptyCtx"), mock.AnythingOfType("int64")).
-		Return(nil, nil)
-	service := NewApp(suite.adRepo, suite.uRepo)
+		Return(&user.User{Id: 0, Verified: true}, nil)
+	service := NewApp(uow.Passthrough(suite.adRepo, suite.uRepo), searchindex.New(), blob.NewMemory())
 
 	ad, err := service.ChangeAdStatus(context.Background(), int64(0), int64(0), true)
 	suite.Nil(err)
 	suite.Zero(ad.ID)
-	suite.Equal(ad.Published, true)
+	suite.Equal(ads.StatePublished, ad.State)
 	suite.Equal(ad.Text, "test")
 }
 
 func (suite *AdServiceChangeAdTestSuite) TestChangeAdStatus_InvalidUserId() {
 	suite.uRepo.On("GetUser", mock.AnythingOfType("*context.emptyCtx"), mock.AnythingOfType("int64")).
 		Return(nil, userrepo.ErrInvalidUserId)
-	service := NewApp(suite.adRepo, suite.uRepo)
+	service := NewApp(uow.Passthrough(suite.adRepo, suite.uRepo), searchindex.New(), blob.NewMemory())
 
 	_, _ = service.ChangeAdStatus(context.Background(), int64(0), int64(0), true)
 	suite.Error(userrepo.ErrInvalidUserId)
@@ -149,39 +216,53 @@ func (suite *AdServiceChangeAdTestSuite) TestChangeAdStatus_InvalidUserId() {
 func (suite *AdServiceChangeAdTestSuite) TestChangeAdStatus_ErrUserCantChangeThisAd() {
 	suite.uRepo.On("GetUser", mock.AnythingOfType("*context.emptyCtx"), mock.AnythingOfType("int64")).
 		Return(nil, nil)
-	service := NewApp(suite.adRepo, suite.uRepo)
+	service := NewApp(uow.Passthrough(suite.adRepo, suite.uRepo), searchindex.New(), blob.NewMemory())
 
 	_, _ = service.ChangeAdStatus(context.Background(), int64(0), int64(1), true)
 	suite.Error(ads.ErrUserCantChangeThisAd)
 }
 
+func (suite *AdServiceChangeAdTestSuite) TestChangeAdStatus_NotVerified() {
+	suite.uRepo.On("GetUser", mock.AnythingOfType("*context.emptyCtx"), mock.AnythingOfType("int64")).
+		Return(&user.User{Id: 0}, nil)
+	service := NewApp(uow.Passthrough(suite.adRepo, suite.uRepo), searchindex.New(), blob.NewMemory())
+
+	_, err := service.ChangeAdStatus(context.Background(), int64(0), int64(0), true)
+	suite.ErrorIs(err, user.ErrNotVerified)
+	suite.adRepo.AssertNotCalled(suite.T(), "UpdateAdState", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	suite.adRepo.AssertNotCalled(suite.T(), "UpdateAdPublication", mock.Anything, mock.Anything, mock.Anything,
+		mock.Anything, mock.Anything)
+}
+
 func TestChangeAd(t *testing.T) {
 	suite.Run(t, new(AdServiceChangeAdTestSuite))
 }
 
 type AdServiceUpdateAdTextTestSuite struct {
 	suite.Suite
-	adRepo *adMocks.Repository
+	adRepo *adMocks.Store
 	uRepo  *uMocks.Repository
 }
 
 func (suite *AdServiceUpdateAdTextTestSuite) SetupTest() {
-	suite.adRepo = &adMocks.Repository{}
+	suite.adRepo = &adMocks.Store{}
 	suite.uRepo = &uMocks.Repository{}
 
 	suite.adRepo.On("GetAdById", mock.AnythingOfType("*context.emptyCtx"), mock.AnythingOfType("int64")).
-		Return(&ads.Ad{Title: "test", Text: "test", ID: int64(0), Published: false}, nil)
+		Return(&ads.Ad{Title: "test", Text: "test", ID: int64(0), State: ads.StateDraft}, nil)
 	suite.adRepo.On("UpdateAdTitleAndText", mock.AnythingOfType("*context.emptyCtx"), mock.AnythingOfType("int64"),
 		mock.AnythingOfType("string"), mock.AnythingOfType("string")).
-		Return(&ads.Ad{Title: "test new", Text: "test new", ID: int64(0), Published: true}, nil)
+		Return(&ads.Ad{Title: "test new", Text: "test new", ID: int64(0), State: ads.StatePublished}, nil)
+	suite.adRepo.On("AddRevision", mock.AnythingOfType("*context.emptyCtx"), mock.AnythingOfType("*ads.Revision")).
+		Return(nil)
 }
 
 func (suite *AdServiceUpdateAdTextTestSuite) TestUpdateAdText_OK() {
 	suite.uRepo.On("GetUser", mock.AnythingOfType("*context.emptyCtx"), mock.AnythingOfType("int64")).
 		Return(nil, nil)
-	service := NewApp(suite.adRepo, suite.uRepo)
+	service := NewApp(uow.Passthrough(suite.adRepo, suite.uRepo), searchindex.New(), blob.NewMemory())
 
-	ad, err := service.UpdateAd(context.Background(), int64(0), int64(0), "test new", "test new")
+	ad, err := service.UpdateAd(context.Background(), int64(0), int64(0), "test new", "test new", int64(0))
 	suite.Nil(err)
 	suite.Zero(ad.ID)
 	suite.Equal(ad.Text, "test new")
@@ -191,24 +272,34 @@ func (suite *AdServiceUpdateAdTextTestSuite) TestUpdateAdText_OK() {
 func (suite *AdServiceUpdateAdTextTestSuite) TestUpdateAdText_InvalidUserId() {
 	suite.uRepo.On("GetUser", mock.AnythingOfType("*context.emptyCtx"), mock.AnythingOfType("int64")).
 		Return(nil, userrepo.ErrInvalidUserId)
-	service := NewApp(suite.adRepo, suite.uRepo)
+	service := NewApp(uow.Passthrough(suite.adRepo, suite.uRepo), searchindex.New(), blob.NewMemory())
 
-	_, _ = service.UpdateAd(context.Background(), int64(0), int64(0), "test new", "test new")
+	_, _ = service.UpdateAd(context.Background(), int64(0), int64(0), "test new", "test new", int64(0))
 	suite.Error(userrepo.ErrInvalidUserId)
 }
 
 func (suite *AdServiceUpdateAdTextTestSuite) TestUpdateAdText_ErrUserCantChangeThisAd() {
 	suite.uRepo.On("GetUser", mock.AnythingOfType("*context.emptyCtx"), mock.AnythingOfType("int64")).
 		Return(nil, nil)
-	service := NewApp(suite.adRepo, suite.uRepo)
-	_, _ = service.UpdateAd(context.Background(), int64(0), int64(1), "test new", "test new")
+	service := NewApp(uow.Passthrough(suite.adRepo, suite.uRepo), searchindex.New(), blob.NewMemory())
+	_, _ = service.UpdateAd(context.Background(), int64(0), int64(1), "test new", "test new", int64(0))
 	suite.Error(ads.ErrUserCantChangeThisAd)
 }
 
+func (suite *AdServiceUpdateAdTextTestSuite) TestUpdateAdText_VersionMismatch() {
+	suite.uRepo.On("GetUser", mock.AnythingOfType("*context.emptyCtx"), mock.AnythingOfType("int64")).
+		Return(nil, nil)
+	service := NewApp(uow.Passthrough(suite.adRepo, suite.uRepo), searchindex.New(), blob.NewMemory())
+
+	_, err := service.UpdateAd(context.Background(), int64(0), int64(0), "test new", "test new", int64(2))
+	suite.ErrorIs(err, ads.ErrVersionMismatch)
+	suite.adRepo.AssertNotCalled(suite.T(), "UpdateAdTitleAndText", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
 func (suite *AdServiceUpdateAdTextTestSuite) TestUpdateAdText_InvalidParams() {
-	service := NewApp(suite.adRepo, suite.uRepo)
+	service := NewApp(uow.Passthrough(suite.adRepo, suite.uRepo), searchindex.New(), blob.NewMemory())
 
-	_, _ = service.UpdateAd(context.Background(), int64(0), int64(0), "", "")
+	_, _ = service.UpdateAd(context.Background(), int64(0), int64(0), "", "", int64(0))
 	suite.Error(ads.ErrInvalidAdParams)
 }
 
@@ -218,26 +309,27 @@ func TestUpdateAdText(t *testing.T) {
 
 type AdServiceDeleteTestSuite struct {
 	suite.Suite
-	adRepo *adMocks.Repository
+	adRepo *adMocks.Store
 	uRepo  *uMocks.Repository
 }
 
 func (suite *AdServiceDeleteTestSuite) SetupTest() {
-	suite.adRepo = &adMocks.Repository{}
+	suite.adRepo = &adMocks.Store{}
 	suite.uRepo = &uMocks.Repository{}
 
-	suite.adRepo.On("DeleteAd", mock.AnythingOfType("*context.emptyCtx"), mock.AnythingOfType("int64")).
-		Return(nil)
+	suite.adRepo.On("TrashAd", mock.AnythingOfType("*context.emptyCtx"), mock.AnythingOfType("int64"),
+		mock.AnythingOfType("time.Time")).
+		Return(&ads.Ad{Title: "test", Text: "test", ID: int64(0), DeletedAt: time.Now()}, nil)
 }
 
 func (suite *AdServiceDeleteTestSuite) TestDeleteAd_OK() {
 	suite.uRepo.On("GetUser", mock.AnythingOfType("*context.emptyCtx"), mock.AnythingOfType("int64")).
 		Return(nil, nil)
 	suite.adRepo.On("GetAdById", mock.AnythingOfType("*context.emptyCtx"), mock.AnythingOfType("int64")).
-		Return(&ads.Ad{Title: "test", Text: "test", ID: int64(0), Published: false}, nil)
-	service := NewApp(suite.adRepo, suite.uRepo)
+		Return(&ads.Ad{Title: "test", Text: "test", ID: int64(0), State: ads.StateDraft}, nil)
+	service := NewApp(uow.Passthrough(suite.adRepo, suite.uRepo), searchindex.New(), blob.NewMemory())
 
-	err := service.DeleteAd(context.Background(), int64(0), int64(0))
+	err := service.DeleteAd(context.Background(), int64(0), int64(0), "")
 	suite.Nil(err)
 }
 
@@ -245,31 +337,555 @@ func (suite *AdServiceDeleteTestSuite) TestDeleteAd_InvalidUserId() {
 	suite.uRepo.On("GetUser", mock.AnythingOfType("*context.emptyCtx"), mock.AnythingOfType("int64")).
 		Return(nil, userrepo.ErrInvalidUserId)
 	suite.adRepo.On("GetAdById", mock.AnythingOfType("*context.emptyCtx"), mock.AnythingOfType("int64")).
-		Return(&ads.Ad{Title: "test", Text: "test", ID: int64(0), Published: false}, nil)
-	service := NewApp(suite.adRepo, suite.uRepo)
+		Return(&ads.Ad{Title: "test", Text: "test", ID: int64(0), State: ads.StateDraft}, nil)
+	service := NewApp(uow.Passthrough(suite.adRepo, suite.uRepo), searchindex.New(), blob.NewMemory())
 
-	_ = service.DeleteAd(context.Background(), int64(0), int64(0))
+	_ = service.DeleteAd(context.Background(), int64(0), int64(0), "")
 	suite.Error(userrepo.ErrInvalidUserId)
 }
 
 func (suite *AdServiceDeleteTestSuite) TestDeleteAd_ErrUnableToDelete() {
+	suite.uRepo.On("GetUser", mock.AnythingOfType("*context.emptyCtx"), int64(1)).
+		Return(&user.User{Id: 1, Role: user.RoleUser}, nil)
 	suite.adRepo.On("GetAdById", mock.AnythingOfType("*context.emptyCtx"), mock.AnythingOfType("int64")).
-		Return(&ads.Ad{Title: "test", Text: "test", ID: int64(0), Published: false}, nil)
-	service := NewApp(suite.adRepo, suite.uRepo)
+		Return(&ads.Ad{Title: "test", Text: "test", ID: int64(0), State: ads.StateDraft}, nil)
+	service := NewApp(uow.Passthrough(suite.adRepo, suite.uRepo), searchindex.New(), blob.NewMemory())
 
-	_ = service.DeleteAd(context.Background(), int64(0), int64(1))
-	suite.Error(ErrUnableToDelete)
+	err := service.DeleteAd(context.Background(), int64(0), int64(1), "spam")
+	suite.ErrorIs(err, ErrUnableToDelete)
 }
 
 func (suite *AdServiceDeleteTestSuite) TestDeleteAd_InvalidAdId() {
 	suite.adRepo.On("GetAdById", mock.AnythingOfType("*context.emptyCtx"), mock.AnythingOfType("int64")).
 		Return(nil, adrepo.ErrInvalidAdId)
-	service := NewApp(suite.adRepo, suite.uRepo)
+	service := NewApp(uow.Passthrough(suite.adRepo, suite.uRepo), searchindex.New(), blob.NewMemory())
 
-	_ = service.DeleteAd(context.Background(), int64(0), int64(0))
+	_ = service.DeleteAd(context.Background(), int64(0), int64(0), "")
 	suite.Error(adrepo.ErrInvalidAdId)
 }
 
 func TestDeleteAd(t *testing.T) {
 	suite.Run(t, new(AdServiceDeleteTestSuite))
 }
+
+func TestRestoreAd(t *testing.T) {
+	adRepo := &adMocks.Store{}
+	uRepo := &uMocks.Repository{}
+	uRepo.On("GetUser", mock.AnythingOfType("*context.emptyCtx"), mock.AnythingOfType("int64")).
+		Return(nil, nil)
+	adRepo.On("GetTrash", mock.AnythingOfType("*context.emptyCtx"), int64(1)).
+		Return([]*ads.Ad{{ID: 5, AuthorID: 1, DeletedAt: time.Now()}}, nil)
+	adRepo.On("RestoreAd", mock.AnythingOfType("*context.emptyCtx"), int64(5)).
+		Return(&ads.Ad{ID: 5, AuthorID: 1, Title: "restored"}, nil)
+	index := searchindex.New()
+	service := NewApp(uow.Passthrough(adRepo, uRepo), index, blob.NewMemory())
+
+	ad, err := service.RestoreAd(context.Background(), 5, 1)
+	assert.Nil(t, err)
+	assert.False(t, ad.Trashed())
+	assert.Equal(t, []int64{5}, index.Search("restored"))
+
+	_, err = service.RestoreAd(context.Background(), 6, 1)
+	assert.ErrorIs(t, err, adrepo.ErrInvalidAdId)
+	adRepo.AssertNumberOfCalls(t, "RestoreAd", 1)
+}
+
+func TestRevertAd(t *testing.T) {
+	adRepo := &adMocks.Store{}
+	uRepo := &uMocks.Repository{}
+	uRepo.On("GetUser", mock.AnythingOfType("*context.emptyCtx"), mock.AnythingOfType("int64")).
+		Return(nil, nil)
+	adRepo.On("GetAdById", mock.AnythingOfType("*context.emptyCtx"), int64(5)).
+		Return(&ads.Ad{ID: 5, AuthorID: 1, Title: "new", Text: "text", State: ads.StatePublished, Version: 3}, nil)
+	adRepo.On("GetRevisions", mock.AnythingOfType("*context.emptyCtx"), int64(5)).
+		Return([]*ads.Revision{{AdID: 5, Number: 1, Title: "old", Text: "text", State: ads.StatePublished}}, nil)
+	adRepo.On("UpdateAdTitleAndText", mock.AnythingOfType("*context.emptyCtx"), int64(5), "old", "text").
+		Return(&ads.Ad{ID: 5, AuthorID: 1, Title: "old", Text: "text", State: ads.StatePublished, Version: 4}, nil)
+	adRepo.On("AddRevision", mock.AnythingOfType("*context.emptyCtx"), mock.MatchedBy(func(rev *ads.Revision) bool {
+		return rev.ActorID == 1 && len(rev.Changes) == 1 && rev.Changes[0].Old == "new"
+	})).Return(nil)
+	service := NewApp(uow.Passthrough(adRepo, uRepo), searchindex.New(), blob.NewMemory())
+
+	ad, err := service.RevertAd(context.Background(), 5, 1, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, "old", ad.Title)
+	adRepo.AssertNotCalled(t, "UpdateAdState", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	adRepo.AssertNumberOfCalls(t, "AddRevision", 1)
+
+	_, err = service.RevertAd(context.Background(), 5, 1, 2)
+	assert.ErrorIs(t, err, ads.ErrInvalidRevision)
+	_, err = service.RevertAd(context.Background(), 5, 2, 1)
+	assert.ErrorIs(t, err, ads.ErrUserCantChangeThisAd)
+}
+
+func TestRevertAdToPublished(t *testing.T) {
+	ctx := context.Background()
+	users := userrepo.New(idgen.NewSequence())
+	adRepo := adrepo.New(idgen.NewSequence())
+	service := NewApp(memuow.New(adRepo, users), searchindex.New(), blob.NewMemory())
+	author, moderator := user.User{}, user.User{}
+	for _, u := range []*user.User{&author, &moderator} {
+		_, err := users.CreateUser(ctx, u)
+		assert.NoError(t, err)
+	}
+	_, err := users.UpdateRole(ctx, moderator.Id, user.RoleModerator)
+	assert.NoError(t, err)
+	category := &ads.Category{Name: "misc"}
+	_, err = adRepo.AddCategory(ctx, category)
+	assert.NoError(t, err)
+	ad, err := service.CreateAd(ctx, "title", "text", ads.Money{}, category.ID, author.Id)
+	assert.NoError(t, err)
+
+	// the ad of an unverified author goes up through a review
+	_, err = service.ChangeAdState(ctx, ad.ID, author.Id, ads.StatePendingReview, "")
+	assert.NoError(t, err)
+	_, err = service.ChangeAdState(ctx, ad.ID, moderator.Id, ads.StatePublished, "")
+	assert.NoError(t, err)
+	_, err = service.ChangeAdState(ctx, ad.ID, author.Id, ads.StateDraft, "")
+	assert.NoError(t, err)
+	revisions, err := service.GetRevisions(ctx, ad.ID)
+	assert.NoError(t, err)
+	published := revisions[2].Number
+
+	// but back to it only the way the author publishes
+	_, err = service.RevertAd(ctx, ad.ID, moderator.Id, published)
+	assert.ErrorIs(t, err, ads.ErrUserCantChangeThisAd)
+	_, err = service.RevertAd(ctx, ad.ID, author.Id, published)
+	assert.ErrorIs(t, err, user.ErrNotVerified)
+	_, err = users.UpdateVerified(ctx, author.Id, true)
+	assert.NoError(t, err)
+	ad, err = service.RevertAd(ctx, ad.ID, author.Id, published)
+	assert.NoError(t, err)
+	assert.Equal(t, ads.StatePublished, ad.State)
+}
+
+func TestModeration(t *testing.T) {
+	ctx := context.Background()
+	users := userrepo.New(idgen.NewSequence())
+	adRepo := adrepo.New(idgen.NewSequence())
+	service := NewApp(memuow.New(adRepo, users), searchindex.New(), blob.NewMemory())
+	author, other, moderator := user.User{Verified: true}, user.User{}, user.User{}
+	for _, u := range []*user.User{&author, &other, &moderator} {
+		_, err := users.CreateUser(ctx, u)
+		assert.NoError(t, err)
+	}
+	_, err := users.UpdateRole(ctx, moderator.Id, user.RoleModerator)
+	assert.NoError(t, err)
+	category := &ads.Category{Name: "misc"}
+	_, err = adRepo.AddCategory(ctx, category)
+	assert.NoError(t, err)
+	ad, err := service.CreateAd(ctx, "title", "text", ads.Money{}, category.ID, author.Id)
+	assert.NoError(t, err)
+	_, err = service.ChangeAdStatus(ctx, ad.ID, author.Id, true)
+	assert.NoError(t, err)
+
+	// moderators can't edit or publish the ads of others
+	_, err = service.UpdateAd(ctx, ad.ID, moderator.Id, "moderated", "text", 0)
+	assert.ErrorIs(t, err, ads.ErrUserCantChangeThisAd)
+	_, err = service.ChangeAdStatus(ctx, ad.ID, moderator.Id, true)
+	assert.ErrorIs(t, err, ads.ErrUserCantChangeThisAd)
+
+	_, err = service.UnpublishAd(ctx, ad.ID, other.Id, "spam")
+	assert.ErrorIs(t, err, ads.ErrUserCantChangeThisAd)
+	_, err = service.UnpublishAd(ctx, ad.ID, moderator.Id, " ")
+	assert.ErrorIs(t, err, ads.ErrReasonRequired)
+	_, err = service.ChangeAdStatus(ctx, ad.ID, moderator.Id, false)
+	assert.ErrorIs(t, err, ads.ErrReasonRequired)
+	ad, err = service.UnpublishAd(ctx, ad.ID, moderator.Id, "spam")
+	assert.NoError(t, err)
+	assert.Equal(t, ads.StateRejected, ad.State)
+	assert.Equal(t, "spam", ad.RejectionReason)
+
+	assert.ErrorIs(t, service.DeleteAd(ctx, ad.ID, other.Id, "spam"), ErrUnableToDelete)
+	assert.ErrorIs(t, service.DeleteAd(ctx, ad.ID, moderator.Id, ""), ads.ErrReasonRequired)
+	assert.NoError(t, service.DeleteAd(ctx, ad.ID, moderator.Id, "still spam"))
+
+	// the author finds the ad in their trash and the reasons in its history
+	trash, err := service.GetTrash(ctx, author.Id)
+	assert.NoError(t, err)
+	assert.Len(t, trash, 1)
+	_, err = service.RestoreAd(ctx, ad.ID, author.Id)
+	assert.NoError(t, err)
+	revisions, err := service.GetRevisions(ctx, ad.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, revisions, 4) {
+		assert.Equal(t, "spam", revisions[2].Reason)
+		assert.Equal(t, moderator.Id, revisions[2].ActorID)
+		assert.Equal(t, "still spam", revisions[3].Reason)
+		assert.Empty(t, revisions[3].Changes)
+	}
+}
+
+func TestChangeAdState(t *testing.T) {
+	ctx := context.Background()
+	users := userrepo.New(idgen.NewSequence())
+	adRepo := adrepo.New(idgen.NewSequence())
+	service := NewApp(memuow.New(adRepo, users), searchindex.New(), blob.NewMemory())
+	author, other, moderator := user.User{Verified: true}, user.User{Verified: true}, user.User{}
+	for _, u := range []*user.User{&author, &other, &moderator} {
+		_, err := users.CreateUser(ctx, u)
+		assert.NoError(t, err)
+	}
+	_, err := users.UpdateRole(ctx, moderator.Id, user.RoleModerator)
+	assert.NoError(t, err)
+	category := &ads.Category{Name: "misc"}
+	_, err = adRepo.AddCategory(ctx, category)
+	assert.NoError(t, err)
+	ad, err := service.CreateAd(ctx, "title", "text", ads.Money{}, category.ID, author.Id)
+	assert.NoError(t, err)
+	assert.Equal(t, ads.StateDraft, ad.State)
+
+	_, err = service.ChangeAdState(ctx, ad.ID, author.Id, "lost", "")
+	assert.ErrorIs(t, err, ads.ErrInvalidState)
+	_, err = service.ChangeAdState(ctx, ad.ID, author.Id, ads.StateSold, "")
+	assert.ErrorIs(t, err, ads.ErrIllegalTransition)
+	_, err = service.ChangeAdState(ctx, ad.ID, other.Id, ads.StatePendingReview, "")
+	assert.ErrorIs(t, err, ads.ErrUserCantChangeThisAd)
+	ad, err = service.ChangeAdState(ctx, ad.ID, author.Id, ads.StatePendingReview, "")
+	assert.NoError(t, err)
+	assert.Equal(t, ads.StatePendingReview, ad.State)
+
+	// only a moderator gets the ad out of the review
+	_, err = service.ChangeAdState(ctx, ad.ID, author.Id, ads.StatePublished, "")
+	assert.ErrorIs(t, err, ads.ErrUserCantChangeThisAd)
+	_, err = service.ChangeAdState(ctx, ad.ID, other.Id, ads.StateRejected, "spam")
+	assert.ErrorIs(t, err, ads.ErrUserCantChangeThisAd)
+	_, err = service.ChangeAdState(ctx, ad.ID, moderator.Id, ads.StateRejected, "")
+	assert.ErrorIs(t, err, ads.ErrReasonRequired)
+	ad, err = service.ChangeAdState(ctx, ad.ID, moderator.Id, ads.StateRejected, "spam")
+	assert.NoError(t, err)
+	assert.Equal(t, ads.StateRejected, ad.State)
+	assert.Equal(t, "spam", ad.RejectionReason)
+
+	// a rejected ad goes up only through another review
+	_, err = service.ChangeAdStatus(ctx, ad.ID, author.Id, true)
+	assert.ErrorIs(t, err, ads.ErrIllegalTransition)
+	ad, err = service.ChangeAdState(ctx, ad.ID, author.Id, ads.StatePendingReview, "")
+	assert.NoError(t, err)
+	assert.Empty(t, ad.RejectionReason)
+	ad, err = service.ChangeAdState(ctx, ad.ID, moderator.Id, ads.StatePublished, "")
+	assert.NoError(t, err)
+	assert.Equal(t, ads.StatePublished, ad.State)
+	assert.False(t, ad.ExpiresAt.IsZero())
+
+	_, err = service.ChangeAdState(ctx, ad.ID, moderator.Id, ads.StateSold, "")
+	assert.ErrorIs(t, err, ads.ErrUserCantChangeThisAd)
+	ad, err = service.ChangeAdState(ctx, ad.ID, author.Id, ads.StateSold, "")
+	assert.NoError(t, err)
+	assert.Equal(t, ads.StateSold, ad.State)
+	_, err = service.ChangeAdState(ctx, ad.ID, author.Id, ads.StatePublished, "")
+	assert.ErrorIs(t, err, ads.ErrIllegalTransition)
+	_, err = service.RenewAd(ctx, ad.ID, author.Id)
+	assert.ErrorIs(t, err, ads.ErrCantRenew)
+
+	revisions, err := service.GetRevisions(ctx, ad.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, revisions, 6) {
+		assert.Equal(t, []ads.Change{{Field: ads.FieldState, Old: "pending_review", New: "rejected"}}, revisions[2].Changes)
+		assert.Equal(t, "spam", revisions[2].Reason)
+		assert.Equal(t, moderator.Id, revisions[4].ActorID)
+	}
+}
+
+func TestGetAdAtRevision(t *testing.T) {
+	adRepo := &adMocks.Store{}
+	adRepo.On("GetAdById", mock.AnythingOfType("*context.emptyCtx"), int64(5)).
+		Return(&ads.Ad{ID: 5, AuthorID: 1, Title: "new", Text: "text", Version: 3}, nil)
+	adRepo.On("GetRevisions", mock.AnythingOfType("*context.emptyCtx"), int64(5)).
+		Return([]*ads.Revision{{AdID: 5, Number: 1, Title: "old", Text: "text"}}, nil)
+	service := NewApp(uow.Passthrough(adRepo, &uMocks.Repository{}), searchindex.New(), blob.NewMemory())
+
+	ad, err := service.GetAdAtRevision(context.Background(), 5, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, "old", ad.Title)
+	assert.Equal(t, int64(1), ad.AuthorID)
+
+	_, err = service.GetAdAtRevision(context.Background(), 5, 0)
+	assert.ErrorIs(t, err, ads.ErrInvalidRevision)
+}
+
+func TestSearchAds(t *testing.T) {
+	adRepo := &adMocks.Store{}
+	index := searchindex.New()
+	index.Put(&ads.Ad{ID: 0, Title: "bike", Text: "red bike"})
+	index.Put(&ads.Ad{ID: 1, Title: "bike"})
+	index.Put(&ads.Ad{ID: 2, Title: "bike", Text: "draft"})
+
+	adRepo.On("GetAdById", mock.AnythingOfType("*context.emptyCtx"), int64(0)).
+		Return(&ads.Ad{ID: 0, Title: "bike", Text: "red bike", State: ads.StatePublished}, nil)
+	adRepo.On("GetAdById", mock.AnythingOfType("*context.emptyCtx"), int64(1)).
+		Return(nil, adrepo.ErrInvalidAdId)
+	adRepo.On("GetAdById", mock.AnythingOfType("*context.emptyCtx"), int64(2)).
+		Return(&ads.Ad{ID: 2, Title: "bike", Text: "draft"}, nil)
+	service := NewApp(uow.Passthrough(adRepo, &uMocks.Repository{}), index, blob.NewMemory())
+
+	found, err := service.SearchAds(context.Background(), "bike")
+	assert.Nil(t, err)
+	assert.Len(t, found, 1)
+	assert.Equal(t, int64(0), found[0].ID)
+	// the ad that is gone from the repository is dropped from the index
+	assert.Equal(t, []int64{0, 2}, index.Search("bike"))
+
+	_, err = service.SearchAds(context.Background(), " ")
+	assert.ErrorIs(t, err, ads.ErrEmptySearchQuery)
+}
+
+func TestCategories(t *testing.T) {
+	ctx := context.Background()
+	users := userrepo.New(idgen.NewSequence())
+	service := NewApp(memuow.New(adrepo.New(idgen.NewSequence()), users), searchindex.New(), blob.NewMemory())
+	admin, seller := user.User{}, user.User{Verified: true}
+	for _, u := range []*user.User{&admin, &seller} {
+		_, err := users.CreateUser(ctx, u)
+		assert.NoError(t, err)
+	}
+	_, err := users.UpdateRole(ctx, admin.Id, user.RoleAdmin)
+	assert.NoError(t, err)
+
+	_, err = service.CreateCategory(ctx, seller.Id, "vehicles", 0)
+	assert.ErrorIs(t, err, user.ErrForbidden)
+	_, err = service.CreateCategory(ctx, admin.Id, " ", 0)
+	assert.ErrorIs(t, err, ads.ErrInvalidCategory)
+	_, err = service.CreateCategory(ctx, admin.Id, "bikes", 42)
+	assert.ErrorIs(t, err, ads.ErrUnknownCategory)
+	vehicles, err := service.CreateCategory(ctx, admin.Id, " vehicles ", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, "vehicles", vehicles.Name)
+	bikes, err := service.CreateCategory(ctx, admin.Id, "bikes", vehicles.ID)
+	assert.NoError(t, err)
+	furniture, err := service.CreateCategory(ctx, admin.Id, "furniture", 0)
+	assert.NoError(t, err)
+
+	// a category can't end up under itself
+	_, err = service.UpdateCategory(ctx, admin.Id, vehicles.ID, "vehicles", bikes.ID)
+	assert.ErrorIs(t, err, ads.ErrInvalidCategory)
+	_, err = service.UpdateCategory(ctx, admin.Id, 42, "missing", 0)
+	assert.ErrorIs(t, err, adrepo.ErrInvalidCategoryId)
+	_, err = service.UpdateCategory(ctx, seller.Id, bikes.ID, "bicycles", vehicles.ID)
+	assert.ErrorIs(t, err, user.ErrForbidden)
+	bikes, err = service.UpdateCategory(ctx, admin.Id, bikes.ID, "bicycles", vehicles.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "bicycles", bikes.Name)
+
+	_, err = service.CreateAd(ctx, "car", "text", ads.Money{}, 42, seller.Id)
+	assert.ErrorIs(t, err, ads.ErrUnknownCategory)
+	bike, err := service.CreateAd(ctx, "bike", "text", ads.Money{}, bikes.ID, seller.Id)
+	assert.NoError(t, err)
+	car, err := service.CreateAd(ctx, "car", "text", ads.Money{}, vehicles.ID, seller.Id)
+	assert.NoError(t, err)
+	desk, err := service.CreateAd(ctx, "desk", "text", ads.Money{}, furniture.ID, seller.Id)
+	assert.NoError(t, err)
+	for _, ad := range []*ads.Ad{bike, car, desk} {
+		_, err = service.ChangeAdStatus(ctx, ad.ID, seller.Id, true)
+		assert.NoError(t, err)
+	}
+	_, err = service.CreateAd(ctx, "draft", "text", ads.Money{}, bikes.ID, seller.Id)
+	assert.NoError(t, err)
+
+	// the filter takes in the subcategories
+	list, _, err := service.GetAll(ctx, ads.Filters{Status: ads.Published, CategoryId: vehicles.ID})
+	assert.NoError(t, err)
+	assert.Len(t, list, 2)
+	list, _, err = service.GetAll(ctx, ads.Filters{Status: ads.Published, CategoryId: bikes.ID})
+	assert.NoError(t, err)
+	assert.Len(t, list, 1)
+	_, _, err = service.GetAll(ctx, ads.Filters{Status: ads.Published, CategoryId: 42})
+	assert.ErrorIs(t, err, ads.ErrInvalidFilters)
+
+	counts, err := service.ListCategories(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []*ads.CategoryCount{
+		{Category: *vehicles, Ads: 2},
+		{Category: *bikes, Ads: 1},
+		{Category: *furniture, Ads: 1},
+	}, counts)
+	got, err := service.GetCategory(ctx, bikes.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, got.Ads)
+	_, err = service.GetCategory(ctx, 42)
+	assert.ErrorIs(t, err, adrepo.ErrInvalidCategoryId)
+
+	assert.ErrorIs(t, service.DeleteCategory(ctx, seller.Id, furniture.ID), user.ErrForbidden)
+	assert.ErrorIs(t, service.DeleteCategory(ctx, admin.Id, furniture.ID), ads.ErrCategoryInUse)
+	assert.NoError(t, service.DeleteAd(ctx, desk.ID, seller.Id, ""))
+	// the trashed ad still holds on to the category
+	assert.ErrorIs(t, service.DeleteCategory(ctx, admin.Id, furniture.ID), ads.ErrCategoryInUse)
+}
+
+func testPNG(t *testing.T, w, h int) []byte {
+	var buf bytes.Buffer
+	assert.NoError(t, png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, w, h))))
+	return buf.Bytes()
+}
+
+func TestImages(t *testing.T) {
+	ctx := context.Background()
+	users := userrepo.New(idgen.NewSequence())
+	adRepo := adrepo.New(idgen.NewSequence())
+	blobs := blob.NewMemory()
+	service := NewApp(memuow.New(adRepo, users), searchindex.New(), blobs)
+	author, other := user.User{Verified: true}, user.User{Verified: true}
+	for _, u := range []*user.User{&author, &other} {
+		_, err := users.CreateUser(ctx, u)
+		assert.NoError(t, err)
+	}
+	category := &ads.Category{Name: "misc"}
+	_, err := adRepo.AddCategory(ctx, category)
+	assert.NoError(t, err)
+	ad, err := service.CreateAd(ctx, "title", "text", ads.Money{}, category.ID, author.Id)
+	assert.NoError(t, err)
+
+	_, err = service.AddImage(ctx, ad.ID, other.Id, "", testPNG(t, 10, 10))
+	assert.ErrorIs(t, err, ads.ErrUserCantChangeThisAd)
+	_, err = service.AddImage(ctx, 42, author.Id, "", testPNG(t, 10, 10))
+	assert.ErrorIs(t, err, adrepo.ErrInvalidAdId)
+	_, err = service.AddImage(ctx, ad.ID, author.Id, "image/png", []byte("not an image"))
+	assert.ErrorIs(t, err, ads.ErrInvalidImage)
+	_, err = service.AddImage(ctx, ad.ID, author.Id, "image/jpeg", testPNG(t, 10, 10))
+	assert.ErrorIs(t, err, ads.ErrInvalidImage)
+	_, err = service.AddImage(ctx, ad.ID, author.Id, "", make([]byte, ads.MaxImageSize+1))
+	assert.ErrorIs(t, err, ads.ErrImageTooLarge)
+	// a small file of a huge image, it is turned down before it is decoded
+	huge := testPNG(t, 10, 10)
+	binary.BigEndian.PutUint32(huge[16:], 4097)
+	binary.BigEndian.PutUint32(huge[20:], 4096)
+	binary.BigEndian.PutUint32(huge[29:], crc32.ChecksumIEEE(huge[12:29]))
+	_, err = service.AddImage(ctx, ad.ID, author.Id, "", huge)
+	assert.ErrorIs(t, err, ads.ErrImageTooLarge)
+
+	cover, err := service.AddImage(ctx, ad.ID, author.Id, "", testPNG(t, 1200, 600))
+	assert.NoError(t, err)
+	assert.Equal(t, ads.Image{ID: cover.ID, AdID: ad.ID, ContentType: "image/png", Size: cover.Size,
+		Width: 1200, Height: 600, CreatedAt: cover.CreatedAt}, *cover)
+	keys, err := blobs.List(ctx, ads.AdImagesPrefix(ad.ID))
+	assert.NoError(t, err)
+	assert.Len(t, keys, 1+len(ads.Thumbnails))
+	_, data, err := service.GetImageFile(ctx, ad.ID, cover.ID, "small")
+	assert.NoError(t, err)
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	assert.NoError(t, err)
+	assert.Equal(t, "jpeg", format)
+	assert.Equal(t, [2]int{160, 80}, [2]int{cfg.Width, cfg.Height})
+
+	second, err := service.AddImage(ctx, ad.ID, author.Id, "image/png", testPNG(t, 10, 10))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, second.Position)
+	_, err = service.ReorderImages(ctx, ad.ID, other.Id, []int64{second.ID, cover.ID})
+	assert.ErrorIs(t, err, ads.ErrUserCantChangeThisAd)
+	_, err = service.ReorderImages(ctx, ad.ID, author.Id, []int64{second.ID})
+	assert.ErrorIs(t, err, ads.ErrInvalidImageOrder)
+	list, err := service.ReorderImages(ctx, ad.ID, author.Id, []int64{second.ID, cover.ID})
+	assert.NoError(t, err)
+	if assert.Len(t, list, 2) {
+		assert.Equal(t, []int64{second.ID, cover.ID}, []int64{list[0].ID, list[1].ID})
+	}
+
+	assert.ErrorIs(t, service.DeleteImage(ctx, ad.ID, other.Id, cover.ID), ads.ErrUserCantChangeThisAd)
+	assert.NoError(t, service.DeleteImage(ctx, ad.ID, author.Id, cover.ID))
+	assert.ErrorIs(t, service.DeleteImage(ctx, ad.ID, author.Id, cover.ID), adrepo.ErrInvalidImageId)
+	_, _, err = service.GetImageFile(ctx, ad.ID, cover.ID, ads.VariantOriginal)
+	assert.ErrorIs(t, err, adrepo.ErrInvalidImageId)
+	keys, err = blobs.List(ctx, ads.AdImagesPrefix(ad.ID))
+	assert.NoError(t, err)
+	assert.Len(t, keys, 1+len(ads.Thumbnails))
+
+	for i := 1; i < ads.MaxImagesPerAd; i++ {
+		_, err = service.AddImage(ctx, ad.ID, author.Id, "", testPNG(t, 1, 1))
+		assert.NoError(t, err)
+	}
+	_, err = service.AddImage(ctx, ad.ID, author.Id, "", testPNG(t, 1, 1))
+	assert.ErrorIs(t, err, ads.ErrTooManyImages)
+
+	// the files of the ads deleted for good are swept with the trash
+	gone, err := service.CreateAd(ctx, "gone", "text", ads.Money{}, category.ID, author.Id)
+	assert.NoError(t, err)
+	_, err = service.AddImage(ctx, gone.ID, author.Id, "", testPNG(t, 1, 1))
+	assert.NoError(t, err)
+	assert.NoError(t, service.DeleteAd(ctx, gone.ID, author.Id, ""))
+	assert.NoError(t, service.PurgeTrash(ctx, -time.Minute))
+	keys, err = blobs.List(ctx, ads.AdImagesPrefix(gone.ID))
+	assert.NoError(t, err)
+	assert.Empty(t, keys)
+	keys, err = blobs.List(ctx, ads.AdImagesPrefix(ad.ID))
+	assert.NoError(t, err)
+	assert.Len(t, keys, ads.MaxImagesPerAd*(1+len(ads.Thumbnails)))
+}
+
+func TestSchedule(t *testing.T) {
+	ctx := context.Background()
+	users := userrepo.New(idgen.NewSequence())
+	adRepo := adrepo.New(idgen.NewSequence())
+	service := NewApp(memuow.New(adRepo, users), searchindex.New(), blob.NewMemory()).(app)
+	now := time.Now().UTC()
+	clock := now
+	service.now = func() time.Time { return clock }
+	author, other := user.User{Verified: true}, user.User{Verified: true}
+	for _, u := range []*user.User{&author, &other} {
+		_, err := users.CreateUser(ctx, u)
+		assert.NoError(t, err)
+	}
+	category := &ads.Category{Name: "misc"}
+	_, err := adRepo.AddCategory(ctx, category)
+	assert.NoError(t, err)
+	ad, err := service.CreateAd(ctx, "title", "text", ads.Money{}, category.ID, author.Id)
+	assert.NoError(t, err)
+
+	_, err = service.ScheduleAd(ctx, ad.ID, other.Id, now.Add(time.Hour), time.Time{})
+	assert.ErrorIs(t, err, ads.ErrUserCantChangeThisAd)
+	_, err = service.ScheduleAd(ctx, ad.ID, author.Id, now.Add(-time.Hour), time.Time{})
+	assert.ErrorIs(t, err, ads.ErrInvalidSchedule)
+	_, err = service.ScheduleAd(ctx, ad.ID, author.Id, now.Add(time.Hour), now.Add(time.Minute))
+	assert.ErrorIs(t, err, ads.ErrInvalidSchedule)
+	ad, err = service.ScheduleAd(ctx, ad.ID, author.Id, now.Add(time.Hour), time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, ads.StateDraft, ad.State)
+	assert.Equal(t, now.Add(time.Hour), ad.PublishAt)
+	assert.Equal(t, now.Add(time.Hour+ads.Lifetime), ad.ExpiresAt)
+	changed, err := service.ApplySchedule(ctx)
+	assert.NoError(t, err)
+	assert.Zero(t, changed)
+
+	// the time comes
+	clock = now.Add(time.Hour)
+	changed, err = service.ApplySchedule(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, changed)
+	ad, err = service.GetAdById(ctx, ad.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, ads.StatePublished, ad.State)
+	assert.True(t, ad.PublishAt.IsZero())
+	assert.Equal(t, now.Add(time.Hour+ads.Lifetime), ad.ExpiresAt)
+
+	clock = now.Add(time.Hour + ads.Lifetime)
+	changed, err = service.ApplySchedule(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, changed)
+	revisions, err := service.GetRevisions(ctx, ad.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, revisions, 3) {
+		assert.Equal(t, ads.SchedulerActor, revisions[1].ActorID)
+		assert.Equal(t, ads.ReasonScheduled, revisions[1].Reason)
+		assert.Equal(t, ads.ReasonExpired, revisions[2].Reason)
+		assert.Equal(t, ads.StateArchived, revisions[2].State)
+	}
+
+	_, err = service.RenewAd(ctx, ad.ID, other.Id)
+	assert.ErrorIs(t, err, ads.ErrUserCantChangeThisAd)
+	ad, err = service.RenewAd(ctx, ad.ID, author.Id)
+	assert.NoError(t, err)
+	assert.Equal(t, ads.StatePublished, ad.State)
+	assert.Equal(t, clock.Add(ads.Lifetime), ad.ExpiresAt)
+
+	// scheduling a published ad takes it down until then
+	ad, err = service.ScheduleAd(ctx, ad.ID, author.Id, clock.Add(time.Hour), clock.Add(2*time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, ads.StateDraft, ad.State)
+	assert.Equal(t, clock.Add(2*time.Hour), ad.ExpiresAt)
+	_, err = service.RenewAd(ctx, ad.ID, author.Id)
+	assert.ErrorIs(t, err, ads.ErrCantRenew)
+	// and publishing it by hand cancels the schedule
+	ad, err = service.ChangeAdStatus(ctx, ad.ID, author.Id, true)
+	assert.NoError(t, err)
+	assert.True(t, ad.PublishAt.IsZero())
+	assert.Equal(t, clock.Add(2*time.Hour), ad.ExpiresAt)
+}
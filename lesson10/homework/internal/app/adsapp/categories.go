@@ -0,0 +1,138 @@
+package adsapp
+
+import (
+	"context"
+	"errors"
+	"homework10/internal/adapters/adrepo"
+	"homework10/internal/entities/ads"
+	"homework10/internal/entities/uow"
+	"homework10/internal/entities/user"
+)
+
+// ListCategories returns the whole tree, ordered by id, with the published
+// ads counted in every category together with its subcategories.
+func (a app) ListCategories(ctx context.Context) ([]*ads.CategoryCount, error) {
+	repo := a.tx.Repositories().Categories
+	list, err := repo.GetCategories(ctx)
+	if err != nil {
+		return nil, err
+	}
+	counts, err := repo.CountAds(ctx, ads.Published)
+	if err != nil {
+		return nil, err
+	}
+	return ads.CountCategories(list, counts), nil
+}
+
+func (a app) GetCategory(ctx context.Context, id int64) (*ads.CategoryCount, error) {
+	list, err := a.ListCategories(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range list {
+		if c.ID == id {
+			return c, nil
+		}
+	}
+	return nil, adrepo.ErrInvalidCategoryId
+}
+
+func (a app) CreateCategory(ctx context.Context, actorId int64, name string, parentId int64) (*ads.Category, error) {
+	c := &ads.Category{Name: name, ParentID: parentId}
+	if err := ads.ValidateCategory(c); err != nil {
+		return nil, err
+	}
+	err := a.tx.Do(ctx, func(repos uow.Repositories) error {
+		if err := checkManageCategories(ctx, repos, actorId); err != nil {
+			return err
+		}
+		if parentId != 0 {
+			if err := checkCategory(ctx, repos.Categories, parentId); err != nil {
+				return err
+			}
+		}
+		_, err := repos.Categories.AddCategory(ctx, c)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// UpdateCategory renames the category and moves it under another parent, a
+// category can't be moved under itself or any of its subcategories.
+func (a app) UpdateCategory(ctx context.Context, actorId, id int64, name string, parentId int64) (*ads.Category, error) {
+	c := &ads.Category{ID: id, Name: name, ParentID: parentId}
+	if err := ads.ValidateCategory(c); err != nil {
+		return nil, err
+	}
+	err := a.tx.Do(ctx, func(repos uow.Repositories) error {
+		if err := checkManageCategories(ctx, repos, actorId); err != nil {
+			return err
+		}
+		list, err := repos.Categories.GetCategories(ctx)
+		if err != nil {
+			return err
+		}
+		if !hasCategory(list, id) {
+			return adrepo.ErrInvalidCategoryId
+		}
+		if parentId != 0 {
+			if !hasCategory(list, parentId) {
+				return ads.ErrUnknownCategory
+			}
+			for _, sub := range ads.Subtree(list, id) {
+				if sub == parentId {
+					return ads.ErrInvalidCategory
+				}
+			}
+		}
+		c, err = repos.Categories.UpdateCategory(ctx, c)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// DeleteCategory fails with ads.ErrCategoryInUse while the category has
+// subcategories or ads, the trashed ones included.
+func (a app) DeleteCategory(ctx context.Context, actorId, id int64) error {
+	return a.tx.Do(ctx, func(repos uow.Repositories) error {
+		if err := checkManageCategories(ctx, repos, actorId); err != nil {
+			return err
+		}
+		return repos.Categories.DeleteCategory(ctx, id)
+	})
+}
+
+func checkManageCategories(ctx context.Context, repos uow.Repositories, actorId int64) error {
+	actor, err := repos.Users.GetUser(ctx, actorId)
+	if err != nil {
+		return err
+	}
+	if !actor.Role.Can(user.ManageCategories) {
+		return user.ErrForbidden
+	}
+	return nil
+}
+
+// checkCategory makes sure the category exists.
+func checkCategory(ctx context.Context, repo ads.CategoryRepository, id int64) error {
+	_, err := repo.GetCategory(ctx, id)
+	if errors.Is(err, adrepo.ErrInvalidCategoryId) {
+		return ads.ErrUnknownCategory
+	}
+	return err
+}
+
+func hasCategory(list []*ads.Category, id int64) bool {
+	for _, c := range list {
+		if c.ID == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,245 @@
+package adsapp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"homework10/internal/adapters/adrepo"
+	"homework10/internal/entities/ads"
+	"homework10/internal/entities/uow"
+	"homework10/pkg/blob"
+	"homework10/pkg/thumbnail"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"sort"
+	"time"
+)
+
+// imageFile is a variant of an uploaded image ready to be stored.
+type imageFile struct {
+	variant ads.ImageVariant
+	data    []byte
+}
+
+// AddImage stores the image with its thumbnails, contentType is the one the
+// client declared, if any.
+func (a app) AddImage(ctx context.Context, adId, userId int64, contentType string, data []byte) (*ads.Image, error) {
+	// the cheap checks go first, the image is decoded only for the author
+	if err := checkEditImages(ctx, a.tx.Repositories(), adId, userId); err != nil {
+		return nil, err
+	}
+	img, files, err := processImage(contentType, data)
+	if err != nil {
+		return nil, err
+	}
+	img.AdID = adId
+	img.CreatedAt = time.Now().UTC()
+	err = a.tx.Do(ctx, func(repos uow.Repositories) error {
+		if err := checkEditImages(ctx, repos, adId, userId); err != nil {
+			return err
+		}
+		list, err := repos.Images.GetImages(ctx, adId)
+		if err != nil {
+			return err
+		}
+		if len(list) >= ads.MaxImagesPerAd {
+			return ads.ErrTooManyImages
+		}
+		_, err = repos.Images.AddImage(ctx, img)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	// the image is stored before its files, so sweepImages never takes the
+	// files for leftovers
+	for _, f := range files {
+		if err = a.blobs.Put(ctx, ads.ImageKey(adId, img.ID, f.variant), f.data); err != nil {
+			_ = a.tx.Do(context.Background(), func(repos uow.Repositories) error {
+				return repos.Images.DeleteImage(context.Background(), adId, img.ID)
+			})
+			a.deleteImageFiles(img)
+			return nil, err
+		}
+	}
+	return img, nil
+}
+
+// processImage checks the upload and makes its thumbnails, each one from
+// the next larger one.
+func processImage(contentType string, data []byte) (*ads.Image, []imageFile, error) {
+	if len(data) > ads.MaxImageSize {
+		return nil, nil, ads.ErrImageTooLarge
+	}
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, ads.ErrInvalidImage
+	}
+	contentType, err = ads.CheckImageType(contentType, "image/"+format)
+	if err != nil {
+		return nil, nil, err
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > ads.MaxImagePixels {
+		return nil, nil, ads.ErrImageTooLarge
+	}
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, ads.ErrInvalidImage
+	}
+
+	variants := make([]ads.ImageVariant, 0, len(ads.Thumbnails))
+	for v := range ads.Thumbnails {
+		variants = append(variants, v)
+	}
+	sort.Slice(variants, func(i, j int) bool {
+		return ads.Thumbnails[variants[i]] > ads.Thumbnails[variants[j]]
+	})
+	files := []imageFile{{variant: ads.VariantOriginal, data: data}}
+	for _, v := range variants {
+		var thumb []byte
+		thumb, src, err = thumbnail.JPEG(src, ads.Thumbnails[v])
+		if err != nil {
+			return nil, nil, err
+		}
+		files = append(files, imageFile{variant: v, data: thumb})
+	}
+	img := &ads.Image{ContentType: contentType, Size: int64(len(data)), Width: cfg.Width, Height: cfg.Height}
+	return img, files, nil
+}
+
+// checkEditImages lets the author of the ad change its images.
+func checkEditImages(ctx context.Context, repos uow.Repositories, adId, userId int64) error {
+	actor, err := repos.Users.GetUser(ctx, userId)
+	if err != nil {
+		return err
+	}
+	ad, err := repos.Ads.GetAdById(ctx, adId)
+	if err != nil {
+		return err
+	}
+	return authorize(userId, actor, ad, actionEdit, "")
+}
+
+// GetImages lists the images of the ad, the cover first.
+func (a app) GetImages(ctx context.Context, adId int64) ([]*ads.Image, error) {
+	repos := a.tx.Repositories()
+	if _, err := repos.Ads.GetAdById(ctx, adId); err != nil {
+		return nil, err
+	}
+	return repos.Images.GetImages(ctx, adId)
+}
+
+// GetImageFile returns the image with the file of one of its variants.
+func (a app) GetImageFile(ctx context.Context, adId, imageId int64, variant ads.ImageVariant) (*ads.Image, []byte, error) {
+	list, err := a.GetImages(ctx, adId)
+	if err != nil {
+		return nil, nil, err
+	}
+	img := findImage(list, imageId)
+	if img == nil {
+		return nil, nil, adrepo.ErrInvalidImageId
+	}
+	data, _, err := a.blobs.Get(ctx, ads.ImageKey(adId, imageId, variant))
+	if errors.Is(err, blob.ErrNotFound) {
+		// the files are still being written
+		return nil, nil, adrepo.ErrInvalidImageId
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return img, data, nil
+}
+
+func findImage(list []*ads.Image, id int64) *ads.Image {
+	for _, img := range list {
+		if img.ID == id {
+			return img
+		}
+	}
+	return nil
+}
+
+// ReorderImages puts the images of the ad in the order of the ids, which
+// has to list all of them.
+func (a app) ReorderImages(ctx context.Context, adId, userId int64, order []int64) ([]*ads.Image, error) {
+	var list []*ads.Image
+	err := a.tx.Do(ctx, func(repos uow.Repositories) error {
+		if err := checkEditImages(ctx, repos, adId, userId); err != nil {
+			return err
+		}
+		var err error
+		list, err = repos.Images.ReorderImages(ctx, adId, order)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (a app) DeleteImage(ctx context.Context, adId, userId, imageId int64) error {
+	var img *ads.Image
+	err := a.tx.Do(ctx, func(repos uow.Repositories) error {
+		if err := checkEditImages(ctx, repos, adId, userId); err != nil {
+			return err
+		}
+		list, err := repos.Images.GetImages(ctx, adId)
+		if err != nil {
+			return err
+		}
+		if img = findImage(list, imageId); img == nil {
+			return adrepo.ErrInvalidImageId
+		}
+		return repos.Images.DeleteImage(ctx, adId, imageId)
+	})
+	if err != nil {
+		return err
+	}
+	// the files left behind by a failure here are swept later
+	a.deleteImageFiles(img)
+	return nil
+}
+
+func (a app) deleteImageFiles(img *ads.Image) {
+	ctx := context.Background()
+	_ = a.blobs.Delete(ctx, ads.ImageKey(img.AdID, img.ID, ads.VariantOriginal))
+	for v := range ads.Thumbnails {
+		_ = a.blobs.Delete(ctx, ads.ImageKey(img.AdID, img.ID, v))
+	}
+}
+
+// sweepImages deletes the files of the images no longer stored. An image is
+// stored before its files, listing them first keeps those of one being added.
+func (a app) sweepImages(ctx context.Context) error {
+	keys, err := a.blobs.List(ctx, "ads/")
+	if err != nil {
+		return err
+	}
+	repo := a.tx.Repositories().Images
+	known := make(map[int64]map[int64]bool)
+	for _, key := range keys {
+		adId, imageId, ok := ads.ParseImageKey(key)
+		if !ok {
+			continue
+		}
+		images, seen := known[adId]
+		if !seen {
+			list, err := repo.GetImages(ctx, adId)
+			if err != nil {
+				return err
+			}
+			images = make(map[int64]bool, len(list))
+			for _, img := range list {
+				images[img.ID] = true
+			}
+			known[adId] = images
+		}
+		if !images[imageId] {
+			if err = a.blobs.Delete(ctx, key); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package adsapp
+
+import (
+	"homework10/internal/entities/ads"
+	"homework10/internal/entities/user"
+	"strings"
+)
+
+// action is something a user does to an ad.
+type action int
+
+const (
+	// actionEdit changes the text or publishes the ad, only its author may
+	actionEdit action = iota
+	actionUnpublish
+	actionDelete
+)
+
+// authorize tells whether the user may do the action to the ad, moderators
+// may unpublish and delete the ads of others when they say why.
+func authorize(userId int64, actor *user.User, ad *ads.Ad, act action, reason string) error {
+	if ad.AuthorID == userId {
+		return nil
+	}
+	if act == actionEdit || !actor.Role.Can(user.ModerateAds) {
+		if act == actionDelete {
+			return ErrUnableToDelete
+		}
+		return ads.ErrUserCantChangeThisAd
+	}
+	if strings.TrimSpace(reason) == "" {
+		return ads.ErrReasonRequired
+	}
+	return nil
+}
+
+// mover is who may make a transition between two states of an ad.
+type mover int
+
+const (
+	byAuthor mover = 1 << iota
+	// byModerator is someone who may moderate the ads of others, never
+	// their own
+	byModerator
+	byScheduler
+)
+
+type transition struct {
+	from, to ads.State
+}
+
+// transitions are the only ways between the states, a rejected ad goes back
+// up only through another review.
+var transitions = map[transition]mover{
+	{ads.StateDraft, ads.StatePublished}:         byAuthor | byScheduler,
+	{ads.StateDraft, ads.StatePendingReview}:     byAuthor,
+	{ads.StatePendingReview, ads.StatePublished}: byModerator,
+	{ads.StatePendingReview, ads.StateRejected}:  byModerator,
+	{ads.StatePublished, ads.StateDraft}:         byAuthor,
+	{ads.StatePublished, ads.StateRejected}:      byModerator,
+	{ads.StatePublished, ads.StateArchived}:      byAuthor | byScheduler,
+	{ads.StatePublished, ads.StateSold}:          byAuthor,
+	{ads.StateRejected, ads.StatePendingReview}:  byAuthor,
+	{ads.StateArchived, ads.StatePublished}:      byAuthor,
+	{ads.StateArchived, ads.StateSold}:           byAuthor,
+}
+
+// authorizeTransition tells whether the user may move the ad to the state,
+// or leave it there, a moderator rejecting an ad has to say why.
+func authorizeTransition(userId int64, actor *user.User, ad *ads.Ad, to ads.State, reason string) error {
+	if ad.State == to {
+		act := actionUnpublish
+		if to == ads.StatePublished {
+			act = actionEdit
+		}
+		return authorize(userId, actor, ad, act, reason)
+	}
+	who, ok := transitions[transition{ad.State, to}]
+	if !ok {
+		return ads.ErrIllegalTransition
+	}
+	if ad.AuthorID == userId {
+		if who&byAuthor == 0 {
+			return ads.ErrUserCantChangeThisAd
+		}
+		return nil
+	}
+	if who&byModerator == 0 || !actor.Role.Can(user.ModerateAds) {
+		return ads.ErrUserCantChangeThisAd
+	}
+	if to == ads.StateRejected && strings.TrimSpace(reason) == "" {
+		return ads.ErrReasonRequired
+	}
+	return nil
+}
+
+// authorizePublishing tells whether the user may put the ad up: its author
+// has to verify their email first, a moderator approving it does not.
+func authorizePublishing(userId int64, actor *user.User, ad *ads.Ad, to ads.State) error {
+	if to == ads.StatePublished && ad.AuthorID == userId && !actor.Verified {
+		return user.ErrNotVerified
+	}
+	return nil
+}
+
+// schedulerMoves tells whether the scheduler may move the ad to the state.
+func schedulerMoves(ad *ads.Ad, to ads.State) bool {
+	return transitions[transition{ad.State, to}]&byScheduler != 0
+}
@@ -0,0 +1,169 @@
+package adsapp
+
+import (
+	"context"
+	"errors"
+	"homework10/internal/adapters/adrepo"
+	"homework10/internal/entities/ads"
+	"homework10/internal/entities/uow"
+	"homework10/internal/entities/user"
+	"time"
+)
+
+// ScheduleAd sets when the ad goes up and expires, a zero time leaves that
+// out. A scheduled ad is a draft until then and stays up for ads.Lifetime.
+func (a app) ScheduleAd(ctx context.Context, adId, userId int64, publishAt, expiresAt time.Time) (*ads.Ad, error) {
+	t := a.now().UTC()
+	publishAt, expiresAt = publishAt.UTC(), expiresAt.UTC()
+	if err := ads.ValidateSchedule(publishAt, expiresAt, t); err != nil {
+		return nil, err
+	}
+	var ad *ads.Ad
+	err := a.tx.Do(ctx, func(repos uow.Repositories) error {
+		actor, err := repos.Users.GetUser(ctx, userId)
+		if err != nil {
+			return err
+		}
+		ad, err = repos.Ads.GetAdById(ctx, adId)
+		if err != nil {
+			return err
+		}
+		if err = authorize(userId, actor, ad, actionEdit, ""); err != nil {
+			return err
+		}
+		if ad.State != ads.StateDraft && !ad.Published() {
+			return ads.ErrIllegalTransition
+		}
+		state := ad.State
+		if !publishAt.IsZero() {
+			state = ads.StateDraft
+			if err = authorizeTransition(userId, actor, ad, state, ""); err != nil {
+				return err
+			}
+			if !actor.Verified {
+				return user.ErrNotVerified
+			}
+		}
+		if expiresAt.IsZero() {
+			switch {
+			case !publishAt.IsZero():
+				expiresAt = publishAt.Add(ads.Lifetime)
+			case ad.Published():
+				expiresAt = ads.Expiration(ad.ExpiresAt, t)
+			}
+		}
+
+		prev := *ad
+		ad, err = repos.Schedule.UpdateAdPublication(ctx, adId, state, publishAt, expiresAt)
+		if err != nil {
+			return err
+		}
+		if prev.State == state {
+			return nil
+		}
+		return repos.Revisions.AddRevision(ctx, ads.NewRevision(&prev, ad, userId, t))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ad, nil
+}
+
+// RenewAd keeps the published or archived ad up for ads.Lifetime from now.
+func (a app) RenewAd(ctx context.Context, adId, userId int64) (*ads.Ad, error) {
+	t := a.now().UTC()
+	var ad *ads.Ad
+	err := a.tx.Do(ctx, func(repos uow.Repositories) error {
+		actor, err := repos.Users.GetUser(ctx, userId)
+		if err != nil {
+			return err
+		}
+		ad, err = repos.Ads.GetAdById(ctx, adId)
+		if err != nil {
+			return err
+		}
+		if err = authorize(userId, actor, ad, actionEdit, ""); err != nil {
+			return err
+		}
+		if ad.State != ads.StatePublished && ad.State != ads.StateArchived {
+			return ads.ErrCantRenew
+		}
+		if err = authorizeTransition(userId, actor, ad, ads.StatePublished, ""); err != nil {
+			return err
+		}
+		if !actor.Verified {
+			return user.ErrNotVerified
+		}
+
+		prev := *ad
+		expiresAt := t.Add(ads.Lifetime)
+		if ad.ExpiresAt.After(expiresAt) {
+			expiresAt = ad.ExpiresAt
+		}
+		ad, err = repos.Schedule.UpdateAdPublication(ctx, adId, ads.StatePublished, time.Time{}, expiresAt)
+		if err != nil {
+			return err
+		}
+		if prev.Published() {
+			return nil
+		}
+		return repos.Revisions.AddRevision(ctx, ads.NewRevision(&prev, ad, userId, t))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ad, nil
+}
+
+// ApplySchedule publishes and archives the ads that are due and returns how
+// many it changed, the revisions are made by ads.SchedulerActor.
+func (a app) ApplySchedule(ctx context.Context) (int, error) {
+	t := a.now().UTC()
+	due, err := a.tx.Repositories().Schedule.GetDueAds(ctx, t)
+	if err != nil {
+		return 0, err
+	}
+	changed := 0
+	for _, ad := range due {
+		ok, err := a.applyDue(ctx, ad.ID, t)
+		if err != nil {
+			return changed, err
+		}
+		if ok {
+			changed++
+		}
+	}
+	return changed, nil
+}
+
+func (a app) applyDue(ctx context.Context, adId int64, t time.Time) (bool, error) {
+	changed := false
+	err := a.tx.Do(ctx, func(repos uow.Repositories) error {
+		changed = false
+		// the ad may have been changed or deleted since it was listed
+		ad, err := repos.Ads.GetAdById(ctx, adId)
+		if errors.Is(err, adrepo.ErrInvalidAdId) {
+			return nil
+		}
+		if err != nil || !ad.Due(t) {
+			return err
+		}
+		to, reason := ads.StateArchived, ads.ReasonExpired
+		if ad.DuePublish(t) {
+			to, reason = ads.StatePublished, ads.ReasonScheduled
+		}
+		if !schedulerMoves(ad, to) {
+			return nil
+		}
+		prev := *ad
+		ad, err = moveAd(ctx, repos, ad, to, "", t)
+		if err != nil {
+			return err
+		}
+		rev := ads.NewRevision(&prev, ad, ads.SchedulerActor, t)
+		rev.Reason = reason
+		changed = true
+		return repos.Revisions.AddRevision(ctx, rev)
+	})
+	return changed, err
+}
@@ -0,0 +1,193 @@
+package authapp
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"homework10/internal/adapters/userrepo"
+	"homework10/internal/app/userapp"
+	"homework10/internal/entities/user"
+	"homework10/pkg/jwt"
+	"strconv"
+	"time"
+)
+
+var (
+	ErrInvalidToken = errors.New("invalid or expired token")
+)
+
+const (
+	accessUse  = "access"
+	refreshUse = "refresh"
+
+	DefaultAccessTTL  = 15 * time.Minute
+	DefaultRefreshTTL = 30 * 24 * time.Hour
+)
+
+// Tokens are issued on login. The access token authenticates requests until
+// ExpiresAt, the refresh token buys a new pair after that.
+type Tokens struct {
+	Access    string
+	Refresh   string
+	ExpiresAt time.Time
+}
+
+// Principal is the user a request is made on behalf of. KeyID is set when
+// the request came with an api key, which may only do what its Scopes allow.
+type Principal struct {
+	UserID int64
+	Role   user.Role
+	KeyID  string
+	Scopes []user.Scope
+}
+
+// Allows tells whether the principal may use the scope, the access tokens
+// allow everything the user may do.
+func (p *Principal) Allows(s user.Scope) bool {
+	if p.KeyID == "" {
+		return true
+	}
+	for _, granted := range p.Scopes {
+		if granted == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Client names the principal for the rate limits, the api keys of a user
+// are limited apart from their tokens.
+func (p *Principal) Client() string {
+	if p.KeyID != "" {
+		return "key:" + p.KeyID
+	}
+	return "user:" + strconv.FormatInt(p.UserID, 10)
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a copy of ctx that carries p.
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFrom returns the principal stored by WithPrincipal, false when the
+// request is anonymous.
+func PrincipalFrom(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(*Principal)
+	return p, ok && p != nil
+}
+
+type App interface {
+	Login(ctx context.Context, userId int64, password string) (*Tokens, error)
+	Refresh(ctx context.Context, refreshToken string) (*Tokens, error)
+	// Authenticate returns the principal the access token was issued to.
+	Authenticate(ctx context.Context, accessToken string) (*Principal, error)
+	// AuthenticateKey returns the principal the api key acts for, a bad key
+	// is ErrInvalidToken too.
+	AuthenticateKey(ctx context.Context, apiKey string) (*Principal, error)
+}
+
+type app struct {
+	users      userapp.App
+	signer     *jwt.Signer
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+	now        func() time.Time
+}
+
+func NewApp(users userapp.App, signer *jwt.Signer, accessTTL, refreshTTL time.Duration) App {
+	return app{users: users, signer: signer, accessTTL: accessTTL, refreshTTL: refreshTTL, now: time.Now}
+}
+
+func (a app) Login(ctx context.Context, userId int64, password string) (*Tokens, error) {
+	u, err := a.users.Authenticate(ctx, userId, password)
+	if err != nil {
+		return nil, err
+	}
+	return a.issue(u)
+}
+
+func (a app) Refresh(ctx context.Context, refreshToken string) (*Tokens, error) {
+	u, err := a.user(ctx, refreshToken, refreshUse)
+	if err != nil {
+		return nil, err
+	}
+	return a.issue(u)
+}
+
+// Authenticate reads the role from the user rather than from the token, so a
+// new role applies to the tokens issued before.
+func (a app) Authenticate(ctx context.Context, accessToken string) (*Principal, error) {
+	u, err := a.user(ctx, accessToken, accessUse)
+	if err != nil {
+		return nil, err
+	}
+	return &Principal{UserID: u.Id, Role: u.Role.OrDefault()}, nil
+}
+
+func (a app) AuthenticateKey(ctx context.Context, apiKey string) (*Principal, error) {
+	u, key, err := a.users.AuthenticateKey(ctx, apiKey)
+	if errors.Is(err, userapp.ErrInvalidAPIKey) {
+		return nil, ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Principal{UserID: u.Id, Role: u.Role.OrDefault(), KeyID: key.ID, Scopes: key.Scopes}, nil
+}
+
+// user returns the user the token was issued to, unless the user is gone or
+// their sessions were revoked after it was issued.
+func (a app) user(ctx context.Context, token, use string) (*user.User, error) {
+	claims, err := a.signer.Parse(token, a.now())
+	if err != nil || claims.Use != use {
+		return nil, ErrInvalidToken
+	}
+	userId, err := strconv.ParseInt(claims.Subject, 10, 64)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	u, err := a.users.GetUser(ctx, userId)
+	if errors.Is(err, userrepo.ErrInvalidUserId) {
+		return nil, ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	if claims.Version != u.SessionVersion {
+		return nil, ErrInvalidToken
+	}
+	return u, nil
+}
+
+func (a app) issue(u *user.User) (*Tokens, error) {
+	now := a.now()
+	tokens := &Tokens{ExpiresAt: now.Add(a.accessTTL)}
+	var err error
+	tokens.Access, err = a.sign(u, accessUse, now, tokens.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	tokens.Refresh, err = a.sign(u, refreshUse, now, now.Add(a.refreshTTL))
+	if err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+func (a app) sign(u *user.User, use string, now, expiresAt time.Time) (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	return a.signer.Sign(jwt.Claims{
+		Subject:   strconv.FormatInt(u.Id, 10),
+		ID:        hex.EncodeToString(id),
+		Use:       use,
+		Version:   u.SessionVersion,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: expiresAt.Unix(),
+	})
+}
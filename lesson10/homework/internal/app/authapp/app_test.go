@@ -0,0 +1,176 @@
+package authapp
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"homework10/internal/adapters/adrepo"
+	"homework10/internal/adapters/memuow"
+	"homework10/internal/adapters/userrepo"
+	"homework10/internal/app/userapp"
+	"homework10/internal/entities/user"
+	"homework10/pkg/idgen"
+	"homework10/pkg/jwt"
+	"homework10/pkg/mail"
+	"homework10/pkg/passwords"
+	"testing"
+	"time"
+)
+
+func newTestApp(t *testing.T) (app, userapp.App, user.Repository) {
+	repo := userrepo.New(idgen.NewSequence())
+	tx := memuow.New(adrepo.New(idgen.NewSequence()), repo)
+	signer, err := jwt.NewSigner([]byte("0123456789abcdef0123456789abcdef"))
+	assert.NoError(t, err)
+	users := userapp.NewApp(tx, passwords.NewForTest(), signer, mail.NewMemory())
+	_, err = users.CreateUser(context.Background(), "tester", "tester@mail.com", "password")
+	assert.NoError(t, err)
+	return NewApp(users, signer, time.Minute, time.Hour).(app), users, repo
+}
+
+func TestLogin(t *testing.T) {
+	a, _, _ := newTestApp(t)
+	ctx := context.Background()
+
+	tokens, err := a.Login(ctx, 0, "password")
+	assert.NoError(t, err)
+	principal, err := a.Authenticate(ctx, tokens.Access)
+	assert.NoError(t, err)
+	assert.Equal(t, &Principal{UserID: 0, Role: user.RoleUser}, principal)
+
+	_, err = a.Login(ctx, 0, "wrong password")
+	assert.ErrorIs(t, err, user.ErrWrongPassword)
+	_, err = a.Login(ctx, 1, "password")
+	assert.ErrorIs(t, err, userrepo.ErrInvalidUserId)
+}
+
+func TestTokenUse(t *testing.T) {
+	a, _, _ := newTestApp(t)
+	ctx := context.Background()
+	tokens, err := a.Login(ctx, 0, "password")
+	assert.NoError(t, err)
+
+	_, err = a.Authenticate(ctx, tokens.Refresh)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+	_, err = a.Refresh(ctx, tokens.Access)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+	_, err = a.Authenticate(ctx, "garbage")
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	refreshed, err := a.Refresh(ctx, tokens.Refresh)
+	assert.NoError(t, err)
+	principal, err := a.Authenticate(ctx, refreshed.Access)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), principal.UserID)
+}
+
+func TestTokenExpiry(t *testing.T) {
+	a, users, _ := newTestApp(t)
+	ctx := context.Background()
+	tokens, err := a.Login(ctx, 0, "password")
+	assert.NoError(t, err)
+
+	later := time.Now().Add(2 * time.Minute)
+	a.now = func() time.Time { return later }
+	_, err = a.Authenticate(ctx, tokens.Access)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+	refreshed, err := a.Refresh(ctx, tokens.Refresh)
+	assert.NoError(t, err)
+	_, err = a.Authenticate(ctx, refreshed.Access)
+	assert.NoError(t, err)
+
+	a.now = func() time.Time { return later.Add(2 * time.Hour) }
+	_, err = a.Refresh(ctx, refreshed.Refresh)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	// the refresh token of a deleted user is worthless
+	a.now = time.Now
+	assert.NoError(t, users.DeleteUser(ctx, 0, 0))
+	_, err = a.Refresh(ctx, tokens.Refresh)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestPrincipalRole(t *testing.T) {
+	a, _, repo := newTestApp(t)
+	ctx := context.Background()
+	tokens, err := a.Login(ctx, 0, "password")
+	assert.NoError(t, err)
+	principal, err := a.Authenticate(ctx, tokens.Access)
+	assert.NoError(t, err)
+	assert.Equal(t, user.RoleUser, principal.Role)
+
+	// the role is read on every request, the tokens issued before see the
+	// new one
+	_, err = repo.UpdateRole(ctx, 0, user.RoleModerator)
+	assert.NoError(t, err)
+	principal, err = a.Authenticate(ctx, tokens.Access)
+	assert.NoError(t, err)
+	assert.Equal(t, user.RoleModerator, principal.Role)
+
+	assert.NoError(t, repo.DeleteUser(ctx, 0))
+	_, err = a.Authenticate(ctx, tokens.Access)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestRevokedSessions(t *testing.T) {
+	a, users, _ := newTestApp(t)
+	ctx := context.Background()
+	tokens, err := a.Login(ctx, 0, "password")
+	assert.NoError(t, err)
+
+	_, err = users.UpdatePassword(ctx, 0, "password", "new password")
+	assert.NoError(t, err)
+	_, err = a.Authenticate(ctx, tokens.Access)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+	_, err = a.Refresh(ctx, tokens.Refresh)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	tokens, err = a.Login(ctx, 0, "new password")
+	assert.NoError(t, err)
+	_, err = a.Authenticate(ctx, tokens.Access)
+	assert.NoError(t, err)
+}
+
+func TestAuthenticateKey(t *testing.T) {
+	a, users, repo := newTestApp(t)
+	ctx := context.Background()
+	_, secret, err := users.CreateAPIKey(ctx, 0, "importer", []user.Scope{user.ScopeAdsRead}, time.Time{})
+	assert.NoError(t, err)
+	_, err = repo.UpdateRole(ctx, 0, user.RoleModerator)
+	assert.NoError(t, err)
+
+	principal, err := a.AuthenticateKey(ctx, secret)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), principal.UserID)
+	assert.Equal(t, user.RoleModerator, principal.Role)
+	assert.True(t, principal.Allows(user.ScopeAdsRead))
+	assert.False(t, principal.Allows(user.ScopeAdsWrite))
+
+	// a key is not a token and the other way round
+	_, err = a.Authenticate(ctx, secret)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+	tokens, err := a.Login(ctx, 0, "password")
+	assert.NoError(t, err)
+	_, err = a.AuthenticateKey(ctx, tokens.Access)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	// the access tokens are not limited to scopes
+	principal, err = a.Authenticate(ctx, tokens.Access)
+	assert.NoError(t, err)
+	assert.True(t, principal.Allows(user.ScopeAdsWrite))
+}
+
+func TestPrincipalContext(t *testing.T) {
+	ctx := context.Background()
+	_, ok := PrincipalFrom(ctx)
+	assert.False(t, ok)
+
+	p := &Principal{UserID: 3}
+	got, ok := PrincipalFrom(WithPrincipal(ctx, p))
+	assert.True(t, ok)
+	assert.Equal(t, p, got)
+}
+
+func TestPrincipalClient(t *testing.T) {
+	assert.Equal(t, "user:3", (&Principal{UserID: 3}).Client())
+	assert.Equal(t, "key:abc", (&Principal{UserID: 3, KeyID: "abc"}).Client())
+}
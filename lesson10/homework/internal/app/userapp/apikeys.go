@@ -0,0 +1,139 @@
+package userapp
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"homework10/internal/adapters/userrepo"
+	"homework10/internal/entities/uow"
+	"homework10/internal/entities/user"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidAPIKey = errors.New("invalid, expired or revoked api key")
+)
+
+const (
+	// a key reads hk_<id>_<secret>, the prefix makes leaked keys easy to
+	// grep for
+	apiKeyPrefix    = "hk_"
+	apiKeyIdLen     = 8
+	apiKeySecretLen = 32
+
+	// the last use of a key is stored at most this often, not on every
+	// request
+	touchEvery = time.Minute
+)
+
+// CreateAPIKey keeps only the hash of the secret, the key can't be shown
+// again.
+func (a app) CreateAPIKey(ctx context.Context, userId int64, name string, scopes []user.Scope,
+	expiresAt time.Time) (*user.APIKey, string, error) {
+	now := a.now().UTC()
+	key := &user.APIKey{
+		UserID:    userId,
+		Name:      name,
+		Scopes:    scopes,
+		CreatedAt: now,
+		ExpiresAt: expiresAt.UTC(),
+	}
+	if err := user.ValidateAPIKey(key, now); err != nil {
+		return nil, "", err
+	}
+	id, err := randomHex(apiKeyIdLen)
+	if err != nil {
+		return nil, "", err
+	}
+	secret, err := randomHex(apiKeySecretLen)
+	if err != nil {
+		return nil, "", err
+	}
+	key.ID, key.Hash = id, hashSecret(secret)
+	err = a.tx.Do(ctx, func(repos uow.Repositories) error {
+		if _, err := repos.Users.GetUser(ctx, userId); err != nil {
+			return err
+		}
+		return repos.Users.AddAPIKey(ctx, key)
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	return key, apiKeyPrefix + id + "_" + secret, nil
+}
+
+func (a app) ListAPIKeys(ctx context.Context, userId int64) ([]*user.APIKey, error) {
+	return a.tx.Repositories().Users.GetAPIKeys(ctx, userId)
+}
+
+// RevokeAPIKey deletes a key of the user, the keys of the others are
+// reported missing.
+func (a app) RevokeAPIKey(ctx context.Context, userId int64, keyId string) error {
+	return a.tx.Do(ctx, func(repos uow.Repositories) error {
+		key, err := repos.Users.GetAPIKey(ctx, keyId)
+		if err != nil {
+			return err
+		}
+		if key.UserID != userId {
+			return userrepo.ErrInvalidAPIKeyId
+		}
+		return repos.Users.DeleteAPIKey(ctx, keyId)
+	})
+}
+
+// AuthenticateKey returns the user the key acts for and the key, every key
+// that can't be used is ErrInvalidAPIKey.
+func (a app) AuthenticateKey(ctx context.Context, apiKey string) (*user.User, *user.APIKey, error) {
+	id, secret, ok := strings.Cut(strings.TrimPrefix(apiKey, apiKeyPrefix), "_")
+	if !ok || !strings.HasPrefix(apiKey, apiKeyPrefix) {
+		return nil, nil, ErrInvalidAPIKey
+	}
+	repo := a.tx.Repositories().Users
+	key, err := repo.GetAPIKey(ctx, id)
+	if errors.Is(err, userrepo.ErrInvalidAPIKeyId) {
+		return nil, nil, ErrInvalidAPIKey
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	now := a.now().UTC()
+	if subtle.ConstantTimeCompare([]byte(hashSecret(secret)), []byte(key.Hash)) != 1 || key.Expired(now) {
+		return nil, nil, ErrInvalidAPIKey
+	}
+	u, err := repo.GetUser(ctx, key.UserID)
+	if errors.Is(err, userrepo.ErrInvalidUserId) {
+		return nil, nil, ErrInvalidAPIKey
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if now.Sub(key.LastUsedAt) >= touchEvery {
+		// the key stays usable when its last use can't be stored
+		err = a.tx.Do(ctx, func(repos uow.Repositories) error {
+			return repos.Users.TouchAPIKey(ctx, key.ID, now)
+		})
+		if err == nil {
+			key.LastUsedAt = now
+		}
+	}
+	return u, key, nil
+}
+
+// hashSecret doesn't need a slow password hash, the secret is random and
+// long enough to not be guessed.
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -2,81 +2,277 @@ package userapp
 
 import (
 	"context"
+	"homework10/internal/entities/uow"
 	"homework10/internal/entities/user"
+	"homework10/pkg/jwt"
+	"homework10/pkg/mail"
+	"time"
 )
 
 type App interface {
+	// CreateUser mails the new user a token to verify their email with.
 	CreateUser(ctx context.Context, nickname, email, password string) (*user.User, error)
 	GetUser(ctx context.Context, id int64) (*user.User, error)
-	DeleteUser(ctx context.Context, id int64) error
-	ChangeNickname(ctx context.Context, id int64, nickname string) (*user.User, error)
-	UpdatePassword(ctx context.Context, id int64, password string) (*user.User, error)
+	// DeleteUser and ChangeNickname act on the actor, only admins may pass
+	// another user.
+	DeleteUser(ctx context.Context, actorId, id int64) error
+	ChangeNickname(ctx context.Context, actorId, id int64, nickname string) (*user.User, error)
+	// UpdatePassword needs the current password of the user, changing it
+	// ends all of their sessions.
+	UpdatePassword(ctx context.Context, id int64, current, password string) (*user.User, error)
+	// ChangeRole gives the user another role, only admins may do it.
+	ChangeRole(ctx context.Context, actorId, id int64, role user.Role) (*user.User, error)
+	// Authenticate checks the password of the user and replaces an outdated
+	// password hash with a fresh one.
+	Authenticate(ctx context.Context, id int64, password string) (*user.User, error)
+	// SendVerification mails the user another verification token.
+	SendVerification(ctx context.Context, id int64) error
+	// VerifyEmail marks the user the token was mailed to verified, a token
+	// can be used once.
+	VerifyEmail(ctx context.Context, token string) (*user.User, error)
+	// ForgotPassword mails the user with the email a password reset token.
+	ForgotPassword(ctx context.Context, email string) error
+	ResetPassword(ctx context.Context, token, password string) (*user.User, error)
+	// CreateAPIKey returns the key along with the secret key the client
+	// sends, the zero expiresAt makes a key that doesn't expire.
+	CreateAPIKey(ctx context.Context, userId int64, name string, scopes []user.Scope,
+		expiresAt time.Time) (*user.APIKey, string, error)
+	ListAPIKeys(ctx context.Context, userId int64) ([]*user.APIKey, error)
+	RevokeAPIKey(ctx context.Context, userId int64, keyId string) error
+	// AuthenticateKey returns the user the api key acts for and records the
+	// use of the key.
+	AuthenticateKey(ctx context.Context, apiKey string) (*user.User, *user.APIKey, error)
+	// ExportUser and EraseUser act on the data of the actor, only admins
+	// may pass another user.
+	ExportUser(ctx context.Context, actorId, id int64) (*Export, error)
+	EraseUser(ctx context.Context, actorId, id int64) error
+	// UpdateProfile changes the profile of the user, only admins may change
+	// the profile of another user.
+	UpdateProfile(ctx context.Context, actorId, id int64, profile user.Profile) (*user.User, error)
+	// GetProfile shows the profile to the viewer, user.Anonymous for the
+	// clients that didn't sign in.
+	GetProfile(ctx context.Context, viewerId, id int64) (*PublicProfile, error)
+	SearchUsers(ctx context.Context, search user.Search) ([]*user.User, string, error)
 }
 
 type app struct {
-	repo user.Repository
+	tx        uow.UnitOfWork
+	passwords user.PasswordHasher
+	signer    *jwt.Signer
+	mailer    mail.Sender
+	now       func() time.Time
 }
 
-func NewApp(repo user.Repository) App {
-	return app{repo: repo}
+func NewApp(tx uow.UnitOfWork, passwords user.PasswordHasher, signer *jwt.Signer, mailer mail.Sender) App {
+	return app{tx: tx, passwords: passwords, signer: signer, mailer: mailer, now: time.Now}
 }
 
 func (a app) CreateUser(ctx context.Context, nickname, email, password string) (*user.User, error) {
 	u := &user.User{
-		Nickname: nickname,
-		Email:    email,
-		Password: password,
+		Nickname:  nickname,
+		Email:     email,
+		Password:  password,
+		Role:      user.RoleUser,
+		CreatedAt: a.now().UTC(),
 	}
 	err := user.ValidateUser(u)
 	if err != nil {
 		return nil, user.ErrInvalidUserParams
 	}
-	id, err := a.repo.CreateUser(ctx, u)
+	if err = user.ValidateEmail(email); err != nil {
+		return nil, err
+	}
+	u.Password, err = a.passwords.Hash(password)
+	if err != nil {
+		return nil, err
+	}
+	// the user is not kept if the token can't be mailed
+	err = a.tx.Do(ctx, func(repos uow.Repositories) error {
+		id, err := repos.Users.CreateUser(ctx, u)
+		if err != nil {
+			return err
+		}
+		u.Id = id
+		return a.sendVerification(ctx, u)
+	})
 	if err != nil {
 		return nil, err
 	}
-	u.Id = id
 	return u, nil
 }
 
-func (a app) ChangeNickname(ctx context.Context, id int64, nickname string) (*user.User, error) {
+func (a app) ChangeNickname(ctx context.Context, actorId, id int64, nickname string) (*user.User, error) {
 	err := user.ValidateUser(&user.User{Nickname: nickname, Password: "mockpass"})
 	if err != nil {
 		return nil, user.ErrInvalidUserParams
 	}
-	u, err := a.repo.GetUser(ctx, id)
+	var u *user.User
+	err = a.tx.Do(ctx, func(repos uow.Repositories) error {
+		if err := checkManage(ctx, repos, actorId, id); err != nil {
+			return err
+		}
+		u, err = repos.Users.GetUser(ctx, id)
+		if err != nil {
+			return err
+		}
+		if u.Nickname == nickname {
+			return nil
+		}
+		u, err = repos.Users.UpdateNick(ctx, id, nickname)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
-	if u.Nickname == nickname {
-		return u, nil
-	}
-	return a.repo.UpdateNick(ctx, id, nickname)
+	return u, nil
 }
 
-func (a app) UpdatePassword(ctx context.Context, id int64, password string) (*user.User, error) {
+func (a app) UpdatePassword(ctx context.Context, id int64, current, password string) (*user.User, error) {
 	err := user.ValidateUser(&user.User{Nickname: "mocknick", Password: password})
 	if err != nil {
 		return nil, user.ErrInvalidUserParams
 	}
-	u, err := a.repo.GetUser(ctx, id)
+	// hashing is slow, so it is done outside of the transaction, which then
+	// makes sure the password was not changed meanwhile
+	u, err := a.tx.Repositories().Users.GetUser(ctx, id)
 	if err != nil {
 		return nil, err
 	}
-	if u.Password == password {
+	ok, _, err := a.passwords.Verify(u.Password, current)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, user.ErrWrongPassword
+	}
+	if current == password {
 		return u, nil
 	}
-	return a.repo.UpdatePassword(ctx, id, password)
+	hash, err := a.passwords.Hash(password)
+	if err != nil {
+		return nil, err
+	}
+	verified := u.Password
+	err = a.tx.Do(ctx, func(repos uow.Repositories) error {
+		u, err = repos.Users.GetUser(ctx, id)
+		if err != nil {
+			return err
+		}
+		if u.Password != verified {
+			return user.ErrWrongPassword
+		}
+		u, err = a.setPassword(ctx, repos, id, hash)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return u, nil
 }
 
-func (a app) DeleteUser(ctx context.Context, id int64) error {
-	_, err := a.repo.GetUser(ctx, id)
+func (a app) ChangeRole(ctx context.Context, actorId, id int64, role user.Role) (*user.User, error) {
+	if _, err := user.ParseRole(string(role)); err != nil {
+		return nil, err
+	}
+	var u *user.User
+	err := a.tx.Do(ctx, func(repos uow.Repositories) error {
+		actor, err := repos.Users.GetUser(ctx, actorId)
+		if err != nil {
+			return err
+		}
+		if !actor.Role.Can(user.ManageRoles) {
+			return user.ErrForbidden
+		}
+		u, err = repos.Users.GetUser(ctx, id)
+		if err != nil {
+			return err
+		}
+		if u.Role.OrDefault() == role {
+			return nil
+		}
+		u, err = repos.Users.UpdateRole(ctx, id, role)
+		return err
+	})
 	if err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// Authenticate rehashes an outdated hash outside of the transaction, a
+// password changed meanwhile turns the login down.
+func (a app) Authenticate(ctx context.Context, id int64, password string) (*user.User, error) {
+	u, err := a.tx.Repositories().Users.GetUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	ok, rehash, err := a.passwords.Verify(u.Password, password)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, user.ErrWrongPassword
+	}
+	if !rehash {
+		return u, nil
+	}
+	hash, err := a.passwords.Hash(password)
+	if err != nil {
+		return nil, err
+	}
+	verified := u.Password
+	err = a.tx.Do(ctx, func(repos uow.Repositories) error {
+		current, err := repos.Users.GetUser(ctx, id)
+		if err != nil {
+			return err
+		}
+		if current.Password != verified {
+			return user.ErrWrongPassword
+		}
+		u, err = repos.Users.UpdatePassword(ctx, id, hash)
 		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// DeleteUser removes the user together with all of their ads.
+func (a app) DeleteUser(ctx context.Context, actorId, id int64) error {
+	return a.tx.Do(ctx, func(repos uow.Repositories) error {
+		err := checkManage(ctx, repos, actorId, id)
+		if err != nil {
+			return err
+		}
+		_, err = repos.Users.GetUser(ctx, id)
+		if err != nil {
+			return err
+		}
+		err = repos.Ads.DeleteAdsByAuthor(ctx, id)
+		if err != nil {
+			return err
+		}
+		return repos.Users.DeleteUser(ctx, id)
+	})
+}
+
+// checkManage lets users manage their own account and the ones with
+// user.ManageUsers the account of anyone.
+func checkManage(ctx context.Context, repos uow.Repositories, actorId, id int64) error {
+	if actorId == id {
+		return nil
+	}
+	actor, err := repos.Users.GetUser(ctx, actorId)
+	if err != nil {
+		return err
+	}
+	if !actor.Role.Can(user.ManageUsers) {
+		return user.ErrForbidden
 	}
-	return a.repo.DeleteUser(ctx, id)
+	return nil
 }
 
 func (a app) GetUser(ctx context.Context, id int64) (*user.User, error) {
-	return a.repo.GetUser(ctx, id)
+	return a.tx.Repositories().Users.GetUser(ctx, id)
 }
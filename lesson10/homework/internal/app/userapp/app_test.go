@@ -2,31 +2,53 @@ package userapp
 
 import (
 	"context"
+	"errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"homework10/internal/adapters/adrepo"
+	"homework10/internal/adapters/memuow"
 	"homework10/internal/adapters/userrepo"
+	adMocks "homework10/internal/app/adsapp/mocks"
 	"homework10/internal/app/userapp/mocks"
+	"homework10/internal/entities/ads"
+	"homework10/internal/entities/uow"
 	"homework10/internal/entities/user"
+	"homework10/pkg/idgen"
+	"homework10/pkg/jwt"
+	"homework10/pkg/mail"
+	"homework10/pkg/passwords"
+	"strings"
 	"testing"
+	"time"
 )
 
+var testSigner, _ = jwt.NewSigner([]byte("0123456789abcdef0123456789abcdef"))
+
+func newTestApp(tx uow.UnitOfWork, hasher user.PasswordHasher) App {
+	return NewApp(tx, hasher, testSigner, mail.NewMemory())
+}
+
 func TestUserService_CreateUserOK(t *testing.T) {
 	repo := &mocks.Repository{}
 	repo.On("CreateUser", mock.AnythingOfType("*context.emptyCtx"), mock.AnythingOfType("*user.User")).
 		Return(int64(0), nil)
 
-	service := NewApp(repo)
+	service := newTestApp(uow.Passthrough(&adMocks.Store{}, repo), passwords.NewForTest())
 
 	u, err := service.CreateUser(context.Background(), "test", "test@gmail.com", "password")
 	assert.Zero(t, u.Id)
 	assert.Equal(t, u.Nickname, "test")
 	assert.Equal(t, u.Email, "test@gmail.com")
 	assert.Nil(t, err)
+	assert.NotEqual(t, u.Password, "password")
+	ok, _, err := passwords.NewForTest().Verify(u.Password, "password")
+	assert.Nil(t, err)
+	assert.True(t, ok)
 }
 
 func TestUserService_CreateUserInvalidParams(t *testing.T) {
 	repo := &mocks.Repository{}
-	service := NewApp(repo)
+	service := newTestApp(uow.Passthrough(&adMocks.Store{}, repo), passwords.NewForTest())
 
 	_, _ = service.CreateUser(context.Background(), "", "", "")
 	assert.Error(t, user.ErrInvalidUserParams)
@@ -37,7 +59,7 @@ func TestUserService_GetUserOK(t *testing.T) {
 	repo.On("GetUser", mock.AnythingOfType("*context.emptyCtx"), mock.AnythingOfType("int64")).
 		Return(&user.User{Id: 0}, nil)
 
-	service := NewApp(repo)
+	service := newTestApp(uow.Passthrough(&adMocks.Store{}, repo), passwords.NewForTest())
 
 	u, err := service.GetUser(context.Background(), int64(0))
 	assert.Nil(t, err)
@@ -50,11 +72,15 @@ func TestUserService_DeleteUserOK(t *testing.T) {
 		Return(&user.User{Id: 0}, nil)
 	repo.On("DeleteUser", mock.AnythingOfType("*context.emptyCtx"), mock.AnythingOfType("int64")).
 		Return(nil)
+	adRepo := &adMocks.Store{}
+	adRepo.On("DeleteAdsByAuthor", mock.AnythingOfType("*context.emptyCtx"), int64(0)).
+		Return(nil)
 
-	service := NewApp(repo)
+	service := newTestApp(uow.Passthrough(adRepo, repo), passwords.NewForTest())
 
-	err := service.DeleteUser(context.Background(), int64(0))
+	err := service.DeleteUser(context.Background(), int64(0), int64(0))
 	assert.Nil(t, err)
+	adRepo.AssertExpectations(t)
 }
 
 func TestUserService_DeleteUserInvalidUserId(t *testing.T) {
@@ -62,25 +88,46 @@ func TestUserService_DeleteUserInvalidUserId(t *testing.T) {
 	repo.On("GetUser", mock.AnythingOfType("*context.emptyCtx"), mock.AnythingOfType("int64")).
 		Return(&user.User{Id: 0}, userrepo.ErrInvalidUserId)
 
-	service := NewApp(repo)
+	service := newTestApp(uow.Passthrough(&adMocks.Store{}, repo), passwords.NewForTest())
 
-	_ = service.DeleteUser(context.Background(), int64(0))
+	_ = service.DeleteUser(context.Background(), int64(0), int64(0))
 	assert.Error(t, userrepo.ErrInvalidUserId)
 }
 
 func TestUserService_UpdatePasswordOK(t *testing.T) {
+	hash, err := passwords.NewForTest().Hash("password")
+	assert.Nil(t, err)
 	repo := &mocks.Repository{}
 	repo.On("GetUser", mock.AnythingOfType("*context.emptyCtx"), mock.AnythingOfType("int64")).
-		Return(&user.User{Id: 0}, nil)
+		Return(&user.User{Id: 0, Password: hash}, nil)
 	repo.On("UpdatePassword", mock.AnythingOfType("*context.emptyCtx"), mock.AnythingOfType("int64"),
 		mock.AnythingOfType("string")).
 		Return(&user.User{Id: 0, Password: "new password"}, nil)
+	repo.On("RevokeSessions", mock.AnythingOfType("*context.emptyCtx"), int64(0)).
+		Return(&user.User{Id: 0, Password: "new password", SessionVersion: 1}, nil)
 
-	service := NewApp(repo)
+	service := newTestApp(uow.Passthrough(&adMocks.Store{}, repo), passwords.NewForTest())
 
-	u, err := service.UpdatePassword(context.Background(), int64(0), "new password")
+	u, err := service.UpdatePassword(context.Background(), int64(0), "password", "new password")
 	assert.Nil(t, err)
 	assert.Equal(t, u.Password, "new password")
+	assert.Equal(t, int64(1), u.SessionVersion)
+	repo.AssertExpectations(t)
+}
+
+func TestUserService_UpdatePasswordWrongPassword(t *testing.T) {
+	hash, err := passwords.NewForTest().Hash("password")
+	assert.Nil(t, err)
+	repo := &mocks.Repository{}
+	repo.On("GetUser", mock.AnythingOfType("*context.emptyCtx"), mock.AnythingOfType("int64")).
+		Return(&user.User{Id: 0, Password: hash}, nil)
+
+	service := newTestApp(uow.Passthrough(&adMocks.Store{}, repo), passwords.NewForTest())
+
+	_, err = service.UpdatePassword(context.Background(), int64(0), "wrong password", "new password")
+	assert.ErrorIs(t, err, user.ErrWrongPassword)
+	repo.AssertNotCalled(t, "UpdatePassword", mock.Anything, mock.Anything, mock.Anything)
+	repo.AssertNotCalled(t, "RevokeSessions", mock.Anything, mock.Anything)
 }
 
 func TestUserService_UpdatePasswordInvalidUserId(t *testing.T) {
@@ -88,9 +135,9 @@ func TestUserService_UpdatePasswordInvalidUserId(t *testing.T) {
 	repo.On("GetUser", mock.AnythingOfType("*context.emptyCtx"), mock.AnythingOfType("int64")).
 		Return(&user.User{Id: 0}, userrepo.ErrInvalidUserId)
 
-	service := NewApp(repo)
+	service := newTestApp(uow.Passthrough(&adMocks.Store{}, repo), passwords.NewForTest())
 
-	_, _ = service.UpdatePassword(context.Background(), int64(0), "new password")
+	_, _ = service.UpdatePassword(context.Background(), int64(0), "password", "new password")
 	assert.Error(t, userrepo.ErrInvalidUserId)
 }
 
@@ -102,20 +149,534 @@ func TestUserService_ChangeNicknameOK(t *testing.T) {
 		mock.AnythingOfType("string")).
 		Return(&user.User{Id: 0, Nickname: "new nickname"}, nil)
 
-	service := NewApp(repo)
+	service := newTestApp(uow.Passthrough(&adMocks.Store{}, repo), passwords.NewForTest())
 
-	u, err := service.ChangeNickname(context.Background(), int64(0), "new nickname")
+	u, err := service.ChangeNickname(context.Background(), int64(0), int64(0), "new nickname")
 	assert.Nil(t, err)
 	assert.Equal(t, u.Nickname, "new nickname")
 }
 
+func TestUserService_ManageOthers(t *testing.T) {
+	repo := &mocks.Repository{}
+	repo.On("GetUser", mock.AnythingOfType("*context.emptyCtx"), int64(1)).
+		Return(&user.User{Id: 1, Role: user.RoleUser}, nil)
+	adRepo := &adMocks.Store{}
+
+	service := newTestApp(uow.Passthrough(adRepo, repo), passwords.NewForTest())
+
+	_, err := service.ChangeNickname(context.Background(), int64(1), int64(0), "stolen")
+	assert.ErrorIs(t, err, user.ErrForbidden)
+	assert.ErrorIs(t, service.DeleteUser(context.Background(), int64(1), int64(0)), user.ErrForbidden)
+	repo.AssertNotCalled(t, "UpdateNick", mock.Anything, mock.Anything, mock.Anything)
+	repo.AssertNotCalled(t, "DeleteUser", mock.Anything, mock.Anything)
+	adRepo.AssertNotCalled(t, "DeleteAdsByAuthor", mock.Anything, mock.Anything)
+}
+
 func TestUserService_ChangeNicknameInvalidUserId(t *testing.T) {
 	repo := &mocks.Repository{}
 	repo.On("GetUser", mock.AnythingOfType("*context.emptyCtx"), mock.AnythingOfType("int64")).
 		Return(&user.User{Id: 0}, userrepo.ErrInvalidUserId)
 
-	service := NewApp(repo)
+	service := newTestApp(uow.Passthrough(&adMocks.Store{}, repo), passwords.NewForTest())
 
-	_, _ = service.ChangeNickname(context.Background(), int64(0), "new nickname")
+	_, _ = service.ChangeNickname(context.Background(), int64(0), int64(0), "new nickname")
 	assert.Error(t, userrepo.ErrInvalidUserId)
 }
+
+func TestUserService_AuthenticateOK(t *testing.T) {
+	hasher := passwords.NewForTest()
+	hash, err := hasher.Hash("password")
+	assert.Nil(t, err)
+	repo := &mocks.Repository{}
+	repo.On("GetUser", mock.AnythingOfType("*context.emptyCtx"), int64(1)).
+		Return(&user.User{Id: 1, Password: hash}, nil)
+
+	service := newTestApp(uow.Passthrough(&adMocks.Store{}, repo), hasher)
+
+	u, err := service.Authenticate(context.Background(), int64(1), "password")
+	assert.Nil(t, err)
+	assert.Equal(t, u.Id, int64(1))
+	repo.AssertNotCalled(t, "UpdatePassword", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestUserService_AuthenticateWrongPassword(t *testing.T) {
+	repo := &mocks.Repository{}
+	repo.On("GetUser", mock.AnythingOfType("*context.emptyCtx"), int64(1)).
+		Return(&user.User{Id: 1, Password: "password"}, nil)
+
+	service := newTestApp(uow.Passthrough(&adMocks.Store{}, repo), passwords.NewForTest().AllowPlaintext())
+
+	_, err := service.Authenticate(context.Background(), int64(1), "wrong password")
+	assert.ErrorIs(t, err, user.ErrWrongPassword)
+	repo.AssertNotCalled(t, "UpdatePassword", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestUserService_AuthenticateMigratesPassword(t *testing.T) {
+	hasher := passwords.NewForTest().AllowPlaintext()
+	isHash := mock.MatchedBy(func(hash string) bool {
+		ok, rehash, err := hasher.Verify(hash, "password")
+		return err == nil && ok && !rehash
+	})
+	repo := &mocks.Repository{}
+	repo.On("GetUser", mock.AnythingOfType("*context.emptyCtx"), int64(1)).
+		Return(&user.User{Id: 1, Password: "password"}, nil)
+	repo.On("UpdatePassword", mock.AnythingOfType("*context.emptyCtx"), int64(1), isHash).
+		Return(&user.User{Id: 1, Password: "hash"}, nil)
+
+	service := newTestApp(uow.Passthrough(&adMocks.Store{}, repo), hasher)
+
+	u, err := service.Authenticate(context.Background(), int64(1), "password")
+	assert.Nil(t, err)
+	assert.Equal(t, u.Password, "hash")
+	repo.AssertExpectations(t)
+}
+
+func TestUserService_PasswordChangedMeanwhile(t *testing.T) {
+	hasher := passwords.NewForTest().AllowPlaintext()
+	hash, err := hasher.Hash("other password")
+	assert.Nil(t, err)
+	// the password is changed between the check and the transaction
+	repo := &mocks.Repository{}
+	repo.On("GetUser", mock.AnythingOfType("*context.emptyCtx"), int64(1)).
+		Return(&user.User{Id: 1, Password: "password"}, nil).Once()
+	repo.On("GetUser", mock.AnythingOfType("*context.emptyCtx"), int64(1)).
+		Return(&user.User{Id: 1, Password: hash}, nil).Once()
+
+	service := newTestApp(uow.Passthrough(&adMocks.Store{}, repo), hasher)
+
+	_, err = service.Authenticate(context.Background(), int64(1), "password")
+	assert.ErrorIs(t, err, user.ErrWrongPassword)
+
+	repo.On("GetUser", mock.AnythingOfType("*context.emptyCtx"), int64(1)).
+		Return(&user.User{Id: 1, Password: "password"}, nil).Once()
+	repo.On("GetUser", mock.AnythingOfType("*context.emptyCtx"), int64(1)).
+		Return(&user.User{Id: 1, Password: hash}, nil).Once()
+
+	_, err = service.UpdatePassword(context.Background(), int64(1), "password", "new password")
+	assert.ErrorIs(t, err, user.ErrWrongPassword)
+	repo.AssertNotCalled(t, "UpdatePassword", mock.Anything, mock.Anything, mock.Anything)
+	repo.AssertExpectations(t)
+}
+
+func TestUserService_CreateUserInvalidEmail(t *testing.T) {
+	repo := &mocks.Repository{}
+	service := newTestApp(uow.Passthrough(&adMocks.Store{}, repo), passwords.NewForTest())
+
+	for _, email := range []string{"", "tester", "tester@", "@mail.com", "Tester <tester@mail.com>", " tester@mail.com"} {
+		_, err := service.CreateUser(context.Background(), "tester", email, "password")
+		assert.ErrorIs(t, err, user.ErrInvalidEmail, email)
+	}
+	repo.AssertNotCalled(t, "CreateUser", mock.Anything, mock.Anything)
+}
+
+type failingSender struct{}
+
+func (failingSender) Send(context.Context, mail.Message) error {
+	return errors.New("mail server is down")
+}
+
+// mailedToken returns the token of the last mail sent to the address.
+func mailedToken(t *testing.T, mailer *mail.Memory, to string) string {
+	t.Helper()
+	sent := mailer.Sent(to)
+	if !assert.NotEmpty(t, sent) {
+		return ""
+	}
+	lines := strings.Split(sent[len(sent)-1].Body, "\n")
+	return lines[len(lines)-1]
+}
+
+func TestEmailVerification(t *testing.T) {
+	ctx := context.Background()
+	mailer := mail.NewMemory()
+	tx := memuow.New(adrepo.New(idgen.NewSequence()), userrepo.New(idgen.NewSequence()))
+	a := NewApp(tx, passwords.NewForTest(), testSigner, mailer).(app)
+
+	u, err := a.CreateUser(ctx, "tester", "Tester@mail.com", "password")
+	assert.NoError(t, err)
+	assert.False(t, u.Verified)
+	_, err = a.CreateUser(ctx, "other", "tester@MAIL.com", "password")
+	assert.ErrorIs(t, err, user.ErrEmailTaken)
+
+	first := mailedToken(t, mailer, "Tester@mail.com")
+	assert.NoError(t, a.SendVerification(ctx, u.Id))
+	second := mailedToken(t, mailer, "Tester@mail.com")
+	assert.NotEqual(t, first, second)
+
+	_, err = a.VerifyEmail(ctx, "garbage")
+	assert.ErrorIs(t, err, ErrInvalidVerifyToken)
+	access, err := testSigner.Sign(jwt.Claims{Subject: "0", Use: "access", ExpiresAt: time.Now().Add(time.Hour).Unix()})
+	assert.NoError(t, err)
+	_, err = a.VerifyEmail(ctx, access)
+	assert.ErrorIs(t, err, ErrInvalidVerifyToken)
+
+	verified, err := a.VerifyEmail(ctx, first)
+	assert.NoError(t, err)
+	assert.True(t, verified.Verified)
+	// every token is single-use, the older ones die with it
+	_, err = a.VerifyEmail(ctx, first)
+	assert.ErrorIs(t, err, ErrInvalidVerifyToken)
+	_, err = a.VerifyEmail(ctx, second)
+	assert.ErrorIs(t, err, ErrInvalidVerifyToken)
+	assert.ErrorIs(t, a.SendVerification(ctx, u.Id), ErrAlreadyVerified)
+}
+
+func TestEmailVerificationExpiry(t *testing.T) {
+	ctx := context.Background()
+	mailer := mail.NewMemory()
+	tx := memuow.New(adrepo.New(idgen.NewSequence()), userrepo.New(idgen.NewSequence()))
+	a := NewApp(tx, passwords.NewForTest(), testSigner, mailer).(app)
+	u, err := a.CreateUser(ctx, "tester", "tester@mail.com", "password")
+	assert.NoError(t, err)
+	token := mailedToken(t, mailer, "tester@mail.com")
+
+	a.now = func() time.Time { return time.Now().Add(VerifyTTL) }
+	_, err = a.VerifyEmail(ctx, token)
+	assert.ErrorIs(t, err, ErrInvalidVerifyToken)
+
+	// the token of a deleted user is worthless
+	a.now = time.Now
+	assert.NoError(t, a.DeleteUser(ctx, u.Id, u.Id))
+	_, err = a.VerifyEmail(ctx, token)
+	assert.ErrorIs(t, err, ErrInvalidVerifyToken)
+}
+
+func TestCreateUserMailFails(t *testing.T) {
+	ctx := context.Background()
+	repo := userrepo.New(idgen.NewSequence())
+	tx := memuow.New(adrepo.New(idgen.NewSequence()), repo)
+	a := NewApp(tx, passwords.NewForTest(), testSigner, failingSender{})
+
+	_, err := a.CreateUser(ctx, "tester", "tester@mail.com", "password")
+	assert.Error(t, err)
+	// the user is gone, the email is free again
+	_, err = repo.GetUser(ctx, 0)
+	assert.ErrorIs(t, err, userrepo.ErrInvalidUserId)
+	_, err = NewApp(tx, passwords.NewForTest(), testSigner, mail.NewMemory()).
+		CreateUser(ctx, "tester", "tester@mail.com", "password")
+	assert.NoError(t, err)
+}
+
+func TestPasswordReset(t *testing.T) {
+	ctx := context.Background()
+	mailer := mail.NewMemory()
+	tx := memuow.New(adrepo.New(idgen.NewSequence()), userrepo.New(idgen.NewSequence()))
+	a := NewApp(tx, passwords.NewForTest(), testSigner, mailer).(app)
+	u, err := a.CreateUser(ctx, "tester", "tester@mail.com", "password")
+	assert.NoError(t, err)
+	verify := mailedToken(t, mailer, "tester@mail.com")
+
+	// nobody learns whether the email is known
+	assert.NoError(t, a.ForgotPassword(ctx, "nobody@mail.com"))
+	assert.Empty(t, mailer.Sent("nobody@mail.com"))
+
+	assert.NoError(t, a.ForgotPassword(ctx, "TESTER@mail.com"))
+	first := mailedToken(t, mailer, "tester@mail.com")
+	assert.NoError(t, a.ForgotPassword(ctx, "tester@mail.com"))
+	second := mailedToken(t, mailer, "tester@mail.com")
+
+	_, err = a.ResetPassword(ctx, first, "new")
+	assert.ErrorIs(t, err, user.ErrInvalidUserParams)
+	_, err = a.ResetPassword(ctx, "garbage", "new password")
+	assert.ErrorIs(t, err, ErrInvalidResetToken)
+	// a verification token can't reset the password
+	_, err = a.ResetPassword(ctx, verify, "new password")
+	assert.ErrorIs(t, err, ErrInvalidResetToken)
+
+	reset, err := a.ResetPassword(ctx, second, "new password")
+	assert.NoError(t, err)
+	assert.Equal(t, u.SessionVersion+1, reset.SessionVersion)
+	_, err = a.Authenticate(ctx, u.Id, "password")
+	assert.ErrorIs(t, err, user.ErrWrongPassword)
+	_, err = a.Authenticate(ctx, u.Id, "new password")
+	assert.NoError(t, err)
+
+	// the reset voids the token it was made with and all the others
+	_, err = a.ResetPassword(ctx, second, "another password")
+	assert.ErrorIs(t, err, ErrInvalidResetToken)
+	_, err = a.ResetPassword(ctx, first, "another password")
+	assert.ErrorIs(t, err, ErrInvalidResetToken)
+}
+
+func TestPasswordResetExpiry(t *testing.T) {
+	ctx := context.Background()
+	mailer := mail.NewMemory()
+	tx := memuow.New(adrepo.New(idgen.NewSequence()), userrepo.New(idgen.NewSequence()))
+	a := NewApp(tx, passwords.NewForTest(), testSigner, mailer).(app)
+	u, err := a.CreateUser(ctx, "tester", "tester@mail.com", "password")
+	assert.NoError(t, err)
+	assert.NoError(t, a.ForgotPassword(ctx, "tester@mail.com"))
+	token := mailedToken(t, mailer, "tester@mail.com")
+
+	a.now = func() time.Time { return time.Now().Add(ResetTTL) }
+	_, err = a.ResetPassword(ctx, token, "new password")
+	assert.ErrorIs(t, err, ErrInvalidResetToken)
+
+	// changing the password voids the reset tokens mailed before
+	a.now = time.Now
+	_, err = a.UpdatePassword(ctx, u.Id, "password", "new password")
+	assert.NoError(t, err)
+	_, err = a.ResetPassword(ctx, token, "another password")
+	assert.ErrorIs(t, err, ErrInvalidResetToken)
+}
+
+func TestAPIKeys(t *testing.T) {
+	ctx := context.Background()
+	tx := memuow.New(adrepo.New(idgen.NewSequence()), userrepo.New(idgen.NewSequence()))
+	a := newTestApp(tx, passwords.NewForTest()).(app)
+	owner, err := a.CreateUser(ctx, "owner", "owner@mail.com", "password")
+	assert.NoError(t, err)
+	other, err := a.CreateUser(ctx, "other", "other@mail.com", "password")
+	assert.NoError(t, err)
+
+	read := []user.Scope{user.ScopeAdsRead}
+	_, _, err = a.CreateAPIKey(ctx, owner.Id, "", read, time.Time{})
+	assert.ErrorIs(t, err, user.ErrInvalidAPIKeyParams)
+	_, _, err = a.CreateAPIKey(ctx, owner.Id, "importer", nil, time.Time{})
+	assert.ErrorIs(t, err, user.ErrInvalidAPIKeyParams)
+	_, _, err = a.CreateAPIKey(ctx, owner.Id, "importer", []user.Scope{"ads:delete"}, time.Time{})
+	assert.ErrorIs(t, err, user.ErrInvalidScope)
+	_, _, err = a.CreateAPIKey(ctx, owner.Id, "importer", read, time.Now().Add(-time.Minute))
+	assert.ErrorIs(t, err, user.ErrInvalidAPIKeyParams)
+	_, _, err = a.CreateAPIKey(ctx, owner.Id+100, "importer", read, time.Time{})
+	assert.ErrorIs(t, err, userrepo.ErrInvalidUserId)
+
+	key, secret, err := a.CreateAPIKey(ctx, owner.Id, "importer", read, time.Time{})
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(secret, apiKeyPrefix+key.ID+"_"))
+	assert.NotContains(t, secret, key.Hash)
+
+	u, used, err := a.AuthenticateKey(ctx, secret)
+	assert.NoError(t, err)
+	assert.Equal(t, owner.Id, u.Id)
+	assert.Equal(t, read, used.Scopes)
+	assert.False(t, used.LastUsedAt.IsZero())
+	for _, bad := range []string{"", "garbage", secret + "0", strings.TrimPrefix(secret, apiKeyPrefix)} {
+		_, _, err = a.AuthenticateKey(ctx, bad)
+		assert.ErrorIs(t, err, ErrInvalidAPIKey, bad)
+	}
+
+	// the others can't see or revoke the key
+	keys, err := a.ListAPIKeys(ctx, other.Id)
+	assert.NoError(t, err)
+	assert.Empty(t, keys)
+	assert.ErrorIs(t, a.RevokeAPIKey(ctx, other.Id, key.ID), userrepo.ErrInvalidAPIKeyId)
+	keys, err = a.ListAPIKeys(ctx, owner.Id)
+	assert.NoError(t, err)
+	assert.Len(t, keys, 1)
+
+	assert.NoError(t, a.RevokeAPIKey(ctx, owner.Id, key.ID))
+	_, _, err = a.AuthenticateKey(ctx, secret)
+	assert.ErrorIs(t, err, ErrInvalidAPIKey)
+}
+
+func TestAPIKeyExpiry(t *testing.T) {
+	ctx := context.Background()
+	tx := memuow.New(adrepo.New(idgen.NewSequence()), userrepo.New(idgen.NewSequence()))
+	a := newTestApp(tx, passwords.NewForTest()).(app)
+	u, err := a.CreateUser(ctx, "tester", "tester@mail.com", "password")
+	assert.NoError(t, err)
+	start := time.Now()
+	_, secret, err := a.CreateAPIKey(ctx, u.Id, "importer", []user.Scope{user.ScopeAdsWrite}, start.Add(time.Hour))
+	assert.NoError(t, err)
+
+	_, key, err := a.AuthenticateKey(ctx, secret)
+	assert.NoError(t, err)
+	firstUse := key.LastUsedAt
+
+	// the last use is not stored on every request
+	a.now = func() time.Time { return start.Add(time.Second) }
+	_, key, err = a.AuthenticateKey(ctx, secret)
+	assert.NoError(t, err)
+	assert.Equal(t, firstUse, key.LastUsedAt)
+	a.now = func() time.Time { return start.Add(touchEvery + time.Second) }
+	_, key, err = a.AuthenticateKey(ctx, secret)
+	assert.NoError(t, err)
+	assert.True(t, key.LastUsedAt.After(firstUse))
+
+	a.now = func() time.Time { return start.Add(time.Hour) }
+	_, _, err = a.AuthenticateKey(ctx, secret)
+	assert.ErrorIs(t, err, ErrInvalidAPIKey)
+
+	// nor do the keys outlive their user
+	a.now = time.Now
+	assert.NoError(t, a.DeleteUser(ctx, u.Id, u.Id))
+	_, _, err = a.AuthenticateKey(ctx, secret)
+	assert.ErrorIs(t, err, ErrInvalidAPIKey)
+}
+
+func TestExportUser(t *testing.T) {
+	ctx := context.Background()
+	adRepo, userRepo := adrepo.New(idgen.NewSequence()), userrepo.New(idgen.NewSequence())
+	a := newTestApp(memuow.New(adRepo, userRepo), passwords.NewForTest()).(app)
+	owner, err := a.CreateUser(ctx, "owner", "owner@mail.com", "password")
+	assert.NoError(t, err)
+	other, err := a.CreateUser(ctx, "other", "other@mail.com", "password")
+	assert.NoError(t, err)
+	admin, err := a.CreateUser(ctx, "admin", "admin@mail.com", "password")
+	assert.NoError(t, err)
+	_, err = userRepo.UpdateRole(ctx, admin.Id, user.RoleAdmin)
+	assert.NoError(t, err)
+
+	draft, err := adRepo.AddAd(ctx, &ads.Ad{Title: "draft", AuthorID: owner.Id})
+	assert.NoError(t, err)
+	published, err := adRepo.AddAd(ctx, &ads.Ad{Title: "published", AuthorID: owner.Id, State: ads.StatePublished})
+	assert.NoError(t, err)
+	trashed, err := adRepo.AddAd(ctx, &ads.Ad{Title: "trashed", AuthorID: owner.Id})
+	assert.NoError(t, err)
+	_, err = adRepo.TrashAd(ctx, trashed, time.Now())
+	assert.NoError(t, err)
+	foreign, err := adRepo.AddAd(ctx, &ads.Ad{Title: "foreign", AuthorID: other.Id})
+	assert.NoError(t, err)
+	assert.NoError(t, adRepo.AddRevision(ctx, &ads.Revision{AdID: draft, ActorID: owner.Id, Title: "draft"}))
+	assert.NoError(t, adRepo.AddRevision(ctx, &ads.Revision{AdID: foreign, ActorID: other.Id, Title: "foreign"}))
+	_, _, err = a.CreateAPIKey(ctx, owner.Id, "importer", []user.Scope{user.ScopeAdsRead}, time.Time{})
+	assert.NoError(t, err)
+
+	export, err := a.ExportUser(ctx, owner.Id, owner.Id)
+	assert.NoError(t, err)
+	assert.Equal(t, owner.Id, export.User.Id)
+	if assert.Len(t, export.Ads, 3) {
+		assert.Equal(t, []int64{draft, published, trashed}, []int64{export.Ads[0].ID, export.Ads[1].ID, export.Ads[2].ID})
+	}
+	if assert.Len(t, export.Activity, 1) {
+		assert.Equal(t, draft, export.Activity[0].AdID)
+	}
+	assert.Len(t, export.APIKeys, 1)
+	assert.False(t, export.ExportedAt.IsZero())
+
+	// only admins may export the data of others
+	_, err = a.ExportUser(ctx, other.Id, owner.Id)
+	assert.ErrorIs(t, err, user.ErrForbidden)
+	export, err = a.ExportUser(ctx, admin.Id, owner.Id)
+	assert.NoError(t, err)
+	assert.Len(t, export.Ads, 3)
+	_, err = a.ExportUser(ctx, admin.Id, owner.Id+100)
+	assert.ErrorIs(t, err, userrepo.ErrInvalidUserId)
+}
+
+func TestEraseUser(t *testing.T) {
+	ctx := context.Background()
+	adRepo, userRepo := adrepo.New(idgen.NewSequence()), userrepo.New(idgen.NewSequence())
+	a := newTestApp(memuow.New(adRepo, userRepo), passwords.NewForTest()).(app)
+	owner, err := a.CreateUser(ctx, "owner", "owner@mail.com", "password")
+	assert.NoError(t, err)
+	other, err := a.CreateUser(ctx, "other", "other@mail.com", "password")
+	assert.NoError(t, err)
+
+	own, err := adRepo.AddAd(ctx, &ads.Ad{Title: "own", AuthorID: owner.Id})
+	assert.NoError(t, err)
+	foreign, err := adRepo.AddAd(ctx, &ads.Ad{Title: "foreign", AuthorID: other.Id})
+	assert.NoError(t, err)
+	assert.NoError(t, adRepo.AddRevision(ctx, &ads.Revision{AdID: foreign, ActorID: other.Id, Title: "foreign"}))
+	assert.NoError(t, adRepo.AddRevision(ctx, &ads.Revision{AdID: foreign, ActorID: owner.Id, Title: "edited"}))
+	_, secret, err := a.CreateAPIKey(ctx, owner.Id, "importer", []user.Scope{user.ScopeAdsRead}, time.Time{})
+	assert.NoError(t, err)
+
+	assert.ErrorIs(t, a.EraseUser(ctx, other.Id, owner.Id), user.ErrForbidden)
+	assert.NoError(t, a.EraseUser(ctx, owner.Id, owner.Id))
+
+	_, err = a.GetUser(ctx, owner.Id)
+	assert.ErrorIs(t, err, userrepo.ErrInvalidUserId)
+	_, err = adRepo.GetAdById(ctx, own)
+	assert.ErrorIs(t, err, adrepo.ErrInvalidAdId)
+	_, _, err = a.AuthenticateKey(ctx, secret)
+	assert.ErrorIs(t, err, ErrInvalidAPIKey)
+	// the history of the ads of others stays, without the erased user in it
+	revisions, err := adRepo.GetRevisions(ctx, foreign)
+	assert.NoError(t, err)
+	if assert.Len(t, revisions, 2) {
+		assert.Equal(t, other.Id, revisions[0].ActorID)
+		assert.Equal(t, ads.ErasedActor, revisions[1].ActorID)
+		assert.Equal(t, "edited", revisions[1].Title)
+	}
+	assert.ErrorIs(t, a.EraseUser(ctx, owner.Id, owner.Id), userrepo.ErrInvalidUserId)
+}
+
+func TestProfile(t *testing.T) {
+	ctx := context.Background()
+	adRepo, userRepo := adrepo.New(idgen.NewSequence()), userrepo.New(idgen.NewSequence())
+	a := newTestApp(memuow.New(adRepo, userRepo), passwords.NewForTest()).(app)
+	owner, err := a.CreateUser(ctx, "owner", "owner@mail.com", "password")
+	assert.NoError(t, err)
+	assert.False(t, owner.CreatedAt.IsZero())
+	other, err := a.CreateUser(ctx, "other", "other@mail.com", "password")
+	assert.NoError(t, err)
+	admin, err := a.CreateUser(ctx, "admin", "admin@mail.com", "password")
+	assert.NoError(t, err)
+	_, err = userRepo.UpdateRole(ctx, admin.Id, user.RoleAdmin)
+	assert.NoError(t, err)
+
+	profile := user.Profile{DisplayName: "Owner", Bio: "sells bikes", City: "Moscow", Phone: "+7 900 000-00-00",
+		CityVisibility: user.VisibilityPublic, PhoneVisibility: user.VisibilityUsers}
+	u, err := a.UpdateProfile(ctx, owner.Id, owner.Id, profile)
+	assert.NoError(t, err)
+	assert.Equal(t, profile, u.Profile)
+	_, err = a.UpdateProfile(ctx, other.Id, owner.Id, profile)
+	assert.ErrorIs(t, err, user.ErrForbidden)
+	_, err = a.UpdateProfile(ctx, owner.Id, owner.Id, user.Profile{Phone: "call me"})
+	assert.ErrorIs(t, err, user.ErrInvalidProfile)
+	_, err = a.UpdateProfile(ctx, owner.Id, owner.Id, user.Profile{CityVisibility: "friends"})
+	assert.ErrorIs(t, err, user.ErrInvalidVisibility)
+
+	published, err := adRepo.AddAd(ctx, &ads.Ad{Title: "published", AuthorID: owner.Id, State: ads.StatePublished})
+	assert.NoError(t, err)
+	_, err = adRepo.AddAd(ctx, &ads.Ad{Title: "draft", AuthorID: owner.Id})
+	assert.NoError(t, err)
+	_, err = adRepo.AddAd(ctx, &ads.Ad{Title: "foreign", AuthorID: other.Id, State: ads.StatePublished})
+	assert.NoError(t, err)
+
+	got, err := a.GetProfile(ctx, user.Anonymous, owner.Id)
+	assert.NoError(t, err)
+	assert.Equal(t, "Moscow", got.User.Profile.City)
+	assert.Empty(t, got.User.Profile.Phone)
+	if assert.Len(t, got.Ads, 1) {
+		assert.Equal(t, published, got.Ads[0].ID)
+	}
+	got, err = a.GetProfile(ctx, other.Id, owner.Id)
+	assert.NoError(t, err)
+	assert.Equal(t, profile.Phone, got.User.Profile.Phone)
+
+	// a private field is seen by the owner and the admins only
+	profile.CityVisibility = user.VisibilityPrivate
+	_, err = a.UpdateProfile(ctx, admin.Id, owner.Id, profile)
+	assert.NoError(t, err)
+	for viewer, city := range map[int64]string{owner.Id: "Moscow", admin.Id: "Moscow", other.Id: "",
+		user.Anonymous: ""} {
+		got, err = a.GetProfile(ctx, viewer, owner.Id)
+		assert.NoError(t, err)
+		assert.Equal(t, city, got.User.Profile.City, viewer)
+	}
+	_, err = a.GetProfile(ctx, user.Anonymous, owner.Id+100)
+	assert.ErrorIs(t, err, userrepo.ErrInvalidUserId)
+}
+
+func TestSearchUsers(t *testing.T) {
+	ctx := context.Background()
+	a := newTestApp(memuow.New(adrepo.New(idgen.NewSequence()), userrepo.New(idgen.NewSequence())),
+		passwords.NewForTest())
+	for _, nick := range []string{"anna", "Andrew", "boris", "ANTON"} {
+		_, err := a.CreateUser(ctx, nick, nick+"@mail.com", "password")
+		assert.NoError(t, err)
+	}
+
+	list, next, err := a.SearchUsers(ctx, user.Search{Prefix: "an", Limit: 2})
+	assert.NoError(t, err)
+	if assert.Len(t, list, 2) {
+		assert.Equal(t, "Andrew", list[0].Nickname)
+		assert.Equal(t, "anna", list[1].Nickname)
+	}
+	assert.NotEmpty(t, next)
+	list, next, err = a.SearchUsers(ctx, user.Search{Prefix: "an", Limit: 2, Cursor: next})
+	assert.NoError(t, err)
+	if assert.Len(t, list, 1) {
+		assert.Equal(t, "ANTON", list[0].Nickname)
+	}
+	assert.Empty(t, next)
+
+	list, _, err = a.SearchUsers(ctx, user.Search{})
+	assert.NoError(t, err)
+	assert.Len(t, list, 4)
+	_, _, err = a.SearchUsers(ctx, user.Search{Limit: user.MaxSearchLimit + 1})
+	assert.ErrorIs(t, err, user.ErrInvalidSearch)
+}
@@ -0,0 +1,81 @@
+package userapp
+
+import (
+	"context"
+	"homework10/internal/entities/ads"
+	"homework10/internal/entities/uow"
+	"homework10/internal/entities/user"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Export is everything stored about a user.
+type Export struct {
+	User *user.User
+	// Ads are all the ads of the user, the trashed ones too, sorted by id
+	Ads []*ads.Ad
+	// Activity are the revisions the user made to any ad
+	Activity   []*ads.Revision
+	APIKeys    []*user.APIKey
+	ExportedAt time.Time
+}
+
+func (a app) ExportUser(ctx context.Context, actorId, id int64) (*Export, error) {
+	export := &Export{ExportedAt: a.now().UTC()}
+	err := a.tx.Do(ctx, func(repos uow.Repositories) error {
+		if err := checkManage(ctx, repos, actorId, id); err != nil {
+			return err
+		}
+		u, err := repos.Users.GetUser(ctx, id)
+		if err != nil {
+			return err
+		}
+		export.User = u
+		author := strconv.FormatInt(id, 10)
+		for _, status := range []ads.Status{ads.Published, ads.Unpublished} {
+			list, err := repos.Ads.GetAll(ctx, ads.Filters{Status: status, AuthorId: author})
+			if err != nil {
+				return err
+			}
+			export.Ads = append(export.Ads, list...)
+		}
+		trash, err := repos.Ads.GetTrash(ctx, id)
+		if err != nil {
+			return err
+		}
+		export.Ads = append(export.Ads, trash...)
+		sort.Slice(export.Ads, func(i, j int) bool {
+			return export.Ads[i].ID < export.Ads[j].ID
+		})
+		if export.Activity, err = repos.Revisions.GetRevisionsByActor(ctx, id); err != nil {
+			return err
+		}
+		export.APIKeys, err = repos.Users.GetAPIKeys(ctx, id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return export, nil
+}
+
+// EraseUser deletes the user with their ads and api keys. The revisions
+// they made to the ads of others stay, with ads.ErasedActor as the actor.
+func (a app) EraseUser(ctx context.Context, actorId, id int64) error {
+	return a.tx.Do(ctx, func(repos uow.Repositories) error {
+		if err := checkManage(ctx, repos, actorId, id); err != nil {
+			return err
+		}
+		if _, err := repos.Users.GetUser(ctx, id); err != nil {
+			return err
+		}
+		if err := repos.Ads.DeleteAdsByAuthor(ctx, id); err != nil {
+			return err
+		}
+		if err := repos.Revisions.EraseActor(ctx, id); err != nil {
+			return err
+		}
+		return repos.Users.DeleteUser(ctx, id)
+	})
+}
@@ -5,6 +5,7 @@ package mocks
 import (
 	context "context"
 	user "homework10/internal/entities/user"
+	time "time"
 
 	mock "github.com/stretchr/testify/mock"
 )
@@ -14,6 +15,20 @@ type Repository struct {
 	mock.Mock
 }
 
+// AddAPIKey provides a mock function with given fields: ctx, key
+func (_m *Repository) AddAPIKey(ctx context.Context, key *user.APIKey) error {
+	ret := _m.Called(ctx, key)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *user.APIKey) error); ok {
+		r0 = rf(ctx, key)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // CreateUser provides a mock function with given fields: ctx, _a1
 func (_m *Repository) CreateUser(ctx context.Context, _a1 *user.User) (int64, error) {
 	ret := _m.Called(ctx, _a1)
@@ -38,6 +53,20 @@ func (_m *Repository) CreateUser(ctx context.Context, _a1 *user.User) (int64, er
 	return r0, r1
 }
 
+// DeleteAPIKey provides a mock function with given fields: ctx, id
+func (_m *Repository) DeleteAPIKey(ctx context.Context, id string) error {
+	ret := _m.Called(ctx, id)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, id)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // DeleteUser provides a mock function with given fields: ctx, id
 func (_m *Repository) DeleteUser(ctx context.Context, id int64) error {
 	ret := _m.Called(ctx, id)
@@ -52,6 +81,58 @@ func (_m *Repository) DeleteUser(ctx context.Context, id int64) error {
 	return r0
 }
 
+// GetAPIKey provides a mock function with given fields: ctx, id
+func (_m *Repository) GetAPIKey(ctx context.Context, id string) (*user.APIKey, error) {
+	ret := _m.Called(ctx, id)
+
+	var r0 *user.APIKey
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*user.APIKey, error)); ok {
+		return rf(ctx, id)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *user.APIKey); ok {
+		r0 = rf(ctx, id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*user.APIKey)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetAPIKeys provides a mock function with given fields: ctx, userId
+func (_m *Repository) GetAPIKeys(ctx context.Context, userId int64) ([]*user.APIKey, error) {
+	ret := _m.Called(ctx, userId)
+
+	var r0 []*user.APIKey
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) ([]*user.APIKey, error)); ok {
+		return rf(ctx, userId)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) []*user.APIKey); ok {
+		r0 = rf(ctx, userId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*user.APIKey)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, userId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetUser provides a mock function with given fields: ctx, id
 func (_m *Repository) GetUser(ctx context.Context, id int64) (*user.User, error) {
 	ret := _m.Called(ctx, id)
@@ -78,6 +159,98 @@ func (_m *Repository) GetUser(ctx context.Context, id int64) (*user.User, error)
 	return r0, r1
 }
 
+// GetUserByEmail provides a mock function with given fields: ctx, email
+func (_m *Repository) GetUserByEmail(ctx context.Context, email string) (*user.User, error) {
+	ret := _m.Called(ctx, email)
+
+	var r0 *user.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*user.User, error)); ok {
+		return rf(ctx, email)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *user.User); ok {
+		r0 = rf(ctx, email)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*user.User)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, email)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RevokeSessions provides a mock function with given fields: ctx, id
+func (_m *Repository) RevokeSessions(ctx context.Context, id int64) (*user.User, error) {
+	ret := _m.Called(ctx, id)
+
+	var r0 *user.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) (*user.User, error)); ok {
+		return rf(ctx, id)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) *user.User); ok {
+		r0 = rf(ctx, id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*user.User)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SearchUsers provides a mock function with given fields: ctx, search
+func (_m *Repository) SearchUsers(ctx context.Context, search user.Search) ([]*user.User, error) {
+	ret := _m.Called(ctx, search)
+
+	var r0 []*user.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, user.Search) ([]*user.User, error)); ok {
+		return rf(ctx, search)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, user.Search) []*user.User); ok {
+		r0 = rf(ctx, search)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*user.User)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, user.Search) error); ok {
+		r1 = rf(ctx, search)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// TouchAPIKey provides a mock function with given fields: ctx, id, at
+func (_m *Repository) TouchAPIKey(ctx context.Context, id string, at time.Time) error {
+	ret := _m.Called(ctx, id, at)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, time.Time) error); ok {
+		r0 = rf(ctx, id, at)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // UpdateNick provides a mock function with given fields: ctx, id, nick
 func (_m *Repository) UpdateNick(ctx context.Context, id int64, nick string) (*user.User, error) {
 	ret := _m.Called(ctx, id, nick)
@@ -130,6 +303,84 @@ func (_m *Repository) UpdatePassword(ctx context.Context, id int64, pass string)
 	return r0, r1
 }
 
+// UpdateProfile provides a mock function with given fields: ctx, id, profile
+func (_m *Repository) UpdateProfile(ctx context.Context, id int64, profile user.Profile) (*user.User, error) {
+	ret := _m.Called(ctx, id, profile)
+
+	var r0 *user.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, user.Profile) (*user.User, error)); ok {
+		return rf(ctx, id, profile)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, user.Profile) *user.User); ok {
+		r0 = rf(ctx, id, profile)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*user.User)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, user.Profile) error); ok {
+		r1 = rf(ctx, id, profile)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UpdateRole provides a mock function with given fields: ctx, id, role
+func (_m *Repository) UpdateRole(ctx context.Context, id int64, role user.Role) (*user.User, error) {
+	ret := _m.Called(ctx, id, role)
+
+	var r0 *user.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, user.Role) (*user.User, error)); ok {
+		return rf(ctx, id, role)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, user.Role) *user.User); ok {
+		r0 = rf(ctx, id, role)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*user.User)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, user.Role) error); ok {
+		r1 = rf(ctx, id, role)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UpdateVerified provides a mock function with given fields: ctx, id, verified
+func (_m *Repository) UpdateVerified(ctx context.Context, id int64, verified bool) (*user.User, error) {
+	ret := _m.Called(ctx, id, verified)
+
+	var r0 *user.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, bool) (*user.User, error)); ok {
+		return rf(ctx, id, verified)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, bool) *user.User); ok {
+		r0 = rf(ctx, id, verified)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*user.User)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, bool) error); ok {
+		r1 = rf(ctx, id, verified)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 type mockConstructorTestingTNewRepository interface {
 	mock.TestingT
 	Cleanup(func())
@@ -0,0 +1,99 @@
+package userapp
+
+import (
+	"context"
+	"homework10/internal/entities/ads"
+	"homework10/internal/entities/uow"
+	"homework10/internal/entities/user"
+	"strconv"
+)
+
+// PublicProfile is the profile page of a seller.
+type PublicProfile struct {
+	// User has the fields of the profile the viewer may not see blanked out
+	User *user.User
+	// Ads are the newest published ads of the user, at most ads.MaxLimit of
+	// them, the rest is listed with the author filter of the ads
+	Ads []*ads.Ad
+}
+
+func (a app) UpdateProfile(ctx context.Context, actorId, id int64, profile user.Profile) (*user.User, error) {
+	if err := user.ValidateProfile(&profile); err != nil {
+		return nil, err
+	}
+	var u *user.User
+	err := a.tx.Do(ctx, func(repos uow.Repositories) error {
+		if err := checkManage(ctx, repos, actorId, id); err != nil {
+			return err
+		}
+		var err error
+		u, err = repos.Users.UpdateProfile(ctx, id, profile)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+func (a app) GetProfile(ctx context.Context, viewerId, id int64) (*PublicProfile, error) {
+	profile := &PublicProfile{}
+	err := a.tx.Do(ctx, func(repos uow.Repositories) error {
+		u, err := repos.Users.GetUser(ctx, id)
+		if err != nil {
+			return err
+		}
+		viewer, err := viewerOf(ctx, repos, viewerId, id)
+		if err != nil {
+			return err
+		}
+		u.Profile = u.Profile.VisibleTo(viewer)
+		profile.User = u
+		profile.Ads, err = repos.Ads.GetAll(ctx, ads.Filters{Status: ads.Published,
+			AuthorId: strconv.FormatInt(id, 10), Desc: true, Limit: ads.MaxLimit})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return profile, nil
+}
+
+// viewerOf tells who the viewer is to the user, the ones with
+// user.ManageUsers see everything the user does.
+func viewerOf(ctx context.Context, repos uow.Repositories, viewerId, id int64) (user.Viewer, error) {
+	switch viewerId {
+	case user.Anonymous:
+		return user.ViewerAnonymous, nil
+	case id:
+		return user.ViewerOwner, nil
+	}
+	v, err := repos.Users.GetUser(ctx, viewerId)
+	if err != nil {
+		return user.ViewerAnonymous, err
+	}
+	if v.Role.Can(user.ManageUsers) {
+		return user.ViewerOwner, nil
+	}
+	return user.ViewerUser, nil
+}
+
+// SearchUsers returns the page of users and the cursor of the next one,
+// empty on the last page.
+func (a app) SearchUsers(ctx context.Context, search user.Search) ([]*user.User, string, error) {
+	if err := search.Normalize(); err != nil {
+		return nil, "", err
+	}
+	limit := search.Limit
+	// one more user tells whether there is a next page
+	search.Limit++
+	list, err := a.tx.Repositories().Users.SearchUsers(ctx, search)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(list) <= limit {
+		return list, "", nil
+	}
+	list = list[:limit]
+	return list, search.NextCursor(list[len(list)-1]), nil
+}
@@ -0,0 +1,89 @@
+package userapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"homework10/internal/adapters/userrepo"
+	"homework10/internal/entities/uow"
+	"homework10/internal/entities/user"
+	"homework10/pkg/mail"
+	"time"
+)
+
+var (
+	ErrInvalidResetToken = errors.New("invalid or expired password reset token")
+)
+
+const (
+	resetUse = "reset_password"
+
+	ResetTTL = time.Hour
+)
+
+// ForgotPassword mails a password reset token to the user with the email.
+// It tells nobody whether there is such a user.
+func (a app) ForgotPassword(ctx context.Context, email string) error {
+	u, err := a.tx.Repositories().Users.GetUserByEmail(ctx, email)
+	if errors.Is(err, userrepo.ErrInvalidUserId) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	token, err := a.signToken(u, resetUse, ResetTTL)
+	if err != nil {
+		return err
+	}
+	return a.mailer.Send(ctx, mail.Message{
+		To:      u.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hello, %s!\n\nSend this token with a new password to POST /user/password/reset within %s. "+
+			"Ignore this mail if you didn't ask for it:\n\n%s", u.Nickname, ResetTTL, token),
+	})
+}
+
+// ResetPassword sets the password of the user the token was mailed to. The
+// reset revokes the sessions of the user, the token itself included.
+func (a app) ResetPassword(ctx context.Context, token, password string) (*user.User, error) {
+	err := user.ValidateUser(&user.User{Nickname: "mocknick", Password: password})
+	if err != nil {
+		return nil, user.ErrInvalidUserParams
+	}
+	claims, id, ok := a.parseToken(token, resetUse)
+	if !ok {
+		return nil, ErrInvalidResetToken
+	}
+	hash, err := a.passwords.Hash(password)
+	if err != nil {
+		return nil, err
+	}
+	var u *user.User
+	err = a.tx.Do(ctx, func(repos uow.Repositories) error {
+		u, err = repos.Users.GetUser(ctx, id)
+		if errors.Is(err, userrepo.ErrInvalidUserId) {
+			return ErrInvalidResetToken
+		}
+		if err != nil {
+			return err
+		}
+		if claims.Version != u.SessionVersion {
+			return ErrInvalidResetToken
+		}
+		u, err = a.setPassword(ctx, repos, id, hash)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// setPassword stores the hash made before the transaction and revokes the
+// sessions started with the old password.
+func (a app) setPassword(ctx context.Context, repos uow.Repositories, id int64, hash string) (*user.User, error) {
+	if _, err := repos.Users.UpdatePassword(ctx, id, hash); err != nil {
+		return nil, err
+	}
+	return repos.Users.RevokeSessions(ctx, id)
+}
@@ -0,0 +1,42 @@
+package userapp
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"homework10/internal/entities/user"
+	"homework10/pkg/jwt"
+	"strconv"
+	"time"
+)
+
+// signToken issues a token for one use to the user. It carries the session
+// version of the user, so revoking their sessions voids it too.
+func (a app) signToken(u *user.User, use string, ttl time.Duration) (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	now := a.now()
+	return a.signer.Sign(jwt.Claims{
+		Subject:   strconv.FormatInt(u.Id, 10),
+		ID:        hex.EncodeToString(id),
+		Use:       use,
+		Version:   u.SessionVersion,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(ttl).Unix(),
+	})
+}
+
+// parseToken returns the claims of a valid token meant for the use and the
+// id of the user it was issued to.
+func (a app) parseToken(token, use string) (*jwt.Claims, int64, bool) {
+	claims, err := a.signer.Parse(token, a.now())
+	if err != nil || claims.Use != use {
+		return nil, 0, false
+	}
+	id, err := strconv.ParseInt(claims.Subject, 10, 64)
+	if err != nil {
+		return nil, 0, false
+	}
+	return claims, id, true
+}
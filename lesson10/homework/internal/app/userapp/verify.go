@@ -0,0 +1,80 @@
+package userapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"homework10/internal/adapters/userrepo"
+	"homework10/internal/entities/uow"
+	"homework10/internal/entities/user"
+	"homework10/pkg/mail"
+	"time"
+)
+
+var (
+	ErrInvalidVerifyToken = errors.New("invalid or expired verification token")
+	ErrAlreadyVerified    = errors.New("the email is already verified")
+)
+
+const (
+	verifyUse = "verify_email"
+
+	VerifyTTL = 24 * time.Hour
+)
+
+func (a app) SendVerification(ctx context.Context, id int64) error {
+	u, err := a.tx.Repositories().Users.GetUser(ctx, id)
+	if err != nil {
+		return err
+	}
+	if u.Verified {
+		return ErrAlreadyVerified
+	}
+	// users created before the emails were checked may have a broken one
+	if err = user.ValidateEmail(u.Email); err != nil {
+		return err
+	}
+	return a.sendVerification(ctx, u)
+}
+
+func (a app) sendVerification(ctx context.Context, u *user.User) error {
+	token, err := a.signToken(u, verifyUse, VerifyTTL)
+	if err != nil {
+		return err
+	}
+	return a.mailer.Send(ctx, mail.Message{
+		To:      u.Email,
+		Subject: "Verify your email",
+		Body: fmt.Sprintf("Hello, %s!\n\nSend this token to POST /user/verify within %s to verify your email:\n\n%s",
+			u.Nickname, VerifyTTL, token),
+	})
+}
+
+// VerifyEmail needs no record of the used tokens, every token of the user
+// is worthless once they are verified.
+func (a app) VerifyEmail(ctx context.Context, token string) (*user.User, error) {
+	claims, id, ok := a.parseToken(token, verifyUse)
+	if !ok {
+		return nil, ErrInvalidVerifyToken
+	}
+	var u *user.User
+	err := a.tx.Do(ctx, func(repos uow.Repositories) error {
+		var err error
+		u, err = repos.Users.GetUser(ctx, id)
+		if errors.Is(err, userrepo.ErrInvalidUserId) {
+			return ErrInvalidVerifyToken
+		}
+		if err != nil {
+			return err
+		}
+		if u.Verified || claims.Version != u.SessionVersion {
+			return ErrInvalidVerifyToken
+		}
+		u, err = repos.Users.UpdateVerified(ctx, id, true)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return u, nil
+}
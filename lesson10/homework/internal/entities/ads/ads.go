@@ -3,21 +3,51 @@ package ads
 import (
 	"errors"
 	"github.com/OkDenAl/validator"
+	"sort"
+	"time"
 )
 
 var (
 	ErrUserCantChangeThisAd = errors.New("the user is trying to change an ad created by another user")
 	ErrInvalidAdParams      = errors.New("invalid ad params")
+	ErrVersionMismatch      = errors.New("the ad has been changed since the given version")
+	ErrReasonRequired       = errors.New("a reason is required to moderate an ad of another user")
 )
 
 type Ad struct {
-	ID           int64
-	Title        string
-	Text         string
-	AuthorID     int64
+	ID       int64
+	Title    string
+	Text     string
+	AuthorID int64
+	// CategoryID is zero for the ads created before there were categories
+	CategoryID int64
+	// Price is the zero Money for the ads created before there were prices
+	Price        Money
 	CreationDate string
 	UpdateDate   string
-	Published    bool
+	State        State
+	// RejectionReason is why a moderator rejected the ad, it is only set in
+	// StateRejected
+	RejectionReason string
+	// Version grows by one with every change of the ad
+	Version int64
+	// DeletedAt is set while the ad is in the trash
+	DeletedAt time.Time
+	// PublishAt is when the scheduler publishes the ad, zero when it is not
+	// scheduled
+	PublishAt time.Time
+	// ExpiresAt is when the scheduler takes the published ad down, zero for
+	// the ads published before ads expired
+	ExpiresAt time.Time
+}
+
+func (ad *Ad) Trashed() bool {
+	return !ad.DeletedAt.IsZero()
+}
+
+// Published tells whether the ad is listed to everyone.
+func (ad *Ad) Published() bool {
+	return ad.State == StatePublished
 }
 
 type ValidatorAd struct {
@@ -27,6 +57,8 @@ type ValidatorAd struct {
 	TextMax  string `validate:"max:500"`
 }
 
+// ValidateAd checks the title and the text, and the price as
+// ValidatePrice does.
 func ValidateAd(ad *Ad) error {
 	vAd := ValidatorAd{
 		TitleMin: ad.Title,
@@ -34,5 +66,18 @@ func ValidateAd(ad *Ad) error {
 		TextMin:  ad.Text,
 		TextMax:  ad.Text,
 	}
-	return validator.Validate(vAd)
+	if err := validator.Validate(vAd); err != nil {
+		return err
+	}
+	return ValidatePrice(&ad.Price)
+}
+
+// SortTrash puts the most recently deleted ads first.
+func SortTrash(list []*Ad) {
+	sort.Slice(list, func(i, j int) bool {
+		if !list[i].DeletedAt.Equal(list[j].DeletedAt) {
+			return list[i].DeletedAt.After(list[j].DeletedAt)
+		}
+		return list[i].ID < list[j].ID
+	})
 }
@@ -0,0 +1,719 @@
+// Package adstest holds the tests every ads.Store has to pass, storage
+// backends run them from their own tests with RunRepositoryTests.
+package adstest
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"homework10/internal/adapters/adrepo"
+	"homework10/internal/entities/ads"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Factory returns an empty repository, it is called once for every test.
+type Factory func(t *testing.T) ads.Store
+
+// RunRepositoryTests checks the contract of ads.Store against the
+// repositories made by newRepo, run it with -race.
+func RunRepositoryTests(t *testing.T, newRepo Factory) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, repo ads.Store)
+	}{
+		{"AddAndGet", testAddAndGet},
+		{"NotFound", testNotFound},
+		{"Update", testUpdate},
+		{"GetByTitle", testGetByTitle},
+		{"Delete", testDelete},
+		{"Filters", testFilters},
+		{"Categories", testCategories},
+		{"CountAds", testCountAds},
+		{"Prices", testPrices},
+		{"Pages", testPages},
+		{"Trash", testTrash},
+		{"Revisions", testRevisions},
+		{"EraseActor", testEraseActor},
+		{"Images", testImages},
+		{"Schedule", testSchedule},
+		{"ConcurrentWriters", testConcurrentWriters},
+		{"CanceledContext", testCanceledContext},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			tc.run(t, newRepo(t))
+		})
+	}
+}
+
+func addAd(t *testing.T, repo ads.Store, ad *ads.Ad) *ads.Ad {
+	t.Helper()
+	if ad.Version == 0 {
+		ad.Version = 1
+	}
+	_, err := repo.AddAd(context.Background(), ad)
+	assert.NoError(t, err)
+	return ad
+}
+
+func ids(list []*ads.Ad) []int64 {
+	resp := make([]int64, len(list))
+	for i, ad := range list {
+		resp[i] = ad.ID
+	}
+	return resp
+}
+
+func testAddAndGet(t *testing.T, repo ads.Store) {
+	ctx := context.Background()
+	ad := &ads.Ad{Title: "title", Text: "text", AuthorID: 3, CategoryID: 2, Price: ads.Money{Amount: 1250, Currency: "RUB"},
+		CreationDate: "2023-04-28", Version: 1}
+	id, err := repo.AddAd(ctx, ad)
+	assert.NoError(t, err)
+	assert.Equal(t, id, ad.ID)
+	other := addAd(t, repo, &ads.Ad{Title: "other"})
+	assert.NotEqual(t, id, other.ID)
+
+	got, err := repo.GetAdById(ctx, id)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, ad, got)
+
+	// the stored ad is not shared with the callers
+	ad.Title = "changed by the caller"
+	got.Text = "changed by the caller"
+	got, err = repo.GetAdById(ctx, id)
+	assert.NoError(t, err)
+	assert.Equal(t, "title", got.Title)
+	assert.Equal(t, "text", got.Text)
+}
+
+func testNotFound(t *testing.T, repo ads.Store) {
+	ctx := context.Background()
+	ad := addAd(t, repo, &ads.Ad{Title: "title"})
+	missing := ad.ID + 1000
+
+	_, err := repo.GetAdById(ctx, missing)
+	assert.ErrorIs(t, err, adrepo.ErrInvalidAdId)
+	_, err = repo.UpdateAdState(ctx, missing, ads.StatePublished, "")
+	assert.ErrorIs(t, err, adrepo.ErrInvalidAdId)
+	_, err = repo.UpdateAdTitleAndText(ctx, missing, "title", "text")
+	assert.ErrorIs(t, err, adrepo.ErrInvalidAdId)
+	_, err = repo.UpdateAdPublication(ctx, missing, ads.StatePublished, time.Time{}, time.Time{})
+	assert.ErrorIs(t, err, adrepo.ErrInvalidAdId)
+	_, err = repo.TrashAd(ctx, missing, time.Now())
+	assert.ErrorIs(t, err, adrepo.ErrInvalidAdId)
+	_, err = repo.RestoreAd(ctx, missing)
+	assert.ErrorIs(t, err, adrepo.ErrInvalidAdId)
+	_, err = repo.GetAdsByTitle(ctx, "missing")
+	assert.ErrorIs(t, err, adrepo.ErrInvalidAdTitle)
+	assert.NoError(t, repo.DeleteAd(ctx, missing))
+	revisions, err := repo.GetRevisions(ctx, missing)
+	assert.NoError(t, err)
+	assert.Empty(t, revisions)
+}
+
+func testUpdate(t *testing.T, repo ads.Store) {
+	ctx := context.Background()
+	ad := addAd(t, repo, &ads.Ad{Title: "title", Text: "text", CreationDate: "2023-04-28"})
+
+	updated, err := repo.UpdateAdState(ctx, ad.ID, ads.StatePublished, "")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, ads.StatePublished, updated.State)
+	assert.Equal(t, int64(2), updated.Version)
+	assert.NotEmpty(t, updated.UpdateDate)
+
+	updated, err = repo.UpdateAdTitleAndText(ctx, ad.ID, "new title", "new text")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "new title", updated.Title)
+	assert.Equal(t, "new text", updated.Text)
+	assert.Equal(t, ads.StatePublished, updated.State)
+	assert.Equal(t, int64(3), updated.Version)
+
+	got, err := repo.GetAdById(ctx, ad.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, updated, got)
+	assert.Equal(t, "2023-04-28", got.CreationDate)
+
+	rejected, err := repo.UpdateAdState(ctx, ad.ID, ads.StateRejected, "spam")
+	assert.NoError(t, err)
+	assert.Equal(t, "spam", rejected.RejectionReason)
+	got, err = repo.GetAdById(ctx, ad.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, rejected, got)
+	// the reason only goes with the rejection
+	updated, err = repo.UpdateAdState(ctx, ad.ID, ads.StatePendingReview, "spam")
+	assert.NoError(t, err)
+	assert.Equal(t, ads.StatePendingReview, updated.State)
+	assert.Empty(t, updated.RejectionReason)
+	_, err = repo.UpdateAdState(ctx, ad.ID, ads.StateRejected, "spam")
+	assert.NoError(t, err)
+	updated, err = repo.UpdateAdPublication(ctx, ad.ID, ads.StatePublished, time.Time{}, time.Time{})
+	assert.NoError(t, err)
+	assert.Empty(t, updated.RejectionReason)
+}
+
+func testGetByTitle(t *testing.T, repo ads.Store) {
+	ctx := context.Background()
+	first := addAd(t, repo, &ads.Ad{Title: "bike for sale"})
+	second := addAd(t, repo, &ads.Ad{Title: "bike"})
+	addAd(t, repo, &ads.Ad{Title: "a bike"})
+
+	found, err := repo.GetAdsByTitle(ctx, "bike")
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []int64{first.ID, second.ID}, ids(found))
+}
+
+func testDelete(t *testing.T, repo ads.Store) {
+	ctx := context.Background()
+	first := addAd(t, repo, &ads.Ad{Title: "first", AuthorID: 1})
+	second := addAd(t, repo, &ads.Ad{Title: "second", AuthorID: 1})
+	third := addAd(t, repo, &ads.Ad{Title: "third", AuthorID: 2})
+
+	assert.NoError(t, repo.DeleteAd(ctx, first.ID))
+	_, err := repo.GetAdById(ctx, first.ID)
+	assert.ErrorIs(t, err, adrepo.ErrInvalidAdId)
+
+	assert.NoError(t, repo.DeleteAdsByAuthor(ctx, 1))
+	_, err = repo.GetAdById(ctx, second.ID)
+	assert.ErrorIs(t, err, adrepo.ErrInvalidAdId)
+	_, err = repo.GetAdById(ctx, third.ID)
+	assert.NoError(t, err)
+
+	// ids are not handed out again
+	fourth := addAd(t, repo, &ads.Ad{Title: "fourth"})
+	assert.NotContains(t, []int64{first.ID, second.ID, third.ID}, fourth.ID)
+}
+
+func testFilters(t *testing.T, repo ads.Store) {
+	ctx := context.Background()
+	bike := addAd(t, repo, &ads.Ad{Title: "bike", AuthorID: 1, CategoryID: 1, State: ads.StatePublished,
+		CreationDate: "2023-04-01"})
+	car := addAd(t, repo, &ads.Ad{Title: "car", AuthorID: 1, CategoryID: 1, State: ads.StateDraft,
+		CreationDate: "2023-04-02"})
+	sold := addAd(t, repo, &ads.Ad{Title: "sold", AuthorID: 1, CategoryID: 1, State: ads.StateSold,
+		CreationDate: "2023-04-03"})
+	apple := addAd(t, repo, &ads.Ad{Title: "apple", AuthorID: 2, CategoryID: 2, State: ads.StatePublished,
+		CreationDate: "2023-04-02"})
+	desk := addAd(t, repo, &ads.Ad{Title: "desk", AuthorID: 2, CategoryID: 3, State: ads.StatePublished,
+		CreationDate: "2023-04-01"})
+	trashed := addAd(t, repo, &ads.Ad{Title: "trashed", AuthorID: 2, State: ads.StatePublished,
+		CreationDate: "2023-04-01"})
+	_, err := repo.TrashAd(ctx, trashed.ID, time.Now())
+	assert.NoError(t, err)
+
+	tests := []struct {
+		filters ads.Filters
+		expect  []int64
+	}{
+		{ads.Filters{Status: ads.Published}, []int64{bike.ID, apple.ID, desk.ID}},
+		{ads.Filters{Status: ads.Unpublished}, []int64{car.ID, sold.ID}},
+		{ads.Filters{Status: ads.Status(ads.StateSold)}, []int64{sold.ID}},
+		{ads.Filters{Status: ads.Status(ads.StateDraft), Categories: []int64{1}}, []int64{car.ID}},
+		{ads.Filters{Status: ads.Status(ads.StateRejected)}, []int64{}},
+		{ads.Filters{Status: ads.Published, AuthorId: "2"}, []int64{apple.ID, desk.ID}},
+		{ads.Filters{Status: ads.Published, Date: "2023-04-01"}, []int64{bike.ID, desk.ID}},
+		{ads.Filters{Status: ads.Unpublished, AuthorId: "2"}, []int64{}},
+		{ads.Filters{Status: "everything"}, []int64{}},
+		{ads.Filters{Status: ads.Published, Sort: ads.SortByTitle}, []int64{apple.ID, bike.ID, desk.ID}},
+		{ads.Filters{Status: ads.Published, Sort: ads.SortByTitle, Desc: true}, []int64{desk.ID, bike.ID, apple.ID}},
+		{ads.Filters{Status: ads.Published, Categories: []int64{1, 3}}, []int64{bike.ID, desk.ID}},
+		{ads.Filters{Status: ads.Unpublished, Categories: []int64{2}}, []int64{}},
+	}
+	for _, tc := range tests {
+		list, err := repo.GetAll(ctx, tc.filters)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.expect, ids(list), fmt.Sprintf("%+v", tc.filters))
+	}
+}
+
+func testCategories(t *testing.T, repo ads.Store) {
+	ctx := context.Background()
+	vehicles := &ads.Category{Name: "vehicles"}
+	id, err := repo.AddCategory(ctx, vehicles)
+	assert.NoError(t, err)
+	assert.Equal(t, id, vehicles.ID)
+	assert.Positive(t, id)
+	bikes := &ads.Category{Name: "bikes", ParentID: vehicles.ID}
+	_, err = repo.AddCategory(ctx, bikes)
+	assert.NoError(t, err)
+
+	got, err := repo.GetCategory(ctx, bikes.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, bikes, got)
+
+	updated, err := repo.UpdateCategory(ctx, &ads.Category{ID: bikes.ID, Name: "bicycles", ParentID: vehicles.ID})
+	assert.NoError(t, err)
+	assert.Equal(t, "bicycles", updated.Name)
+	list, err := repo.GetCategories(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []*ads.Category{vehicles, updated}, list)
+
+	// a category with subcategories or ads, trashed ones too, can't go
+	assert.ErrorIs(t, repo.DeleteCategory(ctx, vehicles.ID), ads.ErrCategoryInUse)
+	ad := addAd(t, repo, &ads.Ad{Title: "bike", CategoryID: bikes.ID})
+	_, err = repo.TrashAd(ctx, ad.ID, time.Now())
+	assert.NoError(t, err)
+	assert.ErrorIs(t, repo.DeleteCategory(ctx, bikes.ID), ads.ErrCategoryInUse)
+	assert.NoError(t, repo.DeleteAd(ctx, ad.ID))
+	assert.NoError(t, repo.DeleteCategory(ctx, bikes.ID))
+	assert.NoError(t, repo.DeleteCategory(ctx, vehicles.ID))
+
+	missing := bikes.ID
+	_, err = repo.GetCategory(ctx, missing)
+	assert.ErrorIs(t, err, adrepo.ErrInvalidCategoryId)
+	_, err = repo.UpdateCategory(ctx, &ads.Category{ID: missing, Name: "missing"})
+	assert.ErrorIs(t, err, adrepo.ErrInvalidCategoryId)
+	assert.ErrorIs(t, repo.DeleteCategory(ctx, missing), adrepo.ErrInvalidCategoryId)
+
+	// ids are not handed out again
+	other := &ads.Category{Name: "other"}
+	_, err = repo.AddCategory(ctx, other)
+	assert.NoError(t, err)
+	assert.Greater(t, other.ID, bikes.ID)
+}
+
+func testCountAds(t *testing.T, repo ads.Store) {
+	ctx := context.Background()
+	addAd(t, repo, &ads.Ad{Title: "a", CategoryID: 1, State: ads.StatePublished})
+	addAd(t, repo, &ads.Ad{Title: "b", CategoryID: 1, State: ads.StatePublished})
+	addAd(t, repo, &ads.Ad{Title: "c", CategoryID: 2, State: ads.StatePublished})
+	addAd(t, repo, &ads.Ad{Title: "d", CategoryID: 2})
+	trashed := addAd(t, repo, &ads.Ad{Title: "e", CategoryID: 2, State: ads.StatePublished})
+	_, err := repo.TrashAd(ctx, trashed.ID, time.Now())
+	assert.NoError(t, err)
+
+	counts, err := repo.CountAds(ctx, ads.Published)
+	assert.NoError(t, err)
+	assert.Equal(t, map[int64]int{1: 2, 2: 1}, counts)
+	counts, err = repo.CountAds(ctx, ads.Unpublished)
+	assert.NoError(t, err)
+	assert.Equal(t, map[int64]int{2: 1}, counts)
+}
+
+func testPrices(t *testing.T, repo ads.Store) {
+	ctx := context.Background()
+	rub := func(amount int64) ads.Money {
+		return ads.Money{Amount: amount, Currency: "RUB"}
+	}
+	cheap := addAd(t, repo, &ads.Ad{Title: "cheap", Price: rub(500), State: ads.StatePublished})
+	pricey := addAd(t, repo, &ads.Ad{Title: "pricey", Price: rub(100000), State: ads.StatePublished})
+	middle := addAd(t, repo, &ads.Ad{Title: "middle", Price: rub(2500), State: ads.StatePublished})
+	same := addAd(t, repo, &ads.Ad{Title: "same", Price: rub(2500), State: ads.StatePublished})
+	dollars := addAd(t, repo, &ads.Ad{Title: "dollars", Price: ads.Money{Amount: 2500, Currency: "USD"},
+		State: ads.StatePublished})
+	addAd(t, repo, &ads.Ad{Title: "no price", State: ads.StatePublished})
+
+	tests := []struct {
+		filters ads.Filters
+		expect  []int64
+	}{
+		{ads.Filters{Status: ads.Published, Currency: "USD"}, []int64{dollars.ID}},
+		{ads.Filters{Status: ads.Published, Currency: "RUB", PriceMin: 1000}, []int64{pricey.ID, middle.ID, same.ID}},
+		{ads.Filters{Status: ads.Published, Currency: "RUB", PriceMax: 2500}, []int64{cheap.ID, middle.ID, same.ID}},
+		{ads.Filters{Status: ads.Published, Currency: "RUB", PriceMin: 2500, PriceMax: 2500}, []int64{middle.ID, same.ID}},
+		{ads.Filters{Status: ads.Published, Currency: "RUB", Sort: ads.SortByPrice},
+			[]int64{cheap.ID, middle.ID, same.ID, pricey.ID}},
+		{ads.Filters{Status: ads.Published, Currency: "RUB", Sort: ads.SortByPrice, Desc: true},
+			[]int64{pricey.ID, same.ID, middle.ID, cheap.ID}},
+	}
+	for _, tc := range tests {
+		list, err := repo.GetAll(ctx, tc.filters)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.expect, ids(list), fmt.Sprintf("%+v", tc.filters))
+	}
+
+	// the pages sorted by price go on from the price of the last ad
+	filters := ads.Filters{Status: ads.Published, Currency: "RUB", Sort: ads.SortByPrice, Limit: 2}
+	var got []int64
+	for i := 0; i < 4; i++ {
+		page, err := repo.GetAll(ctx, filters)
+		if !assert.NoError(t, err) || len(page) == 0 {
+			break
+		}
+		got = append(got, ids(page)...)
+		filters.Cursor = filters.NextCursor(page[len(page)-1])
+	}
+	assert.Equal(t, []int64{cheap.ID, middle.ID, same.ID, pricey.ID}, got)
+}
+
+func testPages(t *testing.T, repo ads.Store) {
+	ctx := context.Background()
+	var all []int64
+	for _, title := range []string{"c", "a", "e", "b", "d"} {
+		all = append(all, addAd(t, repo, &ads.Ad{Title: title, State: ads.StatePublished}).ID)
+	}
+
+	filters := ads.Filters{Status: ads.Published, Sort: ads.SortByTitle, Desc: true, Limit: 2}
+	var got []int64
+	for i := 0; i < len(all); i++ {
+		page, err := repo.GetAll(ctx, filters)
+		if !assert.NoError(t, err) || len(page) == 0 {
+			break
+		}
+		assert.LessOrEqual(t, len(page), 2)
+		got = append(got, ids(page)...)
+		filters.Cursor = filters.NextCursor(page[len(page)-1])
+	}
+	// e, d, c, b, a
+	assert.Equal(t, []int64{all[2], all[4], all[0], all[3], all[1]}, got)
+}
+
+func testTrash(t *testing.T, repo ads.Store) {
+	ctx := context.Background()
+	older := addAd(t, repo, &ads.Ad{Title: "older", AuthorID: 1})
+	newer := addAd(t, repo, &ads.Ad{Title: "newer", AuthorID: 1})
+	addAd(t, repo, &ads.Ad{Title: "kept", AuthorID: 1})
+	at := time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	trashed, err := repo.TrashAd(ctx, older.ID, at)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.True(t, trashed.DeletedAt.Equal(at))
+	assert.Equal(t, int64(2), trashed.Version)
+	_, err = repo.TrashAd(ctx, newer.ID, at.Add(time.Hour))
+	assert.NoError(t, err)
+	_, err = repo.TrashAd(ctx, older.ID, at)
+	assert.ErrorIs(t, err, adrepo.ErrInvalidAdId)
+
+	_, err = repo.GetAdById(ctx, older.ID)
+	assert.ErrorIs(t, err, adrepo.ErrInvalidAdId)
+	_, err = repo.GetAdsByTitle(ctx, "older")
+	assert.ErrorIs(t, err, adrepo.ErrInvalidAdTitle)
+	_, err = repo.UpdateAdState(ctx, older.ID, ads.StatePublished, "")
+	assert.ErrorIs(t, err, adrepo.ErrInvalidAdId)
+
+	trash, err := repo.GetTrash(ctx, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{newer.ID, older.ID}, ids(trash))
+	trash, err = repo.GetTrash(ctx, 2)
+	assert.NoError(t, err)
+	assert.Empty(t, trash)
+
+	restored, err := repo.RestoreAd(ctx, newer.ID)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.False(t, restored.Trashed())
+	_, err = repo.RestoreAd(ctx, newer.ID)
+	assert.ErrorIs(t, err, adrepo.ErrInvalidAdId)
+	_, err = repo.GetAdById(ctx, newer.ID)
+	assert.NoError(t, err)
+
+	// only ads trashed before the given time are purged
+	assert.NoError(t, repo.PurgeTrash(ctx, at))
+	trash, err = repo.GetTrash(ctx, 1)
+	assert.NoError(t, err)
+	assert.Len(t, trash, 1)
+	assert.NoError(t, repo.PurgeTrash(ctx, at.Add(time.Second)))
+	trash, err = repo.GetTrash(ctx, 1)
+	assert.NoError(t, err)
+	assert.Empty(t, trash)
+	_, err = repo.RestoreAd(ctx, older.ID)
+	assert.ErrorIs(t, err, adrepo.ErrInvalidAdId)
+}
+
+func testRevisions(t *testing.T, repo ads.Store) {
+	ctx := context.Background()
+	ad := addAd(t, repo, &ads.Ad{Title: "title"})
+	other := addAd(t, repo, &ads.Ad{Title: "other"})
+	at := time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	first := &ads.Revision{AdID: ad.ID, ActorID: 1, CreatedAt: at, Title: "title",
+		Changes: []ads.Change{{Field: ads.FieldTitle, New: "title"}}}
+	assert.NoError(t, repo.AddRevision(ctx, first))
+	assert.Equal(t, int64(1), first.Number)
+	assert.NoError(t, repo.AddRevision(ctx, &ads.Revision{AdID: other.ID, CreatedAt: at, Title: "other"}))
+	second := &ads.Revision{AdID: ad.ID, ActorID: 2, CreatedAt: at.Add(time.Minute), Title: "title",
+		State: ads.StatePublished, Changes: []ads.Change{{Field: ads.FieldState, Old: "draft", New: "published"}}}
+	assert.NoError(t, repo.AddRevision(ctx, second))
+	assert.Equal(t, int64(2), second.Number)
+
+	revisions, err := repo.GetRevisions(ctx, ad.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, revisions, 2) {
+		assert.Equal(t, first, revisions[0])
+		assert.Equal(t, second, revisions[1])
+	}
+
+	assert.NoError(t, repo.DeleteAd(ctx, ad.ID))
+	revisions, err = repo.GetRevisions(ctx, ad.ID)
+	assert.NoError(t, err)
+	assert.Empty(t, revisions)
+	revisions, err = repo.GetRevisions(ctx, other.ID)
+	assert.NoError(t, err)
+	assert.Len(t, revisions, 1)
+}
+
+func testEraseActor(t *testing.T, repo ads.Store) {
+	ctx := context.Background()
+	ad := addAd(t, repo, &ads.Ad{Title: "title"})
+	other := addAd(t, repo, &ads.Ad{Title: "other"})
+	at := time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)
+	for _, rev := range []*ads.Revision{
+		{AdID: other.ID, ActorID: 1, CreatedAt: at, Title: "other"},
+		{AdID: ad.ID, ActorID: 1, CreatedAt: at, Title: "title"},
+		{AdID: ad.ID, ActorID: 2, CreatedAt: at, Title: "title"},
+		{AdID: ad.ID, ActorID: 1, CreatedAt: at, Title: "title"},
+	} {
+		assert.NoError(t, repo.AddRevision(ctx, rev))
+	}
+
+	revisions, err := repo.GetRevisionsByActor(ctx, 1)
+	assert.NoError(t, err)
+	if assert.Len(t, revisions, 3) {
+		assert.Equal(t, [3]int64{ad.ID, ad.ID, other.ID}, [3]int64{revisions[0].AdID, revisions[1].AdID, revisions[2].AdID})
+		assert.Equal(t, [3]int64{1, 3, 1}, [3]int64{revisions[0].Number, revisions[1].Number, revisions[2].Number})
+	}
+
+	assert.NoError(t, repo.EraseActor(ctx, 1))
+	revisions, err = repo.GetRevisionsByActor(ctx, 1)
+	assert.NoError(t, err)
+	assert.Empty(t, revisions)
+	revisions, err = repo.GetRevisionsByActor(ctx, ads.ErasedActor)
+	assert.NoError(t, err)
+	assert.Len(t, revisions, 3)
+	revisions, err = repo.GetRevisions(ctx, ad.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, revisions, 3) {
+		assert.Equal(t, ads.ErasedActor, revisions[0].ActorID)
+		assert.Equal(t, int64(2), revisions[1].ActorID)
+		assert.Equal(t, ads.ErasedActor, revisions[2].ActorID)
+		assert.Equal(t, "title", revisions[2].Title)
+	}
+	assert.NoError(t, repo.EraseActor(ctx, 1))
+}
+
+func imageIds(list []*ads.Image) []int64 {
+	resp := make([]int64, len(list))
+	for i, img := range list {
+		resp[i] = img.ID
+	}
+	return resp
+}
+
+func testImages(t *testing.T, repo ads.Store) {
+	ctx := context.Background()
+	ad := addAd(t, repo, &ads.Ad{Title: "title", AuthorID: 1})
+	other := addAd(t, repo, &ads.Ad{Title: "other", AuthorID: 2})
+	at := time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	first := &ads.Image{AdID: ad.ID, ContentType: "image/jpeg", Size: 2048, Width: 800, Height: 600, CreatedAt: at}
+	_, err := repo.AddImage(ctx, first)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, first.Position)
+	second := &ads.Image{AdID: ad.ID, ContentType: "image/png", Size: 1024, Width: 10, Height: 20, CreatedAt: at}
+	_, err = repo.AddImage(ctx, second)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, second.Position)
+	assert.NotEqual(t, first.ID, second.ID)
+	_, err = repo.AddImage(ctx, &ads.Image{AdID: other.ID, ContentType: "image/png", CreatedAt: at})
+	assert.NoError(t, err)
+
+	list, err := repo.GetImages(ctx, ad.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, list, 2) {
+		assert.Equal(t, first, list[0])
+		assert.Equal(t, second, list[1])
+	}
+	list, err = repo.GetImages(ctx, 100)
+	assert.NoError(t, err)
+	assert.Empty(t, list)
+
+	list, err = repo.ReorderImages(ctx, ad.ID, []int64{second.ID, first.ID})
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{second.ID, first.ID}, imageIds(list))
+	list, err = repo.GetImages(ctx, ad.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{second.ID, first.ID}, imageIds(list))
+	_, err = repo.ReorderImages(ctx, ad.ID, []int64{second.ID})
+	assert.ErrorIs(t, err, ads.ErrInvalidImageOrder)
+	_, err = repo.ReorderImages(ctx, ad.ID, []int64{second.ID, second.ID})
+	assert.ErrorIs(t, err, ads.ErrInvalidImageOrder)
+
+	assert.NoError(t, repo.DeleteImage(ctx, ad.ID, second.ID))
+	assert.ErrorIs(t, repo.DeleteImage(ctx, ad.ID, second.ID), adrepo.ErrInvalidImageId)
+	assert.ErrorIs(t, repo.DeleteImage(ctx, other.ID, first.ID), adrepo.ErrInvalidImageId)
+	third := &ads.Image{AdID: ad.ID, ContentType: "image/jpeg", CreatedAt: at}
+	_, err = repo.AddImage(ctx, third)
+	assert.NoError(t, err)
+	assert.Greater(t, third.ID, second.ID)
+	list, err = repo.GetImages(ctx, ad.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{first.ID, third.ID}, imageIds(list))
+
+	// the images stay with a trashed ad and go away with a deleted one
+	_, err = repo.TrashAd(ctx, ad.ID, at)
+	assert.NoError(t, err)
+	list, err = repo.GetImages(ctx, ad.ID)
+	assert.NoError(t, err)
+	assert.Len(t, list, 2)
+	assert.NoError(t, repo.PurgeTrash(ctx, at.Add(time.Second)))
+	list, err = repo.GetImages(ctx, ad.ID)
+	assert.NoError(t, err)
+	assert.Empty(t, list)
+	assert.NoError(t, repo.DeleteAdsByAuthor(ctx, 2))
+	list, err = repo.GetImages(ctx, other.ID)
+	assert.NoError(t, err)
+	assert.Empty(t, list)
+}
+
+func testSchedule(t *testing.T, repo ads.Store) {
+	ctx := context.Background()
+	now := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	hour := time.Hour
+	scheduled := addAd(t, repo, &ads.Ad{Title: "scheduled", State: ads.StateDraft, PublishAt: now.Add(-hour),
+		ExpiresAt: now.Add(hour)})
+	later := addAd(t, repo, &ads.Ad{Title: "later", State: ads.StateDraft, PublishAt: now.Add(hour)})
+	addAd(t, repo, &ads.Ad{Title: "in review", State: ads.StatePendingReview, PublishAt: now.Add(-hour)})
+	expired := addAd(t, repo, &ads.Ad{Title: "expired", State: ads.StatePublished, ExpiresAt: now})
+	live := addAd(t, repo, &ads.Ad{Title: "live", State: ads.StatePublished, ExpiresAt: now.Add(hour)})
+	addAd(t, repo, &ads.Ad{Title: "archived", State: ads.StateArchived, ExpiresAt: now.Add(-hour)})
+	addAd(t, repo, &ads.Ad{Title: "forever", State: ads.StatePublished})
+	trashed := addAd(t, repo, &ads.Ad{Title: "trashed", State: ads.StatePublished, ExpiresAt: now.Add(-hour)})
+	_, err := repo.TrashAd(ctx, trashed.ID, now)
+	assert.NoError(t, err)
+
+	got, err := repo.GetAdById(ctx, scheduled.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, scheduled, got)
+	due, err := repo.GetDueAds(ctx, now)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{scheduled.ID, expired.ID}, ids(due))
+
+	updated, err := repo.UpdateAdPublication(ctx, scheduled.ID, ads.StatePublished, time.Time{}, now.Add(2*hour))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, ads.StatePublished, updated.State)
+	assert.True(t, updated.PublishAt.IsZero())
+	assert.Equal(t, now.Add(2*hour), updated.ExpiresAt)
+	assert.Equal(t, int64(2), updated.Version)
+	assert.NotEmpty(t, updated.UpdateDate)
+	got, err = repo.GetAdById(ctx, scheduled.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, updated, got)
+	_, err = repo.UpdateAdPublication(ctx, expired.ID, ads.StateArchived, time.Time{}, now)
+	assert.NoError(t, err)
+
+	due, err = repo.GetDueAds(ctx, now)
+	assert.NoError(t, err)
+	assert.Empty(t, due)
+	due, err = repo.GetDueAds(ctx, now.Add(hour))
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{later.ID, live.ID}, ids(due))
+}
+
+func testConcurrentWriters(t *testing.T, repo ads.Store) {
+	const workers, perWorker = 8, 20
+	ctx := context.Background()
+	shared := addAd(t, repo, &ads.Ad{Title: "shared", State: ads.StatePublished})
+
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		added = make(map[int64]struct{})
+	)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			for j := 0; j < perWorker; j++ {
+				id, err := repo.AddAd(ctx, &ads.Ad{Title: fmt.Sprintf("ad %d %d", worker, j), Version: 1})
+				assert.NoError(t, err)
+				mu.Lock()
+				added[id] = struct{}{}
+				mu.Unlock()
+
+				_, err = repo.UpdateAdTitleAndText(ctx, shared.ID, "shared", fmt.Sprintf("%d %d", worker, j))
+				assert.NoError(t, err)
+				_, err = repo.GetAll(ctx, ads.Filters{Status: ads.Unpublished})
+				assert.NoError(t, err)
+				_, err = repo.GetAdsByTitle(ctx, "ad")
+				assert.NoError(t, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Len(t, added, workers*perWorker)
+	list, err := repo.GetAll(ctx, ads.Filters{Status: ads.Unpublished})
+	assert.NoError(t, err)
+	assert.Len(t, list, workers*perWorker)
+	got, err := repo.GetAdById(ctx, shared.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1+workers*perWorker), got.Version)
+}
+
+func testCanceledContext(t *testing.T, repo ads.Store) {
+	ad := addAd(t, repo, &ads.Ad{Title: "title", AuthorID: 1})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := repo.AddAd(ctx, &ads.Ad{Title: "canceled"})
+	assert.ErrorIs(t, err, context.Canceled)
+	_, err = repo.GetAdById(ctx, ad.ID)
+	assert.ErrorIs(t, err, context.Canceled)
+	_, err = repo.GetAdsByTitle(ctx, "title")
+	assert.ErrorIs(t, err, context.Canceled)
+	_, err = repo.GetAll(ctx, ads.Filters{Status: ads.Unpublished})
+	assert.ErrorIs(t, err, context.Canceled)
+	_, err = repo.UpdateAdState(ctx, ad.ID, ads.StatePublished, "")
+	assert.ErrorIs(t, err, context.Canceled)
+	_, err = repo.UpdateAdTitleAndText(ctx, ad.ID, "canceled", "canceled")
+	assert.ErrorIs(t, err, context.Canceled)
+	_, err = repo.UpdateAdPublication(ctx, ad.ID, ads.StatePublished, time.Time{}, time.Time{})
+	assert.ErrorIs(t, err, context.Canceled)
+	_, err = repo.GetDueAds(ctx, time.Now())
+	assert.ErrorIs(t, err, context.Canceled)
+	_, err = repo.TrashAd(ctx, ad.ID, time.Now())
+	assert.ErrorIs(t, err, context.Canceled)
+	_, err = repo.RestoreAd(ctx, ad.ID)
+	assert.ErrorIs(t, err, context.Canceled)
+	_, err = repo.GetTrash(ctx, 1)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.ErrorIs(t, repo.PurgeTrash(ctx, time.Now()), context.Canceled)
+	assert.ErrorIs(t, repo.AddRevision(ctx, &ads.Revision{AdID: ad.ID}), context.Canceled)
+	_, err = repo.GetRevisions(ctx, ad.ID)
+	assert.ErrorIs(t, err, context.Canceled)
+	_, err = repo.GetRevisionsByActor(ctx, 1)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.ErrorIs(t, repo.EraseActor(ctx, 1), context.Canceled)
+	assert.ErrorIs(t, repo.DeleteAd(ctx, ad.ID), context.Canceled)
+	assert.ErrorIs(t, repo.DeleteAdsByAuthor(ctx, 1), context.Canceled)
+	_, err = repo.AddCategory(ctx, &ads.Category{Name: "canceled"})
+	assert.ErrorIs(t, err, context.Canceled)
+	_, err = repo.GetCategory(ctx, 1)
+	assert.ErrorIs(t, err, context.Canceled)
+	_, err = repo.GetCategories(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+	_, err = repo.UpdateCategory(ctx, &ads.Category{ID: 1, Name: "canceled"})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.ErrorIs(t, repo.DeleteCategory(ctx, 1), context.Canceled)
+	_, err = repo.CountAds(ctx, ads.Published)
+	assert.ErrorIs(t, err, context.Canceled)
+	_, err = repo.AddImage(ctx, &ads.Image{AdID: ad.ID})
+	assert.ErrorIs(t, err, context.Canceled)
+	_, err = repo.GetImages(ctx, ad.ID)
+	assert.ErrorIs(t, err, context.Canceled)
+	_, err = repo.ReorderImages(ctx, ad.ID, nil)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.ErrorIs(t, repo.DeleteImage(ctx, ad.ID, 1), context.Canceled)
+
+	// nothing has changed
+	got, err := repo.GetAdById(context.Background(), ad.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, ad, got)
+	list, err := repo.GetAdsByTitle(context.Background(), "")
+	assert.NoError(t, err)
+	assert.Len(t, list, 1)
+}
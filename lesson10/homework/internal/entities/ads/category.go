@@ -0,0 +1,75 @@
+package ads
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	ErrInvalidCategory = errors.New("invalid category params")
+	ErrUnknownCategory = errors.New("unknown category")
+	ErrCategoryInUse   = errors.New("the category still has ads or subcategories")
+)
+
+const maxCategoryNameLen = 50
+
+// Category is a node of the category tree. Category ids start at 1, the
+// zero ParentID makes a top level category.
+type Category struct {
+	ID       int64
+	ParentID int64
+	Name     string
+}
+
+// CategoryCount is a category with the number of published ads in it and in
+// all of its subcategories.
+type CategoryCount struct {
+	Category
+	Ads int
+}
+
+// ValidateCategory trims the name, which has to be there.
+func ValidateCategory(c *Category) error {
+	c.Name = strings.TrimSpace(c.Name)
+	if c.Name == "" || utf8.RuneCountInString(c.Name) > maxCategoryNameLen || c.ParentID < 0 {
+		return ErrInvalidCategory
+	}
+	return nil
+}
+
+// SortCategories orders the categories by id.
+func SortCategories(list []*Category) {
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].ID < list[j].ID
+	})
+}
+
+// Subtree returns the id of the category followed by the ids of all of its
+// subcategories, at any depth.
+func Subtree(list []*Category, id int64) []int64 {
+	children := make(map[int64][]int64)
+	for _, c := range list {
+		children[c.ParentID] = append(children[c.ParentID], c.ID)
+	}
+	resp := []int64{id}
+	for i := 0; i < len(resp); i++ {
+		resp = append(resp, children[resp[i]]...)
+	}
+	return resp
+}
+
+// CountCategories adds the ads counted in every category up along the tree,
+// counts maps a category id to the number of ads right in it.
+func CountCategories(list []*Category, counts map[int64]int) []*CategoryCount {
+	resp := make([]*CategoryCount, 0, len(list))
+	for _, c := range list {
+		total := 0
+		for _, id := range Subtree(list, c.ID) {
+			total += counts[id]
+		}
+		resp = append(resp, &CategoryCount{Category: *c, Ads: total})
+	}
+	return resp
+}
@@ -0,0 +1,35 @@
+package ads
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestValidateCategory(t *testing.T) {
+	c := &Category{Name: "  bikes "}
+	assert.NoError(t, ValidateCategory(c))
+	assert.Equal(t, "bikes", c.Name)
+
+	assert.ErrorIs(t, ValidateCategory(&Category{Name: " "}), ErrInvalidCategory)
+	assert.ErrorIs(t, ValidateCategory(&Category{Name: strings.Repeat("я", 51)}), ErrInvalidCategory)
+	assert.ErrorIs(t, ValidateCategory(&Category{Name: "bikes", ParentID: -1}), ErrInvalidCategory)
+}
+
+func TestSubtreeAndCounts(t *testing.T) {
+	list := []*Category{
+		{ID: 1, Name: "vehicles"},
+		{ID: 2, ParentID: 1, Name: "bikes"},
+		{ID: 3, ParentID: 2, Name: "mountain bikes"},
+		{ID: 4, Name: "furniture"},
+	}
+	assert.Equal(t, []int64{1, 2, 3}, Subtree(list, 1))
+	assert.Equal(t, []int64{4}, Subtree(list, 4))
+
+	counts := CountCategories(list, map[int64]int{0: 5, 1: 1, 3: 2, 4: 7})
+	total := make([]int, len(counts))
+	for i, c := range counts {
+		total[i] = c.Ads
+	}
+	assert.Equal(t, []int{3, 2, 2, 7}, total)
+}
@@ -15,22 +15,108 @@ type Filters struct {
 	Status   Status
 	Date     string
 	AuthorId string
+	// CategoryId takes in the subcategories, the app looks their ids up into
+	// Categories before the filters reach a repository.
+	CategoryId int64
+	Categories []int64
+	// PriceMin and PriceMax are in minor units, zero is no bound, and need
+	// Currency. Currency alone lists the ads priced in it.
+	PriceMin int64
+	PriceMax int64
+	Currency string
+
+	Sort SortField
+	Desc bool
+	// Limit is the page size, zero means no limit
+	Limit int
+	// Cursor is the opaque token of the page to start from
+	Cursor string
 }
 
+// Status picks the ads in one State, or in any state but published.
 type Status string
 
 const (
-	Published   Status = "published"
+	Published   Status = Status(StatePublished)
 	Unpublished Status = "unpublished"
 )
 
+// State is the state the status stands for, empty for Unpublished.
+func (s Status) State() State {
+	if s == Unpublished {
+		return ""
+	}
+	return State(s)
+}
+
 func (f *Filters) ValidateFilters() error {
 	if !isDateValid(f.Date) || !isStatusValid(f.Status) || !isAuthorIdValid(f.AuthorId) {
 		return ErrInvalidFilters
 	}
+	if !isSortValid(f.Sort) || !isLimitValid(f.Limit) || f.CategoryId < 0 {
+		return ErrInvalidFilters
+	}
+	if err := f.validatePrice(); err != nil {
+		return err
+	}
+	_, _, err := f.After()
+	return err
+}
+
+// Match reports whether the ad should be listed, ads in the trash never are.
+func (f *Filters) Match(ad *Ad) bool {
+	if ad.Trashed() {
+		return false
+	}
+	if f.Date != "" && ad.CreationDate != f.Date {
+		return false
+	}
+	if f.AuthorId != "" && strconv.FormatInt(ad.AuthorID, 10) != f.AuthorId {
+		return false
+	}
+	if len(f.Categories) > 0 && !containsId(f.Categories, ad.CategoryID) {
+		return false
+	}
+	if f.Currency != "" && ad.Price.Currency != f.Currency {
+		return false
+	}
+	if (f.PriceMin > 0 && ad.Price.Amount < f.PriceMin) || (f.PriceMax > 0 && ad.Price.Amount > f.PriceMax) {
+		return false
+	}
+	if f.Status == Unpublished {
+		return !ad.Published()
+	}
+	return ad.State == f.Status.State()
+}
+
+// validatePrice upper cases the currency.
+func (f *Filters) validatePrice() error {
+	if f.PriceMin < 0 || f.PriceMax < 0 || (f.PriceMax > 0 && f.PriceMin > f.PriceMax) {
+		return ErrInvalidFilters
+	}
+	if f.Currency == "" {
+		if f.PriceMin > 0 || f.PriceMax > 0 {
+			return ErrInvalidFilters
+		}
+		return nil
+	}
+	code, err := ParseCurrency(f.Currency)
+	if err != nil {
+		return ErrInvalidFilters
+	}
+	f.Currency = code
 	return nil
 }
 
+func containsId(ids []int64, id int64) bool {
+	for _, candidate := range ids {
+		if candidate == id {
+			return true
+		}
+	}
+	return false
+}
+
 func isDateValid(date string) bool {
 	if date == "" {
 		return true
@@ -60,13 +146,7 @@ func isDateValid(date string) bool {
 }
 
 func isStatusValid(status Status) bool {
-	switch status {
-	case Unpublished:
-	case Published:
-	default:
-		return false
-	}
-	return true
+	return status == Unpublished || status.State().Valid()
 }
 
 func isAuthorIdValid(id string) bool {
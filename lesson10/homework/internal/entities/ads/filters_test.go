@@ -74,6 +74,11 @@ func TestFiltersIsValid(t *testing.T) {
 			In:     IsValidStatusTest{Stat: Unpublished},
 			Expect: true,
 		},
+		{
+			Name:   "correct status archived",
+			In:     IsValidStatusTest{Stat: Status(StateArchived)},
+			Expect: true,
+		},
 		{
 			Name:   "incorrect status",
 			In:     IsValidStatusTest{Stat: Status("incorrect")},
@@ -0,0 +1,151 @@
+package ads
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidImage      = errors.New("the file is not a jpeg or png image")
+	ErrImageTooLarge     = errors.New("the image is too large")
+	ErrTooManyImages     = errors.New("the ad has as many images as it may have")
+	ErrInvalidImageOrder = errors.New("the order has to list every image of the ad once")
+)
+
+const (
+	// MaxImageSize is the size of the uploaded file in bytes.
+	MaxImageSize = 5 << 20
+	// MaxImagePixels keeps small files of huge images from being decoded,
+	// a decoded image takes up to 4 bytes a pixel.
+	MaxImagePixels = 4096 * 4096
+	MaxImagesPerAd = 10
+)
+
+// ImageVariant is a size an image is stored in, the original upload or one
+// of the thumbnails.
+type ImageVariant string
+
+const VariantOriginal ImageVariant = "original"
+
+// Thumbnails maps the thumbnail variants to the side of the square they are
+// fitted in, in pixels.
+var Thumbnails = map[ImageVariant]int{
+	"small":  160,
+	"medium": 480,
+	"large":  1024,
+}
+
+// ParseVariant reads a variant, the empty one is the original.
+func ParseVariant(s string) (ImageVariant, error) {
+	v := ImageVariant(s)
+	if v == "" || v == VariantOriginal {
+		return VariantOriginal, nil
+	}
+	if _, ok := Thumbnails[v]; !ok {
+		return "", ErrInvalidImage
+	}
+	return v, nil
+}
+
+// Image is a photo of an ad, its files are kept in the blob storage under
+// ImageKey.
+type Image struct {
+	ID   int64
+	AdID int64
+	// Position orders the images of an ad, the first one is the cover
+	Position    int
+	ContentType string
+	// Size is the size of the original file in bytes
+	Size      int64
+	Width     int
+	Height    int
+	CreatedAt time.Time
+}
+
+// ContentTypeOf is the type the variant is stored in, the thumbnails are
+// always jpegs.
+func (img *Image) ContentTypeOf(v ImageVariant) string {
+	if v == VariantOriginal {
+		return img.ContentType
+	}
+	return "image/jpeg"
+}
+
+// CheckImageType accepts jpeg and png, application/octet-stream counts as
+// no declared type.
+func CheckImageType(declared, detected string) (string, error) {
+	declared = strings.ToLower(strings.TrimSpace(strings.Split(declared, ";")[0]))
+	if declared != "" && declared != "application/octet-stream" && declared != detected {
+		return "", ErrInvalidImage
+	}
+	switch detected {
+	case "image/jpeg", "image/png":
+		return detected, nil
+	}
+	return "", ErrInvalidImage
+}
+
+// ImageKey is the blob storage key of a variant of an image, all the
+// files of an ad share the AdImagesPrefix.
+func ImageKey(adId, imageId int64, v ImageVariant) string {
+	return fmt.Sprintf("%s%d/%s", AdImagesPrefix(adId), imageId, v)
+}
+
+func AdImagesPrefix(adId int64) string {
+	return fmt.Sprintf("ads/%d/", adId)
+}
+
+// ParseImageKey is the reverse of ImageKey.
+func ParseImageKey(key string) (adId, imageId int64, ok bool) {
+	parts := strings.Split(key, "/")
+	if len(parts) != 4 || parts[0] != "ads" {
+		return 0, 0, false
+	}
+	adId, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	imageId, err = strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	return adId, imageId, true
+}
+
+// SortImages orders the images of an ad by position, then by id.
+func SortImages(list []*Image) {
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Position != list[j].Position {
+			return list[i].Position < list[j].Position
+		}
+		return list[i].ID < list[j].ID
+	})
+}
+
+// ReorderImages gives the images the positions of their ids in order, which
+// has to list all of them once.
+func ReorderImages(list []*Image, order []int64) error {
+	if len(order) != len(list) {
+		return ErrInvalidImageOrder
+	}
+	positions := make(map[int64]int, len(order))
+	for i, id := range order {
+		if _, ok := positions[id]; ok {
+			return ErrInvalidImageOrder
+		}
+		positions[id] = i
+	}
+	for _, img := range list {
+		pos, ok := positions[img.ID]
+		if !ok {
+			return ErrInvalidImageOrder
+		}
+		img.Position = pos
+	}
+	SortImages(list)
+	return nil
+}
@@ -0,0 +1,60 @@
+package ads
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestCheckImageType(t *testing.T) {
+	tests := []struct {
+		declared, detected string
+		want               string
+		wantErr            error
+	}{
+		{"", "image/png", "image/png", nil},
+		{"image/JPEG; charset=binary", "image/jpeg", "image/jpeg", nil},
+		{"application/octet-stream", "image/jpeg", "image/jpeg", nil},
+		{"image/png", "image/jpeg", "", ErrInvalidImage},
+		{"", "image/gif", "", ErrInvalidImage},
+		{"image/gif", "image/gif", "", ErrInvalidImage},
+	}
+	for _, tc := range tests {
+		got, err := CheckImageType(tc.declared, tc.detected)
+		assert.ErrorIs(t, err, tc.wantErr)
+		assert.Equal(t, tc.want, got)
+	}
+}
+
+func TestParseVariant(t *testing.T) {
+	v, err := ParseVariant("")
+	assert.NoError(t, err)
+	assert.Equal(t, VariantOriginal, v)
+	v, err = ParseVariant("small")
+	assert.NoError(t, err)
+	assert.Equal(t, ImageVariant("small"), v)
+	_, err = ParseVariant("huge")
+	assert.ErrorIs(t, err, ErrInvalidImage)
+}
+
+func TestImageKey(t *testing.T) {
+	key := ImageKey(3, 14, "medium")
+	assert.Equal(t, "ads/3/14/medium", key)
+	adId, imageId, ok := ParseImageKey(key)
+	assert.True(t, ok)
+	assert.Equal(t, [2]int64{3, 14}, [2]int64{adId, imageId})
+
+	for _, key = range []string{"ads/3/14", "users/3/14/small", "ads/x/14/small", "ads/3/14/small/more"} {
+		_, _, ok = ParseImageKey(key)
+		assert.False(t, ok, key)
+	}
+}
+
+func TestReorderImages(t *testing.T) {
+	list := []*Image{{ID: 1, Position: 0}, {ID: 2, Position: 1}, {ID: 3, Position: 2}}
+	assert.NoError(t, ReorderImages(list, []int64{3, 1, 2}))
+	assert.Equal(t, []*Image{{ID: 3, Position: 0}, {ID: 1, Position: 1}, {ID: 2, Position: 2}}, list)
+
+	for _, order := range [][]int64{{3, 1}, {3, 1, 1}, {3, 1, 4}} {
+		assert.ErrorIs(t, ReorderImages(list, order), ErrInvalidImageOrder)
+	}
+}
@@ -0,0 +1,63 @@
+package ads
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrInvalidPrice = errors.New("invalid price, want a non-negative amount in minor units and a currency code")
+)
+
+// Money is an amount in minor units, the zero Money is no price.
+type Money struct {
+	Amount int64
+	// Currency is the ISO 4217 code in upper case, e.g. RUB
+	Currency string
+}
+
+func (m Money) IsZero() bool {
+	return m == Money{}
+}
+
+// ParseCurrency upper cases the code and checks it is three latin letters.
+func ParseCurrency(s string) (string, error) {
+	code := strings.ToUpper(strings.TrimSpace(s))
+	if len(code) != 3 {
+		return "", ErrInvalidPrice
+	}
+	for _, r := range code {
+		if r < 'A' || r > 'Z' {
+			return "", ErrInvalidPrice
+		}
+	}
+	return code, nil
+}
+
+// ValidatePrice normalizes the currency of a price, any price but the zero
+// one needs a currency.
+func ValidatePrice(m *Money) error {
+	if m.IsZero() {
+		return nil
+	}
+	if m.Amount < 0 {
+		return ErrInvalidPrice
+	}
+	var err error
+	m.Currency, err = ParseCurrency(m.Currency)
+	return err
+}
+
+// ParsePrice turns the price_min and price_max query parameters, in minor
+// units, into the price bounds of Filters, an empty one means no bound.
+func ParsePrice(price string) (int64, error) {
+	if price == "" {
+		return 0, nil
+	}
+	n, err := strconv.ParseInt(price, 10, 64)
+	if err != nil || n < 0 {
+		return 0, ErrInvalidFilters
+	}
+	return n, nil
+}
@@ -0,0 +1,33 @@
+package ads
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestValidatePrice(t *testing.T) {
+	m := &Money{Amount: 1250, Currency: " rub"}
+	assert.NoError(t, ValidatePrice(m))
+	assert.Equal(t, "RUB", m.Currency)
+
+	assert.NoError(t, ValidatePrice(&Money{}))
+	assert.ErrorIs(t, ValidatePrice(&Money{Amount: 100}), ErrInvalidPrice)
+	assert.ErrorIs(t, ValidatePrice(&Money{Amount: -1, Currency: "RUB"}), ErrInvalidPrice)
+	assert.ErrorIs(t, ValidatePrice(&Money{Amount: 1, Currency: "RU1"}), ErrInvalidPrice)
+	assert.ErrorIs(t, ValidatePrice(&Money{Amount: 1, Currency: "рубль"}), ErrInvalidPrice)
+}
+
+func TestParsePrice(t *testing.T) {
+	n, err := ParsePrice("")
+	assert.NoError(t, err)
+	assert.Zero(t, n)
+
+	n, err = ParsePrice("1250")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1250), n)
+
+	_, err = ParsePrice("-1")
+	assert.ErrorIs(t, err, ErrInvalidFilters)
+	_, err = ParsePrice("12.50")
+	assert.ErrorIs(t, err, ErrInvalidFilters)
+}
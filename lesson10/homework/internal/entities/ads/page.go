@@ -0,0 +1,174 @@
+package ads
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+type SortField string
+
+const (
+	SortById           SortField = "id"
+	SortByCreationDate SortField = "creation_date"
+	SortByUpdateDate   SortField = "update_date"
+	SortByTitle        SortField = "title"
+	// SortByPrice orders by the amount alone, whatever the currency, so it
+	// is meant to go together with the currency filter.
+	SortByPrice SortField = "price"
+)
+
+// MaxLimit caps the page size a client can ask for, a zero limit returns
+// every matching ad.
+const MaxLimit = 100
+
+// cursor points at the last ad of the previous page. It remembers the order
+// it was made for, so it cannot be replayed against another one.
+type cursor struct {
+	Sort SortField `json:"s"`
+	Desc bool      `json:"d,omitempty"`
+	Key  string    `json:"k,omitempty"`
+	ID   int64     `json:"i"`
+}
+
+func (f *Filters) sortField() SortField {
+	if f.Sort == "" {
+		return SortById
+	}
+	return f.Sort
+}
+
+// SortKey is the value of the sort field of the ad, ads with equal keys are
+// ordered by id.
+func (f *Filters) SortKey(ad *Ad) string {
+	switch f.sortField() {
+	case SortByCreationDate:
+		return ad.CreationDate
+	case SortByUpdateDate:
+		return ad.UpdateDate
+	case SortByTitle:
+		return ad.Title
+	case SortByPrice:
+		return PriceKey(ad.Price.Amount)
+	}
+	return ""
+}
+
+// PriceKey pads the amount so the keys of non-negative amounts sort the
+// way the amounts do.
+func PriceKey(amount int64) string {
+	return fmt.Sprintf("%019d", amount)
+}
+
+// Less reports whether a goes before b in the requested order.
+func (f *Filters) Less(a, b *Ad) bool {
+	ka, kb := f.SortKey(a), f.SortKey(b)
+	if ka != kb {
+		return (ka < kb) != f.Desc
+	}
+	if a.ID != b.ID {
+		return (a.ID < b.ID) != f.Desc
+	}
+	return false
+}
+
+// NextCursor returns the token of the page that follows the given ad.
+func (f *Filters) NextCursor(last *Ad) string {
+	data, _ := json.Marshal(cursor{Sort: f.sortField(), Desc: f.Desc, Key: f.SortKey(last), ID: last.ID})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// After decodes the cursor into the ad position the page starts after,
+// ok is false for the first page.
+func (f *Filters) After() (after *Ad, ok bool, err error) {
+	if f.Cursor == "" {
+		return nil, false, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(f.Cursor)
+	if err != nil {
+		return nil, false, ErrInvalidFilters
+	}
+	var c cursor
+	if err = json.Unmarshal(data, &c); err != nil {
+		return nil, false, ErrInvalidFilters
+	}
+	if c.Sort != f.sortField() || c.Desc != f.Desc {
+		return nil, false, ErrInvalidFilters
+	}
+	after = &Ad{ID: c.ID}
+	switch c.Sort {
+	case SortByCreationDate:
+		after.CreationDate = c.Key
+	case SortByUpdateDate:
+		after.UpdateDate = c.Key
+	case SortByTitle:
+		after.Title = c.Key
+	case SortByPrice:
+		after.Price.Amount, err = strconv.ParseInt(c.Key, 10, 64)
+		if err != nil {
+			return nil, false, ErrInvalidFilters
+		}
+	}
+	return after, true, nil
+}
+
+// Page sorts the matching ads and cuts the requested page out of them, it
+// is meant for repositories that keep ads in memory.
+func (f *Filters) Page(list []*Ad) ([]*Ad, error) {
+	after, ok, err := f.After()
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return f.Less(list[i], list[j])
+	})
+	if ok {
+		start := sort.Search(len(list), func(i int) bool {
+			return f.Less(after, list[i])
+		})
+		list = list[start:]
+	}
+	if f.Limit > 0 && len(list) > f.Limit {
+		list = list[:f.Limit]
+	}
+	return list, nil
+}
+
+func isSortValid(field SortField) bool {
+	switch field {
+	case "", SortById, SortByCreationDate, SortByUpdateDate, SortByTitle, SortByPrice:
+		return true
+	}
+	return false
+}
+
+func isLimitValid(limit int) bool {
+	return limit >= 0 && limit <= MaxLimit
+}
+
+// ParseOrder turns the order query parameter, asc or desc, into
+// Filters.Desc.
+func ParseOrder(order string) (bool, error) {
+	switch order {
+	case "", "asc":
+		return false, nil
+	case "desc":
+		return true, nil
+	}
+	return false, ErrInvalidFilters
+}
+
+// ParseLimit turns the limit query parameter into Filters.Limit, an empty
+// one means no limit.
+func ParseLimit(limit string) (int, error) {
+	if limit == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(limit)
+	if err != nil || !isLimitValid(n) {
+		return 0, ErrInvalidFilters
+	}
+	return n, nil
+}
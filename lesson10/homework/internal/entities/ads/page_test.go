@@ -0,0 +1,85 @@
+package ads
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type PageTestCase struct {
+	Name    string
+	Filters Filters
+	Expect  []int64
+}
+
+func testAds() []*Ad {
+	return []*Ad{
+		{ID: 0, Title: "b", CreationDate: "2023-04-02"},
+		{ID: 1, Title: "a", CreationDate: "2023-04-01"},
+		{ID: 2, Title: "b", CreationDate: "2023-04-01"},
+		{ID: 3, Title: "c", CreationDate: "2023-04-03"},
+	}
+}
+
+func ids(list []*Ad) []int64 {
+	resp := make([]int64, len(list))
+	for i, ad := range list {
+		resp[i] = ad.ID
+	}
+	return resp
+}
+
+func TestPage(t *testing.T) {
+	tests := []PageTestCase{
+		{Name: "default is by id", Filters: Filters{}, Expect: []int64{0, 1, 2, 3}},
+		{Name: "by title ties by id", Filters: Filters{Sort: SortByTitle}, Expect: []int64{1, 0, 2, 3}},
+		{Name: "by title desc", Filters: Filters{Sort: SortByTitle, Desc: true}, Expect: []int64{3, 2, 0, 1}},
+		{Name: "by creation date", Filters: Filters{Sort: SortByCreationDate}, Expect: []int64{1, 2, 0, 3}},
+		{Name: "limit", Filters: Filters{Sort: SortByCreationDate, Limit: 3}, Expect: []int64{1, 2, 0}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.Name, func(t *testing.T) {
+			page, err := tc.Filters.Page(testAds())
+			assert.NoError(t, err)
+			assert.Equal(t, tc.Expect, ids(page))
+		})
+	}
+}
+
+func TestPageCursor(t *testing.T) {
+	for _, f := range []Filters{
+		{Sort: SortById, Limit: 1},
+		{Sort: SortByTitle, Limit: 3},
+		{Sort: SortByTitle, Desc: true, Limit: 2},
+		{Sort: SortByCreationDate, Limit: 2},
+	} {
+		all, err := f.Page(testAds())
+		assert.NoError(t, err)
+		whole := Filters{Sort: f.Sort, Desc: f.Desc}
+		expect, _ := whole.Page(testAds())
+
+		var got []*Ad
+		for len(all) > 0 {
+			got = append(got, all...)
+			f.Cursor = f.NextCursor(all[len(all)-1])
+			all, err = f.Page(testAds())
+			assert.NoError(t, err)
+		}
+		assert.Equal(t, ids(expect), ids(got))
+	}
+}
+
+func TestCursorValidation(t *testing.T) {
+	f := Filters{Status: Published, Sort: SortByTitle}
+	cursor := f.NextCursor(&Ad{ID: 1, Title: "a"})
+
+	f.Cursor = cursor
+	assert.NoError(t, f.ValidateFilters())
+	f.Cursor = "not a cursor"
+	assert.ErrorIs(t, f.ValidateFilters(), ErrInvalidFilters)
+	f = Filters{Status: Published, Sort: SortById, Cursor: cursor}
+	assert.ErrorIs(t, f.ValidateFilters(), ErrInvalidFilters)
+	f = Filters{Status: Published, Sort: "rating"}
+	assert.ErrorIs(t, f.ValidateFilters(), ErrInvalidFilters)
+	f = Filters{Status: Published, Limit: MaxLimit + 1}
+	assert.ErrorIs(t, f.ValidateFilters(), ErrInvalidFilters)
+}
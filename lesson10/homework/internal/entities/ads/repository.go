@@ -2,14 +2,81 @@ package ads
 
 import (
 	"context"
+	"time"
 )
 
+// Store is implemented by the ad storages, the unit of work hands its parts
+// out separately.
+type Store interface {
+	Repository
+	RevisionRepository
+	CategoryRepository
+	ImageRepository
+	ScheduleRepository
+}
+
 type Repository interface {
 	AddAd(ctx context.Context, ad *Ad) (int64, error)
 	GetAdById(ctx context.Context, adId int64) (*Ad, error)
 	GetAdsByTitle(ctx context.Context, title string) ([]*Ad, error)
 	GetAll(ctx context.Context, filters Filters) ([]*Ad, error)
-	UpdateAdStatus(ctx context.Context, adId int64, newStatus bool) (*Ad, error)
+	// UpdateAdState keeps the rejection reason only in StateRejected
+	UpdateAdState(ctx context.Context, adId int64, state State, rejectionReason string) (*Ad, error)
 	UpdateAdTitleAndText(ctx context.Context, adId int64, newTitle, newText string) (*Ad, error)
 	DeleteAd(ctx context.Context, adId int64) error
+	DeleteAdsByAuthor(ctx context.Context, authorId int64) error
+	// trashed ads are left out of every read above, only GetTrash lists them
+	TrashAd(ctx context.Context, adId int64, at time.Time) (*Ad, error)
+	RestoreAd(ctx context.Context, adId int64) (*Ad, error)
+	GetTrash(ctx context.Context, authorId int64) ([]*Ad, error)
+	PurgeTrash(ctx context.Context, before time.Time) error
+}
+
+type RevisionRepository interface {
+	// AddRevision numbers rev as the next revision of its ad, the revisions
+	// go away together with the ad when it is deleted for good
+	AddRevision(ctx context.Context, rev *Revision) error
+	GetRevisions(ctx context.Context, adId int64) ([]*Revision, error)
+	// GetRevisionsByActor returns the revisions the user made to any ad,
+	// sorted by ad and number
+	GetRevisionsByActor(ctx context.Context, actorId int64) ([]*Revision, error)
+	// EraseActor replaces the user with ErasedActor in all the revisions
+	EraseActor(ctx context.Context, actorId int64) error
+}
+
+type CategoryRepository interface {
+	// AddCategory gives the category the next id, the ids start at 1.
+	AddCategory(ctx context.Context, c *Category) (int64, error)
+	GetCategory(ctx context.Context, id int64) (*Category, error)
+	// GetCategories returns the whole tree, sorted by id.
+	GetCategories(ctx context.Context) ([]*Category, error)
+	UpdateCategory(ctx context.Context, c *Category) (*Category, error)
+	// DeleteCategory fails with ErrCategoryInUse while there are
+	// subcategories or ads in the category, the trashed ones too.
+	DeleteCategory(ctx context.Context, id int64) error
+	// CountAds returns the number of ads with the status right in every
+	// category, the trashed ones left out.
+	CountAds(ctx context.Context, status Status) (map[int64]int, error)
+}
+
+type ImageRepository interface {
+	// AddImage puts the image after the others of its ad, the images go away
+	// with the ad but their files are left to the app.
+	AddImage(ctx context.Context, img *Image) (int64, error)
+	// GetImages returns the images of the ad sorted as SortImages does, the
+	// ones of trashed ads too.
+	GetImages(ctx context.Context, adId int64) ([]*Image, error)
+	// ReorderImages fails with ErrInvalidImageOrder unless order lists
+	// every image of the ad once.
+	ReorderImages(ctx context.Context, adId int64, order []int64) ([]*Image, error)
+	DeleteImage(ctx context.Context, adId, imageId int64) error
+}
+
+type ScheduleRepository interface {
+	// UpdateAdPublication sets the state of the ad together with when the
+	// scheduler publishes and archives it, the rejection reason is cleared
+	UpdateAdPublication(ctx context.Context, adId int64, state State, publishAt, expiresAt time.Time) (*Ad, error)
+	// GetDueAds returns the ads the scheduler has to publish or archive at
+	// now as Ad.Due tells, sorted by id, the trashed ones left out
+	GetDueAds(ctx context.Context, now time.Time) ([]*Ad, error)
 }
@@ -0,0 +1,114 @@
+package ads
+
+import (
+	"errors"
+	"sort"
+	"time"
+)
+
+var (
+	ErrInvalidRevision = errors.New("the ad has no such revision")
+)
+
+const (
+	FieldTitle = "title"
+	FieldText  = "text"
+	FieldState = "state"
+	// FieldPublished is the field of the status changes recorded before the
+	// ads had states, its values are "true" and "false"
+	FieldPublished = "published"
+
+	// ErasedActor stands in for the actor of the revisions made by a user
+	// whose data was erased.
+	ErasedActor int64 = -1
+)
+
+// Change is a field of an ad before and after a revision.
+type Change struct {
+	Field string
+	Old   string
+	New   string
+}
+
+// Revision is the ad right after a change, with who made it and when.
+// Revisions are numbered from 1 and never change, but for erasing their actor.
+type Revision struct {
+	AdID    int64
+	Number  int64
+	ActorID int64
+	// Reason is given by a moderator changing the ad of another user, or by
+	// the scheduler
+	Reason    string
+	CreatedAt time.Time
+	Title     string
+	Text      string
+	State     State
+	Changes   []Change
+}
+
+// NewRevision records ad as it is now, prev is the ad before the change or
+// nil if the ad has just been created.
+func NewRevision(prev, ad *Ad, actorId int64, at time.Time) *Revision {
+	if prev == nil {
+		prev = &Ad{}
+	}
+	rev := &Revision{
+		AdID:      ad.ID,
+		ActorID:   actorId,
+		CreatedAt: at,
+		Title:     ad.Title,
+		Text:      ad.Text,
+		State:     ad.State,
+	}
+	if prev.Title != ad.Title {
+		rev.Changes = append(rev.Changes, Change{Field: FieldTitle, Old: prev.Title, New: ad.Title})
+	}
+	if prev.Text != ad.Text {
+		rev.Changes = append(rev.Changes, Change{Field: FieldText, Old: prev.Text, New: ad.Text})
+	}
+	if prev.State != ad.State {
+		rev.Changes = append(rev.Changes, Change{Field: FieldState, Old: string(prev.State), New: string(ad.State)})
+	}
+	return rev
+}
+
+// Apply returns a copy of ad with the fields it had at the revision.
+func (rev *Revision) Apply(ad *Ad) *Ad {
+	c := *ad
+	c.Title = rev.Title
+	c.Text = rev.Text
+	c.State = rev.State
+	c.RejectionReason = ""
+	if rev.State == StateRejected {
+		c.RejectionReason = rev.Reason
+	}
+	return &c
+}
+
+// SortRevisions orders the revisions by ad and number.
+func SortRevisions(list []*Revision) {
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].AdID != list[j].AdID {
+			return list[i].AdID < list[j].AdID
+		}
+		return list[i].Number < list[j].Number
+	})
+}
+
+// EraseActor returns a copy of the revisions of an ad with ErasedActor in
+// place of the user, false when the user made none of them.
+func EraseActor(list []*Revision, actorId int64) ([]*Revision, bool) {
+	var erased []*Revision
+	for i, rev := range list {
+		if rev.ActorID != actorId {
+			continue
+		}
+		if erased == nil {
+			erased = append(make([]*Revision, 0, len(list)), list...)
+		}
+		c := *rev
+		c.ActorID = ErasedActor
+		erased[i] = &c
+	}
+	return erased, erased != nil
+}
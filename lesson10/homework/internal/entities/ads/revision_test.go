@@ -0,0 +1,41 @@
+package ads
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestNewRevision(t *testing.T) {
+	at := time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)
+	ad := &Ad{ID: 3, Title: "title", Text: "text", State: StateDraft}
+
+	created := NewRevision(nil, ad, 1, at)
+	assert.Equal(t, int64(3), created.AdID)
+	assert.Equal(t, []Change{
+		{Field: FieldTitle, Old: "", New: "title"},
+		{Field: FieldText, Old: "", New: "text"},
+		{Field: FieldState, Old: "", New: "draft"},
+	}, created.Changes)
+
+	published := *ad
+	published.State = StatePublished
+	rev := NewRevision(ad, &published, 2, at)
+	assert.Equal(t, int64(2), rev.ActorID)
+	assert.Equal(t, StatePublished, rev.State)
+	assert.Equal(t, []Change{{Field: FieldState, Old: "draft", New: "published"}}, rev.Changes)
+
+	assert.Empty(t, NewRevision(ad, ad, 1, at).Changes)
+}
+
+func TestRevisionApply(t *testing.T) {
+	rev := &Revision{Title: "old title", Text: "old text", State: StateDraft}
+	ad := &Ad{ID: 3, Title: "title", Text: "text", State: StateRejected, RejectionReason: "spam", Version: 4}
+
+	old := rev.Apply(ad)
+	assert.Equal(t, &Ad{ID: 3, Title: "old title", Text: "old text", State: StateDraft, Version: 4}, old)
+	assert.Equal(t, "title", ad.Title)
+
+	rev = &Revision{Title: "title", Text: "text", State: StateRejected, Reason: "offensive"}
+	assert.Equal(t, "offensive", rev.Apply(ad).RejectionReason)
+}
@@ -0,0 +1,66 @@
+package ads
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrInvalidSchedule = errors.New("the ad can only be scheduled to go up and expire in the future, in that order")
+	ErrCantRenew       = errors.New("only the published and the archived ads can be renewed")
+)
+
+// Lifetime is how long a published ad stays up unless it is given another
+// expiration or renewed.
+const Lifetime = 30 * 24 * time.Hour
+
+const (
+	// SchedulerActor stands in for the actor of the revisions made by the
+	// scheduler publishing and taking down ads.
+	SchedulerActor int64 = -2
+
+	ReasonScheduled = "scheduled publication"
+	ReasonExpired   = "expired"
+)
+
+// ValidateSchedule checks the times an author sets at now, a zero time is
+// left out.
+func ValidateSchedule(publishAt, expiresAt, now time.Time) error {
+	if !publishAt.IsZero() && !publishAt.After(now) {
+		return ErrInvalidSchedule
+	}
+	if !expiresAt.IsZero() && (!expiresAt.After(now) || !expiresAt.After(publishAt)) {
+		return ErrInvalidSchedule
+	}
+	return nil
+}
+
+// Expiration is when an ad published at t expires: at expiresAt if that is
+// later, Lifetime after t otherwise.
+func Expiration(expiresAt, t time.Time) time.Time {
+	if expiresAt.After(t) {
+		return expiresAt
+	}
+	return t.Add(Lifetime)
+}
+
+// DuePublish tells whether the scheduler has to publish the ad at now.
+func (ad *Ad) DuePublish(now time.Time) bool {
+	return ad.State == StateDraft && !ad.PublishAt.IsZero() && !ad.PublishAt.After(now)
+}
+
+// DueExpire tells whether the scheduler has to archive the ad at now.
+func (ad *Ad) DueExpire(now time.Time) bool {
+	return ad.Published() && ad.Expired(now)
+}
+
+// Expired tells whether the expiration of the ad has passed at now, it is
+// still up until the scheduler archives it.
+func (ad *Ad) Expired(now time.Time) bool {
+	return !ad.ExpiresAt.IsZero() && !ad.ExpiresAt.After(now)
+}
+
+// Due tells whether the scheduler has anything to do with the ad at now.
+func (ad *Ad) Due(now time.Time) bool {
+	return !ad.Trashed() && (ad.DuePublish(now) || ad.DueExpire(now))
+}
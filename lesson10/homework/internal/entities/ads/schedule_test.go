@@ -0,0 +1,49 @@
+package ads
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestValidateSchedule(t *testing.T) {
+	now := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	hour := time.Hour
+	assert.NoError(t, ValidateSchedule(time.Time{}, time.Time{}, now))
+	assert.NoError(t, ValidateSchedule(now.Add(hour), time.Time{}, now))
+	assert.NoError(t, ValidateSchedule(time.Time{}, now.Add(hour), now))
+	assert.NoError(t, ValidateSchedule(now.Add(hour), now.Add(2*hour), now))
+	assert.ErrorIs(t, ValidateSchedule(now, time.Time{}, now), ErrInvalidSchedule)
+	assert.ErrorIs(t, ValidateSchedule(time.Time{}, now.Add(-hour), now), ErrInvalidSchedule)
+	assert.ErrorIs(t, ValidateSchedule(now.Add(2*hour), now.Add(hour), now), ErrInvalidSchedule)
+}
+
+func TestExpiration(t *testing.T) {
+	now := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	assert.Equal(t, now.Add(Lifetime), Expiration(time.Time{}, now))
+	assert.Equal(t, now.Add(Lifetime), Expiration(now, now))
+	assert.Equal(t, now.Add(time.Hour), Expiration(now.Add(time.Hour), now))
+}
+
+func TestAdDue(t *testing.T) {
+	now := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		ad   Ad
+		want bool
+	}{
+		{"draft", Ad{State: StateDraft}, false},
+		{"scheduled", Ad{State: StateDraft, PublishAt: now.Add(time.Hour)}, false},
+		{"to publish", Ad{State: StateDraft, PublishAt: now}, true},
+		{"sent to review", Ad{State: StatePendingReview, PublishAt: now}, false},
+		{"up", Ad{State: StatePublished, ExpiresAt: now.Add(time.Hour)}, false},
+		{"up for good", Ad{State: StatePublished}, false},
+		{"to archive", Ad{State: StatePublished, ExpiresAt: now}, true},
+		{"archived", Ad{State: StateArchived, ExpiresAt: now}, false},
+		{"sold", Ad{State: StateSold, ExpiresAt: now}, false},
+		{"trashed", Ad{State: StateDraft, PublishAt: now, DeletedAt: now}, false},
+	}
+	for _, tc := range tests {
+		assert.Equal(t, tc.want, tc.ad.Due(now), tc.name)
+	}
+}
@@ -0,0 +1,15 @@
+package ads
+
+import "errors"
+
+var (
+	ErrEmptySearchQuery = errors.New("search query is empty")
+)
+
+// SearchIndex finds ads by the words of their title and text. Search returns
+// ad ids ordered from the most relevant one.
+type SearchIndex interface {
+	Put(ad *Ad)
+	Remove(adId int64)
+	Search(query string) []int64
+}
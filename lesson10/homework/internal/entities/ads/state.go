@@ -0,0 +1,64 @@
+package ads
+
+import (
+	"errors"
+)
+
+var (
+	ErrInvalidState      = errors.New("unknown ad state")
+	ErrIllegalTransition = errors.New("the ad can't go from its state to that one")
+)
+
+// State is where an ad is in its lifecycle, only the published ads are
+// listed to everyone.
+type State string
+
+const (
+	StateDraft         State = "draft"
+	StatePendingReview State = "pending_review"
+	StatePublished     State = "published"
+	// StateRejected is set by a moderator, the ad keeps the reason
+	StateRejected State = "rejected"
+	// StateArchived is where the ads go when they expire
+	StateArchived State = "archived"
+	StateSold     State = "sold"
+)
+
+// States lists every state in the order an ad usually goes through them.
+var States = []State{StateDraft, StatePendingReview, StatePublished, StateRejected, StateArchived, StateSold}
+
+func (s State) Valid() bool {
+	for _, state := range States {
+		if s == state {
+			return true
+		}
+	}
+	return false
+}
+
+func ParseState(s string) (State, error) {
+	state := State(s)
+	if !state.Valid() {
+		return "", ErrInvalidState
+	}
+	return state, nil
+}
+
+// StateOf maps the Published flag the ads were stored with before they had
+// states: the published ads stay published, the others become drafts.
+func StateOf(published bool) State {
+	if published {
+		return StatePublished
+	}
+	return StateDraft
+}
+
+// SetState moves the ad to the state, the rejection reason is only kept in
+// StateRejected.
+func (ad *Ad) SetState(state State, rejectionReason string) {
+	ad.State = state
+	ad.RejectionReason = ""
+	if state == StateRejected {
+		ad.RejectionReason = rejectionReason
+	}
+}
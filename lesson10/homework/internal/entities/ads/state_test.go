@@ -0,0 +1,39 @@
+package ads
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParseState(t *testing.T) {
+	for _, state := range States {
+		got, err := ParseState(string(state))
+		assert.NoError(t, err)
+		assert.Equal(t, state, got)
+	}
+	for _, s := range []string{"", "unpublished", "Published"} {
+		_, err := ParseState(s)
+		assert.ErrorIs(t, err, ErrInvalidState, s)
+	}
+}
+
+func TestFiltersMatchState(t *testing.T) {
+	archived := &Ad{State: StateArchived}
+	published := &Ad{State: StatePublished}
+	tests := []struct {
+		status Status
+		ad     *Ad
+		want   bool
+	}{
+		{Published, published, true},
+		{Published, archived, false},
+		{Unpublished, archived, true},
+		{Unpublished, published, false},
+		{Status(StateArchived), archived, true},
+		{Status(StateSold), archived, false},
+	}
+	for _, tc := range tests {
+		f := Filters{Status: tc.status}
+		assert.Equal(t, tc.want, f.Match(tc.ad), tc.status)
+	}
+}
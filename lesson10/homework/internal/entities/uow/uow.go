@@ -0,0 +1,47 @@
+package uow
+
+import (
+	"context"
+	"homework10/internal/entities/ads"
+	"homework10/internal/entities/user"
+)
+
+type Repositories struct {
+	Ads        ads.Repository
+	Revisions  ads.RevisionRepository
+	Categories ads.CategoryRepository
+	Images     ads.ImageRepository
+	Schedule   ads.ScheduleRepository
+	Users      user.Repository
+}
+
+// NewRepositories hands out the parts of the ad store separately.
+func NewRepositories(adStore ads.Store, userRepo user.Repository) Repositories {
+	return Repositories{Ads: adStore, Revisions: adStore, Categories: adStore, Images: adStore,
+		Schedule: adStore, Users: userRepo}
+}
+
+// UnitOfWork applies the writes fn makes through repos only if it returns
+// nil, Do must not be nested. Reads outside Do may see uncommitted writes.
+type UnitOfWork interface {
+	Repositories() Repositories
+	Do(ctx context.Context, fn func(repos Repositories) error) error
+}
+
+type passthrough struct {
+	repos Repositories
+}
+
+// Passthrough runs fn directly on the given repositories without any
+// transaction, it is meant for tests with mocked repositories.
+func Passthrough(adStore ads.Store, userRepo user.Repository) UnitOfWork {
+	return passthrough{repos: NewRepositories(adStore, userRepo)}
+}
+
+func (p passthrough) Repositories() Repositories {
+	return p.repos
+}
+
+func (p passthrough) Do(ctx context.Context, fn func(repos Repositories) error) error {
+	return fn(p.repos)
+}
@@ -0,0 +1,89 @@
+package user
+
+import (
+	"errors"
+	"sort"
+	"time"
+)
+
+var (
+	ErrInvalidScope        = errors.New("unknown api key scope")
+	ErrInvalidAPIKeyParams = errors.New("invalid api key params")
+)
+
+const maxAPIKeyNameLen = 64
+
+// Scope is a part of the api an APIKey may call.
+type Scope string
+
+const (
+	ScopeAdsRead  Scope = "ads:read"
+	ScopeAdsWrite Scope = "ads:write"
+)
+
+func ParseScope(s string) (Scope, error) {
+	switch sc := Scope(s); sc {
+	case ScopeAdsRead, ScopeAdsWrite:
+		return sc, nil
+	}
+	return "", ErrInvalidScope
+}
+
+// APIKey lets a machine client act for the user within its scopes, only the
+// hash of the secret part of the key is stored.
+type APIKey struct {
+	ID     string
+	UserID int64
+	Name   string
+	Hash   string
+	Scopes []Scope
+	// CreatedAt and LastUsedAt are UTC, the zero ExpiresAt means the key
+	// doesn't expire and the zero LastUsedAt that it was never used.
+	CreatedAt  time.Time
+	ExpiresAt  time.Time
+	LastUsedAt time.Time
+}
+
+// ValidateAPIKey wants a name, at least one known scope and an expiry in
+// the future, if any.
+func ValidateAPIKey(k *APIKey, now time.Time) error {
+	if k.Name == "" || len(k.Name) > maxAPIKeyNameLen || len(k.Scopes) == 0 || k.Expired(now) {
+		return ErrInvalidAPIKeyParams
+	}
+	for _, s := range k.Scopes {
+		if _, err := ParseScope(string(s)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (k *APIKey) Expired(now time.Time) bool {
+	return !k.ExpiresAt.IsZero() && !now.Before(k.ExpiresAt)
+}
+
+func (k *APIKey) HasScope(s Scope) bool {
+	for _, granted := range k.Scopes {
+		if granted == s {
+			return true
+		}
+	}
+	return false
+}
+
+// CloneAPIKey copies the key together with its scopes.
+func CloneAPIKey(k *APIKey) *APIKey {
+	c := *k
+	c.Scopes = append([]Scope(nil), k.Scopes...)
+	return &c
+}
+
+// SortAPIKeys puts the oldest keys first.
+func SortAPIKeys(list []*APIKey) {
+	sort.Slice(list, func(i, j int) bool {
+		if !list[i].CreatedAt.Equal(list[j].CreatedAt) {
+			return list[i].CreatedAt.Before(list[j].CreatedAt)
+		}
+		return list[i].ID < list[j].ID
+	})
+}
@@ -0,0 +1,35 @@
+package user
+
+import (
+	"errors"
+	"net/mail"
+	"strings"
+)
+
+var (
+	ErrInvalidEmail = errors.New("invalid email")
+	ErrEmailTaken   = errors.New("the email is already taken")
+	ErrNotVerified  = errors.New("the email of the user is not verified")
+)
+
+// maxEmailLen is the longest address SMTP can deliver to, RFC 5321.
+const maxEmailLen = 254
+
+// ValidateEmail accepts a bare address like name@example.com, without a
+// display name or angle brackets.
+func ValidateEmail(email string) error {
+	if len(email) > maxEmailLen {
+		return ErrInvalidEmail
+	}
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email || addr.Name != "" {
+		return ErrInvalidEmail
+	}
+	return nil
+}
+
+// EmailKey is what keeps the emails unique regardless of case, an empty
+// email has no key.
+func EmailKey(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
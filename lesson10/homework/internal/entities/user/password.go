@@ -0,0 +1,16 @@
+package user
+
+import "errors"
+
+var (
+	ErrWrongPassword = errors.New("wrong password")
+)
+
+// PasswordHasher turns passwords into encoded hashes that carry their
+// algorithm and parameters, so User.Password never holds the password itself.
+type PasswordHasher interface {
+	Hash(password string) (string, error)
+	// Verify reports whether password matches encoded and whether encoded is
+	// outdated and should be replaced with a fresh Hash of the password.
+	Verify(encoded, password string) (ok, rehash bool, err error)
+}
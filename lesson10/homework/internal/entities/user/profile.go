@@ -0,0 +1,119 @@
+package user
+
+import (
+	"errors"
+	"unicode/utf8"
+)
+
+var (
+	ErrInvalidProfile    = errors.New("invalid profile params")
+	ErrInvalidVisibility = errors.New("unknown visibility, want public, users or private")
+)
+
+const (
+	maxDisplayNameLen = 50
+	maxBioLen         = 1000
+	maxCityLen        = 100
+	minPhoneDigits    = 5
+	maxPhoneDigits    = 15
+)
+
+// Anonymous is the viewer id of the clients that didn't sign in.
+const Anonymous int64 = -1
+
+// Visibility says who sees a field of the profile besides its owner and
+// the admins.
+type Visibility string
+
+const (
+	VisibilityPublic  Visibility = "public"
+	VisibilityUsers   Visibility = "users"
+	VisibilityPrivate Visibility = "private"
+)
+
+// ParseVisibility reads a visibility, the empty one is private.
+func ParseVisibility(s string) (Visibility, error) {
+	switch v := Visibility(s); v {
+	case "":
+		return VisibilityPrivate, nil
+	case VisibilityPublic, VisibilityUsers, VisibilityPrivate:
+		return v, nil
+	}
+	return "", ErrInvalidVisibility
+}
+
+// Viewer is who looks at a profile.
+type Viewer int
+
+const (
+	ViewerAnonymous Viewer = iota
+	ViewerUser
+	// ViewerOwner is the owner of the profile or an admin.
+	ViewerOwner
+)
+
+func (v Visibility) Allows(viewer Viewer) bool {
+	switch v {
+	case VisibilityPublic:
+		return true
+	case VisibilityUsers:
+		return viewer >= ViewerUser
+	}
+	return viewer == ViewerOwner
+}
+
+// Profile is what the user tells about themselves, the city and the phone
+// are shown as their visibility allows.
+type Profile struct {
+	DisplayName     string
+	Bio             string
+	City            string
+	Phone           string
+	CityVisibility  Visibility
+	PhoneVisibility Visibility
+}
+
+// ValidateProfile checks the fields and makes the empty visibilities
+// private.
+func ValidateProfile(p *Profile) error {
+	if utf8.RuneCountInString(p.DisplayName) > maxDisplayNameLen || utf8.RuneCountInString(p.Bio) > maxBioLen ||
+		utf8.RuneCountInString(p.City) > maxCityLen {
+		return ErrInvalidProfile
+	}
+	if p.Phone != "" && !isPhone(p.Phone) {
+		return ErrInvalidProfile
+	}
+	var err error
+	if p.CityVisibility, err = ParseVisibility(string(p.CityVisibility)); err != nil {
+		return err
+	}
+	p.PhoneVisibility, err = ParseVisibility(string(p.PhoneVisibility))
+	return err
+}
+
+func isPhone(phone string) bool {
+	digits := 0
+	for i, r := range phone {
+		switch {
+		case r >= '0' && r <= '9':
+			digits++
+		case r == '+' && i == 0:
+		case r == ' ', r == '-', r == '(', r == ')':
+		default:
+			return false
+		}
+	}
+	return digits >= minPhoneDigits && digits <= maxPhoneDigits
+}
+
+// VisibleTo returns the profile with the fields the viewer may not see
+// blanked out.
+func (p Profile) VisibleTo(viewer Viewer) Profile {
+	if !p.CityVisibility.Allows(viewer) {
+		p.City = ""
+	}
+	if !p.PhoneVisibility.Allows(viewer) {
+		p.Phone = ""
+	}
+	return p
+}
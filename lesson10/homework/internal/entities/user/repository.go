@@ -1,11 +1,34 @@
 package user
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
+// Repository keeps the emails unique by their EmailKey, CreateUser fails
+// with ErrEmailTaken on a duplicate.
 type Repository interface {
 	GetUser(ctx context.Context, id int64) (*User, error)
 	DeleteUser(ctx context.Context, id int64) error
 	CreateUser(ctx context.Context, user *User) (int64, error)
 	UpdateNick(ctx context.Context, id int64, nick string) (*User, error)
 	UpdatePassword(ctx context.Context, id int64, pass string) (*User, error)
+	UpdateRole(ctx context.Context, id int64, role Role) (*User, error)
+	UpdateVerified(ctx context.Context, id int64, verified bool) (*User, error)
+	UpdateProfile(ctx context.Context, id int64, profile Profile) (*User, error)
+	// RevokeSessions bumps the SessionVersion of the user.
+	RevokeSessions(ctx context.Context, id int64) (*User, error)
+	// GetUserByEmail finds the user by the EmailKey of their email.
+	GetUserByEmail(ctx context.Context, email string) (*User, error)
+	// SearchUsers returns a page of the users the search matches, see
+	// Search.Page.
+	SearchUsers(ctx context.Context, search Search) ([]*User, error)
+
+	// The api keys go away with their user.
+	AddAPIKey(ctx context.Context, key *APIKey) error
+	GetAPIKey(ctx context.Context, id string) (*APIKey, error)
+	// GetAPIKeys returns the keys of the user, the oldest first.
+	GetAPIKeys(ctx context.Context, userId int64) ([]*APIKey, error)
+	DeleteAPIKey(ctx context.Context, id string) error
+	TouchAPIKey(ctx context.Context, id string, at time.Time) error
 }
@@ -0,0 +1,63 @@
+package user
+
+import "errors"
+
+var (
+	ErrInvalidRole = errors.New("unknown role")
+	ErrForbidden   = errors.New("the user is not allowed to do this")
+)
+
+// Role decides what a user may do besides managing their own ads, the empty
+// role of the users stored before roles counts as RoleUser.
+type Role string
+
+const (
+	RoleUser      Role = "user"
+	RoleModerator Role = "moderator"
+	RoleAdmin     Role = "admin"
+)
+
+// Permission is something only some of the roles may do.
+type Permission int
+
+const (
+	// ModerateAds allows to unpublish and delete the ads of other users.
+	ModerateAds Permission = iota
+	// ManageRoles allows to change the roles of other users.
+	ManageRoles
+	// ManageUsers allows to change, delete, export and erase the accounts of
+	// other users.
+	ManageUsers
+	// ManageCategories allows to change the category tree of the ads.
+	ManageCategories
+)
+
+var permissions = map[Role][]Permission{
+	RoleModerator: {ModerateAds},
+	RoleAdmin:     {ModerateAds, ManageRoles, ManageUsers, ManageCategories},
+}
+
+func ParseRole(s string) (Role, error) {
+	switch r := Role(s); r {
+	case RoleUser, RoleModerator, RoleAdmin:
+		return r, nil
+	}
+	return "", ErrInvalidRole
+}
+
+func (r Role) Can(p Permission) bool {
+	for _, granted := range permissions[r] {
+		if granted == p {
+			return true
+		}
+	}
+	return false
+}
+
+// OrDefault returns RoleUser for the empty role.
+func (r Role) OrDefault() Role {
+	if r == "" {
+		return RoleUser
+	}
+	return r
+}
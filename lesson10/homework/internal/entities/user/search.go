@@ -0,0 +1,122 @@
+package user
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrInvalidSearch = errors.New("invalid user search params")
+)
+
+const (
+	DefaultSearchLimit = 20
+	MaxSearchLimit     = 100
+)
+
+// NicknameKey is what the users are searched and ordered by, the nickname
+// in any case.
+func NicknameKey(nickname string) string {
+	return strings.ToLower(nickname)
+}
+
+// Search finds the users whose nickname starts with Prefix, in any case,
+// ordered by NicknameKey and then by id.
+type Search struct {
+	Prefix string
+	Limit  int
+	Cursor string
+}
+
+// searchCursor points at the last user of the previous page.
+type searchCursor struct {
+	Key string `json:"k"`
+	ID  int64  `json:"i"`
+}
+
+// Normalize fills in the default limit and checks the search.
+func (s *Search) Normalize() error {
+	if s.Limit == 0 {
+		s.Limit = DefaultSearchLimit
+	}
+	if s.Limit < 0 || s.Limit > MaxSearchLimit {
+		return ErrInvalidSearch
+	}
+	_, _, err := s.After()
+	return err
+}
+
+// Matches reports whether the nickname starts with the prefix.
+func (s *Search) Matches(u *User) bool {
+	return strings.HasPrefix(NicknameKey(u.Nickname), NicknameKey(s.Prefix))
+}
+
+func searchLess(a, b *User) bool {
+	ka, kb := NicknameKey(a.Nickname), NicknameKey(b.Nickname)
+	if ka != kb {
+		return ka < kb
+	}
+	return a.Id < b.Id
+}
+
+// NextCursor returns the token of the page that follows the given user.
+func (s *Search) NextCursor(last *User) string {
+	data, _ := json.Marshal(searchCursor{Key: NicknameKey(last.Nickname), ID: last.Id})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// After decodes the cursor into the nickname key and the id the page
+// starts after, ok is false for the first page.
+func (s *Search) After() (after *User, ok bool, err error) {
+	if s.Cursor == "" {
+		return nil, false, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(s.Cursor)
+	if err != nil {
+		return nil, false, ErrInvalidSearch
+	}
+	var c searchCursor
+	if err = json.Unmarshal(data, &c); err != nil {
+		return nil, false, ErrInvalidSearch
+	}
+	return &User{Id: c.ID, Nickname: c.Key}, true, nil
+}
+
+// Page picks the matching users, sorts them and cuts the requested page out
+// of them, it is meant for repositories that keep users in memory.
+func (s *Search) Page(list []*User) ([]*User, error) {
+	after, ok, err := s.After()
+	if err != nil {
+		return nil, err
+	}
+	matching := make([]*User, 0)
+	for _, u := range list {
+		if s.Matches(u) && (!ok || searchLess(after, u)) {
+			matching = append(matching, u)
+		}
+	}
+	sort.Slice(matching, func(i, j int) bool {
+		return searchLess(matching[i], matching[j])
+	})
+	if s.Limit > 0 && len(matching) > s.Limit {
+		matching = matching[:s.Limit]
+	}
+	return matching, nil
+}
+
+// ParseSearchLimit turns the limit query parameter into Search.Limit, an
+// empty one means the default.
+func ParseSearchLimit(limit string) (int, error) {
+	if limit == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(limit)
+	if err != nil || n <= 0 || n > MaxSearchLimit {
+		return 0, ErrInvalidSearch
+	}
+	return n, nil
+}
@@ -3,6 +3,7 @@ package user
 import (
 	"errors"
 	"github.com/OkDenAl/validator"
+	"time"
 )
 
 var (
@@ -14,6 +15,16 @@ type User struct {
 	Nickname string
 	Email    string
 	Password string
+	Role     Role
+	// Verified is set once the user proved they own the email.
+	Verified bool
+	// SessionVersion is carried by the tokens issued to the user, bumping it
+	// ends all of their sessions.
+	SessionVersion int64
+	Profile        Profile
+	// CreatedAt is the UTC time the user registered, it is zero for the
+	// users registered before it was kept.
+	CreatedAt time.Time
 }
 
 type ValidatorUser struct {
@@ -0,0 +1,397 @@
+// Package usertest holds the tests every user.Repository has to pass,
+// storage backends run them from their own tests with RunRepositoryTests.
+package usertest
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"homework10/internal/adapters/userrepo"
+	"homework10/internal/entities/user"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Factory returns an empty repository, it is called once for every test.
+type Factory func(t *testing.T) user.Repository
+
+// RunRepositoryTests checks the contract of user.Repository against the
+// repositories made by newRepo, run it with -race.
+func RunRepositoryTests(t *testing.T, newRepo Factory) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, repo user.Repository)
+	}{
+		{"CreateAndGet", testCreateAndGet},
+		{"NotFound", testNotFound},
+		{"Update", testUpdate},
+		{"Delete", testDelete},
+		{"UniqueEmail", testUniqueEmail},
+		{"Profile", testProfile},
+		{"Search", testSearch},
+		{"APIKeys", testAPIKeys},
+		{"ConcurrentWriters", testConcurrentWriters},
+		{"CanceledContext", testCanceledContext},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			tc.run(t, newRepo(t))
+		})
+	}
+}
+
+func createUser(t *testing.T, repo user.Repository, nickname string) *user.User {
+	t.Helper()
+	u := &user.User{Nickname: nickname, Email: nickname + "@mail.com", Password: "password"}
+	_, err := repo.CreateUser(context.Background(), u)
+	assert.NoError(t, err)
+	return u
+}
+
+func testCreateAndGet(t *testing.T, repo user.Repository) {
+	ctx := context.Background()
+	u := &user.User{Nickname: "nickname", Email: "email@mail.com", Password: "password"}
+	id, err := repo.CreateUser(ctx, u)
+	assert.NoError(t, err)
+	assert.Equal(t, id, u.Id)
+	other := createUser(t, repo, "other")
+	assert.NotEqual(t, id, other.Id)
+
+	got, err := repo.GetUser(ctx, id)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, u, got)
+
+	// the stored user is not shared with the callers
+	u.Nickname = "changed by the caller"
+	got.Email = "changed by the caller"
+	got, err = repo.GetUser(ctx, id)
+	assert.NoError(t, err)
+	assert.Equal(t, "nickname", got.Nickname)
+	assert.Equal(t, "email@mail.com", got.Email)
+}
+
+func testNotFound(t *testing.T, repo user.Repository) {
+	ctx := context.Background()
+	u := createUser(t, repo, "nickname")
+	missing := u.Id + 1000
+
+	_, err := repo.GetUser(ctx, missing)
+	assert.ErrorIs(t, err, userrepo.ErrInvalidUserId)
+	_, err = repo.UpdateNick(ctx, missing, "nick")
+	assert.ErrorIs(t, err, userrepo.ErrInvalidUserId)
+	_, err = repo.UpdatePassword(ctx, missing, "password")
+	assert.ErrorIs(t, err, userrepo.ErrInvalidUserId)
+	_, err = repo.UpdateRole(ctx, missing, user.RoleAdmin)
+	assert.ErrorIs(t, err, userrepo.ErrInvalidUserId)
+	_, err = repo.UpdateVerified(ctx, missing, true)
+	assert.ErrorIs(t, err, userrepo.ErrInvalidUserId)
+	_, err = repo.RevokeSessions(ctx, missing)
+	assert.ErrorIs(t, err, userrepo.ErrInvalidUserId)
+	_, err = repo.UpdateProfile(ctx, missing, user.Profile{DisplayName: "name"})
+	assert.ErrorIs(t, err, userrepo.ErrInvalidUserId)
+	_, err = repo.GetUserByEmail(ctx, "missing@mail.com")
+	assert.ErrorIs(t, err, userrepo.ErrInvalidUserId)
+	_, err = repo.GetUserByEmail(ctx, "")
+	assert.ErrorIs(t, err, userrepo.ErrInvalidUserId)
+	_, err = repo.GetAPIKey(ctx, "missing")
+	assert.ErrorIs(t, err, userrepo.ErrInvalidAPIKeyId)
+	assert.ErrorIs(t, repo.TouchAPIKey(ctx, "missing", time.Now()), userrepo.ErrInvalidAPIKeyId)
+	assert.NoError(t, repo.DeleteAPIKey(ctx, "missing"))
+	assert.NoError(t, repo.DeleteUser(ctx, missing))
+}
+
+func testUpdate(t *testing.T, repo user.Repository) {
+	ctx := context.Background()
+	u := createUser(t, repo, "nickname")
+
+	updated, err := repo.UpdateNick(ctx, u.Id, "new nick")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "new nick", updated.Nickname)
+	assert.Equal(t, u.Password, updated.Password)
+
+	updated, err = repo.UpdatePassword(ctx, u.Id, "new password")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "new nick", updated.Nickname)
+	assert.Equal(t, "new password", updated.Password)
+
+	updated, err = repo.UpdateRole(ctx, u.Id, user.RoleModerator)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, user.RoleModerator, updated.Role)
+	assert.Equal(t, "new password", updated.Password)
+
+	updated, err = repo.UpdateVerified(ctx, u.Id, true)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.True(t, updated.Verified)
+	assert.Equal(t, user.RoleModerator, updated.Role)
+
+	for version := int64(1); version <= 2; version++ {
+		updated, err = repo.RevokeSessions(ctx, u.Id)
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, version, updated.SessionVersion)
+		assert.True(t, updated.Verified)
+	}
+
+	got, err := repo.GetUser(ctx, u.Id)
+	assert.NoError(t, err)
+	assert.Equal(t, updated, got)
+}
+
+func testProfile(t *testing.T, repo user.Repository) {
+	ctx := context.Background()
+	u := &user.User{Nickname: "nickname", Email: "nickname@mail.com", Password: "password",
+		Profile:   user.Profile{DisplayName: "Nick", CityVisibility: user.VisibilityPublic},
+		CreatedAt: time.Date(2023, 4, 28, 12, 0, 0, 1, time.UTC)}
+	_, err := repo.CreateUser(ctx, u)
+	assert.NoError(t, err)
+	got, err := repo.GetUser(ctx, u.Id)
+	assert.NoError(t, err)
+	assert.Equal(t, u, got)
+
+	profile := user.Profile{DisplayName: "Nick Name", Bio: "sells bikes", City: "Moscow", Phone: "+7 900 000-00-00",
+		CityVisibility: user.VisibilityPublic, PhoneVisibility: user.VisibilityUsers}
+	updated, err := repo.UpdateProfile(ctx, u.Id, profile)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, profile, updated.Profile)
+	assert.Equal(t, u.CreatedAt, updated.CreatedAt)
+	assert.Equal(t, u.Password, updated.Password)
+
+	got, err = repo.GetUser(ctx, u.Id)
+	assert.NoError(t, err)
+	assert.Equal(t, updated, got)
+}
+
+func nicknames(list []*user.User) []string {
+	resp := make([]string, len(list))
+	for i, u := range list {
+		resp[i] = u.Nickname
+	}
+	return resp
+}
+
+func testSearch(t *testing.T, repo user.Repository) {
+	ctx := context.Background()
+	for _, nick := range []string{"bob", "Alice", "alex", "ALBERT", "al_pacino", "Ålice"} {
+		createUser(t, repo, nick)
+	}
+	renamed := createUser(t, repo, "zed")
+	_, err := repo.UpdateNick(ctx, renamed.Id, "Alfred")
+	assert.NoError(t, err)
+
+	list, err := repo.SearchUsers(ctx, user.Search{Prefix: "AL"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"al_pacino", "ALBERT", "alex", "Alfred", "Alice"}, nicknames(list))
+	// the underscore is not a wildcard
+	list, err = repo.SearchUsers(ctx, user.Search{Prefix: "al_"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"al_pacino"}, nicknames(list))
+	list, err = repo.SearchUsers(ctx, user.Search{Prefix: "å"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Ålice"}, nicknames(list))
+	list, err = repo.SearchUsers(ctx, user.Search{Prefix: "nobody"})
+	assert.NoError(t, err)
+	assert.Empty(t, list)
+
+	var pages []string
+	search := user.Search{Prefix: "al", Limit: 2}
+	for {
+		page, err := repo.SearchUsers(ctx, search)
+		if !assert.NoError(t, err) || len(page) == 0 {
+			break
+		}
+		pages = append(pages, nicknames(page)...)
+		search.Cursor = search.NextCursor(page[len(page)-1])
+	}
+	assert.Equal(t, []string{"al_pacino", "ALBERT", "alex", "Alfred", "Alice"}, pages)
+
+	_, err = repo.SearchUsers(ctx, user.Search{Prefix: "al", Cursor: "not a cursor"})
+	assert.ErrorIs(t, err, user.ErrInvalidSearch)
+}
+
+func testDelete(t *testing.T, repo user.Repository) {
+	ctx := context.Background()
+	first := createUser(t, repo, "first")
+	second := createUser(t, repo, "second")
+
+	assert.NoError(t, repo.DeleteUser(ctx, second.Id))
+	_, err := repo.GetUser(ctx, second.Id)
+	assert.ErrorIs(t, err, userrepo.ErrInvalidUserId)
+	_, err = repo.GetUser(ctx, first.Id)
+	assert.NoError(t, err)
+
+	// ids are not handed out again, not even the highest one
+	third := createUser(t, repo, "third")
+	assert.NotContains(t, []int64{first.Id, second.Id}, third.Id)
+}
+
+func testUniqueEmail(t *testing.T, repo user.Repository) {
+	ctx := context.Background()
+	first := &user.User{Nickname: "first", Email: "Tester@Mail.com", Verified: true}
+	_, err := repo.CreateUser(ctx, first)
+	assert.NoError(t, err)
+
+	_, err = repo.CreateUser(ctx, &user.User{Nickname: "second", Email: "tester@mail.COM"})
+	assert.ErrorIs(t, err, user.ErrEmailTaken)
+	got, err := repo.GetUser(ctx, first.Id)
+	assert.NoError(t, err)
+	assert.Equal(t, first, got)
+	got, err = repo.GetUserByEmail(ctx, " TESTER@mail.com")
+	assert.NoError(t, err)
+	assert.Equal(t, first, got)
+
+	// the users from before the emails were checked may have none
+	for i := 0; i < 2; i++ {
+		_, err = repo.CreateUser(ctx, &user.User{Nickname: "no email"})
+		assert.NoError(t, err)
+	}
+
+	// a deleted user gives their email back
+	assert.NoError(t, repo.DeleteUser(ctx, first.Id))
+	_, err = repo.CreateUser(ctx, &user.User{Nickname: "second", Email: "tester@mail.com"})
+	assert.NoError(t, err)
+}
+
+func testAPIKeys(t *testing.T, repo user.Repository) {
+	ctx := context.Background()
+	owner := createUser(t, repo, "owner")
+	other := createUser(t, repo, "other")
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	newer := &user.APIKey{ID: "newer", UserID: owner.Id, Name: "importer", Hash: "hash1",
+		Scopes: []user.Scope{user.ScopeAdsRead}, CreatedAt: created.Add(time.Hour)}
+	older := &user.APIKey{ID: "older", UserID: owner.Id, Name: "partner", Hash: "hash2",
+		Scopes: []user.Scope{user.ScopeAdsRead, user.ScopeAdsWrite}, CreatedAt: created,
+		ExpiresAt: created.Add(24 * time.Hour)}
+	foreign := &user.APIKey{ID: "foreign", UserID: other.Id, Name: "other", Hash: "hash3",
+		Scopes: []user.Scope{user.ScopeAdsWrite}, CreatedAt: created}
+	for _, key := range []*user.APIKey{newer, older, foreign} {
+		assert.NoError(t, repo.AddAPIKey(ctx, key))
+	}
+
+	got, err := repo.GetAPIKey(ctx, "older")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, older, got)
+	// the stored key is not shared with the callers
+	got.Scopes[0] = "changed by the caller"
+	got, err = repo.GetAPIKey(ctx, "older")
+	assert.NoError(t, err)
+	assert.Equal(t, user.ScopeAdsRead, got.Scopes[0])
+
+	list, err := repo.GetAPIKeys(ctx, owner.Id)
+	assert.NoError(t, err)
+	assert.Equal(t, []*user.APIKey{older, newer}, list)
+
+	used := created.Add(2 * time.Hour)
+	assert.NoError(t, repo.TouchAPIKey(ctx, "newer", used))
+	got, err = repo.GetAPIKey(ctx, "newer")
+	assert.NoError(t, err)
+	assert.True(t, used.Equal(got.LastUsedAt))
+
+	assert.NoError(t, repo.DeleteAPIKey(ctx, "newer"))
+	_, err = repo.GetAPIKey(ctx, "newer")
+	assert.ErrorIs(t, err, userrepo.ErrInvalidAPIKeyId)
+
+	// the keys go away with their user
+	assert.NoError(t, repo.DeleteUser(ctx, owner.Id))
+	_, err = repo.GetAPIKey(ctx, "older")
+	assert.ErrorIs(t, err, userrepo.ErrInvalidAPIKeyId)
+	list, err = repo.GetAPIKeys(ctx, owner.Id)
+	assert.NoError(t, err)
+	assert.Empty(t, list)
+	_, err = repo.GetAPIKey(ctx, "foreign")
+	assert.NoError(t, err)
+}
+
+func testConcurrentWriters(t *testing.T, repo user.Repository) {
+	const workers, perWorker = 8, 20
+	ctx := context.Background()
+	shared := createUser(t, repo, "shared")
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		created = make(map[int64]struct{})
+	)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			for j := 0; j < perWorker; j++ {
+				id, err := repo.CreateUser(ctx, &user.User{Nickname: fmt.Sprintf("user %d %d", worker, j)})
+				assert.NoError(t, err)
+				mu.Lock()
+				created[id] = struct{}{}
+				mu.Unlock()
+
+				_, err = repo.UpdateNick(ctx, shared.Id, fmt.Sprintf("shared %d %d", worker, j))
+				assert.NoError(t, err)
+				_, err = repo.GetUser(ctx, shared.Id)
+				assert.NoError(t, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Len(t, created, workers*perWorker)
+	for id := range created {
+		_, err := repo.GetUser(ctx, id)
+		assert.NoError(t, err)
+	}
+}
+
+func testCanceledContext(t *testing.T, repo user.Repository) {
+	u := createUser(t, repo, "nickname")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := repo.CreateUser(ctx, &user.User{Nickname: "canceled"})
+	assert.ErrorIs(t, err, context.Canceled)
+	_, err = repo.GetUser(ctx, u.Id)
+	assert.ErrorIs(t, err, context.Canceled)
+	_, err = repo.UpdateNick(ctx, u.Id, "canceled")
+	assert.ErrorIs(t, err, context.Canceled)
+	_, err = repo.UpdatePassword(ctx, u.Id, "canceled")
+	assert.ErrorIs(t, err, context.Canceled)
+	_, err = repo.UpdateRole(ctx, u.Id, user.RoleAdmin)
+	assert.ErrorIs(t, err, context.Canceled)
+	_, err = repo.UpdateVerified(ctx, u.Id, true)
+	assert.ErrorIs(t, err, context.Canceled)
+	_, err = repo.RevokeSessions(ctx, u.Id)
+	assert.ErrorIs(t, err, context.Canceled)
+	_, err = repo.UpdateProfile(ctx, u.Id, user.Profile{DisplayName: "canceled"})
+	assert.ErrorIs(t, err, context.Canceled)
+	_, err = repo.GetUserByEmail(ctx, u.Email)
+	assert.ErrorIs(t, err, context.Canceled)
+	_, err = repo.SearchUsers(ctx, user.Search{Prefix: "nick"})
+	assert.ErrorIs(t, err, context.Canceled)
+	key := &user.APIKey{ID: "canceled", UserID: u.Id, Name: "canceled", Scopes: []user.Scope{user.ScopeAdsRead}}
+	assert.ErrorIs(t, repo.AddAPIKey(ctx, key), context.Canceled)
+	_, err = repo.GetAPIKey(ctx, key.ID)
+	assert.ErrorIs(t, err, context.Canceled)
+	_, err = repo.GetAPIKeys(ctx, u.Id)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.ErrorIs(t, repo.TouchAPIKey(ctx, key.ID, time.Now()), context.Canceled)
+	assert.ErrorIs(t, repo.DeleteAPIKey(ctx, key.ID), context.Canceled)
+	assert.ErrorIs(t, repo.DeleteUser(ctx, u.Id), context.Canceled)
+
+	// nothing has changed
+	got, err := repo.GetUser(context.Background(), u.Id)
+	assert.NoError(t, err)
+	assert.Equal(t, u, got)
+}
@@ -4,230 +4,500 @@ import (
 	"context"
 	"errors"
 	"github.com/golang/protobuf/ptypes/empty"
-	"homework10/internal/adapters/userrepo"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"homework10/internal/adapters/adrepo"
+	"homework10/internal/app/adsapp"
+	"homework10/internal/app/authapp"
 	"homework10/internal/entities/ads"
 	"homework10/internal/entities/user"
 	"homework10/internal/ports/grpc/base"
+	"io"
+	"strings"
 	"time"
 )
 
-var (
-	ErrUnableToDelete = errors.New("unable to delete an ad created by another user")
-)
-
 type AdService struct {
-	repo     ads.Repository
-	userRepo user.Repository
+	app adsapp.App
 	base.UnimplementedAdServiceServer
 }
 
-func NewAdService(repo ads.Repository, userRepo user.Repository) *AdService {
+func NewAdService(a adsapp.App) *AdService {
 	return &AdService{
-		repo:     repo,
-		userRepo: userRepo,
+		app: a,
+	}
+}
+
+// permissionError turns the refusals of the permission checks into statuses
+// the way the REST port does, other errors are returned as they are.
+func permissionError(err error) error {
+	switch {
+	case errors.Is(err, ads.ErrUserCantChangeThisAd), errors.Is(err, adsapp.ErrUnableToDelete),
+		errors.Is(err, user.ErrForbidden), errors.Is(err, user.ErrNotVerified):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, ads.ErrReasonRequired), errors.Is(err, user.ErrInvalidRole):
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	return err
+}
+
+// actingUser returns the user the call is made on behalf of, the auth
+// interceptor takes them from the access token.
+func actingUser(ctx context.Context) (int64, error) {
+	principal, ok := authapp.PrincipalFrom(ctx)
+	if !ok {
+		return 0, status.Error(codes.Unauthenticated, authapp.ErrInvalidToken.Error())
+	}
+	return principal.UserID, nil
+}
+
+func adToResponse(ad *ads.Ad) *base.AdResponse {
+	resp := &base.AdResponse{
+		Id:              ad.ID,
+		Title:           ad.Title,
+		Text:            ad.Text,
+		AuthorId:        ad.AuthorID,
+		CategoryId:      ad.CategoryID,
+		Published:       ad.Published(),
+		State:           stateToProto(ad.State),
+		RejectionReason: ad.RejectionReason,
+		CreationDate:    ad.CreationDate,
+		UpdateDate:      ad.UpdateDate,
+		Version:         ad.Version,
+	}
+	if ad.Trashed() {
+		resp.DeletedAt = ad.DeletedAt.Format(time.RFC3339)
+	}
+	if !ad.PublishAt.IsZero() {
+		resp.PublishAt = ad.PublishAt.Format(time.RFC3339)
+	}
+	if !ad.ExpiresAt.IsZero() {
+		resp.ExpiresAt = ad.ExpiresAt.Format(time.RFC3339)
+	}
+	if !ad.Price.IsZero() {
+		resp.Price = &base.Money{Amount: ad.Price.Amount, Currency: ad.Price.Currency}
+	}
+	return resp
+}
+
+// stateToProto gives AD_STATE_UNSPECIFIED for the states the enum lacks.
+func stateToProto(state ads.State) base.AdState {
+	return base.AdState(base.AdState_value["AD_STATE_"+strings.ToUpper(string(state))])
+}
+
+// stateFromProto gives the empty state for AD_STATE_UNSPECIFIED, and an
+// invalid one for the values it doesn't know.
+func stateFromProto(state base.AdState) ads.State {
+	if state == base.AdState_AD_STATE_UNSPECIFIED {
+		return ""
 	}
+	return ads.State(strings.ToLower(strings.TrimPrefix(state.String(), "AD_STATE_")))
+}
+
+func adsToResponse(adsArr []*ads.Ad) *base.ListAdResponse {
+	response := make([]*base.AdResponse, len(adsArr))
+	for i, ad := range adsArr {
+		response[i] = adToResponse(ad)
+	}
+	return &base.ListAdResponse{List: response}
+}
+
+func revisionToResponse(rev *ads.Revision) *base.RevisionResponse {
+	changes := make([]*base.Change, len(rev.Changes))
+	for i, change := range rev.Changes {
+		changes[i] = &base.Change{Field: change.Field, Old: change.Old, New: change.New}
+	}
+	return &base.RevisionResponse{
+		AdId:      rev.AdID,
+		Number:    rev.Number,
+		ActorId:   rev.ActorID,
+		Reason:    rev.Reason,
+		CreatedAt: rev.CreatedAt.Format(time.RFC3339Nano),
+		Title:     rev.Title,
+		Text:      rev.Text,
+		Published: rev.State == ads.StatePublished,
+		State:     stateToProto(rev.State),
+		Changes:   changes,
+	}
+}
+
+func revisionsToResponse(revisions []*ads.Revision) *base.ListAdRevisionsResponse {
+	response := make([]*base.RevisionResponse, len(revisions))
+	for i, rev := range revisions {
+		response[i] = revisionToResponse(rev)
+	}
+	return &base.ListAdRevisionsResponse{List: response}
 }
 
 func (a *AdService) CreateAd(ctx context.Context, req *base.CreateAdRequest) (*base.AdResponse, error) {
-	_, err := a.userRepo.GetUser(ctx, req.UserId)
+	userId, err := actingUser(ctx)
 	if err != nil {
 		return nil, err
 	}
-	t := time.Now().UTC()
-	ad := &ads.Ad{
-		Title:        req.Title,
-		Text:         req.Text,
-		AuthorID:     req.UserId,
-		Published:    false,
-		CreationDate: t.Format(time.DateOnly),
+	price := ads.Money{Amount: req.GetPrice().GetAmount(), Currency: req.GetPrice().GetCurrency()}
+	ad, err := a.app.CreateAd(ctx, req.Title, req.Text, price, req.CategoryId, userId)
+	if errors.Is(err, ads.ErrUnknownCategory) || errors.Is(err, ads.ErrInvalidPrice) {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
 	}
-	err = ads.ValidateAd(ad)
 	if err != nil {
-		return nil, ads.ErrInvalidAdParams
+		return nil, err
 	}
-	id, err := a.repo.AddAd(ctx, ad)
+	return adToResponse(ad), nil
+}
+
+func (a *AdService) ChangeAdStatus(ctx context.Context, req *base.ChangeAdStatusRequest) (*base.AdResponse, error) {
+	userId, err := actingUser(ctx)
 	if err != nil {
 		return nil, err
 	}
-	ad.ID = id
-	return &base.AdResponse{
-		Id:           ad.ID,
-		Title:        ad.Title,
-		Text:         ad.Text,
-		AuthorId:     ad.AuthorID,
-		Published:    ad.Published,
-		CreationDate: ad.CreationDate,
-		UpdateDate:   ad.UpdateDate,
-	}, nil
+	ad, err := a.app.ChangeAdStatus(ctx, req.AdId, userId, req.Published)
+	if err != nil {
+		return nil, stateError(err)
+	}
+	return adToResponse(ad), nil
 }
 
-func (a *AdService) ChangeAdStatus(ctx context.Context, req *base.ChangeAdStatusRequest) (*base.AdResponse, error) {
-	_, err := a.userRepo.GetUser(ctx, req.UserId)
+func stateError(err error) error {
+	switch {
+	case errors.Is(err, ads.ErrInvalidState):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, ads.ErrIllegalTransition):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, adrepo.ErrInvalidAdId):
+		return status.Error(codes.NotFound, err.Error())
+	}
+	return permissionError(err)
+}
+
+func (a *AdService) ChangeAdState(ctx context.Context, req *base.ChangeAdStateRequest) (*base.AdResponse, error) {
+	userId, err := actingUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ad, err := a.app.ChangeAdState(ctx, req.AdId, userId, stateFromProto(req.State), req.Reason)
+	if err != nil {
+		return nil, stateError(err)
+	}
+	return adToResponse(ad), nil
+}
+
+func (a *AdService) UnpublishAd(ctx context.Context, req *base.UnpublishAdRequest) (*base.AdResponse, error) {
+	userId, err := actingUser(ctx)
 	if err != nil {
 		return nil, err
 	}
-	ad, err := a.repo.GetAdById(ctx, req.AdId)
+	ad, err := a.app.UnpublishAd(ctx, req.AdId, userId, req.Reason)
+	if err != nil {
+		return nil, stateError(err)
+	}
+	return adToResponse(ad), nil
+}
+
+func (a *AdService) UpdateAd(ctx context.Context, req *base.UpdateAdRequest) (*base.AdResponse, error) {
+	userId, err := actingUser(ctx)
 	if err != nil {
 		return nil, err
 	}
-	if ad.AuthorID != req.UserId {
-		return nil, ads.ErrUserCantChangeThisAd
+	ad, err := a.app.UpdateAd(ctx, req.AdId, userId, req.Title, req.Text, req.ExpectedVersion)
+	if errors.Is(err, ads.ErrVersionMismatch) {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
 	}
-	if ad.Published == req.Published {
-		return &base.AdResponse{
-			Id:           ad.ID,
-			Title:        ad.Title,
-			Text:         ad.Text,
-			AuthorId:     ad.AuthorID,
-			Published:    ad.Published,
-			CreationDate: ad.CreationDate,
-			UpdateDate:   ad.UpdateDate,
-		}, nil
+	if err != nil {
+		return nil, permissionError(err)
 	}
+	return adToResponse(ad), nil
+}
 
-	t := time.Now().UTC()
-	ad.UpdateDate = t.Format(time.DateOnly)
+func (a *AdService) GetAdById(ctx context.Context, req *base.GetAdByIdRequest) (*base.AdResponse, error) {
+	ad, err := a.app.GetAdById(ctx, req.AdId)
+	if err != nil {
+		return nil, err
+	}
+	return adToResponse(ad), nil
+}
 
-	ad, err = a.repo.UpdateAdStatus(ctx, req.AdId, req.Published)
+func (a *AdService) GetAdByTitle(ctx context.Context, req *base.GetAdByTitleRequest) (*base.ListAdResponse, error) {
+	adsArr, err := a.app.GetAdsByTitle(ctx, req.Title)
 	if err != nil {
 		return nil, err
 	}
-	return &base.AdResponse{
-		Id:           ad.ID,
-		Title:        ad.Title,
-		Text:         ad.Text,
-		AuthorId:     ad.AuthorID,
-		Published:    ad.Published,
-		CreationDate: ad.CreationDate,
-		UpdateDate:   ad.UpdateDate,
-	}, nil
+	return adsToResponse(adsArr), nil
 }
 
-func (a *AdService) UpdateAd(ctx context.Context, req *base.UpdateAdRequest) (*base.AdResponse, error) {
-	err := ads.ValidateAd(&ads.Ad{Title: req.Title, Text: req.Text})
+func (a *AdService) ListAds(ctx context.Context, f *base.Filters) (*base.ListAdResponse, error) {
+	if f.State != base.AdState_AD_STATE_UNSPECIFIED {
+		f.Status = string(stateFromProto(f.State))
+	}
+	if f.Status == "" {
+		f.Status = string(ads.Published)
+	}
+	desc, err := ads.ParseOrder(f.Order)
 	if err != nil {
-		return nil, ads.ErrInvalidAdParams
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	filters := ads.Filters{
+		Status:     ads.Status(f.Status),
+		Date:       f.Date,
+		AuthorId:   f.AuthorId,
+		CategoryId: f.CategoryId,
+		PriceMin:   f.PriceMin,
+		PriceMax:   f.PriceMax,
+		Currency:   f.Currency,
+		Sort:       ads.SortField(f.Sort),
+		Desc:       desc,
+		Limit:      int(f.Limit),
+		Cursor:     f.PageToken,
+	}
+	adsArr, nextPageToken, err := a.app.GetAll(ctx, filters)
+	if errors.Is(err, ads.ErrInvalidFilters) {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
 	}
-	_, err = a.userRepo.GetUser(ctx, req.UserId)
 	if err != nil {
 		return nil, err
 	}
-	ad, err := a.repo.GetAdById(ctx, req.AdId)
+	resp := adsToResponse(adsArr)
+	resp.NextPageToken = nextPageToken
+	return resp, nil
+}
+
+func (a *AdService) SearchAds(ctx context.Context, req *base.SearchAdsRequest) (*base.ListAdResponse, error) {
+	adsArr, err := a.app.SearchAds(ctx, req.Query)
+	if errors.Is(err, ads.ErrEmptySearchQuery) {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
 	if err != nil {
 		return nil, err
 	}
-	if ad.AuthorID != req.UserId {
-		return nil, ads.ErrUserCantChangeThisAd
+	return adsToResponse(adsArr), nil
+}
+
+func (a *AdService) DeleteAd(ctx context.Context, req *base.DeleteAdRequest) (*empty.Empty, error) {
+	userId, err := actingUser(ctx)
+	if err != nil {
+		return nil, err
 	}
-	if ad.Text == req.Text && ad.Title == req.Title {
-		return &base.AdResponse{
-			Id:           ad.ID,
-			Title:        ad.Title,
-			Text:         ad.Text,
-			AuthorId:     ad.AuthorID,
-			Published:    ad.Published,
-			CreationDate: ad.CreationDate,
-			UpdateDate:   ad.UpdateDate,
-		}, nil
+	err = a.app.DeleteAd(ctx, req.AdId, userId, req.Reason)
+	if err != nil {
+		return nil, permissionError(err)
 	}
+	return &empty.Empty{}, nil
+}
+
+func (a *AdService) RestoreAd(ctx context.Context, req *base.RestoreAdRequest) (*base.AdResponse, error) {
+	userId, err := actingUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ad, err := a.app.RestoreAd(ctx, req.AdId, userId)
+	if err != nil {
+		return nil, err
+	}
+	return adToResponse(ad), nil
+}
+
+// parseTime reads an RFC 3339 time, the empty string is the zero time.
+func parseTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return t, nil
+}
 
-	t := time.Now().UTC()
-	ad.UpdateDate = t.Format(time.DateOnly)
+func scheduleError(err error) error {
+	switch {
+	case errors.Is(err, ads.ErrInvalidSchedule):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, ads.ErrCantRenew), errors.Is(err, ads.ErrIllegalTransition):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, adrepo.ErrInvalidAdId):
+		return status.Error(codes.NotFound, err.Error())
+	}
+	return permissionError(err)
+}
 
-	ad, err = a.repo.UpdateAdTitleAndText(ctx, req.AdId, req.Title, req.Text)
+func (a *AdService) ScheduleAd(ctx context.Context, req *base.ScheduleAdRequest) (*base.AdResponse, error) {
+	userId, err := actingUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	publishAt, err := parseTime(req.PublishAt)
+	if err != nil {
+		return nil, err
+	}
+	expiresAt, err := parseTime(req.ExpiresAt)
 	if err != nil {
 		return nil, err
 	}
-	return &base.AdResponse{
-		Id:           ad.ID,
-		Title:        ad.Title,
-		Text:         ad.Text,
-		AuthorId:     ad.AuthorID,
-		Published:    ad.Published,
-		CreationDate: ad.CreationDate,
-		UpdateDate:   ad.UpdateDate,
-	}, nil
+	ad, err := a.app.ScheduleAd(ctx, req.AdId, userId, publishAt, expiresAt)
+	if err != nil {
+		return nil, scheduleError(err)
+	}
+	return adToResponse(ad), nil
 }
 
-func (a *AdService) GetAdById(ctx context.Context, req *base.GetAdByIdRequest) (*base.AdResponse, error) {
-	ad, err := a.repo.GetAdById(ctx, req.AdId)
+func (a *AdService) RenewAd(ctx context.Context, req *base.RenewAdRequest) (*base.AdResponse, error) {
+	userId, err := actingUser(ctx)
 	if err != nil {
 		return nil, err
 	}
-	return &base.AdResponse{
-		Id:           ad.ID,
-		Title:        ad.Title,
-		Text:         ad.Text,
-		AuthorId:     ad.AuthorID,
-		Published:    ad.Published,
-		CreationDate: ad.CreationDate,
-		UpdateDate:   ad.UpdateDate,
-	}, nil
+	ad, err := a.app.RenewAd(ctx, req.AdId, userId)
+	if err != nil {
+		return nil, scheduleError(err)
+	}
+	return adToResponse(ad), nil
 }
 
-func (a *AdService) GetAdByTitle(ctx context.Context, req *base.GetAdByTitleRequest) (*base.ListAdResponse, error) {
-	adsArr, err := a.repo.GetAdsByTitle(ctx, req.Title)
+func (a *AdService) ListTrash(ctx context.Context, req *base.ListTrashRequest) (*base.ListAdResponse, error) {
+	userId, err := actingUser(ctx)
 	if err != nil {
 		return nil, err
 	}
-	response := make([]*base.AdResponse, len(adsArr))
-	for i, ad := range adsArr {
-		response[i] = &base.AdResponse{
-			Id:           ad.ID,
-			Title:        ad.Title,
-			Text:         ad.Text,
-			AuthorId:     ad.AuthorID,
-			Published:    ad.Published,
-			CreationDate: ad.CreationDate,
-			UpdateDate:   ad.UpdateDate,
-		}
+	adsArr, err := a.app.GetTrash(ctx, userId)
+	if err != nil {
+		return nil, err
 	}
-	return &base.ListAdResponse{List: response}, nil
+	return adsToResponse(adsArr), nil
 }
 
-func (a *AdService) ListAds(ctx context.Context, f *base.Filters) (*base.ListAdResponse, error) {
-	if f.Status == "" {
-		f.Status = string(ads.Published)
+func (a *AdService) ListAdRevisions(ctx context.Context, req *base.ListAdRevisionsRequest) (*base.ListAdRevisionsResponse, error) {
+	revisions, err := a.app.GetRevisions(ctx, req.AdId)
+	if err != nil {
+		return nil, err
 	}
-	filters := ads.Filters{
-		Status:   ads.Status(f.Status),
-		Date:     f.Date,
-		AuthorId: f.AuthorId,
+	return revisionsToResponse(revisions), nil
+}
+
+func (a *AdService) GetAdRevision(ctx context.Context, req *base.GetAdRevisionRequest) (*base.AdResponse, error) {
+	ad, err := a.app.GetAdAtRevision(ctx, req.AdId, req.Revision)
+	if errors.Is(err, ads.ErrInvalidRevision) {
+		return nil, status.Error(codes.NotFound, err.Error())
 	}
-	err := filters.ValidateFilters()
 	if err != nil {
 		return nil, err
 	}
-	adsArr, err := a.repo.GetAll(ctx, filters)
+	return adToResponse(ad), nil
+}
+
+func (a *AdService) RevertAd(ctx context.Context, req *base.RevertAdRequest) (*base.AdResponse, error) {
+	userId, err := actingUser(ctx)
 	if err != nil {
 		return nil, err
 	}
-	response := make([]*base.AdResponse, len(adsArr))
-	for i, ad := range adsArr {
-		response[i] = &base.AdResponse{
-			Id:           ad.ID,
-			Title:        ad.Title,
-			Text:         ad.Text,
-			AuthorId:     ad.AuthorID,
-			Published:    ad.Published,
-			CreationDate: ad.CreationDate,
-			UpdateDate:   ad.UpdateDate,
+	ad, err := a.app.RevertAd(ctx, req.AdId, userId, req.Revision)
+	if errors.Is(err, ads.ErrInvalidRevision) {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	if err != nil {
+		return nil, stateError(err)
+	}
+	return adToResponse(ad), nil
+}
+
+func imageToResponse(img *ads.Image) *base.ImageResponse {
+	return &base.ImageResponse{
+		Id:          img.ID,
+		AdId:        img.AdID,
+		Position:    int32(img.Position),
+		ContentType: img.ContentType,
+		Size:        img.Size,
+		Width:       int32(img.Width),
+		Height:      int32(img.Height),
+		CreatedAt:   img.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+func imagesToResponse(list []*ads.Image) *base.ListImagesResponse {
+	resp := make([]*base.ImageResponse, len(list))
+	for i, img := range list {
+		resp[i] = imageToResponse(img)
+	}
+	return &base.ListImagesResponse{List: resp}
+}
+
+func imageError(err error) error {
+	switch {
+	case errors.Is(err, ads.ErrInvalidImage), errors.Is(err, ads.ErrInvalidImageOrder):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, ads.ErrImageTooLarge):
+		return status.Error(codes.ResourceExhausted, err.Error())
+	case errors.Is(err, ads.ErrTooManyImages):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, adrepo.ErrInvalidAdId), errors.Is(err, adrepo.ErrInvalidImageId):
+		return status.Error(codes.NotFound, err.Error())
+	}
+	return permissionError(err)
+}
+
+func (a *AdService) UploadImage(stream base.AdService_UploadImageServer) error {
+	ctx := stream.Context()
+	userId, err := actingUser(ctx)
+	if err != nil {
+		return err
+	}
+	req, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	info := req.GetInfo()
+	if info == nil {
+		return status.Error(codes.InvalidArgument, "the first message has to carry the info of the image")
+	}
+	var data []byte
+	for {
+		req, err = stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
 		}
+		if req.GetInfo() != nil {
+			return status.Error(codes.InvalidArgument, "the info of the image is sent once")
+		}
+		// the upload is cut short rather than read in full
+		if len(data)+len(req.GetChunk()) > ads.MaxImageSize {
+			return imageError(ads.ErrImageTooLarge)
+		}
+		data = append(data, req.GetChunk()...)
 	}
-	return &base.ListAdResponse{List: response}, nil
+	img, err := a.app.AddImage(ctx, info.AdId, userId, info.ContentType, data)
+	if err != nil {
+		return imageError(err)
+	}
+	return stream.SendAndClose(imageToResponse(img))
 }
 
-func (a *AdService) DeleteAd(ctx context.Context, req *base.DeleteAdRequest) (*empty.Empty, error) {
-	ad, err := a.repo.GetAdById(ctx, req.AdId)
+func (a *AdService) ListAdImages(ctx context.Context, req *base.ListAdImagesRequest) (*base.ListImagesResponse, error) {
+	list, err := a.app.GetImages(ctx, req.AdId)
+	if err != nil {
+		return nil, imageError(err)
+	}
+	return imagesToResponse(list), nil
+}
+
+func (a *AdService) ReorderAdImages(ctx context.Context, req *base.ReorderAdImagesRequest) (*base.ListImagesResponse, error) {
+	userId, err := actingUser(ctx)
 	if err != nil {
 		return nil, err
 	}
-	if ad.AuthorID != req.AuthorId {
-		return nil, ErrUnableToDelete
+	list, err := a.app.ReorderImages(ctx, req.AdId, userId, req.Order)
+	if err != nil {
+		return nil, imageError(err)
 	}
-	_, err = a.userRepo.GetUser(ctx, req.AuthorId)
+	return imagesToResponse(list), nil
+}
+
+func (a *AdService) DeleteAdImage(ctx context.Context, req *base.DeleteAdImageRequest) (*empty.Empty, error) {
+	userId, err := actingUser(ctx)
 	if err != nil {
-		return nil, userrepo.ErrInvalidUserId
+		return nil, err
+	}
+	if err = a.app.DeleteImage(ctx, req.AdId, userId, req.ImageId); err != nil {
+		return nil, imageError(err)
 	}
-	return &empty.Empty{}, a.repo.DeleteAd(ctx, req.AdId)
+	return &empty.Empty{}, nil
 }
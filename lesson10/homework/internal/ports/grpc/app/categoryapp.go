@@ -0,0 +1,95 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"github.com/golang/protobuf/ptypes/empty"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"homework10/internal/adapters/adrepo"
+	"homework10/internal/app/adsapp"
+	"homework10/internal/entities/ads"
+	"homework10/internal/ports/grpc/base"
+)
+
+type CategoryService struct {
+	app adsapp.App
+	base.UnimplementedCategoryServiceServer
+}
+
+func NewCategoryService(a adsapp.App) *CategoryService {
+	return &CategoryService{
+		app: a,
+	}
+}
+
+func categoryToResponse(c *ads.CategoryCount) *base.CategoryResponse {
+	return &base.CategoryResponse{Id: c.ID, ParentId: c.ParentID, Name: c.Name, Ads: int32(c.Ads)}
+}
+
+func categoryError(err error) error {
+	switch {
+	case errors.Is(err, ads.ErrInvalidCategory), errors.Is(err, ads.ErrUnknownCategory):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, adrepo.ErrInvalidCategoryId):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, ads.ErrCategoryInUse):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	}
+	return permissionError(err)
+}
+
+func (s *CategoryService) ListCategories(ctx context.Context, _ *empty.Empty) (*base.ListCategoriesResponse, error) {
+	list, err := s.app.ListCategories(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resp := make([]*base.CategoryResponse, len(list))
+	for i, c := range list {
+		resp[i] = categoryToResponse(c)
+	}
+	return &base.ListCategoriesResponse{List: resp}, nil
+}
+
+func (s *CategoryService) GetCategory(ctx context.Context, req *base.GetCategoryRequest) (*base.CategoryResponse, error) {
+	c, err := s.app.GetCategory(ctx, req.Id)
+	if err != nil {
+		return nil, categoryError(err)
+	}
+	return categoryToResponse(c), nil
+}
+
+func (s *CategoryService) CreateCategory(ctx context.Context, req *base.CreateCategoryRequest) (*base.CategoryResponse, error) {
+	userId, err := actingUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c, err := s.app.CreateCategory(ctx, userId, req.Name, req.ParentId)
+	if err != nil {
+		return nil, categoryError(err)
+	}
+	return categoryToResponse(&ads.CategoryCount{Category: *c}), nil
+}
+
+func (s *CategoryService) UpdateCategory(ctx context.Context, req *base.UpdateCategoryRequest) (*base.CategoryResponse, error) {
+	userId, err := actingUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c, err := s.app.UpdateCategory(ctx, userId, req.Id, req.Name, req.ParentId)
+	if err != nil {
+		return nil, categoryError(err)
+	}
+	return categoryToResponse(&ads.CategoryCount{Category: *c}), nil
+}
+
+func (s *CategoryService) DeleteCategory(ctx context.Context, req *base.DeleteCategoryRequest) (*empty.Empty, error) {
+	userId, err := actingUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err = s.app.DeleteCategory(ctx, userId, req.Id); err != nil {
+		return nil, categoryError(err)
+	}
+	return &empty.Empty{}, nil
+}
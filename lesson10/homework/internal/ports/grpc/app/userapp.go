@@ -2,88 +2,270 @@ package app
 
 import (
 	"context"
+	"errors"
 	"github.com/golang/protobuf/ptypes/empty"
-	"homework10/internal/entities/ads"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"homework10/internal/app/authapp"
+	"homework10/internal/app/userapp"
 	"homework10/internal/entities/user"
 	"homework10/internal/ports/grpc/base"
+	"time"
 )
 
 type UserService struct {
-	repo user.Repository
+	app userapp.App
 	base.UnimplementedUserServiceServer
 }
 
-func NewUserService(repo user.Repository) *UserService {
+func NewUserService(u userapp.App) *UserService {
 	return &UserService{
-		repo: repo,
+		app: u,
 	}
 }
 
+func userToResponse(usr *user.User) *base.UserResponse {
+	return &base.UserResponse{
+		Id:           usr.Id,
+		Nickname:     usr.Nickname,
+		Email:        usr.Email,
+		Role:         string(usr.Role.OrDefault()),
+		Verified:     usr.Verified,
+		RegisteredAt: registeredAt(usr),
+	}
+}
+
+func registeredAt(usr *user.User) string {
+	if usr.CreatedAt.IsZero() {
+		return ""
+	}
+	return usr.CreatedAt.Format(time.RFC3339)
+}
+
+func profileToResponse(usr *user.User) *base.ProfileResponse {
+	return &base.ProfileResponse{
+		Id:           usr.Id,
+		Nickname:     usr.Nickname,
+		DisplayName:  usr.Profile.DisplayName,
+		Bio:          usr.Profile.Bio,
+		City:         usr.Profile.City,
+		Phone:        usr.Profile.Phone,
+		RegisteredAt: registeredAt(usr),
+	}
+}
+
+func apiKeyToResponse(k *user.APIKey) *base.APIKeyResponse {
+	resp := &base.APIKeyResponse{
+		Id:        k.ID,
+		Name:      k.Name,
+		Scopes:    make([]string, len(k.Scopes)),
+		CreatedAt: k.CreatedAt.Format(time.RFC3339),
+	}
+	for i, s := range k.Scopes {
+		resp.Scopes[i] = string(s)
+	}
+	if !k.ExpiresAt.IsZero() {
+		resp.ExpiresAt = k.ExpiresAt.Format(time.RFC3339)
+	}
+	if !k.LastUsedAt.IsZero() {
+		resp.LastUsedAt = k.LastUsedAt.Format(time.RFC3339)
+	}
+	return resp
+}
+
+func exportToResponse(export *userapp.Export) *base.ExportUserResponse {
+	resp := &base.ExportUserResponse{
+		User:       userToResponse(export.User),
+		Ads:        make([]*base.AdResponse, len(export.Ads)),
+		Activity:   make([]*base.RevisionResponse, len(export.Activity)),
+		ApiKeys:    make([]*base.APIKeyResponse, len(export.APIKeys)),
+		ExportedAt: export.ExportedAt.Format(time.RFC3339),
+	}
+	for i, ad := range export.Ads {
+		resp.Ads[i] = adToResponse(ad)
+	}
+	for i, rev := range export.Activity {
+		resp.Activity[i] = revisionToResponse(rev)
+	}
+	for i, k := range export.APIKeys {
+		resp.ApiKeys[i] = apiKeyToResponse(k)
+	}
+	return resp
+}
+
+// userError turns the errors of the email checks into statuses, other
+// errors are returned as they are.
+func userError(err error) error {
+	switch {
+	case errors.Is(err, user.ErrInvalidEmail), errors.Is(err, userapp.ErrInvalidVerifyToken),
+		errors.Is(err, userapp.ErrInvalidResetToken), errors.Is(err, user.ErrInvalidUserParams),
+		errors.Is(err, user.ErrInvalidProfile), errors.Is(err, user.ErrInvalidVisibility),
+		errors.Is(err, user.ErrInvalidSearch):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, user.ErrEmailTaken):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, userapp.ErrAlreadyVerified):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	}
+	return err
+}
+
 func (us *UserService) CreateUser(ctx context.Context, req *base.CreateUserRequest) (*base.UserResponse, error) {
-	usr := &user.User{
-		Nickname: req.Nickname,
-		Email:    req.Email,
-		Password: req.Password,
+	usr, err := us.app.CreateUser(ctx, req.Nickname, req.Email, req.Password)
+	if err != nil {
+		return nil, userError(err)
 	}
-	err := user.ValidateUser(usr)
+	return userToResponse(usr), nil
+}
+
+func (us *UserService) ChangeNickname(ctx context.Context, req *base.ChangeNicknameRequest) (*base.UserResponse, error) {
+	actorId, err := actingUser(ctx)
 	if err != nil {
-		return nil, user.ErrInvalidUserParams
+		return nil, err
 	}
-	id, err := us.repo.CreateUser(ctx, usr)
+	usr, err := us.app.ChangeNickname(ctx, actorId, req.Id, req.Nickname)
+	if err != nil {
+		return nil, permissionError(err)
+	}
+	return userToResponse(usr), nil
+}
+
+func (us *UserService) GetUser(ctx context.Context, req *base.GetUserRequest) (*base.UserResponse, error) {
+	usr, err := us.app.GetUser(ctx, req.Id)
 	if err != nil {
 		return nil, err
 	}
-	usr.Id = id
-	return &base.UserResponse{
-		Id:       usr.Id,
-		Nickname: usr.Nickname,
-		Email:    usr.Email,
-	}, nil
+	return userToResponse(usr), nil
 }
 
-func (us *UserService) ChangeNickname(ctx context.Context, req *base.ChangeNicknameRequest) (*base.UserResponse, error) {
-	err := user.ValidateUser(&user.User{Nickname: req.Nickname, Password: "mockpass"})
+func (us *UserService) DeleteUser(ctx context.Context, req *base.DeleteUserRequest) (*empty.Empty, error) {
+	actorId, err := actingUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err = us.app.DeleteUser(ctx, actorId, req.Id); err != nil {
+		return nil, permissionError(err)
+	}
+	return &empty.Empty{}, nil
+}
+
+func (us *UserService) ChangeRole(ctx context.Context, req *base.ChangeRoleRequest) (*base.UserResponse, error) {
+	actorId, err := actingUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	usr, err := us.app.ChangeRole(ctx, actorId, req.Id, user.Role(req.Role))
+	if err != nil {
+		return nil, permissionError(err)
+	}
+	return userToResponse(usr), nil
+}
+
+func (us *UserService) VerifyEmail(ctx context.Context, req *base.VerifyEmailRequest) (*base.UserResponse, error) {
+	usr, err := us.app.VerifyEmail(ctx, req.Token)
+	if err != nil {
+		return nil, userError(err)
+	}
+	return userToResponse(usr), nil
+}
+
+func (us *UserService) SendVerification(ctx context.Context, _ *empty.Empty) (*empty.Empty, error) {
+	userId, err := actingUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err = us.app.SendVerification(ctx, userId); err != nil {
+		return nil, userError(err)
+	}
+	return &empty.Empty{}, nil
+}
+
+func (us *UserService) ForgotPassword(ctx context.Context, req *base.ForgotPasswordRequest) (*empty.Empty, error) {
+	if err := us.app.ForgotPassword(ctx, req.Email); err != nil {
+		return nil, err
+	}
+	return &empty.Empty{}, nil
+}
+
+func (us *UserService) ResetPassword(ctx context.Context, req *base.ResetPasswordRequest) (*base.UserResponse, error) {
+	usr, err := us.app.ResetPassword(ctx, req.Token, req.Password)
 	if err != nil {
-		return nil, ads.ErrInvalidAdParams
+		return nil, userError(err)
 	}
-	usr, err := us.repo.GetUser(ctx, req.Id)
+	return userToResponse(usr), nil
+}
+
+func (us *UserService) ExportUser(ctx context.Context, req *base.ExportUserRequest) (*base.ExportUserResponse, error) {
+	actorId, err := actingUser(ctx)
 	if err != nil {
 		return nil, err
 	}
-	if usr.Nickname == req.Nickname {
-		return &base.UserResponse{
-			Id:       usr.Id,
-			Nickname: usr.Nickname,
-			Email:    usr.Email,
-		}, nil
+	export, err := us.app.ExportUser(ctx, actorId, req.Id)
+	if err != nil {
+		return nil, permissionError(err)
 	}
-	usr, err = us.repo.UpdateNick(ctx, req.Id, req.Nickname)
+	return exportToResponse(export), nil
+}
+
+func (us *UserService) EraseUser(ctx context.Context, req *base.EraseUserRequest) (*empty.Empty, error) {
+	actorId, err := actingUser(ctx)
 	if err != nil {
 		return nil, err
 	}
-	return &base.UserResponse{
-		Id:       usr.Id,
-		Nickname: usr.Nickname,
-		Email:    usr.Email,
-	}, nil
+	if err = us.app.EraseUser(ctx, actorId, req.Id); err != nil {
+		return nil, permissionError(err)
+	}
+	return &empty.Empty{}, nil
 }
 
-func (us *UserService) GetUser(ctx context.Context, req *base.GetUserRequest) (*base.UserResponse, error) {
-	usr, err := us.repo.GetUser(ctx, req.Id)
+func (us *UserService) GetProfile(ctx context.Context, req *base.GetProfileRequest) (*base.ProfileResponse, error) {
+	viewerId := user.Anonymous
+	if principal, ok := authapp.PrincipalFrom(ctx); ok {
+		viewerId = principal.UserID
+	}
+	profile, err := us.app.GetProfile(ctx, viewerId, req.Id)
 	if err != nil {
 		return nil, err
 	}
-	return &base.UserResponse{
-		Id:       usr.Id,
-		Nickname: usr.Nickname,
-		Email:    usr.Email,
-	}, nil
+	resp := profileToResponse(profile.User)
+	resp.Ads = make([]*base.AdResponse, len(profile.Ads))
+	for i, ad := range profile.Ads {
+		resp.Ads[i] = adToResponse(ad)
+	}
+	return resp, nil
 }
 
-func (us *UserService) DeleteUser(ctx context.Context, req *base.DeleteUserRequest) (*empty.Empty, error) {
-	_, err := us.repo.GetUser(ctx, req.Id)
+func (us *UserService) UpdateProfile(ctx context.Context, req *base.UpdateProfileRequest) (*base.ProfileResponse, error) {
+	actorId, err := actingUser(ctx)
 	if err != nil {
 		return nil, err
 	}
-	return &empty.Empty{}, us.repo.DeleteUser(ctx, req.Id)
+	usr, err := us.app.UpdateProfile(ctx, actorId, req.Id, user.Profile{
+		DisplayName:     req.DisplayName,
+		Bio:             req.Bio,
+		City:            req.City,
+		Phone:           req.Phone,
+		CityVisibility:  user.Visibility(req.CityVisibility),
+		PhoneVisibility: user.Visibility(req.PhoneVisibility),
+	})
+	if err != nil {
+		return nil, permissionError(userError(err))
+	}
+	resp := profileToResponse(usr)
+	resp.CityVisibility = string(usr.Profile.CityVisibility)
+	resp.PhoneVisibility = string(usr.Profile.PhoneVisibility)
+	return resp, nil
+}
+
+func (us *UserService) SearchUsers(ctx context.Context, req *base.SearchUsersRequest) (*base.SearchUsersResponse, error) {
+	list, next, err := us.app.SearchUsers(ctx, user.Search{Prefix: req.Prefix, Limit: int(req.Limit),
+		Cursor: req.PageToken})
+	if err != nil {
+		return nil, userError(err)
+	}
+	resp := &base.SearchUsersResponse{List: make([]*base.UserSummary, len(list)), NextPageToken: next}
+	for i, usr := range list {
+		resp.List[i] = &base.UserSummary{Id: usr.Id, Nickname: usr.Nickname, DisplayName: usr.Profile.DisplayName}
+	}
+	return resp, nil
 }
@@ -0,0 +1,291 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"homework10/internal/app/authapp"
+	"homework10/internal/entities/user"
+	"strings"
+)
+
+// Access says who may call a method.
+type Access int
+
+const (
+	// Public methods can be called without a token.
+	Public Access = iota
+	// Authenticated methods need a valid access token.
+	Authenticated
+	// OwnerOnly methods need the token of the owner of the resource, or of
+	// an admin.
+	OwnerOnly
+	// AdminOnly methods need the token of an admin.
+	AdminOnly
+)
+
+// OwnerFunc returns the id of the user who owns the resource req is about.
+type OwnerFunc func(ctx context.Context, req interface{}) (int64, error)
+
+type Policy struct {
+	Access Access
+	// Owner is required by OwnerOnly methods.
+	Owner OwnerFunc
+	// Scope lets the api keys with it call the method, the methods without
+	// one are for access tokens only.
+	Scope user.Scope
+}
+
+// Policies maps the full method names, e.g. /ad.AdService/CreateAd, to their
+// policies. Methods that are not listed can't be called.
+type Policies map[string]Policy
+
+var (
+	errMissingToken   = status.Error(codes.Unauthenticated, "missing access token")
+	errInvalidToken   = status.Error(codes.Unauthenticated, authapp.ErrInvalidToken.Error())
+	errNotAllowed     = status.Error(codes.PermissionDenied, "not allowed to call this method")
+	errMissingScope   = status.Error(codes.PermissionDenied, "the api key lacks the scope of this method")
+	errNotOwner       = status.Error(codes.PermissionDenied, "not the owner of the resource")
+	errUnknownRequest = status.Error(codes.Internal, "can't find the owner of the request")
+)
+
+type authInterceptor struct {
+	auth     authapp.App
+	policies Policies
+}
+
+// apiKeyMetadata carries the api key, the lowercase X-API-Key header.
+const apiKeyMetadata = "x-api-key"
+
+// AuthInterceptors put the principal of the bearer token or the x-api-key
+// metadata into the context and enforce the policies.
+func AuthInterceptors(auth authapp.App, policies Policies) (grpc.UnaryServerInterceptor, grpc.StreamServerInterceptor) {
+	i := authInterceptor{auth: auth, policies: policies}
+	return i.unary, i.stream
+}
+
+// IdentifyInterceptors look up who calls ahead of the rate limits, invalid
+// credentials pass as anonymous until the auth interceptors.
+func IdentifyInterceptors(auth authapp.App) (grpc.UnaryServerInterceptor, grpc.StreamServerInterceptor) {
+	unary := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		return handler(identify(ctx, auth), req)
+	}
+	stream := func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &identifiedStream{ServerStream: ss, ctx: identify(ss.Context(), auth)})
+	}
+	return unary, stream
+}
+
+// identify takes the api key if there is one and the bearer token
+// otherwise, as authorize does.
+func identify(ctx context.Context, auth authapp.App) context.Context {
+	var (
+		principal *authapp.Principal
+		err       error
+	)
+	if key, ok := firstMetadata(ctx, apiKeyMetadata); ok {
+		principal, err = auth.AuthenticateKey(ctx, key)
+	} else if token, ok := bearerToken(ctx); ok {
+		principal, err = auth.Authenticate(ctx, token)
+	} else {
+		return ctx
+	}
+	if err != nil {
+		return ctx
+	}
+	return authapp.WithPrincipal(ctx, principal)
+}
+
+type identifiedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *identifiedStream) Context() context.Context {
+	return s.ctx
+}
+
+func (i authInterceptor) unary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, policy, err := i.authorize(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkOwner(ctx, policy, req); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (i authInterceptor) stream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, policy, err := i.authorize(ss.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	return handler(srv, &authStream{ServerStream: ss, ctx: ctx, policy: policy})
+}
+
+// authorize checks everything but the owner, which needs the request.
+func (i authInterceptor) authorize(ctx context.Context, method string) (context.Context, Policy, error) {
+	policy, ok := i.policies[method]
+	if !ok {
+		return nil, Policy{}, errNotAllowed
+	}
+	// the principal found by IdentifyInterceptors came from the same
+	// credentials, it is not looked up again
+	principal, identified := authapp.PrincipalFrom(ctx)
+	var err error
+	if key, ok := firstMetadata(ctx, apiKeyMetadata); ok {
+		if policy.Scope == "" {
+			return nil, Policy{}, errNotAllowed
+		}
+		if !identified {
+			principal, err = i.auth.AuthenticateKey(ctx, key)
+		}
+	} else if token, ok := bearerToken(ctx); ok {
+		if !identified {
+			principal, err = i.auth.Authenticate(ctx, token)
+		}
+	} else if policy.Access == Public {
+		return ctx, policy, nil
+	} else {
+		return nil, Policy{}, errMissingToken
+	}
+	if errors.Is(err, authapp.ErrInvalidToken) {
+		return nil, Policy{}, errInvalidToken
+	}
+	if err != nil {
+		return nil, Policy{}, err
+	}
+	if policy.Access == AdminOnly && principal.Role != user.RoleAdmin {
+		return nil, Policy{}, errNotAllowed
+	}
+	if policy.Scope != "" && !principal.Allows(policy.Scope) {
+		return nil, Policy{}, errMissingScope
+	}
+	return authapp.WithPrincipal(ctx, principal), policy, nil
+}
+
+func checkOwner(ctx context.Context, policy Policy, req interface{}) error {
+	if policy.Access != OwnerOnly {
+		return nil
+	}
+	principal, _ := authapp.PrincipalFrom(ctx)
+	if principal.Role == user.RoleAdmin {
+		return nil
+	}
+	if policy.Owner == nil {
+		return errUnknownRequest
+	}
+	owner, err := policy.Owner(ctx, req)
+	if err != nil {
+		return err
+	}
+	if owner != principal.UserID {
+		return errNotOwner
+	}
+	return nil
+}
+
+func firstMetadata(ctx context.Context, key string) (string, bool) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, value := range md.Get(key) {
+		if value = strings.TrimSpace(value); value != "" {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+func bearerToken(ctx context.Context) (string, bool) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, value := range md.Get("authorization") {
+		scheme, token, _ := strings.Cut(value, " ")
+		token = strings.TrimSpace(token)
+		if strings.EqualFold(scheme, "Bearer") && token != "" {
+			return token, true
+		}
+	}
+	return "", false
+}
+
+// authStream hands the principal to the handler and checks the owner of
+// every message it receives.
+type authStream struct {
+	grpc.ServerStream
+	ctx    context.Context
+	policy Policy
+}
+
+func (s *authStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *authStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	return checkOwner(s.ctx, s.policy, m)
+}
+
+// UserOwner treats the user in requests with a user id as their own owner.
+func UserOwner(_ context.Context, req interface{}) (int64, error) {
+	r, ok := req.(interface{ GetId() int64 })
+	if !ok {
+		return 0, errUnknownRequest
+	}
+	return r.GetId(), nil
+}
+
+// DefaultPolicies are the policies of the ad, user and category services,
+// only the ad service and the category reads take api keys.
+func DefaultPolicies() Policies {
+	userOwner := Policy{Access: OwnerOnly, Owner: UserOwner}
+	read := Policy{Access: Public, Scope: user.ScopeAdsRead}
+	write := Policy{Access: Authenticated, Scope: user.ScopeAdsWrite}
+	return Policies{
+		"/ad.AdService/CreateAd":           write,
+		"/ad.AdService/ChangeAdStatus":     write,
+		"/ad.AdService/UnpublishAd":        write,
+		"/ad.AdService/ChangeAdState":      write,
+		"/ad.AdService/UpdateAd":           write,
+		"/ad.AdService/GetAdById":          read,
+		"/ad.AdService/GetAdByTitle":       read,
+		"/ad.AdService/ListAds":            read,
+		"/ad.AdService/SearchAds":          read,
+		"/ad.AdService/DeleteAd":           write,
+		"/ad.AdService/RestoreAd":          write,
+		"/ad.AdService/ListTrash":          {Access: Authenticated, Scope: user.ScopeAdsRead},
+		"/ad.AdService/ListAdRevisions":    read,
+		"/ad.AdService/GetAdRevision":      read,
+		"/ad.AdService/RevertAd":           write,
+		"/ad.AdService/ScheduleAd":         write,
+		"/ad.AdService/RenewAd":            write,
+		"/ad.AdService/UploadImage":        write,
+		"/ad.AdService/ListAdImages":       read,
+		"/ad.AdService/ReorderAdImages":    write,
+		"/ad.AdService/DeleteAdImage":      write,
+		"/ad.UserService/CreateUser":       {Access: Public},
+		"/ad.UserService/ChangeNickname":   userOwner,
+		"/ad.UserService/GetUser":          {Access: Public},
+		"/ad.UserService/DeleteUser":       userOwner,
+		"/ad.UserService/ChangeRole":       {Access: AdminOnly},
+		"/ad.UserService/VerifyEmail":      {Access: Public},
+		"/ad.UserService/SendVerification": {Access: Authenticated},
+		"/ad.UserService/ForgotPassword":   {Access: Public},
+		"/ad.UserService/ResetPassword":    {Access: Public},
+		"/ad.UserService/ExportUser":       userOwner,
+		"/ad.UserService/EraseUser":        userOwner,
+		"/ad.UserService/GetProfile":       {Access: Public},
+		"/ad.UserService/UpdateProfile":    userOwner,
+		"/ad.UserService/SearchUsers":      {Access: Public},
+
+		"/ad.CategoryService/ListCategories": read,
+		"/ad.CategoryService/GetCategory":    read,
+		"/ad.CategoryService/CreateCategory": {Access: AdminOnly},
+		"/ad.CategoryService/UpdateCategory": {Access: AdminOnly},
+		"/ad.CategoryService/DeleteCategory": {Access: AdminOnly},
+	}
+}
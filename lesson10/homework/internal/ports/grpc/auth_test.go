@@ -0,0 +1,148 @@
+package grpc
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"homework10/internal/app/authapp"
+	"homework10/internal/entities/user"
+	"testing"
+)
+
+type fakeAuth struct {
+	authapp.App
+}
+
+func (fakeAuth) Authenticate(_ context.Context, token string) (*authapp.Principal, error) {
+	switch token {
+	case "user":
+		return &authapp.Principal{UserID: 1, Role: user.RoleUser}, nil
+	case "admin":
+		return &authapp.Principal{UserID: 2, Role: user.RoleAdmin}, nil
+	}
+	return nil, authapp.ErrInvalidToken
+}
+
+func (fakeAuth) AuthenticateKey(_ context.Context, key string) (*authapp.Principal, error) {
+	if key == "reader" {
+		return &authapp.Principal{UserID: 3, Role: user.RoleUser, KeyID: "reader", Scopes: []user.Scope{user.ScopeAdsRead}}, nil
+	}
+	return nil, authapp.ErrInvalidToken
+}
+
+type idRequest struct {
+	id int64
+}
+
+func (r *idRequest) GetId() int64 {
+	return r.id
+}
+
+func withToken(token string) context.Context {
+	return metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+token))
+}
+
+func withAPIKey(key string) context.Context {
+	return metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-api-key", key))
+}
+
+func TestAuthUnaryInterceptor(t *testing.T) {
+	unary, _ := AuthInterceptors(fakeAuth{}, Policies{
+		"/test/Public": {Access: Public},
+		"/test/Admin":  {Access: AdminOnly},
+		"/test/Owner":  {Access: OwnerOnly, Owner: UserOwner},
+		"/test/Read":   {Access: Public, Scope: user.ScopeAdsRead},
+		"/test/Write":  {Access: Authenticated, Scope: user.ScopeAdsWrite},
+	})
+	var principal *authapp.Principal
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		principal, _ = authapp.PrincipalFrom(ctx)
+		return nil, nil
+	}
+	call := func(ctx context.Context, method string, req interface{}) error {
+		principal = nil
+		_, err := unary(ctx, req, &grpc.UnaryServerInfo{FullMethod: method}, handler)
+		return err
+	}
+
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		method string
+		req    interface{}
+		code   codes.Code
+		userId int64
+	}{
+		{"public anonymous", context.Background(), "/test/Public", nil, codes.OK, -1},
+		{"public with token", withToken("user"), "/test/Public", nil, codes.OK, 1},
+		{"public invalid token", withToken("garbage"), "/test/Public", nil, codes.Unauthenticated, -1},
+		{"admin anonymous", context.Background(), "/test/Admin", nil, codes.Unauthenticated, -1},
+		{"admin as user", withToken("user"), "/test/Admin", nil, codes.PermissionDenied, -1},
+		{"admin as admin", withToken("admin"), "/test/Admin", nil, codes.OK, 2},
+		{"owner", withToken("user"), "/test/Owner", &idRequest{id: 1}, codes.OK, 1},
+		{"not owner", withToken("user"), "/test/Owner", &idRequest{id: 3}, codes.PermissionDenied, -1},
+		{"owner as admin", withToken("admin"), "/test/Owner", &idRequest{id: 3}, codes.OK, 2},
+		{"unknown method", withToken("admin"), "/test/Unknown", nil, codes.PermissionDenied, -1},
+		{"key with scope", withAPIKey("reader"), "/test/Read", nil, codes.OK, 3},
+		{"key without scope", withAPIKey("reader"), "/test/Write", nil, codes.PermissionDenied, -1},
+		{"key on unscoped method", withAPIKey("reader"), "/test/Public", nil, codes.PermissionDenied, -1},
+		{"invalid key", withAPIKey("garbage"), "/test/Read", nil, codes.Unauthenticated, -1},
+		{"token on scoped method", withToken("user"), "/test/Write", nil, codes.OK, 1},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := call(tc.ctx, tc.method, tc.req)
+			assert.Equal(t, tc.code, status.Code(err))
+			if tc.userId < 0 {
+				assert.Nil(t, principal)
+			} else if assert.NotNil(t, principal) {
+				assert.Equal(t, tc.userId, principal.UserID)
+			}
+		})
+	}
+}
+
+type fakeStream struct {
+	grpc.ServerStream
+	ctx  context.Context
+	msgs []*idRequest
+}
+
+func (s *fakeStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *fakeStream) RecvMsg(m interface{}) error {
+	m.(*idRequest).id, s.msgs = s.msgs[0].id, s.msgs[1:]
+	return nil
+}
+
+func TestAuthStreamInterceptor(t *testing.T) {
+	_, stream := AuthInterceptors(fakeAuth{}, Policies{
+		"/test/Owner": {Access: OwnerOnly, Owner: UserOwner},
+	})
+	info := &grpc.StreamServerInfo{FullMethod: "/test/Owner"}
+	var errs []error
+	handler := func(srv interface{}, ss grpc.ServerStream) error {
+		principal, ok := authapp.PrincipalFrom(ss.Context())
+		assert.True(t, ok)
+		assert.Equal(t, int64(1), principal.UserID)
+		for i := 0; i < 2; i++ {
+			errs = append(errs, ss.RecvMsg(&idRequest{}))
+		}
+		return nil
+	}
+
+	err := stream(nil, &fakeStream{ctx: context.Background()}, info, handler)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	ss := &fakeStream{ctx: withToken("user"), msgs: []*idRequest{{id: 1}, {id: 3}}}
+	assert.NoError(t, stream(nil, ss, info, handler))
+	if assert.Len(t, errs, 2) {
+		assert.NoError(t, errs[0])
+		assert.Equal(t, codes.PermissionDenied, status.Code(errs[1]))
+	}
+}
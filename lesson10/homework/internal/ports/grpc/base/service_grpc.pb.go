@@ -20,13 +20,27 @@ import (
 const _ = grpc.SupportPackageIsVersion7
 
 const (
-	AdService_CreateAd_FullMethodName       = "/ad.AdService/CreateAd"
-	AdService_ChangeAdStatus_FullMethodName = "/ad.AdService/ChangeAdStatus"
-	AdService_UpdateAd_FullMethodName       = "/ad.AdService/UpdateAd"
-	AdService_GetAdById_FullMethodName      = "/ad.AdService/GetAdById"
-	AdService_GetAdByTitle_FullMethodName   = "/ad.AdService/GetAdByTitle"
-	AdService_ListAds_FullMethodName        = "/ad.AdService/ListAds"
-	AdService_DeleteAd_FullMethodName       = "/ad.AdService/DeleteAd"
+	AdService_CreateAd_FullMethodName        = "/ad.AdService/CreateAd"
+	AdService_ChangeAdStatus_FullMethodName  = "/ad.AdService/ChangeAdStatus"
+	AdService_UnpublishAd_FullMethodName     = "/ad.AdService/UnpublishAd"
+	AdService_ChangeAdState_FullMethodName   = "/ad.AdService/ChangeAdState"
+	AdService_UpdateAd_FullMethodName        = "/ad.AdService/UpdateAd"
+	AdService_GetAdById_FullMethodName       = "/ad.AdService/GetAdById"
+	AdService_GetAdByTitle_FullMethodName    = "/ad.AdService/GetAdByTitle"
+	AdService_ListAds_FullMethodName         = "/ad.AdService/ListAds"
+	AdService_SearchAds_FullMethodName       = "/ad.AdService/SearchAds"
+	AdService_DeleteAd_FullMethodName        = "/ad.AdService/DeleteAd"
+	AdService_RestoreAd_FullMethodName       = "/ad.AdService/RestoreAd"
+	AdService_ListTrash_FullMethodName       = "/ad.AdService/ListTrash"
+	AdService_ListAdRevisions_FullMethodName = "/ad.AdService/ListAdRevisions"
+	AdService_GetAdRevision_FullMethodName   = "/ad.AdService/GetAdRevision"
+	AdService_RevertAd_FullMethodName        = "/ad.AdService/RevertAd"
+	AdService_ScheduleAd_FullMethodName      = "/ad.AdService/ScheduleAd"
+	AdService_RenewAd_FullMethodName         = "/ad.AdService/RenewAd"
+	AdService_UploadImage_FullMethodName     = "/ad.AdService/UploadImage"
+	AdService_ListAdImages_FullMethodName    = "/ad.AdService/ListAdImages"
+	AdService_ReorderAdImages_FullMethodName = "/ad.AdService/ReorderAdImages"
+	AdService_DeleteAdImage_FullMethodName   = "/ad.AdService/DeleteAdImage"
 )
 
 // AdServiceClient is the client API for AdService service.
@@ -35,11 +49,33 @@ const (
 type AdServiceClient interface {
 	CreateAd(ctx context.Context, in *CreateAdRequest, opts ...grpc.CallOption) (*AdResponse, error)
 	ChangeAdStatus(ctx context.Context, in *ChangeAdStatusRequest, opts ...grpc.CallOption) (*AdResponse, error)
+	UnpublishAd(ctx context.Context, in *UnpublishAdRequest, opts ...grpc.CallOption) (*AdResponse, error)
+	// ChangeAdState moves the ad to another state of its lifecycle, only
+	// along the transitions the author or a moderator may make.
+	ChangeAdState(ctx context.Context, in *ChangeAdStateRequest, opts ...grpc.CallOption) (*AdResponse, error)
 	UpdateAd(ctx context.Context, in *UpdateAdRequest, opts ...grpc.CallOption) (*AdResponse, error)
 	GetAdById(ctx context.Context, in *GetAdByIdRequest, opts ...grpc.CallOption) (*AdResponse, error)
 	GetAdByTitle(ctx context.Context, in *GetAdByTitleRequest, opts ...grpc.CallOption) (*ListAdResponse, error)
 	ListAds(ctx context.Context, in *Filters, opts ...grpc.CallOption) (*ListAdResponse, error)
+	SearchAds(ctx context.Context, in *SearchAdsRequest, opts ...grpc.CallOption) (*ListAdResponse, error)
 	DeleteAd(ctx context.Context, in *DeleteAdRequest, opts ...grpc.CallOption) (*empty.Empty, error)
+	RestoreAd(ctx context.Context, in *RestoreAdRequest, opts ...grpc.CallOption) (*AdResponse, error)
+	ListTrash(ctx context.Context, in *ListTrashRequest, opts ...grpc.CallOption) (*ListAdResponse, error)
+	ListAdRevisions(ctx context.Context, in *ListAdRevisionsRequest, opts ...grpc.CallOption) (*ListAdRevisionsResponse, error)
+	GetAdRevision(ctx context.Context, in *GetAdRevisionRequest, opts ...grpc.CallOption) (*AdResponse, error)
+	RevertAd(ctx context.Context, in *RevertAdRequest, opts ...grpc.CallOption) (*AdResponse, error)
+	// ScheduleAd sets when the ad goes up and when it expires, an ad without
+	// an expiration stays up for 30 days.
+	ScheduleAd(ctx context.Context, in *ScheduleAdRequest, opts ...grpc.CallOption) (*AdResponse, error)
+	// RenewAd keeps the ad up for another 30 days, and puts it back up if it
+	// has expired.
+	RenewAd(ctx context.Context, in *RenewAdRequest, opts ...grpc.CallOption) (*AdResponse, error)
+	// UploadImage takes the info of the image first, then its file in chunks
+	// of at most 5 MiB in all.
+	UploadImage(ctx context.Context, opts ...grpc.CallOption) (AdService_UploadImageClient, error)
+	ListAdImages(ctx context.Context, in *ListAdImagesRequest, opts ...grpc.CallOption) (*ListImagesResponse, error)
+	ReorderAdImages(ctx context.Context, in *ReorderAdImagesRequest, opts ...grpc.CallOption) (*ListImagesResponse, error)
+	DeleteAdImage(ctx context.Context, in *DeleteAdImageRequest, opts ...grpc.CallOption) (*empty.Empty, error)
 }
 
 type adServiceClient struct {
@@ -68,6 +104,24 @@ func (c *adServiceClient) ChangeAdStatus(ctx context.Context, in *ChangeAdStatus
 	return out, nil
 }
 
+func (c *adServiceClient) UnpublishAd(ctx context.Context, in *UnpublishAdRequest, opts ...grpc.CallOption) (*AdResponse, error) {
+	out := new(AdResponse)
+	err := c.cc.Invoke(ctx, AdService_UnpublishAd_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adServiceClient) ChangeAdState(ctx context.Context, in *ChangeAdStateRequest, opts ...grpc.CallOption) (*AdResponse, error) {
+	out := new(AdResponse)
+	err := c.cc.Invoke(ctx, AdService_ChangeAdState_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *adServiceClient) UpdateAd(ctx context.Context, in *UpdateAdRequest, opts ...grpc.CallOption) (*AdResponse, error) {
 	out := new(AdResponse)
 	err := c.cc.Invoke(ctx, AdService_UpdateAd_FullMethodName, in, out, opts...)
@@ -104,6 +158,15 @@ func (c *adServiceClient) ListAds(ctx context.Context, in *Filters, opts ...grpc
 	return out, nil
 }
 
+func (c *adServiceClient) SearchAds(ctx context.Context, in *SearchAdsRequest, opts ...grpc.CallOption) (*ListAdResponse, error) {
+	out := new(ListAdResponse)
+	err := c.cc.Invoke(ctx, AdService_SearchAds_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *adServiceClient) DeleteAd(ctx context.Context, in *DeleteAdRequest, opts ...grpc.CallOption) (*empty.Empty, error) {
 	out := new(empty.Empty)
 	err := c.cc.Invoke(ctx, AdService_DeleteAd_FullMethodName, in, out, opts...)
@@ -113,17 +176,163 @@ func (c *adServiceClient) DeleteAd(ctx context.Context, in *DeleteAdRequest, opt
 	return out, nil
 }
 
+func (c *adServiceClient) RestoreAd(ctx context.Context, in *RestoreAdRequest, opts ...grpc.CallOption) (*AdResponse, error) {
+	out := new(AdResponse)
+	err := c.cc.Invoke(ctx, AdService_RestoreAd_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adServiceClient) ListTrash(ctx context.Context, in *ListTrashRequest, opts ...grpc.CallOption) (*ListAdResponse, error) {
+	out := new(ListAdResponse)
+	err := c.cc.Invoke(ctx, AdService_ListTrash_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adServiceClient) ListAdRevisions(ctx context.Context, in *ListAdRevisionsRequest, opts ...grpc.CallOption) (*ListAdRevisionsResponse, error) {
+	out := new(ListAdRevisionsResponse)
+	err := c.cc.Invoke(ctx, AdService_ListAdRevisions_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adServiceClient) GetAdRevision(ctx context.Context, in *GetAdRevisionRequest, opts ...grpc.CallOption) (*AdResponse, error) {
+	out := new(AdResponse)
+	err := c.cc.Invoke(ctx, AdService_GetAdRevision_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adServiceClient) RevertAd(ctx context.Context, in *RevertAdRequest, opts ...grpc.CallOption) (*AdResponse, error) {
+	out := new(AdResponse)
+	err := c.cc.Invoke(ctx, AdService_RevertAd_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adServiceClient) ScheduleAd(ctx context.Context, in *ScheduleAdRequest, opts ...grpc.CallOption) (*AdResponse, error) {
+	out := new(AdResponse)
+	err := c.cc.Invoke(ctx, AdService_ScheduleAd_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adServiceClient) RenewAd(ctx context.Context, in *RenewAdRequest, opts ...grpc.CallOption) (*AdResponse, error) {
+	out := new(AdResponse)
+	err := c.cc.Invoke(ctx, AdService_RenewAd_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adServiceClient) UploadImage(ctx context.Context, opts ...grpc.CallOption) (AdService_UploadImageClient, error) {
+	stream, err := c.cc.NewStream(ctx, &AdService_ServiceDesc.Streams[0], AdService_UploadImage_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &adServiceUploadImageClient{stream}
+	return x, nil
+}
+
+type AdService_UploadImageClient interface {
+	Send(*UploadImageRequest) error
+	CloseAndRecv() (*ImageResponse, error)
+	grpc.ClientStream
+}
+
+type adServiceUploadImageClient struct {
+	grpc.ClientStream
+}
+
+func (x *adServiceUploadImageClient) Send(m *UploadImageRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *adServiceUploadImageClient) CloseAndRecv() (*ImageResponse, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(ImageResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *adServiceClient) ListAdImages(ctx context.Context, in *ListAdImagesRequest, opts ...grpc.CallOption) (*ListImagesResponse, error) {
+	out := new(ListImagesResponse)
+	err := c.cc.Invoke(ctx, AdService_ListAdImages_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adServiceClient) ReorderAdImages(ctx context.Context, in *ReorderAdImagesRequest, opts ...grpc.CallOption) (*ListImagesResponse, error) {
+	out := new(ListImagesResponse)
+	err := c.cc.Invoke(ctx, AdService_ReorderAdImages_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adServiceClient) DeleteAdImage(ctx context.Context, in *DeleteAdImageRequest, opts ...grpc.CallOption) (*empty.Empty, error) {
+	out := new(empty.Empty)
+	err := c.cc.Invoke(ctx, AdService_DeleteAdImage_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // AdServiceServer is the server API for AdService service.
 // All implementations must embed UnimplementedAdServiceServer
 // for forward compatibility
 type AdServiceServer interface {
 	CreateAd(context.Context, *CreateAdRequest) (*AdResponse, error)
 	ChangeAdStatus(context.Context, *ChangeAdStatusRequest) (*AdResponse, error)
+	UnpublishAd(context.Context, *UnpublishAdRequest) (*AdResponse, error)
+	// ChangeAdState moves the ad to another state of its lifecycle, only
+	// along the transitions the author or a moderator may make.
+	ChangeAdState(context.Context, *ChangeAdStateRequest) (*AdResponse, error)
 	UpdateAd(context.Context, *UpdateAdRequest) (*AdResponse, error)
 	GetAdById(context.Context, *GetAdByIdRequest) (*AdResponse, error)
 	GetAdByTitle(context.Context, *GetAdByTitleRequest) (*ListAdResponse, error)
 	ListAds(context.Context, *Filters) (*ListAdResponse, error)
+	SearchAds(context.Context, *SearchAdsRequest) (*ListAdResponse, error)
 	DeleteAd(context.Context, *DeleteAdRequest) (*empty.Empty, error)
+	RestoreAd(context.Context, *RestoreAdRequest) (*AdResponse, error)
+	ListTrash(context.Context, *ListTrashRequest) (*ListAdResponse, error)
+	ListAdRevisions(context.Context, *ListAdRevisionsRequest) (*ListAdRevisionsResponse, error)
+	GetAdRevision(context.Context, *GetAdRevisionRequest) (*AdResponse, error)
+	RevertAd(context.Context, *RevertAdRequest) (*AdResponse, error)
+	// ScheduleAd sets when the ad goes up and when it expires, an ad without
+	// an expiration stays up for 30 days.
+	ScheduleAd(context.Context, *ScheduleAdRequest) (*AdResponse, error)
+	// RenewAd keeps the ad up for another 30 days, and puts it back up if it
+	// has expired.
+	RenewAd(context.Context, *RenewAdRequest) (*AdResponse, error)
+	// UploadImage takes the info of the image first, then its file in chunks
+	// of at most 5 MiB in all.
+	UploadImage(AdService_UploadImageServer) error
+	ListAdImages(context.Context, *ListAdImagesRequest) (*ListImagesResponse, error)
+	ReorderAdImages(context.Context, *ReorderAdImagesRequest) (*ListImagesResponse, error)
+	DeleteAdImage(context.Context, *DeleteAdImageRequest) (*empty.Empty, error)
 	mustEmbedUnimplementedAdServiceServer()
 }
 
@@ -131,200 +340,1151 @@ type AdServiceServer interface {
 type UnimplementedAdServiceServer struct {
 }
 
-func (UnimplementedAdServiceServer) CreateAd(context.Context, *CreateAdRequest) (*AdResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method CreateAd not implemented")
-}
-func (UnimplementedAdServiceServer) ChangeAdStatus(context.Context, *ChangeAdStatusRequest) (*AdResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method ChangeAdStatus not implemented")
-}
-func (UnimplementedAdServiceServer) UpdateAd(context.Context, *UpdateAdRequest) (*AdResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method UpdateAd not implemented")
-}
-func (UnimplementedAdServiceServer) GetAdById(context.Context, *GetAdByIdRequest) (*AdResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method GetAdById not implemented")
-}
-func (UnimplementedAdServiceServer) GetAdByTitle(context.Context, *GetAdByTitleRequest) (*ListAdResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method GetAdByTitle not implemented")
-}
-func (UnimplementedAdServiceServer) ListAds(context.Context, *Filters) (*ListAdResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method ListAds not implemented")
+func (UnimplementedAdServiceServer) CreateAd(context.Context, *CreateAdRequest) (*AdResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateAd not implemented")
+}
+func (UnimplementedAdServiceServer) ChangeAdStatus(context.Context, *ChangeAdStatusRequest) (*AdResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ChangeAdStatus not implemented")
+}
+func (UnimplementedAdServiceServer) UnpublishAd(context.Context, *UnpublishAdRequest) (*AdResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UnpublishAd not implemented")
+}
+func (UnimplementedAdServiceServer) ChangeAdState(context.Context, *ChangeAdStateRequest) (*AdResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ChangeAdState not implemented")
+}
+func (UnimplementedAdServiceServer) UpdateAd(context.Context, *UpdateAdRequest) (*AdResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateAd not implemented")
+}
+func (UnimplementedAdServiceServer) GetAdById(context.Context, *GetAdByIdRequest) (*AdResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetAdById not implemented")
+}
+func (UnimplementedAdServiceServer) GetAdByTitle(context.Context, *GetAdByTitleRequest) (*ListAdResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetAdByTitle not implemented")
+}
+func (UnimplementedAdServiceServer) ListAds(context.Context, *Filters) (*ListAdResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListAds not implemented")
+}
+func (UnimplementedAdServiceServer) SearchAds(context.Context, *SearchAdsRequest) (*ListAdResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SearchAds not implemented")
+}
+func (UnimplementedAdServiceServer) DeleteAd(context.Context, *DeleteAdRequest) (*empty.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteAd not implemented")
+}
+func (UnimplementedAdServiceServer) RestoreAd(context.Context, *RestoreAdRequest) (*AdResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RestoreAd not implemented")
+}
+func (UnimplementedAdServiceServer) ListTrash(context.Context, *ListTrashRequest) (*ListAdResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListTrash not implemented")
+}
+func (UnimplementedAdServiceServer) ListAdRevisions(context.Context, *ListAdRevisionsRequest) (*ListAdRevisionsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListAdRevisions not implemented")
+}
+func (UnimplementedAdServiceServer) GetAdRevision(context.Context, *GetAdRevisionRequest) (*AdResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetAdRevision not implemented")
+}
+func (UnimplementedAdServiceServer) RevertAd(context.Context, *RevertAdRequest) (*AdResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RevertAd not implemented")
+}
+func (UnimplementedAdServiceServer) ScheduleAd(context.Context, *ScheduleAdRequest) (*AdResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ScheduleAd not implemented")
+}
+func (UnimplementedAdServiceServer) RenewAd(context.Context, *RenewAdRequest) (*AdResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RenewAd not implemented")
+}
+func (UnimplementedAdServiceServer) UploadImage(AdService_UploadImageServer) error {
+	return status.Errorf(codes.Unimplemented, "method UploadImage not implemented")
+}
+func (UnimplementedAdServiceServer) ListAdImages(context.Context, *ListAdImagesRequest) (*ListImagesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListAdImages not implemented")
+}
+func (UnimplementedAdServiceServer) ReorderAdImages(context.Context, *ReorderAdImagesRequest) (*ListImagesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReorderAdImages not implemented")
+}
+func (UnimplementedAdServiceServer) DeleteAdImage(context.Context, *DeleteAdImageRequest) (*empty.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteAdImage not implemented")
+}
+func (UnimplementedAdServiceServer) mustEmbedUnimplementedAdServiceServer() {}
+
+// UnsafeAdServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to AdServiceServer will
+// result in compilation errors.
+type UnsafeAdServiceServer interface {
+	mustEmbedUnimplementedAdServiceServer()
+}
+
+func RegisterAdServiceServer(s grpc.ServiceRegistrar, srv AdServiceServer) {
+	s.RegisterService(&AdService_ServiceDesc, srv)
+}
+
+func _AdService_CreateAd_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateAdRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdServiceServer).CreateAd(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdService_CreateAd_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdServiceServer).CreateAd(ctx, req.(*CreateAdRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdService_ChangeAdStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ChangeAdStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdServiceServer).ChangeAdStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdService_ChangeAdStatus_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdServiceServer).ChangeAdStatus(ctx, req.(*ChangeAdStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdService_UnpublishAd_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UnpublishAdRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdServiceServer).UnpublishAd(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdService_UnpublishAd_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdServiceServer).UnpublishAd(ctx, req.(*UnpublishAdRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdService_ChangeAdState_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ChangeAdStateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdServiceServer).ChangeAdState(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdService_ChangeAdState_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdServiceServer).ChangeAdState(ctx, req.(*ChangeAdStateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdService_UpdateAd_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateAdRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdServiceServer).UpdateAd(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdService_UpdateAd_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdServiceServer).UpdateAd(ctx, req.(*UpdateAdRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdService_GetAdById_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetAdByIdRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdServiceServer).GetAdById(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdService_GetAdById_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdServiceServer).GetAdById(ctx, req.(*GetAdByIdRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdService_GetAdByTitle_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetAdByTitleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdServiceServer).GetAdByTitle(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdService_GetAdByTitle_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdServiceServer).GetAdByTitle(ctx, req.(*GetAdByTitleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdService_ListAds_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Filters)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdServiceServer).ListAds(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdService_ListAds_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdServiceServer).ListAds(ctx, req.(*Filters))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdService_SearchAds_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SearchAdsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdServiceServer).SearchAds(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdService_SearchAds_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdServiceServer).SearchAds(ctx, req.(*SearchAdsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdService_DeleteAd_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteAdRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdServiceServer).DeleteAd(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdService_DeleteAd_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdServiceServer).DeleteAd(ctx, req.(*DeleteAdRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdService_RestoreAd_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RestoreAdRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdServiceServer).RestoreAd(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdService_RestoreAd_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdServiceServer).RestoreAd(ctx, req.(*RestoreAdRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdService_ListTrash_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListTrashRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdServiceServer).ListTrash(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdService_ListTrash_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdServiceServer).ListTrash(ctx, req.(*ListTrashRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdService_ListAdRevisions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListAdRevisionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdServiceServer).ListAdRevisions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdService_ListAdRevisions_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdServiceServer).ListAdRevisions(ctx, req.(*ListAdRevisionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdService_GetAdRevision_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetAdRevisionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdServiceServer).GetAdRevision(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdService_GetAdRevision_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdServiceServer).GetAdRevision(ctx, req.(*GetAdRevisionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdService_RevertAd_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RevertAdRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdServiceServer).RevertAd(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdService_RevertAd_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdServiceServer).RevertAd(ctx, req.(*RevertAdRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdService_ScheduleAd_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ScheduleAdRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdServiceServer).ScheduleAd(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdService_ScheduleAd_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdServiceServer).ScheduleAd(ctx, req.(*ScheduleAdRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdService_RenewAd_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RenewAdRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdServiceServer).RenewAd(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdService_RenewAd_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdServiceServer).RenewAd(ctx, req.(*RenewAdRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdService_UploadImage_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(AdServiceServer).UploadImage(&adServiceUploadImageServer{stream})
+}
+
+type AdService_UploadImageServer interface {
+	SendAndClose(*ImageResponse) error
+	Recv() (*UploadImageRequest, error)
+	grpc.ServerStream
+}
+
+type adServiceUploadImageServer struct {
+	grpc.ServerStream
+}
+
+func (x *adServiceUploadImageServer) SendAndClose(m *ImageResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *adServiceUploadImageServer) Recv() (*UploadImageRequest, error) {
+	m := new(UploadImageRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _AdService_ListAdImages_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListAdImagesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdServiceServer).ListAdImages(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdService_ListAdImages_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdServiceServer).ListAdImages(ctx, req.(*ListAdImagesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdService_ReorderAdImages_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReorderAdImagesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdServiceServer).ReorderAdImages(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdService_ReorderAdImages_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdServiceServer).ReorderAdImages(ctx, req.(*ReorderAdImagesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdService_DeleteAdImage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteAdImageRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdServiceServer).DeleteAdImage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdService_DeleteAdImage_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdServiceServer).DeleteAdImage(ctx, req.(*DeleteAdImageRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// AdService_ServiceDesc is the grpc.ServiceDesc for AdService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var AdService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "ad.AdService",
+	HandlerType: (*AdServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "CreateAd",
+			Handler:    _AdService_CreateAd_Handler,
+		},
+		{
+			MethodName: "ChangeAdStatus",
+			Handler:    _AdService_ChangeAdStatus_Handler,
+		},
+		{
+			MethodName: "UnpublishAd",
+			Handler:    _AdService_UnpublishAd_Handler,
+		},
+		{
+			MethodName: "ChangeAdState",
+			Handler:    _AdService_ChangeAdState_Handler,
+		},
+		{
+			MethodName: "UpdateAd",
+			Handler:    _AdService_UpdateAd_Handler,
+		},
+		{
+			MethodName: "GetAdById",
+			Handler:    _AdService_GetAdById_Handler,
+		},
+		{
+			MethodName: "GetAdByTitle",
+			Handler:    _AdService_GetAdByTitle_Handler,
+		},
+		{
+			MethodName: "ListAds",
+			Handler:    _AdService_ListAds_Handler,
+		},
+		{
+			MethodName: "SearchAds",
+			Handler:    _AdService_SearchAds_Handler,
+		},
+		{
+			MethodName: "DeleteAd",
+			Handler:    _AdService_DeleteAd_Handler,
+		},
+		{
+			MethodName: "RestoreAd",
+			Handler:    _AdService_RestoreAd_Handler,
+		},
+		{
+			MethodName: "ListTrash",
+			Handler:    _AdService_ListTrash_Handler,
+		},
+		{
+			MethodName: "ListAdRevisions",
+			Handler:    _AdService_ListAdRevisions_Handler,
+		},
+		{
+			MethodName: "GetAdRevision",
+			Handler:    _AdService_GetAdRevision_Handler,
+		},
+		{
+			MethodName: "RevertAd",
+			Handler:    _AdService_RevertAd_Handler,
+		},
+		{
+			MethodName: "ScheduleAd",
+			Handler:    _AdService_ScheduleAd_Handler,
+		},
+		{
+			MethodName: "RenewAd",
+			Handler:    _AdService_RenewAd_Handler,
+		},
+		{
+			MethodName: "ListAdImages",
+			Handler:    _AdService_ListAdImages_Handler,
+		},
+		{
+			MethodName: "ReorderAdImages",
+			Handler:    _AdService_ReorderAdImages_Handler,
+		},
+		{
+			MethodName: "DeleteAdImage",
+			Handler:    _AdService_DeleteAdImage_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "UploadImage",
+			Handler:       _AdService_UploadImage_Handler,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "service.proto",
+}
+
+const (
+	UserService_CreateUser_FullMethodName       = "/ad.UserService/CreateUser"
+	UserService_ChangeNickname_FullMethodName   = "/ad.UserService/ChangeNickname"
+	UserService_GetUser_FullMethodName          = "/ad.UserService/GetUser"
+	UserService_DeleteUser_FullMethodName       = "/ad.UserService/DeleteUser"
+	UserService_ChangeRole_FullMethodName       = "/ad.UserService/ChangeRole"
+	UserService_VerifyEmail_FullMethodName      = "/ad.UserService/VerifyEmail"
+	UserService_SendVerification_FullMethodName = "/ad.UserService/SendVerification"
+	UserService_ForgotPassword_FullMethodName   = "/ad.UserService/ForgotPassword"
+	UserService_ResetPassword_FullMethodName    = "/ad.UserService/ResetPassword"
+	UserService_ExportUser_FullMethodName       = "/ad.UserService/ExportUser"
+	UserService_EraseUser_FullMethodName        = "/ad.UserService/EraseUser"
+	UserService_GetProfile_FullMethodName       = "/ad.UserService/GetProfile"
+	UserService_UpdateProfile_FullMethodName    = "/ad.UserService/UpdateProfile"
+	UserService_SearchUsers_FullMethodName      = "/ad.UserService/SearchUsers"
+)
+
+// UserServiceClient is the client API for UserService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type UserServiceClient interface {
+	CreateUser(ctx context.Context, in *CreateUserRequest, opts ...grpc.CallOption) (*UserResponse, error)
+	ChangeNickname(ctx context.Context, in *ChangeNicknameRequest, opts ...grpc.CallOption) (*UserResponse, error)
+	GetUser(ctx context.Context, in *GetUserRequest, opts ...grpc.CallOption) (*UserResponse, error)
+	DeleteUser(ctx context.Context, in *DeleteUserRequest, opts ...grpc.CallOption) (*empty.Empty, error)
+	ChangeRole(ctx context.Context, in *ChangeRoleRequest, opts ...grpc.CallOption) (*UserResponse, error)
+	// VerifyEmail takes the token mailed to the user, SendVerification mails
+	// the calling user a new one.
+	VerifyEmail(ctx context.Context, in *VerifyEmailRequest, opts ...grpc.CallOption) (*UserResponse, error)
+	SendVerification(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*empty.Empty, error)
+	// ForgotPassword mails a reset token to the user with the email, if there
+	// is one. ResetPassword ends all the sessions of the user.
+	ForgotPassword(ctx context.Context, in *ForgotPasswordRequest, opts ...grpc.CallOption) (*empty.Empty, error)
+	ResetPassword(ctx context.Context, in *ResetPasswordRequest, opts ...grpc.CallOption) (*UserResponse, error)
+	// ExportUser returns everything stored about the user, EraseUser deletes
+	// it. Users may only pass themselves, admins anyone.
+	ExportUser(ctx context.Context, in *ExportUserRequest, opts ...grpc.CallOption) (*ExportUserResponse, error)
+	EraseUser(ctx context.Context, in *EraseUserRequest, opts ...grpc.CallOption) (*empty.Empty, error)
+	// GetProfile needs no token, callers that send one may see more of the
+	// profile. UpdateProfile replaces the whole profile of the user.
+	GetProfile(ctx context.Context, in *GetProfileRequest, opts ...grpc.CallOption) (*ProfileResponse, error)
+	UpdateProfile(ctx context.Context, in *UpdateProfileRequest, opts ...grpc.CallOption) (*ProfileResponse, error)
+	// SearchUsers finds the users whose nickname starts with the prefix, in
+	// any case.
+	SearchUsers(ctx context.Context, in *SearchUsersRequest, opts ...grpc.CallOption) (*SearchUsersResponse, error)
+}
+
+type userServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewUserServiceClient(cc grpc.ClientConnInterface) UserServiceClient {
+	return &userServiceClient{cc}
+}
+
+func (c *userServiceClient) CreateUser(ctx context.Context, in *CreateUserRequest, opts ...grpc.CallOption) (*UserResponse, error) {
+	out := new(UserResponse)
+	err := c.cc.Invoke(ctx, UserService_CreateUser_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServiceClient) ChangeNickname(ctx context.Context, in *ChangeNicknameRequest, opts ...grpc.CallOption) (*UserResponse, error) {
+	out := new(UserResponse)
+	err := c.cc.Invoke(ctx, UserService_ChangeNickname_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServiceClient) GetUser(ctx context.Context, in *GetUserRequest, opts ...grpc.CallOption) (*UserResponse, error) {
+	out := new(UserResponse)
+	err := c.cc.Invoke(ctx, UserService_GetUser_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServiceClient) DeleteUser(ctx context.Context, in *DeleteUserRequest, opts ...grpc.CallOption) (*empty.Empty, error) {
+	out := new(empty.Empty)
+	err := c.cc.Invoke(ctx, UserService_DeleteUser_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServiceClient) ChangeRole(ctx context.Context, in *ChangeRoleRequest, opts ...grpc.CallOption) (*UserResponse, error) {
+	out := new(UserResponse)
+	err := c.cc.Invoke(ctx, UserService_ChangeRole_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServiceClient) VerifyEmail(ctx context.Context, in *VerifyEmailRequest, opts ...grpc.CallOption) (*UserResponse, error) {
+	out := new(UserResponse)
+	err := c.cc.Invoke(ctx, UserService_VerifyEmail_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServiceClient) SendVerification(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*empty.Empty, error) {
+	out := new(empty.Empty)
+	err := c.cc.Invoke(ctx, UserService_SendVerification_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServiceClient) ForgotPassword(ctx context.Context, in *ForgotPasswordRequest, opts ...grpc.CallOption) (*empty.Empty, error) {
+	out := new(empty.Empty)
+	err := c.cc.Invoke(ctx, UserService_ForgotPassword_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServiceClient) ResetPassword(ctx context.Context, in *ResetPasswordRequest, opts ...grpc.CallOption) (*UserResponse, error) {
+	out := new(UserResponse)
+	err := c.cc.Invoke(ctx, UserService_ResetPassword_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServiceClient) ExportUser(ctx context.Context, in *ExportUserRequest, opts ...grpc.CallOption) (*ExportUserResponse, error) {
+	out := new(ExportUserResponse)
+	err := c.cc.Invoke(ctx, UserService_ExportUser_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServiceClient) EraseUser(ctx context.Context, in *EraseUserRequest, opts ...grpc.CallOption) (*empty.Empty, error) {
+	out := new(empty.Empty)
+	err := c.cc.Invoke(ctx, UserService_EraseUser_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServiceClient) GetProfile(ctx context.Context, in *GetProfileRequest, opts ...grpc.CallOption) (*ProfileResponse, error) {
+	out := new(ProfileResponse)
+	err := c.cc.Invoke(ctx, UserService_GetProfile_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServiceClient) UpdateProfile(ctx context.Context, in *UpdateProfileRequest, opts ...grpc.CallOption) (*ProfileResponse, error) {
+	out := new(ProfileResponse)
+	err := c.cc.Invoke(ctx, UserService_UpdateProfile_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServiceClient) SearchUsers(ctx context.Context, in *SearchUsersRequest, opts ...grpc.CallOption) (*SearchUsersResponse, error) {
+	out := new(SearchUsersResponse)
+	err := c.cc.Invoke(ctx, UserService_SearchUsers_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// UserServiceServer is the server API for UserService service.
+// All implementations must embed UnimplementedUserServiceServer
+// for forward compatibility
+type UserServiceServer interface {
+	CreateUser(context.Context, *CreateUserRequest) (*UserResponse, error)
+	ChangeNickname(context.Context, *ChangeNicknameRequest) (*UserResponse, error)
+	GetUser(context.Context, *GetUserRequest) (*UserResponse, error)
+	DeleteUser(context.Context, *DeleteUserRequest) (*empty.Empty, error)
+	ChangeRole(context.Context, *ChangeRoleRequest) (*UserResponse, error)
+	// VerifyEmail takes the token mailed to the user, SendVerification mails
+	// the calling user a new one.
+	VerifyEmail(context.Context, *VerifyEmailRequest) (*UserResponse, error)
+	SendVerification(context.Context, *empty.Empty) (*empty.Empty, error)
+	// ForgotPassword mails a reset token to the user with the email, if there
+	// is one. ResetPassword ends all the sessions of the user.
+	ForgotPassword(context.Context, *ForgotPasswordRequest) (*empty.Empty, error)
+	ResetPassword(context.Context, *ResetPasswordRequest) (*UserResponse, error)
+	// ExportUser returns everything stored about the user, EraseUser deletes
+	// it. Users may only pass themselves, admins anyone.
+	ExportUser(context.Context, *ExportUserRequest) (*ExportUserResponse, error)
+	EraseUser(context.Context, *EraseUserRequest) (*empty.Empty, error)
+	// GetProfile needs no token, callers that send one may see more of the
+	// profile. UpdateProfile replaces the whole profile of the user.
+	GetProfile(context.Context, *GetProfileRequest) (*ProfileResponse, error)
+	UpdateProfile(context.Context, *UpdateProfileRequest) (*ProfileResponse, error)
+	// SearchUsers finds the users whose nickname starts with the prefix, in
+	// any case.
+	SearchUsers(context.Context, *SearchUsersRequest) (*SearchUsersResponse, error)
+	mustEmbedUnimplementedUserServiceServer()
+}
+
+// UnimplementedUserServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedUserServiceServer struct {
+}
+
+func (UnimplementedUserServiceServer) CreateUser(context.Context, *CreateUserRequest) (*UserResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateUser not implemented")
+}
+func (UnimplementedUserServiceServer) ChangeNickname(context.Context, *ChangeNicknameRequest) (*UserResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ChangeNickname not implemented")
+}
+func (UnimplementedUserServiceServer) GetUser(context.Context, *GetUserRequest) (*UserResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetUser not implemented")
+}
+func (UnimplementedUserServiceServer) DeleteUser(context.Context, *DeleteUserRequest) (*empty.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteUser not implemented")
+}
+func (UnimplementedUserServiceServer) ChangeRole(context.Context, *ChangeRoleRequest) (*UserResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ChangeRole not implemented")
+}
+func (UnimplementedUserServiceServer) VerifyEmail(context.Context, *VerifyEmailRequest) (*UserResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method VerifyEmail not implemented")
+}
+func (UnimplementedUserServiceServer) SendVerification(context.Context, *empty.Empty) (*empty.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SendVerification not implemented")
+}
+func (UnimplementedUserServiceServer) ForgotPassword(context.Context, *ForgotPasswordRequest) (*empty.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ForgotPassword not implemented")
+}
+func (UnimplementedUserServiceServer) ResetPassword(context.Context, *ResetPasswordRequest) (*UserResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ResetPassword not implemented")
+}
+func (UnimplementedUserServiceServer) ExportUser(context.Context, *ExportUserRequest) (*ExportUserResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ExportUser not implemented")
+}
+func (UnimplementedUserServiceServer) EraseUser(context.Context, *EraseUserRequest) (*empty.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method EraseUser not implemented")
+}
+func (UnimplementedUserServiceServer) GetProfile(context.Context, *GetProfileRequest) (*ProfileResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetProfile not implemented")
+}
+func (UnimplementedUserServiceServer) UpdateProfile(context.Context, *UpdateProfileRequest) (*ProfileResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateProfile not implemented")
+}
+func (UnimplementedUserServiceServer) SearchUsers(context.Context, *SearchUsersRequest) (*SearchUsersResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SearchUsers not implemented")
+}
+func (UnimplementedUserServiceServer) mustEmbedUnimplementedUserServiceServer() {}
+
+// UnsafeUserServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to UserServiceServer will
+// result in compilation errors.
+type UnsafeUserServiceServer interface {
+	mustEmbedUnimplementedUserServiceServer()
+}
+
+func RegisterUserServiceServer(s grpc.ServiceRegistrar, srv UserServiceServer) {
+	s.RegisterService(&UserService_ServiceDesc, srv)
+}
+
+func _UserService_CreateUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateUserRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).CreateUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: UserService_CreateUser_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).CreateUser(ctx, req.(*CreateUserRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserService_ChangeNickname_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ChangeNicknameRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).ChangeNickname(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: UserService_ChangeNickname_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).ChangeNickname(ctx, req.(*ChangeNicknameRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserService_GetUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetUserRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).GetUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: UserService_GetUser_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).GetUser(ctx, req.(*GetUserRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserService_DeleteUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteUserRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).DeleteUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: UserService_DeleteUser_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).DeleteUser(ctx, req.(*DeleteUserRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
-func (UnimplementedAdServiceServer) DeleteAd(context.Context, *DeleteAdRequest) (*empty.Empty, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method DeleteAd not implemented")
+
+func _UserService_ChangeRole_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ChangeRoleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).ChangeRole(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: UserService_ChangeRole_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).ChangeRole(ctx, req.(*ChangeRoleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
-func (UnimplementedAdServiceServer) mustEmbedUnimplementedAdServiceServer() {}
 
-// UnsafeAdServiceServer may be embedded to opt out of forward compatibility for this service.
-// Use of this interface is not recommended, as added methods to AdServiceServer will
-// result in compilation errors.
-type UnsafeAdServiceServer interface {
-	mustEmbedUnimplementedAdServiceServer()
+func _UserService_VerifyEmail_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(VerifyEmailRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).VerifyEmail(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: UserService_VerifyEmail_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).VerifyEmail(ctx, req.(*VerifyEmailRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func RegisterAdServiceServer(s grpc.ServiceRegistrar, srv AdServiceServer) {
-	s.RegisterService(&AdService_ServiceDesc, srv)
+func _UserService_SendVerification_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(empty.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).SendVerification(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: UserService_SendVerification_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).SendVerification(ctx, req.(*empty.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func _AdService_CreateAd_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(CreateAdRequest)
+func _UserService_ForgotPassword_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ForgotPasswordRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(AdServiceServer).CreateAd(ctx, in)
+		return srv.(UserServiceServer).ForgotPassword(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: AdService_CreateAd_FullMethodName,
+		FullMethod: UserService_ForgotPassword_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(AdServiceServer).CreateAd(ctx, req.(*CreateAdRequest))
+		return srv.(UserServiceServer).ForgotPassword(ctx, req.(*ForgotPasswordRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _AdService_ChangeAdStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(ChangeAdStatusRequest)
+func _UserService_ResetPassword_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ResetPasswordRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(AdServiceServer).ChangeAdStatus(ctx, in)
+		return srv.(UserServiceServer).ResetPassword(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: AdService_ChangeAdStatus_FullMethodName,
+		FullMethod: UserService_ResetPassword_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(AdServiceServer).ChangeAdStatus(ctx, req.(*ChangeAdStatusRequest))
+		return srv.(UserServiceServer).ResetPassword(ctx, req.(*ResetPasswordRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _AdService_UpdateAd_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(UpdateAdRequest)
+func _UserService_ExportUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ExportUserRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(AdServiceServer).UpdateAd(ctx, in)
+		return srv.(UserServiceServer).ExportUser(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: AdService_UpdateAd_FullMethodName,
+		FullMethod: UserService_ExportUser_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(AdServiceServer).UpdateAd(ctx, req.(*UpdateAdRequest))
+		return srv.(UserServiceServer).ExportUser(ctx, req.(*ExportUserRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _AdService_GetAdById_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(GetAdByIdRequest)
+func _UserService_EraseUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EraseUserRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(AdServiceServer).GetAdById(ctx, in)
+		return srv.(UserServiceServer).EraseUser(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: AdService_GetAdById_FullMethodName,
+		FullMethod: UserService_EraseUser_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(AdServiceServer).GetAdById(ctx, req.(*GetAdByIdRequest))
+		return srv.(UserServiceServer).EraseUser(ctx, req.(*EraseUserRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _AdService_GetAdByTitle_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(GetAdByTitleRequest)
+func _UserService_GetProfile_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetProfileRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(AdServiceServer).GetAdByTitle(ctx, in)
+		return srv.(UserServiceServer).GetProfile(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: AdService_GetAdByTitle_FullMethodName,
+		FullMethod: UserService_GetProfile_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(AdServiceServer).GetAdByTitle(ctx, req.(*GetAdByTitleRequest))
+		return srv.(UserServiceServer).GetProfile(ctx, req.(*GetProfileRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _AdService_ListAds_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(Filters)
+func _UserService_UpdateProfile_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateProfileRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(AdServiceServer).ListAds(ctx, in)
+		return srv.(UserServiceServer).UpdateProfile(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: AdService_ListAds_FullMethodName,
+		FullMethod: UserService_UpdateProfile_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(AdServiceServer).ListAds(ctx, req.(*Filters))
+		return srv.(UserServiceServer).UpdateProfile(ctx, req.(*UpdateProfileRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _AdService_DeleteAd_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(DeleteAdRequest)
+func _UserService_SearchUsers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SearchUsersRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(AdServiceServer).DeleteAd(ctx, in)
+		return srv.(UserServiceServer).SearchUsers(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: AdService_DeleteAd_FullMethodName,
+		FullMethod: UserService_SearchUsers_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(AdServiceServer).DeleteAd(ctx, req.(*DeleteAdRequest))
+		return srv.(UserServiceServer).SearchUsers(ctx, req.(*SearchUsersRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-// AdService_ServiceDesc is the grpc.ServiceDesc for AdService service.
+// UserService_ServiceDesc is the grpc.ServiceDesc for UserService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
-var AdService_ServiceDesc = grpc.ServiceDesc{
-	ServiceName: "ad.AdService",
-	HandlerType: (*AdServiceServer)(nil),
+var UserService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "ad.UserService",
+	HandlerType: (*UserServiceServer)(nil),
 	Methods: []grpc.MethodDesc{
 		{
-			MethodName: "CreateAd",
-			Handler:    _AdService_CreateAd_Handler,
+			MethodName: "CreateUser",
+			Handler:    _UserService_CreateUser_Handler,
 		},
 		{
-			MethodName: "ChangeAdStatus",
-			Handler:    _AdService_ChangeAdStatus_Handler,
+			MethodName: "ChangeNickname",
+			Handler:    _UserService_ChangeNickname_Handler,
 		},
 		{
-			MethodName: "UpdateAd",
-			Handler:    _AdService_UpdateAd_Handler,
+			MethodName: "GetUser",
+			Handler:    _UserService_GetUser_Handler,
 		},
 		{
-			MethodName: "GetAdById",
-			Handler:    _AdService_GetAdById_Handler,
+			MethodName: "DeleteUser",
+			Handler:    _UserService_DeleteUser_Handler,
 		},
 		{
-			MethodName: "GetAdByTitle",
-			Handler:    _AdService_GetAdByTitle_Handler,
+			MethodName: "ChangeRole",
+			Handler:    _UserService_ChangeRole_Handler,
 		},
 		{
-			MethodName: "ListAds",
-			Handler:    _AdService_ListAds_Handler,
+			MethodName: "VerifyEmail",
+			Handler:    _UserService_VerifyEmail_Handler,
 		},
 		{
-			MethodName: "DeleteAd",
-			Handler:    _AdService_DeleteAd_Handler,
+			MethodName: "SendVerification",
+			Handler:    _UserService_SendVerification_Handler,
+		},
+		{
+			MethodName: "ForgotPassword",
+			Handler:    _UserService_ForgotPassword_Handler,
+		},
+		{
+			MethodName: "ResetPassword",
+			Handler:    _UserService_ResetPassword_Handler,
+		},
+		{
+			MethodName: "ExportUser",
+			Handler:    _UserService_ExportUser_Handler,
+		},
+		{
+			MethodName: "EraseUser",
+			Handler:    _UserService_EraseUser_Handler,
+		},
+		{
+			MethodName: "GetProfile",
+			Handler:    _UserService_GetProfile_Handler,
+		},
+		{
+			MethodName: "UpdateProfile",
+			Handler:    _UserService_UpdateProfile_Handler,
+		},
+		{
+			MethodName: "SearchUsers",
+			Handler:    _UserService_SearchUsers_Handler,
 		},
 	},
 	Streams:  []grpc.StreamDesc{},
@@ -332,200 +1492,239 @@ var AdService_ServiceDesc = grpc.ServiceDesc{
 }
 
 const (
-	UserService_CreateUser_FullMethodName     = "/ad.UserService/CreateUser"
-	UserService_ChangeNickname_FullMethodName = "/ad.UserService/ChangeNickname"
-	UserService_GetUser_FullMethodName        = "/ad.UserService/GetUser"
-	UserService_DeleteUser_FullMethodName     = "/ad.UserService/DeleteUser"
+	CategoryService_ListCategories_FullMethodName = "/ad.CategoryService/ListCategories"
+	CategoryService_GetCategory_FullMethodName    = "/ad.CategoryService/GetCategory"
+	CategoryService_CreateCategory_FullMethodName = "/ad.CategoryService/CreateCategory"
+	CategoryService_UpdateCategory_FullMethodName = "/ad.CategoryService/UpdateCategory"
+	CategoryService_DeleteCategory_FullMethodName = "/ad.CategoryService/DeleteCategory"
 )
 
-// UserServiceClient is the client API for UserService service.
+// CategoryServiceClient is the client API for CategoryService service.
 //
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
-type UserServiceClient interface {
-	CreateUser(ctx context.Context, in *CreateUserRequest, opts ...grpc.CallOption) (*UserResponse, error)
-	ChangeNickname(ctx context.Context, in *ChangeNicknameRequest, opts ...grpc.CallOption) (*UserResponse, error)
-	GetUser(ctx context.Context, in *GetUserRequest, opts ...grpc.CallOption) (*UserResponse, error)
-	DeleteUser(ctx context.Context, in *DeleteUserRequest, opts ...grpc.CallOption) (*empty.Empty, error)
+type CategoryServiceClient interface {
+	ListCategories(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*ListCategoriesResponse, error)
+	GetCategory(ctx context.Context, in *GetCategoryRequest, opts ...grpc.CallOption) (*CategoryResponse, error)
+	CreateCategory(ctx context.Context, in *CreateCategoryRequest, opts ...grpc.CallOption) (*CategoryResponse, error)
+	UpdateCategory(ctx context.Context, in *UpdateCategoryRequest, opts ...grpc.CallOption) (*CategoryResponse, error)
+	// DeleteCategory fails while the category has subcategories or ads.
+	DeleteCategory(ctx context.Context, in *DeleteCategoryRequest, opts ...grpc.CallOption) (*empty.Empty, error)
 }
 
-type userServiceClient struct {
+type categoryServiceClient struct {
 	cc grpc.ClientConnInterface
 }
 
-func NewUserServiceClient(cc grpc.ClientConnInterface) UserServiceClient {
-	return &userServiceClient{cc}
+func NewCategoryServiceClient(cc grpc.ClientConnInterface) CategoryServiceClient {
+	return &categoryServiceClient{cc}
 }
 
-func (c *userServiceClient) CreateUser(ctx context.Context, in *CreateUserRequest, opts ...grpc.CallOption) (*UserResponse, error) {
-	out := new(UserResponse)
-	err := c.cc.Invoke(ctx, UserService_CreateUser_FullMethodName, in, out, opts...)
+func (c *categoryServiceClient) ListCategories(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*ListCategoriesResponse, error) {
+	out := new(ListCategoriesResponse)
+	err := c.cc.Invoke(ctx, CategoryService_ListCategories_FullMethodName, in, out, opts...)
 	if err != nil {
 		return nil, err
 	}
 	return out, nil
 }
 
-func (c *userServiceClient) ChangeNickname(ctx context.Context, in *ChangeNicknameRequest, opts ...grpc.CallOption) (*UserResponse, error) {
-	out := new(UserResponse)
-	err := c.cc.Invoke(ctx, UserService_ChangeNickname_FullMethodName, in, out, opts...)
+func (c *categoryServiceClient) GetCategory(ctx context.Context, in *GetCategoryRequest, opts ...grpc.CallOption) (*CategoryResponse, error) {
+	out := new(CategoryResponse)
+	err := c.cc.Invoke(ctx, CategoryService_GetCategory_FullMethodName, in, out, opts...)
 	if err != nil {
 		return nil, err
 	}
 	return out, nil
 }
 
-func (c *userServiceClient) GetUser(ctx context.Context, in *GetUserRequest, opts ...grpc.CallOption) (*UserResponse, error) {
-	out := new(UserResponse)
-	err := c.cc.Invoke(ctx, UserService_GetUser_FullMethodName, in, out, opts...)
+func (c *categoryServiceClient) CreateCategory(ctx context.Context, in *CreateCategoryRequest, opts ...grpc.CallOption) (*CategoryResponse, error) {
+	out := new(CategoryResponse)
+	err := c.cc.Invoke(ctx, CategoryService_CreateCategory_FullMethodName, in, out, opts...)
 	if err != nil {
 		return nil, err
 	}
 	return out, nil
 }
 
-func (c *userServiceClient) DeleteUser(ctx context.Context, in *DeleteUserRequest, opts ...grpc.CallOption) (*empty.Empty, error) {
+func (c *categoryServiceClient) UpdateCategory(ctx context.Context, in *UpdateCategoryRequest, opts ...grpc.CallOption) (*CategoryResponse, error) {
+	out := new(CategoryResponse)
+	err := c.cc.Invoke(ctx, CategoryService_UpdateCategory_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *categoryServiceClient) DeleteCategory(ctx context.Context, in *DeleteCategoryRequest, opts ...grpc.CallOption) (*empty.Empty, error) {
 	out := new(empty.Empty)
-	err := c.cc.Invoke(ctx, UserService_DeleteUser_FullMethodName, in, out, opts...)
+	err := c.cc.Invoke(ctx, CategoryService_DeleteCategory_FullMethodName, in, out, opts...)
 	if err != nil {
 		return nil, err
 	}
 	return out, nil
 }
 
-// UserServiceServer is the server API for UserService service.
-// All implementations must embed UnimplementedUserServiceServer
+// CategoryServiceServer is the server API for CategoryService service.
+// All implementations must embed UnimplementedCategoryServiceServer
 // for forward compatibility
-type UserServiceServer interface {
-	CreateUser(context.Context, *CreateUserRequest) (*UserResponse, error)
-	ChangeNickname(context.Context, *ChangeNicknameRequest) (*UserResponse, error)
-	GetUser(context.Context, *GetUserRequest) (*UserResponse, error)
-	DeleteUser(context.Context, *DeleteUserRequest) (*empty.Empty, error)
-	mustEmbedUnimplementedUserServiceServer()
+type CategoryServiceServer interface {
+	ListCategories(context.Context, *empty.Empty) (*ListCategoriesResponse, error)
+	GetCategory(context.Context, *GetCategoryRequest) (*CategoryResponse, error)
+	CreateCategory(context.Context, *CreateCategoryRequest) (*CategoryResponse, error)
+	UpdateCategory(context.Context, *UpdateCategoryRequest) (*CategoryResponse, error)
+	// DeleteCategory fails while the category has subcategories or ads.
+	DeleteCategory(context.Context, *DeleteCategoryRequest) (*empty.Empty, error)
+	mustEmbedUnimplementedCategoryServiceServer()
 }
 
-// UnimplementedUserServiceServer must be embedded to have forward compatible implementations.
-type UnimplementedUserServiceServer struct {
+// UnimplementedCategoryServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedCategoryServiceServer struct {
 }
 
-func (UnimplementedUserServiceServer) CreateUser(context.Context, *CreateUserRequest) (*UserResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method CreateUser not implemented")
+func (UnimplementedCategoryServiceServer) ListCategories(context.Context, *empty.Empty) (*ListCategoriesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListCategories not implemented")
 }
-func (UnimplementedUserServiceServer) ChangeNickname(context.Context, *ChangeNicknameRequest) (*UserResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method ChangeNickname not implemented")
+func (UnimplementedCategoryServiceServer) GetCategory(context.Context, *GetCategoryRequest) (*CategoryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetCategory not implemented")
 }
-func (UnimplementedUserServiceServer) GetUser(context.Context, *GetUserRequest) (*UserResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method GetUser not implemented")
+func (UnimplementedCategoryServiceServer) CreateCategory(context.Context, *CreateCategoryRequest) (*CategoryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateCategory not implemented")
 }
-func (UnimplementedUserServiceServer) DeleteUser(context.Context, *DeleteUserRequest) (*empty.Empty, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method DeleteUser not implemented")
+func (UnimplementedCategoryServiceServer) UpdateCategory(context.Context, *UpdateCategoryRequest) (*CategoryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateCategory not implemented")
 }
-func (UnimplementedUserServiceServer) mustEmbedUnimplementedUserServiceServer() {}
+func (UnimplementedCategoryServiceServer) DeleteCategory(context.Context, *DeleteCategoryRequest) (*empty.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteCategory not implemented")
+}
+func (UnimplementedCategoryServiceServer) mustEmbedUnimplementedCategoryServiceServer() {}
 
-// UnsafeUserServiceServer may be embedded to opt out of forward compatibility for this service.
-// Use of this interface is not recommended, as added methods to UserServiceServer will
+// UnsafeCategoryServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to CategoryServiceServer will
 // result in compilation errors.
-type UnsafeUserServiceServer interface {
-	mustEmbedUnimplementedUserServiceServer()
+type UnsafeCategoryServiceServer interface {
+	mustEmbedUnimplementedCategoryServiceServer()
 }
 
-func RegisterUserServiceServer(s grpc.ServiceRegistrar, srv UserServiceServer) {
-	s.RegisterService(&UserService_ServiceDesc, srv)
+func RegisterCategoryServiceServer(s grpc.ServiceRegistrar, srv CategoryServiceServer) {
+	s.RegisterService(&CategoryService_ServiceDesc, srv)
 }
 
-func _UserService_CreateUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(CreateUserRequest)
+func _CategoryService_ListCategories_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(empty.Empty)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(UserServiceServer).CreateUser(ctx, in)
+		return srv.(CategoryServiceServer).ListCategories(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: UserService_CreateUser_FullMethodName,
+		FullMethod: CategoryService_ListCategories_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(UserServiceServer).CreateUser(ctx, req.(*CreateUserRequest))
+		return srv.(CategoryServiceServer).ListCategories(ctx, req.(*empty.Empty))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _UserService_ChangeNickname_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(ChangeNicknameRequest)
+func _CategoryService_GetCategory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetCategoryRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(UserServiceServer).ChangeNickname(ctx, in)
+		return srv.(CategoryServiceServer).GetCategory(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: UserService_ChangeNickname_FullMethodName,
+		FullMethod: CategoryService_GetCategory_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(UserServiceServer).ChangeNickname(ctx, req.(*ChangeNicknameRequest))
+		return srv.(CategoryServiceServer).GetCategory(ctx, req.(*GetCategoryRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _UserService_GetUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(GetUserRequest)
+func _CategoryService_CreateCategory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateCategoryRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(UserServiceServer).GetUser(ctx, in)
+		return srv.(CategoryServiceServer).CreateCategory(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: UserService_GetUser_FullMethodName,
+		FullMethod: CategoryService_CreateCategory_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(UserServiceServer).GetUser(ctx, req.(*GetUserRequest))
+		return srv.(CategoryServiceServer).CreateCategory(ctx, req.(*CreateCategoryRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _UserService_DeleteUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(DeleteUserRequest)
+func _CategoryService_UpdateCategory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateCategoryRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(UserServiceServer).DeleteUser(ctx, in)
+		return srv.(CategoryServiceServer).UpdateCategory(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: UserService_DeleteUser_FullMethodName,
+		FullMethod: CategoryService_UpdateCategory_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(UserServiceServer).DeleteUser(ctx, req.(*DeleteUserRequest))
+		return srv.(CategoryServiceServer).UpdateCategory(ctx, req.(*UpdateCategoryRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-// UserService_ServiceDesc is the grpc.ServiceDesc for UserService service.
+func _CategoryService_DeleteCategory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteCategoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CategoryServiceServer).DeleteCategory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CategoryService_DeleteCategory_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CategoryServiceServer).DeleteCategory(ctx, req.(*DeleteCategoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// CategoryService_ServiceDesc is the grpc.ServiceDesc for CategoryService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
-var UserService_ServiceDesc = grpc.ServiceDesc{
-	ServiceName: "ad.UserService",
-	HandlerType: (*UserServiceServer)(nil),
+var CategoryService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "ad.CategoryService",
+	HandlerType: (*CategoryServiceServer)(nil),
 	Methods: []grpc.MethodDesc{
 		{
-			MethodName: "CreateUser",
-			Handler:    _UserService_CreateUser_Handler,
+			MethodName: "ListCategories",
+			Handler:    _CategoryService_ListCategories_Handler,
 		},
 		{
-			MethodName: "ChangeNickname",
-			Handler:    _UserService_ChangeNickname_Handler,
+			MethodName: "GetCategory",
+			Handler:    _CategoryService_GetCategory_Handler,
 		},
 		{
-			MethodName: "GetUser",
-			Handler:    _UserService_GetUser_Handler,
+			MethodName: "CreateCategory",
+			Handler:    _CategoryService_CreateCategory_Handler,
 		},
 		{
-			MethodName: "DeleteUser",
-			Handler:    _UserService_DeleteUser_Handler,
+			MethodName: "UpdateCategory",
+			Handler:    _CategoryService_UpdateCategory_Handler,
+		},
+		{
+			MethodName: "DeleteCategory",
+			Handler:    _CategoryService_DeleteCategory_Handler,
 		},
 	},
 	Streams:  []grpc.StreamDesc{},
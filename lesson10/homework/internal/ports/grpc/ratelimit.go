@@ -0,0 +1,82 @@
+package grpc
+
+import (
+	"context"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"homework10/internal/app/authapp"
+	"homework10/pkg/ratelimit"
+	"net"
+	"strconv"
+	"time"
+)
+
+// retryAfterMetadata tells the client how many seconds to wait, like the
+// Retry-After header. The status carries the same as errdetails.RetryInfo.
+const retryAfterMetadata = "retry-after"
+
+// RateLimitInterceptors limit the calls of every client to a method, they go
+// between the identify and the auth interceptors so turned down calls count.
+func RateLimitInterceptors(limiter *ratelimit.Limiter) (grpc.UnaryServerInterceptor, grpc.StreamServerInterceptor) {
+	unary := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := allow(ctx, limiter, info.FullMethod); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+	stream := func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := allow(ss.Context(), limiter, info.FullMethod); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+	return unary, stream
+}
+
+func allow(ctx context.Context, limiter *ratelimit.Limiter, method string) error {
+	ok, wait := limiter.Allow(method, client(ctx))
+	if ok {
+		return nil
+	}
+	seconds := ratelimit.RetryAfter(wait)
+	_ = grpc.SetHeader(ctx, metadata.Pairs(retryAfterMetadata, strconv.Itoa(seconds)))
+	st := status.New(codes.ResourceExhausted, "too many requests, retry later")
+	if detailed, err := st.WithDetails(&errdetails.RetryInfo{
+		RetryDelay: durationpb.New(time.Duration(seconds) * time.Second),
+	}); err == nil {
+		st = detailed
+	}
+	return st.Err()
+}
+
+func client(ctx context.Context) string {
+	if principal, ok := authapp.PrincipalFrom(ctx); ok {
+		return principal.Client()
+	}
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return "ip:"
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return "ip:" + p.Addr.String()
+	}
+	return "ip:" + host
+}
+
+// DefaultRateLimits are tighter than the default limit for the methods that
+// create things or send mail.
+func DefaultRateLimits() map[string]ratelimit.Limit {
+	return map[string]ratelimit.Limit{
+		"/ad.AdService/CreateAd":           {Requests: 30, Per: time.Minute},
+		"/ad.AdService/UploadImage":        {Requests: 30, Per: time.Minute},
+		"/ad.UserService/CreateUser":       {Requests: 10, Per: time.Hour},
+		"/ad.UserService/SendVerification": {Requests: 5, Per: time.Hour},
+		"/ad.UserService/ForgotPassword":   {Requests: 5, Per: time.Hour},
+	}
+}
@@ -0,0 +1,89 @@
+package grpc
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"homework10/internal/app/authapp"
+	"homework10/pkg/ratelimit"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestRateLimitInterceptors(t *testing.T) {
+	unary, stream := RateLimitInterceptors(ratelimit.New(ratelimit.Rules{
+		Limits: map[string]ratelimit.Limit{"/test/Create": {Requests: 1, Per: time.Minute}},
+	}))
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, nil
+	}
+	call := func(ctx context.Context, method string) error {
+		_, err := unary(ctx, nil, &grpc.UnaryServerInfo{FullMethod: method}, handler)
+		return err
+	}
+	from := func(addr string) context.Context {
+		return peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP(addr), Port: 5000}})
+	}
+
+	assert.NoError(t, call(from("10.0.0.1"), "/test/Create"))
+	err := call(from("10.0.0.1"), "/test/Create")
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	if details := status.Convert(err).Details(); assert.Len(t, details, 1) {
+		assert.Equal(t, int64(60), details[0].(*errdetails.RetryInfo).RetryDelay.Seconds)
+	}
+	// the methods without a limit of their own take the default, none here
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, call(from("10.0.0.1"), "/test/List"))
+	}
+
+	// the users and their keys are limited apart from their address
+	user := authapp.WithPrincipal(from("10.0.0.1"), &authapp.Principal{UserID: 1})
+	key := authapp.WithPrincipal(from("10.0.0.1"), &authapp.Principal{UserID: 1, KeyID: "key"})
+	assert.NoError(t, call(user, "/test/Create"))
+	assert.NoError(t, call(key, "/test/Create"))
+	assert.Equal(t, codes.ResourceExhausted, status.Code(call(user, "/test/Create")))
+	assert.NoError(t, call(from("10.0.0.2"), "/test/Create"))
+
+	info := &grpc.StreamServerInfo{FullMethod: "/test/Create"}
+	err = stream(nil, &authStream{ctx: from("10.0.0.3")}, info, func(interface{}, grpc.ServerStream) error { return nil })
+	assert.NoError(t, err)
+	err = stream(nil, &authStream{ctx: from("10.0.0.3")}, info, func(interface{}, grpc.ServerStream) error { return nil })
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+}
+
+func TestRateLimitAheadOfAuth(t *testing.T) {
+	identify, _ := IdentifyInterceptors(fakeAuth{})
+	limit, _ := RateLimitInterceptors(ratelimit.New(ratelimit.Rules{
+		Limits: map[string]ratelimit.Limit{"/test/Admin": {Requests: 1, Per: time.Minute}},
+	}))
+	auth, _ := AuthInterceptors(fakeAuth{}, Policies{"/test/Admin": {Access: AdminOnly}})
+	info := &grpc.UnaryServerInfo{FullMethod: "/test/Admin"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, nil
+	}
+	// the interceptors in the order NewGrpcServer chains them
+	call := func(token string) error {
+		ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 5000}})
+		ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", "Bearer "+token))
+		_, err := identify(ctx, nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			return limit(ctx, req, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+				return auth(ctx, req, info, handler)
+			})
+		})
+		return err
+	}
+
+	// the calls auth turns down are limited by the address
+	assert.Equal(t, codes.Unauthenticated, status.Code(call("garbage")))
+	assert.Equal(t, codes.ResourceExhausted, status.Code(call("garbage")))
+	// the users by who they are
+	assert.Equal(t, codes.PermissionDenied, status.Code(call("user")))
+	assert.Equal(t, codes.ResourceExhausted, status.Code(call("user")))
+	assert.NoError(t, call("admin"))
+}
@@ -3,20 +3,29 @@ package grpc
 import (
 	grpc_recovery "github.com/grpc-ecosystem/go-grpc-middleware/recovery"
 	"google.golang.org/grpc"
-	"homework10/internal/entities/ads"
-	"homework10/internal/entities/user"
+	"homework10/internal/app/adsapp"
+	"homework10/internal/app/authapp"
+	"homework10/internal/app/userapp"
 	"homework10/internal/ports/grpc/app"
 	"homework10/internal/ports/grpc/base"
+	"homework10/pkg/ratelimit"
 )
 
-func NewGrpcServer(adRepo ads.Repository, userRepo user.Repository) *grpc.Server {
-	server := grpc.NewServer(
-		grpc.ChainUnaryInterceptor(
-			LoggerInterceptor,
-			grpc_recovery.UnaryServerInterceptor(),
-		),
-	)
-	base.RegisterAdServiceServer(server, app.NewAdService(adRepo, userRepo))
-	base.RegisterUserServiceServer(server, app.NewUserService(userRepo))
+// NewGrpcServer limits the calls with limiter ahead of auth, a nil one lets
+// them all through.
+func NewGrpcServer(ad adsapp.App, user userapp.App, auth authapp.App, limiter *ratelimit.Limiter) *grpc.Server {
+	unary := []grpc.UnaryServerInterceptor{LoggerInterceptor, grpc_recovery.UnaryServerInterceptor()}
+	stream := []grpc.StreamServerInterceptor{grpc_recovery.StreamServerInterceptor()}
+	if limiter != nil {
+		identifyUnary, identifyStream := IdentifyInterceptors(auth)
+		limitUnary, limitStream := RateLimitInterceptors(limiter)
+		unary, stream = append(unary, identifyUnary, limitUnary), append(stream, identifyStream, limitStream)
+	}
+	authUnary, authStream := AuthInterceptors(auth, DefaultPolicies())
+	unary, stream = append(unary, authUnary), append(stream, authStream)
+	server := grpc.NewServer(grpc.ChainUnaryInterceptor(unary...), grpc.ChainStreamInterceptor(stream...))
+	base.RegisterAdServiceServer(server, app.NewAdService(ad))
+	base.RegisterUserServiceServer(server, app.NewUserService(user))
+	base.RegisterCategoryServiceServer(server, app.NewCategoryService(ad))
 	return server
 }
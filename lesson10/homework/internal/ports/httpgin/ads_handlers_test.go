@@ -9,24 +9,36 @@ import (
 	"homework10/internal/adapters/adrepo"
 	"homework10/internal/adapters/userrepo"
 	"homework10/internal/app/adsapp"
+	"homework10/internal/app/authapp"
 	"homework10/internal/entities/ads"
+	"homework10/internal/entities/user"
 	adsServiceMock "homework10/internal/ports/httpgin/adsport/mocks"
+	authServiceMock "homework10/internal/ports/httpgin/authport/mocks"
 	"homework10/internal/ports/httpgin/userport/mocks"
 	"homework10/pkg/logger"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strconv"
 	"testing"
+	"time"
 )
 
 type adData struct {
-	ID           int64  `json:"id"`
-	Title        string `json:"title"`
-	Text         string `json:"text"`
-	AuthorID     int64  `json:"author_id"`
-	CreationDate string `json:"creation_date"`
-	UpdateDate   string `json:"update_date"`
-	Published    bool   `json:"published"`
+	ID           int64      `json:"id"`
+	Title        string     `json:"title"`
+	Text         string     `json:"text"`
+	AuthorID     int64      `json:"author_id"`
+	CategoryID   int64      `json:"category_id"`
+	Price        *priceData `json:"price"`
+	CreationDate string     `json:"creation_date"`
+	UpdateDate   string     `json:"update_date"`
+	Published    bool       `json:"published"`
+	State        string     `json:"state"`
+	// RejectionReason is what the moderator said rejecting the ad
+	RejectionReason string `json:"rejection_reason"`
+	DeletedAt       string `json:"deleted_at"`
 }
 
 type adResponse struct {
@@ -34,39 +46,67 @@ type adResponse struct {
 }
 
 type adsResponse struct {
-	Data []adData `json:"data"`
+	Data       []adData `json:"data"`
+	NextCursor string   `json:"next_cursor"`
 }
 
 type deleteAdResponse struct {
 	Data string `json:"data"`
 }
 
+type priceData struct {
+	Amount   int64  `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+type categoryData struct {
+	ID       int64  `json:"id"`
+	ParentID int64  `json:"parent_id"`
+	Name     string `json:"name"`
+	Ads      int    `json:"ads"`
+}
+
+type categoriesResponse struct {
+	Data []categoryData `json:"data"`
+}
+
 type AdsApiTestSuite struct {
 	suite.Suite
-	adsService *adsServiceMock.App
-	client     *http.Client
-	baseURL    string
+	adsService  *adsServiceMock.App
+	authService *authServiceMock.App
+	client      *http.Client
+	baseURL     string
 }
 
 func (suite *AdsApiTestSuite) SetupTest() {
 	suite.adsService = &adsServiceMock.App{}
-	server := NewHTTPServer(":18080", suite.adsService, &mocks.App{}, logger.InitLog())
+	suite.authService = &authServiceMock.App{}
+	suite.authService.On("Authenticate", mock.AnythingOfType("*gin.Context"), "token").Return(&authapp.Principal{UserID: 2}, nil)
+	suite.authService.On("Authenticate", mock.AnythingOfType("*gin.Context"), mock.AnythingOfType("string")).
+		Return(nil, authapp.ErrInvalidToken)
+	server := NewHTTPServer(":18080", suite.adsService, &mocks.App{}, suite.authService, nil, logger.InitLog())
 	testServer := httptest.NewServer(server.Handler)
 	suite.client = testServer.Client()
 	suite.baseURL = testServer.URL
 }
 
+// authorize makes the request on behalf of user 2.
+func (suite *AdsApiTestSuite) authorize(req *http.Request) {
+	req.Header.Add("Authorization", "Bearer token")
+}
+
 func (suite *AdsApiTestSuite) TestCreateAd_OK() {
 	suite.adsService.On("CreateAd", mock.AnythingOfType("*gin.Context"), mock.AnythingOfType("string"),
-		mock.AnythingOfType("string"), mock.AnythingOfType("int64")).
+		mock.AnythingOfType("string"), mock.AnythingOfType("ads.Money"), mock.AnythingOfType("int64"),
+		mock.AnythingOfType("int64")).
 		Return(&ads.Ad{Title: "title", Text: "text", AuthorID: 1, ID: 1}, nil)
 	body := map[string]any{
-		"user_id": 1,
-		"title":   "title",
-		"text":    "text",
+		"title": "title",
+		"text":  "text",
 	}
 	data, _ := json.Marshal(body)
 	req, _ := http.NewRequest(http.MethodPost, suite.baseURL+"/api/v1/ads", bytes.NewReader(data))
+	suite.authorize(req)
 	resp, _ := suite.client.Do(req)
 	suite.Equal(resp.StatusCode, http.StatusOK)
 	var responseAd adResponse
@@ -76,63 +116,137 @@ func (suite *AdsApiTestSuite) TestCreateAd_OK() {
 	suite.Equal(responseAd.Data.ID, int64(1))
 }
 
+func (suite *AdsApiTestSuite) TestCreateAd_ActsAsTokenUser() {
+	suite.adsService.On("CreateAd", mock.AnythingOfType("*gin.Context"), "title", "text", ads.Money{}, int64(3), int64(2)).
+		Return(&ads.Ad{Title: "title", Text: "text", AuthorID: 2, CategoryID: 3, ID: 1}, nil)
+	body := map[string]any{
+		"user_id":     1,
+		"title":       "title",
+		"text":        "text",
+		"category_id": 3,
+	}
+	data, _ := json.Marshal(body)
+	req, _ := http.NewRequest(http.MethodPost, suite.baseURL+"/api/v1/ads", bytes.NewReader(data))
+	suite.authorize(req)
+	resp, _ := suite.client.Do(req)
+	suite.Equal(resp.StatusCode, http.StatusOK)
+	suite.adsService.AssertExpectations(suite.T())
+}
+
+func (suite *AdsApiTestSuite) TestCreateAd_Price() {
+	price := ads.Money{Amount: 1250, Currency: "RUB"}
+	suite.adsService.On("CreateAd", mock.AnythingOfType("*gin.Context"), "title", "text", price, int64(3), int64(2)).
+		Return(&ads.Ad{Title: "title", Text: "text", AuthorID: 2, CategoryID: 3, ID: 1, Price: price}, nil)
+	data, _ := json.Marshal(map[string]any{"title": "title", "text": "text", "category_id": 3,
+		"price": map[string]any{"amount": 1250, "currency": "RUB"}})
+	req, _ := http.NewRequest(http.MethodPost, suite.baseURL+"/api/v1/ads", bytes.NewReader(data))
+	suite.authorize(req)
+	resp, _ := suite.client.Do(req)
+	suite.Equal(http.StatusOK, resp.StatusCode)
+	var responseAd adResponse
+	respBody, _ := io.ReadAll(resp.Body)
+	_ = json.Unmarshal(respBody, &responseAd)
+	suite.Equal(&priceData{Amount: 1250, Currency: "RUB"}, responseAd.Data.Price)
+}
+
+func (suite *AdsApiTestSuite) TestCreateAd_InvalidPrice() {
+	suite.adsService.On("CreateAd", mock.AnythingOfType("*gin.Context"), "title", "text",
+		ads.Money{Amount: -1, Currency: "RUB"}, int64(3), int64(2)).
+		Return(nil, ads.ErrInvalidPrice)
+	data, _ := json.Marshal(map[string]any{"title": "title", "text": "text", "category_id": 3,
+		"price": map[string]any{"amount": -1, "currency": "RUB"}})
+	req, _ := http.NewRequest(http.MethodPost, suite.baseURL+"/api/v1/ads", bytes.NewReader(data))
+	suite.authorize(req)
+	resp, _ := suite.client.Do(req)
+	suite.Equal(http.StatusBadRequest, resp.StatusCode)
+}
+
+func (suite *AdsApiTestSuite) TestCreateAd_NoToken() {
+	body := map[string]any{
+		"user_id": 2,
+		"title":   "title",
+		"text":    "text",
+	}
+	data, _ := json.Marshal(body)
+	req, _ := http.NewRequest(http.MethodPost, suite.baseURL+"/api/v1/ads", bytes.NewReader(data))
+	resp, _ := suite.client.Do(req)
+	suite.Equal(resp.StatusCode, http.StatusUnauthorized)
+	suite.adsService.AssertNotCalled(suite.T(), "CreateAd", mock.Anything, mock.Anything, mock.Anything, mock.Anything,
+		mock.Anything, mock.Anything)
+}
+
 func (suite *AdsApiTestSuite) TestCreateAd_InvalidParams() {
 	suite.adsService.On("CreateAd", mock.AnythingOfType("*gin.Context"), mock.AnythingOfType("string"),
-		mock.AnythingOfType("string"), mock.AnythingOfType("int64")).
+		mock.AnythingOfType("string"), mock.AnythingOfType("ads.Money"), mock.AnythingOfType("int64"),
+		mock.AnythingOfType("int64")).
 		Return(nil, ads.ErrInvalidAdParams)
 	body := map[string]any{
-		"user_id": 1,
-		"title":   "",
-		"text":    "",
+		"title": "",
+		"text":  "",
 	}
 	data, _ := json.Marshal(body)
 	req, _ := http.NewRequest(http.MethodPost, suite.baseURL+"/api/v1/ads", bytes.NewReader(data))
+	suite.authorize(req)
 	resp, _ := suite.client.Do(req)
 	suite.Equal(resp.StatusCode, http.StatusBadRequest)
 }
 
+func (suite *AdsApiTestSuite) TestCreateAd_UnknownCategory() {
+	suite.adsService.On("CreateAd", mock.AnythingOfType("*gin.Context"), "title", "text", ads.Money{}, int64(42), int64(2)).
+		Return(nil, ads.ErrUnknownCategory)
+	data, _ := json.Marshal(map[string]any{"title": "title", "text": "text", "category_id": 42})
+	req, _ := http.NewRequest(http.MethodPost, suite.baseURL+"/api/v1/ads", bytes.NewReader(data))
+	suite.authorize(req)
+	resp, _ := suite.client.Do(req)
+	suite.Equal(http.StatusBadRequest, resp.StatusCode)
+}
+
 func (suite *AdsApiTestSuite) TestCreateAd_InvalidUserUd() {
 	suite.adsService.On("CreateAd", mock.AnythingOfType("*gin.Context"), mock.AnythingOfType("string"),
-		mock.AnythingOfType("string"), mock.AnythingOfType("int64")).
+		mock.AnythingOfType("string"), mock.AnythingOfType("ads.Money"), mock.AnythingOfType("int64"),
+		mock.AnythingOfType("int64")).
 		Return(nil, userrepo.ErrInvalidUserId)
 	body := map[string]any{
-		"user_id": 1,
-		"title":   "",
-		"text":    "",
+		"title": "",
+		"text":  "",
 	}
 	data, _ := json.Marshal(body)
 	req, _ := http.NewRequest(http.MethodPost, suite.baseURL+"/api/v1/ads", bytes.NewReader(data))
+	suite.authorize(req)
 	resp, _ := suite.client.Do(req)
 	suite.Equal(resp.StatusCode, http.StatusNotFound)
 }
 
 func (suite *AdsApiTestSuite) TestCreateAd_UnexpectedError() {
 	suite.adsService.On("CreateAd", mock.AnythingOfType("*gin.Context"), mock.AnythingOfType("string"),
-		mock.AnythingOfType("string"), mock.AnythingOfType("int64")).
+		mock.AnythingOfType("string"), mock.AnythingOfType("ads.Money"), mock.AnythingOfType("int64"),
+		mock.AnythingOfType("int64")).
 		Return(nil, errors.New("server error"))
 	body := map[string]any{
-		"user_id": 1,
-		"title":   "",
-		"text":    "",
+		"title": "",
+		"text":  "",
 	}
 	data, _ := json.Marshal(body)
 	req, _ := http.NewRequest(http.MethodPost, suite.baseURL+"/api/v1/ads", bytes.NewReader(data))
+	suite.authorize(req)
 	resp, _ := suite.client.Do(req)
 	suite.Equal(resp.StatusCode, http.StatusInternalServerError)
 }
 
 func (suite *AdsApiTestSuite) TestCreateAd_NilBody() {
 	req, _ := http.NewRequest(http.MethodPost, suite.baseURL+"/api/v1/ads", nil)
+	suite.authorize(req)
 	resp, _ := suite.client.Do(req)
 	suite.Equal(resp.StatusCode, http.StatusBadRequest)
 }
 
 func (suite *AdsApiTestSuite) TestGetAdById_OK() {
 	suite.adsService.On("GetAdById", mock.AnythingOfType("*gin.Context"), mock.AnythingOfType("int64")).
-		Return(&ads.Ad{Title: "title", Text: "text", AuthorID: 1, ID: 10}, nil)
+		Return(&ads.Ad{Title: "title", Text: "text", AuthorID: 1, ID: 10, Version: 4}, nil)
 	req, _ := http.NewRequest(http.MethodGet, suite.baseURL+"/api/v1/ads/id/10", nil)
 	resp, _ := suite.client.Do(req)
 	suite.Equal(resp.StatusCode, http.StatusOK)
+	suite.Equal(`"4"`, resp.Header.Get("ETag"))
 	var responseAd adResponse
 	respBody, _ := io.ReadAll(resp.Body)
 	_ = json.Unmarshal(respBody, &responseAd)
@@ -189,7 +303,7 @@ func (suite *AdsApiTestSuite) TestGetAdsByTitle_UnexpectedError() {
 func (suite *AdsApiTestSuite) TestGetAllAds_OK() {
 	suite.adsService.On("GetAll", mock.AnythingOfType("*gin.Context"), mock.AnythingOfType("ads.Filters")).
 		Return([]*ads.Ad{{Title: "example", Text: "text", AuthorID: 1, ID: 10},
-			{Title: "example2", Text: "text2", AuthorID: 1, ID: 10}}, nil)
+			{Title: "example2", Text: "text2", AuthorID: 1, ID: 10}}, "next", nil)
 	req, _ := http.NewRequest(http.MethodGet, suite.baseURL+"/api/v1/ads?author_id=1", nil)
 	resp, _ := suite.client.Do(req)
 	suite.Equal(resp.StatusCode, http.StatusOK)
@@ -199,11 +313,59 @@ func (suite *AdsApiTestSuite) TestGetAllAds_OK() {
 	suite.Len(responseAd.Data, 2)
 	suite.Equal(responseAd.Data[0].Title, "example")
 	suite.Equal(responseAd.Data[0].ID, int64(10))
+	suite.Equal(responseAd.NextCursor, "next")
+}
+
+func (suite *AdsApiTestSuite) TestGetAllAds_Category() {
+	suite.adsService.On("GetAll", mock.AnythingOfType("*gin.Context"),
+		mock.MatchedBy(func(f ads.Filters) bool { return f.CategoryId == 3 })).
+		Return([]*ads.Ad{{Title: "bike", CategoryID: 4, ID: 10}}, "", nil)
+	req, _ := http.NewRequest(http.MethodGet, suite.baseURL+"/api/v1/ads?category=3", nil)
+	resp, _ := suite.client.Do(req)
+	suite.Equal(http.StatusOK, resp.StatusCode)
+	var responseAd adsResponse
+	respBody, _ := io.ReadAll(resp.Body)
+	_ = json.Unmarshal(respBody, &responseAd)
+	suite.Len(responseAd.Data, 1)
+	suite.Equal(int64(4), responseAd.Data[0].CategoryID)
+
+	req, _ = http.NewRequest(http.MethodGet, suite.baseURL+"/api/v1/ads?category=bikes", nil)
+	resp, _ = suite.client.Do(req)
+	suite.Equal(http.StatusBadRequest, resp.StatusCode)
+	suite.adsService.AssertNumberOfCalls(suite.T(), "GetAll", 1)
+}
+
+func (suite *AdsApiTestSuite) TestGetAllAds_Price() {
+	suite.adsService.On("GetAll", mock.AnythingOfType("*gin.Context"),
+		ads.Filters{Status: ads.Published, PriceMin: 100, PriceMax: 5000, Currency: "rub", Sort: ads.SortByPrice}).
+		Return([]*ads.Ad{{Title: "bike", Price: ads.Money{Amount: 2500, Currency: "RUB"}, ID: 10}}, "", nil)
+	req, _ := http.NewRequest(http.MethodGet,
+		suite.baseURL+"/api/v1/ads?price_min=100&price_max=5000&currency=rub&sort=price", nil)
+	resp, _ := suite.client.Do(req)
+	suite.Equal(http.StatusOK, resp.StatusCode)
+	var responseAd adsResponse
+	respBody, _ := io.ReadAll(resp.Body)
+	_ = json.Unmarshal(respBody, &responseAd)
+	if suite.Len(responseAd.Data, 1) {
+		suite.Equal(int64(2500), responseAd.Data[0].Price.Amount)
+	}
+
+	req, _ = http.NewRequest(http.MethodGet, suite.baseURL+"/api/v1/ads?price_min=-5&currency=RUB", nil)
+	resp, _ = suite.client.Do(req)
+	suite.Equal(http.StatusBadRequest, resp.StatusCode)
+	suite.adsService.AssertNumberOfCalls(suite.T(), "GetAll", 1)
+}
+
+func (suite *AdsApiTestSuite) TestGetAllAds_InvalidLimit() {
+	req, _ := http.NewRequest(http.MethodGet, suite.baseURL+"/api/v1/ads?limit=1000", nil)
+	resp, _ := suite.client.Do(req)
+	suite.Equal(resp.StatusCode, http.StatusBadRequest)
+	suite.adsService.AssertNotCalled(suite.T(), "GetAll", mock.Anything, mock.Anything)
 }
 
 func (suite *AdsApiTestSuite) TestGetAllAds_InvalidFilters() {
 	suite.adsService.On("GetAll", mock.AnythingOfType("*gin.Context"), mock.AnythingOfType("ads.Filters")).
-		Return(nil, ads.ErrInvalidFilters)
+		Return(nil, "", ads.ErrInvalidFilters)
 	req, _ := http.NewRequest(http.MethodGet, suite.baseURL+"/api/v1/ads?autdffdvfhor_id=1", nil)
 	resp, _ := suite.client.Do(req)
 	suite.Equal(resp.StatusCode, http.StatusBadRequest)
@@ -211,22 +373,43 @@ func (suite *AdsApiTestSuite) TestGetAllAds_InvalidFilters() {
 
 func (suite *AdsApiTestSuite) TestGetAllAds_UnexpectedError() {
 	suite.adsService.On("GetAll", mock.AnythingOfType("*gin.Context"), mock.AnythingOfType("ads.Filters")).
-		Return(nil, errors.New("unexpected error"))
+		Return(nil, "", errors.New("unexpected error"))
 	req, _ := http.NewRequest(http.MethodGet, suite.baseURL+"/api/v1/ads?author_id=1", nil)
 	resp, _ := suite.client.Do(req)
 	suite.Equal(resp.StatusCode, http.StatusInternalServerError)
 }
 
+func (suite *AdsApiTestSuite) TestSearchAds_OK() {
+	suite.adsService.On("SearchAds", mock.AnythingOfType("*gin.Context"), "красный велосипед").
+		Return([]*ads.Ad{{Title: "велосипед", Text: "красный", AuthorID: 1, ID: 3, State: ads.StatePublished}}, nil)
+	req, _ := http.NewRequest(http.MethodGet, suite.baseURL+"/api/v1/ads/search?q="+url.QueryEscape("красный велосипед"), nil)
+	resp, _ := suite.client.Do(req)
+	suite.Equal(resp.StatusCode, http.StatusOK)
+	var responseAd adsResponse
+	respBody, _ := io.ReadAll(resp.Body)
+	_ = json.Unmarshal(respBody, &responseAd)
+	suite.Len(responseAd.Data, 1)
+	suite.Equal(responseAd.Data[0].ID, int64(3))
+}
+
+func (suite *AdsApiTestSuite) TestSearchAds_EmptyQuery() {
+	suite.adsService.On("SearchAds", mock.AnythingOfType("*gin.Context"), "").
+		Return(nil, ads.ErrEmptySearchQuery)
+	req, _ := http.NewRequest(http.MethodGet, suite.baseURL+"/api/v1/ads/search", nil)
+	resp, _ := suite.client.Do(req)
+	suite.Equal(resp.StatusCode, http.StatusBadRequest)
+}
+
 func (suite *AdsApiTestSuite) TestChangeAdStatus_OK() {
 	suite.adsService.On("ChangeAdStatus", mock.AnythingOfType("*gin.Context"), mock.AnythingOfType("int64"),
 		mock.AnythingOfType("int64"), mock.AnythingOfType("bool")).
-		Return(&ads.Ad{ID: 1, AuthorID: 1, Published: true}, nil)
+		Return(&ads.Ad{ID: 1, AuthorID: 1, State: ads.StatePublished}, nil)
 	body := map[string]any{
-		"user_id":   1,
 		"published": true,
 	}
 	data, _ := json.Marshal(body)
 	req, _ := http.NewRequest(http.MethodPut, suite.baseURL+"/api/v1/ads/1/status", bytes.NewReader(data))
+	suite.authorize(req)
 	resp, _ := suite.client.Do(req)
 	suite.Equal(resp.StatusCode, http.StatusOK)
 	var responseAd adResponse
@@ -234,6 +417,7 @@ func (suite *AdsApiTestSuite) TestChangeAdStatus_OK() {
 	_ = json.Unmarshal(respBody, &responseAd)
 	suite.Equal(responseAd.Data.AuthorID, int64(1))
 	suite.Equal(responseAd.Data.Published, true)
+	suite.Equal("published", responseAd.Data.State)
 	suite.Equal(responseAd.Data.ID, int64(1))
 }
 
@@ -242,11 +426,11 @@ func (suite *AdsApiTestSuite) TestChangeAdStatus_UserCantChangeThisAd() {
 		mock.AnythingOfType("int64"), mock.AnythingOfType("bool")).
 		Return(nil, ads.ErrUserCantChangeThisAd)
 	body := map[string]any{
-		"user_id":   1,
 		"published": true,
 	}
 	data, _ := json.Marshal(body)
 	req, _ := http.NewRequest(http.MethodPut, suite.baseURL+"/api/v1/ads/1/status", bytes.NewReader(data))
+	suite.authorize(req)
 	resp, _ := suite.client.Do(req)
 	suite.Equal(resp.StatusCode, http.StatusForbidden)
 }
@@ -256,11 +440,11 @@ func (suite *AdsApiTestSuite) TestChangeAdStatus_InvalidUserId() {
 		mock.AnythingOfType("int64"), mock.AnythingOfType("bool")).
 		Return(nil, userrepo.ErrInvalidUserId)
 	body := map[string]any{
-		"user_id":   1,
 		"published": true,
 	}
 	data, _ := json.Marshal(body)
 	req, _ := http.NewRequest(http.MethodPut, suite.baseURL+"/api/v1/ads/1/status", bytes.NewReader(data))
+	suite.authorize(req)
 	resp, _ := suite.client.Do(req)
 	suite.Equal(resp.StatusCode, http.StatusNotFound)
 }
@@ -270,11 +454,11 @@ func (suite *AdsApiTestSuite) TestChangeAdStatus_InvalidAdId() {
 		mock.AnythingOfType("int64"), mock.AnythingOfType("bool")).
 		Return(nil, adrepo.ErrInvalidAdId)
 	body := map[string]any{
-		"user_id":   1,
 		"published": true,
 	}
 	data, _ := json.Marshal(body)
 	req, _ := http.NewRequest(http.MethodPut, suite.baseURL+"/api/v1/ads/1/status", bytes.NewReader(data))
+	suite.authorize(req)
 	resp, _ := suite.client.Do(req)
 	suite.Equal(resp.StatusCode, http.StatusNotFound)
 }
@@ -284,11 +468,11 @@ func (suite *AdsApiTestSuite) TestChangeAdStatus_InvalidAdParams() {
 		mock.AnythingOfType("int64"), mock.AnythingOfType("bool")).
 		Return(nil, ads.ErrInvalidAdParams)
 	body := map[string]any{
-		"user_id":   1,
 		"published": true,
 	}
 	data, _ := json.Marshal(body)
 	req, _ := http.NewRequest(http.MethodPut, suite.baseURL+"/api/v1/ads/1/status", bytes.NewReader(data))
+	suite.authorize(req)
 	resp, _ := suite.client.Do(req)
 	suite.Equal(resp.StatusCode, http.StatusBadRequest)
 }
@@ -298,32 +482,34 @@ func (suite *AdsApiTestSuite) TestChangeAdStatus_UnexpectedError() {
 		mock.AnythingOfType("int64"), mock.AnythingOfType("bool")).
 		Return(nil, errors.New("unexpected Error"))
 	body := map[string]any{
-		"user_id":   1,
 		"published": true,
 	}
 	data, _ := json.Marshal(body)
 	req, _ := http.NewRequest(http.MethodPut, suite.baseURL+"/api/v1/ads/1/status", bytes.NewReader(data))
+	suite.authorize(req)
 	resp, _ := suite.client.Do(req)
 	suite.Equal(resp.StatusCode, http.StatusInternalServerError)
 }
 
 func (suite *AdsApiTestSuite) TestChangeAdStatus_NilBody() {
 	req, _ := http.NewRequest(http.MethodPut, suite.baseURL+"/api/v1/ads/1/status", nil)
+	suite.authorize(req)
 	resp, _ := suite.client.Do(req)
 	suite.Equal(resp.StatusCode, http.StatusBadRequest)
 }
 
 func (suite *AdsApiTestSuite) TestUpdateText_OK() {
 	suite.adsService.On("UpdateAd", mock.AnythingOfType("*gin.Context"), mock.AnythingOfType("int64"),
-		mock.AnythingOfType("int64"), mock.AnythingOfType("string"), mock.AnythingOfType("string")).
+		mock.AnythingOfType("int64"), mock.AnythingOfType("string"), mock.AnythingOfType("string"),
+		mock.AnythingOfType("int64")).
 		Return(&ads.Ad{ID: 1, AuthorID: 1, Text: "new text", Title: "new title"}, nil)
 	body := map[string]any{
-		"user_id": 1,
-		"title":   "new title",
-		"text":    "new text",
+		"title": "new title",
+		"text":  "new text",
 	}
 	data, _ := json.Marshal(body)
 	req, _ := http.NewRequest(http.MethodPut, suite.baseURL+"/api/v1/ads/1/text", bytes.NewReader(data))
+	suite.authorize(req)
 	resp, _ := suite.client.Do(req)
 	suite.Equal(resp.StatusCode, http.StatusOK)
 	var responseAd adResponse
@@ -336,94 +522,113 @@ func (suite *AdsApiTestSuite) TestUpdateText_OK() {
 
 func (suite *AdsApiTestSuite) TestUpdateAdText_UserCantChangeThisAd() {
 	suite.adsService.On("UpdateAd", mock.AnythingOfType("*gin.Context"), mock.AnythingOfType("int64"),
-		mock.AnythingOfType("int64"), mock.AnythingOfType("string"), mock.AnythingOfType("string")).
+		mock.AnythingOfType("int64"), mock.AnythingOfType("string"), mock.AnythingOfType("string"),
+		mock.AnythingOfType("int64")).
 		Return(nil, ads.ErrUserCantChangeThisAd)
 	body := map[string]any{
-		"user_id": 1,
-		"title":   "new title",
-		"text":    "new text",
+		"title": "new title",
+		"text":  "new text",
 	}
 	data, _ := json.Marshal(body)
 	req, _ := http.NewRequest(http.MethodPut, suite.baseURL+"/api/v1/ads/1/text", bytes.NewReader(data))
+	suite.authorize(req)
 	resp, _ := suite.client.Do(req)
 	suite.Equal(resp.StatusCode, http.StatusForbidden)
 }
 
 func (suite *AdsApiTestSuite) TestUpdateAdText_InvalidUserId() {
 	suite.adsService.On("UpdateAd", mock.AnythingOfType("*gin.Context"), mock.AnythingOfType("int64"),
-		mock.AnythingOfType("int64"), mock.AnythingOfType("string"), mock.AnythingOfType("string")).
+		mock.AnythingOfType("int64"), mock.AnythingOfType("string"), mock.AnythingOfType("string"),
+		mock.AnythingOfType("int64")).
 		Return(nil, userrepo.ErrInvalidUserId)
 	body := map[string]any{
-		"user_id": 1,
-		"title":   "new title",
-		"text":    "new text",
+		"title": "new title",
+		"text":  "new text",
 	}
 	data, _ := json.Marshal(body)
 	req, _ := http.NewRequest(http.MethodPut, suite.baseURL+"/api/v1/ads/1/text", bytes.NewReader(data))
+	suite.authorize(req)
 	resp, _ := suite.client.Do(req)
 	suite.Equal(resp.StatusCode, http.StatusNotFound)
 }
 
 func (suite *AdsApiTestSuite) TestUpdateAdText_InvalidAdParams() {
 	suite.adsService.On("UpdateAd", mock.AnythingOfType("*gin.Context"), mock.AnythingOfType("int64"),
-		mock.AnythingOfType("int64"), mock.AnythingOfType("string"), mock.AnythingOfType("string")).
+		mock.AnythingOfType("int64"), mock.AnythingOfType("string"), mock.AnythingOfType("string"),
+		mock.AnythingOfType("int64")).
 		Return(nil, ads.ErrInvalidAdParams)
 	body := map[string]any{
-		"user_id": 1,
-		"title":   "",
-		"text":    "",
+		"title": "",
+		"text":  "",
 	}
 	data, _ := json.Marshal(body)
 	req, _ := http.NewRequest(http.MethodPut, suite.baseURL+"/api/v1/ads/1/text", bytes.NewReader(data))
+	suite.authorize(req)
 	resp, _ := suite.client.Do(req)
 	suite.Equal(resp.StatusCode, http.StatusBadRequest)
 }
 
 func (suite *AdsApiTestSuite) TestUpdateAdText_InvalidAdId() {
 	suite.adsService.On("UpdateAd", mock.AnythingOfType("*gin.Context"), mock.AnythingOfType("int64"),
-		mock.AnythingOfType("int64"), mock.AnythingOfType("string"), mock.AnythingOfType("string")).
+		mock.AnythingOfType("int64"), mock.AnythingOfType("string"), mock.AnythingOfType("string"),
+		mock.AnythingOfType("int64")).
 		Return(nil, adrepo.ErrInvalidAdId)
 	body := map[string]any{
-		"user_id": 1,
-		"title":   "",
-		"text":    "",
+		"title": "",
+		"text":  "",
 	}
 	data, _ := json.Marshal(body)
 	req, _ := http.NewRequest(http.MethodPut, suite.baseURL+"/api/v1/ads/1/text", bytes.NewReader(data))
+	suite.authorize(req)
 	resp, _ := suite.client.Do(req)
 	suite.Equal(resp.StatusCode, http.StatusNotFound)
 }
 
 func (suite *AdsApiTestSuite) TestUpdateAdText_UnexpectedError() {
 	suite.adsService.On("UpdateAd", mock.AnythingOfType("*gin.Context"), mock.AnythingOfType("int64"),
-		mock.AnythingOfType("int64"), mock.AnythingOfType("string"), mock.AnythingOfType("string")).
+		mock.AnythingOfType("int64"), mock.AnythingOfType("string"), mock.AnythingOfType("string"),
+		mock.AnythingOfType("int64")).
 		Return(nil, errors.New("unexpected error"))
 	body := map[string]any{
-		"user_id": 1,
-		"title":   "",
-		"text":    "",
+		"title": "",
+		"text":  "",
 	}
 	data, _ := json.Marshal(body)
 	req, _ := http.NewRequest(http.MethodPut, suite.baseURL+"/api/v1/ads/1/text", bytes.NewReader(data))
+	suite.authorize(req)
 	resp, _ := suite.client.Do(req)
 	suite.Equal(resp.StatusCode, http.StatusInternalServerError)
 }
 
+func (suite *AdsApiTestSuite) TestUpdateAdText_VersionMismatch() {
+	suite.adsService.On("UpdateAd", mock.AnythingOfType("*gin.Context"), mock.AnythingOfType("int64"),
+		mock.AnythingOfType("int64"), mock.AnythingOfType("string"), mock.AnythingOfType("string"), int64(3)).
+		Return(nil, ads.ErrVersionMismatch)
+	body := map[string]any{
+		"title": "new title",
+		"text":  "new text",
+	}
+	data, _ := json.Marshal(body)
+	req, _ := http.NewRequest(http.MethodPut, suite.baseURL+"/api/v1/ads/1/text", bytes.NewReader(data))
+	suite.authorize(req)
+	req.Header.Set("If-Match", `"3"`)
+	resp, _ := suite.client.Do(req)
+	suite.Equal(resp.StatusCode, http.StatusPreconditionFailed)
+}
+
 func (suite *AdsApiTestSuite) TestUpdateAdText_NilBody() {
 	req, _ := http.NewRequest(http.MethodPut, suite.baseURL+"/api/v1/ads/1/text", nil)
+	suite.authorize(req)
 	resp, _ := suite.client.Do(req)
 	suite.Equal(resp.StatusCode, http.StatusBadRequest)
 }
 
 func (suite *AdsApiTestSuite) TestDeleteAd_OK() {
 	suite.adsService.On("DeleteAd", mock.AnythingOfType("*gin.Context"), mock.AnythingOfType("int64"),
-		mock.AnythingOfType("int64")).
+		mock.AnythingOfType("int64"), mock.AnythingOfType("string")).
 		Return(nil)
-	body := map[string]any{
-		"user_id": 1,
-	}
-	data, _ := json.Marshal(body)
-	req, _ := http.NewRequest(http.MethodDelete, suite.baseURL+"/api/v1/ads/1/delete", bytes.NewReader(data))
+	req, _ := http.NewRequest(http.MethodDelete, suite.baseURL+"/api/v1/ads/1/delete", nil)
+	suite.authorize(req)
 	resp, _ := suite.client.Do(req)
 	suite.Equal(resp.StatusCode, http.StatusOK)
 	var responseAd deleteAdResponse
@@ -432,67 +637,323 @@ func (suite *AdsApiTestSuite) TestDeleteAd_OK() {
 	suite.Equal(responseAd.Data, "ad successfully deleted")
 }
 
-func (suite *AdsApiTestSuite) TestDeleteAd_NilBody() {
-	suite.adsService.On("DeleteAd", mock.AnythingOfType("*gin.Context"), mock.AnythingOfType("int64"),
-		mock.AnythingOfType("int64")).
-		Return(nil)
+func (suite *AdsApiTestSuite) TestDeleteAd_NoToken() {
 	req, _ := http.NewRequest(http.MethodDelete, suite.baseURL+"/api/v1/ads/1/delete", nil)
 	resp, _ := suite.client.Do(req)
-	suite.Equal(resp.StatusCode, http.StatusBadRequest)
+	suite.Equal(resp.StatusCode, http.StatusUnauthorized)
+	suite.adsService.AssertNotCalled(suite.T(), "DeleteAd", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
 }
 
 func (suite *AdsApiTestSuite) TestDeleteAd_InvalidUserId() {
 	suite.adsService.On("DeleteAd", mock.AnythingOfType("*gin.Context"), mock.AnythingOfType("int64"),
-		mock.AnythingOfType("int64")).
+		mock.AnythingOfType("int64"), mock.AnythingOfType("string")).
 		Return(userrepo.ErrInvalidUserId)
-	body := map[string]any{
-		"user_id": 1,
-	}
-	data, _ := json.Marshal(body)
-	req, _ := http.NewRequest(http.MethodDelete, suite.baseURL+"/api/v1/ads/1/delete", bytes.NewReader(data))
+	req, _ := http.NewRequest(http.MethodDelete, suite.baseURL+"/api/v1/ads/1/delete", nil)
+	suite.authorize(req)
 	resp, _ := suite.client.Do(req)
 	suite.Equal(resp.StatusCode, http.StatusNotFound)
 }
 
 func (suite *AdsApiTestSuite) TestDeleteAd_InvalidAdId() {
 	suite.adsService.On("DeleteAd", mock.AnythingOfType("*gin.Context"), mock.AnythingOfType("int64"),
-		mock.AnythingOfType("int64")).
+		mock.AnythingOfType("int64"), mock.AnythingOfType("string")).
 		Return(adrepo.ErrInvalidAdId)
-	body := map[string]any{
-		"user_id": 1,
-	}
-	data, _ := json.Marshal(body)
-	req, _ := http.NewRequest(http.MethodDelete, suite.baseURL+"/api/v1/ads/1/delete", bytes.NewReader(data))
+	req, _ := http.NewRequest(http.MethodDelete, suite.baseURL+"/api/v1/ads/1/delete", nil)
+	suite.authorize(req)
 	resp, _ := suite.client.Do(req)
 	suite.Equal(resp.StatusCode, http.StatusNotFound)
 }
 
 func (suite *AdsApiTestSuite) TestDeleteAd_UnableToDelete() {
 	suite.adsService.On("DeleteAd", mock.AnythingOfType("*gin.Context"), mock.AnythingOfType("int64"),
-		mock.AnythingOfType("int64")).
+		mock.AnythingOfType("int64"), mock.AnythingOfType("string")).
 		Return(adsapp.ErrUnableToDelete)
-	body := map[string]any{
-		"user_id": -1,
-	}
-	data, _ := json.Marshal(body)
-	req, _ := http.NewRequest(http.MethodDelete, suite.baseURL+"/api/v1/ads/1/delete", bytes.NewReader(data))
+	req, _ := http.NewRequest(http.MethodDelete, suite.baseURL+"/api/v1/ads/1/delete", nil)
+	suite.authorize(req)
 	resp, _ := suite.client.Do(req)
 	suite.Equal(resp.StatusCode, http.StatusForbidden)
 }
 
+func (suite *AdsApiTestSuite) TestDeleteAd_Reason() {
+	suite.adsService.On("DeleteAd", mock.AnythingOfType("*gin.Context"), int64(1), int64(2), "").
+		Return(ads.ErrReasonRequired)
+	suite.adsService.On("DeleteAd", mock.AnythingOfType("*gin.Context"), int64(1), int64(2), "spam ad").
+		Return(nil)
+	req, _ := http.NewRequest(http.MethodDelete, suite.baseURL+"/api/v1/ads/1/delete", nil)
+	suite.authorize(req)
+	resp, _ := suite.client.Do(req)
+	suite.Equal(http.StatusBadRequest, resp.StatusCode)
+
+	req, _ = http.NewRequest(http.MethodDelete, suite.baseURL+"/api/v1/ads/1/delete?reason=spam+ad", nil)
+	suite.authorize(req)
+	resp, _ = suite.client.Do(req)
+	suite.Equal(http.StatusOK, resp.StatusCode)
+}
+
+func (suite *AdsApiTestSuite) TestUnpublishAd() {
+	suite.adsService.On("UnpublishAd", mock.AnythingOfType("*gin.Context"), int64(1), int64(2), "").
+		Return(nil, ads.ErrReasonRequired)
+	suite.adsService.On("UnpublishAd", mock.AnythingOfType("*gin.Context"), int64(1), int64(2), "spam").
+		Return(&ads.Ad{ID: 1, AuthorID: 3, Title: "title", Text: "text", Version: 3}, nil)
+	suite.adsService.On("UnpublishAd", mock.AnythingOfType("*gin.Context"), int64(2), int64(2), "spam").
+		Return(nil, ads.ErrUserCantChangeThisAd)
+
+	tests := []struct {
+		adId   int
+		body   string
+		status int
+	}{
+		{1, `{}`, http.StatusBadRequest},
+		{1, `{"reason":"spam"}`, http.StatusOK},
+		{2, `{"reason":"spam"}`, http.StatusForbidden},
+	}
+	for _, tc := range tests {
+		req, _ := http.NewRequest(http.MethodPost, suite.baseURL+"/api/v1/ads/"+strconv.Itoa(tc.adId)+"/unpublish",
+			bytes.NewReader([]byte(tc.body)))
+		req.Header.Add("Content-Type", "application/json")
+		suite.authorize(req)
+		resp, _ := suite.client.Do(req)
+		suite.Equal(tc.status, resp.StatusCode, tc.body)
+	}
+}
+
+func (suite *AdsApiTestSuite) TestChangeAdState() {
+	suite.adsService.On("ChangeAdState", mock.AnythingOfType("*gin.Context"), int64(1), int64(2),
+		ads.StateRejected, "spam").
+		Return(&ads.Ad{ID: 1, AuthorID: 3, State: ads.StateRejected, RejectionReason: "spam"}, nil)
+	suite.adsService.On("ChangeAdState", mock.AnythingOfType("*gin.Context"), int64(1), int64(2),
+		ads.StateRejected, "").
+		Return(nil, ads.ErrReasonRequired)
+	suite.adsService.On("ChangeAdState", mock.AnythingOfType("*gin.Context"), int64(1), int64(2),
+		ads.State("lost"), "").
+		Return(nil, ads.ErrInvalidState)
+	suite.adsService.On("ChangeAdState", mock.AnythingOfType("*gin.Context"), int64(1), int64(2),
+		ads.StateSold, "").
+		Return(nil, ads.ErrIllegalTransition)
+	suite.adsService.On("ChangeAdState", mock.AnythingOfType("*gin.Context"), int64(2), int64(2),
+		ads.StatePublished, "").
+		Return(nil, ads.ErrUserCantChangeThisAd)
+
+	tests := []struct {
+		adId   int
+		body   string
+		status int
+	}{
+		{1, `{"state":"rejected","reason":"spam"}`, http.StatusOK},
+		{1, `{"state":"rejected"}`, http.StatusBadRequest},
+		{1, `{"state":"lost"}`, http.StatusBadRequest},
+		{1, `{"state":"sold"}`, http.StatusConflict},
+		{2, `{"state":"published"}`, http.StatusForbidden},
+	}
+	for _, tc := range tests {
+		req, _ := http.NewRequest(http.MethodPut, suite.baseURL+"/api/v1/ads/"+strconv.Itoa(tc.adId)+"/state",
+			bytes.NewReader([]byte(tc.body)))
+		req.Header.Add("Content-Type", "application/json")
+		suite.authorize(req)
+		resp, _ := suite.client.Do(req)
+		suite.Equal(tc.status, resp.StatusCode, tc.body)
+		if tc.status == http.StatusOK {
+			var responseAd adResponse
+			respBody, _ := io.ReadAll(resp.Body)
+			_ = json.Unmarshal(respBody, &responseAd)
+			suite.Equal("rejected", responseAd.Data.State)
+			suite.Equal("spam", responseAd.Data.RejectionReason)
+			suite.False(responseAd.Data.Published)
+		}
+	}
+}
+
 func (suite *AdsApiTestSuite) TestDeleteAd_UnexpectedError() {
 	suite.adsService.On("DeleteAd", mock.AnythingOfType("*gin.Context"), mock.AnythingOfType("int64"),
-		mock.AnythingOfType("int64")).
+		mock.AnythingOfType("int64"), mock.AnythingOfType("string")).
 		Return(errors.New("unexpected error"))
-	body := map[string]any{
-		"user_id": -1,
-	}
-	data, _ := json.Marshal(body)
-	req, _ := http.NewRequest(http.MethodDelete, suite.baseURL+"/api/v1/ads/1/delete", bytes.NewReader(data))
+	req, _ := http.NewRequest(http.MethodDelete, suite.baseURL+"/api/v1/ads/1/delete", nil)
+	suite.authorize(req)
 	resp, _ := suite.client.Do(req)
 	suite.Equal(resp.StatusCode, http.StatusInternalServerError)
 }
 
+func (suite *AdsApiTestSuite) TestRestoreAd_OK() {
+	suite.adsService.On("RestoreAd", mock.AnythingOfType("*gin.Context"), int64(1), int64(2)).
+		Return(&ads.Ad{ID: 1, AuthorID: 2, Title: "title", Version: 3}, nil)
+	req, _ := http.NewRequest(http.MethodPost, suite.baseURL+"/api/v1/ads/1/restore", nil)
+	suite.authorize(req)
+	resp, _ := suite.client.Do(req)
+	suite.Equal(resp.StatusCode, http.StatusOK)
+	suite.Equal(`"3"`, resp.Header.Get("ETag"))
+}
+
+func (suite *AdsApiTestSuite) TestRestoreAd_InvalidAdId() {
+	suite.adsService.On("RestoreAd", mock.AnythingOfType("*gin.Context"), mock.AnythingOfType("int64"),
+		mock.AnythingOfType("int64")).
+		Return(nil, adrepo.ErrInvalidAdId)
+	req, _ := http.NewRequest(http.MethodPost, suite.baseURL+"/api/v1/ads/1/restore", nil)
+	suite.authorize(req)
+	resp, _ := suite.client.Do(req)
+	suite.Equal(resp.StatusCode, http.StatusNotFound)
+}
+
+func (suite *AdsApiTestSuite) TestGetTrash_OK() {
+	suite.adsService.On("GetTrash", mock.AnythingOfType("*gin.Context"), int64(2)).
+		Return([]*ads.Ad{{ID: 1, AuthorID: 2, DeletedAt: time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)}}, nil)
+	req, _ := http.NewRequest(http.MethodGet, suite.baseURL+"/api/v1/ads/trash", nil)
+	suite.authorize(req)
+	resp, _ := suite.client.Do(req)
+	suite.Equal(resp.StatusCode, http.StatusOK)
+	var responseAd adsResponse
+	respBody, _ := io.ReadAll(resp.Body)
+	_ = json.Unmarshal(respBody, &responseAd)
+	suite.Len(responseAd.Data, 1)
+	suite.Equal("2023-05-01T10:00:00Z", responseAd.Data[0].DeletedAt)
+}
+
+func (suite *AdsApiTestSuite) TestGetTrash_InvalidToken() {
+	req, _ := http.NewRequest(http.MethodGet, suite.baseURL+"/api/v1/ads/trash", nil)
+	req.Header.Add("Authorization", "Bearer expired")
+	resp, _ := suite.client.Do(req)
+	suite.Equal(resp.StatusCode, http.StatusUnauthorized)
+	suite.Equal(`Bearer realm="api"`, resp.Header.Get("WWW-Authenticate"))
+}
+
+func (suite *AdsApiTestSuite) TestAPIKeyScopes() {
+	suite.authService.On("AuthenticateKey", mock.AnythingOfType("*gin.Context"), "reader").
+		Return(&authapp.Principal{UserID: 2, KeyID: "reader", Scopes: []user.Scope{user.ScopeAdsRead}}, nil)
+	suite.authService.On("AuthenticateKey", mock.AnythingOfType("*gin.Context"), mock.AnythingOfType("string")).
+		Return(nil, authapp.ErrInvalidToken)
+	suite.adsService.On("GetTrash", mock.AnythingOfType("*gin.Context"), int64(2)).Return([]*ads.Ad{}, nil)
+
+	tests := []struct {
+		method string
+		path   string
+		key    string
+		status int
+	}{
+		{http.MethodGet, "/api/v1/ads/trash", "reader", http.StatusOK},
+		{http.MethodPost, "/api/v1/ads", "reader", http.StatusForbidden},
+		{http.MethodGet, "/api/v1/ads/trash", "revoked", http.StatusUnauthorized},
+	}
+	for _, tc := range tests {
+		req, _ := http.NewRequest(tc.method, suite.baseURL+tc.path, bytes.NewReader([]byte(`{"title":"title","text":"text"}`)))
+		req.Header.Add("X-API-Key", tc.key)
+		resp, _ := suite.client.Do(req)
+		suite.Equal(tc.status, resp.StatusCode, tc.method+" "+tc.path)
+	}
+}
+
+func (suite *AdsApiTestSuite) TestGetRevisions_OK() {
+	suite.adsService.On("GetRevisions", mock.AnythingOfType("*gin.Context"), int64(1)).
+		Return([]*ads.Revision{{AdID: 1, Number: 1, ActorID: 2, CreatedAt: time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC),
+			Changes: []ads.Change{{Field: ads.FieldTitle, New: "title"}}}}, nil)
+	req, _ := http.NewRequest(http.MethodGet, suite.baseURL+"/api/v1/ads/1/revisions", nil)
+	resp, _ := suite.client.Do(req)
+	suite.Equal(resp.StatusCode, http.StatusOK)
+	var response struct {
+		Data []struct {
+			Number    int64  `json:"number"`
+			ActorID   int64  `json:"actor_id"`
+			CreatedAt string `json:"created_at"`
+			Changes   []struct {
+				Field string `json:"field"`
+				New   string `json:"new"`
+			} `json:"changes"`
+		} `json:"data"`
+	}
+	respBody, _ := io.ReadAll(resp.Body)
+	_ = json.Unmarshal(respBody, &response)
+	suite.Len(response.Data, 1)
+	suite.Equal(int64(2), response.Data[0].ActorID)
+	suite.Equal("2023-05-01T10:00:00Z", response.Data[0].CreatedAt)
+	suite.Equal("title", response.Data[0].Changes[0].New)
+}
+
+func (suite *AdsApiTestSuite) TestGetAdAtRevision_InvalidRevision() {
+	suite.adsService.On("GetAdAtRevision", mock.AnythingOfType("*gin.Context"), int64(1), int64(7)).
+		Return(nil, ads.ErrInvalidRevision)
+	req, _ := http.NewRequest(http.MethodGet, suite.baseURL+"/api/v1/ads/1/revisions/7", nil)
+	resp, _ := suite.client.Do(req)
+	suite.Equal(resp.StatusCode, http.StatusNotFound)
+
+	req, _ = http.NewRequest(http.MethodGet, suite.baseURL+"/api/v1/ads/1/revisions/abc", nil)
+	resp, _ = suite.client.Do(req)
+	suite.Equal(resp.StatusCode, http.StatusBadRequest)
+}
+
+func (suite *AdsApiTestSuite) TestRevertAd_OK() {
+	suite.adsService.On("RevertAd", mock.AnythingOfType("*gin.Context"), int64(1), int64(2), int64(1)).
+		Return(&ads.Ad{ID: 1, AuthorID: 2, Title: "title", Version: 5}, nil)
+	req, _ := http.NewRequest(http.MethodPost, suite.baseURL+"/api/v1/ads/1/revisions/1/revert", nil)
+	suite.authorize(req)
+	resp, _ := suite.client.Do(req)
+	suite.Equal(resp.StatusCode, http.StatusOK)
+	suite.Equal(`"5"`, resp.Header.Get("ETag"))
+}
+
+func (suite *AdsApiTestSuite) TestRevertAd_UserCantChangeThisAd() {
+	suite.adsService.On("RevertAd", mock.AnythingOfType("*gin.Context"), mock.AnythingOfType("int64"),
+		mock.AnythingOfType("int64"), mock.AnythingOfType("int64")).
+		Return(nil, ads.ErrUserCantChangeThisAd)
+	req, _ := http.NewRequest(http.MethodPost, suite.baseURL+"/api/v1/ads/1/revisions/1/revert", nil)
+	suite.authorize(req)
+	resp, _ := suite.client.Do(req)
+	suite.Equal(resp.StatusCode, http.StatusForbidden)
+}
+
+func (suite *AdsApiTestSuite) TestListCategories() {
+	suite.adsService.On("ListCategories", mock.AnythingOfType("*gin.Context")).
+		Return([]*ads.CategoryCount{{Category: ads.Category{ID: 1, Name: "vehicles"}, Ads: 3},
+			{Category: ads.Category{ID: 2, ParentID: 1, Name: "bikes"}, Ads: 1}}, nil)
+	req, _ := http.NewRequest(http.MethodGet, suite.baseURL+"/api/v1/categories", nil)
+	resp, _ := suite.client.Do(req)
+	suite.Equal(http.StatusOK, resp.StatusCode)
+	var response categoriesResponse
+	respBody, _ := io.ReadAll(resp.Body)
+	_ = json.Unmarshal(respBody, &response)
+	suite.Equal([]categoryData{{ID: 1, Name: "vehicles", Ads: 3}, {ID: 2, ParentID: 1, Name: "bikes", Ads: 1}},
+		response.Data)
+}
+
+func (suite *AdsApiTestSuite) TestGetCategory_NotFound() {
+	suite.adsService.On("GetCategory", mock.AnythingOfType("*gin.Context"), int64(7)).
+		Return(nil, adrepo.ErrInvalidCategoryId)
+	req, _ := http.NewRequest(http.MethodGet, suite.baseURL+"/api/v1/categories/7", nil)
+	resp, _ := suite.client.Do(req)
+	suite.Equal(http.StatusNotFound, resp.StatusCode)
+}
+
+func (suite *AdsApiTestSuite) TestCreateCategory() {
+	suite.adsService.On("CreateCategory", mock.AnythingOfType("*gin.Context"), int64(2), "bikes", int64(1)).
+		Return(&ads.Category{ID: 2, ParentID: 1, Name: "bikes"}, nil)
+	data, _ := json.Marshal(map[string]any{"name": "bikes", "parent_id": 1})
+	req, _ := http.NewRequest(http.MethodPost, suite.baseURL+"/api/v1/categories", bytes.NewReader(data))
+	resp, _ := suite.client.Do(req)
+	suite.Equal(http.StatusUnauthorized, resp.StatusCode)
+
+	req, _ = http.NewRequest(http.MethodPost, suite.baseURL+"/api/v1/categories", bytes.NewReader(data))
+	suite.authorize(req)
+	resp, _ = suite.client.Do(req)
+	suite.Equal(http.StatusOK, resp.StatusCode)
+	suite.adsService.AssertExpectations(suite.T())
+}
+
+func (suite *AdsApiTestSuite) TestUpdateCategory_Forbidden() {
+	suite.adsService.On("UpdateCategory", mock.AnythingOfType("*gin.Context"), int64(2), int64(3), "bikes", int64(0)).
+		Return(nil, user.ErrForbidden)
+	data, _ := json.Marshal(map[string]any{"name": "bikes"})
+	req, _ := http.NewRequest(http.MethodPut, suite.baseURL+"/api/v1/categories/3", bytes.NewReader(data))
+	suite.authorize(req)
+	resp, _ := suite.client.Do(req)
+	suite.Equal(http.StatusForbidden, resp.StatusCode)
+}
+
+func (suite *AdsApiTestSuite) TestDeleteCategory_InUse() {
+	suite.adsService.On("DeleteCategory", mock.AnythingOfType("*gin.Context"), int64(2), int64(3)).
+		Return(ads.ErrCategoryInUse)
+	req, _ := http.NewRequest(http.MethodDelete, suite.baseURL+"/api/v1/categories/3", nil)
+	suite.authorize(req)
+	resp, _ := suite.client.Do(req)
+	suite.Equal(http.StatusConflict, resp.StatusCode)
+}
+
 func TestAdsApi(t *testing.T) {
 	suite.Run(t, new(AdsApiTestSuite))
 }
@@ -1,16 +1,44 @@
 package adsport
 
 import (
+	"bytes"
+	"fmt"
 	"github.com/gin-gonic/gin"
 	"homework10/internal/adapters/adrepo"
 	"homework10/internal/adapters/userrepo"
 	"homework10/internal/app/adsapp"
 	"homework10/internal/entities/ads"
+	"homework10/internal/entities/user"
+	"homework10/internal/ports/httpgin/authport"
+	"io"
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
 )
 
+func setETag(c *gin.Context, ad *ads.Ad) {
+	c.Header("ETag", strconv.Quote(strconv.FormatInt(ad.Version, 10)))
+}
+
+// ifMatchVersion returns the version the If-Match header expects, zero for
+// any, a tag that is not ours turns into a version that never matches.
+func ifMatchVersion(c *gin.Context) int64 {
+	tag := strings.TrimSpace(c.GetHeader("If-Match"))
+	if tag == "" || tag == "*" {
+		return 0
+	}
+	tag, err := strconv.Unquote(strings.TrimPrefix(tag, "W/"))
+	if err != nil {
+		return -1
+	}
+	version, err := strconv.ParseInt(tag, 10, 64)
+	if err != nil || version <= 0 {
+		return -1
+	}
+	return version
+}
+
 func createAd(a adsapp.App) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var reqBody createAdRequest
@@ -19,10 +47,11 @@ func createAd(a adsapp.App) gin.HandlerFunc {
 			c.JSON(http.StatusBadRequest, AdErrorResponse(err))
 			return
 		}
-		ad, err := a.CreateAd(c, reqBody.Title, reqBody.Text, reqBody.UserID)
+		price := ads.Money{Amount: reqBody.Price.Amount, Currency: reqBody.Price.Currency}
+		ad, err := a.CreateAd(c, reqBody.Title, reqBody.Text, price, reqBody.CategoryID, authport.UserID(c))
 		if err != nil {
 			switch err {
-			case ads.ErrInvalidAdParams:
+			case ads.ErrInvalidAdParams, ads.ErrUnknownCategory, ads.ErrInvalidPrice:
 				c.JSON(http.StatusBadRequest, AdErrorResponse(err))
 			case userrepo.ErrInvalidUserId:
 				c.JSON(http.StatusNotFound, AdErrorResponse(err))
@@ -31,6 +60,7 @@ func createAd(a adsapp.App) gin.HandlerFunc {
 			}
 			return
 		}
+		setETag(c, ad)
 		c.JSON(http.StatusOK, AdSuccessResponse(ad))
 	}
 }
@@ -49,6 +79,7 @@ func getAdById(a adsapp.App) gin.HandlerFunc {
 			}
 			return
 		}
+		setETag(c, ad)
 		c.JSON(http.StatusOK, AdSuccessResponse(ad))
 	}
 }
@@ -72,12 +103,29 @@ func getAdsByTitle(a adsapp.App) gin.HandlerFunc {
 
 func getAllAds(a adsapp.App) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		desc, errOrder := ads.ParseOrder(c.Query("order"))
+		limit, errLimit := ads.ParseLimit(c.Query("limit"))
+		category, errCategory := parseCategory(c.Query("category"))
+		priceMin, errMin := ads.ParsePrice(c.Query("price_min"))
+		priceMax, errMax := ads.ParsePrice(c.Query("price_max"))
+		if errOrder != nil || errLimit != nil || errCategory != nil || errMin != nil || errMax != nil {
+			c.JSON(http.StatusBadRequest, AdErrorResponse(ads.ErrInvalidFilters))
+			return
+		}
 		filters := ads.Filters{
-			Status:   ads.Status(c.DefaultQuery("status", string(ads.Published))),
-			Date:     c.Query("date"),
-			AuthorId: c.Query("author_id"),
+			Status:     ads.Status(c.DefaultQuery("status", string(ads.Published))),
+			Date:       c.Query("date"),
+			AuthorId:   c.Query("author_id"),
+			CategoryId: category,
+			PriceMin:   priceMin,
+			PriceMax:   priceMax,
+			Currency:   c.Query("currency"),
+			Sort:       ads.SortField(c.Query("sort")),
+			Desc:       desc,
+			Limit:      limit,
+			Cursor:     c.Query("cursor"),
 		}
-		adsArr, err := a.GetAll(c, filters)
+		adsArr, nextCursor, err := a.GetAll(c, filters)
 		if err != nil {
 			switch err {
 			case ads.ErrInvalidFilters:
@@ -88,6 +136,22 @@ func getAllAds(a adsapp.App) gin.HandlerFunc {
 			return
 		}
 		log.Println(len(adsArr))
+		c.JSON(http.StatusOK, AdsPageSuccessResponse(adsArr, nextCursor))
+	}
+}
+
+func searchAds(a adsapp.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		adsArr, err := a.SearchAds(c, c.Query("q"))
+		if err != nil {
+			switch err {
+			case ads.ErrEmptySearchQuery:
+				c.JSON(http.StatusBadRequest, AdErrorResponse(err))
+			default:
+				c.JSON(http.StatusInternalServerError, AdErrorResponse(err))
+			}
+			return
+		}
 		c.JSON(http.StatusOK, AdsSuccessResponse(adsArr))
 	}
 }
@@ -100,7 +164,58 @@ func changeAdStatus(a adsapp.App) gin.HandlerFunc {
 			return
 		}
 		adId, _ := strconv.Atoi(c.Param("ad_id"))
-		ad, err := a.ChangeAdStatus(c, int64(adId), reqBody.UserID, reqBody.Published)
+		ad, err := a.ChangeAdStatus(c, int64(adId), authport.UserID(c), reqBody.Published)
+		if err != nil {
+			stateError(c, err)
+			return
+		}
+		setETag(c, ad)
+		c.JSON(http.StatusOK, AdSuccessResponse(ad))
+	}
+}
+
+func changeAdState(a adsapp.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var reqBody changeAdStateRequest
+		if err := c.BindJSON(&reqBody); err != nil {
+			c.JSON(http.StatusBadRequest, AdErrorResponse(err))
+			return
+		}
+		adId, _ := strconv.Atoi(c.Param("ad_id"))
+		ad, err := a.ChangeAdState(c, int64(adId), authport.UserID(c), ads.State(reqBody.State), reqBody.Reason)
+		if err != nil {
+			stateError(c, err)
+			return
+		}
+		setETag(c, ad)
+		c.JSON(http.StatusOK, AdSuccessResponse(ad))
+	}
+}
+
+func stateError(c *gin.Context, err error) {
+	switch err {
+	case ads.ErrUserCantChangeThisAd, user.ErrNotVerified:
+		c.JSON(http.StatusForbidden, AdErrorResponse(err))
+	case userrepo.ErrInvalidUserId, adrepo.ErrInvalidAdId:
+		c.JSON(http.StatusNotFound, AdErrorResponse(err))
+	case ads.ErrInvalidAdParams, ads.ErrInvalidState, ads.ErrReasonRequired:
+		c.JSON(http.StatusBadRequest, AdErrorResponse(err))
+	case ads.ErrIllegalTransition:
+		c.JSON(http.StatusConflict, AdErrorResponse(err))
+	default:
+		c.JSON(http.StatusInternalServerError, AdErrorResponse(err))
+	}
+}
+
+func unpublishAd(a adsapp.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var reqBody unpublishAdRequest
+		if err := c.BindJSON(&reqBody); err != nil {
+			c.JSON(http.StatusBadRequest, AdErrorResponse(err))
+			return
+		}
+		adId, _ := strconv.Atoi(c.Param("ad_id"))
+		ad, err := a.UnpublishAd(c, int64(adId), authport.UserID(c), reqBody.Reason)
 		if err != nil {
 			switch err {
 			case ads.ErrUserCantChangeThisAd:
@@ -109,17 +224,64 @@ func changeAdStatus(a adsapp.App) gin.HandlerFunc {
 				c.JSON(http.StatusNotFound, AdErrorResponse(err))
 			case adrepo.ErrInvalidAdId:
 				c.JSON(http.StatusNotFound, AdErrorResponse(err))
-			case ads.ErrInvalidAdParams:
+			case ads.ErrReasonRequired:
 				c.JSON(http.StatusBadRequest, AdErrorResponse(err))
 			default:
 				c.JSON(http.StatusInternalServerError, AdErrorResponse(err))
 			}
 			return
 		}
+		setETag(c, ad)
+		c.JSON(http.StatusOK, AdSuccessResponse(ad))
+	}
+}
+
+func scheduleAd(a adsapp.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var reqBody scheduleAdRequest
+		if err := c.BindJSON(&reqBody); err != nil {
+			c.JSON(http.StatusBadRequest, AdErrorResponse(err))
+			return
+		}
+		adId, _ := strconv.Atoi(c.Param("ad_id"))
+		ad, err := a.ScheduleAd(c, int64(adId), authport.UserID(c), reqBody.PublishAt, reqBody.ExpiresAt)
+		if err != nil {
+			scheduleError(c, err)
+			return
+		}
+		setETag(c, ad)
+		c.JSON(http.StatusOK, AdSuccessResponse(ad))
+	}
+}
+
+func renewAd(a adsapp.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		adId, _ := strconv.Atoi(c.Param("ad_id"))
+		ad, err := a.RenewAd(c, int64(adId), authport.UserID(c))
+		if err != nil {
+			scheduleError(c, err)
+			return
+		}
+		setETag(c, ad)
 		c.JSON(http.StatusOK, AdSuccessResponse(ad))
 	}
 }
 
+func scheduleError(c *gin.Context, err error) {
+	switch err {
+	case ads.ErrUserCantChangeThisAd, user.ErrNotVerified:
+		c.JSON(http.StatusForbidden, AdErrorResponse(err))
+	case userrepo.ErrInvalidUserId, adrepo.ErrInvalidAdId:
+		c.JSON(http.StatusNotFound, AdErrorResponse(err))
+	case ads.ErrInvalidSchedule:
+		c.JSON(http.StatusBadRequest, AdErrorResponse(err))
+	case ads.ErrCantRenew, ads.ErrIllegalTransition:
+		c.JSON(http.StatusConflict, AdErrorResponse(err))
+	default:
+		c.JSON(http.StatusInternalServerError, AdErrorResponse(err))
+	}
+}
+
 func updateAd(a adsapp.App) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var reqBody updateAdRequest
@@ -128,9 +290,11 @@ func updateAd(a adsapp.App) gin.HandlerFunc {
 			return
 		}
 		adId, _ := strconv.Atoi(c.Param("ad_id"))
-		ad, err := a.UpdateAd(c, int64(adId), reqBody.UserID, reqBody.Title, reqBody.Text)
+		ad, err := a.UpdateAd(c, int64(adId), authport.UserID(c), reqBody.Title, reqBody.Text, ifMatchVersion(c))
 		if err != nil {
 			switch err {
+			case ads.ErrVersionMismatch:
+				c.JSON(http.StatusPreconditionFailed, AdErrorResponse(err))
 			case ads.ErrUserCantChangeThisAd:
 				c.JSON(http.StatusForbidden, AdErrorResponse(err))
 			case userrepo.ErrInvalidUserId:
@@ -144,22 +308,19 @@ func updateAd(a adsapp.App) gin.HandlerFunc {
 			}
 			return
 		}
+		setETag(c, ad)
 		c.JSON(http.StatusOK, AdSuccessResponse(ad))
 	}
 }
 
 func deleteAd(a adsapp.App) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var reqBody deleteAdRequest
-		err := c.BindJSON(&reqBody)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, AdErrorResponse(err))
-			return
-		}
 		id, _ := strconv.Atoi(c.Param("ad_id"))
-		err = a.DeleteAd(c, int64(id), reqBody.UserID)
+		err := a.DeleteAd(c, int64(id), authport.UserID(c), c.Query("reason"))
 		if err != nil {
 			switch err {
+			case ads.ErrReasonRequired:
+				c.JSON(http.StatusBadRequest, AdErrorResponse(err))
 			case userrepo.ErrInvalidUserId:
 				c.JSON(http.StatusNotFound, AdErrorResponse(err))
 			case adrepo.ErrInvalidAdId:
@@ -174,3 +335,339 @@ func deleteAd(a adsapp.App) gin.HandlerFunc {
 		c.JSON(http.StatusOK, AdDeleteSuccessResponse())
 	}
 }
+
+func restoreAd(a adsapp.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		adId, _ := strconv.Atoi(c.Param("ad_id"))
+		ad, err := a.RestoreAd(c, int64(adId), authport.UserID(c))
+		if err != nil {
+			switch err {
+			case userrepo.ErrInvalidUserId:
+				c.JSON(http.StatusNotFound, AdErrorResponse(err))
+			case adrepo.ErrInvalidAdId:
+				c.JSON(http.StatusNotFound, AdErrorResponse(err))
+			default:
+				c.JSON(http.StatusInternalServerError, AdErrorResponse(err))
+			}
+			return
+		}
+		setETag(c, ad)
+		c.JSON(http.StatusOK, AdSuccessResponse(ad))
+	}
+}
+
+func getTrash(a adsapp.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		adsArr, err := a.GetTrash(c, authport.UserID(c))
+		if err != nil {
+			switch err {
+			case userrepo.ErrInvalidUserId:
+				c.JSON(http.StatusNotFound, AdErrorResponse(err))
+			default:
+				c.JSON(http.StatusInternalServerError, AdErrorResponse(err))
+			}
+			return
+		}
+		c.JSON(http.StatusOK, AdsSuccessResponse(adsArr))
+	}
+}
+
+func getRevisions(a adsapp.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		adId, _ := strconv.Atoi(c.Param("ad_id"))
+		revisions, err := a.GetRevisions(c, int64(adId))
+		if err != nil {
+			switch err {
+			case adrepo.ErrInvalidAdId:
+				c.JSON(http.StatusNotFound, AdErrorResponse(err))
+			default:
+				c.JSON(http.StatusInternalServerError, AdErrorResponse(err))
+			}
+			return
+		}
+		c.JSON(http.StatusOK, RevisionsSuccessResponse(revisions))
+	}
+}
+
+func getAdAtRevision(a adsapp.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		adId, _ := strconv.Atoi(c.Param("ad_id"))
+		revision, err := strconv.ParseInt(c.Param("revision"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, AdErrorResponse(ads.ErrInvalidRevision))
+			return
+		}
+		ad, err := a.GetAdAtRevision(c, int64(adId), revision)
+		if err != nil {
+			switch err {
+			case adrepo.ErrInvalidAdId:
+				c.JSON(http.StatusNotFound, AdErrorResponse(err))
+			case ads.ErrInvalidRevision:
+				c.JSON(http.StatusNotFound, AdErrorResponse(err))
+			default:
+				c.JSON(http.StatusInternalServerError, AdErrorResponse(err))
+			}
+			return
+		}
+		c.JSON(http.StatusOK, AdSuccessResponse(ad))
+	}
+}
+
+func revertAd(a adsapp.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		adId, _ := strconv.Atoi(c.Param("ad_id"))
+		revision, err := strconv.ParseInt(c.Param("revision"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, AdErrorResponse(ads.ErrInvalidRevision))
+			return
+		}
+		ad, err := a.RevertAd(c, int64(adId), authport.UserID(c), revision)
+		if err != nil {
+			switch err {
+			case ads.ErrUserCantChangeThisAd, user.ErrNotVerified:
+				c.JSON(http.StatusForbidden, AdErrorResponse(err))
+			case userrepo.ErrInvalidUserId:
+				c.JSON(http.StatusNotFound, AdErrorResponse(err))
+			case adrepo.ErrInvalidAdId:
+				c.JSON(http.StatusNotFound, AdErrorResponse(err))
+			case ads.ErrInvalidRevision:
+				c.JSON(http.StatusNotFound, AdErrorResponse(err))
+			case ads.ErrIllegalTransition:
+				c.JSON(http.StatusConflict, AdErrorResponse(err))
+			default:
+				c.JSON(http.StatusInternalServerError, AdErrorResponse(err))
+			}
+			return
+		}
+		setETag(c, ad)
+		c.JSON(http.StatusOK, AdSuccessResponse(ad))
+	}
+}
+
+// parseCategory reads the category filter, an empty one lists all the ads.
+func parseCategory(category string) (int64, error) {
+	if category == "" {
+		return 0, nil
+	}
+	id, err := strconv.ParseInt(category, 10, 64)
+	if err != nil || id <= 0 {
+		return 0, ads.ErrInvalidFilters
+	}
+	return id, nil
+}
+
+func listCategories(a adsapp.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		list, err := a.ListCategories(c)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, AdErrorResponse(err))
+			return
+		}
+		c.JSON(http.StatusOK, CategoriesSuccessResponse(list))
+	}
+}
+
+func getCategory(a adsapp.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, _ := strconv.Atoi(c.Param("category_id"))
+		category, err := a.GetCategory(c, int64(id))
+		if err != nil {
+			switch err {
+			case adrepo.ErrInvalidCategoryId:
+				c.JSON(http.StatusNotFound, AdErrorResponse(err))
+			default:
+				c.JSON(http.StatusInternalServerError, AdErrorResponse(err))
+			}
+			return
+		}
+		c.JSON(http.StatusOK, CategorySuccessResponse(category))
+	}
+}
+
+func createCategory(a adsapp.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var reqBody categoryRequest
+		err := c.BindJSON(&reqBody)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, AdErrorResponse(err))
+			return
+		}
+		category, err := a.CreateCategory(c, authport.UserID(c), reqBody.Name, reqBody.ParentID)
+		if err != nil {
+			categoryError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, CategorySuccessResponse(&ads.CategoryCount{Category: *category}))
+	}
+}
+
+func updateCategory(a adsapp.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var reqBody categoryRequest
+		err := c.BindJSON(&reqBody)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, AdErrorResponse(err))
+			return
+		}
+		id, _ := strconv.Atoi(c.Param("category_id"))
+		category, err := a.UpdateCategory(c, authport.UserID(c), int64(id), reqBody.Name, reqBody.ParentID)
+		if err != nil {
+			categoryError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, CategorySuccessResponse(&ads.CategoryCount{Category: *category}))
+	}
+}
+
+func deleteCategory(a adsapp.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, _ := strconv.Atoi(c.Param("category_id"))
+		err := a.DeleteCategory(c, authport.UserID(c), int64(id))
+		if err != nil {
+			categoryError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, CategoryDeleteSuccessResponse())
+	}
+}
+
+func categoryError(c *gin.Context, err error) {
+	switch err {
+	case ads.ErrInvalidCategory, ads.ErrUnknownCategory:
+		c.JSON(http.StatusBadRequest, AdErrorResponse(err))
+	case user.ErrForbidden:
+		c.JSON(http.StatusForbidden, AdErrorResponse(err))
+	case adrepo.ErrInvalidCategoryId, userrepo.ErrInvalidUserId:
+		c.JSON(http.StatusNotFound, AdErrorResponse(err))
+	case ads.ErrCategoryInUse:
+		c.JSON(http.StatusConflict, AdErrorResponse(err))
+	default:
+		c.JSON(http.StatusInternalServerError, AdErrorResponse(err))
+	}
+}
+
+// maxMultipartOverhead is the room the multipart envelope around an
+// uploaded image gets on top of the image itself.
+const maxMultipartOverhead = 64 << 10
+
+func addImage(a adsapp.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		adId, _ := strconv.Atoi(c.Param("ad_id"))
+		limit := int64(ads.MaxImageSize + maxMultipartOverhead)
+		if c.Request.ContentLength > limit {
+			c.JSON(http.StatusRequestEntityTooLarge, AdErrorResponse(ads.ErrImageTooLarge))
+			return
+		}
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+		header, err := c.FormFile("image")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, AdErrorResponse(err))
+			return
+		}
+		if header.Size > ads.MaxImageSize {
+			c.JSON(http.StatusRequestEntityTooLarge, AdErrorResponse(ads.ErrImageTooLarge))
+			return
+		}
+		f, err := header.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, AdErrorResponse(err))
+			return
+		}
+		data, err := io.ReadAll(f)
+		_ = f.Close()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, AdErrorResponse(err))
+			return
+		}
+		img, err := a.AddImage(c, int64(adId), authport.UserID(c), header.Header.Get("Content-Type"), data)
+		if err != nil {
+			imageError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, ImageSuccessResponse(c.Request.URL.Path, img))
+	}
+}
+
+func getImages(a adsapp.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		adId, _ := strconv.Atoi(c.Param("ad_id"))
+		list, err := a.GetImages(c, int64(adId))
+		if err != nil {
+			imageError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, ImagesSuccessResponse(c.Request.URL.Path, list))
+	}
+}
+
+// getImageFile serves the image or, with the size parameter, a thumbnail,
+// the files never change and may be cached for good.
+func getImageFile(a adsapp.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		adId, _ := strconv.Atoi(c.Param("ad_id"))
+		imageId, _ := strconv.Atoi(c.Param("image_id"))
+		variant, err := ads.ParseVariant(c.Query("size"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, AdErrorResponse(err))
+			return
+		}
+		img, data, err := a.GetImageFile(c, int64(adId), int64(imageId), variant)
+		if err != nil {
+			imageError(c, err)
+			return
+		}
+		c.Header("Content-Type", img.ContentTypeOf(variant))
+		c.Header("Cache-Control", "public, max-age=31536000, immutable")
+		c.Header("ETag", strconv.Quote(fmt.Sprintf("%d-%s", img.ID, variant)))
+		c.Header("X-Content-Type-Options", "nosniff")
+		http.ServeContent(c.Writer, c.Request, "", img.CreatedAt, bytes.NewReader(data))
+	}
+}
+
+func reorderImages(a adsapp.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var reqBody reorderImagesRequest
+		err := c.BindJSON(&reqBody)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, AdErrorResponse(err))
+			return
+		}
+		adId, _ := strconv.Atoi(c.Param("ad_id"))
+		list, err := a.ReorderImages(c, int64(adId), authport.UserID(c), reqBody.Order)
+		if err != nil {
+			imageError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, ImagesSuccessResponse(c.Request.URL.Path, list))
+	}
+}
+
+func deleteImage(a adsapp.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		adId, _ := strconv.Atoi(c.Param("ad_id"))
+		imageId, _ := strconv.Atoi(c.Param("image_id"))
+		err := a.DeleteImage(c, int64(adId), authport.UserID(c), int64(imageId))
+		if err != nil {
+			imageError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, ImageDeleteSuccessResponse())
+	}
+}
+
+func imageError(c *gin.Context, err error) {
+	switch err {
+	case ads.ErrInvalidImage, ads.ErrInvalidImageOrder:
+		c.JSON(http.StatusBadRequest, AdErrorResponse(err))
+	case ads.ErrUserCantChangeThisAd, user.ErrNotVerified:
+		c.JSON(http.StatusForbidden, AdErrorResponse(err))
+	case adrepo.ErrInvalidAdId, adrepo.ErrInvalidImageId, userrepo.ErrInvalidUserId:
+		c.JSON(http.StatusNotFound, AdErrorResponse(err))
+	case ads.ErrTooManyImages:
+		c.JSON(http.StatusConflict, AdErrorResponse(err))
+	case ads.ErrImageTooLarge:
+		c.JSON(http.StatusRequestEntityTooLarge, AdErrorResponse(err))
+	default:
+		c.JSON(http.StatusInternalServerError, AdErrorResponse(err))
+	}
+}
@@ -3,9 +3,9 @@
 package mocks
 
 import (
-	ads "homework10/internal/entities/ads"
-
 	context "context"
+	ads "homework10/internal/entities/ads"
+	time "time"
 
 	mock "github.com/stretchr/testify/mock"
 )
@@ -15,6 +15,82 @@ type App struct {
 	mock.Mock
 }
 
+// AddImage provides a mock function with given fields: ctx, adId, userId, contentType, data
+func (_m *App) AddImage(ctx context.Context, adId int64, userId int64, contentType string, data []byte) (*ads.Image, error) {
+	ret := _m.Called(ctx, adId, userId, contentType, data)
+
+	var r0 *ads.Image
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64, string, []byte) (*ads.Image, error)); ok {
+		return rf(ctx, adId, userId, contentType, data)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64, string, []byte) *ads.Image); ok {
+		r0 = rf(ctx, adId, userId, contentType, data)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*ads.Image)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, int64, string, []byte) error); ok {
+		r1 = rf(ctx, adId, userId, contentType, data)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ApplySchedule provides a mock function with given fields: ctx
+func (_m *App) ApplySchedule(ctx context.Context) (int, error) {
+	ret := _m.Called(ctx)
+
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) (int, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) int); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ChangeAdState provides a mock function with given fields: ctx, adId, userId, state, reason
+func (_m *App) ChangeAdState(ctx context.Context, adId int64, userId int64, state ads.State, reason string) (*ads.Ad, error) {
+	ret := _m.Called(ctx, adId, userId, state, reason)
+
+	var r0 *ads.Ad
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64, ads.State, string) (*ads.Ad, error)); ok {
+		return rf(ctx, adId, userId, state, reason)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64, ads.State, string) *ads.Ad); ok {
+		r0 = rf(ctx, adId, userId, state, reason)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*ads.Ad)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, int64, ads.State, string) error); ok {
+		r1 = rf(ctx, adId, userId, state, reason)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // ChangeAdStatus provides a mock function with given fields: ctx, adId, userId, newStatus
 func (_m *App) ChangeAdStatus(ctx context.Context, adId int64, userId int64, newStatus bool) (*ads.Ad, error) {
 	ret := _m.Called(ctx, adId, userId, newStatus)
@@ -41,25 +117,25 @@ func (_m *App) ChangeAdStatus(ctx context.Context, adId int64, userId int64, new
 	return r0, r1
 }
 
-// CreateAd provides a mock function with given fields: ctx, title, text, id
-func (_m *App) CreateAd(ctx context.Context, title string, text string, id int64) (*ads.Ad, error) {
-	ret := _m.Called(ctx, title, text, id)
+// CreateAd provides a mock function with given fields: ctx, title, text, price, categoryId, userId
+func (_m *App) CreateAd(ctx context.Context, title string, text string, price ads.Money, categoryId int64, userId int64) (*ads.Ad, error) {
+	ret := _m.Called(ctx, title, text, price, categoryId, userId)
 
 	var r0 *ads.Ad
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, string, string, int64) (*ads.Ad, error)); ok {
-		return rf(ctx, title, text, id)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, ads.Money, int64, int64) (*ads.Ad, error)); ok {
+		return rf(ctx, title, text, price, categoryId, userId)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, string, string, int64) *ads.Ad); ok {
-		r0 = rf(ctx, title, text, id)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, ads.Money, int64, int64) *ads.Ad); ok {
+		r0 = rf(ctx, title, text, price, categoryId, userId)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*ads.Ad)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, string, string, int64) error); ok {
-		r1 = rf(ctx, title, text, id)
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, ads.Money, int64, int64) error); ok {
+		r1 = rf(ctx, title, text, price, categoryId, userId)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -67,13 +143,67 @@ func (_m *App) CreateAd(ctx context.Context, title string, text string, id int64
 	return r0, r1
 }
 
-// DeleteAd provides a mock function with given fields: ctx, adId, userId
-func (_m *App) DeleteAd(ctx context.Context, adId int64, userId int64) error {
-	ret := _m.Called(ctx, adId, userId)
+// CreateCategory provides a mock function with given fields: ctx, actorId, name, parentId
+func (_m *App) CreateCategory(ctx context.Context, actorId int64, name string, parentId int64) (*ads.Category, error) {
+	ret := _m.Called(ctx, actorId, name, parentId)
+
+	var r0 *ads.Category
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string, int64) (*ads.Category, error)); ok {
+		return rf(ctx, actorId, name, parentId)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string, int64) *ads.Category); ok {
+		r0 = rf(ctx, actorId, name, parentId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*ads.Category)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, string, int64) error); ok {
+		r1 = rf(ctx, actorId, name, parentId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DeleteAd provides a mock function with given fields: ctx, adId, userId, reason
+func (_m *App) DeleteAd(ctx context.Context, adId int64, userId int64, reason string) error {
+	ret := _m.Called(ctx, adId, userId, reason)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64, string) error); ok {
+		r0 = rf(ctx, adId, userId, reason)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeleteCategory provides a mock function with given fields: ctx, actorId, id
+func (_m *App) DeleteCategory(ctx context.Context, actorId int64, id int64) error {
+	ret := _m.Called(ctx, actorId, id)
 
 	var r0 error
 	if rf, ok := ret.Get(0).(func(context.Context, int64, int64) error); ok {
-		r0 = rf(ctx, adId, userId)
+		r0 = rf(ctx, actorId, id)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeleteImage provides a mock function with given fields: ctx, adId, userId, imageId
+func (_m *App) DeleteImage(ctx context.Context, adId int64, userId int64, imageId int64) error {
+	ret := _m.Called(ctx, adId, userId, imageId)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64, int64) error); ok {
+		r0 = rf(ctx, adId, userId, imageId)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -81,6 +211,32 @@ func (_m *App) DeleteAd(ctx context.Context, adId int64, userId int64) error {
 	return r0
 }
 
+// GetAdAtRevision provides a mock function with given fields: ctx, adId, revision
+func (_m *App) GetAdAtRevision(ctx context.Context, adId int64, revision int64) (*ads.Ad, error) {
+	ret := _m.Called(ctx, adId, revision)
+
+	var r0 *ads.Ad
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64) (*ads.Ad, error)); ok {
+		return rf(ctx, adId, revision)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64) *ads.Ad); ok {
+		r0 = rf(ctx, adId, revision)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*ads.Ad)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, int64) error); ok {
+		r1 = rf(ctx, adId, revision)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetAdById provides a mock function with given fields: ctx, id
 func (_m *App) GetAdById(ctx context.Context, id int64) (*ads.Ad, error) {
 	ret := _m.Called(ctx, id)
@@ -134,12 +290,13 @@ func (_m *App) GetAdsByTitle(ctx context.Context, title string) ([]*ads.Ad, erro
 }
 
 // GetAll provides a mock function with given fields: ctx, filters
-func (_m *App) GetAll(ctx context.Context, filters ads.Filters) ([]*ads.Ad, error) {
+func (_m *App) GetAll(ctx context.Context, filters ads.Filters) ([]*ads.Ad, string, error) {
 	ret := _m.Called(ctx, filters)
 
 	var r0 []*ads.Ad
-	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, ads.Filters) ([]*ads.Ad, error)); ok {
+	var r1 string
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, ads.Filters) ([]*ads.Ad, string, error)); ok {
 		return rf(ctx, filters)
 	}
 	if rf, ok := ret.Get(0).(func(context.Context, ads.Filters) []*ads.Ad); ok {
@@ -150,8 +307,101 @@ func (_m *App) GetAll(ctx context.Context, filters ads.Filters) ([]*ads.Ad, erro
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, ads.Filters) error); ok {
+	if rf, ok := ret.Get(1).(func(context.Context, ads.Filters) string); ok {
 		r1 = rf(ctx, filters)
+	} else {
+		r1 = ret.Get(1).(string)
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, ads.Filters) error); ok {
+		r2 = rf(ctx, filters)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// GetCategory provides a mock function with given fields: ctx, id
+func (_m *App) GetCategory(ctx context.Context, id int64) (*ads.CategoryCount, error) {
+	ret := _m.Called(ctx, id)
+
+	var r0 *ads.CategoryCount
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) (*ads.CategoryCount, error)); ok {
+		return rf(ctx, id)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) *ads.CategoryCount); ok {
+		r0 = rf(ctx, id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*ads.CategoryCount)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetImageFile provides a mock function with given fields: ctx, adId, imageId, variant
+func (_m *App) GetImageFile(ctx context.Context, adId int64, imageId int64, variant ads.ImageVariant) (*ads.Image, []byte, error) {
+	ret := _m.Called(ctx, adId, imageId, variant)
+
+	var r0 *ads.Image
+	var r1 []byte
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64, ads.ImageVariant) (*ads.Image, []byte, error)); ok {
+		return rf(ctx, adId, imageId, variant)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64, ads.ImageVariant) *ads.Image); ok {
+		r0 = rf(ctx, adId, imageId, variant)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*ads.Image)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, int64, ads.ImageVariant) []byte); ok {
+		r1 = rf(ctx, adId, imageId, variant)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).([]byte)
+		}
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, int64, int64, ads.ImageVariant) error); ok {
+		r2 = rf(ctx, adId, imageId, variant)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// GetImages provides a mock function with given fields: ctx, adId
+func (_m *App) GetImages(ctx context.Context, adId int64) ([]*ads.Image, error) {
+	ret := _m.Called(ctx, adId)
+
+	var r0 []*ads.Image
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) ([]*ads.Image, error)); ok {
+		return rf(ctx, adId)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) []*ads.Image); ok {
+		r0 = rf(ctx, adId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*ads.Image)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, adId)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -159,25 +409,325 @@ func (_m *App) GetAll(ctx context.Context, filters ads.Filters) ([]*ads.Ad, erro
 	return r0, r1
 }
 
-// UpdateAd provides a mock function with given fields: ctx, adId, userId, title, text
-func (_m *App) UpdateAd(ctx context.Context, adId int64, userId int64, title string, text string) (*ads.Ad, error) {
-	ret := _m.Called(ctx, adId, userId, title, text)
+// GetRevisions provides a mock function with given fields: ctx, adId
+func (_m *App) GetRevisions(ctx context.Context, adId int64) ([]*ads.Revision, error) {
+	ret := _m.Called(ctx, adId)
+
+	var r0 []*ads.Revision
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) ([]*ads.Revision, error)); ok {
+		return rf(ctx, adId)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) []*ads.Revision); ok {
+		r0 = rf(ctx, adId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*ads.Revision)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, adId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetTrash provides a mock function with given fields: ctx, userId
+func (_m *App) GetTrash(ctx context.Context, userId int64) ([]*ads.Ad, error) {
+	ret := _m.Called(ctx, userId)
+
+	var r0 []*ads.Ad
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) ([]*ads.Ad, error)); ok {
+		return rf(ctx, userId)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) []*ads.Ad); ok {
+		r0 = rf(ctx, userId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*ads.Ad)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, userId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListCategories provides a mock function with given fields: ctx
+func (_m *App) ListCategories(ctx context.Context) ([]*ads.CategoryCount, error) {
+	ret := _m.Called(ctx)
+
+	var r0 []*ads.CategoryCount
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) ([]*ads.CategoryCount, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) []*ads.CategoryCount); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*ads.CategoryCount)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// PurgeTrash provides a mock function with given fields: ctx, olderThan
+func (_m *App) PurgeTrash(ctx context.Context, olderThan time.Duration) error {
+	ret := _m.Called(ctx, olderThan)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, time.Duration) error); ok {
+		r0 = rf(ctx, olderThan)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RenewAd provides a mock function with given fields: ctx, adId, userId
+func (_m *App) RenewAd(ctx context.Context, adId int64, userId int64) (*ads.Ad, error) {
+	ret := _m.Called(ctx, adId, userId)
+
+	var r0 *ads.Ad
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64) (*ads.Ad, error)); ok {
+		return rf(ctx, adId, userId)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64) *ads.Ad); ok {
+		r0 = rf(ctx, adId, userId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*ads.Ad)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, int64) error); ok {
+		r1 = rf(ctx, adId, userId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ReorderImages provides a mock function with given fields: ctx, adId, userId, order
+func (_m *App) ReorderImages(ctx context.Context, adId int64, userId int64, order []int64) ([]*ads.Image, error) {
+	ret := _m.Called(ctx, adId, userId, order)
+
+	var r0 []*ads.Image
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64, []int64) ([]*ads.Image, error)); ok {
+		return rf(ctx, adId, userId, order)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64, []int64) []*ads.Image); ok {
+		r0 = rf(ctx, adId, userId, order)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*ads.Image)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, int64, []int64) error); ok {
+		r1 = rf(ctx, adId, userId, order)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RestoreAd provides a mock function with given fields: ctx, adId, userId
+func (_m *App) RestoreAd(ctx context.Context, adId int64, userId int64) (*ads.Ad, error) {
+	ret := _m.Called(ctx, adId, userId)
 
 	var r0 *ads.Ad
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, int64, int64, string, string) (*ads.Ad, error)); ok {
-		return rf(ctx, adId, userId, title, text)
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64) (*ads.Ad, error)); ok {
+		return rf(ctx, adId, userId)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, int64, int64, string, string) *ads.Ad); ok {
-		r0 = rf(ctx, adId, userId, title, text)
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64) *ads.Ad); ok {
+		r0 = rf(ctx, adId, userId)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*ads.Ad)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, int64, int64, string, string) error); ok {
-		r1 = rf(ctx, adId, userId, title, text)
+	if rf, ok := ret.Get(1).(func(context.Context, int64, int64) error); ok {
+		r1 = rf(ctx, adId, userId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RevertAd provides a mock function with given fields: ctx, adId, userId, revision
+func (_m *App) RevertAd(ctx context.Context, adId int64, userId int64, revision int64) (*ads.Ad, error) {
+	ret := _m.Called(ctx, adId, userId, revision)
+
+	var r0 *ads.Ad
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64, int64) (*ads.Ad, error)); ok {
+		return rf(ctx, adId, userId, revision)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64, int64) *ads.Ad); ok {
+		r0 = rf(ctx, adId, userId, revision)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*ads.Ad)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, int64, int64) error); ok {
+		r1 = rf(ctx, adId, userId, revision)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ScheduleAd provides a mock function with given fields: ctx, adId, userId, publishAt, expiresAt
+func (_m *App) ScheduleAd(ctx context.Context, adId int64, userId int64, publishAt time.Time, expiresAt time.Time) (*ads.Ad, error) {
+	ret := _m.Called(ctx, adId, userId, publishAt, expiresAt)
+
+	var r0 *ads.Ad
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64, time.Time, time.Time) (*ads.Ad, error)); ok {
+		return rf(ctx, adId, userId, publishAt, expiresAt)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64, time.Time, time.Time) *ads.Ad); ok {
+		r0 = rf(ctx, adId, userId, publishAt, expiresAt)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*ads.Ad)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, int64, time.Time, time.Time) error); ok {
+		r1 = rf(ctx, adId, userId, publishAt, expiresAt)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SearchAds provides a mock function with given fields: ctx, query
+func (_m *App) SearchAds(ctx context.Context, query string) ([]*ads.Ad, error) {
+	ret := _m.Called(ctx, query)
+
+	var r0 []*ads.Ad
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]*ads.Ad, error)); ok {
+		return rf(ctx, query)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []*ads.Ad); ok {
+		r0 = rf(ctx, query)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*ads.Ad)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, query)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UnpublishAd provides a mock function with given fields: ctx, adId, userId, reason
+func (_m *App) UnpublishAd(ctx context.Context, adId int64, userId int64, reason string) (*ads.Ad, error) {
+	ret := _m.Called(ctx, adId, userId, reason)
+
+	var r0 *ads.Ad
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64, string) (*ads.Ad, error)); ok {
+		return rf(ctx, adId, userId, reason)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64, string) *ads.Ad); ok {
+		r0 = rf(ctx, adId, userId, reason)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*ads.Ad)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, int64, string) error); ok {
+		r1 = rf(ctx, adId, userId, reason)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UpdateAd provides a mock function with given fields: ctx, adId, userId, title, text, version
+func (_m *App) UpdateAd(ctx context.Context, adId int64, userId int64, title string, text string, version int64) (*ads.Ad, error) {
+	ret := _m.Called(ctx, adId, userId, title, text, version)
+
+	var r0 *ads.Ad
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64, string, string, int64) (*ads.Ad, error)); ok {
+		return rf(ctx, adId, userId, title, text, version)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64, string, string, int64) *ads.Ad); ok {
+		r0 = rf(ctx, adId, userId, title, text, version)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*ads.Ad)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, int64, string, string, int64) error); ok {
+		r1 = rf(ctx, adId, userId, title, text, version)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UpdateCategory provides a mock function with given fields: ctx, actorId, id, name, parentId
+func (_m *App) UpdateCategory(ctx context.Context, actorId int64, id int64, name string, parentId int64) (*ads.Category, error) {
+	ret := _m.Called(ctx, actorId, id, name, parentId)
+
+	var r0 *ads.Category
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64, string, int64) (*ads.Category, error)); ok {
+		return rf(ctx, actorId, id, name, parentId)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64, string, int64) *ads.Category); ok {
+		r0 = rf(ctx, actorId, id, name, parentId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*ads.Category)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, int64, string, int64) error); ok {
+		r1 = rf(ctx, actorId, id, name, parentId)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -1,39 +1,118 @@
 package adsport
 
 import (
+	"fmt"
 	"github.com/gin-gonic/gin"
 	"homework10/internal/entities/ads"
+	"strings"
+	"time"
 )
 
 type createAdRequest struct {
-	Title  string `json:"title"`
-	Text   string `json:"text"`
-	UserID int64  `json:"user_id"`
+	Title      string `json:"title"`
+	Text       string `json:"text"`
+	CategoryID int64  `json:"category_id"`
+	// Price may be left out, the ad has no price then
+	Price priceData `json:"price"`
+}
+
+// priceData is the amount in the minor units of the currency, e.g. 1250
+// RUB is 12.50 rubles.
+type priceData struct {
+	Amount   int64  `json:"amount"`
+	Currency string `json:"currency"`
 }
 
 type adResponse struct {
-	ID           int64  `json:"id"`
-	Title        string `json:"title"`
-	Text         string `json:"text"`
-	AuthorID     int64  `json:"author_id"`
-	CreationDate string `json:"creation_date"`
-	UpdateDate   string `json:"update_date"`
-	Published    bool   `json:"published"`
+	ID           int64      `json:"id"`
+	Title        string     `json:"title"`
+	Text         string     `json:"text"`
+	AuthorID     int64      `json:"author_id"`
+	CategoryID   int64      `json:"category_id"`
+	Price        *priceData `json:"price,omitempty"`
+	CreationDate string     `json:"creation_date"`
+	UpdateDate   string     `json:"update_date"`
+	Published    bool       `json:"published"`
+	State        string     `json:"state"`
+	// RejectionReason is what the moderator said rejecting the ad
+	RejectionReason string `json:"rejection_reason,omitempty"`
+	Version         int64  `json:"version"`
+	DeletedAt       string `json:"deleted_at,omitempty"`
+	PublishAt       string `json:"publish_at,omitempty"`
+	ExpiresAt       string `json:"expires_at,omitempty"`
 }
 
 type changeAdStatusRequest struct {
-	Published bool  `json:"published"`
-	UserID    int64 `json:"user_id"`
+	Published bool `json:"published"`
+}
+
+// changeAdStateRequest takes one of ads.States, the reason is required to
+// reject the ad.
+type changeAdStateRequest struct {
+	State  string `json:"state"`
+	Reason string `json:"reason"`
+}
+
+type unpublishAdRequest struct {
+	Reason string `json:"reason"`
+}
+
+// scheduleAdRequest takes RFC 3339 times, the ones left out are not set.
+type scheduleAdRequest struct {
+	PublishAt time.Time `json:"publish_at"`
+	ExpiresAt time.Time `json:"expires_at"`
 }
 
 type updateAdRequest struct {
-	Title  string `json:"title"`
-	Text   string `json:"text"`
-	UserID int64  `json:"user_id"`
+	Title string `json:"title"`
+	Text  string `json:"text"`
+}
+
+type categoryRequest struct {
+	Name     string `json:"name"`
+	ParentID int64  `json:"parent_id"`
 }
 
-type deleteAdRequest struct {
-	UserID int64 `json:"user_id"`
+type categoryResponse struct {
+	ID       int64  `json:"id"`
+	ParentID int64  `json:"parent_id"`
+	Name     string `json:"name"`
+	Ads      int    `json:"ads"`
+}
+
+type reorderImagesRequest struct {
+	Order []int64 `json:"order"`
+}
+
+type imageResponse struct {
+	ID          int64  `json:"id"`
+	Position    int    `json:"position"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+	Width       int    `json:"width"`
+	Height      int    `json:"height"`
+	CreatedAt   string `json:"created_at"`
+	URL         string `json:"url"`
+	// Thumbnails are the urls of the thumbnails by size
+	Thumbnails map[string]string `json:"thumbnails"`
+}
+
+type changeResponse struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+type revisionResponse struct {
+	Number    int64            `json:"number"`
+	ActorID   int64            `json:"actor_id"`
+	Reason    string           `json:"reason,omitempty"`
+	CreatedAt string           `json:"created_at"`
+	Title     string           `json:"title"`
+	Text      string           `json:"text"`
+	Published bool             `json:"published"`
+	State     string           `json:"state"`
+	Changes   []changeResponse `json:"changes"`
 }
 
 func AdDeleteSuccessResponse() *gin.H {
@@ -46,13 +125,21 @@ func AdDeleteSuccessResponse() *gin.H {
 func AdSuccessResponse(ad *ads.Ad) *gin.H {
 	return &gin.H{
 		"data": adResponse{
-			ID:           ad.ID,
-			Title:        ad.Title,
-			Text:         ad.Text,
-			AuthorID:     ad.AuthorID,
-			Published:    ad.Published,
-			CreationDate: ad.CreationDate,
-			UpdateDate:   ad.UpdateDate,
+			ID:              ad.ID,
+			Title:           ad.Title,
+			Text:            ad.Text,
+			AuthorID:        ad.AuthorID,
+			CategoryID:      ad.CategoryID,
+			Price:           price(ad),
+			Published:       ad.Published(),
+			State:           string(ad.State),
+			RejectionReason: ad.RejectionReason,
+			CreationDate:    ad.CreationDate,
+			UpdateDate:      ad.UpdateDate,
+			Version:         ad.Version,
+			DeletedAt:       deletedAt(ad),
+			PublishAt:       formatTime(ad.PublishAt),
+			ExpiresAt:       formatTime(ad.ExpiresAt),
 		},
 		"error": nil,
 	}
@@ -62,13 +149,21 @@ func AdsSuccessResponse(ads []*ads.Ad) *gin.H {
 	resp := make([]adResponse, len(ads))
 	for i, ad := range ads {
 		resp[i] = adResponse{
-			ID:           ad.ID,
-			Title:        ad.Title,
-			Text:         ad.Text,
-			AuthorID:     ad.AuthorID,
-			Published:    ad.Published,
-			CreationDate: ad.CreationDate,
-			UpdateDate:   ad.UpdateDate,
+			ID:              ad.ID,
+			Title:           ad.Title,
+			Text:            ad.Text,
+			AuthorID:        ad.AuthorID,
+			CategoryID:      ad.CategoryID,
+			Price:           price(ad),
+			Published:       ad.Published(),
+			State:           string(ad.State),
+			RejectionReason: ad.RejectionReason,
+			CreationDate:    ad.CreationDate,
+			UpdateDate:      ad.UpdateDate,
+			Version:         ad.Version,
+			DeletedAt:       deletedAt(ad),
+			PublishAt:       formatTime(ad.PublishAt),
+			ExpiresAt:       formatTime(ad.ExpiresAt),
 		}
 	}
 	return &gin.H{
@@ -77,9 +172,138 @@ func AdsSuccessResponse(ads []*ads.Ad) *gin.H {
 	}
 }
 
+func AdsPageSuccessResponse(ads []*ads.Ad, nextCursor string) *gin.H {
+	resp := AdsSuccessResponse(ads)
+	(*resp)["next_cursor"] = nextCursor
+	return resp
+}
+
+func RevisionsSuccessResponse(revisions []*ads.Revision) *gin.H {
+	resp := make([]revisionResponse, len(revisions))
+	for i, rev := range revisions {
+		changes := make([]changeResponse, len(rev.Changes))
+		for j, change := range rev.Changes {
+			changes[j] = changeResponse{Field: change.Field, Old: change.Old, New: change.New}
+		}
+		resp[i] = revisionResponse{
+			Number:    rev.Number,
+			ActorID:   rev.ActorID,
+			Reason:    rev.Reason,
+			CreatedAt: rev.CreatedAt.Format(time.RFC3339Nano),
+			Title:     rev.Title,
+			Text:      rev.Text,
+			Published: rev.State == ads.StatePublished,
+			State:     string(rev.State),
+			Changes:   changes,
+		}
+	}
+	return &gin.H{
+		"data":  resp,
+		"error": nil,
+	}
+}
+
+func CategoryDeleteSuccessResponse() *gin.H {
+	return &gin.H{
+		"data":  "category successfully deleted",
+		"error": nil,
+	}
+}
+
+func CategorySuccessResponse(c *ads.CategoryCount) *gin.H {
+	return &gin.H{
+		"data":  toCategoryResponse(c),
+		"error": nil,
+	}
+}
+
+func CategoriesSuccessResponse(list []*ads.CategoryCount) *gin.H {
+	resp := make([]categoryResponse, len(list))
+	for i, c := range list {
+		resp[i] = toCategoryResponse(c)
+	}
+	return &gin.H{
+		"data":  resp,
+		"error": nil,
+	}
+}
+
+func toCategoryResponse(c *ads.CategoryCount) categoryResponse {
+	return categoryResponse{ID: c.ID, ParentID: c.ParentID, Name: c.Name, Ads: c.Ads}
+}
+
+func ImageDeleteSuccessResponse() *gin.H {
+	return &gin.H{
+		"data":  "image successfully deleted",
+		"error": nil,
+	}
+}
+
+// ImageSuccessResponse gives the urls of the files under path, the path of
+// the images of the ad.
+func ImageSuccessResponse(path string, img *ads.Image) *gin.H {
+	return &gin.H{
+		"data":  toImageResponse(path, img),
+		"error": nil,
+	}
+}
+
+func ImagesSuccessResponse(path string, list []*ads.Image) *gin.H {
+	resp := make([]imageResponse, len(list))
+	for i, img := range list {
+		resp[i] = toImageResponse(path, img)
+	}
+	return &gin.H{
+		"data":  resp,
+		"error": nil,
+	}
+}
+
+func toImageResponse(path string, img *ads.Image) imageResponse {
+	url := fmt.Sprintf("%s/%d", strings.TrimSuffix(path, "/"), img.ID)
+	thumbnails := make(map[string]string, len(ads.Thumbnails))
+	for v := range ads.Thumbnails {
+		thumbnails[string(v)] = url + "?size=" + string(v)
+	}
+	return imageResponse{
+		ID:          img.ID,
+		Position:    img.Position,
+		ContentType: img.ContentType,
+		Size:        img.Size,
+		Width:       img.Width,
+		Height:      img.Height,
+		CreatedAt:   img.CreatedAt.Format(time.RFC3339Nano),
+		URL:         url,
+		Thumbnails:  thumbnails,
+	}
+}
+
 func AdErrorResponse(err error) *gin.H {
 	return &gin.H{
 		"data":  nil,
 		"error": err.Error(),
 	}
 }
+
+// price is nil for the ads without one.
+func price(ad *ads.Ad) *priceData {
+	if ad.Price.IsZero() {
+		return nil
+	}
+	return &priceData{Amount: ad.Price.Amount, Currency: ad.Price.Currency}
+}
+
+// formatTime leaves out the zero time.
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+func deletedAt(ad *ads.Ad) string {
+	if !ad.Trashed() {
+		return ""
+	}
+	return ad.DeletedAt.Format(time.RFC3339)
+}
@@ -5,12 +5,34 @@ import (
 	"homework10/internal/app/adsapp"
 )
 
-func AppRouter(r *gin.RouterGroup, a adsapp.App) {
-	r.POST("/ads", createAd(a))
+// AppRouter registers the ads routes, the ones acting on behalf of a user
+// go through read or write.
+func AppRouter(r *gin.RouterGroup, a adsapp.App, read, write gin.HandlerFunc) {
+	r.POST("/ads", write, createAd(a))
 	r.GET("/ads", getAllAds(a))
 	r.GET("/ads/id/:ad_id", getAdById(a))
 	r.GET("/ads/title/:title", getAdsByTitle(a))
-	r.PUT("/ads/:ad_id/status", changeAdStatus(a))
-	r.PUT("/ads/:ad_id/text", updateAd(a))
-	r.DELETE("/ads/:ad_id/delete", deleteAd(a))
+	r.GET("/ads/search", searchAds(a))
+	r.PUT("/ads/:ad_id/status", write, changeAdStatus(a))
+	r.PUT("/ads/:ad_id/state", write, changeAdState(a))
+	r.POST("/ads/:ad_id/unpublish", write, unpublishAd(a))
+	r.PUT("/ads/:ad_id/schedule", write, scheduleAd(a))
+	r.POST("/ads/:ad_id/renew", write, renewAd(a))
+	r.PUT("/ads/:ad_id/text", write, updateAd(a))
+	r.DELETE("/ads/:ad_id/delete", write, deleteAd(a))
+	r.GET("/ads/trash", read, getTrash(a))
+	r.POST("/ads/:ad_id/restore", write, restoreAd(a))
+	r.GET("/ads/:ad_id/revisions", getRevisions(a))
+	r.GET("/ads/:ad_id/revisions/:revision", getAdAtRevision(a))
+	r.POST("/ads/:ad_id/revisions/:revision/revert", write, revertAd(a))
+	r.GET("/categories", listCategories(a))
+	r.GET("/categories/:category_id", getCategory(a))
+	r.POST("/categories", write, createCategory(a))
+	r.PUT("/categories/:category_id", write, updateCategory(a))
+	r.DELETE("/categories/:category_id", write, deleteCategory(a))
+	r.GET("/ads/:ad_id/images", getImages(a))
+	r.POST("/ads/:ad_id/images", write, addImage(a))
+	r.PUT("/ads/:ad_id/images", write, reorderImages(a))
+	r.GET("/ads/:ad_id/images/:image_id", getImageFile(a))
+	r.DELETE("/ads/:ad_id/images/:image_id", write, deleteImage(a))
 }
@@ -0,0 +1,106 @@
+package httpgin
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"homework10/internal/adapters/userrepo"
+	"homework10/internal/app/authapp"
+	"homework10/internal/entities/user"
+	adsServiceMock "homework10/internal/ports/httpgin/adsport/mocks"
+	authServiceMock "homework10/internal/ports/httpgin/authport/mocks"
+	userServiceMock "homework10/internal/ports/httpgin/userport/mocks"
+	"homework10/pkg/logger"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type tokensData struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+type tokensResponse struct {
+	Data tokensData `json:"data"`
+}
+
+type AuthApiTestSuite struct {
+	suite.Suite
+	authService *authServiceMock.App
+	client      *http.Client
+	baseURL     string
+}
+
+func (suite *AuthApiTestSuite) SetupTest() {
+	suite.authService = &authServiceMock.App{}
+	server := NewHTTPServer(":18080", &adsServiceMock.App{}, &userServiceMock.App{}, suite.authService, nil, logger.InitLog())
+	testServer := httptest.NewServer(server.Handler)
+	suite.client = testServer.Client()
+	suite.baseURL = testServer.URL
+}
+
+func (suite *AuthApiTestSuite) post(path string, body map[string]any) *http.Response {
+	data, _ := json.Marshal(body)
+	req, _ := http.NewRequest(http.MethodPost, suite.baseURL+path, bytes.NewReader(data))
+	resp, _ := suite.client.Do(req)
+	return resp
+}
+
+func (suite *AuthApiTestSuite) TestLogin_OK() {
+	suite.authService.On("Login", mock.AnythingOfType("*gin.Context"), int64(1), "password").
+		Return(&authapp.Tokens{Access: "access", Refresh: "refresh", ExpiresAt: time.Now().Add(time.Hour)}, nil)
+	resp := suite.post("/api/v1/auth/login", map[string]any{"user_id": 1, "password": "password"})
+	suite.Equal(resp.StatusCode, http.StatusOK)
+	var response tokensResponse
+	respBody, _ := io.ReadAll(resp.Body)
+	_ = json.Unmarshal(respBody, &response)
+	suite.Equal("access", response.Data.AccessToken)
+	suite.Equal("refresh", response.Data.RefreshToken)
+	suite.Equal("Bearer", response.Data.TokenType)
+	suite.InDelta(3600, response.Data.ExpiresIn, 5)
+}
+
+func (suite *AuthApiTestSuite) TestLogin_WrongCredentials() {
+	suite.authService.On("Login", mock.AnythingOfType("*gin.Context"), int64(1), mock.AnythingOfType("string")).
+		Return(nil, user.ErrWrongPassword)
+	suite.authService.On("Login", mock.AnythingOfType("*gin.Context"), int64(2), mock.AnythingOfType("string")).
+		Return(nil, userrepo.ErrInvalidUserId)
+	resp := suite.post("/api/v1/auth/login", map[string]any{"user_id": 1, "password": "wrong"})
+	suite.Equal(resp.StatusCode, http.StatusUnauthorized)
+	resp = suite.post("/api/v1/auth/login", map[string]any{"user_id": 2, "password": "password"})
+	suite.Equal(resp.StatusCode, http.StatusUnauthorized)
+}
+
+func (suite *AuthApiTestSuite) TestLogin_NilBody() {
+	req, _ := http.NewRequest(http.MethodPost, suite.baseURL+"/api/v1/auth/login", nil)
+	resp, _ := suite.client.Do(req)
+	suite.Equal(resp.StatusCode, http.StatusBadRequest)
+}
+
+func (suite *AuthApiTestSuite) TestRefresh_OK() {
+	suite.authService.On("Refresh", mock.AnythingOfType("*gin.Context"), "refresh").
+		Return(&authapp.Tokens{Access: "new access", Refresh: "new refresh", ExpiresAt: time.Now().Add(time.Hour)}, nil)
+	resp := suite.post("/api/v1/auth/refresh", map[string]any{"refresh_token": "refresh"})
+	suite.Equal(resp.StatusCode, http.StatusOK)
+	var response tokensResponse
+	respBody, _ := io.ReadAll(resp.Body)
+	_ = json.Unmarshal(respBody, &response)
+	suite.Equal("new access", response.Data.AccessToken)
+}
+
+func (suite *AuthApiTestSuite) TestRefresh_InvalidToken() {
+	suite.authService.On("Refresh", mock.AnythingOfType("*gin.Context"), mock.AnythingOfType("string")).
+		Return(nil, authapp.ErrInvalidToken)
+	resp := suite.post("/api/v1/auth/refresh", map[string]any{"refresh_token": "expired"})
+	suite.Equal(resp.StatusCode, http.StatusUnauthorized)
+}
+
+func TestAuthApi(t *testing.T) {
+	suite.Run(t, new(AuthApiTestSuite))
+}
@@ -0,0 +1,52 @@
+package authport
+
+import (
+	"github.com/gin-gonic/gin"
+	"homework10/internal/adapters/userrepo"
+	"homework10/internal/app/authapp"
+	"homework10/internal/entities/user"
+	"net/http"
+)
+
+func login(a authapp.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var reqBody loginRequest
+		if err := c.BindJSON(&reqBody); err != nil {
+			c.JSON(http.StatusBadRequest, AuthErrorResponse(err))
+			return
+		}
+		tokens, err := a.Login(c, reqBody.UserID, reqBody.Password)
+		if err != nil {
+			switch err {
+			case user.ErrWrongPassword, userrepo.ErrInvalidUserId:
+				// the caller does not learn which of the two was wrong
+				c.JSON(http.StatusUnauthorized, AuthErrorResponse(user.ErrWrongPassword))
+			default:
+				c.JSON(http.StatusInternalServerError, AuthErrorResponse(err))
+			}
+			return
+		}
+		c.JSON(http.StatusOK, TokensSuccessResponse(tokens))
+	}
+}
+
+func refresh(a authapp.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var reqBody refreshRequest
+		if err := c.BindJSON(&reqBody); err != nil {
+			c.JSON(http.StatusBadRequest, AuthErrorResponse(err))
+			return
+		}
+		tokens, err := a.Refresh(c, reqBody.RefreshToken)
+		if err != nil {
+			switch err {
+			case authapp.ErrInvalidToken:
+				c.JSON(http.StatusUnauthorized, AuthErrorResponse(err))
+			default:
+				c.JSON(http.StatusInternalServerError, AuthErrorResponse(err))
+			}
+			return
+		}
+		c.JSON(http.StatusOK, TokensSuccessResponse(tokens))
+	}
+}
@@ -0,0 +1,104 @@
+package authport
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"homework10/internal/app/authapp"
+	"homework10/internal/entities/user"
+	"net/http"
+	"strings"
+)
+
+const (
+	userIdKey    = "auth_user_id"
+	principalKey = "auth_principal"
+
+	APIKeyHeader = "X-API-Key"
+)
+
+var (
+	ErrMissingScope = errors.New("the api key lacks the scope of this request")
+)
+
+// Authenticated lets through the requests with a valid access token in the
+// Authorization: Bearer header and records whose token it is for UserID.
+func Authenticated(a authapp.App) gin.HandlerFunc {
+	return authenticated(a, "")
+}
+
+// Scoped also lets through the requests with an api key in the X-API-Key
+// header, if the key has the scope.
+func Scoped(a authapp.App, scope user.Scope) gin.HandlerFunc {
+	return authenticated(a, scope)
+}
+
+func authenticated(a authapp.App, scope user.Scope) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, ok := Principal(c)
+		// the principal Identify found came from the key when there was one,
+		// the routes without a scope take the token only
+		if !ok || (principal.KeyID != "" && scope == "") {
+			var err error
+			principal, err = authenticate(c, a, scope != "")
+			if err != nil {
+				unauthorized(c)
+				return
+			}
+		}
+		if scope != "" && !principal.Allows(scope) {
+			c.AbortWithStatusJSON(http.StatusForbidden, AuthErrorResponse(ErrMissingScope))
+			return
+		}
+		c.Set(userIdKey, principal.UserID)
+		c.Next()
+	}
+}
+
+// Identify looks up who made the request ahead of the routes, once, invalid
+// credentials pass as anonymous until a route needs a user.
+func Identify(a authapp.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := Principal(c); ok {
+			c.Next()
+			return
+		}
+		if principal, err := authenticate(c, a, true); err == nil {
+			c.Set(principalKey, principal)
+		}
+		c.Next()
+	}
+}
+
+// Principal returns the user found by Identify, false for anonymous
+// requests.
+func Principal(c *gin.Context) (*authapp.Principal, bool) {
+	principal, ok := c.Get(principalKey)
+	if !ok {
+		return nil, false
+	}
+	return principal.(*authapp.Principal), true
+}
+
+// authenticate takes the api key if there is one and keys are accepted,
+// the bearer token otherwise.
+func authenticate(c *gin.Context, a authapp.App, keys bool) (*authapp.Principal, error) {
+	if key := c.GetHeader(APIKeyHeader); key != "" && keys {
+		return a.AuthenticateKey(c, strings.TrimSpace(key))
+	}
+	scheme, token, _ := strings.Cut(c.GetHeader("Authorization"), " ")
+	if !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return nil, authapp.ErrInvalidToken
+	}
+	return a.Authenticate(c, strings.TrimSpace(token))
+}
+
+// UserID returns the user who made the request, it is only set behind
+// Authenticated.
+func UserID(c *gin.Context) int64 {
+	return c.GetInt64(userIdKey)
+}
+
+func unauthorized(c *gin.Context) {
+	c.Header("WWW-Authenticate", `Bearer realm="api"`)
+	c.AbortWithStatusJSON(http.StatusUnauthorized, AuthErrorResponse(authapp.ErrInvalidToken))
+}
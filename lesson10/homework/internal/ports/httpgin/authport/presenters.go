@@ -0,0 +1,42 @@
+package authport
+
+import (
+	"github.com/gin-gonic/gin"
+	"homework10/internal/app/authapp"
+	"time"
+)
+
+type loginRequest struct {
+	UserID   int64  `json:"user_id"`
+	Password string `json:"password"`
+}
+
+type refreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type tokensResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+func TokensSuccessResponse(tokens *authapp.Tokens) *gin.H {
+	return &gin.H{
+		"data": tokensResponse{
+			AccessToken:  tokens.Access,
+			RefreshToken: tokens.Refresh,
+			TokenType:    "Bearer",
+			ExpiresIn:    int64(time.Until(tokens.ExpiresAt).Seconds()),
+		},
+		"error": nil,
+	}
+}
+
+func AuthErrorResponse(err error) *gin.H {
+	return &gin.H{
+		"data":  nil,
+		"error": err.Error(),
+	}
+}
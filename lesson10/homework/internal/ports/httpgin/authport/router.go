@@ -0,0 +1,11 @@
+package authport
+
+import (
+	"github.com/gin-gonic/gin"
+	"homework10/internal/app/authapp"
+)
+
+func AppRouter(r *gin.RouterGroup, a authapp.App) {
+	r.POST("/auth/login", login(a))
+	r.POST("/auth/refresh", refresh(a))
+}
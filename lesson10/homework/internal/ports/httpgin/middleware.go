@@ -1,11 +1,20 @@
 package httpgin
 
 import (
+	"errors"
 	"github.com/gin-gonic/gin"
+	"homework10/internal/ports/httpgin/authport"
 	"homework10/pkg/logger"
+	"homework10/pkg/ratelimit"
+	"net/http"
+	"strconv"
 	"time"
 )
 
+var (
+	ErrTooManyRequests = errors.New("too many requests, retry later")
+)
+
 func Logger(log logger.Logger) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		t := time.Now()
@@ -17,3 +26,34 @@ func Logger(log logger.Logger) gin.HandlerFunc {
 			c.Request.URL.Path, "\tStatus:", status)
 	}
 }
+
+// RateLimit limits the requests of every client to a route, e.g. POST
+// /api/v1/ads, anonymous clients are told apart by their address.
+func RateLimit(limiter *ratelimit.Limiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		client := "ip:" + c.ClientIP()
+		if principal, ok := authport.Principal(c); ok {
+			client = principal.Client()
+		}
+		ok, wait := limiter.Allow(c.Request.Method+" "+c.FullPath(), client)
+		if !ok {
+			c.Header("Retry-After", strconv.Itoa(ratelimit.RetryAfter(wait)))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"data": nil, "error": ErrTooManyRequests.Error()})
+			return
+		}
+		c.Next()
+	}
+}
+
+// DefaultRateLimits are tighter than the default limit for the routes that
+// create things or send mail, and the login, which guesses passwords.
+func DefaultRateLimits() map[string]ratelimit.Limit {
+	return map[string]ratelimit.Limit{
+		"POST /api/v1/ads":                  {Requests: 30, Per: time.Minute},
+		"POST /api/v1/ads/:ad_id/images":    {Requests: 30, Per: time.Minute},
+		"POST /api/v1/user":                 {Requests: 10, Per: time.Hour},
+		"POST /api/v1/user/verification":    {Requests: 5, Per: time.Hour},
+		"POST /api/v1/user/password/forgot": {Requests: 5, Per: time.Hour},
+		"POST /api/v1/auth/login":           {Requests: 10, Per: time.Minute},
+	}
+}
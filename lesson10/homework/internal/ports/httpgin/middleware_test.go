@@ -0,0 +1,77 @@
+package httpgin
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"homework10/internal/app/authapp"
+	"homework10/internal/entities/ads"
+	"homework10/internal/entities/user"
+	adsServiceMock "homework10/internal/ports/httpgin/adsport/mocks"
+	authServiceMock "homework10/internal/ports/httpgin/authport/mocks"
+	"homework10/internal/ports/httpgin/userport/mocks"
+	"homework10/pkg/logger"
+	"homework10/pkg/ratelimit"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRateLimit(t *testing.T) {
+	adsService, authService := &adsServiceMock.App{}, &authServiceMock.App{}
+	adsService.On("GetAdById", mock.AnythingOfType("*gin.Context"), mock.AnythingOfType("int64")).
+		Return(&ads.Ad{ID: 1}, nil)
+	authService.On("Authenticate", mock.AnythingOfType("*gin.Context"), "token").
+		Return(&authapp.Principal{UserID: 2}, nil)
+	authService.On("Authenticate", mock.AnythingOfType("*gin.Context"), mock.AnythingOfType("string")).
+		Return(nil, authapp.ErrInvalidToken)
+	authService.On("AuthenticateKey", mock.AnythingOfType("*gin.Context"), "key").
+		Return(&authapp.Principal{UserID: 2, KeyID: "key", Scopes: []user.Scope{user.ScopeAdsRead}}, nil)
+	limiter := ratelimit.New(ratelimit.Rules{
+		Default: ratelimit.Limit{Requests: 100, Per: time.Minute},
+		Limits:  map[string]ratelimit.Limit{"GET /api/v1/ads/id/:ad_id": {Requests: 2, Per: time.Minute}},
+	})
+	server := NewHTTPServer(":18080", adsService, &mocks.App{}, authService, limiter, logger.InitLog())
+	testServer := httptest.NewServer(server.Handler)
+	defer testServer.Close()
+
+	get := func(header, value string) *http.Response {
+		req, _ := http.NewRequest(http.MethodGet, testServer.URL+"/api/v1/ads/id/1", nil)
+		if header != "" {
+			req.Header.Add(header, value)
+		}
+		// X-Forwarded-For is not trusted, it can't dodge the limit
+		req.Header.Add("X-Forwarded-For", "10.0.0."+value)
+		resp, err := testServer.Client().Do(req)
+		assert.NoError(t, err)
+		return resp
+	}
+
+	// the anonymous clients share the limit of their address, an invalid
+	// token doesn't get a bucket of its own
+	assert.Equal(t, http.StatusOK, get("", "1").StatusCode)
+	assert.Equal(t, http.StatusOK, get("", "2").StatusCode)
+	resp := get("Authorization", "Bearer garbage")
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	assert.Equal(t, "30", resp.Header.Get("Retry-After"))
+
+	// the users and their api keys have their own
+	for i := 0; i < 2; i++ {
+		assert.Equal(t, http.StatusOK, get("Authorization", "Bearer token").StatusCode)
+		assert.Equal(t, http.StatusOK, get("X-API-Key", "key").StatusCode)
+	}
+	assert.Equal(t, http.StatusTooManyRequests, get("Authorization", "Bearer token").StatusCode)
+	assert.Equal(t, http.StatusTooManyRequests, get("X-API-Key", "key").StatusCode)
+
+	// the limit is the route's, whatever the ad, and the other routes have
+	// limits of their own
+	req, _ := http.NewRequest(http.MethodGet, testServer.URL+"/api/v1/ads/id/2", nil)
+	resp, err := testServer.Client().Do(req)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	req, _ = http.NewRequest(http.MethodGet, testServer.URL+"/api/v1/ads/trash", nil)
+	req.Header.Add("Authorization", "Bearer garbage")
+	resp, err = testServer.Client().Do(req)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
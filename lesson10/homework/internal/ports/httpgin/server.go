@@ -2,22 +2,37 @@ package httpgin
 
 import (
 	"homework10/internal/app/adsapp"
+	"homework10/internal/app/authapp"
 	"homework10/internal/app/userapp"
+	"homework10/internal/entities/user"
 	"homework10/internal/ports/httpgin/adsport"
+	"homework10/internal/ports/httpgin/authport"
 	"homework10/internal/ports/httpgin/userport"
 	"homework10/pkg/logger"
+	"homework10/pkg/ratelimit"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 )
 
-func NewHTTPServer(port string, ad adsapp.App, user userapp.App, log logger.Logger) *http.Server {
+// NewHTTPServer limits the requests with limiter, a nil one lets them all
+// through.
+func NewHTTPServer(port string, ad adsapp.App, users userapp.App, auth authapp.App, limiter *ratelimit.Limiter,
+	log logger.Logger) *http.Server {
 	gin.SetMode(gin.ReleaseMode)
 	handler := gin.New()
+	// X-Forwarded-For is not trusted, it would let the clients pick the
+	// address they are rate limited by
+	_ = handler.SetTrustedProxies(nil)
 	api := handler.Group("/api/v1", Logger(log), gin.Recovery())
+	if limiter != nil {
+		api.Use(authport.Identify(auth), RateLimit(limiter))
+	}
 	{
-		adsport.AppRouter(api, ad)
-		userport.AppRouter(api, user)
+		authenticated := authport.Authenticated(auth)
+		adsport.AppRouter(api, ad, authport.Scoped(auth, user.ScopeAdsRead), authport.Scoped(auth, user.ScopeAdsWrite))
+		userport.AppRouter(api, users, authenticated, authport.Identify(auth))
+		authport.AppRouter(api, auth)
 	}
 
 	return &http.Server{Addr: port, Handler: handler}
@@ -7,14 +7,19 @@ import (
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
 	"homework10/internal/adapters/userrepo"
+	"homework10/internal/app/authapp"
+	"homework10/internal/app/userapp"
+	"homework10/internal/entities/ads"
 	"homework10/internal/entities/user"
 	ads1ServiceMock "homework10/internal/ports/httpgin/adsport/mocks"
+	authServiceMock "homework10/internal/ports/httpgin/authport/mocks"
 	user1ServiceMock "homework10/internal/ports/httpgin/userport/mocks"
 	"homework10/pkg/logger"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 )
 
 var ErrUnexpected = errors.New("unexpected server error")
@@ -24,6 +29,8 @@ type userData struct {
 	Nickname string `json:"nickname"`
 	Email    string `json:"email"`
 	Password string `json:"password"`
+	Role     string `json:"role"`
+	Verified bool   `json:"verified"`
 }
 
 type userResponse struct {
@@ -39,7 +46,10 @@ type UserApiTestSuite struct {
 
 func (suite *UserApiTestSuite) SetupTest() {
 	suite.userService = &user1ServiceMock.App{}
-	server := NewHTTPServer(":18080", &ads1ServiceMock.App{}, suite.userService, logger.InitLog())
+	authService := &authServiceMock.App{}
+	authService.On("Authenticate", mock.AnythingOfType("*gin.Context"), "token").
+		Return(&authapp.Principal{UserID: 2, Role: user.RoleAdmin}, nil)
+	server := NewHTTPServer(":18080", &ads1ServiceMock.App{}, suite.userService, authService, nil, logger.InitLog())
 	testServer := httptest.NewServer(server.Handler)
 	suite.client = testServer.Client()
 	suite.baseURL = testServer.URL
@@ -85,6 +95,27 @@ func (suite *UserApiTestSuite) TestCreateUser_InvalidUserParams() {
 	suite.Equal(resp.StatusCode, http.StatusBadRequest)
 }
 
+func (suite *UserApiTestSuite) TestCreateUser_Email() {
+	suite.userService.On("CreateUser", mock.AnythingOfType("*gin.Context"), "nick", "email", "password").
+		Return(nil, user.ErrInvalidEmail)
+	suite.userService.On("CreateUser", mock.AnythingOfType("*gin.Context"), "nick", "taken@mail.com", "password").
+		Return(nil, user.ErrEmailTaken)
+
+	tests := []struct {
+		email  string
+		status int
+	}{
+		{"email", http.StatusBadRequest},
+		{"taken@mail.com", http.StatusConflict},
+	}
+	for _, tc := range tests {
+		data, _ := json.Marshal(map[string]any{"nickname": "nick", "email": tc.email, "password": "password"})
+		req, _ := http.NewRequest(http.MethodPost, suite.baseURL+"/api/v1/user", bytes.NewReader(data))
+		resp, _ := suite.client.Do(req)
+		suite.Equal(tc.status, resp.StatusCode, tc.email)
+	}
+}
+
 func (suite *UserApiTestSuite) TestCreateUser_UnexpectedError() {
 	suite.userService.On("CreateUser", mock.AnythingOfType("*gin.Context"), mock.AnythingOfType("string"),
 		mock.AnythingOfType("string"), mock.AnythingOfType("string")).
@@ -101,7 +132,7 @@ func (suite *UserApiTestSuite) TestCreateUser_UnexpectedError() {
 }
 
 func (suite *UserApiTestSuite) TestChangeNickname_Ok() {
-	suite.userService.On("ChangeNickname", mock.AnythingOfType("*gin.Context"), mock.AnythingOfType("int64"),
+	suite.userService.On("ChangeNickname", mock.AnythingOfType("*gin.Context"), int64(2), mock.AnythingOfType("int64"),
 		mock.AnythingOfType("string")).
 		Return(&user.User{Nickname: "new nick", Email: "email", Password: "pass", Id: 1}, nil)
 	body := map[string]any{
@@ -109,6 +140,7 @@ func (suite *UserApiTestSuite) TestChangeNickname_Ok() {
 	}
 	data, _ := json.Marshal(body)
 	req, _ := http.NewRequest(http.MethodPut, suite.baseURL+"/api/v1/user/1/nick", bytes.NewReader(data))
+	req.Header.Add("Authorization", "Bearer token")
 	resp, _ := suite.client.Do(req)
 	suite.Equal(resp.StatusCode, http.StatusOK)
 	var response userResponse
@@ -119,12 +151,13 @@ func (suite *UserApiTestSuite) TestChangeNickname_Ok() {
 
 func (suite *UserApiTestSuite) TestChangeNickname_NilBody() {
 	req, _ := http.NewRequest(http.MethodPut, suite.baseURL+"/api/v1/user/1/nick", nil)
+	req.Header.Add("Authorization", "Bearer token")
 	resp, _ := suite.client.Do(req)
 	suite.Equal(resp.StatusCode, http.StatusBadRequest)
 }
 
 func (suite *UserApiTestSuite) TestChangeNickname_InvalidUserParams() {
-	suite.userService.On("ChangeNickname", mock.AnythingOfType("*gin.Context"), mock.AnythingOfType("int64"),
+	suite.userService.On("ChangeNickname", mock.AnythingOfType("*gin.Context"), int64(2), mock.AnythingOfType("int64"),
 		mock.AnythingOfType("string")).
 		Return(nil, user.ErrInvalidUserParams)
 	body := map[string]any{
@@ -132,12 +165,13 @@ func (suite *UserApiTestSuite) TestChangeNickname_InvalidUserParams() {
 	}
 	data, _ := json.Marshal(body)
 	req, _ := http.NewRequest(http.MethodPut, suite.baseURL+"/api/v1/user/1/nick", bytes.NewReader(data))
+	req.Header.Add("Authorization", "Bearer token")
 	resp, _ := suite.client.Do(req)
 	suite.Equal(resp.StatusCode, http.StatusBadRequest)
 }
 
 func (suite *UserApiTestSuite) TestChangeNickname_InvalidUserId() {
-	suite.userService.On("ChangeNickname", mock.AnythingOfType("*gin.Context"), mock.AnythingOfType("int64"),
+	suite.userService.On("ChangeNickname", mock.AnythingOfType("*gin.Context"), int64(2), mock.AnythingOfType("int64"),
 		mock.AnythingOfType("string")).
 		Return(nil, userrepo.ErrInvalidUserId)
 	body := map[string]any{
@@ -145,12 +179,13 @@ func (suite *UserApiTestSuite) TestChangeNickname_InvalidUserId() {
 	}
 	data, _ := json.Marshal(body)
 	req, _ := http.NewRequest(http.MethodPut, suite.baseURL+"/api/v1/user/-1/nick", bytes.NewReader(data))
+	req.Header.Add("Authorization", "Bearer token")
 	resp, _ := suite.client.Do(req)
 	suite.Equal(resp.StatusCode, http.StatusNotFound)
 }
 
 func (suite *UserApiTestSuite) TestChangeNickname_UnexpectedError() {
-	suite.userService.On("ChangeNickname", mock.AnythingOfType("*gin.Context"), mock.AnythingOfType("int64"),
+	suite.userService.On("ChangeNickname", mock.AnythingOfType("*gin.Context"), int64(2), mock.AnythingOfType("int64"),
 		mock.AnythingOfType("string")).
 		Return(nil, ErrUnexpected)
 	body := map[string]any{
@@ -158,13 +193,14 @@ func (suite *UserApiTestSuite) TestChangeNickname_UnexpectedError() {
 	}
 	data, _ := json.Marshal(body)
 	req, _ := http.NewRequest(http.MethodPut, suite.baseURL+"/api/v1/user/-1/nick", bytes.NewReader(data))
+	req.Header.Add("Authorization", "Bearer token")
 	resp, _ := suite.client.Do(req)
 	suite.Equal(resp.StatusCode, http.StatusInternalServerError)
 }
 
 func (suite *UserApiTestSuite) TestUpdatePassword_Ok() {
 	suite.userService.On("UpdatePassword", mock.AnythingOfType("*gin.Context"), mock.AnythingOfType("int64"),
-		mock.AnythingOfType("string")).
+		mock.AnythingOfType("string"), mock.AnythingOfType("string")).
 		Return(&user.User{Nickname: "nick", Email: "email", Password: "new pass", Id: 1}, nil)
 	body := map[string]any{
 		"password": "new pass",
@@ -183,7 +219,7 @@ func (suite *UserApiTestSuite) TestUpdatePassword_NilBody() {
 
 func (suite *UserApiTestSuite) TestUpdatePassword_InvalidUserParams() {
 	suite.userService.On("UpdatePassword", mock.AnythingOfType("*gin.Context"), mock.AnythingOfType("int64"),
-		mock.AnythingOfType("string")).
+		mock.AnythingOfType("string"), mock.AnythingOfType("string")).
 		Return(nil, user.ErrInvalidUserParams)
 	body := map[string]any{
 		"password": "",
@@ -196,7 +232,7 @@ func (suite *UserApiTestSuite) TestUpdatePassword_InvalidUserParams() {
 
 func (suite *UserApiTestSuite) TestUpdatePassword_InvalidUserId() {
 	suite.userService.On("UpdatePassword", mock.AnythingOfType("*gin.Context"), mock.AnythingOfType("int64"),
-		mock.AnythingOfType("string")).
+		mock.AnythingOfType("string"), mock.AnythingOfType("string")).
 		Return(nil, userrepo.ErrInvalidUserId)
 	body := map[string]any{
 		"password": "new pass",
@@ -209,7 +245,7 @@ func (suite *UserApiTestSuite) TestUpdatePassword_InvalidUserId() {
 
 func (suite *UserApiTestSuite) TestUpdatePassword_UnexpectedError() {
 	suite.userService.On("UpdatePassword", mock.AnythingOfType("*gin.Context"), mock.AnythingOfType("int64"),
-		mock.AnythingOfType("string")).
+		mock.AnythingOfType("string"), mock.AnythingOfType("string")).
 		Return(nil, ErrUnexpected)
 	body := map[string]any{
 		"password": "new pass",
@@ -221,29 +257,61 @@ func (suite *UserApiTestSuite) TestUpdatePassword_UnexpectedError() {
 }
 
 func (suite *UserApiTestSuite) TestDeleteUser_Ok() {
-	suite.userService.On("DeleteUser", mock.AnythingOfType("*gin.Context"), mock.AnythingOfType("int64")).
+	suite.userService.On("DeleteUser", mock.AnythingOfType("*gin.Context"), int64(2), mock.AnythingOfType("int64")).
 		Return(nil)
 	req, _ := http.NewRequest(http.MethodDelete, suite.baseURL+"/api/v1/user/1/delete", nil)
+	req.Header.Add("Authorization", "Bearer token")
 	resp, _ := suite.client.Do(req)
 	suite.Equal(resp.StatusCode, http.StatusOK)
 }
 
 func (suite *UserApiTestSuite) TestDeleteUser_InvalidUserId() {
-	suite.userService.On("DeleteUser", mock.AnythingOfType("*gin.Context"), mock.AnythingOfType("int64")).
+	suite.userService.On("DeleteUser", mock.AnythingOfType("*gin.Context"), int64(2), mock.AnythingOfType("int64")).
 		Return(userrepo.ErrInvalidUserId)
 	req, _ := http.NewRequest(http.MethodDelete, suite.baseURL+"/api/v1/user/-1/delete", nil)
+	req.Header.Add("Authorization", "Bearer token")
 	resp, _ := suite.client.Do(req)
 	suite.Equal(resp.StatusCode, http.StatusNotFound)
 }
 
 func (suite *UserApiTestSuite) TestDeleteUser_UnexpectedError() {
-	suite.userService.On("DeleteUser", mock.AnythingOfType("*gin.Context"), mock.AnythingOfType("int64")).
+	suite.userService.On("DeleteUser", mock.AnythingOfType("*gin.Context"), int64(2), mock.AnythingOfType("int64")).
 		Return(ErrUnexpected)
 	req, _ := http.NewRequest(http.MethodDelete, suite.baseURL+"/api/v1/user/1/delete", nil)
+	req.Header.Add("Authorization", "Bearer token")
 	resp, _ := suite.client.Do(req)
 	suite.Equal(resp.StatusCode, http.StatusInternalServerError)
 }
 
+func (suite *UserApiTestSuite) TestManageOthers() {
+	suite.userService.On("ChangeNickname", mock.AnythingOfType("*gin.Context"), int64(2), int64(5),
+		mock.AnythingOfType("string")).
+		Return(nil, user.ErrForbidden)
+	suite.userService.On("DeleteUser", mock.AnythingOfType("*gin.Context"), int64(2), int64(5)).
+		Return(user.ErrForbidden)
+	data, _ := json.Marshal(map[string]any{"nickname": "stolen"})
+
+	for _, token := range []string{"", "token"} {
+		status := http.StatusUnauthorized
+		if token != "" {
+			status = http.StatusForbidden
+		}
+		req, _ := http.NewRequest(http.MethodPut, suite.baseURL+"/api/v1/user/5/nick", bytes.NewReader(data))
+		if token != "" {
+			req.Header.Add("Authorization", "Bearer "+token)
+		}
+		resp, _ := suite.client.Do(req)
+		suite.Equal(status, resp.StatusCode)
+
+		req, _ = http.NewRequest(http.MethodDelete, suite.baseURL+"/api/v1/user/5/delete", nil)
+		if token != "" {
+			req.Header.Add("Authorization", "Bearer "+token)
+		}
+		resp, _ = suite.client.Do(req)
+		suite.Equal(status, resp.StatusCode)
+	}
+}
+
 func (suite *UserApiTestSuite) TestGetUser_Ok() {
 	suite.userService.On("GetUser", mock.AnythingOfType("*gin.Context"), mock.AnythingOfType("int64")).
 		Return(&user.User{Nickname: "nick", Email: "email", Password: "pass", Id: 1}, nil)
@@ -272,6 +340,444 @@ func (suite *UserApiTestSuite) TestGetUser_UnexpectedError() {
 	suite.Equal(resp.StatusCode, http.StatusInternalServerError)
 }
 
+func (suite *UserApiTestSuite) TestChangeRole() {
+	suite.userService.On("ChangeRole", mock.AnythingOfType("*gin.Context"), int64(2), int64(1), user.RoleModerator).
+		Return(&user.User{Nickname: "nick", Email: "email", Id: 1, Role: user.RoleModerator}, nil)
+	suite.userService.On("ChangeRole", mock.AnythingOfType("*gin.Context"), int64(2), int64(1), user.Role("root")).
+		Return(nil, user.ErrInvalidRole)
+	suite.userService.On("ChangeRole", mock.AnythingOfType("*gin.Context"), int64(2), int64(3), user.RoleAdmin).
+		Return(nil, user.ErrForbidden)
+
+	tests := []struct {
+		userId string
+		body   string
+		token  string
+		status int
+	}{
+		{"1", `{"role":"moderator"}`, "token", http.StatusOK},
+		{"1", `{"role":"root"}`, "token", http.StatusBadRequest},
+		{"3", `{"role":"admin"}`, "token", http.StatusForbidden},
+		{"1", `{"role":"moderator"}`, "", http.StatusUnauthorized},
+	}
+	for _, tc := range tests {
+		req, _ := http.NewRequest(http.MethodPut, suite.baseURL+"/api/v1/user/"+tc.userId+"/role",
+			bytes.NewReader([]byte(tc.body)))
+		req.Header.Add("Content-Type", "application/json")
+		if tc.token != "" {
+			req.Header.Add("Authorization", "Bearer "+tc.token)
+		}
+		resp, _ := suite.client.Do(req)
+		suite.Equal(tc.status, resp.StatusCode, tc.body)
+		if tc.status != http.StatusOK {
+			continue
+		}
+		var response userResponse
+		respBody, _ := io.ReadAll(resp.Body)
+		_ = json.Unmarshal(respBody, &response)
+		suite.Equal("moderator", response.Data.Role)
+	}
+}
+
+func (suite *UserApiTestSuite) TestVerifyEmail() {
+	suite.userService.On("VerifyEmail", mock.AnythingOfType("*gin.Context"), "good").
+		Return(&user.User{Nickname: "nick", Email: "nick@mail.com", Id: 1, Verified: true}, nil)
+	suite.userService.On("VerifyEmail", mock.AnythingOfType("*gin.Context"), "used").
+		Return(nil, userapp.ErrInvalidVerifyToken)
+
+	tests := []struct {
+		body   string
+		status int
+	}{
+		{`{"token":"good"}`, http.StatusOK},
+		{`{"token":"used"}`, http.StatusBadRequest},
+		{`not json`, http.StatusBadRequest},
+	}
+	for _, tc := range tests {
+		req, _ := http.NewRequest(http.MethodPost, suite.baseURL+"/api/v1/user/verify", bytes.NewReader([]byte(tc.body)))
+		req.Header.Add("Content-Type", "application/json")
+		resp, _ := suite.client.Do(req)
+		suite.Equal(tc.status, resp.StatusCode, tc.body)
+		if tc.status != http.StatusOK {
+			continue
+		}
+		var response userResponse
+		respBody, _ := io.ReadAll(resp.Body)
+		_ = json.Unmarshal(respBody, &response)
+		suite.True(response.Data.Verified)
+	}
+}
+
+func (suite *UserApiTestSuite) TestSendVerification() {
+	suite.userService.On("SendVerification", mock.AnythingOfType("*gin.Context"), int64(2)).
+		Return(userapp.ErrAlreadyVerified).Once()
+	suite.userService.On("SendVerification", mock.AnythingOfType("*gin.Context"), int64(2)).
+		Return(nil)
+
+	tests := []struct {
+		token  string
+		status int
+	}{
+		{"", http.StatusUnauthorized},
+		{"token", http.StatusConflict},
+		{"token", http.StatusOK},
+	}
+	for _, tc := range tests {
+		req, _ := http.NewRequest(http.MethodPost, suite.baseURL+"/api/v1/user/verification", nil)
+		if tc.token != "" {
+			req.Header.Add("Authorization", "Bearer "+tc.token)
+		}
+		resp, _ := suite.client.Do(req)
+		suite.Equal(tc.status, resp.StatusCode)
+	}
+}
+
+func (suite *UserApiTestSuite) TestUpdatePassword_WrongPassword() {
+	suite.userService.On("UpdatePassword", mock.AnythingOfType("*gin.Context"), int64(1), "old pass", "new pass").
+		Return(nil, user.ErrWrongPassword)
+	body := map[string]any{
+		"current_password": "old pass",
+		"password":         "new pass",
+	}
+	data, _ := json.Marshal(body)
+	req, _ := http.NewRequest(http.MethodPut, suite.baseURL+"/api/v1/user/1/password", bytes.NewReader(data))
+	resp, _ := suite.client.Do(req)
+	suite.Equal(http.StatusForbidden, resp.StatusCode)
+}
+
+func (suite *UserApiTestSuite) TestForgotPassword() {
+	suite.userService.On("ForgotPassword", mock.AnythingOfType("*gin.Context"), "nick@mail.com").
+		Return(nil)
+	suite.userService.On("ForgotPassword", mock.AnythingOfType("*gin.Context"), "broken@mail.com").
+		Return(ErrUnexpected)
+
+	tests := []struct {
+		body   string
+		status int
+	}{
+		{`{"email":"nick@mail.com"}`, http.StatusOK},
+		{`{"email":"broken@mail.com"}`, http.StatusInternalServerError},
+		{`not json`, http.StatusBadRequest},
+	}
+	for _, tc := range tests {
+		req, _ := http.NewRequest(http.MethodPost, suite.baseURL+"/api/v1/user/password/forgot", bytes.NewReader([]byte(tc.body)))
+		req.Header.Add("Content-Type", "application/json")
+		resp, _ := suite.client.Do(req)
+		suite.Equal(tc.status, resp.StatusCode, tc.body)
+	}
+}
+
+func (suite *UserApiTestSuite) TestResetPassword() {
+	suite.userService.On("ResetPassword", mock.AnythingOfType("*gin.Context"), "good", "new pass").
+		Return(&user.User{Nickname: "nick", Email: "nick@mail.com", Id: 1}, nil)
+	suite.userService.On("ResetPassword", mock.AnythingOfType("*gin.Context"), "used", "new pass").
+		Return(nil, userapp.ErrInvalidResetToken)
+	suite.userService.On("ResetPassword", mock.AnythingOfType("*gin.Context"), "good", "").
+		Return(nil, user.ErrInvalidUserParams)
+
+	tests := []struct {
+		body   string
+		status int
+	}{
+		{`{"token":"good","password":"new pass"}`, http.StatusOK},
+		{`{"token":"used","password":"new pass"}`, http.StatusBadRequest},
+		{`{"token":"good","password":""}`, http.StatusBadRequest},
+		{`not json`, http.StatusBadRequest},
+	}
+	for _, tc := range tests {
+		req, _ := http.NewRequest(http.MethodPost, suite.baseURL+"/api/v1/user/password/reset", bytes.NewReader([]byte(tc.body)))
+		req.Header.Add("Content-Type", "application/json")
+		resp, _ := suite.client.Do(req)
+		suite.Equal(tc.status, resp.StatusCode, tc.body)
+	}
+}
+
+type apiKeyData struct {
+	Id         string     `json:"id"`
+	Key        string     `json:"key"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+}
+
+func (suite *UserApiTestSuite) TestCreateAPIKey() {
+	expires := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	suite.userService.On("CreateAPIKey", mock.AnythingOfType("*gin.Context"), int64(2), "importer",
+		[]user.Scope{user.ScopeAdsRead}, expires).
+		Return(&user.APIKey{ID: "id", Name: "importer", Scopes: []user.Scope{user.ScopeAdsRead}, ExpiresAt: expires},
+			"hk_id_secret", nil)
+	suite.userService.On("CreateAPIKey", mock.AnythingOfType("*gin.Context"), int64(2), "",
+		mock.Anything, mock.Anything).
+		Return(nil, "", user.ErrInvalidAPIKeyParams)
+
+	tests := []struct {
+		body   string
+		token  string
+		status int
+	}{
+		{`{"name":"importer","scopes":["ads:read"],"expires_at":"2030-01-01T00:00:00Z"}`, "token", http.StatusOK},
+		{`{"name":"importer","scopes":["ads:delete"]}`, "token", http.StatusBadRequest},
+		{`{"name":"","scopes":["ads:read"]}`, "token", http.StatusBadRequest},
+		{`{"name":"importer","scopes":["ads:read"]}`, "", http.StatusUnauthorized},
+	}
+	for _, tc := range tests {
+		req, _ := http.NewRequest(http.MethodPost, suite.baseURL+"/api/v1/user/keys", bytes.NewReader([]byte(tc.body)))
+		if tc.token != "" {
+			req.Header.Add("Authorization", "Bearer "+tc.token)
+		}
+		resp, _ := suite.client.Do(req)
+		suite.Equal(tc.status, resp.StatusCode, tc.body)
+		if tc.status != http.StatusOK {
+			continue
+		}
+		var response struct {
+			Data apiKeyData `json:"data"`
+		}
+		respBody, _ := io.ReadAll(resp.Body)
+		_ = json.Unmarshal(respBody, &response)
+		suite.Equal("hk_id_secret", response.Data.Key)
+		suite.Equal([]string{"ads:read"}, response.Data.Scopes)
+		suite.Nil(response.Data.LastUsedAt)
+	}
+}
+
+func (suite *UserApiTestSuite) TestListAPIKeys() {
+	suite.userService.On("ListAPIKeys", mock.AnythingOfType("*gin.Context"), int64(2)).
+		Return([]*user.APIKey{{ID: "id", Name: "importer", Hash: "hash", Scopes: []user.Scope{user.ScopeAdsWrite},
+			LastUsedAt: time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)}}, nil)
+
+	req, _ := http.NewRequest(http.MethodGet, suite.baseURL+"/api/v1/user/keys", nil)
+	req.Header.Add("Authorization", "Bearer token")
+	resp, _ := suite.client.Do(req)
+	suite.Equal(http.StatusOK, resp.StatusCode)
+	var response struct {
+		Data []apiKeyData `json:"data"`
+	}
+	respBody, _ := io.ReadAll(resp.Body)
+	_ = json.Unmarshal(respBody, &response)
+	if suite.Len(response.Data, 1) {
+		// neither the key nor its hash are ever listed
+		suite.Empty(response.Data[0].Key)
+		suite.NotContains(string(respBody), "hash")
+		suite.Nil(response.Data[0].ExpiresAt)
+		suite.NotNil(response.Data[0].LastUsedAt)
+	}
+
+	// a key can't manage the keys
+	req, _ = http.NewRequest(http.MethodGet, suite.baseURL+"/api/v1/user/keys", nil)
+	req.Header.Add("X-API-Key", "hk_id_secret")
+	resp, _ = suite.client.Do(req)
+	suite.Equal(http.StatusUnauthorized, resp.StatusCode)
+}
+
+func (suite *UserApiTestSuite) TestRevokeAPIKey() {
+	suite.userService.On("RevokeAPIKey", mock.AnythingOfType("*gin.Context"), int64(2), "id").Return(nil)
+	suite.userService.On("RevokeAPIKey", mock.AnythingOfType("*gin.Context"), int64(2), "other").
+		Return(userrepo.ErrInvalidAPIKeyId)
+
+	for key, status := range map[string]int{"id": http.StatusOK, "other": http.StatusNotFound} {
+		req, _ := http.NewRequest(http.MethodDelete, suite.baseURL+"/api/v1/user/keys/"+key, nil)
+		req.Header.Add("Authorization", "Bearer token")
+		resp, _ := suite.client.Do(req)
+		suite.Equal(status, resp.StatusCode, key)
+	}
+}
+
+func (suite *UserApiTestSuite) TestExportUser() {
+	at := time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)
+	suite.userService.On("ExportUser", mock.AnythingOfType("*gin.Context"), int64(2), int64(1)).
+		Return(&userapp.Export{
+			User:       &user.User{Id: 1, Nickname: "owner", Password: "password hash"},
+			Ads:        []*ads.Ad{{ID: 3, Title: "title", AuthorID: 1}, {ID: 4, Title: "trashed", DeletedAt: at}},
+			Activity:   []*ads.Revision{{AdID: 3, Number: 1, ActorID: 1, CreatedAt: at, Title: "title"}},
+			APIKeys:    []*user.APIKey{{ID: "id", Name: "importer", Hash: "key hash"}},
+			ExportedAt: at,
+		}, nil)
+	suite.userService.On("ExportUser", mock.AnythingOfType("*gin.Context"), int64(2), int64(5)).
+		Return(nil, user.ErrForbidden)
+	suite.userService.On("ExportUser", mock.AnythingOfType("*gin.Context"), int64(2), int64(6)).
+		Return(nil, userrepo.ErrInvalidUserId)
+
+	req, _ := http.NewRequest(http.MethodGet, suite.baseURL+"/api/v1/user/1/export", nil)
+	req.Header.Add("Authorization", "Bearer token")
+	resp, _ := suite.client.Do(req)
+	suite.Equal(http.StatusOK, resp.StatusCode)
+	suite.Contains(resp.Header.Get("Content-Disposition"), "attachment")
+	var response struct {
+		Data struct {
+			User userData `json:"user"`
+			Ads  []struct {
+				ID        int64  `json:"id"`
+				DeletedAt string `json:"deleted_at"`
+			} `json:"ads"`
+			Activity []struct {
+				AdID int64 `json:"ad_id"`
+			} `json:"activity"`
+			APIKeys []apiKeyData `json:"api_keys"`
+		} `json:"data"`
+	}
+	respBody, _ := io.ReadAll(resp.Body)
+	suite.NoError(json.Unmarshal(respBody, &response))
+	suite.Equal("owner", response.Data.User.Nickname)
+	if suite.Len(response.Data.Ads, 2) {
+		suite.Empty(response.Data.Ads[0].DeletedAt)
+		suite.NotEmpty(response.Data.Ads[1].DeletedAt)
+	}
+	if suite.Len(response.Data.Activity, 1) {
+		suite.Equal(int64(3), response.Data.Activity[0].AdID)
+	}
+	suite.Len(response.Data.APIKeys, 1)
+	// no hashes leave the service
+	suite.NotContains(string(respBody), "hash")
+
+	for id, status := range map[string]int{"5": http.StatusForbidden, "6": http.StatusNotFound} {
+		req, _ = http.NewRequest(http.MethodGet, suite.baseURL+"/api/v1/user/"+id+"/export", nil)
+		req.Header.Add("Authorization", "Bearer token")
+		resp, _ = suite.client.Do(req)
+		suite.Equal(status, resp.StatusCode, id)
+	}
+	resp, _ = suite.client.Get(suite.baseURL + "/api/v1/user/1/export")
+	suite.Equal(http.StatusUnauthorized, resp.StatusCode)
+}
+
+func (suite *UserApiTestSuite) TestEraseUser() {
+	suite.userService.On("EraseUser", mock.AnythingOfType("*gin.Context"), int64(2), int64(1)).Return(nil)
+	suite.userService.On("EraseUser", mock.AnythingOfType("*gin.Context"), int64(2), int64(5)).
+		Return(user.ErrForbidden)
+	suite.userService.On("EraseUser", mock.AnythingOfType("*gin.Context"), int64(2), int64(6)).
+		Return(userrepo.ErrInvalidUserId)
+	suite.userService.On("EraseUser", mock.AnythingOfType("*gin.Context"), int64(2), int64(7)).
+		Return(ErrUnexpected)
+
+	statuses := map[string]int{"1": http.StatusOK, "5": http.StatusForbidden, "6": http.StatusNotFound,
+		"7": http.StatusInternalServerError}
+	for id, status := range statuses {
+		req, _ := http.NewRequest(http.MethodDelete, suite.baseURL+"/api/v1/user/"+id+"/erase", nil)
+		req.Header.Add("Authorization", "Bearer token")
+		resp, _ := suite.client.Do(req)
+		suite.Equal(status, resp.StatusCode, id)
+	}
+}
+
+func (suite *UserApiTestSuite) TestGetProfile() {
+	at := time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)
+	profile := &userapp.PublicProfile{
+		User: &user.User{Id: 1, Nickname: "owner", Email: "owner@mail.com", Password: "password hash",
+			Profile: user.Profile{DisplayName: "Owner", City: "Moscow"}, CreatedAt: at},
+		Ads: []*ads.Ad{{ID: 3, Title: "title", AuthorID: 1, State: ads.StatePublished}},
+	}
+	suite.userService.On("GetProfile", mock.AnythingOfType("*gin.Context"), user.Anonymous, int64(1)).
+		Return(profile, nil)
+	suite.userService.On("GetProfile", mock.AnythingOfType("*gin.Context"), int64(2), int64(1)).
+		Return(profile, nil)
+	suite.userService.On("GetProfile", mock.AnythingOfType("*gin.Context"), user.Anonymous, int64(6)).
+		Return(nil, userrepo.ErrInvalidUserId)
+
+	resp, _ := suite.client.Get(suite.baseURL + "/api/v1/user/1/profile")
+	suite.Equal(http.StatusOK, resp.StatusCode)
+	var response struct {
+		Data struct {
+			Id           int64  `json:"id"`
+			DisplayName  string `json:"display_name"`
+			City         string `json:"city"`
+			RegisteredAt string `json:"registered_at"`
+			Ads          []struct {
+				ID int64 `json:"id"`
+			} `json:"ads"`
+		} `json:"data"`
+	}
+	respBody, _ := io.ReadAll(resp.Body)
+	suite.NoError(json.Unmarshal(respBody, &response))
+	suite.Equal("Owner", response.Data.DisplayName)
+	suite.Equal("Moscow", response.Data.City)
+	suite.Equal("2023-05-01T10:00:00Z", response.Data.RegisteredAt)
+	if suite.Len(response.Data.Ads, 1) {
+		suite.Equal(int64(3), response.Data.Ads[0].ID)
+	}
+	// the profile page is public, the email and the password stay private
+	suite.NotContains(string(respBody), "owner@mail.com")
+	suite.NotContains(string(respBody), "hash")
+
+	// signed in viewers are told apart
+	req, _ := http.NewRequest(http.MethodGet, suite.baseURL+"/api/v1/user/1/profile", nil)
+	req.Header.Add("Authorization", "Bearer token")
+	resp, _ = suite.client.Do(req)
+	suite.Equal(http.StatusOK, resp.StatusCode)
+	suite.userService.AssertCalled(suite.T(), "GetProfile", mock.AnythingOfType("*gin.Context"), int64(2), int64(1))
+
+	resp, _ = suite.client.Get(suite.baseURL + "/api/v1/user/6/profile")
+	suite.Equal(http.StatusNotFound, resp.StatusCode)
+}
+
+func (suite *UserApiTestSuite) TestUpdateProfile() {
+	profile := user.Profile{DisplayName: "Owner", City: "Moscow", CityVisibility: user.VisibilityPublic}
+	suite.userService.On("UpdateProfile", mock.AnythingOfType("*gin.Context"), int64(2), int64(1), profile).
+		Return(&user.User{Id: 1, Nickname: "owner", Profile: profile}, nil)
+	suite.userService.On("UpdateProfile", mock.AnythingOfType("*gin.Context"), int64(2), int64(5), profile).
+		Return(nil, user.ErrForbidden)
+	suite.userService.On("UpdateProfile", mock.AnythingOfType("*gin.Context"), int64(2), int64(6), profile).
+		Return(nil, userrepo.ErrInvalidUserId)
+	suite.userService.On("UpdateProfile", mock.AnythingOfType("*gin.Context"), int64(2), int64(7), profile).
+		Return(nil, user.ErrInvalidProfile)
+
+	body, _ := json.Marshal(map[string]any{"display_name": "Owner", "city": "Moscow", "city_visibility": "public"})
+	statuses := map[string]int{"1": http.StatusOK, "5": http.StatusForbidden, "6": http.StatusNotFound,
+		"7": http.StatusBadRequest}
+	for id, status := range statuses {
+		req, _ := http.NewRequest(http.MethodPut, suite.baseURL+"/api/v1/user/"+id+"/profile", bytes.NewReader(body))
+		req.Header.Add("Authorization", "Bearer token")
+		resp, _ := suite.client.Do(req)
+		suite.Equal(status, resp.StatusCode, id)
+		if status == http.StatusOK {
+			var response struct {
+				Data struct {
+					City           string `json:"city"`
+					CityVisibility string `json:"city_visibility"`
+				} `json:"data"`
+			}
+			respBody, _ := io.ReadAll(resp.Body)
+			suite.NoError(json.Unmarshal(respBody, &response))
+			suite.Equal("Moscow", response.Data.City)
+			suite.Equal("public", response.Data.CityVisibility)
+		}
+	}
+	req, _ := http.NewRequest(http.MethodPut, suite.baseURL+"/api/v1/user/1/profile", bytes.NewReader(body))
+	resp, _ := suite.client.Do(req)
+	suite.Equal(http.StatusUnauthorized, resp.StatusCode)
+}
+
+func (suite *UserApiTestSuite) TestSearchUsers() {
+	suite.userService.On("SearchUsers", mock.AnythingOfType("*gin.Context"),
+		user.Search{Prefix: "an", Limit: 2, Cursor: "cursor"}).
+		Return([]*user.User{{Id: 1, Nickname: "anna", Email: "anna@mail.com"}, {Id: 2, Nickname: "Anton"}}, "next",
+			nil)
+	suite.userService.On("SearchUsers", mock.AnythingOfType("*gin.Context"), user.Search{Cursor: "bad"}).
+		Return(nil, "", user.ErrInvalidSearch)
+
+	resp, _ := suite.client.Get(suite.baseURL + "/api/v1/user/search?prefix=an&limit=2&cursor=cursor")
+	suite.Equal(http.StatusOK, resp.StatusCode)
+	var response struct {
+		Data []struct {
+			Id       int64  `json:"id"`
+			Nickname string `json:"nickname"`
+		} `json:"data"`
+		NextCursor string `json:"next_cursor"`
+	}
+	respBody, _ := io.ReadAll(resp.Body)
+	suite.NoError(json.Unmarshal(respBody, &response))
+	if suite.Len(response.Data, 2) {
+		suite.Equal("anna", response.Data[0].Nickname)
+	}
+	suite.Equal("next", response.NextCursor)
+	suite.NotContains(string(respBody), "anna@mail.com")
+
+	for query, status := range map[string]int{"limit=0": http.StatusBadRequest, "limit=x": http.StatusBadRequest,
+		"cursor=bad": http.StatusBadRequest} {
+		resp, _ = suite.client.Get(suite.baseURL + "/api/v1/user/search?" + query)
+		suite.Equal(status, resp.StatusCode, query)
+	}
+}
+
 func TestUserApi(t *testing.T) {
 	suite.Run(t, new(UserApiTestSuite))
 }
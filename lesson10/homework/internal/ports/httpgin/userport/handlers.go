@@ -1,10 +1,12 @@
 package userport
 
 import (
+	"fmt"
 	"github.com/gin-gonic/gin"
 	"homework10/internal/adapters/userrepo"
 	"homework10/internal/app/userapp"
 	"homework10/internal/entities/user"
+	"homework10/internal/ports/httpgin/authport"
 	"net/http"
 	"strconv"
 )
@@ -20,8 +22,10 @@ func createUser(u userapp.App) gin.HandlerFunc {
 		us, err := u.CreateUser(c, reqBody.Nickname, reqBody.Email, reqBody.Password)
 		if err != nil {
 			switch err {
-			case user.ErrInvalidUserParams:
+			case user.ErrInvalidUserParams, user.ErrInvalidEmail:
 				c.JSON(http.StatusBadRequest, UserErrorResponse(err))
+			case user.ErrEmailTaken:
+				c.JSON(http.StatusConflict, UserErrorResponse(err))
 			default:
 				c.JSON(http.StatusInternalServerError, UserErrorResponse(err))
 			}
@@ -40,11 +44,13 @@ func changeNickname(u userapp.App) gin.HandlerFunc {
 			return
 		}
 		id, _ := strconv.Atoi(c.Param("user_id"))
-		us, err := u.ChangeNickname(c, int64(id), reqBody.Nickname)
+		us, err := u.ChangeNickname(c, authport.UserID(c), int64(id), reqBody.Nickname)
 		if err != nil {
 			switch err {
 			case user.ErrInvalidUserParams:
 				c.JSON(http.StatusBadRequest, UserErrorResponse(err))
+			case user.ErrForbidden:
+				c.JSON(http.StatusForbidden, UserErrorResponse(err))
 			case userrepo.ErrInvalidUserId:
 				c.JSON(http.StatusNotFound, UserErrorResponse(err))
 			default:
@@ -65,11 +71,13 @@ func updatePassword(u userapp.App) gin.HandlerFunc {
 			return
 		}
 		id, _ := strconv.Atoi(c.Param("user_id"))
-		us, err := u.UpdatePassword(c, int64(id), reqBody.Password)
+		us, err := u.UpdatePassword(c, int64(id), reqBody.CurrentPassword, reqBody.Password)
 		if err != nil {
 			switch err {
 			case user.ErrInvalidUserParams:
 				c.JSON(http.StatusBadRequest, UserErrorResponse(err))
+			case user.ErrWrongPassword:
+				c.JSON(http.StatusForbidden, UserErrorResponse(err))
 			case userrepo.ErrInvalidUserId:
 				c.JSON(http.StatusNotFound, UserErrorResponse(err))
 			default:
@@ -84,9 +92,11 @@ func updatePassword(u userapp.App) gin.HandlerFunc {
 func deleteUser(u userapp.App) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		id, _ := strconv.Atoi(c.Param("user_id"))
-		err := u.DeleteUser(c, int64(id))
+		err := u.DeleteUser(c, authport.UserID(c), int64(id))
 		if err != nil {
 			switch err {
+			case user.ErrForbidden:
+				c.JSON(http.StatusForbidden, UserErrorResponse(err))
 			case userrepo.ErrInvalidUserId:
 				c.JSON(http.StatusNotFound, UserErrorResponse(err))
 			default:
@@ -114,3 +124,280 @@ func getUser(u userapp.App) gin.HandlerFunc {
 		c.JSON(http.StatusOK, UserSuccessResponse(u))
 	}
 }
+
+func changeRole(u userapp.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var reqBody changeRoleRequest
+		if err := c.BindJSON(&reqBody); err != nil {
+			c.JSON(http.StatusBadRequest, UserErrorResponse(err))
+			return
+		}
+		id, _ := strconv.Atoi(c.Param("user_id"))
+		us, err := u.ChangeRole(c, authport.UserID(c), int64(id), user.Role(reqBody.Role))
+		if err != nil {
+			switch err {
+			case user.ErrInvalidRole:
+				c.JSON(http.StatusBadRequest, UserErrorResponse(err))
+			case user.ErrForbidden:
+				c.JSON(http.StatusForbidden, UserErrorResponse(err))
+			case userrepo.ErrInvalidUserId:
+				c.JSON(http.StatusNotFound, UserErrorResponse(err))
+			default:
+				c.JSON(http.StatusInternalServerError, UserErrorResponse(err))
+			}
+			return
+		}
+		c.JSON(http.StatusOK, UserSuccessResponse(us))
+	}
+}
+
+func verifyEmail(u userapp.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var reqBody verifyEmailRequest
+		if err := c.BindJSON(&reqBody); err != nil {
+			c.JSON(http.StatusBadRequest, UserErrorResponse(err))
+			return
+		}
+		us, err := u.VerifyEmail(c, reqBody.Token)
+		if err != nil {
+			switch err {
+			case userapp.ErrInvalidVerifyToken:
+				c.JSON(http.StatusBadRequest, UserErrorResponse(err))
+			default:
+				c.JSON(http.StatusInternalServerError, UserErrorResponse(err))
+			}
+			return
+		}
+		c.JSON(http.StatusOK, UserSuccessResponse(us))
+	}
+}
+
+func sendVerification(u userapp.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		err := u.SendVerification(c, authport.UserID(c))
+		if err != nil {
+			switch err {
+			case user.ErrInvalidEmail:
+				c.JSON(http.StatusBadRequest, UserErrorResponse(err))
+			case userapp.ErrAlreadyVerified:
+				c.JSON(http.StatusConflict, UserErrorResponse(err))
+			case userrepo.ErrInvalidUserId:
+				c.JSON(http.StatusNotFound, UserErrorResponse(err))
+			default:
+				c.JSON(http.StatusInternalServerError, UserErrorResponse(err))
+			}
+			return
+		}
+		c.JSON(http.StatusOK, VerificationSentResponse())
+	}
+}
+
+func forgotPassword(u userapp.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var reqBody forgotPasswordRequest
+		if err := c.BindJSON(&reqBody); err != nil {
+			c.JSON(http.StatusBadRequest, UserErrorResponse(err))
+			return
+		}
+		if err := u.ForgotPassword(c, reqBody.Email); err != nil {
+			c.JSON(http.StatusInternalServerError, UserErrorResponse(err))
+			return
+		}
+		c.JSON(http.StatusOK, ResetSentResponse())
+	}
+}
+
+func resetPassword(u userapp.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var reqBody resetPasswordRequest
+		if err := c.BindJSON(&reqBody); err != nil {
+			c.JSON(http.StatusBadRequest, UserErrorResponse(err))
+			return
+		}
+		us, err := u.ResetPassword(c, reqBody.Token, reqBody.Password)
+		if err != nil {
+			switch err {
+			case user.ErrInvalidUserParams, userapp.ErrInvalidResetToken:
+				c.JSON(http.StatusBadRequest, UserErrorResponse(err))
+			default:
+				c.JSON(http.StatusInternalServerError, UserErrorResponse(err))
+			}
+			return
+		}
+		c.JSON(http.StatusOK, UserSuccessResponse(us))
+	}
+}
+
+func createAPIKey(u userapp.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var reqBody createAPIKeyRequest
+		if err := c.BindJSON(&reqBody); err != nil {
+			c.JSON(http.StatusBadRequest, UserErrorResponse(err))
+			return
+		}
+		scopes := make([]user.Scope, 0, len(reqBody.Scopes))
+		for _, s := range reqBody.Scopes {
+			scope, err := user.ParseScope(s)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, UserErrorResponse(err))
+				return
+			}
+			scopes = append(scopes, scope)
+		}
+		key, secret, err := u.CreateAPIKey(c, authport.UserID(c), reqBody.Name, scopes, reqBody.ExpiresAt)
+		if err != nil {
+			switch err {
+			case user.ErrInvalidAPIKeyParams, user.ErrInvalidScope:
+				c.JSON(http.StatusBadRequest, UserErrorResponse(err))
+			case userrepo.ErrInvalidUserId:
+				c.JSON(http.StatusNotFound, UserErrorResponse(err))
+			default:
+				c.JSON(http.StatusInternalServerError, UserErrorResponse(err))
+			}
+			return
+		}
+		c.JSON(http.StatusOK, APIKeyCreatedResponse(key, secret))
+	}
+}
+
+func listAPIKeys(u userapp.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		keys, err := u.ListAPIKeys(c, authport.UserID(c))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, UserErrorResponse(err))
+			return
+		}
+		c.JSON(http.StatusOK, APIKeysSuccessResponse(keys))
+	}
+}
+
+func revokeAPIKey(u userapp.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		err := u.RevokeAPIKey(c, authport.UserID(c), c.Param("key_id"))
+		if err != nil {
+			switch err {
+			case userrepo.ErrInvalidAPIKeyId:
+				c.JSON(http.StatusNotFound, UserErrorResponse(err))
+			default:
+				c.JSON(http.StatusInternalServerError, UserErrorResponse(err))
+			}
+			return
+		}
+		c.JSON(http.StatusOK, APIKeyRevokedResponse())
+	}
+}
+
+func exportUser(u userapp.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, _ := strconv.Atoi(c.Param("user_id"))
+		export, err := u.ExportUser(c, authport.UserID(c), int64(id))
+		if err != nil {
+			switch err {
+			case user.ErrForbidden:
+				c.JSON(http.StatusForbidden, UserErrorResponse(err))
+			case userrepo.ErrInvalidUserId:
+				c.JSON(http.StatusNotFound, UserErrorResponse(err))
+			default:
+				c.JSON(http.StatusInternalServerError, UserErrorResponse(err))
+			}
+			return
+		}
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="user-%d-export.json"`, id))
+		c.JSON(http.StatusOK, ExportSuccessResponse(export))
+	}
+}
+
+func eraseUser(u userapp.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, _ := strconv.Atoi(c.Param("user_id"))
+		err := u.EraseUser(c, authport.UserID(c), int64(id))
+		if err != nil {
+			switch err {
+			case user.ErrForbidden:
+				c.JSON(http.StatusForbidden, UserErrorResponse(err))
+			case userrepo.ErrInvalidUserId:
+				c.JSON(http.StatusNotFound, UserErrorResponse(err))
+			default:
+				c.JSON(http.StatusInternalServerError, UserErrorResponse(err))
+			}
+			return
+		}
+		c.JSON(http.StatusOK, UserErasedResponse())
+	}
+}
+
+func getProfile(u userapp.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, _ := strconv.Atoi(c.Param("user_id"))
+		viewer := user.Anonymous
+		if principal, ok := authport.Principal(c); ok {
+			viewer = principal.UserID
+		}
+		profile, err := u.GetProfile(c, viewer, int64(id))
+		if err != nil {
+			switch err {
+			case userrepo.ErrInvalidUserId:
+				c.JSON(http.StatusNotFound, UserErrorResponse(err))
+			default:
+				c.JSON(http.StatusInternalServerError, UserErrorResponse(err))
+			}
+			return
+		}
+		c.JSON(http.StatusOK, ProfileSuccessResponse(profile))
+	}
+}
+
+func updateProfile(u userapp.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var reqBody profileRequest
+		if err := c.BindJSON(&reqBody); err != nil {
+			c.JSON(http.StatusBadRequest, UserErrorResponse(err))
+			return
+		}
+		id, _ := strconv.Atoi(c.Param("user_id"))
+		us, err := u.UpdateProfile(c, authport.UserID(c), int64(id), user.Profile{
+			DisplayName:     reqBody.DisplayName,
+			Bio:             reqBody.Bio,
+			City:            reqBody.City,
+			Phone:           reqBody.Phone,
+			CityVisibility:  user.Visibility(reqBody.CityVisibility),
+			PhoneVisibility: user.Visibility(reqBody.PhoneVisibility),
+		})
+		if err != nil {
+			switch err {
+			case user.ErrInvalidProfile, user.ErrInvalidVisibility:
+				c.JSON(http.StatusBadRequest, UserErrorResponse(err))
+			case user.ErrForbidden:
+				c.JSON(http.StatusForbidden, UserErrorResponse(err))
+			case userrepo.ErrInvalidUserId:
+				c.JSON(http.StatusNotFound, UserErrorResponse(err))
+			default:
+				c.JSON(http.StatusInternalServerError, UserErrorResponse(err))
+			}
+			return
+		}
+		c.JSON(http.StatusOK, OwnProfileSuccessResponse(us))
+	}
+}
+
+func searchUsers(u userapp.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit, err := user.ParseSearchLimit(c.Query("limit"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, UserErrorResponse(err))
+			return
+		}
+		list, next, err := u.SearchUsers(c, user.Search{Prefix: c.Query("prefix"), Limit: limit,
+			Cursor: c.Query("cursor")})
+		if err != nil {
+			switch err {
+			case user.ErrInvalidSearch:
+				c.JSON(http.StatusBadRequest, UserErrorResponse(err))
+			default:
+				c.JSON(http.StatusInternalServerError, UserErrorResponse(err))
+			}
+			return
+		}
+		c.JSON(http.StatusOK, UsersFoundResponse(list, next))
+	}
+}
@@ -4,7 +4,9 @@ package mocks
 
 import (
 	context "context"
+	userapp "homework10/internal/app/userapp"
 	user "homework10/internal/entities/user"
+	time "time"
 
 	mock "github.com/stretchr/testify/mock"
 )
@@ -14,17 +16,17 @@ type App struct {
 	mock.Mock
 }
 
-// ChangeNickname provides a mock function with given fields: ctx, id, nickname
-func (_m *App) ChangeNickname(ctx context.Context, id int64, nickname string) (*user.User, error) {
-	ret := _m.Called(ctx, id, nickname)
+// Authenticate provides a mock function with given fields: ctx, id, password
+func (_m *App) Authenticate(ctx context.Context, id int64, password string) (*user.User, error) {
+	ret := _m.Called(ctx, id, password)
 
 	var r0 *user.User
 	var r1 error
 	if rf, ok := ret.Get(0).(func(context.Context, int64, string) (*user.User, error)); ok {
-		return rf(ctx, id, nickname)
+		return rf(ctx, id, password)
 	}
 	if rf, ok := ret.Get(0).(func(context.Context, int64, string) *user.User); ok {
-		r0 = rf(ctx, id, nickname)
+		r0 = rf(ctx, id, password)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*user.User)
@@ -32,7 +34,68 @@ func (_m *App) ChangeNickname(ctx context.Context, id int64, nickname string) (*
 	}
 
 	if rf, ok := ret.Get(1).(func(context.Context, int64, string) error); ok {
-		r1 = rf(ctx, id, nickname)
+		r1 = rf(ctx, id, password)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// AuthenticateKey provides a mock function with given fields: ctx, apiKey
+func (_m *App) AuthenticateKey(ctx context.Context, apiKey string) (*user.User, *user.APIKey, error) {
+	ret := _m.Called(ctx, apiKey)
+
+	var r0 *user.User
+	var r1 *user.APIKey
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*user.User, *user.APIKey, error)); ok {
+		return rf(ctx, apiKey)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *user.User); ok {
+		r0 = rf(ctx, apiKey)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*user.User)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) *user.APIKey); ok {
+		r1 = rf(ctx, apiKey)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(*user.APIKey)
+		}
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, string) error); ok {
+		r2 = rf(ctx, apiKey)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// ChangeNickname provides a mock function with given fields: ctx, actorId, id, nickname
+func (_m *App) ChangeNickname(ctx context.Context, actorId int64, id int64, nickname string) (*user.User, error) {
+	ret := _m.Called(ctx, actorId, id, nickname)
+
+	var r0 *user.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64, string) (*user.User, error)); ok {
+		return rf(ctx, actorId, id, nickname)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64, string) *user.User); ok {
+		r0 = rf(ctx, actorId, id, nickname)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*user.User)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, int64, string) error); ok {
+		r1 = rf(ctx, actorId, id, nickname)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -40,6 +103,65 @@ func (_m *App) ChangeNickname(ctx context.Context, id int64, nickname string) (*
 	return r0, r1
 }
 
+// ChangeRole provides a mock function with given fields: ctx, actorId, id, role
+func (_m *App) ChangeRole(ctx context.Context, actorId int64, id int64, role user.Role) (*user.User, error) {
+	ret := _m.Called(ctx, actorId, id, role)
+
+	var r0 *user.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64, user.Role) (*user.User, error)); ok {
+		return rf(ctx, actorId, id, role)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64, user.Role) *user.User); ok {
+		r0 = rf(ctx, actorId, id, role)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*user.User)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, int64, user.Role) error); ok {
+		r1 = rf(ctx, actorId, id, role)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CreateAPIKey provides a mock function with given fields: ctx, userId, name, scopes, expiresAt
+func (_m *App) CreateAPIKey(ctx context.Context, userId int64, name string, scopes []user.Scope, expiresAt time.Time) (*user.APIKey, string, error) {
+	ret := _m.Called(ctx, userId, name, scopes, expiresAt)
+
+	var r0 *user.APIKey
+	var r1 string
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string, []user.Scope, time.Time) (*user.APIKey, string, error)); ok {
+		return rf(ctx, userId, name, scopes, expiresAt)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string, []user.Scope, time.Time) *user.APIKey); ok {
+		r0 = rf(ctx, userId, name, scopes, expiresAt)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*user.APIKey)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, string, []user.Scope, time.Time) string); ok {
+		r1 = rf(ctx, userId, name, scopes, expiresAt)
+	} else {
+		r1 = ret.Get(1).(string)
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, int64, string, []user.Scope, time.Time) error); ok {
+		r2 = rf(ctx, userId, name, scopes, expiresAt)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
 // CreateUser provides a mock function with given fields: ctx, nickname, email, password
 func (_m *App) CreateUser(ctx context.Context, nickname string, email string, password string) (*user.User, error) {
 	ret := _m.Called(ctx, nickname, email, password)
@@ -66,13 +188,67 @@ func (_m *App) CreateUser(ctx context.Context, nickname string, email string, pa
 	return r0, r1
 }
 
-// DeleteUser provides a mock function with given fields: ctx, id
-func (_m *App) DeleteUser(ctx context.Context, id int64) error {
-	ret := _m.Called(ctx, id)
+// DeleteUser provides a mock function with given fields: ctx, actorId, id
+func (_m *App) DeleteUser(ctx context.Context, actorId int64, id int64) error {
+	ret := _m.Called(ctx, actorId, id)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, int64) error); ok {
-		r0 = rf(ctx, id)
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64) error); ok {
+		r0 = rf(ctx, actorId, id)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// EraseUser provides a mock function with given fields: ctx, actorId, id
+func (_m *App) EraseUser(ctx context.Context, actorId int64, id int64) error {
+	ret := _m.Called(ctx, actorId, id)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64) error); ok {
+		r0 = rf(ctx, actorId, id)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ExportUser provides a mock function with given fields: ctx, actorId, id
+func (_m *App) ExportUser(ctx context.Context, actorId int64, id int64) (*userapp.Export, error) {
+	ret := _m.Called(ctx, actorId, id)
+
+	var r0 *userapp.Export
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64) (*userapp.Export, error)); ok {
+		return rf(ctx, actorId, id)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64) *userapp.Export); ok {
+		r0 = rf(ctx, actorId, id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*userapp.Export)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, int64) error); ok {
+		r1 = rf(ctx, actorId, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ForgotPassword provides a mock function with given fields: ctx, email
+func (_m *App) ForgotPassword(ctx context.Context, email string) error {
+	ret := _m.Called(ctx, email)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, email)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -80,6 +256,32 @@ func (_m *App) DeleteUser(ctx context.Context, id int64) error {
 	return r0
 }
 
+// GetProfile provides a mock function with given fields: ctx, viewerId, id
+func (_m *App) GetProfile(ctx context.Context, viewerId int64, id int64) (*userapp.PublicProfile, error) {
+	ret := _m.Called(ctx, viewerId, id)
+
+	var r0 *userapp.PublicProfile
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64) (*userapp.PublicProfile, error)); ok {
+		return rf(ctx, viewerId, id)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64) *userapp.PublicProfile); ok {
+		r0 = rf(ctx, viewerId, id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*userapp.PublicProfile)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, int64) error); ok {
+		r1 = rf(ctx, viewerId, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetUser provides a mock function with given fields: ctx, id
 func (_m *App) GetUser(ctx context.Context, id int64) (*user.User, error) {
 	ret := _m.Called(ctx, id)
@@ -106,25 +308,190 @@ func (_m *App) GetUser(ctx context.Context, id int64) (*user.User, error) {
 	return r0, r1
 }
 
-// UpdatePassword provides a mock function with given fields: ctx, id, password
-func (_m *App) UpdatePassword(ctx context.Context, id int64, password string) (*user.User, error) {
-	ret := _m.Called(ctx, id, password)
+// ListAPIKeys provides a mock function with given fields: ctx, userId
+func (_m *App) ListAPIKeys(ctx context.Context, userId int64) ([]*user.APIKey, error) {
+	ret := _m.Called(ctx, userId)
+
+	var r0 []*user.APIKey
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) ([]*user.APIKey, error)); ok {
+		return rf(ctx, userId)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) []*user.APIKey); ok {
+		r0 = rf(ctx, userId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*user.APIKey)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, userId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ResetPassword provides a mock function with given fields: ctx, token, password
+func (_m *App) ResetPassword(ctx context.Context, token string, password string) (*user.User, error) {
+	ret := _m.Called(ctx, token, password)
 
 	var r0 *user.User
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, int64, string) (*user.User, error)); ok {
-		return rf(ctx, id, password)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (*user.User, error)); ok {
+		return rf(ctx, token, password)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, int64, string) *user.User); ok {
-		r0 = rf(ctx, id, password)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) *user.User); ok {
+		r0 = rf(ctx, token, password)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*user.User)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, int64, string) error); ok {
-		r1 = rf(ctx, id, password)
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, token, password)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RevokeAPIKey provides a mock function with given fields: ctx, userId, keyId
+func (_m *App) RevokeAPIKey(ctx context.Context, userId int64, keyId string) error {
+	ret := _m.Called(ctx, userId, keyId)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string) error); ok {
+		r0 = rf(ctx, userId, keyId)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SearchUsers provides a mock function with given fields: ctx, search
+func (_m *App) SearchUsers(ctx context.Context, search user.Search) ([]*user.User, string, error) {
+	ret := _m.Called(ctx, search)
+
+	var r0 []*user.User
+	var r1 string
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, user.Search) ([]*user.User, string, error)); ok {
+		return rf(ctx, search)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, user.Search) []*user.User); ok {
+		r0 = rf(ctx, search)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*user.User)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, user.Search) string); ok {
+		r1 = rf(ctx, search)
+	} else {
+		r1 = ret.Get(1).(string)
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, user.Search) error); ok {
+		r2 = rf(ctx, search)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// SendVerification provides a mock function with given fields: ctx, id
+func (_m *App) SendVerification(ctx context.Context, id int64) error {
+	ret := _m.Called(ctx, id)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) error); ok {
+		r0 = rf(ctx, id)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UpdatePassword provides a mock function with given fields: ctx, id, current, password
+func (_m *App) UpdatePassword(ctx context.Context, id int64, current string, password string) (*user.User, error) {
+	ret := _m.Called(ctx, id, current, password)
+
+	var r0 *user.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string, string) (*user.User, error)); ok {
+		return rf(ctx, id, current, password)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string, string) *user.User); ok {
+		r0 = rf(ctx, id, current, password)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*user.User)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, string, string) error); ok {
+		r1 = rf(ctx, id, current, password)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UpdateProfile provides a mock function with given fields: ctx, actorId, id, profile
+func (_m *App) UpdateProfile(ctx context.Context, actorId int64, id int64, profile user.Profile) (*user.User, error) {
+	ret := _m.Called(ctx, actorId, id, profile)
+
+	var r0 *user.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64, user.Profile) (*user.User, error)); ok {
+		return rf(ctx, actorId, id, profile)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64, user.Profile) *user.User); ok {
+		r0 = rf(ctx, actorId, id, profile)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*user.User)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, int64, user.Profile) error); ok {
+		r1 = rf(ctx, actorId, id, profile)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// VerifyEmail provides a mock function with given fields: ctx, token
+func (_m *App) VerifyEmail(ctx context.Context, token string) (*user.User, error) {
+	ret := _m.Called(ctx, token)
+
+	var r0 *user.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*user.User, error)); ok {
+		return rf(ctx, token)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *user.User); ok {
+		r0 = rf(ctx, token)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*user.User)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, token)
 	} else {
 		r1 = ret.Error(1)
 	}
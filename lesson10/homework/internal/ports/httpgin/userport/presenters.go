@@ -2,7 +2,10 @@ package userport
 
 import (
 	"github.com/gin-gonic/gin"
+	"homework10/internal/app/userapp"
+	"homework10/internal/entities/ads"
 	"homework10/internal/entities/user"
+	"time"
 )
 
 type createUserRequest struct {
@@ -11,10 +14,15 @@ type createUserRequest struct {
 	Password string `json:"password"`
 }
 
+// userResponse leaves out registered_at for the users registered before it
+// was kept.
 type userResponse struct {
-	Id       int64  `json:"id"`
-	Nickname string `json:"nickname"`
-	Email    string `json:"email"`
+	Id           int64      `json:"id"`
+	Nickname     string     `json:"nickname"`
+	Email        string     `json:"email"`
+	Role         string     `json:"role"`
+	Verified     bool       `json:"verified"`
+	RegisteredAt *time.Time `json:"registered_at,omitempty"`
 }
 
 type changeNicknameRequest struct {
@@ -22,20 +30,213 @@ type changeNicknameRequest struct {
 }
 
 type updatePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	Password        string `json:"password"`
+}
+
+type forgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+type resetPasswordRequest struct {
+	Token    string `json:"token"`
 	Password string `json:"password"`
 }
 
+// profileRequest replaces the whole profile, the empty visibilities are
+// private.
+type profileRequest struct {
+	DisplayName     string `json:"display_name"`
+	Bio             string `json:"bio"`
+	City            string `json:"city"`
+	Phone           string `json:"phone"`
+	CityVisibility  string `json:"city_visibility"`
+	PhoneVisibility string `json:"phone_visibility"`
+}
+
+// profileResponse is the public page of a user, it leaves out the fields
+// the viewer may not see.
+type profileResponse struct {
+	Id           int64        `json:"id"`
+	Nickname     string       `json:"nickname"`
+	DisplayName  string       `json:"display_name"`
+	Bio          string       `json:"bio"`
+	City         string       `json:"city,omitempty"`
+	Phone        string       `json:"phone,omitempty"`
+	RegisteredAt *time.Time   `json:"registered_at,omitempty"`
+	Ads          []adResponse `json:"ads"`
+}
+
+// ownProfileResponse is the profile as its owner sees it after a change.
+type ownProfileResponse struct {
+	profileRequest
+	Id       int64  `json:"id"`
+	Nickname string `json:"nickname"`
+}
+
+type adResponse struct {
+	ID           int64  `json:"id"`
+	Title        string `json:"title"`
+	Text         string `json:"text"`
+	CreationDate string `json:"creation_date"`
+	UpdateDate   string `json:"update_date"`
+}
+
+type userSummary struct {
+	Id          int64  `json:"id"`
+	Nickname    string `json:"nickname"`
+	DisplayName string `json:"display_name"`
+}
+
+type changeRoleRequest struct {
+	Role string `json:"role"`
+}
+
+type verifyEmailRequest struct {
+	Token string `json:"token"`
+}
+
+// createAPIKeyRequest leaves out expires_at for a key that doesn't expire.
+type createAPIKeyRequest struct {
+	Name      string    `json:"name"`
+	Scopes    []string  `json:"scopes"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// apiKeyResponse leaves out the times that are not set. Key is only there
+// when the key was just created.
+type apiKeyResponse struct {
+	Id         string     `json:"id"`
+	Key        string     `json:"key,omitempty"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+// exportResponse is the archive of the data of a user, it leaves out the
+// password and key hashes.
+type exportResponse struct {
+	User       userResponse       `json:"user"`
+	Ads        []exportedAd       `json:"ads"`
+	Activity   []exportedRevision `json:"activity"`
+	APIKeys    []apiKeyResponse   `json:"api_keys"`
+	ExportedAt time.Time          `json:"exported_at"`
+}
+
+type exportedAd struct {
+	ID           int64      `json:"id"`
+	Title        string     `json:"title"`
+	Text         string     `json:"text"`
+	CreationDate string     `json:"creation_date"`
+	UpdateDate   string     `json:"update_date"`
+	Published    bool       `json:"published"`
+	State        string     `json:"state"`
+	DeletedAt    *time.Time `json:"deleted_at,omitempty"`
+}
+
+type exportedChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+type exportedRevision struct {
+	AdID      int64            `json:"ad_id"`
+	Number    int64            `json:"number"`
+	Reason    string           `json:"reason,omitempty"`
+	CreatedAt time.Time        `json:"created_at"`
+	Title     string           `json:"title"`
+	Text      string           `json:"text"`
+	Published bool             `json:"published"`
+	State     string           `json:"state"`
+	Changes   []exportedChange `json:"changes"`
+}
+
+func userToResponse(u *user.User) userResponse {
+	resp := userResponse{
+		Id:       u.Id,
+		Email:    u.Email,
+		Nickname: u.Nickname,
+		Role:     string(u.Role.OrDefault()),
+		Verified: u.Verified,
+	}
+	if !u.CreatedAt.IsZero() {
+		resp.RegisteredAt = &u.CreatedAt
+	}
+	return resp
+}
+
 func UserSuccessResponse(u *user.User) *gin.H {
 	return &gin.H{
-		"data": userResponse{
+		"data":  userToResponse(u),
+		"error": nil,
+	}
+}
+
+func ProfileSuccessResponse(p *userapp.PublicProfile) *gin.H {
+	u := p.User
+	data := profileResponse{
+		Id:          u.Id,
+		Nickname:    u.Nickname,
+		DisplayName: u.Profile.DisplayName,
+		Bio:         u.Profile.Bio,
+		City:        u.Profile.City,
+		Phone:       u.Profile.Phone,
+		Ads:         make([]adResponse, 0, len(p.Ads)),
+	}
+	if !u.CreatedAt.IsZero() {
+		data.RegisteredAt = &u.CreatedAt
+	}
+	for _, ad := range p.Ads {
+		data.Ads = append(data.Ads, adResponse{
+			ID:           ad.ID,
+			Title:        ad.Title,
+			Text:         ad.Text,
+			CreationDate: ad.CreationDate,
+			UpdateDate:   ad.UpdateDate,
+		})
+	}
+	return &gin.H{
+		"data":  data,
+		"error": nil,
+	}
+}
+
+func OwnProfileSuccessResponse(u *user.User) *gin.H {
+	p := u.Profile
+	return &gin.H{
+		"data": ownProfileResponse{
+			profileRequest: profileRequest{
+				DisplayName:     p.DisplayName,
+				Bio:             p.Bio,
+				City:            p.City,
+				Phone:           p.Phone,
+				CityVisibility:  string(p.CityVisibility),
+				PhoneVisibility: string(p.PhoneVisibility),
+			},
 			Id:       u.Id,
-			Email:    u.Email,
 			Nickname: u.Nickname,
 		},
 		"error": nil,
 	}
 }
 
+// UsersFoundResponse carries the cursor of the next page, empty on the
+// last one.
+func UsersFoundResponse(list []*user.User, next string) *gin.H {
+	data := make([]userSummary, 0, len(list))
+	for _, u := range list {
+		data = append(data, userSummary{Id: u.Id, Nickname: u.Nickname, DisplayName: u.Profile.DisplayName})
+	}
+	return &gin.H{
+		"data":        data,
+		"next_cursor": next,
+		"error":       nil,
+	}
+}
+
 func UserDeleteSuccessResponse() *gin.H {
 	return &gin.H{
 		"data":  "user successfully deleted",
@@ -43,9 +244,130 @@ func UserDeleteSuccessResponse() *gin.H {
 	}
 }
 
+func UserErasedResponse() *gin.H {
+	return &gin.H{
+		"data":  "user data successfully erased",
+		"error": nil,
+	}
+}
+
+func VerificationSentResponse() *gin.H {
+	return &gin.H{
+		"data":  "verification token sent",
+		"error": nil,
+	}
+}
+
+// ResetSentResponse is the same whether the email is known or not.
+func ResetSentResponse() *gin.H {
+	return &gin.H{
+		"data":  "a reset token is mailed to the address if it belongs to a user",
+		"error": nil,
+	}
+}
+
 func UserErrorResponse(err error) *gin.H {
 	return &gin.H{
 		"data":  nil,
 		"error": err.Error(),
 	}
 }
+
+func apiKeyToResponse(k *user.APIKey) apiKeyResponse {
+	resp := apiKeyResponse{
+		Id:        k.ID,
+		Name:      k.Name,
+		Scopes:    make([]string, 0, len(k.Scopes)),
+		CreatedAt: k.CreatedAt,
+	}
+	for _, s := range k.Scopes {
+		resp.Scopes = append(resp.Scopes, string(s))
+	}
+	if !k.ExpiresAt.IsZero() {
+		resp.ExpiresAt = &k.ExpiresAt
+	}
+	if !k.LastUsedAt.IsZero() {
+		resp.LastUsedAt = &k.LastUsedAt
+	}
+	return resp
+}
+
+func APIKeyCreatedResponse(k *user.APIKey, secret string) *gin.H {
+	resp := apiKeyToResponse(k)
+	resp.Key = secret
+	return &gin.H{
+		"data":  resp,
+		"error": nil,
+	}
+}
+
+func APIKeysSuccessResponse(keys []*user.APIKey) *gin.H {
+	data := make([]apiKeyResponse, 0, len(keys))
+	for _, k := range keys {
+		data = append(data, apiKeyToResponse(k))
+	}
+	return &gin.H{
+		"data":  data,
+		"error": nil,
+	}
+}
+
+func APIKeyRevokedResponse() *gin.H {
+	return &gin.H{
+		"data":  "api key revoked",
+		"error": nil,
+	}
+}
+
+func ExportSuccessResponse(export *userapp.Export) *gin.H {
+	resp := exportResponse{
+		User:       userToResponse(export.User),
+		Ads:        make([]exportedAd, 0, len(export.Ads)),
+		Activity:   make([]exportedRevision, 0, len(export.Activity)),
+		APIKeys:    make([]apiKeyResponse, 0, len(export.APIKeys)),
+		ExportedAt: export.ExportedAt,
+	}
+	for _, ad := range export.Ads {
+		exported := exportedAd{
+			ID:           ad.ID,
+			Title:        ad.Title,
+			Text:         ad.Text,
+			CreationDate: ad.CreationDate,
+			UpdateDate:   ad.UpdateDate,
+			Published:    ad.Published(),
+			State:        string(ad.State),
+		}
+		if ad.Trashed() {
+			exported.DeletedAt = &ad.DeletedAt
+		}
+		resp.Ads = append(resp.Ads, exported)
+	}
+	for _, rev := range export.Activity {
+		resp.Activity = append(resp.Activity, revisionToExport(rev))
+	}
+	for _, k := range export.APIKeys {
+		resp.APIKeys = append(resp.APIKeys, apiKeyToResponse(k))
+	}
+	return &gin.H{
+		"data":  resp,
+		"error": nil,
+	}
+}
+
+func revisionToExport(rev *ads.Revision) exportedRevision {
+	changes := make([]exportedChange, len(rev.Changes))
+	for i, change := range rev.Changes {
+		changes[i] = exportedChange{Field: change.Field, Old: change.Old, New: change.New}
+	}
+	return exportedRevision{
+		AdID:      rev.AdID,
+		Number:    rev.Number,
+		Reason:    rev.Reason,
+		CreatedAt: rev.CreatedAt,
+		Title:     rev.Title,
+		Text:      rev.Text,
+		Published: rev.State == ads.StatePublished,
+		State:     string(rev.State),
+		Changes:   changes,
+	}
+}
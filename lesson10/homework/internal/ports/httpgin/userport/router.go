@@ -5,10 +5,27 @@ import (
 	"homework10/internal/app/userapp"
 )
 
-func AppRouter(r *gin.RouterGroup, u userapp.App) {
+// AppRouter registers the user routes, identify shows more to signed in
+// users.
+func AppRouter(r *gin.RouterGroup, u userapp.App, authenticated, identify gin.HandlerFunc) {
 	r.POST("/user", createUser(u))
+	r.GET("/user/search", searchUsers(u))
 	r.GET("/user/:user_id/get", getUser(u))
-	r.PUT("/user/:user_id/nick", changeNickname(u))
+	r.GET("/user/:user_id/profile", identify, getProfile(u))
+	r.PUT("/user/:user_id/profile", authenticated, updateProfile(u))
+	r.PUT("/user/:user_id/nick", authenticated, changeNickname(u))
 	r.PUT("/user/:user_id/password", updatePassword(u))
-	r.DELETE("/user/:user_id/delete", deleteUser(u))
+	r.DELETE("/user/:user_id/delete", authenticated, deleteUser(u))
+	r.PUT("/user/:user_id/role", authenticated, changeRole(u))
+	r.GET("/user/:user_id/export", authenticated, exportUser(u))
+	r.DELETE("/user/:user_id/erase", authenticated, eraseUser(u))
+	// the token mailed to the user is proof enough, verify needs no login
+	r.POST("/user/verify", verifyEmail(u))
+	r.POST("/user/verification", authenticated, sendVerification(u))
+	r.POST("/user/password/forgot", forgotPassword(u))
+	r.POST("/user/password/reset", resetPassword(u))
+	// keys are managed with an access token only, a key can't make more
+	r.POST("/user/keys", authenticated, createAPIKey(u))
+	r.GET("/user/keys", authenticated, listAPIKeys(u))
+	r.DELETE("/user/keys/:key_id", authenticated, revokeAPIKey(u))
 }
@@ -0,0 +1,40 @@
+package tests
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestAPIKeys(t *testing.T) {
+	client := getTestClient()
+
+	_, err := client.createUser("importer", "importer@mail.com", "importer")
+	assert.NoError(t, err)
+	_, err = client.createAPIKey(0, "batch", "ads:delete")
+	assert.ErrorIs(t, err, ErrBadRequest)
+	reader, err := client.createAPIKey(0, "partner", "ads:read")
+	assert.NoError(t, err)
+	writer, err := client.createAPIKey(0, "batch", "ads:read", "ads:write")
+	assert.NoError(t, err)
+
+	ad, err := client.createAdWithKey(writer.Data.Key, "imported", "by the batch importer")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), ad.Data.AuthorID)
+	_, err = client.createAdWithKey(reader.Data.Key, "imported", "by the partner")
+	assert.ErrorIs(t, err, ErrForbidden)
+	_, err = client.createAdWithKey("hk_garbage", "imported", "by nobody")
+	assert.ErrorIs(t, err, ErrUnauthorized)
+
+	keys, err := client.listAPIKeys(0)
+	assert.NoError(t, err)
+	if assert.Len(t, keys.Data, 2) {
+		assert.Equal(t, reader.Data.ID, keys.Data[0].ID)
+		assert.Empty(t, keys.Data[0].Key)
+		assert.NotNil(t, keys.Data[1].LastUsedAt)
+	}
+
+	assert.NoError(t, client.revokeAPIKey(0, writer.Data.ID))
+	assert.ErrorIs(t, client.revokeAPIKey(0, writer.Data.ID), ErrNotFound)
+	_, err = client.createAdWithKey(writer.Data.Key, "imported", "after the revocation")
+	assert.ErrorIs(t, err, ErrUnauthorized)
+}
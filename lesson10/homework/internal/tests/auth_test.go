@@ -0,0 +1,137 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func TestLogin(t *testing.T) {
+	client := getTestClient()
+
+	_, err := client.createUser("tester", "tester@mail.com", "tester")
+	assert.NoError(t, err)
+
+	response, err := client.login(0, "tester")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, response.Data.AccessToken)
+	assert.NotEmpty(t, response.Data.RefreshToken)
+	assert.Equal(t, "Bearer", response.Data.TokenType)
+	assert.Positive(t, response.Data.ExpiresIn)
+
+	_, err = client.login(0, "wrong password")
+	assert.ErrorIs(t, err, ErrUnauthorized)
+	_, err = client.login(1, "tester")
+	assert.ErrorIs(t, err, ErrUnauthorized)
+}
+
+func TestRefreshTokens(t *testing.T) {
+	client := getTestClient()
+
+	_, err := client.createUser("tester", "tester@mail.com", "tester")
+	assert.NoError(t, err)
+	tokens, err := client.login(0, "tester")
+	assert.NoError(t, err)
+
+	refreshed, err := client.refresh(tokens.Data.RefreshToken)
+	assert.NoError(t, err)
+	assert.NotEqual(t, tokens.Data.AccessToken, refreshed.Data.AccessToken)
+
+	// an access token is no refresh token and the other way round
+	_, err = client.refresh(tokens.Data.AccessToken)
+	assert.ErrorIs(t, err, ErrUnauthorized)
+	client.tokens[0] = refreshed.Data.RefreshToken
+	_, err = client.createAd(0, "hello", "world")
+	assert.ErrorIs(t, err, ErrUnauthorized)
+	client.tokens[0] = refreshed.Data.AccessToken
+	_, err = client.createAd(0, "hello", "world")
+	assert.NoError(t, err)
+
+	// a deleted user can't refresh
+	_, err = client.deleteUser(0)
+	assert.NoError(t, err)
+	_, err = client.refresh(refreshed.Data.RefreshToken)
+	assert.ErrorIs(t, err, ErrUnauthorized)
+}
+
+func TestAdsRequireToken(t *testing.T) {
+	client := getTestClient()
+
+	_, err := client.createUser("tester", "tester@mail.com", "tester")
+	assert.NoError(t, err)
+
+	data, _ := json.Marshal(map[string]any{"user_id": 0, "title": "hello", "text": "world",
+		"category_id": testCategory})
+	for _, header := range []string{"", "Bearer", "Bearer not-a-token", "Basic dGVzdGVyOnRlc3Rlcg=="} {
+		req, _ := http.NewRequest(http.MethodPost, client.baseURL+"/api/v1/ads", bytes.NewReader(data))
+		if header != "" {
+			req.Header.Add("Authorization", header)
+		}
+		var response adResponse
+		err = client.getResponse(req, &response)
+		assert.ErrorIs(t, err, ErrUnauthorized, header)
+	}
+
+	// reads stay public
+	_, err = client.listAds()
+	assert.NoError(t, err)
+}
+
+func TestUsersRequireOwner(t *testing.T) {
+	client := getTestClient()
+
+	_, err := client.createUser("tester", "tester@mail.com", "tester")
+	assert.NoError(t, err)
+	_, err = client.createUser("tester1", "tester1@mail.com", "tester1")
+	assert.NoError(t, err)
+	ad, err := client.createAd(0, "hello", "world")
+	assert.NoError(t, err)
+
+	// another user
+	other, err := client.login(1, "tester1")
+	assert.NoError(t, err)
+	client.tokens[0] = other.Data.AccessToken
+	_, err = client.changeNickname(0, "stolen")
+	assert.ErrorIs(t, err, ErrForbidden)
+	_, err = client.deleteUser(0)
+	assert.ErrorIs(t, err, ErrForbidden)
+
+	// nobody
+	delete(client.tokens, 0)
+	delete(client.passwords, 0)
+	_, err = client.changeNickname(0, "stolen")
+	assert.ErrorIs(t, err, ErrUnauthorized)
+	_, err = client.deleteUser(0)
+	assert.ErrorIs(t, err, ErrUnauthorized)
+
+	_, err = client.getAdById(ad.Data.ID)
+	assert.NoError(t, err)
+}
+
+func TestActingUserComesFromToken(t *testing.T) {
+	client := getTestClient()
+
+	_, err := client.createUser("tester", "tester@mail.com", "tester")
+	assert.NoError(t, err)
+	_, err = client.createUser("tester1", "tester1@mail.com", "tester1")
+	assert.NoError(t, err)
+	tokens, err := client.login(1, "tester1")
+	assert.NoError(t, err)
+
+	// user_id in the body is ignored
+	data, _ := json.Marshal(map[string]any{"user_id": 0, "title": "hello", "text": "world",
+		"category_id": testCategory})
+	req, _ := http.NewRequest(http.MethodPost, client.baseURL+"/api/v1/ads", bytes.NewReader(data))
+	req.Header.Add("Authorization", "Bearer "+tokens.Data.AccessToken)
+	var response adResponse
+	err = client.getResponse(req, &response)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), response.Data.AuthorID)
+
+	_, err = client.changeAdStatus(0, response.Data.ID, true)
+	assert.ErrorIs(t, err, ErrForbidden)
+	_, err = client.changeAdStatus(1, response.Data.ID, true)
+	assert.NoError(t, err)
+}
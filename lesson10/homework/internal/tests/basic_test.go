@@ -2,6 +2,7 @@ package tests
 
 import (
 	"log"
+	"net/url"
 	"sync"
 	"testing"
 	"time"
@@ -118,6 +119,30 @@ func TestUpdateAd(t *testing.T) {
 	assert.Equal(t, response.Data.Text, "мир")
 }
 
+func TestUpdateAdIfMatch(t *testing.T) {
+	client := getTestClient()
+
+	_, err := client.createUser("tester", "tester", "tester")
+	assert.NoError(t, err)
+
+	response, err := client.createAd(0, "hello", "world")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), response.Data.Version)
+
+	response, err = client.updateAdIfMatch(0, response.Data.ID, "title", "text", `"1"`)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), response.Data.Version)
+
+	// the second writer still holds the old version
+	_, err = client.updateAdIfMatch(0, response.Data.ID, "other title", "other text", `"1"`)
+	assert.ErrorIs(t, err, ErrPreconditionFailed)
+
+	response, err = client.getAdById(response.Data.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "title", response.Data.Title)
+	assert.Equal(t, int64(2), response.Data.Version)
+}
+
 func TestGetAdById(t *testing.T) {
 	client := getTestClient()
 
@@ -244,6 +269,72 @@ func TestListAds(t *testing.T) {
 	assert.True(t, ads.Data[0].Published)
 }
 
+func TestListAdsPages(t *testing.T) {
+	client := getTestClient()
+
+	_, err := client.createUser("tester", "tester", "tester")
+	assert.NoError(t, err)
+	for _, title := range []string{"d", "b", "e", "a", "c"} {
+		_, err = client.createAd(0, title, "text")
+		assert.NoError(t, err)
+	}
+
+	params := url.Values{"status": {"unpublished"}, "sort": {"title"}, "order": {"desc"}, "limit": {"2"}}
+	var titles []string
+	for {
+		response, err := client.listAdsWithParams(params)
+		assert.NoError(t, err)
+		for _, ad := range response.Data {
+			titles = append(titles, ad.Title)
+		}
+		if response.NextCursor == "" {
+			break
+		}
+		params.Set("cursor", response.NextCursor)
+	}
+	assert.Equal(t, []string{"e", "d", "c", "b", "a"}, titles)
+
+	// a cursor is bound to the order it was made for
+	params.Set("order", "asc")
+	_, err = client.listAdsWithParams(params)
+	assert.ErrorIs(t, err, ErrBadRequest)
+}
+
+func TestSearchAds(t *testing.T) {
+	client := getTestClient()
+
+	_, err := client.createUser("tester", "tester", "tester")
+	assert.NoError(t, err)
+
+	bike, err := client.createAd(0, "Продам велосипед", "Горный, почти новый")
+	assert.NoError(t, err)
+	_, err = client.changeAdStatus(0, bike.Data.ID, true)
+	assert.NoError(t, err)
+	scooter, err := client.createAd(0, "Куплю самокат", "Или велосипед")
+	assert.NoError(t, err)
+	_, err = client.changeAdStatus(0, scooter.Data.ID, true)
+	assert.NoError(t, err)
+	_, err = client.createAd(0, "Велосипед", "Черновик, не опубликован")
+	assert.NoError(t, err)
+
+	response, err := client.searchAds("ВЕЛОСИПЕДЫ")
+	assert.NoError(t, err)
+	assert.Len(t, response.Data, 2)
+	assert.Equal(t, bike.Data.ID, response.Data[0].ID)
+	assert.Equal(t, scooter.Data.ID, response.Data[1].ID)
+
+	_, err = client.updateAd(0, scooter.Data.ID, "Куплю самокат", "Детский")
+	assert.NoError(t, err)
+	_, err = client.deleteAd(bike.Data.ID, 0)
+	assert.NoError(t, err)
+	response, err = client.searchAds("велосипед")
+	assert.NoError(t, err)
+	assert.Empty(t, response.Data)
+
+	_, err = client.searchAds("  ")
+	assert.ErrorIs(t, err, ErrBadRequest)
+}
+
 func TestDeleteAd(t *testing.T) {
 	client := getTestClient()
 
@@ -269,3 +360,88 @@ func TestDeleteAd(t *testing.T) {
 	_, _ = client.deleteAd(0, 0)
 	assert.Error(t, ErrNotFound)
 }
+
+func TestTrashAndRestoreAd(t *testing.T) {
+	client := getTestClient()
+
+	_, err := client.createUser("tester", "tester", "tester")
+	assert.NoError(t, err)
+	_, err = client.createUser("tester1", "tester1", "tester1")
+	assert.NoError(t, err)
+
+	response, err := client.createAd(0, "hello", "world")
+	assert.NoError(t, err)
+	_, err = client.deleteAd(response.Data.ID, 0)
+	assert.NoError(t, err)
+
+	// the trashed ad is hidden from every read but the trash
+	_, err = client.getAdById(response.Data.ID)
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = client.getAdByTitle("hello")
+	assert.ErrorIs(t, err, ErrNotFound)
+	list, err := client.listAdsWithParams(url.Values{"status": {"unpublished"}})
+	assert.NoError(t, err)
+	assert.Empty(t, list.Data)
+
+	trash, err := client.getTrash(0)
+	assert.NoError(t, err)
+	assert.Len(t, trash.Data, 1)
+	assert.NotEmpty(t, trash.Data[0].DeletedAt)
+	trash, err = client.getTrash(1)
+	assert.NoError(t, err)
+	assert.Empty(t, trash.Data)
+
+	_, err = client.restoreAd(response.Data.ID, 1)
+	assert.ErrorIs(t, err, ErrNotFound)
+	restored, err := client.restoreAd(response.Data.ID, 0)
+	assert.NoError(t, err)
+	assert.Empty(t, restored.Data.DeletedAt)
+	_, err = client.getAdById(response.Data.ID)
+	assert.NoError(t, err)
+	_, err = client.restoreAd(response.Data.ID, 0)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestAdRevisions(t *testing.T) {
+	client := getTestClient()
+
+	_, err := client.createUser("tester", "tester", "tester")
+	assert.NoError(t, err)
+	_, err = client.createUser("tester1", "tester1", "tester1")
+	assert.NoError(t, err)
+
+	response, err := client.createAd(0, "hello", "world")
+	assert.NoError(t, err)
+	_, err = client.updateAd(0, response.Data.ID, "hello", "there")
+	assert.NoError(t, err)
+	_, err = client.changeAdStatus(0, response.Data.ID, true)
+	assert.NoError(t, err)
+
+	revisions, err := client.getRevisions(response.Data.ID)
+	assert.NoError(t, err)
+	assert.Len(t, revisions.Data, 3)
+	assert.Equal(t, int64(2), revisions.Data[1].Number)
+	assert.Equal(t, int64(0), revisions.Data[1].ActorID)
+	assert.Equal(t, []changeData{{Field: "text", Old: "world", New: "there"}}, revisions.Data[1].Changes)
+	assert.Equal(t, []changeData{{Field: "state", Old: "draft", New: "published"}}, revisions.Data[2].Changes)
+
+	first, err := client.getAdAtRevision(response.Data.ID, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, "world", first.Data.Text)
+	assert.False(t, first.Data.Published)
+	_, err = client.getAdAtRevision(response.Data.ID, 4)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	_, err = client.revertAd(response.Data.ID, 1, 1)
+	assert.ErrorIs(t, err, ErrForbidden)
+	reverted, err := client.revertAd(response.Data.ID, 0, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, "world", reverted.Data.Text)
+	assert.False(t, reverted.Data.Published)
+
+	// the revert is recorded on top of the history
+	revisions, err = client.getRevisions(response.Data.ID)
+	assert.NoError(t, err)
+	assert.Len(t, revisions.Data, 4)
+	assert.Len(t, revisions.Data[3].Changes, 2)
+}
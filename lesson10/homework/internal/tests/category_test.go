@@ -0,0 +1,68 @@
+package tests
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/url"
+	"strconv"
+	"testing"
+)
+
+func TestCategories(t *testing.T) {
+	client := getTestClient()
+
+	_, err := client.createUser("admin", "admin@mail.com", "admin")
+	assert.NoError(t, err)
+	assert.NoError(t, client.makeAdmin(0))
+	_, err = client.createUser("seller", "seller@mail.com", "seller")
+	assert.NoError(t, err)
+
+	_, err = client.createCategory(1, "vehicles", 0)
+	assert.ErrorIs(t, err, ErrForbidden)
+	_, err = client.createCategory(-1, "vehicles", 0)
+	assert.ErrorIs(t, err, ErrUnauthorized)
+	vehicles, err := client.createCategory(0, "vehicles", 0)
+	assert.NoError(t, err)
+	bikes, err := client.createCategory(0, "bikes", vehicles.Data.ID)
+	assert.NoError(t, err)
+	_, err = client.createCategory(0, "boats", 42)
+	assert.ErrorIs(t, err, ErrBadRequest)
+
+	_, err = client.createAdInCategory(1, 42, "bike", "fast")
+	assert.ErrorIs(t, err, ErrBadRequest)
+	bike, err := client.createAdInCategory(1, bikes.Data.ID, "bike", "fast")
+	assert.NoError(t, err)
+	assert.Equal(t, bikes.Data.ID, bike.Data.CategoryID)
+	_, err = client.changeAdStatus(1, bike.Data.ID, true)
+	assert.NoError(t, err)
+	car, err := client.createAdInCategory(1, vehicles.Data.ID, "car", "slow")
+	assert.NoError(t, err)
+	_, err = client.changeAdStatus(1, car.Data.ID, true)
+	assert.NoError(t, err)
+	_, err = client.createAd(1, "desk", "wooden")
+	assert.NoError(t, err)
+
+	// the category lists the ads of its subcategories too
+	list, err := client.listAdsWithParams(url.Values{"category": {strconv.FormatInt(vehicles.Data.ID, 10)}})
+	assert.NoError(t, err)
+	assert.Len(t, list.Data, 2)
+	list, err = client.listAdsWithParams(url.Values{"category": {strconv.FormatInt(bikes.Data.ID, 10)}})
+	assert.NoError(t, err)
+	assert.Len(t, list.Data, 1)
+	_, err = client.listAdsWithParams(url.Values{"category": {"42"}})
+	assert.ErrorIs(t, err, ErrBadRequest)
+
+	categories, err := client.listCategories()
+	assert.NoError(t, err)
+	assert.Equal(t, []categoryData{
+		{ID: testCategory, Name: "misc"},
+		{ID: vehicles.Data.ID, Name: "vehicles", Ads: 2},
+		{ID: bikes.Data.ID, ParentID: vehicles.Data.ID, Name: "bikes", Ads: 1},
+	}, categories.Data)
+
+	assert.ErrorIs(t, client.deleteCategory(0, vehicles.Data.ID), ErrConflict)
+	assert.ErrorIs(t, client.deleteCategory(1, vehicles.Data.ID), ErrForbidden)
+	empty, err := client.createCategory(0, "empty", 0)
+	assert.NoError(t, err)
+	assert.NoError(t, client.deleteCategory(0, empty.Data.ID))
+	assert.ErrorIs(t, client.deleteCategory(0, empty.Data.ID), ErrNotFound)
+}
@@ -88,3 +88,25 @@ func TestGetAdWithIncorrectTitle(t *testing.T) {
 	_, err = client.getAdByTitle("easy hw")
 	assert.ErrorIs(t, err, ErrNotFound)
 }
+
+func TestDeleteUserDeletesTheirAds(t *testing.T) {
+	client := getTestClient()
+
+	_, err := client.createUser("tester", "tester", "tester")
+	assert.NoError(t, err)
+	_, err = client.createUser("tester1", "tester1", "tester1")
+	assert.NoError(t, err)
+
+	own, err := client.createAd(0, "hello", "world")
+	assert.NoError(t, err)
+	other, err := client.createAd(1, "hello", "world")
+	assert.NoError(t, err)
+
+	_, err = client.deleteUser(0)
+	assert.NoError(t, err)
+
+	_, err = client.getAdById(own.Data.ID)
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = client.getAdById(other.Data.ID)
+	assert.NoError(t, err)
+}
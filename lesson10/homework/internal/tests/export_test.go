@@ -0,0 +1,65 @@
+package tests
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestExportUser(t *testing.T) {
+	client := getTestClient()
+
+	_, err := client.createUser("owner", "owner@mail.com", "owner")
+	assert.NoError(t, err)
+	_, err = client.createUser("other", "other@mail.com", "other")
+	assert.NoError(t, err)
+	_, err = client.createUser("admin", "admin@mail.com", "admin")
+	assert.NoError(t, err)
+	assert.NoError(t, client.makeAdmin(2))
+	ad, err := client.createAd(0, "hello", "world")
+	assert.NoError(t, err)
+	_, err = client.updateAd(0, ad.Data.ID, "hello", "there")
+	assert.NoError(t, err)
+	trashed, err := client.createAd(0, "old", "news")
+	assert.NoError(t, err)
+	_, err = client.deleteAd(trashed.Data.ID, 0)
+	assert.NoError(t, err)
+	_, err = client.createAPIKey(0, "partner", "ads:read")
+	assert.NoError(t, err)
+
+	export, err := client.exportUser(0, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, "owner", export.Data.User.Nickname)
+	if assert.Len(t, export.Data.Ads, 2) {
+		assert.Equal(t, "there", export.Data.Ads[0].Text)
+		assert.NotEmpty(t, export.Data.Ads[1].DeletedAt)
+	}
+	assert.Len(t, export.Data.Activity, 3)
+	assert.Len(t, export.Data.APIKeys, 1)
+
+	_, err = client.exportUser(1, 0)
+	assert.ErrorIs(t, err, ErrForbidden)
+	export, err = client.exportUser(2, 0)
+	assert.NoError(t, err)
+	assert.Len(t, export.Data.Ads, 2)
+	_, err = client.exportUser(2, 7)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestEraseUser(t *testing.T) {
+	client := getTestClient()
+
+	_, err := client.createUser("owner", "owner@mail.com", "owner")
+	assert.NoError(t, err)
+	_, err = client.createUser("other", "other@mail.com", "other")
+	assert.NoError(t, err)
+	ad, err := client.createAd(0, "hello", "world")
+	assert.NoError(t, err)
+
+	assert.ErrorIs(t, client.eraseUser(1, 0), ErrForbidden)
+	assert.NoError(t, client.eraseUser(0, 0))
+
+	_, err = client.getUser(0)
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = client.getAdById(ad.Data.ID)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
@@ -2,47 +2,22 @@ package tests
 
 import (
 	"context"
-	"google.golang.org/grpc/credentials/insecure"
 	"homework10/internal/adapters/adrepo"
 	"homework10/internal/adapters/userrepo"
+	"homework10/internal/entities/ads"
 	"homework10/internal/entities/user"
-	"homework10/internal/ports/grpc/app"
 	"homework10/internal/ports/grpc/base"
-	"net"
+	"io"
 	"testing"
 	"time"
 
+	"github.com/golang/protobuf/ptypes/empty"
 	"github.com/stretchr/testify/assert"
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 )
 
-func server(ctx context.Context, t *testing.T) *grpc.ClientConn {
-	lis := bufconn.Listen(1024 * 1024)
-	t.Cleanup(func() {
-		lis.Close()
-	})
-	srv := grpc.NewServer()
-	t.Cleanup(func() {
-		srv.Stop()
-	})
-	userRepo := userrepo.New()
-	base.RegisterUserServiceServer(srv, app.NewUserService(userRepo))
-	base.RegisterAdServiceServer(srv, app.NewAdService(adrepo.New(), userRepo))
-	go func() {
-		assert.NoError(t, srv.Serve(lis), "srv.Serve")
-	}()
-	dialer := func(context.Context, string) (net.Conn, error) {
-		return lis.Dial()
-	}
-	conn, err := grpc.DialContext(ctx, "", grpc.WithContextDialer(dialer), grpc.WithTransportCredentials(insecure.NewCredentials()))
-	assert.NoError(t, err, "grpc.DialContext")
-	t.Cleanup(func() {
-		conn.Close()
-	})
-	return conn
-}
-
 func TestGRRPCCreateUser(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	t.Cleanup(func() {
@@ -284,3 +259,555 @@ func TestGRRPCDeleteAd(t *testing.T) {
 	_, _ = clientAd.DeleteAd(ctx, &base.DeleteAdRequest{AdId: 1, AuthorId: 0})
 	assert.Error(t, adrepo.ErrInvalidAdId)
 }
+
+func TestGRRPCUpdateAdVersion(t *testing.T) {
+	ctx, gc := newGRPCClient(t, "Oleg")
+	res, err := gc.ads.CreateAd(ctx, &base.CreateAdRequest{Title: "tester ad", Text: "tester"})
+	assert.NoError(t, err)
+	assert.Equal(t, res.Version, int64(1))
+	res, err = gc.ads.UpdateAd(ctx, &base.UpdateAdRequest{AdId: res.Id, Title: "new title", Text: "new text"})
+	assert.NoError(t, err)
+	assert.Equal(t, res.Version, int64(2))
+
+	_, err = gc.ads.UpdateAd(ctx, &base.UpdateAdRequest{AdId: res.Id, Title: "title", Text: "text", ExpectedVersion: 1})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	res, err = gc.ads.UpdateAd(ctx, &base.UpdateAdRequest{AdId: res.Id, Title: "title", Text: "text", ExpectedVersion: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, res.Version, int64(3))
+}
+
+func TestGRRPCListAdsPages(t *testing.T) {
+	ctx, gc := newGRPCClient(t, "Oleg")
+	for i := 0; i < 3; i++ {
+		_, err := gc.ads.CreateAd(ctx, &base.CreateAdRequest{Title: "tester ad", Text: "tester", UserId: 0})
+		assert.NoError(t, err)
+	}
+
+	res, err := gc.ads.ListAds(ctx, &base.Filters{Status: "unpublished", Order: "desc", Limit: 2})
+	assert.NoError(t, err)
+	assert.Len(t, res.List, 2)
+	assert.Equal(t, res.List[0].Id, int64(2))
+	assert.NotEmpty(t, res.NextPageToken)
+	res, err = gc.ads.ListAds(ctx, &base.Filters{Status: "unpublished", Order: "desc", Limit: 2, PageToken: res.NextPageToken})
+	assert.NoError(t, err)
+	assert.Len(t, res.List, 1)
+	assert.Equal(t, res.List[0].Id, int64(0))
+	assert.Empty(t, res.NextPageToken)
+
+	_, err = gc.ads.ListAds(ctx, &base.Filters{Status: "unpublished", Sort: "rating"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestGRRPCAdPrices(t *testing.T) {
+	ctx, gc := newGRPCClient(t, "Oleg")
+
+	for _, amount := range []int64{5000, 1000, 3000} {
+		res, err := gc.ads.CreateAd(ctx, &base.CreateAdRequest{Title: "bike", Text: "bike",
+			Price: &base.Money{Amount: amount, Currency: "rub"}})
+		assert.NoError(t, err)
+		assert.Equal(t, "RUB", res.GetPrice().GetCurrency())
+	}
+	_, err := gc.ads.CreateAd(ctx, &base.CreateAdRequest{Title: "bike", Text: "bike",
+		Price: &base.Money{Amount: -1, Currency: "RUB"}})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	res, err := gc.ads.ListAds(ctx, &base.Filters{Status: "unpublished", Currency: "RUB", PriceMin: 2000, Sort: "price"})
+	assert.NoError(t, err)
+	if assert.Len(t, res.List, 2) {
+		assert.Equal(t, int64(3000), res.List[0].Price.Amount)
+		assert.Equal(t, int64(5000), res.List[1].Price.Amount)
+	}
+	_, err = gc.ads.ListAds(ctx, &base.Filters{Status: "unpublished", PriceMin: 2000})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestGRRPCSearchAds(t *testing.T) {
+	ctx, gc := newGRPCClient(t, "Oleg")
+
+	_, err := gc.ads.CreateAd(ctx, &base.CreateAdRequest{Title: "Red bike", Text: "Mountain bike", UserId: 0})
+	assert.NoError(t, err)
+	_, err = gc.ads.ChangeAdStatus(ctx, &base.ChangeAdStatusRequest{AdId: 0, UserId: 0, Published: true})
+	assert.NoError(t, err)
+	res, err := gc.ads.SearchAds(ctx, &base.SearchAdsRequest{Query: "bikes"})
+	assert.NoError(t, err)
+	assert.Len(t, res.List, 1)
+	assert.Equal(t, res.List[0].Title, "Red bike")
+	_, err = gc.ads.SearchAds(ctx, &base.SearchAdsRequest{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestGRRPCAdRevisions(t *testing.T) {
+	ctx, gc := newGRPCClient(t, "Oleg")
+	ad, err := gc.ads.CreateAd(ctx, &base.CreateAdRequest{Title: "tester ad", Text: "tester", UserId: 0})
+	assert.NoError(t, err)
+	_, err = gc.ads.UpdateAd(ctx, &base.UpdateAdRequest{AdId: ad.Id, UserId: 0, Title: "new title", Text: "tester"})
+	assert.NoError(t, err)
+
+	res, err := gc.ads.ListAdRevisions(ctx, &base.ListAdRevisionsRequest{AdId: ad.Id})
+	assert.NoError(t, err)
+	assert.Len(t, res.List, 2)
+	assert.Equal(t, "title", res.List[1].Changes[0].Field)
+	assert.Equal(t, "tester ad", res.List[1].Changes[0].Old)
+
+	old, err := gc.ads.GetAdRevision(ctx, &base.GetAdRevisionRequest{AdId: ad.Id, Revision: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, "tester ad", old.Title)
+	_, err = gc.ads.GetAdRevision(ctx, &base.GetAdRevisionRequest{AdId: ad.Id, Revision: 3})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	reverted, err := gc.ads.RevertAd(ctx, &base.RevertAdRequest{AdId: ad.Id, UserId: 0, Revision: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, "tester ad", reverted.Title)
+}
+
+func TestGRRPCAuthPolicies(t *testing.T) {
+	ctx, gc := newGRPCClient(t, "Oleg", "Denis", "Admin")
+	gc.setRole(t, 2, user.RoleAdmin)
+	oleg, denis, admin := gc.as(ctx, t, 0), gc.as(ctx, t, 1), gc.as(ctx, t, 2)
+
+	// nobody the client knows
+	_, err := gc.ads.CreateAd(ctx, &base.CreateAdRequest{Title: "tester ad", Text: "tester", UserId: nobody})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	invalid := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer garbage")
+	_, err = gc.ads.CreateAd(invalid, &base.CreateAdRequest{Title: "tester ad", Text: "tester"})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	// the author comes from the token, not from the request
+	ad, err := gc.ads.CreateAd(oleg, &base.CreateAdRequest{Title: "tester ad", Text: "tester", UserId: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), ad.AuthorId)
+
+	_, err = gc.ads.GetAdById(ctx, &base.GetAdByIdRequest{AdId: ad.Id})
+	assert.NoError(t, err)
+	_, err = gc.ads.GetAdById(invalid, &base.GetAdByIdRequest{AdId: ad.Id})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	_, err = gc.ads.UpdateAd(denis, &base.UpdateAdRequest{AdId: ad.Id, Title: "stolen", Text: "stolen"})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = gc.ads.DeleteAd(denis, &base.DeleteAdRequest{AdId: ad.Id, Reason: "spam"})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = gc.users.ChangeNickname(denis, &base.ChangeNicknameRequest{Id: 0, Nickname: "stolen"})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = gc.users.DeleteUser(denis, &base.DeleteUserRequest{Id: 0})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	// admins pass the owner checks
+	usr, err := gc.users.ChangeNickname(admin, &base.ChangeNicknameRequest{Id: 0, Nickname: "Olegator"})
+	assert.NoError(t, err)
+	assert.Equal(t, "Olegator", usr.Nickname)
+}
+
+func TestGRRPCModeration(t *testing.T) {
+	ctx, gc := newGRPCClient(t, "Oleg", "Denis", "Admin")
+	gc.setRole(t, 2, user.RoleAdmin)
+	oleg, denis, admin := gc.as(ctx, t, 0), gc.as(ctx, t, 1), gc.as(ctx, t, 2)
+
+	_, err := gc.users.ChangeRole(denis, &base.ChangeRoleRequest{Id: 1, Role: "moderator"})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = gc.users.ChangeRole(admin, &base.ChangeRoleRequest{Id: 1, Role: "superuser"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	usr, err := gc.users.ChangeRole(admin, &base.ChangeRoleRequest{Id: 1, Role: "moderator"})
+	assert.NoError(t, err)
+	assert.Equal(t, "moderator", usr.Role)
+
+	ad, err := gc.ads.CreateAd(oleg, &base.CreateAdRequest{Title: "tester ad", Text: "tester"})
+	assert.NoError(t, err)
+	_, err = gc.ads.ChangeAdStatus(oleg, &base.ChangeAdStatusRequest{AdId: ad.Id, Published: true})
+	assert.NoError(t, err)
+
+	_, err = gc.ads.UpdateAd(denis, &base.UpdateAdRequest{AdId: ad.Id, Title: "moderated", Text: "tester"})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = gc.ads.UnpublishAd(denis, &base.UnpublishAdRequest{AdId: ad.Id})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	res, err := gc.ads.UnpublishAd(denis, &base.UnpublishAdRequest{AdId: ad.Id, Reason: "spam"})
+	assert.NoError(t, err)
+	assert.False(t, res.Published)
+	assert.Equal(t, base.AdState_AD_STATE_REJECTED, res.State)
+
+	revisions, err := gc.ads.ListAdRevisions(ctx, &base.ListAdRevisionsRequest{AdId: ad.Id})
+	assert.NoError(t, err)
+	if assert.Len(t, revisions.List, 3) {
+		assert.Equal(t, "spam", revisions.List[2].Reason)
+	}
+
+	_, err = gc.ads.DeleteAd(denis, &base.DeleteAdRequest{AdId: ad.Id})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = gc.ads.DeleteAd(denis, &base.DeleteAdRequest{AdId: ad.Id, Reason: "spam"})
+	assert.NoError(t, err)
+	_, err = gc.ads.GetAdById(ctx, &base.GetAdByIdRequest{AdId: ad.Id})
+	assert.Error(t, err)
+}
+
+func TestGRRPCAdStates(t *testing.T) {
+	ctx, gc := newGRPCClient(t, "Oleg", "Denis")
+	gc.setRole(t, 1, user.RoleModerator)
+	oleg, denis := gc.as(ctx, t, 0), gc.as(ctx, t, 1)
+
+	ad, err := gc.ads.CreateAd(oleg, &base.CreateAdRequest{Title: "tester ad", Text: "tester"})
+	assert.NoError(t, err)
+	assert.Equal(t, base.AdState_AD_STATE_DRAFT, ad.State)
+	_, err = gc.ads.ChangeAdState(ctx, &base.ChangeAdStateRequest{AdId: ad.Id, State: base.AdState_AD_STATE_PENDING_REVIEW})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	_, err = gc.ads.ChangeAdState(oleg, &base.ChangeAdStateRequest{AdId: ad.Id})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = gc.ads.ChangeAdState(oleg, &base.ChangeAdStateRequest{AdId: ad.Id, State: base.AdState_AD_STATE_ARCHIVED})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	_, err = gc.ads.ChangeAdState(oleg, &base.ChangeAdStateRequest{AdId: ad.Id, State: base.AdState_AD_STATE_PENDING_REVIEW})
+	assert.NoError(t, err)
+
+	_, err = gc.ads.ChangeAdState(oleg, &base.ChangeAdStateRequest{AdId: ad.Id, State: base.AdState_AD_STATE_PUBLISHED})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = gc.ads.ChangeAdState(denis, &base.ChangeAdStateRequest{AdId: ad.Id, State: base.AdState_AD_STATE_REJECTED})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	res, err := gc.ads.ChangeAdState(denis, &base.ChangeAdStateRequest{AdId: ad.Id, State: base.AdState_AD_STATE_REJECTED, Reason: "spam"})
+	assert.NoError(t, err)
+	assert.Equal(t, base.AdState_AD_STATE_REJECTED, res.State)
+	assert.Equal(t, "spam", res.RejectionReason)
+
+	_, err = gc.ads.ChangeAdState(oleg, &base.ChangeAdStateRequest{AdId: ad.Id, State: base.AdState_AD_STATE_PENDING_REVIEW})
+	assert.NoError(t, err)
+	res, err = gc.ads.ChangeAdState(denis, &base.ChangeAdStateRequest{AdId: ad.Id, State: base.AdState_AD_STATE_PUBLISHED})
+	assert.NoError(t, err)
+	assert.True(t, res.Published)
+	_, err = gc.ads.ChangeAdState(oleg, &base.ChangeAdStateRequest{AdId: ad.Id, State: base.AdState_AD_STATE_SOLD})
+	assert.NoError(t, err)
+
+	list, err := gc.ads.ListAds(ctx, &base.Filters{State: base.AdState_AD_STATE_SOLD})
+	assert.NoError(t, err)
+	if assert.Len(t, list.List, 1) {
+		assert.Equal(t, ad.Id, list.List[0].Id)
+	}
+	list, err = gc.ads.ListAds(ctx, &base.Filters{})
+	assert.NoError(t, err)
+	assert.Empty(t, list.List)
+
+	revisions, err := gc.ads.ListAdRevisions(ctx, &base.ListAdRevisionsRequest{AdId: ad.Id})
+	assert.NoError(t, err)
+	if assert.Len(t, revisions.List, 6) {
+		assert.Equal(t, base.AdState_AD_STATE_REJECTED, revisions.List[2].State)
+		assert.Equal(t, "spam", revisions.List[2].Reason)
+	}
+}
+
+func TestGRRPCEmailVerification(t *testing.T) {
+	ctx, gc := newGRPCClient(t)
+
+	_, err := gc.users.CreateUser(ctx, &base.CreateUserRequest{Nickname: "Oleg", Email: "oleg", Password: "easyhw"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	usr, err := gc.users.CreateUser(ctx, &base.CreateUserRequest{Nickname: "Oleg", Email: "oleg@tinkoff.com", Password: "easyhw"})
+	assert.NoError(t, err)
+	assert.False(t, usr.Verified)
+	_, err = gc.users.CreateUser(ctx, &base.CreateUserRequest{Nickname: "Oleg2", Email: "OLEG@tinkoff.com", Password: "easyhw"})
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+
+	oleg := gc.withToken(ctx, t, 0)
+	ad, err := gc.ads.CreateAd(oleg, &base.CreateAdRequest{Title: "tester ad", Text: "tester"})
+	assert.NoError(t, err)
+	_, err = gc.ads.ChangeAdStatus(oleg, &base.ChangeAdStatusRequest{AdId: ad.Id, Published: true})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = gc.users.SendVerification(ctx, &empty.Empty{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	_, err = gc.users.SendVerification(oleg, &empty.Empty{})
+	assert.NoError(t, err)
+	assert.Len(t, gc.mailer.Sent("oleg@tinkoff.com"), 2)
+
+	_, err = gc.users.VerifyEmail(ctx, &base.VerifyEmailRequest{Token: "garbage"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	usr, err = gc.users.VerifyEmail(ctx, &base.VerifyEmailRequest{Token: mailedToken(gc.mailer, "oleg@tinkoff.com")})
+	assert.NoError(t, err)
+	assert.True(t, usr.Verified)
+	_, err = gc.users.SendVerification(oleg, &empty.Empty{})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	res, err := gc.ads.ChangeAdStatus(oleg, &base.ChangeAdStatusRequest{AdId: ad.Id, Published: true})
+	assert.NoError(t, err)
+	assert.True(t, res.Published)
+}
+
+func TestGRRPCResetPassword(t *testing.T) {
+	ctx, gc := newGRPCClient(t, "Oleg")
+	oleg := gc.withToken(ctx, t, 0)
+	_, err := gc.users.SendVerification(oleg, &empty.Empty{})
+	assert.NoError(t, err)
+
+	_, err = gc.users.ForgotPassword(ctx, &base.ForgotPasswordRequest{Email: "nobody@tinkoff.com"})
+	assert.NoError(t, err)
+	_, err = gc.users.ForgotPassword(ctx, &base.ForgotPasswordRequest{Email: "oleg@tinkoff.com"})
+	assert.NoError(t, err)
+	token := mailedToken(gc.mailer, "oleg@tinkoff.com")
+
+	_, err = gc.users.ResetPassword(ctx, &base.ResetPasswordRequest{Token: token, Password: ""})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	usr, err := gc.users.ResetPassword(ctx, &base.ResetPasswordRequest{Token: token, Password: "hardhw"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), usr.Id)
+	_, err = gc.users.ResetPassword(ctx, &base.ResetPasswordRequest{Token: token, Password: "hardhw"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	// the token from before the reset is revoked
+	_, err = gc.users.SendVerification(oleg, &empty.Empty{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestGRRPCAPIKeys(t *testing.T) {
+	ctx, gc := newGRPCClient(t, "Oleg")
+	_, readKey, err := gc.userApp.CreateAPIKey(ctx, 0, "reader", []user.Scope{user.ScopeAdsRead}, time.Time{})
+	assert.NoError(t, err)
+	_, writeKey, err := gc.userApp.CreateAPIKey(ctx, 0, "writer", []user.Scope{user.ScopeAdsWrite}, time.Time{})
+	assert.NoError(t, err)
+	reader := metadata.AppendToOutgoingContext(ctx, "x-api-key", readKey)
+	writer := metadata.AppendToOutgoingContext(ctx, "x-api-key", writeKey)
+
+	ad, err := gc.ads.CreateAd(writer, &base.CreateAdRequest{Title: "imported", Text: "by the importer"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), ad.AuthorId)
+	_, err = gc.ads.CreateAd(reader, &base.CreateAdRequest{Title: "imported", Text: "by the partner"})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = gc.ads.GetAdById(reader, &base.GetAdByIdRequest{AdId: ad.Id})
+	assert.NoError(t, err)
+
+	// the user service is for the users themselves
+	_, err = gc.users.SendVerification(writer, &empty.Empty{})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	bad := metadata.AppendToOutgoingContext(ctx, "x-api-key", "hk_garbage")
+	_, err = gc.ads.GetAdById(bad, &base.GetAdByIdRequest{AdId: ad.Id})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestGRRPCExportAndEraseUser(t *testing.T) {
+	ctx, gc := newGRPCClient(t, "Oleg", "Ivan")
+	oleg, ivan := gc.withToken(ctx, t, 0), gc.withToken(ctx, t, 1)
+	ad, err := gc.ads.CreateAd(oleg, &base.CreateAdRequest{Title: "hello", Text: "world"})
+	assert.NoError(t, err)
+	_, _, err = gc.userApp.CreateAPIKey(ctx, 0, "reader", []user.Scope{user.ScopeAdsRead}, time.Time{})
+	assert.NoError(t, err)
+
+	export, err := gc.users.ExportUser(oleg, &base.ExportUserRequest{Id: 0})
+	assert.NoError(t, err)
+	assert.Equal(t, "Oleg", export.User.Nickname)
+	if assert.Len(t, export.Ads, 1) {
+		assert.Equal(t, ad.Id, export.Ads[0].Id)
+	}
+	if assert.NotEmpty(t, export.Activity) {
+		assert.Equal(t, ad.Id, export.Activity[0].AdId)
+	}
+	assert.Len(t, export.ApiKeys, 1)
+	assert.NotEmpty(t, export.ExportedAt)
+
+	_, err = gc.users.ExportUser(ctx, &base.ExportUserRequest{Id: 0})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	_, err = gc.users.ExportUser(ivan, &base.ExportUserRequest{Id: 0})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = gc.users.EraseUser(ivan, &base.EraseUserRequest{Id: 0})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = gc.users.EraseUser(oleg, &base.EraseUserRequest{Id: 0})
+	assert.NoError(t, err)
+	_, err = gc.users.GetUser(ctx, &base.GetUserRequest{Id: 0})
+	assert.Error(t, err)
+	_, err = gc.ads.GetAdById(ivan, &base.GetAdByIdRequest{AdId: ad.Id})
+	assert.Error(t, err)
+}
+
+func TestGRRPCProfileAndSearch(t *testing.T) {
+	ctx, gc := newGRPCClient(t, "Oleg", "olga")
+	oleg, olga := gc.as(ctx, t, 0), gc.withToken(ctx, t, 1)
+
+	profile, err := gc.users.UpdateProfile(oleg, &base.UpdateProfileRequest{Id: 0, DisplayName: "Oleg T.",
+		City: "Moscow", Phone: "+7 900 000-00-00", CityVisibility: "public", PhoneVisibility: "users"})
+	assert.NoError(t, err)
+	assert.Equal(t, "users", profile.PhoneVisibility)
+	_, err = gc.users.UpdateProfile(olga, &base.UpdateProfileRequest{Id: 0, DisplayName: "Olga"})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = gc.users.UpdateProfile(oleg, &base.UpdateProfileRequest{Id: 0, Phone: "call me"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	ad, err := gc.ads.CreateAd(oleg, &base.CreateAdRequest{Title: "hello", Text: "world"})
+	assert.NoError(t, err)
+	_, err = gc.ads.ChangeAdStatus(oleg, &base.ChangeAdStatusRequest{AdId: ad.Id, Published: true})
+	assert.NoError(t, err)
+	_, err = gc.ads.CreateAd(oleg, &base.CreateAdRequest{Title: "draft", Text: "world"})
+	assert.NoError(t, err)
+
+	profile, err = gc.users.GetProfile(ctx, &base.GetProfileRequest{Id: 0})
+	assert.NoError(t, err)
+	assert.Equal(t, "Oleg T.", profile.DisplayName)
+	assert.Equal(t, "Moscow", profile.City)
+	assert.Empty(t, profile.Phone)
+	assert.NotEmpty(t, profile.RegisteredAt)
+	if assert.Len(t, profile.Ads, 1) {
+		assert.Equal(t, ad.Id, profile.Ads[0].Id)
+	}
+	profile, err = gc.users.GetProfile(olga, &base.GetProfileRequest{Id: 0})
+	assert.NoError(t, err)
+	assert.Equal(t, "+7 900 000-00-00", profile.Phone)
+	_, err = gc.users.GetProfile(ctx, &base.GetProfileRequest{Id: 5})
+	assert.Error(t, err)
+
+	found, err := gc.users.SearchUsers(ctx, &base.SearchUsersRequest{Prefix: "OL", Limit: 1})
+	assert.NoError(t, err)
+	if assert.Len(t, found.List, 1) {
+		assert.Equal(t, "Oleg T.", found.List[0].DisplayName)
+	}
+	found, err = gc.users.SearchUsers(ctx, &base.SearchUsersRequest{Prefix: "OL", Limit: 1,
+		PageToken: found.NextPageToken})
+	assert.NoError(t, err)
+	if assert.Len(t, found.List, 1) {
+		assert.Equal(t, "olga", found.List[0].Nickname)
+	}
+	assert.Empty(t, found.NextPageToken)
+	_, err = gc.users.SearchUsers(ctx, &base.SearchUsersRequest{Limit: 1000})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestGRRPCCategories(t *testing.T) {
+	ctx, gc := newGRPCClient(t, "Oleg", "admin")
+	gc.setRole(t, 1, user.RoleAdmin)
+	oleg, admin := gc.as(ctx, t, 0), gc.withToken(ctx, t, 1)
+
+	_, err := gc.categories.CreateCategory(oleg, &base.CreateCategoryRequest{Name: "vehicles"})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	vehicles, err := gc.categories.CreateCategory(admin, &base.CreateCategoryRequest{Name: "vehicles"})
+	assert.NoError(t, err)
+	bikes, err := gc.categories.CreateCategory(admin, &base.CreateCategoryRequest{Name: "bikes", ParentId: vehicles.Id})
+	assert.NoError(t, err)
+	_, err = gc.categories.UpdateCategory(admin, &base.UpdateCategoryRequest{Id: vehicles.Id, Name: "vehicles",
+		ParentId: bikes.Id})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = gc.ads.CreateAd(oleg, &base.CreateAdRequest{Title: "bike", Text: "fast", CategoryId: 42})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	ad, err := gc.ads.CreateAd(oleg, &base.CreateAdRequest{Title: "bike", Text: "fast", CategoryId: bikes.Id})
+	assert.NoError(t, err)
+	assert.Equal(t, bikes.Id, ad.CategoryId)
+	_, err = gc.ads.ChangeAdStatus(oleg, &base.ChangeAdStatusRequest{AdId: ad.Id, Published: true})
+	assert.NoError(t, err)
+
+	list, err := gc.ads.ListAds(ctx, &base.Filters{CategoryId: vehicles.Id})
+	assert.NoError(t, err)
+	assert.Len(t, list.List, 1)
+	list, err = gc.ads.ListAds(ctx, &base.Filters{CategoryId: testCategory})
+	assert.NoError(t, err)
+	assert.Empty(t, list.List)
+
+	got, err := gc.categories.GetCategory(ctx, &base.GetCategoryRequest{Id: vehicles.Id})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), got.Ads)
+	_, err = gc.categories.GetCategory(ctx, &base.GetCategoryRequest{Id: 42})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	categories, err := gc.categories.ListCategories(ctx, &empty.Empty{})
+	assert.NoError(t, err)
+	assert.Len(t, categories.List, 3)
+
+	_, err = gc.categories.DeleteCategory(admin, &base.DeleteCategoryRequest{Id: bikes.Id})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+}
+
+func TestGRRPCAdImages(t *testing.T) {
+	ctx, gc := newGRPCClient(t, "Oleg", "Ivan")
+	author, other := gc.as(ctx, t, 0), gc.as(ctx, t, 1)
+	ad, err := gc.ads.CreateAd(author, &base.CreateAdRequest{Title: "bike", Text: "bike"})
+	assert.NoError(t, err)
+
+	// upload sends the image in chunks of the given size
+	upload := func(ctx context.Context, adId int64, data []byte, chunk int) (*base.ImageResponse, error) {
+		stream, err := gc.ads.UploadImage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		err = stream.Send(&base.UploadImageRequest{Data: &base.UploadImageRequest_Info{
+			Info: &base.UploadImageInfo{AdId: adId, ContentType: "image/png"}}})
+		for ; err == nil && len(data) > 0; data = data[chunk:] {
+			if chunk > len(data) {
+				chunk = len(data)
+			}
+			err = stream.Send(&base.UploadImageRequest{Data: &base.UploadImageRequest_Chunk{Chunk: data[:chunk]}})
+		}
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		return stream.CloseAndRecv()
+	}
+
+	img, err := upload(author, ad.Id, testPNG(300, 200), 1000)
+	assert.NoError(t, err)
+	assert.Equal(t, "image/png", img.ContentType)
+	assert.Equal(t, [2]int32{300, 200}, [2]int32{img.Width, img.Height})
+	_, err = upload(other, ad.Id, testPNG(10, 10), 1000)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = upload(ctx, ad.Id, testPNG(10, 10), 1000)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	_, err = upload(author, 42, testPNG(10, 10), 1000)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	_, err = upload(author, ad.Id, []byte("not an image"), 1000)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = upload(author, ad.Id, make([]byte, ads.MaxImageSize+1), 1<<20)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	second, err := upload(author, ad.Id, testPNG(10, 10), 10)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), second.Position)
+
+	// the info has to come first
+	stream, err := gc.ads.UploadImage(author)
+	assert.NoError(t, err)
+	assert.NoError(t, stream.Send(&base.UploadImageRequest{Data: &base.UploadImageRequest_Chunk{Chunk: testPNG(1, 1)}}))
+	_, err = stream.CloseAndRecv()
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	list, err := gc.ads.ReorderAdImages(author, &base.ReorderAdImagesRequest{AdId: ad.Id, Order: []int64{second.Id, img.Id}})
+	assert.NoError(t, err)
+	if assert.Len(t, list.List, 2) {
+		assert.Equal(t, second.Id, list.List[0].Id)
+	}
+	_, err = gc.ads.ReorderAdImages(author, &base.ReorderAdImagesRequest{AdId: ad.Id, Order: []int64{second.Id}})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = gc.ads.DeleteAdImage(other, &base.DeleteAdImageRequest{AdId: ad.Id, ImageId: img.Id})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = gc.ads.DeleteAdImage(author, &base.DeleteAdImageRequest{AdId: ad.Id, ImageId: img.Id})
+	assert.NoError(t, err)
+	_, err = gc.ads.DeleteAdImage(author, &base.DeleteAdImageRequest{AdId: ad.Id, ImageId: img.Id})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	list, err = gc.ads.ListAdImages(ctx, &base.ListAdImagesRequest{AdId: ad.Id})
+	assert.NoError(t, err)
+	if assert.Len(t, list.List, 1) {
+		assert.Equal(t, second.Id, list.List[0].Id)
+	}
+}
+
+func TestGRRPCScheduleAd(t *testing.T) {
+	ctx, gc := newGRPCClient(t, "Oleg", "Ivan")
+	author, other := gc.as(ctx, t, 0), gc.as(ctx, t, 1)
+	ad, err := gc.ads.CreateAd(author, &base.CreateAdRequest{Title: "bike", Text: "bike"})
+	assert.NoError(t, err)
+
+	publishAt := time.Now().UTC().Add(time.Hour).Truncate(time.Second).Format(time.RFC3339)
+	_, err = gc.ads.ScheduleAd(author, &base.ScheduleAdRequest{AdId: ad.Id, PublishAt: "tomorrow"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = gc.ads.ScheduleAd(author, &base.ScheduleAdRequest{AdId: ad.Id, PublishAt: "2000-01-01T00:00:00Z"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = gc.ads.ScheduleAd(other, &base.ScheduleAdRequest{AdId: ad.Id, PublishAt: publishAt})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = gc.ads.ScheduleAd(ctx, &base.ScheduleAdRequest{AdId: ad.Id, PublishAt: publishAt})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	res, err := gc.ads.ScheduleAd(author, &base.ScheduleAdRequest{AdId: ad.Id, PublishAt: publishAt})
+	assert.NoError(t, err)
+	assert.False(t, res.Published)
+	assert.Equal(t, publishAt, res.PublishAt)
+	assert.NotEmpty(t, res.ExpiresAt)
+	_, err = gc.ads.RenewAd(author, &base.RenewAdRequest{AdId: ad.Id})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	// publishing by hand calls off the schedule
+	_, err = gc.ads.ChangeAdStatus(author, &base.ChangeAdStatusRequest{AdId: ad.Id, Published: true})
+	assert.NoError(t, err)
+	res, err = gc.ads.RenewAd(author, &base.RenewAdRequest{AdId: ad.Id})
+	assert.NoError(t, err)
+	assert.True(t, res.Published)
+	assert.Empty(t, res.PublishAt)
+	_, err = gc.ads.RenewAd(other, &base.RenewAdRequest{AdId: ad.Id})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
@@ -0,0 +1,194 @@
+package tests
+
+import (
+	"context"
+	"google.golang.org/grpc/credentials/insecure"
+	"homework10/internal/adapters/memuow"
+	"homework10/internal/adapters/searchindex"
+	"homework10/internal/adapters/userrepo"
+	"homework10/internal/app/adsapp"
+	"homework10/internal/app/authapp"
+	"homework10/internal/app/userapp"
+	"homework10/internal/entities/user"
+	grpcInterface "homework10/internal/ports/grpc"
+	"homework10/internal/ports/grpc/base"
+	"homework10/pkg/blob"
+	"homework10/pkg/idgen"
+	"homework10/pkg/jwt"
+	"homework10/pkg/mail"
+	"homework10/pkg/passwords"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// grpcClient acts on behalf of the users it has created, like testClient: a
+// call naming one of them in its request goes with their token unless it has
+// one already. The ads it creates go to testCategory unless told otherwise.
+type grpcClient struct {
+	conn       *grpc.ClientConn
+	users      base.UserServiceClient
+	ads        base.AdServiceClient
+	categories base.CategoryServiceClient
+	passwords  map[int64]string
+	tokens     map[int64]string
+	auth       authapp.App
+	userApp    userapp.App
+	userRepo   user.Repository
+	mailer     *mail.Memory
+}
+
+func server(ctx context.Context, t *testing.T) *grpc.ClientConn {
+	return startGRPC(ctx, t).conn
+}
+
+// newGRPCClient starts a server for the test and signs up the users, their
+// passwords are "easyhw".
+func newGRPCClient(t *testing.T, nicknames ...string) (context.Context, *grpcClient) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	t.Cleanup(func() {
+		cancel()
+	})
+	gc := startGRPC(ctx, t)
+	for _, nickname := range nicknames {
+		_, err := gc.users.CreateUser(ctx, &base.CreateUserRequest{Nickname: nickname,
+			Email: strings.ToLower(nickname) + "@tinkoff.com", Password: "easyhw"})
+		assert.NoError(t, err, "client.CreateUser")
+	}
+	return ctx, gc
+}
+
+func startGRPC(ctx context.Context, t *testing.T) *grpcClient {
+	lis := bufconn.Listen(1024 * 1024)
+	t.Cleanup(func() {
+		lis.Close()
+	})
+	users := userrepo.New(idgen.NewSequence())
+	tx := memuow.New(newTestAdRepo(), users)
+	signer, err := jwt.NewSigner(testTokenKey)
+	assert.NoError(t, err)
+	mailer := mail.NewMemory()
+	userApp := userapp.NewApp(tx, passwords.NewForTest(), signer, mailer)
+	authApp := authapp.NewApp(userApp, signer, authapp.DefaultAccessTTL, authapp.DefaultRefreshTTL)
+	srv := grpcInterface.NewGrpcServer(adsapp.NewApp(tx, searchindex.New(), blob.NewMemory()), userApp, authApp, nil)
+	t.Cleanup(func() {
+		srv.Stop()
+	})
+	go func() {
+		assert.NoError(t, srv.Serve(lis), "srv.Serve")
+	}()
+	gc := &grpcClient{
+		passwords: make(map[int64]string),
+		tokens:    make(map[int64]string),
+		auth:      authApp,
+		userApp:   userApp,
+		userRepo:  users,
+		mailer:    mailer,
+	}
+	dialer := func(context.Context, string) (net.Conn, error) {
+		return lis.Dial()
+	}
+	conn, err := grpc.DialContext(ctx, "", grpc.WithContextDialer(dialer), grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithUnaryInterceptor(gc.intercept))
+	assert.NoError(t, err, "grpc.DialContext")
+	t.Cleanup(func() {
+		conn.Close()
+	})
+	gc.conn = conn
+	gc.users = base.NewUserServiceClient(conn)
+	gc.ads = base.NewAdServiceClient(conn)
+	gc.categories = base.NewCategoryServiceClient(conn)
+	return gc
+}
+
+func (gc *grpcClient) intercept(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn,
+	invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	if r, ok := req.(*base.CreateAdRequest); ok && r.CategoryId == 0 {
+		r.CategoryId = testCategory
+	}
+	if md, _ := metadata.FromOutgoingContext(ctx); len(md.Get("authorization")) == 0 && len(md.Get("x-api-key")) == 0 {
+		if userId, ok := actingUser(req); ok {
+			ctx = gc.authorize(ctx, userId)
+		}
+	}
+	err := invoker(ctx, method, req, reply, cc, opts...)
+	if r, ok := req.(*base.CreateUserRequest); ok && err == nil {
+		gc.passwords[reply.(*base.UserResponse).Id] = r.Password
+	}
+	return err
+}
+
+// actingUser is the user the request names as the one making it.
+func actingUser(req any) (int64, bool) {
+	switch r := req.(type) {
+	case *base.CreateAdRequest:
+		return r.UserId, true
+	case *base.ChangeAdStatusRequest:
+		return r.UserId, true
+	case *base.UpdateAdRequest:
+		return r.UserId, true
+	case *base.DeleteAdRequest:
+		return r.AuthorId, true
+	case *base.RevertAdRequest:
+		return r.UserId, true
+	case *base.ChangeNicknameRequest:
+		return r.Id, true
+	case *base.DeleteUserRequest:
+		return r.Id, true
+	}
+	return 0, false
+}
+
+// authorize logs the user in with their email verified, a user the client
+// doesn't know goes without a token.
+func (gc *grpcClient) authorize(ctx context.Context, userId int64) context.Context {
+	token, ok := gc.tokens[userId]
+	if !ok {
+		password, known := gc.passwords[userId]
+		if !known || gc.verify(ctx, userId) != nil {
+			return ctx
+		}
+		tokens, err := gc.auth.Login(ctx, userId, password)
+		if err != nil {
+			return ctx
+		}
+		token = tokens.Access
+		gc.tokens[userId] = token
+	}
+	return metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token)
+}
+
+func (gc *grpcClient) verify(ctx context.Context, userId int64) error {
+	u, err := gc.userRepo.GetUser(ctx, userId)
+	if err != nil || u.Verified {
+		return err
+	}
+	_, err = gc.userApp.VerifyEmail(ctx, mailedToken(gc.mailer, u.Email))
+	return err
+}
+
+// as makes calls on behalf of the user, who verifies their email first to
+// publish ads.
+func (gc *grpcClient) as(ctx context.Context, t *testing.T, userId int64) context.Context {
+	assert.NoError(t, gc.verify(ctx, userId), "verify")
+	return gc.withToken(ctx, t, userId)
+}
+
+func (gc *grpcClient) withToken(ctx context.Context, t *testing.T, userId int64) context.Context {
+	tokens, err := gc.auth.Login(ctx, userId, "easyhw")
+	if !assert.NoError(t, err, "auth.Login") {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+tokens.Access)
+}
+
+func (gc *grpcClient) setRole(t *testing.T, userId int64, role user.Role) {
+	_, err := gc.userRepo.UpdateRole(context.Background(), userId, role)
+	assert.NoError(t, err, "users.UpdateRole")
+}
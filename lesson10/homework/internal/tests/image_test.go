@@ -0,0 +1,120 @@
+package tests
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"homework10/internal/entities/ads"
+	"image"
+	"io"
+	"net/http"
+	"testing"
+)
+
+func TestImages(t *testing.T) {
+	client := getTestClient()
+
+	_, err := client.createUser("seller", "seller@mail.com", "seller")
+	assert.NoError(t, err)
+	_, err = client.createUser("buyer", "buyer@mail.com", "buyer")
+	assert.NoError(t, err)
+	ad, err := client.createAd(0, "bike", "fast")
+	assert.NoError(t, err)
+
+	_, err = client.uploadImage(-1, ad.Data.ID, "", testPNG(10, 10))
+	assert.ErrorIs(t, err, ErrUnauthorized)
+	_, err = client.uploadImage(1, ad.Data.ID, "", testPNG(10, 10))
+	assert.ErrorIs(t, err, ErrForbidden)
+	_, err = client.uploadImage(0, 42, "", testPNG(10, 10))
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = client.uploadImage(0, ad.Data.ID, "image/png", []byte("GIF89a"))
+	assert.ErrorIs(t, err, ErrBadRequest)
+	_, err = client.uploadImage(0, ad.Data.ID, "image/jpeg", testPNG(10, 10))
+	assert.ErrorIs(t, err, ErrBadRequest)
+	_, err = client.uploadImage(0, ad.Data.ID, "", make([]byte, ads.MaxImageSize+1))
+	assert.ErrorIs(t, err, ErrTooLarge)
+
+	cover, err := client.uploadImage(0, ad.Data.ID, "image/png", testPNG(600, 300))
+	assert.NoError(t, err)
+	assert.Equal(t, "image/png", cover.Data.ContentType)
+	assert.Equal(t, [2]int{600, 300}, [2]int{cover.Data.Width, cover.Data.Height})
+	assert.Len(t, cover.Data.Thumbnails, len(ads.Thumbnails))
+	second, err := client.uploadImage(0, ad.Data.ID, "application/octet-stream", testPNG(10, 10))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, second.Data.Position)
+
+	resp, err := client.getImageFile(cover.Data.URL)
+	assert.NoError(t, err)
+	data, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "image/png", resp.Header.Get("Content-Type"))
+	assert.Contains(t, resp.Header.Get("Cache-Control"), "immutable")
+	assert.Equal(t, testPNG(600, 300), data)
+	etag := resp.Header.Get("ETag")
+
+	// the thumbnails are jpegs fitted in their square
+	resp, err = client.getImageFile(cover.Data.Thumbnails["small"])
+	assert.NoError(t, err)
+	data, _ = io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	assert.Equal(t, "image/jpeg", resp.Header.Get("Content-Type"))
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	assert.NoError(t, err)
+	assert.Equal(t, "jpeg", format)
+	assert.Equal(t, [2]int{160, 80}, [2]int{cfg.Width, cfg.Height})
+
+	// the cached copy is still good
+	req, _ := http.NewRequest(http.MethodGet, client.baseURL+cover.Data.URL, nil)
+	req.Header.Set("If-None-Match", etag)
+	resp, err = client.client.Do(req)
+	assert.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusNotModified, resp.StatusCode)
+
+	resp, err = client.getImageFile(cover.Data.URL + "?size=huge")
+	assert.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	_, err = client.reorderImages(1, ad.Data.ID, []int64{second.Data.ID, cover.Data.ID})
+	assert.ErrorIs(t, err, ErrForbidden)
+	_, err = client.reorderImages(0, ad.Data.ID, []int64{second.Data.ID})
+	assert.ErrorIs(t, err, ErrBadRequest)
+	list, err := client.reorderImages(0, ad.Data.ID, []int64{second.Data.ID, cover.Data.ID})
+	assert.NoError(t, err)
+	if assert.Len(t, list.Data, 2) {
+		assert.Equal(t, second.Data.ID, list.Data[0].ID)
+		assert.Equal(t, 1, list.Data[1].Position)
+	}
+
+	assert.ErrorIs(t, client.deleteImage(1, ad.Data.ID, cover.Data.ID), ErrForbidden)
+	assert.NoError(t, client.deleteImage(0, ad.Data.ID, cover.Data.ID))
+	assert.ErrorIs(t, client.deleteImage(0, ad.Data.ID, cover.Data.ID), ErrNotFound)
+	resp, err = client.getImageFile(cover.Data.URL)
+	assert.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	list, err = client.getImages(ad.Data.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, list.Data, 1) {
+		assert.Equal(t, second.Data.ID, list.Data[0].ID)
+	}
+	_, err = client.getImages(42)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestImagesLimit(t *testing.T) {
+	client := getTestClient()
+
+	_, err := client.createUser("seller", "seller@mail.com", "seller")
+	assert.NoError(t, err)
+	ad, err := client.createAd(0, "bike", "fast")
+	assert.NoError(t, err)
+	for i := 0; i < ads.MaxImagesPerAd; i++ {
+		_, err = client.uploadImage(0, ad.Data.ID, "", testPNG(1, 1))
+		assert.NoError(t, err)
+	}
+	_, err = client.uploadImage(0, ad.Data.ID, "", testPNG(1, 1))
+	assert.ErrorIs(t, err, ErrConflict)
+}
@@ -0,0 +1,150 @@
+package tests
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/url"
+	"testing"
+)
+
+func TestChangeRole(t *testing.T) {
+	client := getTestClient()
+
+	for _, nick := range []string{"author", "moderator", "admin"} {
+		_, err := client.createUser(nick, nick+"@mail.com", "password")
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, client.makeAdmin(2))
+
+	usr, err := client.getUser(0)
+	assert.NoError(t, err)
+	assert.Equal(t, "user", usr.Data.Role)
+
+	_, err = client.changeRole(0, 1, "moderator")
+	assert.ErrorIs(t, err, ErrForbidden)
+	_, err = client.changeRole(2, 1, "superuser")
+	assert.ErrorIs(t, err, ErrBadRequest)
+	_, err = client.changeRole(2, 3, "moderator")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	usr, err = client.changeRole(2, 1, "moderator")
+	assert.NoError(t, err)
+	assert.Equal(t, "moderator", usr.Data.Role)
+	// a moderator can't hand out roles
+	_, err = client.changeRole(1, 0, "moderator")
+	assert.ErrorIs(t, err, ErrForbidden)
+}
+
+func TestModerateAds(t *testing.T) {
+	client := getTestClient()
+
+	for _, nick := range []string{"author", "moderator", "admin", "other"} {
+		_, err := client.createUser(nick, nick+"@mail.com", "password")
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, client.makeAdmin(2))
+	_, err := client.changeRole(2, 1, "moderator")
+	assert.NoError(t, err)
+
+	ad, err := client.createAd(0, "hello", "world")
+	assert.NoError(t, err)
+	_, err = client.changeAdStatus(0, ad.Data.ID, true)
+	assert.NoError(t, err)
+
+	// moderators take ads down, they don't edit them
+	_, err = client.updateAd(1, ad.Data.ID, "moderated", "world")
+	assert.ErrorIs(t, err, ErrForbidden)
+	_, err = client.unpublishAd(3, ad.Data.ID, "spam")
+	assert.ErrorIs(t, err, ErrForbidden)
+	_, err = client.unpublishAd(1, ad.Data.ID, "")
+	assert.ErrorIs(t, err, ErrBadRequest)
+
+	unpublished, err := client.unpublishAd(1, ad.Data.ID, "spam")
+	assert.NoError(t, err)
+	assert.False(t, unpublished.Data.Published)
+	assert.Equal(t, "rejected", unpublished.Data.State)
+	assert.Equal(t, "spam", unpublished.Data.RejectionReason)
+	revisions, err := client.getRevisions(ad.Data.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, revisions.Data, 3) {
+		assert.Equal(t, int64(1), revisions.Data[2].ActorID)
+		assert.Equal(t, "spam", revisions.Data[2].Reason)
+	}
+
+	_, err = client.deleteAdWithReason(ad.Data.ID, 3, "spam")
+	assert.ErrorIs(t, err, ErrForbidden)
+	_, err = client.deleteAd(ad.Data.ID, 1)
+	assert.ErrorIs(t, err, ErrBadRequest)
+	// admins moderate too
+	_, err = client.deleteAdWithReason(ad.Data.ID, 2, "offensive")
+	assert.NoError(t, err)
+
+	trash, err := client.getTrash(0)
+	assert.NoError(t, err)
+	assert.Len(t, trash.Data, 1)
+}
+
+func TestAdStates(t *testing.T) {
+	client := getTestClient()
+
+	for _, nick := range []string{"author", "moderator", "admin"} {
+		_, err := client.createUser(nick, nick+"@mail.com", "password")
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, client.makeAdmin(2))
+	_, err := client.changeRole(2, 1, "moderator")
+	assert.NoError(t, err)
+
+	ad, err := client.createAd(0, "hello", "world")
+	assert.NoError(t, err)
+	assert.Equal(t, "draft", ad.Data.State)
+	_, err = client.changeAdState(0, ad.Data.ID, "lost", "")
+	assert.ErrorIs(t, err, ErrBadRequest)
+	_, err = client.changeAdState(0, ad.Data.ID, "sold", "")
+	assert.ErrorIs(t, err, ErrConflict)
+	_, err = client.changeAdState(0, 42, "pending_review", "")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	ad, err = client.changeAdState(0, ad.Data.ID, "pending_review", "")
+	assert.NoError(t, err)
+	assert.Equal(t, "pending_review", ad.Data.State)
+	// the review is up to the moderators, who give the reason to reject
+	_, err = client.changeAdState(0, ad.Data.ID, "published", "")
+	assert.ErrorIs(t, err, ErrForbidden)
+	_, err = client.changeAdState(1, ad.Data.ID, "rejected", "")
+	assert.ErrorIs(t, err, ErrBadRequest)
+	ad, err = client.changeAdState(1, ad.Data.ID, "rejected", "blurry photos")
+	assert.NoError(t, err)
+	assert.Equal(t, "rejected", ad.Data.State)
+	assert.Equal(t, "blurry photos", ad.Data.RejectionReason)
+	_, err = client.changeAdStatus(0, ad.Data.ID, true)
+	assert.ErrorIs(t, err, ErrConflict)
+
+	_, err = client.changeAdState(0, ad.Data.ID, "pending_review", "")
+	assert.NoError(t, err)
+	ad, err = client.changeAdState(1, ad.Data.ID, "published", "")
+	assert.NoError(t, err)
+	assert.True(t, ad.Data.Published)
+	assert.Empty(t, ad.Data.RejectionReason)
+	ad, err = client.changeAdState(0, ad.Data.ID, "sold", "")
+	assert.NoError(t, err)
+	assert.False(t, ad.Data.Published)
+
+	sold, err := client.listAdsWithParams(url.Values{"status": {"sold"}})
+	assert.NoError(t, err)
+	if assert.Len(t, sold.Data, 1) {
+		assert.Equal(t, ad.Data.ID, sold.Data[0].ID)
+	}
+	published, err := client.listAds()
+	assert.NoError(t, err)
+	assert.Empty(t, published.Data)
+	_, err = client.listAdsWithParams(url.Values{"status": {"lost"}})
+	assert.ErrorIs(t, err, ErrBadRequest)
+
+	revisions, err := client.getRevisions(ad.Data.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, revisions.Data, 6) {
+		assert.Equal(t, "blurry photos", revisions.Data[2].Reason)
+		assert.Equal(t, "rejected", revisions.Data[2].State)
+		assert.Equal(t, []changeData{{Field: "state", Old: "published", New: "sold"}}, revisions.Data[5].Changes)
+	}
+}
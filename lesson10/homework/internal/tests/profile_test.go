@@ -0,0 +1,81 @@
+package tests
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/url"
+	"testing"
+)
+
+func TestProfile(t *testing.T) {
+	client := getTestClient()
+
+	_, err := client.createUser("owner", "owner@mail.com", "owner")
+	assert.NoError(t, err)
+	_, err = client.createUser("other", "other@mail.com", "other")
+	assert.NoError(t, err)
+
+	profile, err := client.updateProfile(0, 0, map[string]any{"display_name": "The Owner", "bio": "sells bikes",
+		"city": "Moscow", "phone": "+7 900 000-00-00", "city_visibility": "public", "phone_visibility": "users"})
+	assert.NoError(t, err)
+	assert.Equal(t, "users", profile.Data.PhoneVisibility)
+	_, err = client.updateProfile(1, 0, map[string]any{"display_name": "Not The Owner"})
+	assert.ErrorIs(t, err, ErrForbidden)
+	_, err = client.updateProfile(0, 0, map[string]any{"city_visibility": "friends"})
+	assert.ErrorIs(t, err, ErrBadRequest)
+	_, err = client.updateProfile(-1, 0, map[string]any{"display_name": "Anonymous"})
+	assert.ErrorIs(t, err, ErrUnauthorized)
+
+	published, err := client.createAd(0, "bike", "fast")
+	assert.NoError(t, err)
+	_, err = client.changeAdStatus(0, published.Data.ID, true)
+	assert.NoError(t, err)
+	_, err = client.createAd(0, "draft", "not yet")
+	assert.NoError(t, err)
+
+	// anonymous viewers don't see the phone, signed in users do
+	profile, err = client.getProfile(-1, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, "The Owner", profile.Data.DisplayName)
+	assert.Equal(t, "Moscow", profile.Data.City)
+	assert.Empty(t, profile.Data.Phone)
+	assert.NotEmpty(t, profile.Data.RegisteredAt)
+	if assert.Len(t, profile.Data.Ads, 1) {
+		assert.Equal(t, published.Data.ID, profile.Data.Ads[0].ID)
+	}
+	profile, err = client.getProfile(1, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, "+7 900 000-00-00", profile.Data.Phone)
+	_, err = client.getProfile(-1, 7)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestSearchUsers(t *testing.T) {
+	client := getTestClient()
+
+	for _, nick := range []string{"marina", "Mark", "oleg", "MARTIN"} {
+		_, err := client.createUser(nick, nick+"@mail.com", "password")
+		assert.NoError(t, err)
+	}
+
+	var found []string
+	params := url.Values{"prefix": {"mar"}, "limit": {"2"}}
+	for page := 0; page < 3; page++ {
+		resp, err := client.searchUsers(params)
+		if !assert.NoError(t, err) {
+			return
+		}
+		for _, u := range resp.Data {
+			found = append(found, u.Nickname)
+		}
+		if resp.NextCursor == "" {
+			break
+		}
+		params.Set("cursor", resp.NextCursor)
+	}
+	assert.Equal(t, []string{"marina", "Mark", "MARTIN"}, found)
+
+	_, err := client.searchUsers(url.Values{"limit": {"1000"}})
+	assert.ErrorIs(t, err, ErrBadRequest)
+	_, err = client.searchUsers(url.Values{"cursor": {"bad"}})
+	assert.ErrorIs(t, err, ErrBadRequest)
+}
@@ -0,0 +1,66 @@
+package tests
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestResetPassword(t *testing.T) {
+	client := getTestClient()
+
+	usr, err := client.createUser("tester", "tester@mail.com", "tester")
+	assert.NoError(t, err)
+	tokens, err := client.login(usr.Data.Id, "tester")
+	assert.NoError(t, err)
+
+	// unknown addresses get the same answer and no mail
+	assert.NoError(t, client.forgotPassword("nobody@mail.com"))
+	assert.Empty(t, client.mailer.Sent("nobody@mail.com"))
+
+	assert.NoError(t, client.forgotPassword("Tester@Mail.com"))
+	token := mailedToken(client.mailer, "tester@mail.com")
+	_, err = client.resetPassword("garbage", "new password")
+	assert.ErrorIs(t, err, ErrBadRequest)
+	_, err = client.resetPassword(token, "")
+	assert.ErrorIs(t, err, ErrBadRequest)
+	_, err = client.resetPassword(token, "new password")
+	assert.NoError(t, err)
+	_, err = client.resetPassword(token, "newer password")
+	assert.ErrorIs(t, err, ErrBadRequest)
+
+	// the sessions started with the old password are over
+	_, err = client.refresh(tokens.Data.RefreshToken)
+	assert.Error(t, err)
+	_, err = client.login(usr.Data.Id, "tester")
+	assert.Error(t, err)
+	_, err = client.login(usr.Data.Id, "new password")
+	assert.NoError(t, err)
+}
+
+func TestUpdatePassword_EndsSessions(t *testing.T) {
+	client := getTestClient()
+
+	usr, err := client.createUser("tester", "tester@mail.com", "tester")
+	assert.NoError(t, err)
+	tokens, err := client.login(usr.Data.Id, "tester")
+	assert.NoError(t, err)
+
+	_, err = client.updatePassword(usr.Data.Id, "new password")
+	assert.NoError(t, err)
+	_, err = client.refresh(tokens.Data.RefreshToken)
+	assert.Error(t, err)
+	ad, err := client.createAd(usr.Data.Id, "hello", "world")
+	assert.NoError(t, err)
+	assert.Equal(t, usr.Data.Id, ad.Data.AuthorID)
+}
+
+func TestUpdatePassword_ChecksCurrent(t *testing.T) {
+	client := getTestClient()
+
+	usr, err := client.createUser("tester", "tester@mail.com", "tester")
+	assert.NoError(t, err)
+
+	client.passwords[usr.Data.Id] = "wrong"
+	_, err = client.updatePassword(usr.Data.Id, "new password")
+	assert.ErrorIs(t, err, ErrForbidden)
+}
@@ -0,0 +1,71 @@
+package tests
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"homework10/internal/entities/ads"
+	"testing"
+	"time"
+)
+
+func TestSchedule(t *testing.T) {
+	client := getTestClient()
+
+	_, err := client.createUser("seller", "seller@mail.com", "seller")
+	assert.NoError(t, err)
+	_, err = client.createUser("buyer", "buyer@mail.com", "buyer")
+	assert.NoError(t, err)
+	bike, err := client.createAd(0, "bike", "fast")
+	assert.NoError(t, err)
+	car, err := client.createAd(0, "car", "faster")
+	assert.NoError(t, err)
+
+	// the times go over the wire in seconds
+	now := time.Now().UTC().Truncate(time.Second)
+	_, err = client.scheduleAd(bike.Data.ID, 1, now.Add(time.Hour), time.Time{})
+	assert.ErrorIs(t, err, ErrForbidden)
+	_, err = client.scheduleAd(42, 0, now.Add(time.Hour), time.Time{})
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = client.scheduleAd(bike.Data.ID, 0, now.Add(-time.Hour), time.Time{})
+	assert.ErrorIs(t, err, ErrBadRequest)
+	_, err = client.scheduleAd(bike.Data.ID, 0, now.Add(time.Hour), now.Add(time.Minute))
+	assert.ErrorIs(t, err, ErrBadRequest)
+
+	scheduled, err := client.scheduleAd(bike.Data.ID, 0, now.Add(2*time.Second), time.Time{})
+	assert.NoError(t, err)
+	assert.False(t, scheduled.Data.Published)
+	assert.Equal(t, now.Add(2*time.Second).Format(time.RFC3339), scheduled.Data.PublishAt)
+	assert.NotEmpty(t, scheduled.Data.ExpiresAt)
+	_, err = client.renewAd(bike.Data.ID, 0)
+	assert.ErrorIs(t, err, ErrConflict)
+
+	_, err = client.changeAdStatus(0, car.Data.ID, true)
+	assert.NoError(t, err)
+	expiring, err := client.scheduleAd(car.Data.ID, 0, time.Time{}, now.Add(2*time.Second))
+	assert.NoError(t, err)
+	assert.True(t, expiring.Data.Published)
+	assert.Empty(t, expiring.Data.PublishAt)
+
+	time.Sleep(time.Until(now.Add(2 * time.Second)))
+	n, err := client.ads.ApplySchedule(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	got, err := client.getAdById(bike.Data.ID)
+	assert.NoError(t, err)
+	assert.True(t, got.Data.Published)
+	assert.Empty(t, got.Data.PublishAt)
+	got, err = client.getAdById(car.Data.ID)
+	assert.NoError(t, err)
+	assert.False(t, got.Data.Published)
+	assert.Equal(t, "archived", got.Data.State)
+
+	_, err = client.renewAd(car.Data.ID, 1)
+	assert.ErrorIs(t, err, ErrForbidden)
+	renewed, err := client.renewAd(car.Data.ID, 0)
+	assert.NoError(t, err)
+	assert.True(t, renewed.Data.Published)
+	expiresAt, err := time.Parse(time.RFC3339, renewed.Data.ExpiresAt)
+	assert.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(ads.Lifetime), expiresAt, time.Minute)
+}
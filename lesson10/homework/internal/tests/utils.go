@@ -2,15 +2,33 @@ package tests
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"homework10/internal/adapters/memuow"
+	"homework10/internal/adapters/searchindex"
 	"homework10/internal/adapters/userrepo"
 	"homework10/internal/app/adsapp"
+	"homework10/internal/app/authapp"
 	"homework10/internal/app/userapp"
+	"homework10/internal/entities/ads"
+	"homework10/internal/entities/user"
+	"homework10/pkg/blob"
+	"homework10/pkg/idgen"
+	"homework10/pkg/jwt"
 	"homework10/pkg/logger"
+	"homework10/pkg/mail"
+	"homework10/pkg/passwords"
+	"image"
+	"image/png"
 	"io"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"net/textproto"
+	"net/url"
+	"strings"
+	"time"
 
 	"homework10/internal/adapters/adrepo"
 	"homework10/internal/ports/httpgin"
@@ -21,9 +39,17 @@ type adData struct {
 	Title        string `json:"title"`
 	Text         string `json:"text"`
 	AuthorID     int64  `json:"author_id"`
+	CategoryID   int64  `json:"category_id"`
 	CreationDate string `json:"creation_date"`
 	UpdateDate   string `json:"update_date"`
 	Published    bool   `json:"published"`
+	State        string `json:"state"`
+	// RejectionReason is set only for the rejected ads
+	RejectionReason string `json:"rejection_reason"`
+	Version         int64  `json:"version"`
+	DeletedAt       string `json:"deleted_at"`
+	PublishAt       string `json:"publish_at"`
+	ExpiresAt       string `json:"expires_at"`
 }
 
 type adResponse struct {
@@ -31,7 +57,30 @@ type adResponse struct {
 }
 
 type adsResponse struct {
-	Data []adData `json:"data"`
+	Data       []adData `json:"data"`
+	NextCursor string   `json:"next_cursor"`
+}
+
+type changeData struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+type revisionData struct {
+	Number    int64        `json:"number"`
+	ActorID   int64        `json:"actor_id"`
+	Reason    string       `json:"reason"`
+	CreatedAt string       `json:"created_at"`
+	Title     string       `json:"title"`
+	Text      string       `json:"text"`
+	Published bool         `json:"published"`
+	State     string       `json:"state"`
+	Changes   []changeData `json:"changes"`
+}
+
+type revisionsResponse struct {
+	Data []revisionData `json:"data"`
 }
 
 type deleteAdResponse struct {
@@ -43,6 +92,8 @@ type userData struct {
 	Nickname string `json:"nickname"`
 	Email    string `json:"email"`
 	Password string `json:"password"`
+	Role     string `json:"role"`
+	Verified bool   `json:"verified"`
 }
 
 type userResponse struct {
@@ -53,29 +104,189 @@ type deleteUserResponse struct {
 	Data string `json:"data"`
 }
 
+type verificationResponse struct {
+	Data string `json:"data"`
+}
+
+type tokensData struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+type tokensResponse struct {
+	Data tokensData `json:"data"`
+}
+
+type apiKeyData struct {
+	ID         string     `json:"id"`
+	Key        string     `json:"key"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+}
+
+type apiKeyResponse struct {
+	Data apiKeyData `json:"data"`
+}
+
+type apiKeysResponse struct {
+	Data []apiKeyData `json:"data"`
+}
+
+type activityData struct {
+	AdID   int64  `json:"ad_id"`
+	Number int64  `json:"number"`
+	Title  string `json:"title"`
+}
+
+type exportResponse struct {
+	Data struct {
+		User     userData       `json:"user"`
+		Ads      []adData       `json:"ads"`
+		Activity []activityData `json:"activity"`
+		APIKeys  []apiKeyData   `json:"api_keys"`
+	} `json:"data"`
+}
+
+type categoryData struct {
+	ID       int64  `json:"id"`
+	ParentID int64  `json:"parent_id"`
+	Name     string `json:"name"`
+	Ads      int    `json:"ads"`
+}
+
+type categoryResponse struct {
+	Data categoryData `json:"data"`
+}
+
+type categoriesResponse struct {
+	Data []categoryData `json:"data"`
+}
+
+type imageData struct {
+	ID          int64             `json:"id"`
+	Position    int               `json:"position"`
+	ContentType string            `json:"content_type"`
+	Size        int64             `json:"size"`
+	Width       int               `json:"width"`
+	Height      int               `json:"height"`
+	URL         string            `json:"url"`
+	Thumbnails  map[string]string `json:"thumbnails"`
+}
+
+type imageResponse struct {
+	Data imageData `json:"data"`
+}
+
+type imagesResponse struct {
+	Data []imageData `json:"data"`
+}
+
+type profileData struct {
+	Id              int64    `json:"id"`
+	Nickname        string   `json:"nickname"`
+	DisplayName     string   `json:"display_name"`
+	Bio             string   `json:"bio"`
+	City            string   `json:"city"`
+	Phone           string   `json:"phone"`
+	CityVisibility  string   `json:"city_visibility"`
+	PhoneVisibility string   `json:"phone_visibility"`
+	RegisteredAt    string   `json:"registered_at"`
+	Ads             []adData `json:"ads"`
+}
+
+type profileResponse struct {
+	Data profileData `json:"data"`
+}
+
+type usersFoundResponse struct {
+	Data       []profileData `json:"data"`
+	NextCursor string        `json:"next_cursor"`
+}
+
 var (
-	ErrBadRequest = fmt.Errorf("bad request")
-	ErrForbidden  = fmt.Errorf("forbidden")
-	ErrNotFound   = fmt.Errorf("not found")
+	ErrBadRequest         = fmt.Errorf("bad request")
+	ErrConflict           = fmt.Errorf("conflict")
+	ErrForbidden          = fmt.Errorf("forbidden")
+	ErrNotFound           = fmt.Errorf("not found")
+	ErrPreconditionFailed = fmt.Errorf("precondition failed")
+	ErrTooLarge           = fmt.Errorf("request entity too large")
+	ErrUnauthorized       = fmt.Errorf("unauthorized")
 )
 
+var testTokenKey = []byte("0123456789abcdef0123456789abcdef")
+
+// testCategory is the category the test servers start with, the ads of the
+// tests go there.
+const testCategory int64 = 1
+
+// nobody is a user the test clients don't know, their calls go without a
+// token.
+const nobody int64 = -1
+
+func newTestAdRepo() ads.Store {
+	repo := adrepo.New(idgen.NewSequence())
+	_, _ = repo.AddCategory(context.Background(), &ads.Category{Name: "misc"})
+	return repo
+}
+
+// testClient acts on behalf of the users it has created: it remembers their
+// passwords and logs them in on their first authenticated request.
 type testClient struct {
-	client  *http.Client
-	baseURL string
+	client    *http.Client
+	baseURL   string
+	passwords map[int64]string
+	tokens    map[int64]string
+	users     user.Repository
+	mailer    *mail.Memory
+	// ads runs what the server does in the background
+	ads adsapp.App
 }
 
 func getTestClient() *testClient {
 	log := logger.InitLog()
-	userRepo := userrepo.New()
-	server := httpgin.NewHTTPServer(":18080", adsapp.NewApp(adrepo.New(), userRepo), userapp.NewApp(userRepo), log)
+	users := userrepo.New(idgen.NewSequence())
+	tx := memuow.New(newTestAdRepo(), users)
+	signer, _ := jwt.NewSigner(testTokenKey)
+	mailer := mail.NewMemory()
+	userApp := userapp.NewApp(tx, passwords.NewForTest(), signer, mailer)
+	authApp := authapp.NewApp(userApp, signer, time.Minute, time.Hour)
+	adsApp := adsapp.NewApp(tx, searchindex.New(), blob.NewMemory())
+	server := httpgin.NewHTTPServer(":18080", adsApp, userApp, authApp, nil, log)
 	testServer := httptest.NewServer(server.Handler)
 
 	return &testClient{
-		client:  testServer.Client(),
-		baseURL: testServer.URL,
+		client:    testServer.Client(),
+		baseURL:   testServer.URL,
+		passwords: make(map[int64]string),
+		tokens:    make(map[int64]string),
+		users:     users,
+		mailer:    mailer,
+		ads:       adsApp,
 	}
 }
 
+// authorize adds the access token of the user to the request, a user the
+// client doesn't know goes without one.
+func (tc *testClient) authorize(req *http.Request, userID int64) {
+	token, ok := tc.tokens[userID]
+	if !ok {
+		password, known := tc.passwords[userID]
+		if !known {
+			return
+		}
+		response, err := tc.login(userID, password)
+		if err != nil {
+			return
+		}
+		token = response.Data.AccessToken
+		tc.tokens[userID] = token
+	}
+	req.Header.Add("Authorization", "Bearer "+token)
+}
+
 func (tc *testClient) getResponse(req *http.Request, out any) error {
 	resp, err := tc.client.Do(req)
 	if err != nil {
@@ -92,6 +303,18 @@ func (tc *testClient) getResponse(req *http.Request, out any) error {
 		if resp.StatusCode == http.StatusForbidden {
 			return ErrForbidden
 		}
+		if resp.StatusCode == http.StatusPreconditionFailed {
+			return ErrPreconditionFailed
+		}
+		if resp.StatusCode == http.StatusUnauthorized {
+			return ErrUnauthorized
+		}
+		if resp.StatusCode == http.StatusConflict {
+			return ErrConflict
+		}
+		if resp.StatusCode == http.StatusRequestEntityTooLarge {
+			return ErrTooLarge
+		}
 		return fmt.Errorf("unexpected status code: %s", resp.Status)
 	}
 
@@ -106,11 +329,37 @@ func (tc *testClient) getResponse(req *http.Request, out any) error {
 	return nil
 }
 
+// call sends the request on behalf of the user with the body as JSON, if any,
+// and reads the response into out.
+func (tc *testClient) call(method, path string, userID int64, body any, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("unable to marshal: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, tc.baseURL+path, reader)
+	if err != nil {
+		return fmt.Errorf("unable to create request: %w", err)
+	}
+	if body != nil {
+		req.Header.Add("Content-Type", "application/json")
+	}
+	tc.authorize(req, userID)
+	return tc.getResponse(req, out)
+}
+
 func (tc *testClient) createAd(userID int64, title string, text string) (adResponse, error) {
+	return tc.createAdInCategory(userID, testCategory, title, text)
+}
+
+func (tc *testClient) createAdInCategory(userID, categoryID int64, title string, text string) (adResponse, error) {
 	body := map[string]any{
-		"user_id": userID,
-		"title":   title,
-		"text":    text,
+		"title":       title,
+		"text":        text,
+		"category_id": categoryID,
 	}
 
 	data, err := json.Marshal(body)
@@ -122,6 +371,7 @@ func (tc *testClient) createAd(userID int64, title string, text string) (adRespo
 	if err != nil {
 		return adResponse{}, fmt.Errorf("unable to create request: %w", err)
 	}
+	tc.authorize(req, userID)
 
 	//req.Header.Add("Content-Type", "application/json")
 
@@ -136,7 +386,6 @@ func (tc *testClient) createAd(userID int64, title string, text string) (adRespo
 
 func (tc *testClient) changeAdStatus(userID int64, adID int64, published bool) (adResponse, error) {
 	body := map[string]any{
-		"user_id":   userID,
 		"published": published,
 	}
 
@@ -149,6 +398,7 @@ func (tc *testClient) changeAdStatus(userID int64, adID int64, published bool) (
 	if err != nil {
 		return adResponse{}, fmt.Errorf("unable to create request: %w", err)
 	}
+	tc.authorize(req, userID)
 
 	req.Header.Add("Content-Type", "application/json")
 
@@ -161,11 +411,28 @@ func (tc *testClient) changeAdStatus(userID int64, adID int64, published bool) (
 	return response, nil
 }
 
+func (tc *testClient) changeAdState(userID int64, adID int64, state, reason string) (adResponse, error) {
+	var response adResponse
+	err := tc.call(http.MethodPut, fmt.Sprintf("/api/v1/ads/%d/state", adID), userID,
+		map[string]any{"state": state, "reason": reason}, &response)
+	return response, err
+}
+
+func (tc *testClient) unpublishAd(userID int64, adID int64, reason string) (adResponse, error) {
+	var response adResponse
+	err := tc.call(http.MethodPost, fmt.Sprintf("/api/v1/ads/%d/unpublish", adID), userID,
+		map[string]any{"reason": reason}, &response)
+	return response, err
+}
+
 func (tc *testClient) updateAd(userID int64, adID int64, title string, text string) (adResponse, error) {
+	return tc.updateAdIfMatch(userID, adID, title, text, "")
+}
+
+func (tc *testClient) updateAdIfMatch(userID int64, adID int64, title string, text string, etag string) (adResponse, error) {
 	body := map[string]any{
-		"user_id": userID,
-		"title":   title,
-		"text":    text,
+		"title": title,
+		"text":  text,
 	}
 
 	data, err := json.Marshal(body)
@@ -177,8 +444,12 @@ func (tc *testClient) updateAd(userID int64, adID int64, title string, text stri
 	if err != nil {
 		return adResponse{}, fmt.Errorf("unable to create request: %w", err)
 	}
+	tc.authorize(req, userID)
 
 	req.Header.Add("Content-Type", "application/json")
+	if etag != "" {
+		req.Header.Add("If-Match", etag)
+	}
 
 	var response adResponse
 	err = tc.getResponse(req, &response)
@@ -226,70 +497,128 @@ func (tc *testClient) getAdByTitle(title string) (adsResponse, error) {
 }
 
 func (tc *testClient) deleteAd(adId, userId int64) (deleteAdResponse, error) {
-	body := map[string]any{
-		"user_id": userId,
+	return tc.deleteAdWithReason(adId, userId, "")
+}
+
+func (tc *testClient) deleteAdWithReason(adId, userId int64, reason string) (deleteAdResponse, error) {
+	path := fmt.Sprintf("/api/v1/ads/%d/delete", adId)
+	if reason != "" {
+		path += "?" + url.Values{"reason": {reason}}.Encode()
 	}
-	data, err := json.Marshal(body)
-	if err != nil {
-		return deleteAdResponse{}, fmt.Errorf("unable to marshal: %w", err)
+	var response deleteAdResponse
+	err := tc.call(http.MethodDelete, path, userId, nil, &response)
+	return response, err
+}
+
+func (tc *testClient) restoreAd(adId, userId int64) (adResponse, error) {
+	var response adResponse
+	err := tc.call(http.MethodPost, fmt.Sprintf("/api/v1/ads/%d/restore", adId), userId, nil, &response)
+	return response, err
+}
+
+// scheduleAd leaves out the zero times.
+func (tc *testClient) scheduleAd(adId, userId int64, publishAt, expiresAt time.Time) (adResponse, error) {
+	body := map[string]any{}
+	if !publishAt.IsZero() {
+		body["publish_at"] = publishAt.Format(time.RFC3339)
 	}
-	req, err := http.NewRequest(http.MethodDelete, fmt.Sprintf(tc.baseURL+"/api/v1/ads/%d/delete", adId), bytes.NewReader(data))
-	if err != nil {
-		return deleteAdResponse{}, fmt.Errorf("unable to create request: %w", err)
+	if !expiresAt.IsZero() {
+		body["expires_at"] = expiresAt.Format(time.RFC3339)
 	}
+	var response adResponse
+	err := tc.call(http.MethodPut, fmt.Sprintf("/api/v1/ads/%d/schedule", adId), userId, body, &response)
+	return response, err
+}
 
-	req.Header.Add("Content-Type", "application/json")
+func (tc *testClient) renewAd(adId, userId int64) (adResponse, error) {
+	var response adResponse
+	err := tc.call(http.MethodPost, fmt.Sprintf("/api/v1/ads/%d/renew", adId), userId, nil, &response)
+	return response, err
+}
 
-	var response deleteAdResponse
-	err = tc.getResponse(req, &response)
-	if err != nil {
-		return deleteAdResponse{}, err
-	}
-	return response, nil
+func (tc *testClient) getTrash(userId int64) (adsResponse, error) {
+	var response adsResponse
+	err := tc.call(http.MethodGet, "/api/v1/ads/trash", userId, nil, &response)
+	return response, err
+}
+
+func (tc *testClient) getRevisions(adId int64) (revisionsResponse, error) {
+	var response revisionsResponse
+	err := tc.call(http.MethodGet, fmt.Sprintf("/api/v1/ads/%d/revisions", adId), nobody, nil, &response)
+	return response, err
+}
+
+func (tc *testClient) getAdAtRevision(adId, revision int64) (adResponse, error) {
+	var response adResponse
+	err := tc.call(http.MethodGet, fmt.Sprintf("/api/v1/ads/%d/revisions/%d", adId, revision), nobody, nil, &response)
+	return response, err
+}
+
+func (tc *testClient) revertAd(adId, userId, revision int64) (adResponse, error) {
+	var response adResponse
+	err := tc.call(http.MethodPost, fmt.Sprintf("/api/v1/ads/%d/revisions/%d/revert", adId, revision), userId, nil, &response)
+	return response, err
 }
 
 func (tc *testClient) listAds() (adsResponse, error) {
-	req, err := http.NewRequest(http.MethodGet, tc.baseURL+"/api/v1/ads", nil)
-	if err != nil {
-		return adsResponse{}, fmt.Errorf("unable to create request: %w", err)
-	}
+	return tc.listAdsWithParams(url.Values{})
+}
 
+func (tc *testClient) listAdsWithParams(params url.Values) (adsResponse, error) {
 	var response adsResponse
-	err = tc.getResponse(req, &response)
-	if err != nil {
-		return adsResponse{}, err
-	}
+	err := tc.call(http.MethodGet, "/api/v1/ads?"+params.Encode(), nobody, nil, &response)
+	return response, err
+}
 
-	return response, nil
+func (tc *testClient) searchAds(query string) (adsResponse, error) {
+	var response adsResponse
+	err := tc.call(http.MethodGet, "/api/v1/ads/search?q="+url.QueryEscape(query), nobody, nil, &response)
+	return response, err
 }
 
+// createUser signs the user up with a verified email, a bare name stands for
+// an address at mail.com.
 func (tc *testClient) createUser(nick, email, pass string) (userResponse, error) {
-	body := map[string]any{
-		"nickname": nick,
-		"email":    email,
-		"password": pass,
-	}
-
-	data, err := json.Marshal(body)
-	if err != nil {
-		return userResponse{}, fmt.Errorf("unable to marshal: %w", err)
+	if !strings.Contains(email, "@") {
+		email += "@mail.com"
 	}
-
-	req, err := http.NewRequest(http.MethodPost, tc.baseURL+"/api/v1/user", bytes.NewReader(data))
-	if err != nil {
-		return userResponse{}, fmt.Errorf("unable to create request: %w", err)
+	if _, err := tc.createUnverifiedUser(nick, email, pass); err != nil {
+		return userResponse{}, err
 	}
+	return tc.verifyEmail(mailedToken(tc.mailer, email))
+}
 
-	req.Header.Add("Content-Type", "application/json")
-
+func (tc *testClient) createUnverifiedUser(nick, email, pass string) (userResponse, error) {
 	var response userResponse
-	err = tc.getResponse(req, &response)
+	err := tc.call(http.MethodPost, "/api/v1/user", nobody,
+		map[string]any{"nickname": nick, "email": email, "password": pass}, &response)
 	if err != nil {
 		return userResponse{}, err
 	}
+	tc.passwords[response.Data.Id] = pass
 	return response, nil
 }
 
+// mailedToken returns the token of the last mail sent to the address.
+func mailedToken(mailer *mail.Memory, email string) string {
+	sent := mailer.Sent(email)
+	if len(sent) == 0 {
+		return ""
+	}
+	lines := strings.Split(sent[len(sent)-1].Body, "\n")
+	return lines[len(lines)-1]
+}
+
+func (tc *testClient) verifyEmail(token string) (userResponse, error) {
+	var response userResponse
+	err := tc.call(http.MethodPost, "/api/v1/user/verify", nobody, map[string]any{"token": token}, &response)
+	return response, err
+}
+
+func (tc *testClient) sendVerification(userID int64) error {
+	return tc.call(http.MethodPost, "/api/v1/user/verification", userID, nil, &verificationResponse{})
+}
+
 func (tc *testClient) getUser(id int64) (userResponse, error) {
 	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf(tc.baseURL+"/api/v1/user/%d/get", id), nil)
 	if err != nil {
@@ -321,6 +650,7 @@ func (tc *testClient) changeNickname(userID int64, nickname string) (userRespons
 	if err != nil {
 		return userResponse{}, fmt.Errorf("unable to create request: %w", err)
 	}
+	tc.authorize(req, userID)
 
 	req.Header.Add("Content-Type", "application/json")
 
@@ -334,8 +664,9 @@ func (tc *testClient) changeNickname(userID int64, nickname string) (userRespons
 
 func (tc *testClient) updatePassword(userID int64, password string) (userResponse, error) {
 	body := map[string]any{
-		"id":       userID,
-		"password": password,
+		"id":               userID,
+		"current_password": tc.passwords[userID],
+		"password":         password,
 	}
 
 	data, err := json.Marshal(body)
@@ -355,6 +686,26 @@ func (tc *testClient) updatePassword(userID int64, password string) (userRespons
 	if err != nil {
 		return userResponse{}, err
 	}
+	// the change ends the sessions of the user
+	tc.passwords[userID] = password
+	delete(tc.tokens, userID)
+	return response, nil
+}
+
+func (tc *testClient) forgotPassword(email string) error {
+	return tc.call(http.MethodPost, "/api/v1/user/password/forgot", nobody, map[string]any{"email": email},
+		&verificationResponse{})
+}
+
+func (tc *testClient) resetPassword(token, password string) (userResponse, error) {
+	var response userResponse
+	err := tc.call(http.MethodPost, "/api/v1/user/password/reset", nobody,
+		map[string]any{"token": token, "password": password}, &response)
+	if err != nil {
+		return userResponse{}, err
+	}
+	tc.passwords[response.Data.Id] = password
+	delete(tc.tokens, response.Data.Id)
 	return response, nil
 }
 
@@ -363,6 +714,7 @@ func (tc *testClient) deleteUser(userID int64) (deleteUserResponse, error) {
 	if err != nil {
 		return deleteUserResponse{}, fmt.Errorf("unable to create request: %w", err)
 	}
+	tc.authorize(req, userID)
 
 	req.Header.Add("Content-Type", "application/json")
 
@@ -373,3 +725,180 @@ func (tc *testClient) deleteUser(userID int64) (deleteUserResponse, error) {
 	}
 	return response, nil
 }
+
+func (tc *testClient) changeRole(actorID, userID int64, role string) (userResponse, error) {
+	var response userResponse
+	err := tc.call(http.MethodPut, fmt.Sprintf("/api/v1/user/%d/role", userID), actorID,
+		map[string]any{"role": role}, &response)
+	return response, err
+}
+
+// makeAdmin bypasses the api, which only lets admins hand out roles.
+func (tc *testClient) makeAdmin(userID int64) error {
+	_, err := tc.users.UpdateRole(context.Background(), userID, user.RoleAdmin)
+	return err
+}
+
+func (tc *testClient) login(userID int64, password string) (tokensResponse, error) {
+	var response tokensResponse
+	err := tc.call(http.MethodPost, "/api/v1/auth/login", nobody,
+		map[string]any{"user_id": userID, "password": password}, &response)
+	return response, err
+}
+
+func (tc *testClient) refresh(refreshToken string) (tokensResponse, error) {
+	var response tokensResponse
+	err := tc.call(http.MethodPost, "/api/v1/auth/refresh", nobody,
+		map[string]any{"refresh_token": refreshToken}, &response)
+	return response, err
+}
+
+func (tc *testClient) createAPIKey(userID int64, name string, scopes ...string) (apiKeyResponse, error) {
+	var response apiKeyResponse
+	err := tc.call(http.MethodPost, "/api/v1/user/keys", userID,
+		map[string]any{"name": name, "scopes": scopes}, &response)
+	return response, err
+}
+
+func (tc *testClient) listAPIKeys(userID int64) (apiKeysResponse, error) {
+	var response apiKeysResponse
+	err := tc.call(http.MethodGet, "/api/v1/user/keys", userID, nil, &response)
+	return response, err
+}
+
+func (tc *testClient) revokeAPIKey(userID int64, keyID string) error {
+	return tc.call(http.MethodDelete, "/api/v1/user/keys/"+keyID, userID, nil, &verificationResponse{})
+}
+
+func (tc *testClient) exportUser(userID int64, id int64) (exportResponse, error) {
+	var response exportResponse
+	err := tc.call(http.MethodGet, fmt.Sprintf("/api/v1/user/%d/export", id), userID, nil, &response)
+	return response, err
+}
+
+func (tc *testClient) eraseUser(userID int64, id int64) error {
+	return tc.call(http.MethodDelete, fmt.Sprintf("/api/v1/user/%d/erase", id), userID, nil, &verificationResponse{})
+}
+
+// getProfile shows the profile to the viewer, a viewer the client doesn't
+// know is anonymous.
+func (tc *testClient) getProfile(viewerID int64, id int64) (profileResponse, error) {
+	var response profileResponse
+	err := tc.call(http.MethodGet, fmt.Sprintf("/api/v1/user/%d/profile", id), viewerID, nil, &response)
+	return response, err
+}
+
+func (tc *testClient) updateProfile(userID int64, id int64, profile map[string]any) (profileResponse, error) {
+	var response profileResponse
+	err := tc.call(http.MethodPut, fmt.Sprintf("/api/v1/user/%d/profile", id), userID, profile, &response)
+	return response, err
+}
+
+func (tc *testClient) searchUsers(params url.Values) (usersFoundResponse, error) {
+	var response usersFoundResponse
+	err := tc.call(http.MethodGet, "/api/v1/user/search?"+params.Encode(), nobody, nil, &response)
+	return response, err
+}
+
+// createAdWithKey makes the request with the api key instead of a token.
+func (tc *testClient) createAdWithKey(key, title, text string) (adResponse, error) {
+	data, err := json.Marshal(map[string]any{"title": title, "text": text, "category_id": testCategory})
+	if err != nil {
+		return adResponse{}, fmt.Errorf("unable to marshal: %w", err)
+	}
+	req, err := http.NewRequest(http.MethodPost, tc.baseURL+"/api/v1/ads", bytes.NewReader(data))
+	if err != nil {
+		return adResponse{}, fmt.Errorf("unable to create request: %w", err)
+	}
+	req.Header.Add("X-API-Key", key)
+
+	var response adResponse
+	err = tc.getResponse(req, &response)
+	if err != nil {
+		return adResponse{}, err
+	}
+	return response, nil
+}
+
+func (tc *testClient) createCategory(userID int64, name string, parentID int64) (categoryResponse, error) {
+	var response categoryResponse
+	err := tc.call(http.MethodPost, "/api/v1/categories", userID,
+		map[string]any{"name": name, "parent_id": parentID}, &response)
+	return response, err
+}
+
+func (tc *testClient) listCategories() (categoriesResponse, error) {
+	var response categoriesResponse
+	err := tc.call(http.MethodGet, "/api/v1/categories", nobody, nil, &response)
+	return response, err
+}
+
+func (tc *testClient) deleteCategory(userID int64, id int64) error {
+	return tc.call(http.MethodDelete, fmt.Sprintf("/api/v1/categories/%d", id), userID, nil, &deleteAdResponse{})
+}
+
+func testPNG(w, h int) []byte {
+	var buf bytes.Buffer
+	_ = png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, w, h)))
+	return buf.Bytes()
+}
+
+// uploadImage sends the image as the multipart form a browser would, an
+// empty contentType leaves the type of the part out.
+func (tc *testClient) uploadImage(userID int64, adID int64, contentType string, data []byte) (imageResponse, error) {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	header := textproto.MIMEHeader{"Content-Disposition": {`form-data; name="image"; filename="photo"`}}
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+	part, err := form.CreatePart(header)
+	if err != nil {
+		return imageResponse{}, fmt.Errorf("unable to create part: %w", err)
+	}
+	_, _ = part.Write(data)
+	if err = form.Close(); err != nil {
+		return imageResponse{}, fmt.Errorf("unable to close form: %w", err)
+	}
+	req, err := http.NewRequest(http.MethodPost, fmt.Sprintf(tc.baseURL+"/api/v1/ads/%d/images", adID), &body)
+	if err != nil {
+		return imageResponse{}, fmt.Errorf("unable to create request: %w", err)
+	}
+	req.Header.Add("Content-Type", form.FormDataContentType())
+	tc.authorize(req, userID)
+
+	var response imageResponse
+	err = tc.getResponse(req, &response)
+	if err != nil {
+		return imageResponse{}, err
+	}
+	return response, nil
+}
+
+func (tc *testClient) getImages(adID int64) (imagesResponse, error) {
+	var response imagesResponse
+	err := tc.call(http.MethodGet, fmt.Sprintf("/api/v1/ads/%d/images", adID), nobody, nil, &response)
+	return response, err
+}
+
+// getImageFile fetches the file under the url of an image response as it
+// is, the caller closes the body.
+func (tc *testClient) getImageFile(url string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, tc.baseURL+url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create request: %w", err)
+	}
+	return tc.client.Do(req)
+}
+
+func (tc *testClient) reorderImages(userID int64, adID int64, order []int64) (imagesResponse, error) {
+	var response imagesResponse
+	err := tc.call(http.MethodPut, fmt.Sprintf("/api/v1/ads/%d/images", adID), userID,
+		map[string]any{"order": order}, &response)
+	return response, err
+}
+
+func (tc *testClient) deleteImage(userID int64, adID int64, imageID int64) error {
+	return tc.call(http.MethodDelete, fmt.Sprintf("/api/v1/ads/%d/images/%d", adID, imageID), userID, nil,
+		&deleteAdResponse{})
+}
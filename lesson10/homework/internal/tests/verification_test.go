@@ -0,0 +1,49 @@
+package tests
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestCreateUser_Email(t *testing.T) {
+	client := getTestClient()
+
+	_, err := client.createUnverifiedUser("tester", "tester", "tester")
+	assert.ErrorIs(t, err, ErrBadRequest)
+	_, err = client.createUnverifiedUser("tester", "Tester <tester@mail.com>", "tester")
+	assert.ErrorIs(t, err, ErrBadRequest)
+
+	_, err = client.createUnverifiedUser("tester", "Tester@Mail.com", "tester")
+	assert.NoError(t, err)
+	_, err = client.createUnverifiedUser("tester1", "tester@mail.com", "tester1")
+	assert.ErrorIs(t, err, ErrConflict)
+}
+
+func TestVerifyEmail(t *testing.T) {
+	client := getTestClient()
+
+	usr, err := client.createUnverifiedUser("tester", "tester@mail.com", "tester")
+	assert.NoError(t, err)
+	assert.False(t, usr.Data.Verified)
+	ad, err := client.createAd(0, "hello", "world")
+	assert.NoError(t, err)
+
+	// drafts are fine, publishing needs a verified email
+	_, err = client.changeAdStatus(0, ad.Data.ID, true)
+	assert.ErrorIs(t, err, ErrForbidden)
+
+	assert.NoError(t, client.sendVerification(0))
+	_, err = client.verifyEmail("garbage")
+	assert.ErrorIs(t, err, ErrBadRequest)
+	token := mailedToken(client.mailer, "tester@mail.com")
+	usr, err = client.verifyEmail(token)
+	assert.NoError(t, err)
+	assert.True(t, usr.Data.Verified)
+	_, err = client.verifyEmail(token)
+	assert.ErrorIs(t, err, ErrBadRequest)
+	assert.ErrorIs(t, client.sendVerification(0), ErrConflict)
+
+	published, err := client.changeAdStatus(0, ad.Data.ID, true)
+	assert.NoError(t, err)
+	assert.True(t, published.Data.Published)
+}
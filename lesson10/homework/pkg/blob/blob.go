@@ -0,0 +1,125 @@
+// Package blob stores the files the service keeps outside of its database,
+// such as the images of the ads.
+package blob
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrNotFound   = errors.New("no such blob")
+	ErrInvalidKey = errors.New("blob keys are slash separated names of letters, digits, dots, dashes and underscores")
+)
+
+// Info describes a stored blob.
+type Info struct {
+	Key     string
+	Size    int64
+	ModTime time.Time
+}
+
+// Store keeps blobs by key. Putting a blob under a taken key replaces it.
+type Store interface {
+	Put(ctx context.Context, key string, data []byte) error
+	// Get fails with ErrNotFound if there is no such blob.
+	Get(ctx context.Context, key string) ([]byte, Info, error)
+	// Delete does nothing if there is no such blob.
+	Delete(ctx context.Context, key string) error
+	// List returns the keys starting with the prefix, sorted.
+	List(ctx context.Context, prefix string) ([]string, error)
+}
+
+// ValidateKey checks that a key can't step out of the store when used as a
+// file path.
+func ValidateKey(key string) error {
+	if key == "" {
+		return ErrInvalidKey
+	}
+	for _, name := range strings.Split(key, "/") {
+		if name == "" || name == "." || name == ".." {
+			return ErrInvalidKey
+		}
+		for _, r := range name {
+			switch {
+			case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			case r == '.', r == '-', r == '_':
+			default:
+				return ErrInvalidKey
+			}
+		}
+	}
+	return nil
+}
+
+type memoryBlob struct {
+	data    []byte
+	modTime time.Time
+}
+
+// Memory keeps the blobs in memory, it is meant for tests.
+type Memory struct {
+	mu    sync.RWMutex
+	blobs map[string]memoryBlob
+}
+
+func NewMemory() *Memory {
+	return &Memory{blobs: make(map[string]memoryBlob)}
+}
+
+func (m *Memory) Put(ctx context.Context, key string, data []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := ValidateKey(key); err != nil {
+		return err
+	}
+	stored := append([]byte(nil), data...)
+	m.mu.Lock()
+	m.blobs[key] = memoryBlob{data: stored, modTime: time.Now().UTC()}
+	m.mu.Unlock()
+	return nil
+}
+
+func (m *Memory) Get(ctx context.Context, key string) ([]byte, Info, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, Info{}, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	b, ok := m.blobs[key]
+	if !ok {
+		return nil, Info{}, ErrNotFound
+	}
+	return append([]byte(nil), b.data...), Info{Key: key, Size: int64(len(b.data)), ModTime: b.modTime}, nil
+}
+
+func (m *Memory) Delete(ctx context.Context, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	delete(m.blobs, key)
+	m.mu.Unlock()
+	return nil
+}
+
+func (m *Memory) List(ctx context.Context, prefix string) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	resp := make([]string, 0)
+	for key := range m.blobs {
+		if strings.HasPrefix(key, prefix) {
+			resp = append(resp, key)
+		}
+	}
+	sort.Strings(resp)
+	return resp, nil
+}
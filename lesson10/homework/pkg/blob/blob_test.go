@@ -0,0 +1,78 @@
+package blob
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStores(t *testing.T) {
+	local, err := NewLocal(filepath.Join(t.TempDir(), "blobs"))
+	assert.NoError(t, err)
+	for name, store := range map[string]Store{"memory": NewMemory(), "local": local} {
+		store := store
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			assert.NoError(t, store.Put(ctx, "ads/1/2/original", []byte("first")))
+			assert.NoError(t, store.Put(ctx, "ads/1/2/small", []byte("small")))
+			assert.NoError(t, store.Put(ctx, "ads/10/3/original", []byte("other")))
+			assert.NoError(t, store.Put(ctx, "ads/1/2/original", []byte("replaced")))
+
+			data, info, err := store.Get(ctx, "ads/1/2/original")
+			assert.NoError(t, err)
+			assert.Equal(t, "replaced", string(data))
+			assert.Equal(t, int64(8), info.Size)
+			assert.False(t, info.ModTime.IsZero())
+			_, _, err = store.Get(ctx, "ads/1/3/original")
+			assert.ErrorIs(t, err, ErrNotFound)
+
+			keys, err := store.List(ctx, "ads/1/")
+			assert.NoError(t, err)
+			assert.Equal(t, []string{"ads/1/2/original", "ads/1/2/small"}, keys)
+
+			assert.NoError(t, store.Delete(ctx, "ads/1/2/small"))
+			assert.NoError(t, store.Delete(ctx, "ads/1/2/small"))
+			keys, err = store.List(ctx, "")
+			assert.NoError(t, err)
+			assert.Equal(t, []string{"ads/1/2/original", "ads/10/3/original"}, keys)
+
+			canceled, cancel := context.WithCancel(ctx)
+			cancel()
+			assert.ErrorIs(t, store.Put(canceled, "ads/1/2/large", nil), context.Canceled)
+			_, _, err = store.Get(canceled, "ads/1/2/original")
+			assert.ErrorIs(t, err, context.Canceled)
+		})
+	}
+}
+
+func TestInvalidKeys(t *testing.T) {
+	dir := t.TempDir()
+	local, err := NewLocal(filepath.Join(dir, "blobs"))
+	assert.NoError(t, err)
+	ctx := context.Background()
+	for _, key := range []string{"", "../secret", "ads/../../secret", "/etc/passwd", "ads//1", `ads\1`, "ads/1.tmp"} {
+		assert.ErrorIs(t, local.Put(ctx, key, []byte("x")), ErrInvalidKey, key)
+		_, _, err = local.Get(ctx, key)
+		assert.ErrorIs(t, err, ErrInvalidKey, key)
+	}
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+func TestLocalDeleteRemovesEmptyDirs(t *testing.T) {
+	dir := t.TempDir()
+	local, err := NewLocal(dir)
+	assert.NoError(t, err)
+	ctx := context.Background()
+	assert.NoError(t, local.Put(ctx, "ads/1/2/original", []byte("x")))
+	assert.NoError(t, local.Put(ctx, "ads/3/4/original", []byte("x")))
+	assert.NoError(t, local.Delete(ctx, "ads/1/2/original"))
+
+	_, err = os.Stat(filepath.Join(dir, "ads", "1"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	_, err = os.Stat(filepath.Join(dir, "ads", "3", "4"))
+	assert.NoError(t, err)
+}
@@ -0,0 +1,40 @@
+package idgen
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrUnknownGenerator = errors.New("unknown id generator")
+)
+
+const (
+	KindSequence  = "sequence"
+	KindSnowflake = "snowflake"
+	KindUUIDv7    = "uuidv7"
+)
+
+// IDGenerator hands out ids that are never handed out again, it is safe for
+// concurrent use.
+type IDGenerator interface {
+	NextID() int64
+	// Resume makes sure no id up to last is handed out anymore, stores that
+	// outlive the process call it on start.
+	Resume(last int64)
+}
+
+// New returns a generator of the given kind, node only matters for
+// Snowflake and UUIDv7.
+func New(kind string, node int64) (IDGenerator, error) {
+	switch kind {
+	case KindSequence:
+		return NewSequence(), nil
+	case KindSnowflake:
+		return NewSnowflake(node)
+	case KindUUIDv7:
+		return NewUUIDv7(node)
+	default:
+		return nil, fmt.Errorf("%w %q", ErrUnknownGenerator, kind)
+	}
+}
@@ -0,0 +1,141 @@
+package idgen
+
+import (
+	"github.com/stretchr/testify/assert"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSequence(t *testing.T) {
+	s := NewSequence()
+	assert.Equal(t, int64(0), s.NextID())
+	assert.Equal(t, int64(1), s.NextID())
+	s.Resume(9)
+	assert.Equal(t, int64(10), s.NextID())
+	s.Resume(3)
+	assert.Equal(t, int64(11), s.NextID())
+}
+
+func TestSnowflake(t *testing.T) {
+	_, err := NewSnowflake(MaxNode + 1)
+	assert.ErrorIs(t, err, ErrInvalidNode)
+
+	now := Epoch.Add(time.Second)
+	s, err := NewSnowflake(5)
+	assert.NoError(t, err)
+	s.now = func() time.Time { return now }
+
+	first := s.NextID()
+	assert.Equal(t, int64(1000)<<22|5<<12, first)
+	assert.Equal(t, first+1, s.NextID())
+
+	// the clock going back does not make the ids go back
+	now = now.Add(-time.Minute)
+	assert.Equal(t, first+2, s.NextID())
+
+	// a full millisecond borrows the next one
+	s.sequence = maxSequence
+	assert.Equal(t, int64(1001)<<22|5<<12, s.NextID())
+
+	s.Resume(int64(2000) << 22)
+	assert.Equal(t, int64(2001)<<22|5<<12, s.NextID())
+}
+
+func TestUUIDv7(t *testing.T) {
+	_, err := NewUUIDv7(MaxNode + 1)
+	assert.ErrorIs(t, err, ErrInvalidNode)
+
+	now := time.UnixMilli(1682935200000)
+	g, err := NewUUIDv7(0x2a5)
+	assert.NoError(t, err)
+	g.now = func() time.Time { return now }
+
+	u := g.NewUUID()
+	assert.Equal(t, byte(0x70), u[6]&0xf0)
+	assert.Equal(t, byte(0x80), u[8]&0xc0)
+	assert.Equal(t, uint16(0x2a5), uint16(u[8]&0x3f)<<4|uint16(u[9]>>4))
+	assert.Regexp(t, regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`), u.String())
+
+	id := g.NextID()
+	assert.Equal(t, now.Sub(Epoch).Milliseconds(), id>>22)
+	assert.Equal(t, int64(0x2a5), id>>12&MaxNode)
+	assert.Equal(t, int64(1), id&0xfff)
+	now = now.Add(-time.Second)
+	assert.Greater(t, g.NextID(), id)
+
+	g.Resume(id + 100)
+	assert.Equal(t, id+101, g.NextID())
+}
+
+func TestUUIDv7Nodes(t *testing.T) {
+	// two instances on the same millisecond, long enough to run out of the
+	// counter
+	now := time.UnixMilli(1682935200000)
+	seen := make(map[int64]struct{})
+	uuids := make(map[UUID]struct{})
+	for _, node := range []int64{1, 2} {
+		g, err := NewUUIDv7(node)
+		assert.NoError(t, err)
+		g.now = func() time.Time { return now }
+		for i := 0; i < 5000; i++ {
+			seen[g.NextID()] = struct{}{}
+			uuids[g.NewUUID()] = struct{}{}
+		}
+	}
+	assert.Len(t, seen, 10000)
+	assert.Len(t, uuids, 10000)
+
+	// a restart on the same millisecond picks up after the last id
+	g, err := NewUUIDv7(1)
+	assert.NoError(t, err)
+	g.now = func() time.Time { return now }
+	last := int64(-1)
+	for id := range seen {
+		if id>>12&MaxNode == 1 && id > last {
+			last = id
+		}
+	}
+	g.Resume(last)
+	_, taken := seen[g.NextID()]
+	assert.False(t, taken)
+}
+
+func TestGeneratorsAreUnique(t *testing.T) {
+	for _, kind := range []string{KindSequence, KindSnowflake, KindUUIDv7} {
+		kind := kind
+		t.Run(kind, func(t *testing.T) {
+			g, err := New(kind, 1)
+			assert.NoError(t, err)
+
+			const workers, perWorker = 8, 2000
+			var (
+				wg   sync.WaitGroup
+				mu   sync.Mutex
+				seen = make(map[int64]struct{}, workers*perWorker)
+			)
+			for i := 0; i < workers; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					ids := make([]int64, perWorker)
+					for j := range ids {
+						ids[j] = g.NextID()
+					}
+					mu.Lock()
+					defer mu.Unlock()
+					for _, id := range ids {
+						assert.GreaterOrEqual(t, id, int64(0))
+						seen[id] = struct{}{}
+					}
+				}()
+			}
+			wg.Wait()
+			assert.Len(t, seen, workers*perWorker)
+		})
+	}
+
+	_, err := New("random", 0)
+	assert.ErrorIs(t, err, ErrUnknownGenerator)
+}
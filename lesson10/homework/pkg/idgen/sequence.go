@@ -0,0 +1,31 @@
+package idgen
+
+import (
+	"sync"
+)
+
+// Sequence hands out 0, 1, 2 and so on.
+type Sequence struct {
+	mu   *sync.Mutex
+	next int64
+}
+
+func NewSequence() *Sequence {
+	return &Sequence{mu: &sync.Mutex{}}
+}
+
+func (s *Sequence) NextID() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := s.next
+	s.next++
+	return id
+}
+
+func (s *Sequence) Resume(last int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if last >= s.next {
+		s.next = last + 1
+	}
+}
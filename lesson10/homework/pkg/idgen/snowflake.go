@@ -0,0 +1,64 @@
+package idgen
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	ErrInvalidNode = errors.New("node must be between 0 and 1023")
+)
+
+const (
+	nodeBits     = 10
+	sequenceBits = 12
+
+	MaxNode     = 1<<nodeBits - 1
+	maxSequence = 1<<sequenceBits - 1
+)
+
+// Epoch is the zero of Snowflake timestamps, 41 bits of milliseconds last
+// for about 69 years from it.
+var Epoch = time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Snowflake builds ids from the milliseconds since Epoch, the node and a
+// sequence, they keep growing when the clock goes back.
+type Snowflake struct {
+	mu       *sync.Mutex
+	node     int64
+	lastTime int64
+	sequence int64
+	now      func() time.Time
+}
+
+func NewSnowflake(node int64) (*Snowflake, error) {
+	if node < 0 || node > MaxNode {
+		return nil, ErrInvalidNode
+	}
+	return &Snowflake{mu: &sync.Mutex{}, node: node, lastTime: -1, now: time.Now}, nil
+}
+
+func (s *Snowflake) NextID() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ms := s.now().Sub(Epoch).Milliseconds()
+	if ms > s.lastTime {
+		s.lastTime, s.sequence = ms, 0
+	} else if s.sequence < maxSequence {
+		s.sequence++
+	} else {
+		s.lastTime, s.sequence = s.lastTime+1, 0
+	}
+	return s.lastTime<<(nodeBits+sequenceBits) | s.node<<sequenceBits | s.sequence
+}
+
+func (s *Snowflake) Resume(last int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ms := last >> (nodeBits + sequenceBits)
+	if ms >= s.lastTime {
+		// the next id moves on to the millisecond after last
+		s.lastTime, s.sequence = ms, maxSequence
+	}
+}
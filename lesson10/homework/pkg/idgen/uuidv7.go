@@ -0,0 +1,85 @@
+package idgen
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+const counterBits = 12
+
+// UUID is an RFC 9562 UUID.
+type UUID [16]byte
+
+func (u UUID) String() string {
+	var buf [36]byte
+	hex.Encode(buf[0:8], u[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], u[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], u[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], u[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], u[10:])
+	return string(buf[:])
+}
+
+// UUIDv7 makes version 7 UUIDs with a 12 bit counter in rand_a (RFC 9562,
+// section 6.2), NextID packs them into int64 the way Snowflake lays it out.
+type UUIDv7 struct {
+	mu       *sync.Mutex
+	node     int64
+	lastTime int64
+	counter  int64
+	now      func() time.Time
+}
+
+func NewUUIDv7(node int64) (*UUIDv7, error) {
+	if node < 0 || node > MaxNode {
+		return nil, ErrInvalidNode
+	}
+	return &UUIDv7{mu: &sync.Mutex{}, node: node, lastTime: -1, now: time.Now}, nil
+}
+
+func (g *UUIDv7) NewUUID() UUID {
+	ms, counter := g.next()
+	var u UUID
+	binary.BigEndian.PutUint64(u[0:8], uint64(ms)<<16|0x7<<counterBits|uint64(counter))
+	_, _ = rand.Read(u[8:])
+	// the variant, then the 10 bits of the node
+	u[8] = 0x80 | byte(g.node>>4)
+	u[9] = byte(g.node&0xf)<<4 | u[9]&0x0f
+	return u
+}
+
+// next returns the unix milliseconds and the counter of the next id.
+func (g *UUIDv7) next() (int64, int64) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	ms := g.now().UnixMilli()
+	if ms > g.lastTime {
+		g.lastTime, g.counter = ms, 0
+	} else if g.counter < 1<<counterBits-1 {
+		g.counter++
+	} else {
+		g.lastTime, g.counter = g.lastTime+1, 0
+	}
+	return g.lastTime, g.counter
+}
+
+func (g *UUIDv7) NextID() int64 {
+	ms, counter := g.next()
+	return (ms-Epoch.UnixMilli())<<(nodeBits+counterBits) | g.node<<counterBits | counter
+}
+
+func (g *UUIDv7) Resume(last int64) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	ms, counter := last>>(nodeBits+counterBits)+Epoch.UnixMilli(), last&(1<<counterBits-1)
+	if ms > g.lastTime || ms == g.lastTime && counter > g.counter {
+		g.lastTime, g.counter = ms, counter
+	}
+}
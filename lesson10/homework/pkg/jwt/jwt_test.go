@@ -0,0 +1,65 @@
+package jwt
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testKey = []byte("0123456789abcdef0123456789abcdef")
+
+func TestSignAndParse(t *testing.T) {
+	s, err := NewSigner(testKey)
+	assert.NoError(t, err)
+	now := time.Unix(1682700000, 0)
+	claims := Claims{Subject: "1", ID: "id", Use: "access", IssuedAt: now.Unix(), ExpiresAt: now.Add(time.Minute).Unix()}
+
+	token, err := s.Sign(claims)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(strings.Split(token, ".")))
+
+	parsed, err := s.Parse(token, now)
+	assert.NoError(t, err)
+	assert.Equal(t, claims, *parsed)
+
+	_, err = s.Parse(token, now.Add(time.Minute))
+	assert.ErrorIs(t, err, ErrExpiredToken)
+}
+
+func TestParseInvalid(t *testing.T) {
+	s, _ := NewSigner(testKey)
+	other, _ := NewSigner([]byte("fedcba9876543210fedcba9876543210"))
+	now := time.Unix(1682700000, 0)
+	token, _ := s.Sign(Claims{Subject: "1", ExpiresAt: now.Add(time.Minute).Unix()})
+	parts := strings.Split(token, ".")
+
+	otherToken, _ := other.Sign(Claims{Subject: "1", ExpiresAt: now.Add(time.Minute).Unix()})
+	forged, _ := json.Marshal(Claims{Subject: "2", ExpiresAt: now.Add(time.Minute).Unix()})
+	none := encoding.EncodeToString([]byte(`{"alg":"none","typ":"JWT"}`))
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"empty", ""},
+		{"two parts", parts[0] + "." + parts[1]},
+		{"other key", otherToken},
+		{"changed claims", parts[0] + "." + encoding.EncodeToString(forged) + "." + parts[2]},
+		{"alg none", none + "." + parts[1] + "."},
+		{"alg none with signature", none + "." + parts[1] + "." + parts[2]},
+		{"broken signature", parts[0] + "." + parts[1] + ".!!!"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := s.Parse(tc.token, now)
+			assert.ErrorIs(t, err, ErrInvalidToken)
+		})
+	}
+}
+
+func TestShortKey(t *testing.T) {
+	_, err := NewSigner([]byte("short"))
+	assert.ErrorIs(t, err, ErrShortKey)
+}
@@ -0,0 +1,66 @@
+// Package mail delivers the messages the service sends to its users.
+package mail
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+)
+
+var (
+	ErrInvalidHeader = errors.New("mail header can't contain line breaks")
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+func (m Message) validate() error {
+	if strings.ContainsAny(m.To, "\r\n") || strings.ContainsAny(m.Subject, "\r\n") {
+		return ErrInvalidHeader
+	}
+	return nil
+}
+
+// Memory keeps the sent messages, it is meant for tests.
+type Memory struct {
+	mu   sync.Mutex
+	sent []Message
+}
+
+func NewMemory() *Memory {
+	return &Memory{}
+}
+
+func (m *Memory) Send(ctx context.Context, msg Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := msg.validate(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	m.sent = append(m.sent, msg)
+	m.mu.Unlock()
+	return nil
+}
+
+// Sent returns the messages sent to the address, the oldest first.
+func (m *Memory) Sent(to string) []Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var resp []Message
+	for _, msg := range m.sent {
+		if msg.To == to {
+			resp = append(resp, msg)
+		}
+	}
+	return resp
+}
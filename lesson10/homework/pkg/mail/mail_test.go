@@ -0,0 +1,62 @@
+package mail
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOutbox(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "outbox")
+	o, err := NewOutbox(dir)
+	assert.NoError(t, err)
+	o.now = func() time.Time { return time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC) }
+	ctx := context.Background()
+
+	assert.NoError(t, o.Send(ctx, Message{To: "a@mail.com", Subject: "first", Body: "hello"}))
+	assert.NoError(t, o.Send(ctx, Message{To: "b@mail.com", Subject: "second", Body: "world"}))
+
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	if !assert.Len(t, entries, 2) {
+		return
+	}
+	data, err := os.ReadFile(filepath.Join(dir, entries[0].Name()))
+	assert.NoError(t, err)
+	assert.Equal(t, "Date: Mon, 01 May 2023 12:00:00 +0000\r\nTo: a@mail.com\r\nSubject: first\r\n\r\nhello\r\n", string(data))
+	assert.True(t, strings.HasSuffix(entries[1].Name(), "-000002.eml"))
+}
+
+func TestHeaderInjection(t *testing.T) {
+	ctx := context.Background()
+	o, err := NewOutbox(t.TempDir())
+	assert.NoError(t, err)
+	m := NewMemory()
+	for _, msg := range []Message{
+		{To: "a@mail.com\r\nBcc: b@mail.com", Subject: "hi"},
+		{To: "a@mail.com", Subject: "hi\nBcc: b@mail.com"},
+	} {
+		assert.ErrorIs(t, o.Send(ctx, msg), ErrInvalidHeader)
+		assert.ErrorIs(t, m.Send(ctx, msg), ErrInvalidHeader)
+	}
+	assert.Empty(t, m.Sent("a@mail.com"))
+}
+
+func TestMemory(t *testing.T) {
+	m := NewMemory()
+	ctx := context.Background()
+	assert.NoError(t, m.Send(ctx, Message{To: "a@mail.com", Body: "1"}))
+	assert.NoError(t, m.Send(ctx, Message{To: "b@mail.com", Body: "2"}))
+	assert.NoError(t, m.Send(ctx, Message{To: "a@mail.com", Body: "3"}))
+
+	sent := m.Sent("a@mail.com")
+	assert.Equal(t, []Message{{To: "a@mail.com", Body: "1"}, {To: "a@mail.com", Body: "3"}}, sent)
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	assert.ErrorIs(t, m.Send(canceled, Message{To: "a@mail.com"}), context.Canceled)
+}
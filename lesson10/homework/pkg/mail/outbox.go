@@ -0,0 +1,47 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+)
+
+// Outbox writes every message to its own .eml file in a directory instead
+// of sending it, so the mail can be read when running the service locally.
+type Outbox struct {
+	dir string
+	seq atomic.Uint64
+	now func() time.Time
+}
+
+func NewOutbox(dir string) (*Outbox, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &Outbox{dir: dir, now: time.Now}, nil
+}
+
+func (o *Outbox) Send(ctx context.Context, msg Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := msg.validate(); err != nil {
+		return err
+	}
+	now := o.now().UTC()
+	// the names sort in the order the messages were sent
+	name := fmt.Sprintf("%s-%06d.eml", now.Format("20060102T150405.000000000"), o.seq.Add(1))
+	f, err := os.OpenFile(filepath.Join(o.dir, name), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(f, "Date: %s\r\nTo: %s\r\nSubject: %s\r\n\r\n%s\r\n",
+		now.Format(time.RFC1123Z), msg.To, msg.Subject, msg.Body)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,113 @@
+package passwords
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrUnknownHasher = errors.New("unknown password hasher")
+	ErrMalformedHash = errors.New("malformed password hash")
+	ErrUnknownHash   = errors.New("password hash of an unknown algorithm")
+)
+
+const (
+	KindBcrypt   = "bcrypt"
+	KindArgon2id = "argon2id"
+)
+
+// Hasher is a single hashing algorithm with fixed parameters. Its encoded
+// hashes name the algorithm and hold the parameters they were made with.
+type Hasher interface {
+	Hash(password string) (string, error)
+	// Identify reports whether encoded was made by this algorithm, with
+	// whatever parameters.
+	Identify(encoded string) bool
+	Verify(encoded, password string) (bool, error)
+	// Outdated reports whether encoded was made with other parameters than
+	// the hasher has now.
+	Outdated(encoded string) bool
+}
+
+// Params tunes the cost of the hashers.
+type Params struct {
+	BcryptCost int
+	Argon2id   Argon2Params
+}
+
+func DefaultParams() Params {
+	return Params{BcryptCost: DefaultBcryptCost, Argon2id: DefaultArgon2Params}
+}
+
+// Manager hashes with the preferred hasher and verifies every known one,
+// the other hashes are reported for rehashing.
+type Manager struct {
+	preferred Hasher
+	hashers   []Hasher
+	plaintext bool
+}
+
+func NewManager(preferred Hasher, others ...Hasher) *Manager {
+	return &Manager{preferred: preferred, hashers: append([]Hasher{preferred}, others...)}
+}
+
+// New returns a manager that hashes with the given kind of hasher and
+// verifies both bcrypt and argon2id hashes.
+func New(kind string, params Params) (*Manager, error) {
+	bcryptHasher, err := NewBcrypt(params.BcryptCost)
+	if err != nil {
+		return nil, err
+	}
+	argon2Hasher, err := NewArgon2id(params.Argon2id)
+	if err != nil {
+		return nil, err
+	}
+	switch kind {
+	case KindBcrypt:
+		return NewManager(bcryptHasher, argon2Hasher), nil
+	case KindArgon2id:
+		return NewManager(argon2Hasher, bcryptHasher), nil
+	default:
+		return nil, fmt.Errorf("%w %q", ErrUnknownHasher, kind)
+	}
+}
+
+// AllowPlaintext accepts the passwords stored before hashing until they are
+// rehashed, anything like "$id$..." is still taken for a hash.
+func (m *Manager) AllowPlaintext() *Manager {
+	m.plaintext = true
+	return m
+}
+
+func (m *Manager) Hash(password string) (string, error) {
+	return m.preferred.Hash(password)
+}
+
+func (m *Manager) Verify(encoded, password string) (ok, rehash bool, err error) {
+	for _, h := range m.hashers {
+		if !h.Identify(encoded) {
+			continue
+		}
+		ok, err = h.Verify(encoded, password)
+		if err != nil || !ok {
+			return false, false, err
+		}
+		return true, h != m.preferred || h.Outdated(encoded), nil
+	}
+	if !m.plaintext || encoded == "" || strings.HasPrefix(encoded, "$") {
+		return false, false, ErrUnknownHash
+	}
+	// a password stored in plain text
+	ok = subtle.ConstantTimeCompare([]byte(encoded), []byte(password)) == 1
+	return ok, ok, nil
+}
+
+// NewForTest returns a manager with the cheapest parameters, it must not be
+// used outside of tests.
+func NewForTest() *Manager {
+	bcryptHasher, _ := NewBcrypt(4)
+	argon2Hasher, _ := NewArgon2id(Argon2Params{Memory: 64, Time: 1, Threads: 1, SaltLen: 16, KeyLen: 32})
+	return NewManager(bcryptHasher, argon2Hasher)
+}
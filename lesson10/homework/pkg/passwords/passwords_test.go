@@ -0,0 +1,142 @@
+package passwords
+
+import (
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+	"strings"
+	"testing"
+)
+
+var testArgon2Params = Argon2Params{Memory: 64, Time: 1, Threads: 1, SaltLen: 16, KeyLen: 32}
+
+func TestHashers(t *testing.T) {
+	bcryptHasher, err := NewBcrypt(bcrypt.MinCost)
+	assert.NoError(t, err)
+	argon2Hasher, err := NewArgon2id(testArgon2Params)
+	assert.NoError(t, err)
+
+	for _, h := range []Hasher{bcryptHasher, argon2Hasher} {
+		encoded, err := h.Hash("password")
+		assert.NoError(t, err)
+		assert.NotContains(t, encoded, "password")
+		assert.True(t, h.Identify(encoded))
+		assert.False(t, h.Outdated(encoded))
+
+		ok, err := h.Verify(encoded, "password")
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		ok, err = h.Verify(encoded, "passwore")
+		assert.NoError(t, err)
+		assert.False(t, ok)
+
+		// hashes are salted
+		other, err := h.Hash("password")
+		assert.NoError(t, err)
+		assert.NotEqual(t, encoded, other)
+	}
+}
+
+func TestArgon2idEncoding(t *testing.T) {
+	h, err := NewArgon2id(testArgon2Params)
+	assert.NoError(t, err)
+	encoded, err := h.Hash("password")
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(encoded, "$argon2id$v=19$m=64,t=1,p=1$"))
+
+	// the parameters are read from the hash
+	stronger, err := NewArgon2id(Argon2Params{Memory: 128, Time: 2, Threads: 1, SaltLen: 16, KeyLen: 32})
+	assert.NoError(t, err)
+	assert.True(t, stronger.Outdated(encoded))
+	ok, err := stronger.Verify(encoded, "password")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	for _, malformed := range []string{
+		"$argon2id$",
+		"$argon2id$v=18$m=64,t=1,p=1$c2FsdHNhbHQ$a2V5",
+		"$argon2id$v=19$m=64,t=0,p=1$c2FsdHNhbHQ$a2V5",
+		"$argon2id$v=19$m=64,t=1,p=1$!!!$a2V5",
+	} {
+		_, err = h.Verify(malformed, "password")
+		assert.ErrorIs(t, err, ErrMalformedHash, malformed)
+		assert.True(t, h.Outdated(malformed))
+	}
+}
+
+func TestInvalidParams(t *testing.T) {
+	_, err := NewBcrypt(bcrypt.MaxCost + 1)
+	assert.ErrorIs(t, err, ErrInvalidCost)
+	_, err = NewArgon2id(Argon2Params{Memory: 64, Time: 0, Threads: 1, SaltLen: 16, KeyLen: 32})
+	assert.ErrorIs(t, err, ErrInvalidCost)
+	_, err = New("md5", DefaultParams())
+	assert.ErrorIs(t, err, ErrUnknownHasher)
+}
+
+func TestManagerVerify(t *testing.T) {
+	params := Params{BcryptCost: bcrypt.MinCost, Argon2id: testArgon2Params}
+	argon2Manager, err := New(KindArgon2id, params)
+	assert.NoError(t, err)
+	bcryptManager, err := New(KindBcrypt, params)
+	assert.NoError(t, err)
+	params.BcryptCost++
+	costlierManager, err := New(KindBcrypt, params)
+	assert.NoError(t, err)
+
+	bcryptHash, err := bcryptManager.Hash("password")
+	assert.NoError(t, err)
+	argon2Hash, err := argon2Manager.Hash("password")
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		manager  *Manager
+		encoded  string
+		password string
+		ok       bool
+		rehash   bool
+	}{
+		{"preferred", bcryptManager, bcryptHash, "password", true, false},
+		{"preferred wrong password", bcryptManager, bcryptHash, "wrong", false, false},
+		{"other algorithm", bcryptManager, argon2Hash, "password", true, true},
+		{"other algorithm wrong password", argon2Manager, bcryptHash, "wrong", false, false},
+		{"other cost", costlierManager, bcryptHash, "password", true, true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ok, rehash, err := tc.manager.Verify(tc.encoded, tc.password)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.ok, ok)
+			assert.Equal(t, tc.rehash, rehash)
+		})
+	}
+}
+
+func TestManagerPlaintext(t *testing.T) {
+	params := Params{BcryptCost: bcrypt.MinCost, Argon2id: testArgon2Params}
+	strict, err := New(KindArgon2id, params)
+	assert.NoError(t, err)
+	legacy, err := New(KindArgon2id, params)
+	assert.NoError(t, err)
+	legacy.AllowPlaintext()
+
+	// a hash, not the password, must never let the user in
+	for _, encoded := range []string{"password", "$5$salt$password", ""} {
+		ok, _, err := strict.Verify(encoded, encoded)
+		assert.ErrorIs(t, err, ErrUnknownHash, encoded)
+		assert.False(t, ok)
+	}
+
+	ok, rehash, err := legacy.Verify("password", "password")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.True(t, rehash)
+	ok, rehash, err = legacy.Verify("password", "wrong")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	assert.False(t, rehash)
+	for _, encoded := range []string{"$5$salt$password", ""} {
+		ok, _, err = legacy.Verify(encoded, encoded)
+		assert.ErrorIs(t, err, ErrUnknownHash, encoded)
+		assert.False(t, ok)
+	}
+}
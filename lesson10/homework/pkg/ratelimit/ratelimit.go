@@ -0,0 +1,170 @@
+// Package ratelimit limits how often clients may call the named parts of an
+// api, e.g. routes or rpcs, with a token bucket per client and name.
+package ratelimit
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrInvalidLimit = errors.New("invalid rate limit, want requests/period like 10/1m")
+)
+
+// sweepEvery is how often the buckets that refilled are dropped.
+const sweepEvery = time.Minute
+
+// Limit lets Requests requests through per Per, all of them at once after a
+// quiet period. The zero Limit lets everything through.
+type Limit struct {
+	Requests int
+	Per      time.Duration
+}
+
+// ParseLimit reads requests/period, e.g. 10/1m or 10/m, and off for no
+// limit.
+func ParseLimit(s string) (Limit, error) {
+	s = strings.TrimSpace(s)
+	if s == "off" {
+		return Limit{}, nil
+	}
+	requests, per, ok := strings.Cut(s, "/")
+	if !ok {
+		return Limit{}, ErrInvalidLimit
+	}
+	n, err := strconv.Atoi(requests)
+	if err != nil || n <= 0 {
+		return Limit{}, ErrInvalidLimit
+	}
+	if per != "" && (per[0] < '0' || per[0] > '9') {
+		per = "1" + per
+	}
+	d, err := time.ParseDuration(per)
+	if err != nil || d <= 0 {
+		return Limit{}, ErrInvalidLimit
+	}
+	return Limit{Requests: n, Per: d}, nil
+}
+
+// ParseLimits reads comma separated name=limit pairs, e.g.
+// "POST /api/v1/ads=10/1m, /ad.AdService/CreateAd=10/1m".
+func ParseLimits(s string) (map[string]Limit, error) {
+	limits := make(map[string]Limit)
+	for _, pair := range strings.Split(s, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		name, limit, ok := strings.Cut(pair, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, ErrInvalidLimit
+		}
+		l, err := ParseLimit(limit)
+		if err != nil {
+			return nil, err
+		}
+		limits[name] = l
+	}
+	return limits, nil
+}
+
+func (l Limit) String() string {
+	if l.Off() {
+		return "off"
+	}
+	return strconv.Itoa(l.Requests) + "/" + l.Per.String()
+}
+
+func (l Limit) Off() bool {
+	return l.Requests <= 0 || l.Per <= 0
+}
+
+// Rules are the limits of the names, Default is the one of the names
+// without a limit of their own.
+type Rules struct {
+	Default Limit
+	Limits  map[string]Limit
+}
+
+func (r Rules) For(name string) Limit {
+	if l, ok := r.Limits[name]; ok {
+		return l
+	}
+	return r.Default
+}
+
+// bucket is a token bucket kept as the time it is full again, every request
+// moves that on by one token and it is empty once more than Per away.
+type bucket struct {
+	full time.Time
+}
+
+// Limiter keeps the buckets of the clients, it is safe for concurrent use.
+type Limiter struct {
+	rules Rules
+	now   func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+func New(rules Rules) *Limiter {
+	return &Limiter{rules: rules, now: time.Now, buckets: make(map[string]*bucket)}
+}
+
+// Allow takes a token from the bucket of the client for the name. When the
+// bucket is empty it returns false and how long until the next token.
+func (l *Limiter) Allow(name, client string) (bool, time.Duration) {
+	limit := l.rules.For(name)
+	if limit.Off() {
+		return true, 0
+	}
+	now := l.now()
+	key := name + "\x00" + client
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if now.Sub(l.lastSweep) >= sweepEvery {
+		l.sweep(now)
+	}
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{full: now}
+		l.buckets[key] = b
+	}
+	full := b.full
+	if full.Before(now) {
+		full = now
+	}
+	full = full.Add(limit.Per / time.Duration(limit.Requests))
+	if wait := full.Add(-limit.Per).Sub(now); wait > 0 {
+		return false, wait
+	}
+	b.full = full
+	return true, 0
+}
+
+// sweep drops the buckets that are full again, a new bucket is the same.
+func (l *Limiter) sweep(now time.Time) {
+	for key, b := range l.buckets {
+		if !now.Before(b.full) {
+			delete(l.buckets, key)
+		}
+	}
+	l.lastSweep = now
+}
+
+// RetryAfter rounds the wait up to whole seconds, the unit of the
+// Retry-After header.
+func RetryAfter(wait time.Duration) int {
+	seconds := int(math.Ceil(wait.Seconds()))
+	if seconds < 1 {
+		return 1
+	}
+	return seconds
+}
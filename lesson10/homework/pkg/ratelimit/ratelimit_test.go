@@ -0,0 +1,106 @@
+package ratelimit
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Limit
+		err  error
+	}{
+		{"10/1m", Limit{Requests: 10, Per: time.Minute}, nil},
+		{" 5/h ", Limit{Requests: 5, Per: time.Hour}, nil},
+		{"off", Limit{}, nil},
+		{"10", Limit{}, ErrInvalidLimit},
+		{"0/1m", Limit{}, ErrInvalidLimit},
+		{"10/forever", Limit{}, ErrInvalidLimit},
+		{"10/-1s", Limit{}, ErrInvalidLimit},
+	}
+	for _, tc := range tests {
+		got, err := ParseLimit(tc.in)
+		assert.ErrorIs(t, err, tc.err, tc.in)
+		assert.Equal(t, tc.want, got, tc.in)
+	}
+
+	limits, err := ParseLimits("POST /api/v1/ads=10/1m, /ad.AdService/CreateAd=off,")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]Limit{
+		"POST /api/v1/ads":       {Requests: 10, Per: time.Minute},
+		"/ad.AdService/CreateAd": {},
+	}, limits)
+	_, err = ParseLimits("=10/1m")
+	assert.ErrorIs(t, err, ErrInvalidLimit)
+	_, err = ParseLimits("create=10")
+	assert.ErrorIs(t, err, ErrInvalidLimit)
+}
+
+func TestLimiter(t *testing.T) {
+	now := time.Unix(1682700000, 0)
+	l := New(Rules{
+		Default: Limit{Requests: 2, Per: time.Minute},
+		Limits:  map[string]Limit{"create": {Requests: 1, Per: time.Hour}, "get": {}},
+	})
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		ok, _ := l.Allow("list", "ip:1")
+		assert.True(t, ok)
+	}
+	ok, wait := l.Allow("list", "ip:1")
+	assert.False(t, ok)
+	assert.Equal(t, 30*time.Second, wait)
+	// the clients and the names have buckets of their own
+	ok, _ = l.Allow("list", "ip:2")
+	assert.True(t, ok)
+	ok, _ = l.Allow("create", "ip:1")
+	assert.True(t, ok)
+	ok, wait = l.Allow("create", "ip:1")
+	assert.False(t, ok)
+	assert.Equal(t, time.Hour, wait)
+	for i := 0; i < 10; i++ {
+		ok, _ = l.Allow("get", "ip:1")
+		assert.True(t, ok)
+	}
+
+	// a token comes back every 30 seconds, the bucket holds at most two
+	now = now.Add(30 * time.Second)
+	ok, _ = l.Allow("list", "ip:1")
+	assert.True(t, ok)
+	ok, wait = l.Allow("list", "ip:1")
+	assert.False(t, ok)
+	assert.Equal(t, 30*time.Second, wait)
+	now = now.Add(time.Hour)
+	for i := 0; i < 2; i++ {
+		ok, _ = l.Allow("list", "ip:1")
+		assert.True(t, ok)
+	}
+	ok, _ = l.Allow("list", "ip:1")
+	assert.False(t, ok)
+}
+
+func TestLimiterSweep(t *testing.T) {
+	now := time.Unix(1682700000, 0)
+	l := New(Rules{Default: Limit{Requests: 1, Per: time.Minute}})
+	l.now = func() time.Time { return now }
+
+	l.Allow("list", "ip:1")
+	now = now.Add(sweepEvery / 2)
+	l.Allow("list", "ip:2")
+	now = now.Add(sweepEvery / 2)
+	l.Allow("list", "ip:3")
+	// the first bucket refilled and is dropped, the second one is still used
+	assert.Len(t, l.buckets, 2)
+	ok, _ := l.Allow("list", "ip:2")
+	assert.False(t, ok)
+}
+
+func TestRetryAfter(t *testing.T) {
+	assert.Equal(t, 1, RetryAfter(0))
+	assert.Equal(t, 1, RetryAfter(time.Millisecond))
+	assert.Equal(t, 2, RetryAfter(1500*time.Millisecond))
+	assert.Equal(t, 60, RetryAfter(time.Minute))
+}
@@ -0,0 +1,80 @@
+// Package thumbnail makes the scaled down copies of the uploaded images.
+package thumbnail
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+)
+
+// Quality of the jpeg encoding of the thumbnails.
+const Quality = 85
+
+// Fit returns the size of a w×h image scaled down to fit in a side×side
+// square, keeping its aspect ratio. Smaller images keep their size.
+func Fit(w, h, side int) (int, int) {
+	if w <= side && h <= side {
+		return w, h
+	}
+	if w >= h {
+		return side, atLeastOne(h * side / w)
+	}
+	return atLeastOne(w * side / h), side
+}
+
+func atLeastOne(n int) int {
+	if n < 1 {
+		return 1
+	}
+	return n
+}
+
+// Resize scales the image to w×h averaging the pixels, the transparent parts
+// are put on white for the jpegs.
+func Resize(src image.Image, w, h int) *image.RGBA {
+	b := src.Bounds()
+	sw, sh := b.Dx(), b.Dy()
+	type sum struct{ r, g, b, a, n uint64 }
+	sums := make([]sum, w*h)
+	for y := 0; y < sh; y++ {
+		row := sums[y*h/sh*w:]
+		for x := 0; x < sw; x++ {
+			r, g, bl, a := src.At(b.Min.X+x, b.Min.Y+y).RGBA()
+			s := &row[x*w/sw]
+			s.r += uint64(r)
+			s.g += uint64(g)
+			s.b += uint64(bl)
+			s.a += uint64(a)
+			s.n++
+		}
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	for i, s := range sums {
+		if s.n == 0 {
+			continue
+		}
+		// the colors are alpha-premultiplied, the white shows through
+		// what the alpha leaves
+		white := 0xffff - s.a/s.n
+		dst.Set(i%w, i/w, color.RGBA64{
+			R: uint16(s.r/s.n + white),
+			G: uint16(s.g/s.n + white),
+			B: uint16(s.b/s.n + white),
+			A: 0xffff,
+		})
+	}
+	return dst
+}
+
+// JPEG scales the image to fit a side×side square and encodes it, the scaled
+// image is returned for the smaller thumbnails.
+func JPEG(src image.Image, side int) ([]byte, image.Image, error) {
+	w, h := Fit(src.Bounds().Dx(), src.Bounds().Dy(), side)
+	img := Resize(src, w, h)
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: Quality}); err != nil {
+		return nil, nil, err
+	}
+	return buf.Bytes(), img, nil
+}
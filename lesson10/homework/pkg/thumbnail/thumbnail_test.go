@@ -0,0 +1,56 @@
+package thumbnail
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"testing"
+)
+
+func TestFit(t *testing.T) {
+	tests := []struct {
+		w, h, side   int
+		wantW, wantH int
+	}{
+		{800, 600, 160, 160, 120},
+		{600, 800, 160, 120, 160},
+		{100, 50, 160, 100, 50},
+		{5000, 2, 160, 160, 1},
+	}
+	for _, tc := range tests {
+		w, h := Fit(tc.w, tc.h, tc.side)
+		assert.Equal(t, [2]int{tc.wantW, tc.wantH}, [2]int{w, h})
+	}
+}
+
+func TestResize(t *testing.T) {
+	// the left half is black, the right half is transparent
+	src := image.NewNRGBA(image.Rect(0, 0, 4, 2))
+	for y := 0; y < 2; y++ {
+		for x := 0; x < 2; x++ {
+			src.Set(x, y, color.Black)
+		}
+	}
+	dst := Resize(src, 2, 1)
+	assert.Equal(t, color.RGBA{A: 0xff}, dst.RGBAAt(0, 0))
+	assert.Equal(t, color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}, dst.RGBAAt(1, 0))
+}
+
+func TestJPEG(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 400, 200))
+	data, scaled, err := JPEG(src, 100)
+	assert.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 100, 50), scaled.Bounds())
+	cfg, err := jpeg.DecodeConfig(bytes.NewReader(data))
+	assert.NoError(t, err)
+	assert.Equal(t, [2]int{100, 50}, [2]int{cfg.Width, cfg.Height})
+}
+
+func BenchmarkJPEG(b *testing.B) {
+	src := image.NewRGBA(image.Rect(0, 0, 2000, 1500))
+	for i := 0; i < b.N; i++ {
+		_, _, _ = JPEG(src, 480)
+	}
+}